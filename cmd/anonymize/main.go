@@ -0,0 +1,82 @@
+// Command anonymize scrubs PII in a copied database (e.g. a staging refresh from production)
+// using the rules in sql/anonymize.json.
+//
+// Usage:
+//
+//	go run ./cmd/anonymize -url postgres://.../staging [-rules sql/anonymize.json] [-dry-run] -yes
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"main.go/internal/anonymize"
+	"main.go/internal/config"
+	"main.go/internal/database"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	url := flag.String("url", "", "database URL of the copy to scrub (required; never defaults to DB_URL)")
+	rulesPath := flag.String("rules", "./sql/anonymize.json", "path to the anonymization rules")
+	dryRun := flag.Bool("dry-run", false, "print the statements without executing them")
+	yes := flag.Bool("yes", false, "confirm the target database may be irreversibly modified")
+	force := flag.Bool("force", false, "allow running when APP_ENV=production or -url equals DB_URL")
+	flag.Parse()
+
+	rules, err := anonymize.LoadRules(*rulesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		for _, rule := range rules.Rules {
+			statement, _ := rule.Statement()
+			fmt.Println(statement + ";")
+		}
+		return
+	}
+
+	if *url == "" {
+		fmt.Fprintln(os.Stderr, "-url is required")
+		os.Exit(2)
+	}
+	if (cfg.IsProduction() || *url == cfg.DBURL) && !*force {
+		fmt.Fprintln(os.Stderr, "Refusing to scrub what looks like the live database (APP_ENV=production or -url equals DB_URL); pass -force if this really is a copy")
+		os.Exit(2)
+	}
+	if !*yes {
+		fmt.Fprintln(os.Stderr, "This irreversibly rewrites data in the target database; re-run with -yes to confirm")
+		os.Exit(2)
+	}
+
+	db, err := database.NewConnection(*url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	results, err := anonymize.Run(context.Background(), db, rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Anonymization failed, no changes were committed: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, result := range results {
+		target := result.Rule.Table
+		if result.Rule.Column != "" {
+			target += "." + result.Rule.Column
+		}
+		fmt.Printf("%-40s %-8s %d row(s)\n", target, result.Rule.Strategy, result.RowsAffected)
+	}
+	fmt.Printf("Anonymized %d rule(s)\n", len(results))
+}
@@ -0,0 +1,123 @@
+// Command apikey manages API keys for machine-to-machine clients.
+//
+// Usage:
+//
+//	go run ./cmd/apikey create -name billing-sync [-scopes reports:read,users:read] [-expires 2160h]
+//	go run ./cmd/apikey list
+//	go run ./cmd/apikey revoke <id|prefix>
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"main.go/internal/auth/apikey"
+	"main.go/internal/config"
+	"main.go/internal/database"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if len(os.Args) < 2 {
+		usage()
+	}
+	if cfg.DBURL == "" {
+		fmt.Fprintln(os.Stderr, "DB_URL is not configured")
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.DBDriver, cfg.DBURL, database.DefaultPoolOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	store := apikey.NewStore(db)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		name := flags.String("name", "", "descriptive name of the client (required)")
+		scopes := flags.String("scopes", "", "comma-separated scopes granted to the key")
+		expires := flags.Duration("expires", 0, "key lifetime, e.g. 2160h (default: never expires)")
+		_ = flags.Parse(os.Args[2:])
+
+		if *name == "" {
+			fmt.Fprintln(os.Stderr, "-name is required")
+			os.Exit(2)
+		}
+		var expiresAt *time.Time
+		if *expires > 0 {
+			t := time.Now().Add(*expires)
+			expiresAt = &t
+		}
+
+		plaintext, key, err := store.Create(ctx, *name, splitScopes(*scopes), expiresAt)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created API key %s (%s)\n", key.ID, key.Name)
+		fmt.Printf("Key: %s\n", plaintext)
+		fmt.Println("Store it now; it cannot be shown again.")
+
+	case "list":
+		keys, err := store.List(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, key := range keys {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "revoked"
+			} else if key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now()) {
+				status = "expired"
+			}
+			lastUsed := "never"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s  %-8s  %-8s  %-24s  last used %-25s  scopes %s\n",
+				key.ID, key.Prefix, status, key.Name, lastUsed, strings.Join(key.Scopes, ","))
+		}
+
+	case "revoke":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		if err := store.Revoke(ctx, os.Args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked API key %s\n", os.Args[2])
+
+	default:
+		usage()
+	}
+}
+
+func splitScopes(value string) []string {
+	scopes := []string{}
+	for _, scope := range strings.Split(value, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey <create|list|revoke> [flags]")
+	os.Exit(2)
+}
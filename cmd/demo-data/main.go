@@ -0,0 +1,67 @@
+// Command demo-data loads the YAML fixtures in sql/fixtures/demo into the configured database.
+//
+// Usage:
+//
+//	go run ./cmd/demo-data [-dir ./sql/fixtures/demo] [-dry-run] [-force]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"main.go/internal/config"
+	"main.go/internal/database"
+	"main.go/internal/fixtures"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	dir := flag.String("dir", "./sql/fixtures/demo", "fixture directory")
+	dryRun := flag.Bool("dry-run", false, "parse the fixtures and list them without inserting")
+	force := flag.Bool("force", false, "allow loading demo data when APP_ENV=production")
+	flag.Parse()
+
+	set, err := fixtures.LoadDir(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		for _, record := range set.Records {
+			fmt.Printf("%-24s %-24s %s\n", record.Table, record.Label, record.Source)
+		}
+		fmt.Printf("%d fixture(s) parsed\n", len(set.Records))
+		return
+	}
+
+	if cfg.IsProduction() && !*force {
+		fmt.Fprintln(os.Stderr, "Refusing to load demo data with APP_ENV=production; pass -force to override")
+		os.Exit(2)
+	}
+	if cfg.DBURL == "" {
+		fmt.Fprintln(os.Stderr, "DB_URL is not configured")
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.DBDriver, cfg.DBURL, database.DefaultPoolOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	loaded, err := set.Insert(context.Background(), db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Loading demo data failed, no changes were committed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Inserted %d fixture(s) from %s\n", loaded.Count(), *dir)
+}
@@ -0,0 +1,84 @@
+// Command enckey generates column encryption keys and re-encrypts columns after a rotation.
+//
+// Usage:
+//
+//	go run ./cmd/enckey generate [-id 2026-10]
+//	go run ./cmd/enckey rotate -table integrations -column api_token [-id-column id]
+//
+// To rotate, add the generated "<id>:<key>" to ENCRYPTION_KEYS and set ENCRYPTION_ACTIVE_KEY to
+// it, deploy, then run rotate for every encrypted column. Drop the old key once no value
+// uses it.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"main.go/internal/config"
+	"main.go/internal/crypto"
+	"main.go/internal/database"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: enckey <generate|rotate> [flags]")
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	switch command {
+	case "generate":
+		flags := flag.NewFlagSet("generate", flag.ExitOnError)
+		id := flags.String("id", time.Now().UTC().Format("2006-01"), "key ID")
+		_ = flags.Parse(args)
+
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s:%s\n", *id, key)
+	case "rotate":
+		flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+		table := flags.String("table", "", "table holding the encrypted column")
+		column := flags.String("column", "", "encrypted column")
+		idColumn := flags.String("id-column", "id", "column identifying rows")
+		_ = flags.Parse(args)
+		if *table == "" || *column == "" {
+			fmt.Fprintln(os.Stderr, "rotate needs -table and -column")
+			os.Exit(2)
+		}
+
+		keyring, err := crypto.NewKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid ENCRYPTION_KEYS: %v\n", err)
+			os.Exit(1)
+		}
+		db, err := database.Open(cfg.DBDriver, cfg.DBURL, database.DefaultPoolOptions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		n, err := crypto.RotateColumn(context.Background(), db, keyring, *table, *idColumn, *column)
+		fmt.Printf("%d value(s) re-encrypted with key %s\n", n, keyring.ActiveKey())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			db.Close()
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s (expected generate or rotate)\n", command)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,210 @@
+// Command icons renders the favicon and touch icon set from one source image into
+// internal/icons/generated, where it is embedded into the binary.
+//
+// Usage:
+//
+//	go generate ./internal/icons
+//	go run ./cmd/icons [-src internal/icons/source.png] [-out internal/icons/generated] [-background "#ffffff"]
+//
+// The source should be a square PNG or JPEG of at least 512x512 pixels; other aspect
+// ratios are centered on a transparent square.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"main.go/internal/icons"
+)
+
+// minSourceSize keeps the largest icon from being upscaled
+const minSourceSize = 512
+
+func main() {
+	src := flag.String("src", "internal/icons/source.png", "source image (PNG or JPEG)")
+	out := flag.String("out", "internal/icons/generated", "output directory")
+	background := flag.String("background", "#ffffff", "background color for touch and maskable icons")
+	safeZone := flag.Float64("maskable-scale", 0.6, "share of a maskable icon the artwork covers")
+	flag.Parse()
+
+	bg, err := parseHexColor(*background)
+	if err != nil {
+		fail(err)
+	}
+	source, err := loadSquare(*src)
+	if err != nil {
+		fail(err)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fail(err)
+	}
+
+	for _, icon := range icons.Set {
+		var img image.Image
+		switch icon.Kind {
+		case icons.KindTouch:
+			img = onBackground(resize(source, icon.Size), icon.Size, bg)
+		case icons.KindMaskable:
+			inner := int(math.Round(float64(icon.Size) * *safeZone))
+			img = onBackground(resize(source, inner), icon.Size, bg)
+		default:
+			img = resize(source, icon.Size)
+		}
+		if err := writePNG(filepath.Join(*out, icon.File), img); err != nil {
+			fail(err)
+		}
+	}
+
+	ico, err := encodeICO(source, icons.FaviconSizes)
+	if err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, icons.Favicon), ico, 0o644); err != nil {
+		fail(err)
+	}
+	fmt.Printf("Wrote %d icons and %s to %s\n", len(icons.Set), icons.Favicon, *out)
+}
+
+// loadSquare decodes the source and centers it on a transparent square canvas
+func loadSquare(path string) (*image.NRGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	b := img.Bounds()
+	side := max(b.Dx(), b.Dy())
+	if side < minSourceSize {
+		return nil, fmt.Errorf("%s is %dx%d; use at least %dx%d", path, b.Dx(), b.Dy(), minSourceSize, minSourceSize)
+	}
+
+	square := image.NewNRGBA(image.Rect(0, 0, side, side))
+	offset := image.Pt((side-b.Dx())/2, (side-b.Dy())/2)
+	draw.Draw(square, b.Sub(b.Min).Add(offset), img, b.Min, draw.Src)
+	return square, nil
+}
+
+// resize scales a square image down with an area average, weighting colors by alpha so
+// transparent edges do not darken
+func resize(src *image.NRGBA, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scale := float64(src.Bounds().Dx()) / float64(size)
+
+	for dy := 0; dy < size; dy++ {
+		y0, y1 := float64(dy)*scale, float64(dy+1)*scale
+		for dx := 0; dx < size; dx++ {
+			x0, x1 := float64(dx)*scale, float64(dx+1)*scale
+
+			var r, g, b, a, total float64
+			for sy := int(y0); float64(sy) < y1; sy++ {
+				wy := math.Min(y1, float64(sy+1)) - math.Max(y0, float64(sy))
+				for sx := int(x0); float64(sx) < x1; sx++ {
+					wx := math.Min(x1, float64(sx+1)) - math.Max(x0, float64(sx))
+					weight := wx * wy
+					px := src.NRGBAAt(sx, sy)
+					alpha := float64(px.A) / 255 * weight
+					r += float64(px.R) * alpha
+					g += float64(px.G) * alpha
+					b += float64(px.B) * alpha
+					a += alpha
+					total += weight
+				}
+			}
+			if a > 0 {
+				dst.SetNRGBA(dx, dy, color.NRGBA{
+					R: uint8(math.Round(r / a)),
+					G: uint8(math.Round(g / a)),
+					B: uint8(math.Round(b / a)),
+					A: uint8(math.Round(a / total * 255)),
+				})
+			}
+		}
+	}
+	return dst
+}
+
+// onBackground centers img on an opaque size x size square of bg
+func onBackground(img *image.NRGBA, size int, bg color.NRGBA) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	offset := (size - img.Bounds().Dx()) / 2
+	draw.Draw(dst, img.Bounds().Add(image.Pt(offset, offset)), img, image.Point{}, draw.Over)
+	return dst
+}
+
+// encodeICO packs PNG-compressed images of each size into one ICO file, which every
+// current browser accepts
+func encodeICO(src *image.NRGBA, sizes []int) ([]byte, error) {
+	images := make([][]byte, len(sizes))
+	for i, size := range sizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, resize(src, size)); err != nil {
+			return nil, err
+		}
+		images[i] = buf.Bytes()
+	}
+
+	var out bytes.Buffer
+	// ICONDIR: reserved, type (1 = icon), image count
+	_ = binary.Write(&out, binary.LittleEndian, [3]uint16{0, 1, uint16(len(sizes))})
+	offset := 6 + 16*len(sizes)
+	for i, size := range sizes {
+		dim := uint8(size)
+		if size >= 256 {
+			dim = 0
+		}
+		// ICONDIRENTRY: width, height, palette size, reserved, planes, bits per pixel, bytes, offset
+		out.Write([]byte{dim, dim, 0, 0})
+		_ = binary.Write(&out, binary.LittleEndian, [2]uint16{1, 32})
+		_ = binary.Write(&out, binary.LittleEndian, [2]uint32{uint32(len(images[i])), uint32(offset)})
+		offset += len(images[i])
+	}
+	for _, img := range images {
+		out.Write(img)
+	}
+	return out.Bytes(), nil
+}
+
+func writePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// parseHexColor parses "#rgb" or "#rrggbb"
+func parseHexColor(value string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q; use #rrggbb", value)
+	}
+	return color.NRGBA{R: uint8(n >> 16), G: uint8(n >> 8), B: uint8(n), A: 255}, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "icons:", err)
+	os.Exit(1)
+}
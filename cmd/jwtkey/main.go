@@ -0,0 +1,84 @@
+// Command jwtkey generates JWT signing keys for JWT_KEYS_DIR.
+//
+// Usage:
+//
+//	go run ./cmd/jwtkey [-dir ./keys] [-alg ES256|RS256] [-kid 2026-10]
+//
+// The key is written to <dir>/<kid>.pem. Set JWT_ACTIVE_KEY_ID=<kid> once every service
+// verifying tokens has picked up the new key from /.well-known/jwks.json.
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"main.go/internal/config"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	defaultDir := cfg.JWTConfig.KeysDir
+	if defaultDir == "" {
+		defaultDir = "./keys"
+	}
+	dir := flag.String("dir", defaultDir, "directory holding signing keys (JWT_KEYS_DIR)")
+	alg := flag.String("alg", "ES256", "signing algorithm: ES256 or RS256")
+	kid := flag.String("kid", time.Now().UTC().Format("20060102-150405"), "key ID, also the file name")
+	flag.Parse()
+
+	var key crypto.Signer
+	switch *alg {
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported -alg %q (use ES256 or RS256)\n", *alg)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate key: %v\n", err)
+		os.Exit(1)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode key: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *dir, err)
+		os.Exit(1)
+	}
+	path := filepath.Join(*dir, *kid+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote %s signing key %s\n", *alg, path)
+	fmt.Printf("It verifies tokens immediately; set JWT_ACTIVE_KEY_ID=%s to start signing with it\n", *kid)
+}
@@ -0,0 +1,178 @@
+// Command migrate lints and applies the SQL migrations in sql/migrations.
+//
+// Usage:
+//
+//	go run ./cmd/migrate [-dir ./sql/migrations] [-allow-destructive] [-dry-run] <lint|status|up>
+//	go run ./cmd/migrate tenant-add <slug> [name]
+//
+// With TENANCY_MODE=schema, up also applies TENANCY_MIGRATIONS_DIR to every tenant schema,
+// and tenant-add registers a tenant and migrates its new schema.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"main.go/internal/config"
+	"main.go/internal/database"
+	"main.go/internal/database/migrate"
+	"main.go/internal/tenancy"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	dir := flag.String("dir", cfg.MigrationsDir, "migration directory")
+	allowDestructive := flag.Bool("allow-destructive", cfg.MigrateAllowDestructive, "apply migrations even when the linter reports dangerous operations")
+	dryRun := flag.Bool("dry-run", false, "lint and list pending migrations without applying them")
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+
+	migrations, err := migrate.Load(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch command {
+	case "lint":
+		os.Exit(lint(migrations, *allowDestructive))
+	case "status", "up", "tenant-add":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s (expected lint, status, up, or tenant-add)\n", command)
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.DBDriver, cfg.DBURL, database.DefaultPoolOptions)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	runner := migrate.NewRunner(db)
+	ctx := context.Background()
+	opts := migrate.Options{AllowDestructive: *allowDestructive, DryRun: *dryRun}
+
+	if command == "tenant-add" {
+		os.Exit(addTenant(ctx, db, cfg, flag.Arg(1), flag.Arg(2), opts))
+	}
+
+	if command == "status" {
+		pending, err := runner.Pending(ctx, migrations)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d migration(s), %d pending\n", len(migrations), len(pending))
+		for _, m := range pending {
+			fmt.Printf("  pending: %s\n", m.Name)
+		}
+		return
+	}
+
+	applied, err := runner.Up(ctx, migrations, opts)
+	if code := report(applied, err, opts, ""); code != 0 {
+		os.Exit(code)
+	}
+	if cfg.Tenancy.Mode != tenancy.ModeSchema {
+		return
+	}
+
+	tenantMigrations, err := migrate.Load(cfg.Tenancy.MigrationsDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := 0
+	err = tenancy.MigrateAll(ctx, db, tenantMigrations, opts, func(t tenancy.Tenant, done []migrate.Migration) {
+		code = report(done, nil, opts, t.Slug)
+	})
+	if err != nil {
+		code = report(nil, err, opts, "")
+	}
+	os.Exit(code)
+}
+
+// addTenant registers a tenant and, in schema mode, creates and migrates its schema
+func addTenant(ctx context.Context, db *database.DB, cfg *config.Config, slug, name string, opts migrate.Options) int {
+	if slug == "" {
+		fmt.Fprintln(os.Stderr, "Usage: migrate tenant-add <slug> [name]")
+		return 2
+	}
+	t, err := tenancy.NewStore(db).Create(ctx, slug, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("tenant %s created (%s)\n", t.Slug, t.ID)
+	if cfg.Tenancy.Mode != tenancy.ModeSchema {
+		return 0
+	}
+
+	migrations, err := migrate.Load(cfg.Tenancy.MigrationsDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	applied, err := tenancy.Migrate(ctx, db, t, migrations, opts)
+	return report(applied, err, opts, t.Slug)
+}
+
+// report prints the migrations applied, to the tenant named when there is one, and
+// returns the process exit code
+func report(applied []migrate.Migration, err error, opts migrate.Options, tenant string) int {
+	verb := "applied"
+	if opts.DryRun {
+		verb = "would apply"
+	}
+	suffix := ""
+	if tenant != "" {
+		suffix = " (tenant " + tenant + ")"
+	}
+	for _, m := range applied {
+		fmt.Printf("%s: %s%s\n", verb, m.Name, suffix)
+	}
+
+	var destructive *migrate.DestructiveError
+	if errors.As(err, &destructive) {
+		fmt.Fprintln(os.Stderr, err)
+		return 3
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%d migration(s) %s%s\n", len(applied), verb, suffix)
+	return 0
+}
+
+// lint prints findings for every migration file and returns the process exit code
+func lint(migrations []migrate.Migration, allowDestructive bool) int {
+	findings := migrate.LintAll(migrations)
+	for _, f := range findings {
+		fmt.Println(f.String())
+	}
+
+	if len(findings) == 0 {
+		fmt.Printf("%d migration(s) checked, no dangerous operations found\n", len(migrations))
+		return 0
+	}
+
+	fmt.Printf("%d finding(s) in %d migration(s)\n", len(findings), len(migrations))
+	if allowDestructive {
+		return 0
+	}
+	return 3
+}
@@ -11,6 +11,7 @@ ENV_FILE=".env"
 DRY_RUN=false
 VERBOSE=false
 BATCH_SIZE=10
+ALLOW_DESTRUCTIVE=false
 
 # Colors for output
 RED='\033[0;31m'
@@ -47,6 +48,10 @@ while [[ $# -gt 0 ]]; do
             BATCH_SIZE="$2"
             shift 2
             ;;
+        --allow-destructive)
+            ALLOW_DESTRUCTIVE=true
+            shift
+            ;;
         -h|--help)
             COMMAND="help"
             break
@@ -219,6 +224,25 @@ list_pending() {
     done
 }
 
+# Function to lint migrations for destructive operations
+lint_migrations() {
+    print_status "HEADER" "🛡️  Linting Migrations"
+
+    ensure_migration_dir
+
+    local lint_args=(-dir "$MIGRATION_DIR")
+    if [ "$ALLOW_DESTRUCTIVE" = true ]; then
+        lint_args+=(-allow-destructive)
+    fi
+
+    if go run ./cmd/migrate "${lint_args[@]}" lint; then
+        print_status "SUCCESS" "Migration lint passed"
+    else
+        print_status "ERROR" "Dangerous operations found; re-run with --allow-destructive once reviewed"
+        return 1
+    fi
+}
+
 # Function to run migrations
 run_migrations() {
     local direction="${1:-up}"
@@ -226,6 +250,10 @@ run_migrations() {
     print_status "HEADER" "🚀 Running Migrations ($direction)"
     
     ensure_migration_dir
+
+    if [ "$direction" = "up" ] && ! lint_migrations; then
+        exit 1
+    fi
     
     local db_url=$(get_db_url)
     if [ $? -ne 0 ]; then
@@ -446,6 +474,7 @@ show_help() {
     echo "  up                      Run pending migrations"
     echo "  down [steps]            Rollback migrations (default: 1 step)"
     echo "  validate                Validate migration files"
+    echo "  lint                    Check migrations for destructive operations"
     echo ""
     echo "Options:"
     echo "  --dir <path>            Migration directory (default: ./sql/migrations)"
@@ -453,12 +482,14 @@ show_help() {
     echo "  --dry-run               Show what would be done without executing"
     echo "  --verbose               Show detailed output"
     echo "  --batch-size <number>   Number of migrations to run in batch"
+    echo "  --allow-destructive     Apply migrations flagged by the linter (drops, table rewrites, ...)"
     echo ""
     echo "Examples:"
     echo "  $0 create \"Add user table\""
     echo "  $0 up --dry-run"
     echo "  $0 down 2"
     echo "  $0 validate --verbose"
+    echo "  $0 up --allow-destructive"
     echo "  $0 status --env .env.production"
 }
 
@@ -485,6 +516,9 @@ case $COMMAND in
     "validate")
         validate_migrations
         ;;
+    "lint")
+        lint_migrations
+        ;;
     *)
         print_status "ERROR" "Unknown command: $COMMAND"
         echo ""
@@ -0,0 +1,137 @@
+package anonymize
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	pq "github.com/lib/pq"
+
+	"main.go/internal/database"
+)
+
+// Supported scrubbing strategies
+const (
+	StrategyEmail  = "email"  // user+<hash>@example.invalid, unique per original value
+	StrategyName   = "name"   // "Person <hash>"
+	StrategyPhone  = "phone"  // +1555XXXXXXX derived from the original value
+	StrategyToken  = "token"  // random, unusable token
+	StrategyHash   = "hash"   // md5 of the original value
+	StrategyFixed  = "fixed"  // the literal Rule.Value
+	StrategyNull   = "null"   // NULL
+	StrategyDelete = "delete" // delete every row of the table (sessions, tokens, ...)
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Rule describes how one column (or, for "delete", one table) is scrubbed
+type Rule struct {
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Strategy string `json:"strategy"`
+	Value    string `json:"value,omitempty"`
+}
+
+// Rules is the on-disk rule file format
+type Rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadRules reads and validates a JSON rule file
+func LoadRules(path string) (*Rules, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(contents, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	for i, rule := range rules.Rules {
+		if _, err := rule.Statement(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return &rules, nil
+}
+
+// Statement builds the SQL statement for the rule. Identifiers are validated and quoted;
+// NULLs stay NULL so optional columns keep their shape.
+func (r Rule) Statement() (string, error) {
+	if !identifierPattern.MatchString(r.Table) {
+		return "", fmt.Errorf("invalid table name %q", r.Table)
+	}
+	table := pq.QuoteIdentifier(r.Table)
+
+	if r.Strategy == StrategyDelete {
+		return fmt.Sprintf("DELETE FROM %s", table), nil
+	}
+
+	if !identifierPattern.MatchString(r.Column) {
+		return "", fmt.Errorf("invalid column name %q for table %s", r.Column, r.Table)
+	}
+	column := pq.QuoteIdentifier(r.Column)
+	digest := fmt.Sprintf("md5(%s::text)", column)
+
+	var expr string
+	switch r.Strategy {
+	case StrategyEmail:
+		expr = fmt.Sprintf("'user+' || %s || '@example.invalid'", digest)
+	case StrategyName:
+		expr = fmt.Sprintf("'Person ' || substr(%s, 1, 8)", digest)
+	case StrategyPhone:
+		expr = fmt.Sprintf("'+1555' || lpad((abs(hashtext(%s::text)) %% 10000000)::text, 7, '0')", column)
+	case StrategyToken:
+		expr = "md5(random()::text || clock_timestamp()::text)"
+	case StrategyHash:
+		expr = digest
+	case StrategyFixed:
+		expr = pq.QuoteLiteral(r.Value)
+	case StrategyNull:
+		return fmt.Sprintf("UPDATE %s SET %s = NULL", table, column), nil
+	default:
+		return "", fmt.Errorf("unknown strategy %q", r.Strategy)
+	}
+
+	return fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NOT NULL", table, column, expr, column), nil
+}
+
+// Result reports how many rows a rule touched
+type Result struct {
+	Rule         Rule
+	Statement    string
+	RowsAffected int64
+}
+
+// Run applies every rule inside one transaction so a failure leaves the copy untouched
+func Run(ctx context.Context, db *database.DB, rules *Rules) ([]Result, error) {
+	var results []Result
+	err := db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// A retried transaction starts over
+		results = make([]Result, 0, len(rules.Rules))
+		for _, rule := range rules.Rules {
+			statement, err := rule.Statement()
+			if err != nil {
+				return err
+			}
+
+			res, err := tx.ExecContext(ctx, statement)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", rule.Table, rule.Column, err)
+			}
+
+			affected, _ := res.RowsAffected()
+			results = append(results, Result{Rule: rule, Statement: statement, RowsAffected: affected})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,514 @@
+// Package attachments attaches uploaded files to any resource, identified by a type name
+// and an ID, e.g. ("user", <user id>). Resource types are registered with the table their
+// rows live in and who may read and change their attachments. Files keep an order and a
+// caption; their contents go to a storage.Storage and their metadata to the attachments
+// table. There is no foreign key to the parent, so removal is two-step: attachments are
+// marked orphaned when removed, when their parent calls DetachAll, or when a background
+// sweep finds the parent row gone, and the cleanup job then deletes the stored files.
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+	"main.go/internal/storage"
+)
+
+// ManageScope lets admins manage the attachments of every resource
+const ManageScope = "attachments:write"
+
+// filePurpose scopes the tokens of signed file links
+const filePurpose = "attachment-file"
+
+// cleanupBatch bounds the files deleted per query
+const cleanupBatch = 100
+
+var (
+	// ErrUnknownType is returned for resource types that were never registered
+	ErrUnknownType = errors.New("unknown attachment resource type")
+	// ErrTooLarge is returned for files over the size limit
+	ErrTooLarge = errors.New("file is too large")
+	// ErrTypeNotAllowed is returned for files whose sniffed content type is not allowed
+	ErrTypeNotAllowed = errors.New("file type is not allowed")
+	// ErrTooMany is returned when a resource already has its maximum number of attachments
+	ErrTooMany = errors.New("too many attachments")
+)
+
+var (
+	uploaded = metrics.NewCounter("attachments", "uploaded_total", "Files attached to resources")
+	orphaned = metrics.NewCounter("attachments", "orphaned_total", "Attachments removed or left without a parent")
+	cleaned  = metrics.NewCounter("attachments", "cleaned_total", "Stored files of removed attachments deleted")
+	infected = metrics.NewCounter("attachments", "infected_total", "Uploads rejected because a scanner found malware")
+)
+
+// Attachment is a file attached to a resource
+type Attachment struct {
+	ID           string    `json:"id"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Position     int       `json:"position"`
+	Caption      string    `json:"caption"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Checksum     string    `json:"checksum"`
+	UploadedBy   string    `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	// key locates the contents in storage; it is never exposed
+	key string
+}
+
+// Type is a kind of resource files can be attached to
+type Type struct {
+	// Name identifies the type in routes and rows, e.g. "user"
+	Name string
+	// Table and IDColumn locate parent rows; attachments whose parent is gone are cleaned up
+	Table    string
+	IDColumn string
+	// CanWrite reports whether the request may add, edit, reorder, or remove the
+	// attachments of resourceID. Holders of ManageScope always may.
+	CanWrite func(c *fiber.Ctx, resourceID string) bool
+	// CanRead reports whether the request may list and download them; nil means CanWrite
+	CanRead func(c *fiber.Ctx, resourceID string) bool
+	// MaxFiles bounds the attachments per resource; 0 means no limit
+	MaxFiles int
+}
+
+// TypeUser is the resource type of files attached to user accounts
+const TypeUser = "user"
+
+// UserType lets signed-in users manage the files attached to their own account
+func UserType(maxFiles int) Type {
+	own := func(c *fiber.Ctx, resourceID string) bool {
+		userID := auth.UserID(c)
+		return userID != "" && userID == resourceID
+	}
+	return Type{Name: TypeUser, Table: "users", IDColumn: "id", CanWrite: own, MaxFiles: maxFiles}
+}
+
+// Options configures uploads
+type Options struct {
+	// MaxBytes bounds the size of a single file
+	MaxBytes int64
+	// AllowedTypes lists the content types accepted, matched against the sniffed type;
+	// "image/*" allows a whole family
+	AllowedTypes []string
+	// Scanner checks every upload for malware before it is attached; nil skips scanning
+	Scanner Scanner
+	// Secret signs file links, which stay valid for URLTTL; AppURL prefixes them
+	Secret string
+	URLTTL time.Duration
+	AppURL string
+}
+
+// Upload is a file to attach
+type Upload struct {
+	Filename   string
+	Size       int64
+	Body       io.Reader
+	Caption    string
+	UploadedBy string
+	// MaxBytes and AllowedTypes replace the Options limits when set, e.g. for large
+	// uploads assembled from chunks
+	MaxBytes     int64
+	AllowedTypes []string
+}
+
+// Manager attaches files and cleans up after removed ones
+type Manager struct {
+	store   *Store
+	storage storage.Storage
+	opts    Options
+	tokens  *auth.SignedTokens
+	clock   clock.Clock
+	logger  *logger.Logger
+
+	mu    sync.RWMutex
+	types map[string]Type
+	// purgeHooks run before the file of a removed attachment is deleted
+	purgeHooks []func(context.Context, Attachment) error
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// New creates a manager storing files in st; register types and call Start to clean up
+func New(store *Store, st storage.Storage, opts Options, clk clock.Clock, l *logger.Logger) *Manager {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Manager{
+		store:   store,
+		storage: st,
+		opts:    opts,
+		tokens:  auth.NewSignedTokens(opts.Secret),
+		clock:   clk,
+		logger:  l,
+		types:   map[string]Type{},
+		stop:    make(chan struct{}),
+	}
+}
+
+// Register makes a resource type attachable
+func (m *Manager) Register(t Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.types[t.Name] = t
+}
+
+// OnPurge runs fn for each removed attachment before the cleanup job deletes its file,
+// e.g. to delete files derived from it. An error keeps the attachment for the next run.
+func (m *Manager) OnPurge(fn func(ctx context.Context, a Attachment) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.purgeHooks = append(m.purgeHooks, fn)
+}
+
+// Type returns the type registered under name
+func (m *Manager) Type(name string) (Type, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.types[name]
+	return t, ok
+}
+
+// Store returns the manager's store
+func (m *Manager) Store() *Store {
+	return m.store
+}
+
+// Storage returns where file contents are kept
+func (m *Manager) Storage() storage.Storage {
+	return m.storage
+}
+
+// CanRead reports whether the request may see the attachments of a resource
+func (m *Manager) CanRead(c *fiber.Ctx, resourceType, resourceID string) bool {
+	t, ok := m.Type(resourceType)
+	if !ok {
+		return false
+	}
+	if t.CanRead != nil && t.CanRead(c, resourceID) {
+		return true
+	}
+	return m.CanWrite(c, resourceType, resourceID)
+}
+
+// CanWrite reports whether the request may change the attachments of a resource
+func (m *Manager) CanWrite(c *fiber.Ctx, resourceType, resourceID string) bool {
+	t, ok := m.Type(resourceType)
+	if !ok {
+		return false
+	}
+	if p, ok := auth.PrincipalFrom(c); ok && p.HasScope(ManageScope) {
+		return true
+	}
+	return t.CanWrite != nil && t.CanWrite(c, resourceID)
+}
+
+// Attach stores an upload and attaches it after the resource's other files. The content
+// type is sniffed from the contents; the name the client sent is only kept for display.
+func (m *Manager) Attach(ctx context.Context, resourceType, resourceID string, up Upload) (*Attachment, error) {
+	t, ok := m.Type(resourceType)
+	if !ok {
+		return nil, ErrUnknownType
+	}
+	maxBytes, allowedTypes := m.opts.MaxBytes, m.opts.AllowedTypes
+	if up.MaxBytes > 0 {
+		maxBytes = up.MaxBytes
+	}
+	if up.AllowedTypes != nil {
+		allowedTypes = up.AllowedTypes
+	}
+	if maxBytes > 0 && up.Size > maxBytes {
+		return nil, ErrTooLarge
+	}
+	if t.MaxFiles > 0 {
+		n, err := m.store.Count(ctx, t.Name, resourceID)
+		if err != nil {
+			return nil, err
+		}
+		if n >= t.MaxFiles {
+			return nil, ErrTooMany
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(up.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowed(allowedTypes, contentType) {
+		return nil, ErrTypeNotAllowed
+	}
+
+	a := &Attachment{
+		ID:           ids.Default().NewID(),
+		ResourceType: t.Name,
+		ResourceID:   resourceID,
+		Caption:      up.Caption,
+		Filename:     sanitizeFilename(up.Filename),
+		ContentType:  contentType,
+		Size:         up.Size,
+		UploadedBy:   up.UploadedBy,
+	}
+	a.key = "attachments/" + t.Name + "/" + a.ID
+
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), up.Body), hash)
+	if err := m.storage.Put(ctx, a.key, body, up.Size, contentType); err != nil {
+		return nil, err
+	}
+	a.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := m.scan(ctx, a); err != nil {
+		if delErr := m.storage.Delete(context.WithoutCancel(ctx), a.key); delErr != nil {
+			m.logger.Warn("Failed to delete rejected upload", zap.String("key", a.key), zap.Error(delErr))
+		}
+		return nil, err
+	}
+
+	created, err := m.store.Insert(ctx, a)
+	if err != nil {
+		if delErr := m.storage.Delete(context.WithoutCancel(ctx), a.key); delErr != nil {
+			m.logger.Warn("Failed to delete unrecorded attachment", zap.String("key", a.key), zap.Error(delErr))
+		}
+		return nil, err
+	}
+	uploaded.Inc()
+	return created, nil
+}
+
+// scan reads a stored upload back through the scanner. Nothing links to the file until it
+// is recorded, so it cannot be downloaded before it passes.
+func (m *Manager) scan(ctx context.Context, a *Attachment) error {
+	if m.opts.Scanner == nil {
+		return nil
+	}
+	r, err := m.storage.Open(ctx, a.key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	err = m.opts.Scanner.Scan(ctx, r)
+	if errors.Is(err, ErrInfected) {
+		infected.Inc()
+		m.logger.Warn("Rejected infected upload", zap.String("resource_type", a.ResourceType),
+			zap.String("resource_id", a.ResourceID), zap.String("uploaded_by", a.UploadedBy), zap.Error(err))
+	}
+	return err
+}
+
+// Open returns the contents of an attachment
+func (m *Manager) Open(ctx context.Context, a *Attachment) (io.ReadCloser, error) {
+	return m.storage.Open(ctx, a.key)
+}
+
+// OpenRange returns length bytes of an attachment starting at offset
+func (m *Manager) OpenRange(ctx context.Context, a *Attachment, offset, length int64) (io.ReadCloser, error) {
+	return m.storage.OpenRange(ctx, a.key, offset, length)
+}
+
+// URL returns a link that downloads a through the file proxy without signing in, and when
+// it expires. Hand it only to callers that may read a, and only when Options.Secret is set.
+func (m *Manager) URL(a *Attachment) (string, time.Time) {
+	expiresAt := m.clock.Now().Add(m.opts.URLTTL)
+	token := m.tokens.Sign(filePurpose, a.ID, a.Checksum, expiresAt)
+	return strings.TrimRight(m.opts.AppURL, "/") + "/files/" + a.ID + "?token=" + url.QueryEscape(token), expiresAt
+}
+
+// SignsURLs reports whether links from URL work, which needs Options.Secret
+func (m *Manager) SignsURLs() bool {
+	return m.opts.Secret != ""
+}
+
+// VerifyURL checks the token of a link to a; it returns auth.ErrTokenExpired or
+// auth.ErrTokenInvalid when the link does not grant access
+func (m *Manager) VerifyURL(a *Attachment, token string) error {
+	if m.opts.Secret == "" {
+		return auth.ErrTokenInvalid
+	}
+	id, err := m.tokens.Verify(token, filePurpose, a.Checksum, m.clock.Now())
+	if err != nil {
+		return err
+	}
+	if id != a.ID {
+		return auth.ErrTokenInvalid
+	}
+	return nil
+}
+
+// Remove detaches an attachment; its file is deleted by the cleanup job
+func (m *Manager) Remove(ctx context.Context, id string) error {
+	if err := m.store.Orphan(ctx, id, m.clock.Now()); err != nil {
+		return err
+	}
+	orphaned.Inc()
+	return nil
+}
+
+// DetachAll removes every attachment of a resource. Call it when deleting the parent so
+// its files go in the next cleanup run instead of waiting for the sweep.
+func (m *Manager) DetachAll(ctx context.Context, resourceType, resourceID string) error {
+	n, err := m.store.OrphanAll(ctx, resourceType, resourceID, m.clock.Now())
+	if err != nil {
+		return err
+	}
+	orphaned.Add(int64(n))
+	return nil
+}
+
+// Cleanup orphans the attachments of deleted parents, then deletes the stored files of
+// removed attachments, and returns how many files were deleted. A file that fails to
+// delete keeps its row and is retried in the next run.
+func (m *Manager) Cleanup(ctx context.Context) (int, error) {
+	m.mu.RLock()
+	types := make([]Type, 0, len(m.types))
+	for _, t := range m.types {
+		types = append(types, t)
+	}
+	hooks := m.purgeHooks
+	m.mu.RUnlock()
+
+	for _, t := range types {
+		if t.Table == "" {
+			continue
+		}
+		n, err := m.store.OrphanMissingParents(ctx, t, m.clock.Now())
+		if err != nil {
+			return 0, err
+		}
+		orphaned.Add(int64(n))
+	}
+
+	total := 0
+	for {
+		list, err := m.store.Orphaned(ctx, cleanupBatch)
+		if err != nil {
+			return total, err
+		}
+		deleted := 0
+		for _, a := range list {
+			if err := runHooks(ctx, hooks, a); err != nil {
+				m.logger.Warn("Failed to clean up after attachment", zap.String("id", a.ID), zap.Error(err))
+				continue
+			}
+			if err := m.storage.Delete(ctx, a.key); err != nil {
+				m.logger.Warn("Failed to delete attachment file", zap.String("id", a.ID), zap.Error(err))
+				continue
+			}
+			if err := m.store.Purge(ctx, a.ID); err != nil {
+				return total, err
+			}
+			deleted++
+		}
+		total += deleted
+		cleaned.Add(int64(deleted))
+		// A batch with failures would be claimed again straight away
+		if len(list) < cleanupBatch || deleted < len(list) {
+			return total, nil
+		}
+	}
+}
+
+func runHooks(ctx context.Context, hooks []func(context.Context, Attachment) error, a Attachment) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start cleans up now and then every interval until Stop
+func (m *Manager) Start(interval time.Duration) {
+	safego.GoNamed("attachment-cleanup", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// One replica cleans up at a time; the others skip the round
+			var n int
+			_, err := m.store.db.WithTryAdvisoryLock(ctx, "attachment-cleanup", func(ctx context.Context) (err error) {
+				n, err = m.Cleanup(ctx)
+				return err
+			})
+			cancel()
+			if err != nil {
+				m.logger.Warn("Failed to clean up attachments", zap.Error(err))
+			} else if n > 0 {
+				m.logger.Info("Removed attachment files deleted", zap.Int("files", n))
+			}
+
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background cleanup
+func (m *Manager) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+func allowed(allowedTypes []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range allowedTypes {
+		if allowed == mediaType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeFilename keeps the base name of an uploaded file without control characters,
+// path separators, or quotes, bounded to 255 bytes
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '/' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		name = "file"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,98 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrInfected is returned for files a Scanner finds malware in
+var ErrInfected = errors.New("file contains malware")
+
+// Scanner checks file contents for malware
+type Scanner interface {
+	// Name identifies the scanner in logs
+	Name() string
+	// Scan returns an error wrapping ErrInfected when r contains malware, and another
+	// error when r could not be scanned
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// clamChunk is the size of the chunks streamed to clamd
+const clamChunk = 32 * 1024
+
+// ClamAV scans files with a clamd daemon over TCP using its INSTREAM command. Files over
+// clamd's StreamMaxLength fail to scan, so raise it to at least ATTACHMENT_MAX_BYTES.
+type ClamAV struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAV creates a scanner for the clamd listening at addr, e.g. "localhost:3310"
+func NewClamAV(addr string) *ClamAV {
+	return &ClamAV{addr: addr, timeout: 2 * time.Minute}
+}
+
+// Name implements Scanner
+func (c *ClamAV) Name() string {
+	return "clamav"
+}
+
+// Scan implements Scanner
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("clamav: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("clamav: %w", err)
+	}
+	// Each chunk is prefixed with its length; a zero length ends the stream
+	buf := make([]byte, 4+clamChunk)
+	for {
+		n, readErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return fmt.Errorf("clamav: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("clamav: failed to read file: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("clamav: %w", err)
+	}
+
+	reply, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil {
+		return fmt.Errorf("clamav: %w", err)
+	}
+	result := strings.TrimSpace(string(bytes.TrimRight(reply, "\x00")))
+	switch {
+	case result == "stream: OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(result, "stream: "), " FOUND")
+		return fmt.Errorf("%w: %s", ErrInfected, signature)
+	}
+	return fmt.Errorf("clamav: unexpected reply %q", result)
+}
@@ -0,0 +1,220 @@
+package attachments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"main.go/internal/database"
+)
+
+var (
+	// ErrNotFound is returned when an attachment does not exist or has been removed
+	ErrNotFound = errors.New("attachment not found")
+	// ErrOrderMismatch is returned when a new order does not list exactly the resource's attachments
+	ErrOrderMismatch = errors.New("order must list every attachment of the resource exactly once")
+)
+
+// Store persists attachment metadata; file contents live in a storage.Storage
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new attachment store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const attachmentColumns = `id, resource_type, resource_id, position, caption, filename, content_type,
+    size, checksum, storage_key, COALESCE(uploaded_by::text, ''), created_at`
+
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*Attachment, error) {
+	var a Attachment
+	if err := row.Scan(&a.ID, &a.ResourceType, &a.ResourceID, &a.Position, &a.Caption, &a.Filename,
+		&a.ContentType, &a.Size, &a.Checksum, &a.key, &a.UploadedBy, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Insert records a stored file, placing it after the resource's other attachments
+func (s *Store) Insert(ctx context.Context, a *Attachment) (*Attachment, error) {
+	created, err := scanAttachment(s.db.QueryRowContext(ctx, `
+INSERT INTO attachments (id, resource_type, resource_id, position, caption, filename, content_type,
+    size, checksum, storage_key, uploaded_by)
+VALUES ($1, $2, $3,
+    (SELECT COALESCE(MAX(position) + 1, 0) FROM attachments
+     WHERE resource_type = $2 AND resource_id = $3 AND orphaned_at IS NULL),
+    $4, $5, $6, $7, $8, $9, NULLIF($10, '')::uuid)
+RETURNING `+attachmentColumns,
+		a.ID, a.ResourceType, a.ResourceID, a.Caption, a.Filename, a.ContentType,
+		a.Size, a.Checksum, a.key, a.UploadedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record attachment: %w", err)
+	}
+	return created, nil
+}
+
+// Find returns the attachment with id
+func (s *Store) Find(ctx context.Context, id string) (*Attachment, error) {
+	a, err := scanAttachment(s.db.QueryRowContext(ctx,
+		`SELECT `+attachmentColumns+` FROM attachments WHERE id = $1 AND orphaned_at IS NULL`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachment: %w", err)
+	}
+	return a, nil
+}
+
+// List returns a resource's attachments in order
+func (s *Store) List(ctx context.Context, resourceType, resourceID string) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+attachmentColumns+` FROM attachments
+WHERE resource_type = $1 AND resource_id = $2 AND orphaned_at IS NULL
+ORDER BY position, created_at`, resourceType, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		list = append(list, *a)
+	}
+	return list, rows.Err()
+}
+
+// Count returns how many attachments a resource has
+func (s *Store) Count(ctx context.Context, resourceType, resourceID string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM attachments
+WHERE resource_type = $1 AND resource_id = $2 AND orphaned_at IS NULL`, resourceType, resourceID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count attachments: %w", err)
+	}
+	return n, nil
+}
+
+// SetCaption replaces an attachment's caption
+func (s *Store) SetCaption(ctx context.Context, id, caption string) (*Attachment, error) {
+	a, err := scanAttachment(s.db.QueryRowContext(ctx, `
+UPDATE attachments SET caption = $2 WHERE id = $1 AND orphaned_at IS NULL
+RETURNING `+attachmentColumns, id, caption))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update caption: %w", err)
+	}
+	return a, nil
+}
+
+// Reorder sets a resource's attachment order to ids
+func (s *Store) Reorder(ctx context.Context, resourceType, resourceID string, ids []string) error {
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+UPDATE attachments a SET position = o.position - 1
+FROM unnest($3::uuid[]) WITH ORDINALITY AS o(id, position)
+WHERE a.id = o.id AND a.resource_type = $1 AND a.resource_id = $2 AND a.orphaned_at IS NULL`,
+			resourceType, resourceID, pq.Array(ids))
+		if err != nil {
+			return fmt.Errorf("failed to reorder attachments: %w", err)
+		}
+		updated, _ := res.RowsAffected()
+
+		var total int64
+		if err := tx.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM attachments
+WHERE resource_type = $1 AND resource_id = $2 AND orphaned_at IS NULL`, resourceType, resourceID).Scan(&total); err != nil {
+			return fmt.Errorf("failed to reorder attachments: %w", err)
+		}
+		if updated != int64(len(ids)) || updated != total {
+			return ErrOrderMismatch
+		}
+		return nil
+	})
+}
+
+// Orphan marks an attachment removed; the cleanup job deletes its file
+func (s *Store) Orphan(ctx context.Context, id string, now time.Time) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE attachments SET orphaned_at = $2 WHERE id = $1 AND orphaned_at IS NULL`, id, now)
+	if err != nil {
+		return fmt.Errorf("failed to remove attachment: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// OrphanAll marks every attachment of a resource removed and returns how many there were
+func (s *Store) OrphanAll(ctx context.Context, resourceType, resourceID string, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+UPDATE attachments SET orphaned_at = $3
+WHERE resource_type = $1 AND resource_id = $2 AND orphaned_at IS NULL`, resourceType, resourceID, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove attachments: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// OrphanMissingParents marks removed the attachments of type t whose parent row no longer
+// exists, and returns how many were found
+func (s *Store) OrphanMissingParents(ctx context.Context, t Type, now time.Time) (int, error) {
+	// Table and column names come from registered types, never from requests
+	res, err := s.db.ExecContext(ctx, `
+UPDATE attachments a SET orphaned_at = $2
+WHERE a.resource_type = $1 AND a.orphaned_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM `+pq.QuoteIdentifier(t.Table)+` p WHERE p.`+pq.QuoteIdentifier(t.IDColumn)+`::text = a.resource_id)`,
+		t.Name, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sweep %s attachments: %w", t.Name, err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// Orphaned returns up to limit removed attachments whose files still have to be deleted
+func (s *Store) Orphaned(ctx context.Context, limit int) ([]Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+attachmentColumns+` FROM attachments
+WHERE orphaned_at IS NOT NULL
+ORDER BY orphaned_at
+LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load removed attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		list = append(list, *a)
+	}
+	return list, rows.Err()
+}
+
+// Purge deletes the row of a removed attachment once its file is gone
+func (s *Store) Purge(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM attachments WHERE id = $1 AND orphaned_at IS NOT NULL`, id); err != nil {
+		return fmt.Errorf("failed to purge attachment: %w", err)
+	}
+	return nil
+}
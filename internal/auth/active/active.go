@@ -0,0 +1,289 @@
+// Package active tracks each login (a browser session or a JWT refresh token family)
+// as an active session, so users can see where they are signed in and revoke individual
+// sessions. Sessions are kept in Redis when the cache is enabled and in Postgres otherwise.
+package active
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+)
+
+// ErrNotFound is returned for sessions that do not exist, belong to another user, or were revoked
+var ErrNotFound = errors.New("session not found")
+
+// Session is one login of a user
+type Session struct {
+	ID     string `json:"id"`
+	UserID string `json:"-"`
+	// Method is how the session authenticates: "session" or "jwt"
+	Method     string    `json:"method"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the session making the request
+	Current bool `json:"current"`
+}
+
+// Options configures a registry
+type Options struct {
+	// TouchInterval limits how often a session's last-seen time and IP are written;
+	// defaults to one minute
+	TouchInterval time.Duration
+}
+
+// Registry records active sessions and rejects requests on revoked ones. A nil
+// registry tracks nothing, so callers need not check whether tracking is enabled.
+type Registry struct {
+	store     Store
+	opts      Options
+	onRevoked func(c *fiber.Ctx) error
+}
+
+// NewRegistry creates a registry backed by store
+func NewRegistry(store Store, opts Options) *Registry {
+	if opts.TouchInterval <= 0 {
+		opts.TouchInterval = time.Minute
+	}
+	return &Registry{store: store, opts: opts}
+}
+
+// OnRevoked registers a hook that runs when a request arrives on a revoked browser
+// session, e.g. to destroy the session cookie; the request then continues anonymously
+func (r *Registry) OnRevoked(hook func(c *fiber.Ctx) error) {
+	r.onRevoked = hook
+}
+
+// Enabled reports whether sessions are tracked
+func (r *Registry) Enabled() bool {
+	return r != nil
+}
+
+// Start records a new session for the principal on the requesting device and sets
+// p.SessionID
+func (r *Registry) Start(c *fiber.Ctx, p *auth.Principal, expiresAt time.Time) error {
+	if r == nil {
+		return nil
+	}
+	now := clock.Now(c)
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	session := &Session{
+		ID:         ids.From(c).NewID(),
+		UserID:     p.ID,
+		Method:     p.Method,
+		Device:     describe(userAgent),
+		UserAgent:  truncate(userAgent, maxUserAgent),
+		IP:         c.IP(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err := r.store.Create(c.UserContext(), session); err != nil {
+		return err
+	}
+	p.SessionID = session.ID
+	return nil
+}
+
+// LoginHook starts a session on every session login, replacing the one the request was
+// already signed in with; ttl is the session cookie lifetime
+func (r *Registry) LoginHook(ttl time.Duration) auth.LoginHook {
+	return func(c *fiber.Ctx, p *auth.Principal) error {
+		if r == nil {
+			return nil
+		}
+		if previous, ok := auth.PrincipalFrom(c); ok && previous.SessionID != "" {
+			err := r.store.Delete(c.UserContext(), previous.ID, previous.SessionID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+		return r.Start(c, p, clock.Now(c).Add(ttl))
+	}
+}
+
+// Extend moves a session's expiry, e.g. when its refresh token is exchanged. It returns
+// ErrNotFound when the session was revoked.
+func (r *Registry) Extend(c *fiber.Ctx, p *auth.Principal, expiresAt time.Time) error {
+	if r == nil || p.SessionID == "" {
+		return nil
+	}
+	session, err := r.store.Get(c.UserContext(), p.ID, p.SessionID)
+	if err != nil {
+		return err
+	}
+	now := clock.Now(c)
+	if !session.ExpiresAt.After(now) {
+		return ErrNotFound
+	}
+	session.LastSeenAt = now
+	session.IP = c.IP()
+	session.ExpiresAt = expiresAt
+	return r.store.Update(c.UserContext(), session)
+}
+
+// List returns the user's active sessions, marking the requesting one as current
+func (r *Registry) List(c *fiber.Ctx, userID string) ([]Session, error) {
+	if r == nil {
+		return nil, nil
+	}
+	sessions, err := r.store.List(c.UserContext(), userID, clock.Now(c))
+	if err != nil {
+		return nil, err
+	}
+	current := ""
+	if p, ok := auth.PrincipalFrom(c); ok {
+		current = p.SessionID
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+		if sessions[i].Device == "" {
+			sessions[i].Device = describe(sessions[i].UserAgent)
+		}
+	}
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions; requests on it are rejected from then on
+func (r *Registry) Revoke(c *fiber.Ctx, userID, id string) error {
+	if r == nil {
+		return ErrNotFound
+	}
+	return r.store.Delete(c.UserContext(), userID, id)
+}
+
+// RevokeAll ends every session of the user and returns how many there were
+func (r *Registry) RevokeAll(c *fiber.Ctx, userID string) (int, error) {
+	if r == nil {
+		return 0, nil
+	}
+	return r.store.DeleteAll(c.UserContext(), userID)
+}
+
+// End removes the requesting session, e.g. on logout
+func (r *Registry) End(c *fiber.Ctx) error {
+	p, ok := auth.PrincipalFrom(c)
+	if r == nil || !ok || p.SessionID == "" {
+		return nil
+	}
+	err := r.store.Delete(c.UserContext(), p.ID, p.SessionID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Middleware checks the principal's session after authentication. Revoked browser
+// sessions are logged out and continue anonymously; revoked tokens get 401. Logins from
+// before tracking was enabled carry no session and pass through. Checks fail closed:
+// if the store is unreachable, requests get 503.
+func (r *Registry) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if r == nil {
+			return c.Next()
+		}
+		p, ok := auth.PrincipalFrom(c)
+		if !ok || p.SessionID == "" {
+			return c.Next()
+		}
+
+		now := clock.Now(c)
+		session, err := r.store.Get(c.UserContext(), p.ID, p.SessionID)
+		if err == nil && !session.ExpiresAt.After(now) {
+			err = ErrNotFound
+		}
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return r.revoked(c, p)
+		case err != nil:
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":   "Service Unavailable",
+				"message": "Session store unavailable",
+				"status":  fiber.StatusServiceUnavailable,
+			})
+		}
+
+		// Touching is best effort; a failed write only leaves last-seen stale
+		if now.Sub(session.LastSeenAt) >= r.opts.TouchInterval || session.IP != c.IP() {
+			session.LastSeenAt = now
+			session.IP = c.IP()
+			_ = r.store.Update(c.UserContext(), session)
+		}
+		return c.Next()
+	}
+}
+
+func (r *Registry) revoked(c *fiber.Ctx, p *auth.Principal) error {
+	if p.Method != auth.MethodSession {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Unauthorized",
+			"message": "Session has been revoked",
+			"status":  fiber.StatusUnauthorized,
+		})
+	}
+	if r.onRevoked != nil {
+		if err := r.onRevoked(c); err != nil {
+			return err
+		}
+	}
+	auth.ClearPrincipal(c)
+	return c.Next()
+}
+
+// sortByLastSeen orders sessions most recently seen first
+func sortByLastSeen(sessions []Session) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+}
+
+// describe names the browser and OS in a user agent, e.g. "Chrome on macOS", for
+// display only
+func describe(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also claim Chrome, and Chrome claims Safari
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"crios/", "Chrome"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	for _, o := range []struct{ token, name string }{
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"android", "Android"},
+		{"mac os x", "macOS"},
+		{"windows", "Windows"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			return browser + " on " + o.name
+		}
+	}
+	return browser
+}
@@ -0,0 +1,280 @@
+package active
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"main.go/internal/database"
+)
+
+// maxUserAgent bounds the stored user agent to the column size
+const maxUserAgent = 512
+
+// Store persists active sessions. Get and Delete only find sessions of the given user.
+type Store interface {
+	Create(ctx context.Context, s *Session) error
+	Get(ctx context.Context, userID, id string) (*Session, error)
+	// Update writes LastSeenAt, IP, and ExpiresAt
+	Update(ctx context.Context, s *Session) error
+	// List returns the user's unexpired sessions, most recently seen first
+	List(ctx context.Context, userID string, now time.Time) ([]Session, error)
+	Delete(ctx context.Context, userID, id string) error
+	DeleteAll(ctx context.Context, userID string) (int, error)
+}
+
+// RedisStore keeps each session as a JSON value expiring with the session, plus a
+// per-user sorted set of session IDs scored by expiry
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client, prefix: "auth:"}
+}
+
+// Create implements Store
+func (s *RedisStore) Create(ctx context.Context, session *Session) error {
+	return s.write(ctx, session)
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, userID, id string) (*Session, error) {
+	data, err := s.client.Get(ctx, s.sessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// Update implements Store
+func (s *RedisStore) Update(ctx context.Context, session *Session) error {
+	return s.write(ctx, session)
+}
+
+// List implements Store
+func (s *RedisStore) List(ctx context.Context, userID string, now time.Time) ([]Session, error) {
+	key := s.userKey(userID)
+	if err := s.client.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10)).Err(); err != nil {
+		return nil, err
+	}
+	ids, err := s.client.ZRange(ctx, key, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.sessionKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal([]byte(data), &session); err == nil {
+			sessions = append(sessions, session)
+		}
+	}
+	sortByLastSeen(sessions)
+	return sessions, nil
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, userID, id string) error {
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.sessionKey(id))
+		pipe.ZRem(ctx, s.userKey(userID), id)
+		return nil
+	})
+	return err
+}
+
+// DeleteAll implements Store
+func (s *RedisStore) DeleteAll(ctx context.Context, userID string) (int, error) {
+	key := s.userKey(userID)
+	ids, err := s.client.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	keys := []string{key}
+	for _, id := range ids {
+		keys = append(keys, s.sessionKey(id))
+	}
+	deleted, err := s.client.Del(ctx, keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+	// The user's set is one of the deleted keys when it existed
+	if len(ids) > 0 {
+		deleted--
+	}
+	return int(deleted), nil
+}
+
+func (s *RedisStore) write(ctx context.Context, session *Session) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	key := s.userKey(session.UserID)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.sessionKey(session.ID), data, ttl)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(session.ExpiresAt.Unix()), Member: session.ID})
+		// The set lives as long as the longest session in it
+		pipe.ExpireGT(ctx, key, ttl)
+		pipe.ExpireNX(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) sessionKey(id string) string {
+	return s.prefix + "session:" + id
+}
+
+func (s *RedisStore) userKey(userID string) string {
+	return s.prefix + "sessions:" + userID
+}
+
+// DBStore keeps sessions in the active_sessions table for deployments without Redis
+type DBStore struct {
+	db *database.DB
+}
+
+// NewDBStore creates a database-backed store
+func NewDBStore(db *database.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+const sessionColumns = `id, user_id, method, user_agent, ip, created_at, last_seen_at, expires_at`
+
+// Create implements Store
+func (s *DBStore) Create(ctx context.Context, session *Session) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO active_sessions (`+sessionColumns+`)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		session.ID, session.UserID, session.Method, truncate(session.UserAgent, maxUserAgent), session.IP,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store
+func (s *DBStore) Get(ctx context.Context, userID, id string) (*Session, error) {
+	var session Session
+	err := s.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM active_sessions WHERE id = $1 AND user_id = $2`, id, userID).
+		Scan(&session.ID, &session.UserID, &session.Method, &session.UserAgent, &session.IP,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	return &session, nil
+}
+
+// Update implements Store
+func (s *DBStore) Update(ctx context.Context, session *Session) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE active_sessions SET last_seen_at = $3, ip = $4, expires_at = $5
+WHERE id = $1 AND user_id = $2`,
+		session.ID, session.UserID, session.LastSeenAt, session.IP, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// List implements Store
+func (s *DBStore) List(ctx context.Context, userID string, now time.Time) ([]Session, error) {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM active_sessions WHERE user_id = $1 AND expires_at <= $2`, userID, now); err != nil {
+		return nil, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+sessionColumns+`
+FROM active_sessions
+WHERE user_id = $1
+ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Method, &session.UserAgent, &session.IP,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Delete implements Store
+func (s *DBStore) Delete(ctx context.Context, userID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM active_sessions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteAll implements Store
+func (s *DBStore) DeleteAll(ctx context.Context, userID string) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM active_sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
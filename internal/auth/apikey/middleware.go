@@ -0,0 +1,51 @@
+package apikey
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/security"
+)
+
+// Header carries the API key on machine-to-machine requests
+const Header = "X-API-Key"
+
+// Middleware authenticates requests carrying an X-API-Key header. Requests without
+// the header pass through so JWT/session authentication still applies; invalid keys get 401.
+func Middleware(store *Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		plaintext := c.Get(Header)
+		if plaintext == "" {
+			return c.Next()
+		}
+
+		key, err := store.Authenticate(c.UserContext(), plaintext, clock.Now(c))
+		if errors.Is(err, ErrInvalidKey) {
+			security.Emit(c, security.EventAPIKeyInvalid, nil)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Unauthorized",
+				"message": err.Error(),
+				"status":  fiber.StatusUnauthorized,
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":   "Service Unavailable",
+				"message": "Failed to verify API key",
+				"status":  fiber.StatusServiceUnavailable,
+			})
+		}
+
+		auth.SetPrincipal(c, &auth.Principal{
+			ID:      "apikey:" + key.ID,
+			Role:    "service",
+			Scopes:  key.Scopes,
+			Method:  auth.MethodAPIKey,
+			TokenID: key.Prefix,
+		})
+		return c.Next()
+	}
+}
@@ -0,0 +1,181 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"main.go/internal/database"
+	"main.go/internal/ids"
+)
+
+// keyPrefix marks strings as API keys so leaked keys are easy to grep for and revoke
+const keyPrefix = "fk_"
+
+// prefixLength is the length of the public lookup prefix following keyPrefix
+const prefixLength = 8
+
+// lastUsedResolution throttles last_used_at writes for busy keys
+const lastUsedResolution = time.Minute
+
+var (
+	// ErrInvalidKey is returned for malformed, unknown, revoked, or expired keys
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrKeyNotFound is returned when revoking a key that does not exist
+	ErrKeyNotFound = errors.New("API key not found")
+)
+
+// Key is a stored API key; the plaintext is only returned once, by Create
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	hash       string
+}
+
+// Store persists hashed API keys
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new API key store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const keyColumns = `id, name, prefix, key_hash, scopes, created_at, last_used_at, expires_at, revoked_at`
+
+// Create generates a key and stores its SHA-256 hash. The returned plaintext
+// ("fk_<prefix>_<secret>") cannot be recovered later.
+func (s *Store) Create(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (string, *Key, error) {
+	gen := ids.Default()
+	prefix := gen.RandomString(prefixLength)
+	plaintext := keyPrefix + prefix + "_" + gen.RandomString(32)
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+	row := s.db.QueryRowContext(ctx, `
+INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING `+keyColumns, name, prefix, hashKey(plaintext), pq.Array(scopes), expiresAt)
+
+	key, err := scanKey(row)
+	if err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+// Authenticate resolves a plaintext key presented at now and records its use
+func (s *Store) Authenticate(ctx context.Context, plaintext string, now time.Time) (*Key, error) {
+	// The prefix has a fixed length because random characters may include "_"
+	rest, found := strings.CutPrefix(plaintext, keyPrefix)
+	if !found || len(rest) <= prefixLength+1 || rest[prefixLength] != '_' {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := scanKey(s.db.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM api_keys WHERE prefix = $1`, rest[:prefixLength]))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.hash), []byte(hashKey(plaintext))) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && key.ExpiresAt.Before(now)) {
+		return nil, ErrInvalidKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, key.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+	return key, nil
+}
+
+// List returns every key, newest first
+func (s *Store) List(ctx context.Context) ([]Key, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+keyColumns+` FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []Key{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke disables a key by ID or prefix
+func (s *Store) Revoke(ctx context.Context, idOrPrefix string) error {
+	result, err := s.db.ExecContext(ctx, `
+UPDATE api_keys SET revoked_at = NOW()
+WHERE (id::text = $1 OR prefix = $1) AND revoked_at IS NULL`, idOrPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanKey(row scanner) (*Key, error) {
+	var key Key
+	var lastUsed, expires, revoked sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.hash, pq.Array(&key.Scopes),
+		&key.CreatedAt, &lastUsed, &expires, &revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key: %w", err)
+	}
+
+	key.LastUsedAt = nullTime(lastUsed)
+	key.ExpiresAt = nullTime(expires)
+	key.RevokedAt = nullTime(revoked)
+	return &key, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// hashKey returns the hex SHA-256 of a key. Keys are long and random, so a fast
+// hash is sufficient (unlike passwords).
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidCredentials is returned when a login and password do not match an active user
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// CredentialChecker verifies a login (email or username) and password
+type CredentialChecker interface {
+	CheckCredentials(ctx context.Context, login, password string) (*Principal, error)
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// currentUserKey is the c.Locals key holding the application's record for the principal
+const currentUserKey = "auth_current_user"
+
+// SetCurrentUser stores the application's user record for the authenticated caller,
+// e.g. the *users.User loaded by users.LoadCurrentUser
+func SetCurrentUser[T any](c *fiber.Ctx, user *T) {
+	c.Locals(currentUserKey, user)
+}
+
+// CurrentUser retrieves the authenticated caller as *T. T is either Principal, which
+// every authenticator populates, or the type stored with SetCurrentUser.
+func CurrentUser[T any](c *fiber.Ctx) (*T, bool) {
+	if user, ok := c.Locals(currentUserKey).(*T); ok && user != nil {
+		return user, true
+	}
+	if p, ok := PrincipalFrom(c); ok {
+		if user, ok := any(p).(*T); ok {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+// MustCurrentUser is CurrentUser for routes whose middleware guarantees the user is
+// present (Required, users.LoadCurrentUser). It panics otherwise, which the recover
+// middleware turns into a 500, so a missing middleware fails loudly instead of
+// being treated as an anonymous request.
+func MustCurrentUser[T any](c *fiber.Ctx) *T {
+	user, ok := CurrentUser[T](c)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("auth: no current user of type %T on %s %s", &zero, c.Method(), c.Path()))
+	}
+	return user
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+)
+
+// Token types carried in the "typ" claim so refresh tokens cannot be used as access tokens
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed, expired, or wrongly signed tokens
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrWrongTokenType is returned when a refresh token is presented as an access token or vice versa
+	ErrWrongTokenType = errors.New("wrong token type")
+)
+
+// Claims are the JWT claims issued by TokenService
+type Claims struct {
+	jwt.RegisteredClaims
+	Email  string   `json:"email,omitempty"`
+	Role   string   `json:"role,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	Type   string   `json:"typ"`
+	// SessionID is carried from the login through every refresh
+	SessionID string `json:"sid,omitempty"`
+}
+
+// Principal converts the claims into the request principal
+func (c *Claims) Principal() *Principal {
+	return &Principal{
+		ID:      c.Subject,
+		Email:   c.Email,
+		Role:    c.Role,
+		Scopes:  c.Scopes,
+		Method:  MethodJWT,
+		TokenID: c.ID,
+		// ExpiresAt is always set; Parse requires the exp claim
+		TokenExpiresAt: c.ExpiresAt.Time,
+		SessionID:      c.SessionID,
+	}
+}
+
+// TokenPair is returned to clients after login or refresh
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	// Token IDs (jti) for revocation tracking; not sent to clients
+	AccessTokenID  string `json:"-"`
+	RefreshTokenID string `json:"-"`
+}
+
+// TokenService issues and validates JWTs, HS256-signed with the shared secret or, when
+// a key set is configured, RS256/ES256-signed with a kid header for JWKS verification
+type TokenService struct {
+	secret     []byte
+	keys       *KeySet
+	revoked    *Revocations
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	clock      clock.Clock
+	ids        ids.Generator
+}
+
+// TokenOptions configures a TokenService
+type TokenOptions struct {
+	Secret string
+	// Keys switches to asymmetric signing with the active key; Secret is then unused
+	Keys *KeySet
+	// Revocations enables logout for stateless tokens; nil disables revocation
+	Revocations *Revocations
+	Issuer      string
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+	// Clock controls issue and expiry times; defaults to the system clock
+	Clock clock.Clock
+	// IDs generates token IDs (jti); defaults to ids.Default()
+	IDs ids.Generator
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(opts TokenOptions) *TokenService {
+	if opts.Clock == nil {
+		opts.Clock = clock.System{}
+	}
+	if opts.IDs == nil {
+		opts.IDs = ids.Default()
+	}
+	return &TokenService{
+		secret:     []byte(opts.Secret),
+		keys:       opts.Keys,
+		revoked:    opts.Revocations,
+		issuer:     opts.Issuer,
+		accessTTL:  opts.AccessTTL,
+		refreshTTL: opts.RefreshTTL,
+		clock:      opts.Clock,
+		ids:        opts.IDs,
+	}
+}
+
+// Issue creates a new access/refresh token pair for the principal
+func (s *TokenService) Issue(p *Principal) (*TokenPair, error) {
+	now := s.clock.Now()
+
+	accessID, refreshID := s.ids.NewID(), s.ids.NewID()
+
+	access, err := s.sign(p, TokenTypeAccess, accessID, now, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(p, TokenTypeRefresh, refreshID, now, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresAt:        now.Add(s.accessTTL),
+		RefreshExpiresAt: now.Add(s.refreshTTL),
+		AccessTokenID:    accessID,
+		RefreshTokenID:   refreshID,
+	}, nil
+}
+
+// Parse validates a token and checks it is of the expected type
+func (s *TokenService) Parse(token, expectedType string) (*Claims, error) {
+	claims := &Claims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, s.verificationKey,
+		jwt.WithValidMethods(s.methods()),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.clock.Now),
+	)
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Type != expectedType {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+func (s *TokenService) sign(p *Principal, tokenType, id string, now time.Time, ttl time.Duration) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   p.ID,
+			Issuer:    s.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Email:     p.Email,
+		Role:      p.Role,
+		Scopes:    p.Scopes,
+		Type:      tokenType,
+		SessionID: p.SessionID,
+	}
+
+	var signed string
+	var err error
+	if s.keys != nil {
+		key := s.keys.Active()
+		t := jwt.NewWithClaims(key.Method, claims)
+		t.Header["kid"] = key.ID
+		signed, err = t.SignedString(key.Private)
+	} else {
+		signed, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s token: %w", tokenType, err)
+	}
+	return signed, nil
+}
+
+// RefreshTTL is how long refresh tokens are valid
+func (s *TokenService) RefreshTTL() time.Duration {
+	return s.refreshTTL
+}
+
+// Revocations returns the revocation list, or nil when revocation is disabled
+func (s *TokenService) Revocations() *Revocations {
+	return s.revoked
+}
+
+// JWKS returns the public keys that verify issued tokens; empty with HS256
+func (s *TokenService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
+// verificationKey selects the key named by the token's kid header
+func (s *TokenService) verificationKey(t *jwt.Token) (interface{}, error) {
+	if s.keys == nil {
+		return s.secret, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	key, ok := s.keys.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.Method.Alg() != t.Method.Alg() {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, t.Method.Alg())
+	}
+	return key.Public(), nil
+}
+
+func (s *TokenService) methods() []string {
+	if s.keys == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return s.keys.Methods()
+}
@@ -0,0 +1,176 @@
+// Package lockout slows down password guessing. Failed logins are counted per account
+// and per client IP; crossing a limit locks that account or IP for a period that doubles
+// with each lockout in the last day.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+)
+
+// Lock scopes
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// strikeMemory is how long past lockouts count towards escalation
+const strikeMemory = 24 * time.Hour
+
+// LockedError is returned while an account or IP is locked
+type LockedError struct {
+	Scope      string
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s locked for %s", e.Scope, e.RetryAfter.Round(time.Second))
+}
+
+// AsLocked unwraps a *LockedError
+func AsLocked(err error) (*LockedError, bool) {
+	var locked *LockedError
+	ok := errors.As(err, &locked)
+	return locked, ok
+}
+
+// Options configures the thresholds and lockout durations
+type Options struct {
+	// MaxAttempts failed logins per account within Window lock the account
+	MaxAttempts int
+	// IPMaxAttempts failed logins per IP within Window lock the IP (credential stuffing)
+	IPMaxAttempts int
+	Window        time.Duration
+	// BaseLockout is the first lockout; each further lockout within a day doubles it up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Guard tracks failed logins and enforces lockouts
+type Guard struct {
+	store  Store
+	opts   Options
+	logger *logger.Logger
+}
+
+// NewGuard creates a guard, filling zero options with conservative defaults
+func NewGuard(store Store, l *logger.Logger, opts Options) *Guard {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.IPMaxAttempts <= 0 {
+		opts.IPMaxAttempts = 20
+	}
+	if opts.Window <= 0 {
+		opts.Window = 15 * time.Minute
+	}
+	if opts.BaseLockout <= 0 {
+		opts.BaseLockout = time.Minute
+	}
+	if opts.MaxLockout < opts.BaseLockout {
+		opts.MaxLockout = time.Hour
+	}
+	return &Guard{store: store, opts: opts, logger: l}
+}
+
+// Check returns a *LockedError when the account or IP is currently locked
+func (g *Guard) Check(ctx context.Context, login, ip string) error {
+	for _, t := range targets(login, ip) {
+		ttl, err := g.store.TTL(ctx, "lock:"+t.key)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			return &LockedError{Scope: t.scope, RetryAfter: ttl}
+		}
+	}
+	return nil
+}
+
+// Failure records a failed login and returns a *LockedError if it triggered a lockout
+func (g *Guard) Failure(ctx context.Context, login, ip string) error {
+	var locked *LockedError
+	for _, t := range targets(login, ip) {
+		limit := g.opts.MaxAttempts
+		if t.scope == ScopeIP {
+			limit = g.opts.IPMaxAttempts
+		}
+
+		failures, err := g.store.Incr(ctx, "fail:"+t.key, g.opts.Window)
+		if err != nil {
+			return err
+		}
+		if failures < limit {
+			continue
+		}
+
+		d, err := g.lock(ctx, t.key)
+		if err != nil {
+			return err
+		}
+		g.logger.Warn("Login locked after repeated failures",
+			zap.String("scope", t.scope),
+			zap.String("key", t.key),
+			zap.Int("failures", failures),
+			zap.Duration("duration", d),
+		)
+		if locked == nil || d > locked.RetryAfter {
+			locked = &LockedError{Scope: t.scope, RetryAfter: d}
+		}
+	}
+	if locked != nil {
+		return locked
+	}
+	return nil
+}
+
+// Success clears the account's failure count; the IP count is kept so one valid
+// login cannot reset a credential-stuffing run
+func (g *Guard) Success(ctx context.Context, login string) error {
+	return g.store.Delete(ctx, "fail:"+accountKey(login))
+}
+
+// lock locks key for an escalating duration and resets its failure count
+func (g *Guard) lock(ctx context.Context, key string) (time.Duration, error) {
+	strikes, err := g.store.Incr(ctx, "strikes:"+key, strikeMemory)
+	if err != nil {
+		return 0, err
+	}
+
+	d := g.opts.BaseLockout
+	for i := 1; i < strikes && d < g.opts.MaxLockout; i++ {
+		d *= 2
+	}
+	if d > g.opts.MaxLockout {
+		d = g.opts.MaxLockout
+	}
+
+	if err := g.store.Set(ctx, "lock:"+key, d); err != nil {
+		return 0, err
+	}
+	return d, g.store.Delete(ctx, "fail:"+key)
+}
+
+type target struct {
+	scope string
+	key   string
+}
+
+func targets(login, ip string) []target {
+	return []target{
+		{scope: ScopeAccount, key: accountKey(login)},
+		{scope: ScopeIP, key: ScopeIP + ":" + ip},
+	}
+}
+
+func accountKey(login string) string {
+	return ScopeAccount + ":" + strings.ToLower(strings.TrimSpace(login))
+}
@@ -0,0 +1,128 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"main.go/internal/database"
+)
+
+// Store holds expiring counters. Counters start at 1 with the given TTL and keep
+// their original expiry as they are incremented.
+type Store interface {
+	Incr(ctx context.Context, key string, ttl time.Duration) (int, error)
+	// Set creates or replaces key with a count of 1 expiring after ttl
+	Set(ctx context.Context, key string, ttl time.Duration) error
+	// TTL returns the time left on key, or zero when it does not exist
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// RedisStore keeps counters in Redis so limits apply across instances
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis-backed store
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client, prefix: "lockout:"}
+}
+
+// incrScript increments a counter and sets its expiry only when it is created
+var incrScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then redis.call("PEXPIRE", KEYS[1], ARGV[1]) end
+return n`)
+
+// Incr implements Store
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	return incrScript.Run(ctx, s.client, []string{s.prefix + key}, ttl.Milliseconds()).Int()
+}
+
+// Set implements Store
+func (s *RedisStore) Set(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+key, 1, ttl).Err()
+}
+
+// TTL implements Store
+func (s *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, s.prefix+key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.prefix + key
+	}
+	return s.client.Del(ctx, prefixed...).Err()
+}
+
+// DBStore keeps counters in the auth_throttle table for deployments without Redis
+type DBStore struct {
+	db *database.DB
+}
+
+// NewDBStore creates a PostgreSQL-backed store
+func NewDBStore(db *database.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// Incr implements Store; an expired row restarts at 1 with a fresh TTL
+func (s *DBStore) Incr(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+INSERT INTO auth_throttle (key, count, expires_at)
+VALUES ($1, 1, NOW() + $2 * INTERVAL '1 millisecond')
+ON CONFLICT (key) DO UPDATE SET
+    count = CASE WHEN auth_throttle.expires_at <= NOW() THEN 1 ELSE auth_throttle.count + 1 END,
+    expires_at = CASE WHEN auth_throttle.expires_at <= NOW() THEN EXCLUDED.expires_at ELSE auth_throttle.expires_at END
+RETURNING count`, key, ttl.Milliseconds()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record attempt: %w", err)
+	}
+	return count, nil
+}
+
+// Set implements Store
+func (s *DBStore) Set(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO auth_throttle (key, count, expires_at)
+VALUES ($1, 1, NOW() + $2 * INTERVAL '1 millisecond')
+ON CONFLICT (key) DO UPDATE SET count = 1, expires_at = EXCLUDED.expires_at`, key, ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to set lock: %w", err)
+	}
+	return nil
+}
+
+// TTL implements Store
+func (s *DBStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ms float64
+	err := s.db.QueryRowContext(ctx, `
+SELECT COALESCE(MAX(EXTRACT(EPOCH FROM (expires_at - NOW())) * 1000), 0)
+FROM auth_throttle
+WHERE key = $1 AND expires_at > NOW()`, key).Scan(&ms)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read lock: %w", err)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Delete implements Store; expired rows are pruned at the same time
+func (s *DBStore) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM auth_throttle WHERE key = $1 OR expires_at <= NOW()`, key); err != nil {
+			return fmt.Errorf("failed to reset attempts: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+const (
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+)
+
+// GitHub signs users in with their GitHub account
+type GitHub struct {
+	config *oauth2.Config
+}
+
+// NewGitHub creates the GitHub provider
+func NewGitHub(clientID, clientSecret, redirectURL string) *GitHub {
+	return &GitHub{config: &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     endpoints.GitHub,
+		Scopes:       []string{"read:user", "user:email"},
+	}}
+}
+
+// Name implements Provider
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// Config implements Provider
+func (g *GitHub) Config() *oauth2.Config {
+	return g.config
+}
+
+// Identity implements Provider. The public profile email may be empty or unverified,
+// so the primary verified address is read from /user/emails.
+func (g *GitHub) Identity(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	var profile struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := fetchJSON(ctx, g.config, token, githubUserURL, &profile); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider: g.Name(),
+		Subject:  strconv.FormatInt(profile.ID, 10),
+		Name:     profile.Name,
+	}
+	if identity.Name == "" {
+		identity.Name = profile.Login
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := fetchJSON(ctx, g.config, token, githubEmailsURL, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+	return identity, nil
+}
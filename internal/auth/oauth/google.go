@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+const googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+
+// Google signs users in with their Google account
+type Google struct {
+	config *oauth2.Config
+}
+
+// NewGoogle creates the Google provider
+func NewGoogle(clientID, clientSecret, redirectURL string) *Google {
+	return &Google{config: &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     endpoints.Google,
+		Scopes:       []string{"openid", "email", "profile"},
+	}}
+}
+
+// Name implements Provider
+func (g *Google) Name() string {
+	return "google"
+}
+
+// Config implements Provider
+func (g *Google) Config() *oauth2.Config {
+	return g.config
+}
+
+// Identity implements Provider using the OpenID Connect userinfo endpoint
+func (g *Google) Identity(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	var profile struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := fetchJSON(ctx, g.config, token, googleUserInfoURL, &profile); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:      g.Name(),
+		Subject:       profile.Sub,
+		Email:         profile.Email,
+		EmailVerified: profile.EmailVerified,
+		Name:          profile.Name,
+	}, nil
+}
@@ -0,0 +1,186 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+
+	"main.go/internal/clock"
+)
+
+// StateCookie holds the signed state and PKCE verifier between redirect and callback
+const StateCookie = "oauth_state"
+
+// stateTTL bounds how long a user may take on the provider's consent screen
+const stateTTL = 10 * time.Minute
+
+var (
+	// ErrInvalidState is returned when the callback state is missing, expired, or does not match
+	ErrInvalidState = errors.New("invalid or expired OAuth state")
+	// ErrAccessDenied is returned when the user declined on the provider's consent screen
+	ErrAccessDenied = errors.New("access denied by user")
+	// ErrProviderUnavailable is returned when a provider's discovery document cannot be loaded
+	ErrProviderUnavailable = errors.New("login provider unavailable")
+)
+
+// pendingLogin is the signed state cookie payload
+type pendingLogin struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
+
+// Manager runs the authorization code flow with state and PKCE for a set of providers
+type Manager struct {
+	providers map[string]Provider
+	key       []byte
+	secure    bool
+}
+
+// NewManager creates an OAuth manager. State cookies are signed with secret.
+func NewManager(secret string, secure bool, providers ...Provider) *Manager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+
+	m := &Manager{providers: map[string]Provider{}, key: key, secure: secure}
+	for _, p := range providers {
+		m.providers[p.Name()] = p
+	}
+	return m
+}
+
+// Provider returns the provider registered under name
+func (m *Manager) Provider(name string) (Provider, bool) {
+	p, ok := m.providers[name]
+	return p, ok
+}
+
+// Names returns the enabled provider names, sorted
+func (m *Manager) Names() []string {
+	names := make([]string, 0, len(m.providers))
+	for name := range m.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Begin stores a fresh state and PKCE verifier and redirects to the provider
+func (m *Manager) Begin(c *fiber.Ctx, p Provider) error {
+	if err := discover(c, p); err != nil {
+		return err
+	}
+
+	pending := pendingLogin{
+		Provider: p.Name(),
+		State:    oauth2.GenerateVerifier(),
+		Verifier: oauth2.GenerateVerifier(),
+		Expires:  clock.Now(c).Add(stateTTL).Unix(),
+	}
+
+	value, err := m.encode(pending)
+	if err != nil {
+		return err
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     StateCookie,
+		Value:    value,
+		Path:     "/auth",
+		MaxAge:   int(stateTTL.Seconds()),
+		Secure:   m.secure,
+		HTTPOnly: true,
+		// Lax keeps the cookie on the provider's top-level redirect back to us
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	url := p.Config().AuthCodeURL(pending.State, oauth2.S256ChallengeOption(pending.Verifier))
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// Complete validates the callback against the state cookie, exchanges the code,
+// and returns the provider identity. The state cookie is single use.
+func (m *Manager) Complete(c *fiber.Ctx, p Provider) (*Identity, error) {
+	pending, err := m.decode(c.Cookies(StateCookie))
+	c.Cookie(&fiber.Cookie{Name: StateCookie, Path: "/auth", MaxAge: -1, Secure: m.secure, HTTPOnly: true})
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	if c.Query("error") != "" {
+		return nil, ErrAccessDenied
+	}
+
+	state := c.Query("state")
+	if pending.Provider != p.Name() || pending.Expires < clock.Now(c).Unix() ||
+		!hmac.Equal([]byte(state), []byte(pending.State)) {
+		return nil, ErrInvalidState
+	}
+
+	if err := discover(c, p); err != nil {
+		return nil, err
+	}
+	token, err := p.Config().Exchange(c.UserContext(), c.Query("code"), oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		return nil, err
+	}
+	return p.Identity(c.UserContext(), token)
+}
+
+// discover loads the endpoints of providers that publish them at runtime
+func discover(c *fiber.Ctx, p Provider) error {
+	d, ok := p.(Discoverer)
+	if !ok {
+		return nil
+	}
+	if err := d.Discover(c.UserContext()); err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	return nil
+}
+
+func (m *Manager) encode(pending pendingLogin) (string, error) {
+	payload, err := json.Marshal(pending)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + m.sign(body), nil
+}
+
+func (m *Manager) decode(value string) (*pendingLogin, error) {
+	body, signature, found := strings.Cut(value, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(m.sign(body))) {
+		return nil, ErrInvalidState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	var pending pendingLogin
+	if err := json.Unmarshal(payload, &pending); err != nil {
+		return nil, ErrInvalidState
+	}
+	return &pending, nil
+}
+
+func (m *Manager) sign(body string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
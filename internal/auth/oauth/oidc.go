@@ -0,0 +1,268 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+
+	"main.go/internal/auth"
+)
+
+// keysRefreshInterval throttles JWKS reloads triggered by unknown key IDs
+const keysRefreshInterval = time.Minute
+
+// ErrIDTokenInvalid is returned when the ID token is missing or fails verification
+var ErrIDTokenInvalid = errors.New("invalid OIDC ID token")
+
+// OIDCOptions configures a generic OpenID Connect provider (Keycloak, Auth0, Okta, ...)
+type OIDCOptions struct {
+	// Name is the URL segment in /auth/{name} routes; defaults to "oidc"
+	Name   string
+	Issuer string
+	// ClientSecret may be empty for public clients; logins always use PKCE
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes defaults to openid, email, and profile
+	Scopes []string
+	// EmailClaim and NameClaim map providers that use non-standard claim names;
+	// they default to "email" and "name"
+	EmailClaim string
+	NameClaim  string
+	// Client defaults to an http.Client with a 10 second timeout
+	Client *http.Client
+}
+
+// discoveryDocument is the subset of /.well-known/openid-configuration the provider uses
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDC signs users in with any OpenID Connect provider. Endpoints are read from the
+// issuer's discovery document on first use, and ID tokens are verified against the
+// issuer's published keys.
+type OIDC struct {
+	opts   OIDCOptions
+	config *oauth2.Config
+
+	mu          sync.Mutex
+	discovery   *discoveryDocument
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDC creates a generic OIDC provider; discovery happens on the first login
+func NewOIDC(opts OIDCOptions) *OIDC {
+	if opts.Name == "" {
+		opts.Name = "oidc"
+	}
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = []string{"openid", "email", "profile"}
+	}
+	if opts.EmailClaim == "" {
+		opts.EmailClaim = "email"
+	}
+	if opts.NameClaim == "" {
+		opts.NameClaim = "name"
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	opts.Issuer = strings.TrimRight(opts.Issuer, "/")
+
+	return &OIDC{opts: opts, config: &oauth2.Config{
+		ClientID:     opts.ClientID,
+		ClientSecret: opts.ClientSecret,
+		RedirectURL:  opts.RedirectURL,
+		Scopes:       opts.Scopes,
+	}}
+}
+
+// Name implements Provider
+func (o *OIDC) Name() string {
+	return o.opts.Name
+}
+
+// Config implements Provider. Its endpoint is empty until Discover succeeds.
+func (o *OIDC) Config() *oauth2.Config {
+	return o.config
+}
+
+// Discover implements Discoverer. A failed discovery is retried on the next login.
+func (o *OIDC) Discover(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return nil
+	}
+
+	var doc discoveryDocument
+	if err := o.getJSON(ctx, o.opts.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return err
+	}
+	// Auth0 publishes its issuer with a trailing slash, so compare without it
+	if strings.TrimRight(doc.Issuer, "/") != o.opts.Issuer {
+		return fmt.Errorf("OIDC discovery issuer %q does not match OIDC_ISSUER %q", doc.Issuer, o.opts.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return fmt.Errorf("OIDC discovery document for %s is incomplete", o.opts.Issuer)
+	}
+
+	o.config.Endpoint = oauth2.Endpoint{AuthURL: doc.AuthorizationEndpoint, TokenURL: doc.TokenEndpoint}
+	o.discovery = &doc
+	return nil
+}
+
+// Identity implements Provider by verifying the ID token and mapping its claims. The
+// userinfo endpoint fills in an email the ID token does not carry.
+func (o *OIDC) Identity(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	raw, _ := token.Extra("id_token").(string)
+	if raw == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrIDTokenInvalid)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, o.keyFunc(ctx),
+		jwt.WithIssuer(o.discovery.Issuer),
+		jwt.WithAudience(o.opts.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalid, err)
+	}
+
+	if stringClaim(claims, o.opts.EmailClaim) == "" && o.discovery.UserInfoEndpoint != "" {
+		userinfo := map[string]interface{}{}
+		if err := fetchJSON(ctx, o.config, token, o.discovery.UserInfoEndpoint, &userinfo); err != nil {
+			return nil, err
+		}
+		// The ID token stays authoritative for the subject
+		if userinfo["sub"] != claims["sub"] {
+			return nil, fmt.Errorf("%w: userinfo subject does not match", ErrIDTokenInvalid)
+		}
+		for k, v := range userinfo {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	subject := stringClaim(claims, "sub")
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrIDTokenInvalid)
+	}
+	return &Identity{
+		Provider:      o.Name(),
+		Subject:       subject,
+		Email:         stringClaim(claims, o.opts.EmailClaim),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Name:          stringClaim(claims, o.opts.NameClaim),
+	}, nil
+}
+
+// keyFunc resolves the ID token's signing key, reloading the JWKS when the provider
+// has rotated to a key ID not seen yet
+func (o *OIDC) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if key, ok := o.lookupKey(kid); ok {
+			return key, nil
+		}
+		if time.Since(o.keysFetched) < keysRefreshInterval {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if err := o.loadKeys(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := o.lookupKey(kid); ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+}
+
+// lookupKey finds a key by ID; tokens without a kid match a single published key
+func (o *OIDC) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+// loadKeys replaces the cached signing keys; keys that are not for signatures or fail
+// to decode are skipped. Callers hold o.mu.
+func (o *OIDC) loadKeys(ctx context.Context) error {
+	var doc auth.JWKS
+	if err := o.getJSON(ctx, o.discovery.JWKSURI, &doc); err != nil {
+		return err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	o.keys = keys
+	o.keysFetched = time.Now()
+	return nil
+}
+
+// getJSON fetches an unauthenticated provider document
+func (o *OIDC) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim accepts true and "true"; some providers send email_verified as a string
+func boolClaim(claims jwt.MapClaims, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
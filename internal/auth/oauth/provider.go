@@ -0,0 +1,55 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// Identity is the profile a provider returns for the logged-in account
+type Identity struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// Provider is an OAuth2 identity provider
+type Provider interface {
+	// Name is the URL segment used in /auth/{provider} routes
+	Name() string
+	// Config returns the OAuth2 client configuration
+	Config() *oauth2.Config
+	// Identity fetches the account profile with an exchanged token
+	Identity(ctx context.Context, token *oauth2.Token) (*Identity, error)
+}
+
+// Discoverer is implemented by providers that load their endpoints at runtime (OIDC).
+// The manager calls Discover before every redirect and code exchange.
+type Discoverer interface {
+	Discover(ctx context.Context) error
+}
+
+// fetchJSON GETs url with the token's authorized client and decodes the JSON body into v
+func fetchJSON(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := cfg.Client(ctx, token).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/database"
+	"main.go/internal/security"
+)
+
+// Authentication methods recorded on a Principal
+const (
+	MethodJWT     = "jwt"
+	MethodSession = "session"
+	MethodAPIKey  = "api_key"
+)
+
+// principalKey is the c.Locals key holding the authenticated *Principal
+const principalKey = "auth_principal"
+
+// Principal is the authenticated caller, regardless of how they authenticated
+type Principal struct {
+	ID      string   `json:"id"`
+	Email   string   `json:"email,omitempty"`
+	Role    string   `json:"role,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	Method  string   `json:"method"`
+	TokenID string   `json:"-"`
+	// TokenExpiresAt is when the JWT expires; zero for other methods
+	TokenExpiresAt time.Time `json:"-"`
+	// SessionID identifies the login this request belongs to (a session, or every token
+	// pair refreshed from one login) when active sessions are tracked
+	SessionID string `json:"-"`
+}
+
+// IsMachine reports whether the principal is a service client rather than a user
+func (p *Principal) IsMachine() bool {
+	return p.Method == MethodAPIKey
+}
+
+// HasScope reports whether the principal was granted scope ("*" grants every scope).
+// Users are also granted the scopes of their role (see SetRoleScopes); machine clients
+// only have the scopes on their key.
+func (p *Principal) HasScope(scope string) bool {
+	if grants(p.Scopes, scope) {
+		return true
+	}
+	return !p.IsMachine() && grants(RoleScopes(p.Role), scope)
+}
+
+func grants(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPrincipal stores the authenticated principal on the request. A user also becomes
+// the owner of c.UserContext(), which database.Owned queries are scoped to.
+func SetPrincipal(c *fiber.Ctx, p *Principal) {
+	c.Locals(principalKey, p)
+	if !p.IsMachine() {
+		c.Locals("user_id", p.ID)
+		setOwner(c, p.ID)
+	}
+}
+
+// ClearPrincipal makes the rest of the request anonymous, e.g. after its session is found revoked
+func ClearPrincipal(c *fiber.Ctx) {
+	c.Locals(principalKey, nil)
+	c.Locals("user_id", nil)
+	setOwner(c, "")
+}
+
+// setOwner makes userID the owner of c.UserContext(), keeping the scope's tenant
+func setOwner(c *fiber.Ctx, userID string) {
+	ctx := c.UserContext()
+	scope, _ := database.ScopeFromContext(ctx)
+	scope.UserID = userID
+	c.SetUserContext(database.ContextWithScope(ctx, scope))
+}
+
+// PrincipalFrom returns the authenticated principal, if any
+func PrincipalFrom(c *fiber.Ctx) (*Principal, bool) {
+	p, ok := c.Locals(principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// UserID returns the authenticated user's ID, or an empty string for anonymous
+// requests and machine clients (API keys are not users)
+func UserID(c *fiber.Ctx) string {
+	if p, ok := PrincipalFrom(c); ok && !p.IsMachine() {
+		return p.ID
+	}
+	return ""
+}
+
+// Required rejects requests that no authenticator populated a principal for
+func Required() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := PrincipalFrom(c); !ok {
+			security.Emit(c, security.EventPermissionDenied, map[string]string{"reason": "authentication required"})
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Unauthorized",
+				"message": "Authentication required",
+				"status":  fiber.StatusUnauthorized,
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,139 @@
+// Package remember implements remember-me logins for session auth. A long-lived cookie,
+// separate from the session cookie, holds a server-tracked token bound to the device it
+// was issued to. When the session expires, the token silently starts a new one. Users
+// can list their remembered devices and revoke them one by one.
+package remember
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/security"
+)
+
+// Cookie is the cookie holding the remember-me token
+const Cookie = "remember_me"
+
+// LoadPrincipal resolves a remembered user ID to a principal, so role changes and deleted
+// accounts take effect on the next remembered login
+type LoadPrincipal func(ctx context.Context, userID string) (*auth.Principal, error)
+
+// Options configures the remember-me cookie
+type Options struct {
+	Expire   time.Duration
+	SameSite string
+	Secure   bool
+}
+
+// Manager issues, verifies, and revokes remember-me cookies
+type Manager struct {
+	store    *Store
+	sessions *auth.Sessions
+	load     LoadPrincipal
+	opts     Options
+}
+
+// NewManager creates a remember-me manager that restores sessions through sessions
+func NewManager(store *Store, sessions *auth.Sessions, load LoadPrincipal, opts Options) *Manager {
+	return &Manager{store: store, sessions: sessions, load: load, opts: opts}
+}
+
+// Store returns the token store, for listing and revoking devices
+func (m *Manager) Store() *Store {
+	return m.store
+}
+
+// Middleware logs requests without a session back in from a valid remember-me cookie.
+// Register it after the session middleware. Invalid tokens clear the cookie; a token
+// presented from another device is revoked and recorded as a security event.
+func (m *Manager) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := auth.PrincipalFrom(c); ok {
+			return c.Next()
+		}
+		token := c.Cookies(Cookie)
+		if token == "" {
+			return c.Next()
+		}
+
+		ctx := c.UserContext()
+		userID, err := m.store.Consume(ctx, token, client(c), clock.Now(c))
+		switch {
+		case errors.Is(err, ErrDeviceMismatch):
+			security.Emit(c, security.EventRememberMismatch, nil)
+			m.clearCookie(c)
+			return c.Next()
+		case errors.Is(err, ErrInvalidToken):
+			m.clearCookie(c)
+			return c.Next()
+		case err != nil:
+			return c.Next()
+		}
+
+		principal, err := m.load(ctx, userID)
+		if err != nil {
+			return c.Next()
+		}
+		// Login sets the principal for the rest of this request
+		_ = m.sessions.Login(c, principal)
+		return c.Next()
+	}
+}
+
+// Remember issues a token for the principal on the current device and sets the cookie
+func (m *Manager) Remember(c *fiber.Ctx, principal *auth.Principal) error {
+	expiresAt := clock.Now(c).Add(m.opts.Expire)
+	token, err := m.store.Issue(c.UserContext(), principal.ID, client(c), expiresAt)
+	if err != nil {
+		return err
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     Cookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		Secure:   m.opts.Secure,
+		HTTPOnly: true,
+		SameSite: m.opts.SameSite,
+	})
+	return nil
+}
+
+// Forget revokes the current device's token and clears the cookie
+func (m *Manager) Forget(c *fiber.Ctx) error {
+	token := c.Cookies(Cookie)
+	if token == "" {
+		return nil
+	}
+	m.clearCookie(c)
+	return m.store.RevokeToken(c.UserContext(), token)
+}
+
+// CurrentDevice returns the ID of the requesting device, or "" if it is not remembered
+func (m *Manager) CurrentDevice(c *fiber.Ctx) (string, error) {
+	token := c.Cookies(Cookie)
+	if token == "" {
+		return "", nil
+	}
+	return m.store.DeviceID(c.UserContext(), token)
+}
+
+func (m *Manager) clearCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     Cookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   m.opts.Secure,
+		HTTPOnly: true,
+		SameSite: m.opts.SameSite,
+	})
+}
+
+func client(c *fiber.Ctx) Client {
+	return Client{UserAgent: c.Get(fiber.HeaderUserAgent), IP: c.IP()}
+}
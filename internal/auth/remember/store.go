@@ -0,0 +1,230 @@
+package remember
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main.go/internal/database"
+	"main.go/internal/ids"
+)
+
+// selectorLength is the length of the public lookup half of a token
+const selectorLength = 16
+
+// maxUserAgent bounds the stored user agent to the column size
+const maxUserAgent = 512
+
+var (
+	// ErrInvalidToken is returned for malformed, unknown, tampered, or expired tokens
+	ErrInvalidToken = errors.New("invalid remember-me token")
+	// ErrDeviceMismatch is returned when a token is presented from a different device than
+	// the one it was issued to; the token is revoked as likely stolen
+	ErrDeviceMismatch = errors.New("remember-me token presented from another device")
+	// ErrDeviceNotFound is returned when revoking a device that does not exist
+	ErrDeviceNotFound = errors.New("remembered device not found")
+)
+
+// Device is a remembered login on one browser; the token itself is never returned
+type Device struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current marks the device making the request
+	Current bool `json:"current"`
+}
+
+// Client identifies the browser a token is issued to or presented from
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Store persists hashed remember-me tokens
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new remember-me token store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Issue creates a token for userID on client. The returned plaintext
+// ("<selector>.<validator>") cannot be recovered later.
+func (s *Store) Issue(ctx context.Context, userID string, client Client, expiresAt time.Time) (string, error) {
+	gen := ids.Default()
+	selector := gen.RandomString(selectorLength)
+	plaintext := selector + "." + gen.RandomString(32)
+
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO remember_tokens (user_id, selector, token_hash, fingerprint, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		userID, selector, hashToken(plaintext), fingerprint(client.UserAgent), truncate(client.UserAgent, maxUserAgent), client.IP, expiresAt)
+	if err != nil {
+		return "", fmt.Errorf("failed to store remember-me token: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Consume verifies a token presented by client at now and returns its user ID. Expired,
+// tampered, and mismatched tokens are deleted.
+func (s *Store) Consume(ctx context.Context, plaintext string, client Client, now time.Time) (string, error) {
+	selector, ok := parseSelector(plaintext)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+
+	var id, userID, hash, print string
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+SELECT id, user_id, token_hash, fingerprint, expires_at
+FROM remember_tokens
+WHERE selector = $1`, selector).Scan(&id, &userID, &hash, &print, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load remember-me token: %w", err)
+	}
+
+	switch {
+	case subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(plaintext))) != 1, !now.Before(expiresAt):
+		return "", s.reject(ctx, id, ErrInvalidToken)
+	case subtle.ConstantTimeCompare([]byte(print), []byte(fingerprint(client.UserAgent))) != 1:
+		return "", s.reject(ctx, id, ErrDeviceMismatch)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE remember_tokens SET last_used_at = NOW(), ip = $2 WHERE id = $1`, id, client.IP); err != nil {
+		return "", fmt.Errorf("failed to record remember-me token use: %w", err)
+	}
+	return userID, nil
+}
+
+// reject deletes a token that failed verification and returns reason
+func (s *Store) reject(ctx context.Context, id string, reason error) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM remember_tokens WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete remember-me token: %w", err)
+	}
+	return reason
+}
+
+// List returns the user's unexpired devices, most recently used first
+func (s *Store) List(ctx context.Context, userID string, now time.Time) ([]Device, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, user_agent, ip, created_at, last_used_at, expires_at
+FROM remember_tokens
+WHERE user_id = $1 AND expires_at > $2
+ORDER BY last_used_at DESC`, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remembered devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []Device{}
+	for rows.Next() {
+		var d Device
+		if err := rows.Scan(&d.ID, &d.UserAgent, &d.IP, &d.CreatedAt, &d.LastUsedAt, &d.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan remembered device: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// DeviceID returns the ID of the device holding plaintext, or "" if it is unknown
+func (s *Store) DeviceID(ctx context.Context, plaintext string) (string, error) {
+	selector, ok := parseSelector(plaintext)
+	if !ok {
+		return "", nil
+	}
+	var id string
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM remember_tokens WHERE selector = $1`, selector).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load remember-me token: %w", err)
+	}
+	return id, nil
+}
+
+// Revoke forgets one of the user's devices
+func (s *Store) Revoke(ctx context.Context, userID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM remember_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke remembered device: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// RevokeToken forgets the device holding plaintext; unknown tokens are ignored
+func (s *Store) RevokeToken(ctx context.Context, plaintext string) error {
+	selector, ok := parseSelector(plaintext)
+	if !ok {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM remember_tokens WHERE selector = $1`, selector); err != nil {
+		return fmt.Errorf("failed to revoke remember-me token: %w", err)
+	}
+	return nil
+}
+
+// RevokeAll forgets every device of the user and returns how many there were
+func (s *Store) RevokeAll(ctx context.Context, userID string) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM remember_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke remembered devices: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// parseSelector returns the lookup half of a token. The selector has a fixed length
+// because random characters may include ".".
+func parseSelector(plaintext string) (string, bool) {
+	if len(plaintext) <= selectorLength+1 || plaintext[selectorLength] != '.' {
+		return "", false
+	}
+	return plaintext[:selectorLength], true
+}
+
+// fingerprint identifies a device by its user agent with version numbers removed, so
+// browser updates keep the device remembered but a token copied to another browser or
+// OS does not verify
+func fingerprint(userAgent string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == '_' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(userAgent))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashToken returns the hex SHA-256 of a token. Tokens are long and random, so a fast
+// hash is sufficient (unlike passwords).
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"main.go/internal/clock"
+)
+
+// ErrTokenRevoked is returned for tokens revoked by logout
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Revocations keeps revoked token IDs (jti) in Redis until the tokens would have expired
+// anyway. Issued token IDs are also tracked per user so every token can be revoked at once.
+// A nil *Revocations disables revocation: nothing is tracked and no token is revoked.
+type Revocations struct {
+	client *redis.Client
+	clock  clock.Clock
+	prefix string
+}
+
+// NewRevocations creates a Redis-backed revocation list
+func NewRevocations(client *redis.Client, clk clock.Clock) *Revocations {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Revocations{client: client, clock: clk, prefix: "auth:"}
+}
+
+// Enabled reports whether tokens can be revoked
+func (r *Revocations) Enabled() bool {
+	return r != nil
+}
+
+// Track records the tokens in pair as issued to subject
+func (r *Revocations) Track(ctx context.Context, subject string, pair *TokenPair) error {
+	if r == nil {
+		return nil
+	}
+	key := r.userKey(subject)
+	now := r.clock.Now()
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key,
+			redis.Z{Score: float64(pair.ExpiresAt.Unix()), Member: pair.AccessTokenID},
+			redis.Z{Score: float64(pair.RefreshExpiresAt.Unix()), Member: pair.RefreshTokenID},
+		)
+		// Drop entries for tokens that have expired on their own
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+		pipe.ExpireAt(ctx, key, latest(pair.ExpiresAt, pair.RefreshExpiresAt))
+		return nil
+	})
+	return err
+}
+
+// Revoke revokes a single token until expiresAt. It reports false when the token was
+// already revoked, which lets refresh-token rotation detect a replayed token.
+func (r *Revocations) Revoke(ctx context.Context, subject, jti string, expiresAt time.Time) (bool, error) {
+	if r == nil || jti == "" {
+		return true, nil
+	}
+	ttl := expiresAt.Sub(r.clock.Now())
+	if ttl <= 0 {
+		return true, nil
+	}
+
+	revoked, err := r.client.SetNX(ctx, r.revokedKey(jti), 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+	if err := r.client.ZRem(ctx, r.userKey(subject), jti).Err(); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// RevokeAll revokes every unexpired token issued to subject and returns how many were revoked
+func (r *Revocations) RevokeAll(ctx context.Context, subject string) (int, error) {
+	if r == nil {
+		return 0, nil
+	}
+	key := r.userKey(subject)
+	now := r.clock.Now()
+
+	issued, err := r.client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, token := range issued {
+			expiresAt := time.Unix(int64(token.Score), 0)
+			pipe.Set(ctx, r.revokedKey(token.Member.(string)), 1, expiresAt.Sub(now))
+		}
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(issued), nil
+}
+
+// IsRevoked reports whether the token ID has been revoked
+func (r *Revocations) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+	n, err := r.client.Exists(ctx, r.revokedKey(jti)).Result()
+	return n > 0, err
+}
+
+func (r *Revocations) revokedKey(jti string) string {
+	return r.prefix + "revoked:" + jti
+}
+
+func (r *Revocations) userKey(subject string) string {
+	return r.prefix + "tokens:" + subject
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
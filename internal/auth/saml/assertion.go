@@ -0,0 +1,194 @@
+package saml
+
+import (
+	"crypto/hmac"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// response is a samlp:Response, parsed from verified bytes only
+type response struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol Response"`
+	ID           string   `xml:"ID,attr"`
+	InResponseTo string   `xml:"InResponseTo,attr"`
+	Destination  string   `xml:"Destination,attr"`
+	Issuer       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Status       struct {
+		StatusCode struct {
+			Value      string `xml:"Value,attr"`
+			StatusCode *struct {
+				Value string `xml:"Value,attr"`
+			} `xml:"urn:oasis:names:tc:SAML:2.0:protocol StatusCode"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:protocol StatusCode"`
+		StatusMessage string `xml:"urn:oasis:names:tc:SAML:2.0:protocol StatusMessage"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:protocol Status"`
+	Assertion *assertion `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+}
+
+// assertion is a saml:Assertion with the statements the service provider reads
+type assertion struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	ID      string   `xml:"ID,attr"`
+	Issuer  string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Subject struct {
+		NameID struct {
+			Format string `xml:"Format,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+		Confirmations []struct {
+			Method string `xml:"Method,attr"`
+			Data   struct {
+				Recipient    string `xml:"Recipient,attr"`
+				InResponseTo string `xml:"InResponseTo,attr"`
+				NotOnOrAfter string `xml:"NotOnOrAfter,attr"`
+			} `xml:"urn:oasis:names:tc:SAML:2.0:assertion SubjectConfirmationData"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:assertion SubjectConfirmation"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Subject"`
+	Conditions struct {
+		NotBefore            string `xml:"NotBefore,attr"`
+		NotOnOrAfter         string `xml:"NotOnOrAfter,attr"`
+		AudienceRestrictions []struct {
+			Audiences []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion Audience"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:assertion AudienceRestriction"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion Conditions"`
+	Attributes []struct {
+		Name         string   `xml:"Name,attr"`
+		FriendlyName string   `xml:"FriendlyName,attr"`
+		Values       []string `xml:"urn:oasis:names:tc:SAML:2.0:assertion AttributeValue"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:assertion AttributeStatement>Attribute"`
+}
+
+// readResponse verifies a decoded SAMLResponse and returns its contents. Either the
+// response or its assertion must be signed by the IdP; everything returned is parsed
+// from the signed bytes, so content outside the signature (wrapping attacks) is ignored.
+func readResponse(data []byte, idp *IdentityProvider) (*response, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if !root.is(nsProtocol, "Response") {
+		return nil, fmt.Errorf("%w: not a SAML Response", ErrInvalidResponse)
+	}
+	if len(root.childElements(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, ErrEncryptedAssertion
+	}
+
+	signedResponse, err := verify(root, idp.Certificates)
+	if err != nil && !errors.Is(err, errNotSigned) {
+		return nil, fmt.Errorf("%w: response %v", ErrInvalidSignature, err)
+	}
+	var resp response
+	if signedResponse != nil {
+		err = xml.Unmarshal(signedResponse, &resp)
+	} else {
+		err = xml.Unmarshal(canonicalize(root, nil, nil), &resp)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	// A failed login carries no assertion; report the status before looking for one
+	if resp.Status.StatusCode.Value != statusSuccess {
+		return &resp, nil
+	}
+
+	assertions := root.childElements(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("%w: expected one assertion, got %d", ErrInvalidResponse, len(assertions))
+	}
+	signedAssertion, err := verify(assertions[0], idp.Certificates)
+	switch {
+	case err == nil:
+		var a assertion
+		if err := xml.Unmarshal(signedAssertion, &a); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+		}
+		resp.Assertion = &a
+	case !errors.Is(err, errNotSigned):
+		return nil, fmt.Errorf("%w: assertion %v", ErrInvalidSignature, err)
+	case signedResponse == nil:
+		return nil, fmt.Errorf("%w: neither the response nor the assertion is signed", ErrInvalidSignature)
+	}
+	return &resp, nil
+}
+
+// validate checks the response against the pending request and the current time
+func (sp *ServiceProvider) validate(resp *response, requestID string, now time.Time) error {
+	if resp.Status.StatusCode.Value != statusSuccess {
+		detail := resp.Status.StatusMessage
+		if detail == "" && resp.Status.StatusCode.StatusCode != nil {
+			detail = resp.Status.StatusCode.StatusCode.Value
+		}
+		return fmt.Errorf("%w: %s", ErrLoginFailed, strings.TrimSpace(detail))
+	}
+	if resp.Destination != "" && resp.Destination != sp.opts.ACSURL {
+		return fmt.Errorf("%w: wrong destination %q", ErrInvalidResponse, resp.Destination)
+	}
+	if resp.Issuer != "" && strings.TrimSpace(resp.Issuer) != sp.idp.EntityID {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidResponse, resp.Issuer)
+	}
+	if resp.InResponseTo != "" && !hmac.Equal([]byte(resp.InResponseTo), []byte(requestID)) {
+		return fmt.Errorf("%w: response is for another request", ErrInvalidResponse)
+	}
+
+	a := resp.Assertion
+	if a == nil {
+		return fmt.Errorf("%w: missing assertion", ErrInvalidResponse)
+	}
+	if strings.TrimSpace(a.Issuer) != sp.idp.EntityID {
+		return fmt.Errorf("%w: unexpected assertion issuer %q", ErrInvalidResponse, a.Issuer)
+	}
+	if strings.TrimSpace(a.Subject.NameID.Value) == "" {
+		return fmt.Errorf("%w: missing NameID", ErrInvalidResponse)
+	}
+
+	skew := sp.opts.ClockSkew
+	if notBefore, ok := parseTime(a.Conditions.NotBefore); ok && now.Add(skew).Before(notBefore) {
+		return fmt.Errorf("%w: assertion is not yet valid", ErrInvalidResponse)
+	}
+	if notOnOrAfter, ok := parseTime(a.Conditions.NotOnOrAfter); ok && !now.Add(-skew).Before(notOnOrAfter) {
+		return fmt.Errorf("%w: assertion has expired", ErrInvalidResponse)
+	}
+	if len(a.Conditions.AudienceRestrictions) == 0 {
+		return fmt.Errorf("%w: assertion has no audience restriction", ErrInvalidResponse)
+	}
+	for _, restriction := range a.Conditions.AudienceRestrictions {
+		if !contains(restriction.Audiences, sp.opts.EntityID) {
+			return fmt.Errorf("%w: assertion is for another audience", ErrInvalidResponse)
+		}
+	}
+
+	// Web browser SSO requires a bearer confirmation for this request, sent to us
+	for _, confirmation := range a.Subject.Confirmations {
+		data := confirmation.Data
+		notOnOrAfter, ok := parseTime(data.NotOnOrAfter)
+		if confirmation.Method == methodBearer && data.Recipient == sp.opts.ACSURL &&
+			ok && now.Add(-skew).Before(notOnOrAfter) &&
+			hmac.Equal([]byte(data.InResponseTo), []byte(requestID)) {
+			return sp.replays.use(a.ID, notOnOrAfter.Add(skew), now)
+		}
+	}
+	return fmt.Errorf("%w: no valid bearer subject confirmation", ErrInvalidResponse)
+}
+
+// parseTime parses an xs:dateTime; SAML requires UTC
+func parseTime(value string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+	return t, err == nil
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == want {
+			return true
+		}
+	}
+	return false
+}
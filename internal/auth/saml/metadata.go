@@ -0,0 +1,177 @@
+package saml
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Bindings and formats used in metadata and requests
+const (
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	nameIDEmail     = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	nameIDTransient = "urn:oasis:names:tc:SAML:2.0:nameid-format:transient"
+)
+
+// maxMetadataSize bounds metadata documents fetched from a URL
+const maxMetadataSize = 1 << 20
+
+// IdentityProvider is what the service provider needs from the IdP's metadata
+type IdentityProvider struct {
+	EntityID string
+	// SSOURL receives authentication requests over the HTTP-Redirect binding
+	SSOURL string
+	// Certificates verify assertion signatures; several are listed during key rollover
+	Certificates []*x509.Certificate
+}
+
+// LoadIdentityProvider reads IdP metadata from an https:// URL or a file path. Metadata
+// is trusted as given, so fetch it over HTTPS or from a file you control.
+func LoadIdentityProvider(ctx context.Context, source string) (*IdentityProvider, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		data, err = fetchMetadata(ctx, source)
+	} else {
+		data, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IdP metadata: %w", err)
+	}
+	return ParseIdentityProvider(data)
+}
+
+func fetchMetadata(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+}
+
+type entityDescriptor struct {
+	EntityID string `xml:"entityID,attr"`
+	IDPSSO   *struct {
+		KeyDescriptors []struct {
+			Use          string   `xml:"use,attr"`
+			Certificates []string `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo>X509Data>X509Certificate"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata KeyDescriptor"`
+		SingleSignOnServices []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata SingleSignOnService"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
+}
+
+// ParseIdentityProvider reads an EntityDescriptor, or the first IdP in an EntitiesDescriptor
+func ParseIdentityProvider(data []byte) (*IdentityProvider, error) {
+	var doc struct {
+		XMLName xml.Name
+		entityDescriptor
+		Descriptors []entityDescriptor `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid IdP metadata: %w", err)
+	}
+
+	var ed *entityDescriptor
+	switch doc.XMLName {
+	case xml.Name{Space: nsMetadata, Local: "EntityDescriptor"}:
+		ed = &doc.entityDescriptor
+	case xml.Name{Space: nsMetadata, Local: "EntitiesDescriptor"}:
+		for i := range doc.Descriptors {
+			if doc.Descriptors[i].IDPSSO != nil {
+				ed = &doc.Descriptors[i]
+				break
+			}
+		}
+	}
+	if ed == nil || ed.IDPSSO == nil {
+		return nil, errors.New("invalid IdP metadata: no IDPSSODescriptor")
+	}
+
+	idp := &IdentityProvider{EntityID: ed.EntityID}
+	for _, sso := range ed.IDPSSO.SingleSignOnServices {
+		if sso.Binding == bindingRedirect {
+			idp.SSOURL = sso.Location
+			break
+		}
+	}
+	for _, kd := range ed.IDPSSO.KeyDescriptors {
+		if kd.Use != "" && kd.Use != "signing" {
+			continue
+		}
+		for _, encoded := range kd.Certificates {
+			der, err := decodeBase64(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IdP certificate: %w", err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IdP certificate: %w", err)
+			}
+			idp.Certificates = append(idp.Certificates, cert)
+		}
+	}
+
+	switch {
+	case idp.EntityID == "":
+		return nil, errors.New("invalid IdP metadata: missing entityID")
+	case idp.SSOURL == "":
+		return nil, errors.New("invalid IdP metadata: no HTTP-Redirect SingleSignOnService")
+	case len(idp.Certificates) == 0:
+		return nil, errors.New("invalid IdP metadata: no signing certificate")
+	}
+	return idp, nil
+}
+
+// spMetadata is the service provider's EntityDescriptor
+type spMetadata struct {
+	XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID string   `xml:"entityID,attr"`
+	SPSSO    struct {
+		AuthnRequestsSigned        bool     `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool     `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string   `xml:"protocolSupportEnumeration,attr"`
+		NameIDFormats              []string `xml:"NameIDFormat"`
+		AssertionConsumerService   struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+			Index    int    `xml:"index,attr"`
+		}
+	} `xml:"SPSSODescriptor"`
+}
+
+func (sp *ServiceProvider) metadata() ([]byte, error) {
+	var m spMetadata
+	m.EntityID = sp.opts.EntityID
+	m.SPSSO.WantAssertionsSigned = true
+	m.SPSSO.ProtocolSupportEnumeration = nsProtocol
+	m.SPSSO.NameIDFormats = []string{nameIDEmail, "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"}
+	m.SPSSO.AssertionConsumerService.Binding = bindingPOST
+	m.SPSSO.AssertionConsumerService.Location = sp.opts.ACSURL
+	body, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,307 @@
+// Package saml implements a SAML 2.0 service provider for enterprise single sign-on
+// (AUTH=saml). Logins are SP-initiated: /saml/login redirects to the identity provider
+// with an AuthnRequest (HTTP-Redirect binding) and the IdP posts a signed response back
+// to /saml/acs (HTTP-POST binding). The IdP is described by its metadata, and the
+// service provider publishes its own at /saml/metadata.
+//
+// Responses must be signed with a certificate from the IdP metadata, on the response,
+// the assertion, or both. Encrypted assertions, signed AuthnRequests, IdP-initiated
+// logins, and single logout are not supported.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+)
+
+// RequestCookie holds the signed ID of the pending AuthnRequest until the IdP posts back
+const RequestCookie = "saml_request"
+
+// requestTTL bounds how long a user may take to sign in at the IdP
+const requestTTL = 10 * time.Minute
+
+// maxResponseSize bounds a decoded SAMLResponse
+const maxResponseSize = 256 << 10
+
+var (
+	// ErrInvalidState is returned when the pending request cookie is missing, expired, or tampered with
+	ErrInvalidState = errors.New("SAML login request expired or was not started here")
+	// ErrInvalidResponse is returned for malformed responses and failed checks on their contents
+	ErrInvalidResponse = errors.New("invalid SAML response")
+	// ErrInvalidSignature is returned when a response is unsigned or its signature does not verify
+	ErrInvalidSignature = errors.New("invalid SAML signature")
+	// ErrEncryptedAssertion is returned for encrypted assertions, which are not supported
+	ErrEncryptedAssertion = errors.New("encrypted SAML assertions are not supported")
+	// ErrReplayed is returned when an assertion is presented a second time
+	ErrReplayed = errors.New("SAML assertion was already used")
+	// ErrLoginFailed is returned when the IdP reports that authentication failed
+	ErrLoginFailed = errors.New("identity provider rejected the login")
+)
+
+// AttributeMap lists, for each user field, the assertion attributes to read it from, in
+// order of preference. Attributes match by Name or FriendlyName.
+type AttributeMap struct {
+	Email     []string
+	Name      []string
+	FirstName []string
+	LastName  []string
+}
+
+// DefaultAttributes covers the attribute names used by common IdPs (Azure AD/Entra ID,
+// ADFS, Okta, Google Workspace, Keycloak) and the LDAP OIDs
+var DefaultAttributes = AttributeMap{
+	Email: []string{
+		"email", "mail", "emailAddress",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	},
+	Name: []string{
+		"displayName", "name",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+	},
+	FirstName: []string{
+		"givenName", "firstName", "first_name",
+		"urn:oid:2.5.4.42",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname",
+	},
+	LastName: []string{
+		"sn", "surname", "lastName", "last_name",
+		"urn:oid:2.5.4.4",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname",
+	},
+}
+
+// Options configures the service provider
+type Options struct {
+	// EntityID identifies this service provider to the IdP, conventionally its metadata URL
+	EntityID string
+	// ACSURL is the assertion consumer service the IdP posts responses to
+	ACSURL string
+	// Attributes maps assertion attributes to user fields; defaults to DefaultAttributes
+	Attributes *AttributeMap
+	// ClockSkew is the tolerance for assertion validity times; defaults to 90 seconds
+	ClockSkew time.Duration
+	// Secret signs the pending request cookie
+	Secret string
+}
+
+// Identity is the user an assertion describes
+type Identity struct {
+	// Subject is the NameID, or the email for transient NameIDs that change every login
+	Subject    string
+	Email      string
+	Name       string
+	Attributes map[string][]string
+}
+
+// ServiceProvider runs SP-initiated logins against one identity provider
+type ServiceProvider struct {
+	idp     *IdentityProvider
+	opts    Options
+	signer  *auth.SignedTokens
+	replays *replayCache
+}
+
+// New creates a service provider for idp
+func New(idp *IdentityProvider, opts Options) *ServiceProvider {
+	if opts.Attributes == nil {
+		opts.Attributes = &DefaultAttributes
+	}
+	if opts.ClockSkew <= 0 {
+		opts.ClockSkew = 90 * time.Second
+	}
+	return &ServiceProvider{
+		idp:     idp,
+		opts:    opts,
+		signer:  auth.NewSignedTokens(opts.Secret),
+		replays: &replayCache{seen: map[string]time.Time{}},
+	}
+}
+
+// IdentityProvider returns the configured IdP
+func (sp *ServiceProvider) IdentityProvider() *IdentityProvider {
+	return sp.idp
+}
+
+// Metadata serves the service provider's metadata for registering it with the IdP
+func (sp *ServiceProvider) Metadata(c *fiber.Ctx) error {
+	body, err := sp.metadata()
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, "application/samlmetadata+xml")
+	return c.Send(body)
+}
+
+// Begin redirects to the IdP with a new AuthnRequest and remembers its ID in a signed cookie
+func (sp *ServiceProvider) Begin(c *fiber.Ctx) error {
+	now := clock.Now(c)
+	// IDs must not start with a digit (xs:ID)
+	requestID := "_" + strings.ReplaceAll(ids.From(c).NewID(), "-", "")
+
+	request, err := xml.Marshal(authnRequest{
+		ID:                          requestID,
+		Version:                     "2.0",
+		IssueInstant:                now.UTC().Format(time.RFC3339),
+		Destination:                 sp.idp.SSOURL,
+		AssertionConsumerServiceURL: sp.opts.ACSURL,
+		ProtocolBinding:             bindingPOST,
+		Issuer:                      sp.opts.EntityID,
+		NameIDPolicy:                nameIDPolicy{AllowCreate: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	// HTTP-Redirect binding: raw DEFLATE, then base64, then URL encoding
+	var compressed bytes.Buffer
+	w, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	if _, err := w.Write(request); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	// The IdP posts back cross-site, which only SameSite=None cookies survive; browsers
+	// accept Secure cookies on http://localhost, so this also works in development
+	c.Cookie(&fiber.Cookie{
+		Name:     RequestCookie,
+		Value:    sp.signer.Sign("saml-request", requestID, "", now.Add(requestTTL)),
+		Path:     "/saml",
+		MaxAge:   int(requestTTL.Seconds()),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteNoneMode,
+	})
+
+	target, err := url.Parse(sp.idp.SSOURL)
+	if err != nil {
+		return err
+	}
+	query := target.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(compressed.Bytes()))
+	target.RawQuery = query.Encode()
+	return c.Redirect(target.String(), fiber.StatusFound)
+}
+
+// Complete verifies the posted SAMLResponse against the pending request and returns the
+// identity it asserts. The request cookie is single use.
+func (sp *ServiceProvider) Complete(c *fiber.Ctx) (*Identity, error) {
+	now := clock.Now(c)
+	requestID, err := sp.signer.Verify(c.Cookies(RequestCookie), "saml-request", "", now)
+	c.Cookie(&fiber.Cookie{Name: RequestCookie, Path: "/saml", MaxAge: -1, Secure: true, HTTPOnly: true, SameSite: fiber.CookieSameSiteNoneMode})
+	if err != nil || requestID == "" {
+		return nil, ErrInvalidState
+	}
+
+	encoded := c.FormValue("SAMLResponse")
+	if encoded == "" || base64.StdEncoding.DecodedLen(len(encoded)) > maxResponseSize {
+		return nil, ErrInvalidResponse
+	}
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	resp, err := readResponse(data, sp.idp)
+	if err != nil {
+		return nil, err
+	}
+	if err := sp.validate(resp, requestID, now); err != nil {
+		return nil, err
+	}
+	return sp.identity(resp.Assertion), nil
+}
+
+// identity maps the assertion's NameID and attributes onto user fields
+func (sp *ServiceProvider) identity(a *assertion) *Identity {
+	attributes := map[string][]string{}
+	for _, attr := range a.Attributes {
+		for _, key := range []string{attr.Name, attr.FriendlyName} {
+			if key != "" {
+				attributes[key] = append(attributes[key], attr.Values...)
+			}
+		}
+	}
+	first := func(names []string) string {
+		for _, name := range names {
+			for _, value := range attributes[name] {
+				if value = strings.TrimSpace(value); value != "" {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+
+	nameID := strings.TrimSpace(a.Subject.NameID.Value)
+	id := &Identity{
+		Subject:    nameID,
+		Email:      first(sp.opts.Attributes.Email),
+		Name:       first(sp.opts.Attributes.Name),
+		Attributes: attributes,
+	}
+	if id.Email == "" && (a.Subject.NameID.Format == nameIDEmail || strings.Contains(nameID, "@")) {
+		id.Email = nameID
+	}
+	if id.Name == "" {
+		id.Name = strings.TrimSpace(first(sp.opts.Attributes.FirstName) + " " + first(sp.opts.Attributes.LastName))
+	}
+	if a.Subject.NameID.Format == nameIDTransient && id.Email != "" {
+		id.Subject = strings.ToLower(id.Email)
+	}
+	return id
+}
+
+type authnRequest struct {
+	XMLName                     xml.Name     `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string       `xml:"ID,attr"`
+	Version                     string       `xml:"Version,attr"`
+	IssueInstant                string       `xml:"IssueInstant,attr"`
+	Destination                 string       `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string       `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string       `xml:"ProtocolBinding,attr"`
+	Issuer                      string       `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameIDPolicy                nameIDPolicy `xml:"NameIDPolicy"`
+}
+
+type nameIDPolicy struct {
+	AllowCreate bool `xml:"AllowCreate,attr"`
+}
+
+// replayCache remembers used assertion IDs until they expire. It is per process; with
+// several instances a replay could land on another one within the assertion's lifetime.
+type replayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func (r *replayCache) use(id string, expiresAt, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, exp := range r.seen {
+		if !now.Before(exp) {
+			delete(r.seen, key)
+		}
+	}
+	if _, ok := r.seen[id]; ok || id == "" {
+		return ErrReplayed
+	}
+	r.seen[id] = expiresAt
+	return nil
+}
@@ -0,0 +1,448 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// XML namespaces and XML-DSig algorithm identifiers
+const (
+	nsXML       = "http://www.w3.org/XML/1998/namespace"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	algExcC14N     = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped   = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algSHA256      = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algSHA512      = "http://www.w3.org/2001/04/xmlenc#sha512"
+	algRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+)
+
+var digestAlgorithms = map[string]crypto.Hash{
+	algSHA256: crypto.SHA256,
+	algSHA512: crypto.SHA512,
+}
+
+var signatureAlgorithms = map[string]crypto.Hash{
+	algRSASHA256:   crypto.SHA256,
+	algRSASHA512:   crypto.SHA512,
+	algECDSASHA256: crypto.SHA256,
+}
+
+// errNotSigned is returned by verify for elements without an enveloped signature
+var errNotSigned = errors.New("element is not signed")
+
+// node is a parsed XML element that keeps namespace prefixes as written, which
+// canonicalization needs and encoding/xml's resolved names lose
+type node struct {
+	parent   *node
+	prefix   string
+	local    string
+	attrs    []xmlAttr
+	children []interface{} // *node or string (character data)
+}
+
+type xmlAttr struct {
+	prefix string
+	local  string
+	value  string
+}
+
+// parseXML parses a document into a node tree. Documents with a DTD or with duplicate
+// ID attributes are rejected; either could make a reference point at something other
+// than the element that was verified.
+func parseXML(data []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *node
+	ids := map[string]bool{}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &node{parent: current, prefix: t.Name.Space, local: t.Name.Local}
+			for _, a := range t.Attr {
+				n.attrs = append(n.attrs, xmlAttr{prefix: a.Name.Space, local: a.Name.Local, value: a.Value})
+				if a.Name.Space == "" && a.Name.Local == "ID" {
+					if ids[a.Value] {
+						return nil, fmt.Errorf("duplicate ID %q", a.Value)
+					}
+					ids[a.Value] = true
+				}
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("multiple root elements")
+				}
+				root = n
+			} else {
+				current.children = append(current.children, n)
+			}
+			current = n
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.prefix || t.Name.Local != current.local {
+				return nil, errors.New("mismatched end element")
+			}
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(t))
+			}
+		case xml.Directive:
+			return nil, errors.New("DTDs are not allowed")
+		}
+	}
+	if root == nil || current != nil {
+		return nil, errors.New("incomplete document")
+	}
+	return root, nil
+}
+
+// lookup resolves a namespace prefix ("" for the default namespace) in scope at n
+func (n *node) lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for e := n; e != nil; e = e.parent {
+		for _, a := range e.attrs {
+			if (prefix == "" && a.prefix == "" && a.local == "xmlns") || (a.prefix == "xmlns" && a.local == prefix) {
+				return a.value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// namespace is the element's namespace URI
+func (n *node) namespace() string {
+	uri, _ := n.lookup(n.prefix)
+	return uri
+}
+
+// is reports whether the element has the given namespace and local name
+func (n *node) is(namespace, local string) bool {
+	return n.local == local && n.namespace() == namespace
+}
+
+// attr returns an unqualified attribute's value
+func (n *node) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.prefix == "" && a.local == local {
+			return a.value
+		}
+	}
+	return ""
+}
+
+// childElements returns the child elements with the given namespace and local name
+func (n *node) childElements(namespace, local string) []*node {
+	var found []*node
+	for _, child := range n.children {
+		if e, ok := child.(*node); ok && e.is(namespace, local) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// child returns the only child element with the given name, or nil when there is not exactly one
+func (n *node) child(namespace, local string) *node {
+	if found := n.childElements(namespace, local); len(found) == 1 {
+		return found[0]
+	}
+	return nil
+}
+
+// text is the element's concatenated character data
+func (n *node) text() string {
+	var b strings.Builder
+	for _, child := range n.children {
+		if s, ok := child.(string); ok {
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
+
+// canonicalize serializes n with Exclusive XML Canonicalization 1.0 (without comments),
+// leaving out skip (the enveloped signature). Prefixes in inclusive are treated as in
+// inclusive canonicalization, per an InclusiveNamespaces PrefixList.
+func canonicalize(n, skip *node, inclusive []string) []byte {
+	c := &canonicalizer{skip: skip, inclusive: inclusive}
+	c.element(n, map[string]string{})
+	return c.buf.Bytes()
+}
+
+type canonicalizer struct {
+	buf       bytes.Buffer
+	skip      *node
+	inclusive []string
+}
+
+func (c *canonicalizer) element(n *node, rendered map[string]string) {
+	// Namespaces the element visibly uses, plus the inclusive ones in scope
+	needed := map[string]bool{n.prefix: true}
+	for _, a := range n.attrs {
+		if a.prefix != "" && a.prefix != "xml" && a.prefix != "xmlns" {
+			needed[a.prefix] = true
+		}
+	}
+	for _, prefix := range c.inclusive {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		if _, ok := n.lookup(prefix); ok {
+			needed[prefix] = true
+		}
+	}
+
+	var declared []string
+	scope := make(map[string]string, len(rendered)+len(needed))
+	for prefix, uri := range rendered {
+		scope[prefix] = uri
+	}
+	for prefix := range needed {
+		uri, ok := n.lookup(prefix)
+		if !ok && prefix != "" {
+			continue
+		}
+		if current, seen := rendered[prefix]; seen && current == uri || !seen && prefix == "" && uri == "" {
+			continue
+		}
+		scope[prefix] = uri
+		declared = append(declared, prefix)
+	}
+	sort.Strings(declared)
+
+	type qualified struct {
+		namespace string
+		attr      xmlAttr
+	}
+	var attrs []qualified
+	for _, a := range n.attrs {
+		if a.prefix == "xmlns" || (a.prefix == "" && a.local == "xmlns") {
+			continue
+		}
+		namespace := ""
+		if a.prefix != "" {
+			namespace, _ = n.lookup(a.prefix)
+		}
+		attrs = append(attrs, qualified{namespace: namespace, attr: a})
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].namespace != attrs[j].namespace {
+			return attrs[i].namespace < attrs[j].namespace
+		}
+		return attrs[i].attr.local < attrs[j].attr.local
+	})
+
+	name := qualifiedName(n.prefix, n.local)
+	c.buf.WriteString("<" + name)
+	for _, prefix := range declared {
+		if prefix == "" {
+			c.buf.WriteString(` xmlns="`)
+		} else {
+			c.buf.WriteString(` xmlns:` + prefix + `="`)
+		}
+		c.buf.WriteString(escapeAttr(scope[prefix]) + `"`)
+	}
+	for _, a := range attrs {
+		c.buf.WriteString(" " + qualifiedName(a.attr.prefix, a.attr.local) + `="` + escapeAttr(a.attr.value) + `"`)
+	}
+	c.buf.WriteString(">")
+
+	for _, child := range n.children {
+		switch v := child.(type) {
+		case *node:
+			if v != c.skip {
+				c.element(v, scope)
+			}
+		case string:
+			c.buf.WriteString(escapeText(v))
+		}
+	}
+	c.buf.WriteString("</" + name + ">")
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
+
+// verify checks the enveloped signature of el against certs and returns the canonical
+// form of el without its signature: the exact bytes the signature covers, which callers
+// must parse instead of the original document.
+//
+// Only what SAML needs is supported: one reference to el itself by ID, the enveloped
+// signature and exclusive canonicalization transforms, SHA-256/512 digests, and RSA or
+// ECDSA signatures. Key material in the signature is ignored; only certs are trusted.
+func verify(el *node, certs []*x509.Certificate) ([]byte, error) {
+	signatures := el.childElements(nsDSig, "Signature")
+	switch len(signatures) {
+	case 0:
+		return nil, errNotSigned
+	case 1:
+	default:
+		return nil, errors.New("multiple signatures")
+	}
+	sig := signatures[0]
+
+	signedInfo := sig.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return nil, errors.New("missing SignedInfo")
+	}
+	c14nMethod := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	if c14nMethod == nil || c14nMethod.attr("Algorithm") != algExcC14N {
+		return nil, errors.New("unsupported canonicalization method")
+	}
+	sigMethod := signedInfo.child(nsDSig, "SignatureMethod")
+	if sigMethod == nil {
+		return nil, errors.New("missing SignatureMethod")
+	}
+	sigHash, ok := signatureAlgorithms[sigMethod.attr("Algorithm")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature method %q", sigMethod.attr("Algorithm"))
+	}
+
+	reference := signedInfo.child(nsDSig, "Reference")
+	if reference == nil {
+		return nil, errors.New("signature must have exactly one reference")
+	}
+	id := el.attr("ID")
+	if id == "" || reference.attr("URI") != "#"+id {
+		return nil, errors.New("signature does not reference the signed element")
+	}
+
+	var inclusive []string
+	canonical := false
+	if transforms := reference.child(nsDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.childElements(nsDSig, "Transform") {
+			switch t.attr("Algorithm") {
+			case algEnveloped:
+			case algExcC14N:
+				canonical = true
+				inclusive = prefixList(t)
+			default:
+				return nil, fmt.Errorf("unsupported transform %q", t.attr("Algorithm"))
+			}
+		}
+	}
+	if !canonical {
+		return nil, errors.New("reference must use exclusive canonicalization")
+	}
+
+	digestMethod := reference.child(nsDSig, "DigestMethod")
+	if digestMethod == nil {
+		return nil, errors.New("missing DigestMethod")
+	}
+	digestHash, ok := digestAlgorithms[digestMethod.attr("Algorithm")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest method %q", digestMethod.attr("Algorithm"))
+	}
+	digestValue := reference.child(nsDSig, "DigestValue")
+	if digestValue == nil {
+		return nil, errors.New("missing DigestValue")
+	}
+	expected, err := decodeBase64(digestValue.text())
+	if err != nil {
+		return nil, errors.New("malformed DigestValue")
+	}
+
+	signed := canonicalize(el, sig, inclusive)
+	if subtle.ConstantTimeCompare(hashOf(digestHash, signed), expected) != 1 {
+		return nil, errors.New("digest mismatch")
+	}
+
+	sigValue := sig.child(nsDSig, "SignatureValue")
+	if sigValue == nil {
+		return nil, errors.New("missing SignatureValue")
+	}
+	signature, err := decodeBase64(sigValue.text())
+	if err != nil {
+		return nil, errors.New("malformed SignatureValue")
+	}
+	digest := hashOf(sigHash, canonicalize(signedInfo, nil, prefixList(c14nMethod)))
+	for _, cert := range certs {
+		if checkSignature(cert.PublicKey, sigHash, digest, signature) {
+			return signed, nil
+		}
+	}
+	return nil, errors.New("signature does not verify with the identity provider's certificate")
+}
+
+// prefixList reads an InclusiveNamespaces PrefixList from a canonicalization element
+func prefixList(method *node) []string {
+	if in := method.child(algExcC14N, "InclusiveNamespaces"); in != nil {
+		return strings.Fields(in.attr("PrefixList"))
+	}
+	return nil
+}
+
+func checkSignature(key crypto.PublicKey, hash crypto.Hash, digest, signature []byte) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, hash, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		// XML-DSig encodes ECDSA signatures as r || s, each padded to the key size
+		if len(signature)%2 != 0 {
+			return false
+		}
+		half := len(signature) / 2
+		r := new(big.Int).SetBytes(signature[:half])
+		s := new(big.Int).SetBytes(signature[half:])
+		return ecdsa.Verify(k, digest, r, s)
+	default:
+		return false
+	}
+}
+
+func hashOf(hash crypto.Hash, data []byte) []byte {
+	switch hash {
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+}
+
+// decodeBase64 decodes standard base64 that may be wrapped across lines
+func decodeBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/database"
+	"main.go/internal/security"
+)
+
+var (
+	roleScopesMu sync.RWMutex
+	// roleScopes grants scopes to users by role; admins get every scope by default
+	roleScopes = map[string][]string{"admin": {"*"}}
+)
+
+// SetRoleScopes replaces the scopes granted to users by role
+func SetRoleScopes(scopes map[string][]string) {
+	roleScopesMu.Lock()
+	defer roleScopesMu.Unlock()
+	roleScopes = scopes
+}
+
+// RoleScopes returns the scopes granted to users with role
+func RoleScopes(role string) []string {
+	roleScopesMu.RLock()
+	defer roleScopesMu.RUnlock()
+	return roleScopes[role]
+}
+
+// Scopes requires the caller to hold every listed scope. Attach it to a route or group
+// after the authenticators:
+//
+//	apiV1.Get("/status", auth.Scopes("read:status"), handler)
+//
+// Anonymous callers get 401; callers missing a scope get 403 listing the missing scopes
+// in the error details.
+func Scopes(required ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := PrincipalFrom(c)
+		if !ok {
+			return Required()(c)
+		}
+
+		var missing []string
+		for _, scope := range required {
+			if !principal.HasScope(scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) == 0 {
+			return c.Next()
+		}
+
+		security.Emit(c, security.EventPermissionDenied, map[string]string{
+			"reason":         "missing scope",
+			"missing_scopes": strings.Join(missing, " "),
+		})
+		// RFC 6750 error for bearer clients
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="`+strings.Join(required, " ")+`"`)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "Forbidden",
+			"message": "Insufficient scope",
+			"status":  fiber.StatusForbidden,
+			"details": map[string]string{
+				"required_scopes": strings.Join(required, " "),
+				"missing_scopes":  strings.Join(missing, " "),
+			},
+		})
+	}
+}
+
+// Privileged lifts owner scoping (database.Owned) for callers holding scope, so admin
+// routes reach every user's rows. Other callers pass through with their queries still
+// scoped; pair it with Scopes to reject them instead:
+//
+//	admin.Use(auth.Scopes("admin"), auth.Privileged("admin"))
+func Privileged(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if principal, ok := PrincipalFrom(c); ok && principal.HasScope(scope) {
+			c.SetUserContext(database.ContextWithPrivilege(c.UserContext()))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// SessionCookie is the cookie holding the session ID
+const SessionCookie = "session_id"
+
+// Session keys holding the authenticated principal
+const (
+	sessionUserID = "user_id"
+	sessionEmail  = "email"
+	sessionRole   = "role"
+	sessionID     = "session_id"
+)
+
+// LoginHook runs before a principal is stored in a new session, e.g. to assign the
+// principal's SessionID; an error aborts the login
+type LoginHook func(c *fiber.Ctx, p *Principal) error
+
+// SessionOptions configures the session cookie
+type SessionOptions struct {
+	HTTPOnly bool
+	SameSite string
+	Expire   time.Duration
+	Secure   bool
+	// Storage defaults to fiber's in-memory storage, which does not survive restarts
+	// or scale past a single instance
+	Storage fiber.Storage
+}
+
+// Sessions authenticates requests with a server-side session referenced by a cookie
+type Sessions struct {
+	store   *session.Store
+	onLogin LoginHook
+}
+
+// NewSessions creates a session authenticator
+func NewSessions(opts SessionOptions) *Sessions {
+	return &Sessions{
+		store: session.New(session.Config{
+			Expiration:     opts.Expire,
+			Storage:        opts.Storage,
+			KeyLookup:      "cookie:" + SessionCookie,
+			CookieSecure:   opts.Secure,
+			CookieHTTPOnly: opts.HTTPOnly,
+			CookieSameSite: normalizeSameSite(opts.SameSite),
+		}),
+	}
+}
+
+// OnLogin registers a hook that runs on every login, including remembered logins
+func (s *Sessions) OnLogin(hook LoginHook) {
+	s.onLogin = hook
+}
+
+// Middleware populates the principal from the session cookie. Requests without a
+// logged-in session pass through anonymously; pair it with Required() on protected groups.
+func (s *Sessions) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sess, err := s.store.Get(c)
+		if err != nil {
+			return c.Next()
+		}
+
+		if id, ok := sess.Get(sessionUserID).(string); ok && id != "" {
+			email, _ := sess.Get(sessionEmail).(string)
+			role, _ := sess.Get(sessionRole).(string)
+			sid, _ := sess.Get(sessionID).(string)
+			SetPrincipal(c, &Principal{
+				ID:        id,
+				Email:     email,
+				Role:      role,
+				Method:    MethodSession,
+				SessionID: sid,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// Login stores the principal in a fresh session. The session ID is regenerated
+// to prevent session fixation.
+func (s *Sessions) Login(c *fiber.Ctx, p *Principal) error {
+	sess, err := s.store.Get(c)
+	if err != nil {
+		return err
+	}
+	if err := sess.Regenerate(); err != nil {
+		return err
+	}
+
+	p.Method = MethodSession
+	if s.onLogin != nil {
+		if err := s.onLogin(c, p); err != nil {
+			return err
+		}
+	}
+
+	sess.Set(sessionUserID, p.ID)
+	sess.Set(sessionEmail, p.Email)
+	sess.Set(sessionRole, p.Role)
+	sess.Set(sessionID, p.SessionID)
+	if err := sess.Save(); err != nil {
+		return err
+	}
+
+	SetPrincipal(c, p)
+	return nil
+}
+
+// Logout destroys the session and expires its cookie
+func (s *Sessions) Logout(c *fiber.Ctx) error {
+	sess, err := s.store.Get(c)
+	if err != nil {
+		return err
+	}
+	return sess.Destroy()
+}
+
+// normalizeSameSite maps SESSION_SAMESITE values onto the casing fiber expects
+func normalizeSameSite(value string) string {
+	switch strings.ToLower(value) {
+	case "strict":
+		return fiber.CookieSameSiteStrictMode
+	case "none":
+		return fiber.CookieSameSiteNoneMode
+	default:
+		return fiber.CookieSameSiteLaxMode
+	}
+}
@@ -0,0 +1,159 @@
+// Package sessionstore keeps server-side sessions (AUTH=session) outside the process, so
+// they survive restarts and are shared by every instance: in Redis when the cache is
+// enabled, otherwise in the sessions table. Both implement fiber.Storage.
+package sessionstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"main.go/internal/cache"
+	"main.go/internal/database"
+	"main.go/internal/logger"
+	"main.go/internal/safego"
+)
+
+// timeout bounds each storage call; fiber.Storage methods take no context
+const timeout = 5 * time.Second
+
+var _ fiber.Storage = (*DBStorage)(nil)
+
+// NewRedisStorage creates a Redis-backed session storage, each session a key expiring
+// with it
+func NewRedisStorage(client *redis.Client) *cache.Storage {
+	return cache.NewStorage(client, "session:")
+}
+
+// DBStorage keeps sessions in the sessions table for deployments without Redis. Keys are
+// stored hashed, so the table does not hold usable session cookies.
+type DBStorage struct {
+	db     *database.DB
+	logger *logger.Logger
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewDBStorage creates a PostgreSQL-backed session storage
+func NewDBStorage(db *database.DB, l *logger.Logger) *DBStorage {
+	return &DBStorage{db: db, logger: l, stop: make(chan struct{})}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get implements fiber.Storage; expired sessions are not returned
+func (s *DBStorage) Get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `
+SELECT data FROM sessions
+WHERE key = $1 AND (expires_at IS NULL OR expires_at > NOW())`, hashKey(key)).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	return data, nil
+}
+
+// Set implements fiber.Storage; exp 0 keeps the session until it is deleted
+func (s *DBStorage) Set(key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO sessions (key, data, expires_at)
+VALUES ($1, $2, CASE WHEN $3::bigint > 0 THEN NOW() + $3::bigint * INTERVAL '1 millisecond' END)
+ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`,
+		hashKey(key), val, exp.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	return nil
+}
+
+// Delete implements fiber.Storage
+func (s *DBStorage) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE key = $1`, hashKey(key)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// Reset implements fiber.Storage; it deletes every session
+func (s *DBStorage) Reset() error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions`); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// Close implements fiber.Storage by stopping cleanup; the database stays open
+func (s *DBStorage) Close() error {
+	s.Stop()
+	return nil
+}
+
+// Cleanup deletes expired sessions and returns how many there were
+func (s *DBStorage) Cleanup(ctx context.Context) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// Start deletes expired sessions now and then every interval until Stop
+func (s *DBStorage) Start(interval time.Duration) {
+	safego.GoNamed("session-cleanup", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// One replica cleans up at a time; the others skip the round
+			var n int
+			_, err := s.db.WithTryAdvisoryLock(ctx, "session-cleanup", func(ctx context.Context) (err error) {
+				n, err = s.Cleanup(ctx)
+				return err
+			})
+			cancel()
+			if err != nil {
+				s.logger.Warn("Failed to clean up sessions", zap.Error(err))
+			} else if n > 0 {
+				s.logger.Info("Expired sessions deleted", zap.Int("sessions", n))
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background cleanup
+func (s *DBStorage) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
@@ -0,0 +1,146 @@
+// Package bodyparser parses request bodies with per-route rules. By default a JSON body
+// is parsed as c.BodyParser does: unknown keys are ignored and numbers in interface{}
+// fields become float64. Routes declared strict reject unknown keys and deeply nested
+// payloads instead, so a client sending a misspelled or unsupported field learns about
+// it rather than having it silently dropped.
+package bodyparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// localsKey holds the rule table for Parse
+const localsKey = "bodyparser.rules"
+
+// ErrTooDeep is returned for JSON bodies nested deeper than Options.MaxDepth
+var ErrTooDeep = errors.New("request body is nested too deeply")
+
+// Options select how a route's JSON bodies are parsed; other content types are parsed
+// by c.BodyParser
+type Options struct {
+	// DisallowUnknownFields rejects keys the target struct has no field for, and data
+	// after the JSON value
+	DisallowUnknownFields bool
+	// MaxDepth rejects objects and arrays nested deeper than this; 0 allows any depth
+	MaxDepth int
+	// UseNumber decodes numbers in interface{} fields as json.Number, keeping integers
+	// beyond float64's precision exact
+	UseNumber bool
+}
+
+// Strict rejects unknown fields and payloads nested more than 32 levels deep
+func Strict() Options {
+	return Options{DisallowUnknownFields: true, MaxDepth: 32}
+}
+
+// Rules holds the parsing options of routes, by route name
+type Rules struct {
+	routes map[string]Options
+}
+
+// New creates an empty rule table
+func New() *Rules {
+	return &Rules{routes: map[string]Options{}}
+}
+
+// Route declares the options for the route registered with .Name(name)
+func (r *Rules) Route(name string, opts Options) *Rules {
+	r.routes[name] = opts
+	return r
+}
+
+// Options returns the options declared for a route name
+func (r *Rules) Options(name string) (Options, bool) {
+	opts, ok := r.routes[name]
+	return opts, ok
+}
+
+// Middleware makes the rules available to Parse
+func (r *Rules) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(localsKey, r)
+		return c.Next()
+	}
+}
+
+// Parse parses the request body into out like c.BodyParser, applying the options
+// declared for the matched route. Handlers call it in place of c.BodyParser:
+//
+//	if err := bodyparser.Parse(c, &req); err != nil {
+//		return utils.BadRequest(c, "Invalid request body")
+//	}
+func Parse(c *fiber.Ctx, out interface{}) error {
+	rules, _ := c.Locals(localsKey).(*Rules)
+	if rules == nil || !isJSON(c) {
+		return c.BodyParser(out)
+	}
+	opts, ok := rules.Options(c.Route().Name)
+	if !ok {
+		return c.BodyParser(out)
+	}
+	return Decode(c.Body(), out, opts)
+}
+
+// Decode parses a JSON body into out with opts
+func Decode(body []byte, out interface{}, opts Options) error {
+	if opts.MaxDepth > 0 {
+		if err := checkDepth(body, opts.MaxDepth); err != nil {
+			return err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if opts.UseNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(out); err != nil {
+		return err
+	}
+	if opts.DisallowUnknownFields {
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return errors.New("unexpected data after the request body")
+		}
+	}
+	return nil
+}
+
+// checkDepth walks body's tokens, failing once objects and arrays nest deeper than max
+func checkDepth(body []byte, max int) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			// Left for Decode to report with its own message
+			return nil
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			if depth++; depth > max {
+				return fmt.Errorf("%w (more than %d levels)", ErrTooDeep, max)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// isJSON reports whether the body is JSON, as c.BodyParser decides
+func isJSON(c *fiber.Ctx) bool {
+	ctype := utils.ToLower(string(c.Request().Header.ContentType()))
+	ctype = utils.ParseVendorSpecificContentType(ctype)
+	return strings.HasPrefix(ctype, fiber.MIMEApplicationJSON)
+}
@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/tinylib/msgp/msgp"
+	"golang.org/x/sync/singleflight"
+
+	"main.go/internal/metrics"
+)
+
+const (
+	// asidePrefix namespaces GetOrSet entries in Redis
+	asidePrefix = "aside:"
+	// asideTimeout bounds each Redis call; GetOrSet fails open to the loader
+	asideTimeout = time.Second
+)
+
+// Entries start with a marker byte, so a cached absence is told apart from a value
+const (
+	markValue    byte = 'v'
+	markNotFound byte = 'n'
+)
+
+// ErrNotFound is returned by a GetOrSet loader (or wrapped in its error) when what it
+// loads does not exist; with Options.NegativeTTL the absence is cached too
+var ErrNotFound = errors.New("not found")
+
+var (
+	asideHits   = metrics.NewCounter("cache", "aside_hits_total", "GetOrSet calls answered from Redis")
+	asideMisses = metrics.NewCounter("cache", "aside_misses_total", "GetOrSet calls that ran their loader")
+	asideErrors = metrics.NewCounter("cache", "aside_errors_total", "GetOrSet reads or writes that failed, served from the loader")
+)
+
+// Codec serializes the values GetOrSet stores
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSON stores values as encoding/json does; the default
+	JSON Codec = jsonCodec{}
+	// MsgPack stores values in MessagePack, which is smaller and faster to decode, using
+	// the methods generated for the type by tinylib/msgp (//go:generate msgp)
+	MsgPack Codec = msgpackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(msgp.Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("msgpack: %T has no generated MarshalMsg method", v)
+	}
+	return m.MarshalMsg(nil)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	u, ok := v.(msgp.Unmarshaler)
+	if !ok {
+		return fmt.Errorf("msgpack: %T has no generated UnmarshalMsg method", v)
+	}
+	_, err := u.UnmarshalMsg(data)
+	return err
+}
+
+// Options adjust a GetOrSet call
+type Options struct {
+	// Codec serializes the value; JSON when nil
+	Codec Codec
+	// NegativeTTL caches a loader's ErrNotFound for this long, so lookups of something
+	// missing do not reach the database each time; absences are not cached when zero
+	NegativeTTL time.Duration
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultClient *redis.Client
+
+	loads singleflight.Group
+)
+
+// Default returns the client GetOrSet uses, nil until SetDefault is called
+func Default() *redis.Client {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClient
+}
+
+// SetDefault sets the client GetOrSet uses (main sets it when Redis is connected)
+func SetDefault(client *redis.Client) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = client
+}
+
+// GetOrSet returns the value cached under key, or calls loader, caches what it returns
+// for ttl and returns that. Concurrent calls for a key in this process share one loader
+// call, which runs with ctx's values but not its cancellation, so one caller going away
+// does not fail the others. Keys are shared by every caller, so name them after what
+// they hold:
+//
+//	plan, err := cache.GetOrSet(ctx, "plan:"+id, 5*time.Minute, func(ctx context.Context) (Plan, error) {
+//		return loadPlan(ctx, db, id)
+//	})
+//
+// Without Redis (SetDefault not called) or when Redis fails, the loader's result is
+// returned uncached. Loader errors are not cached, except ErrNotFound with NegativeTTL.
+func GetOrSet[T any](ctx context.Context, key string, ttl time.Duration, loader func(context.Context) (T, error), opts ...Options) (T, error) {
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Codec == nil {
+		opt.Codec = JSON
+	}
+	client := Default()
+
+	if client != nil {
+		cached, err := getAside[T](ctx, client, key, opt.Codec)
+		if err != nil {
+			asideErrors.Inc()
+		} else if cached != nil {
+			asideHits.Inc()
+			return cached.value, cached.err
+		}
+	}
+
+	result, err, _ := loads.Do(key, func() (interface{}, error) {
+		asideMisses.Inc()
+		value, err := loader(context.WithoutCancel(ctx))
+		if client != nil {
+			if err := setAside(ctx, client, key, value, err, ttl, opt); err != nil {
+				asideErrors.Inc()
+			}
+		}
+		return value, err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	value, ok := result.(T)
+	if !ok {
+		// Another call loaded a different type under the same key
+		return loader(ctx)
+	}
+	return value, nil
+}
+
+// asideEntry is a cached value, or a cached ErrNotFound
+type asideEntry[T any] struct {
+	value T
+	err   error
+}
+
+// getAside returns the entry cached under key, or nil when there is none
+func getAside[T any](ctx context.Context, client *redis.Client, key string, codec Codec) (*asideEntry[T], error) {
+	ctx, cancel := context.WithTimeout(ctx, asideTimeout)
+	defer cancel()
+	data, err := client.Get(ctx, asidePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty cache entry %q", key)
+	}
+	switch data[0] {
+	case markNotFound:
+		return &asideEntry[T]{err: ErrNotFound}, nil
+	case markValue:
+		var entry asideEntry[T]
+		if err := codec.Unmarshal(data[1:], &entry.value); err != nil {
+			return nil, err
+		}
+		return &entry, nil
+	}
+	return nil, fmt.Errorf("unknown cache entry %q", key)
+}
+
+// setAside caches a loader's result: its value, or ErrNotFound when opt asks for that
+func setAside[T any](ctx context.Context, client *redis.Client, key string, value T, loadErr error, ttl time.Duration, opt Options) error {
+	var data []byte
+	switch {
+	case loadErr == nil:
+		encoded, err := opt.Codec.Marshal(&value)
+		if err != nil {
+			return err
+		}
+		data = append([]byte{markValue}, encoded...)
+	case errors.Is(loadErr, ErrNotFound) && opt.NegativeTTL > 0:
+		data, ttl = []byte{markNotFound}, opt.NegativeTTL
+	default:
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), asideTimeout)
+	defer cancel()
+	return client.Set(ctx, asidePrefix+key, data, ttl).Err()
+}
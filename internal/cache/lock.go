@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+const (
+	// lockPrefix namespaces locks in Redis
+	lockPrefix = "lock:"
+	// lockRetryMin and lockRetryMax bound the random wait between attempts of Lock
+	lockRetryMin = 50 * time.Millisecond
+	lockRetryMax = 250 * time.Millisecond
+	// minLockTTL is the shortest TTL a lock may have: TryLock deducts a few milliseconds
+	// of clock drift from the TTL, so shorter locks would expire before they are held
+	minLockTTL = 10 * time.Millisecond
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when another holder has the lock
+	ErrLockHeld = errors.New("lock is held elsewhere")
+	// ErrLockLost is the cause of a lock's context ending because the lock could not be
+	// renewed, so another holder may have taken it
+	ErrLockLost = errors.New("lock lost")
+	// ErrNoRedis is returned by Lock and TryLock without a default client
+	ErrNoRedis = errors.New("redis is not configured")
+	// ErrLockTTL is returned by Lock and TryLock for a TTL shorter than 10ms
+	ErrLockTTL = fmt.Errorf("lock ttl must be at least %v", minLockTTL)
+
+	locksLost = metrics.NewCounter("cache", "locks_lost_total", "Distributed locks that could not be renewed before expiring")
+)
+
+// releaseScript deletes a lock only while it still holds the caller's token, so a holder
+// whose lock expired cannot release the next holder's
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// extendScript renews a lock only while it still holds the caller's token
+var extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// Locker takes locks that replicas of the app coordinate on, such as "run this job on one
+// replica at a time". With one client a lock is a key holding a random token; with
+// several independent Redis servers it follows Redlock: a lock is held when a majority
+// of them grant it within its TTL, so losing a minority of servers loses no locks.
+type Locker struct {
+	clients []*redis.Client
+}
+
+// NewLocker creates a locker on one Redis client, or on several independent ones
+func NewLocker(clients ...*redis.Client) *Locker {
+	return &Locker{clients: clients}
+}
+
+// Lock waits until it holds the lock called key, or until ctx ends (see Locker.Lock), on
+// the default client
+func Lock(ctx context.Context, key string, ttl time.Duration) (*Held, error) {
+	client := Default()
+	if client == nil {
+		return nil, ErrNoRedis
+	}
+	return NewLocker(client).Lock(ctx, key, ttl)
+}
+
+// TryLock takes the lock called key if it is free (see Locker.TryLock), on the default
+// client
+func TryLock(ctx context.Context, key string, ttl time.Duration) (*Held, error) {
+	client := Default()
+	if client == nil {
+		return nil, ErrNoRedis
+	}
+	return NewLocker(client).TryLock(ctx, key, ttl)
+}
+
+// Held is a lock taken by Lock or TryLock. It is renewed every third of its TTL until
+// Release, so work may outlast the TTL; the TTL only bounds how long a crashed holder
+// keeps others waiting. Work done under the lock should use Context, which ends with
+// ErrLockLost as its cause when the lock could not be renewed in time.
+type Held struct {
+	locker *Locker
+	key    string
+	token  string
+	ttl    time.Duration
+
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Lock waits until it holds the lock called key, retrying at random intervals, or until
+// ctx ends. Release it when done:
+//
+//	lock, err := cache.Lock(ctx, "reindex", 30*time.Second)
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Release(context.Background())
+//	return reindex(lock.Context())
+func (l *Locker) Lock(ctx context.Context, key string, ttl time.Duration) (*Held, error) {
+	for {
+		held, err := l.TryLock(ctx, key, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return held, err
+		}
+		wait := lockRetryMin + rand.N(lockRetryMax-lockRetryMin)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// TryLock takes the lock called key if no one holds it, and returns ErrLockHeld if
+// someone does. A job every replica runs on a schedule uses it to skip a round another
+// replica is already doing.
+func (l *Locker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Held, error) {
+	if ttl < minLockTTL {
+		return nil, ErrLockTTL
+	}
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	key = lockPrefix + key
+
+	start := time.Now()
+	granted, err := l.each(ctx, func(ctx context.Context, client *redis.Client) (bool, error) {
+		return client.SetNX(ctx, key, token, ttl).Result()
+	})
+	// The lock is only good for what is left of its TTL, less allowance for clock drift
+	// between the servers
+	validity := ttl - time.Since(start) - ttl/100 - 2*time.Millisecond
+	if granted < l.quorum() || validity <= 0 {
+		l.release(context.WithoutCancel(ctx), key, token)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrLockHeld
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	held := &Held{
+		locker:  l,
+		key:     key,
+		token:   token,
+		ttl:     ttl,
+		ctx:     lockCtx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	safego.GoNamed("cache-lock-renewal", held.renew)
+	return held, nil
+}
+
+// Context returns a context that ends when the lock is lost or released, or when the
+// context the lock was taken with ends
+func (h *Held) Context() context.Context {
+	return h.ctx
+}
+
+// Release stops renewing the lock and frees it for the next holder
+func (h *Held) Release(ctx context.Context) error {
+	var err error
+	h.once.Do(func() {
+		close(h.stop)
+		<-h.stopped
+		h.cancel(context.Canceled)
+		err = h.locker.release(ctx, h.key, h.token)
+	})
+	return err
+}
+
+// renew extends the lock every third of its TTL until Release, giving up once a renewal
+// fails to reach a quorum
+func (h *Held) renew() {
+	defer close(h.stopped)
+	// If a renewal panics, the lock is no longer kept alive, so end its context as lost
+	released := false
+	defer func() {
+		if !released {
+			h.cancel(ErrLockLost)
+		}
+	}()
+	ticker := time.NewTicker(h.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			released = true
+			return
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(h.ctx), h.ttl/3)
+		extended, _ := h.locker.each(ctx, func(ctx context.Context, client *redis.Client) (bool, error) {
+			n, err := extendScript.Run(ctx, client, []string{h.key}, h.token, h.ttl.Milliseconds()).Int()
+			return n == 1, err
+		})
+		cancel()
+		if extended < h.locker.quorum() {
+			locksLost.Inc()
+			h.cancel(ErrLockLost)
+			return
+		}
+	}
+}
+
+// release deletes the lock from every server still holding the token
+func (l *Locker) release(ctx context.Context, key, token string) error {
+	_, err := l.each(ctx, func(ctx context.Context, client *redis.Client) (bool, error) {
+		n, err := releaseScript.Run(ctx, client, []string{key}, token).Int()
+		return n == 1, err
+	})
+	return err
+}
+
+// quorum is how many servers must agree for a lock to be held
+func (l *Locker) quorum() int {
+	return len(l.clients)/2 + 1
+}
+
+// each runs fn on every server at once and counts those returning true; err is the
+// first error any of them returned
+func (l *Locker) each(ctx context.Context, fn func(context.Context, *redis.Client) (bool, error)) (int, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	count := 0
+	var firstErr error
+	for _, client := range l.clients {
+		wg.Add(1)
+		safego.GoNamed("cache-lock", func() {
+			defer wg.Done()
+			ok, err := fn(ctx, client)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				count++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		})
+	}
+	wg.Wait()
+	return count, firstErr
+}
+
+// newLockToken returns a random token identifying one holding of a lock
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
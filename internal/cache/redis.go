@@ -0,0 +1,31 @@
+// Package cache connects to Redis/Valkey for features that need shared state across
+// instances (replay protection, rate limiting, login throttling), caches values loaded
+// from slower stores with GetOrSet, and coordinates replicas with Lock.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedis connects to addr and verifies the connection with a PING
+func NewRedis(addr, password string) (*redis.Client, error) {
+	if password == "null" {
+		password = ""
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", addr, err)
+	}
+	return client, nil
+}
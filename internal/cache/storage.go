@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// storageTimeout bounds each storage call; fiber.Storage methods take no context
+const storageTimeout = 5 * time.Second
+
+var _ fiber.Storage = (*Storage)(nil)
+
+// Storage is a fiber.Storage keeping each entry as a Redis key under a prefix, expiring
+// with it, for fiber middleware whose state must be shared by every instance
+// (sessions, rate limits)
+type Storage struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewStorage creates a storage keeping its keys under prefix, e.g. "session:"
+func NewStorage(client *redis.Client, prefix string) *Storage {
+	return &Storage{client: client, prefix: prefix}
+}
+
+// Get implements fiber.Storage
+func (s *Storage) Get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Set implements fiber.Storage
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	return s.client.Set(ctx, s.prefix+key, val, exp).Err()
+}
+
+// Delete implements fiber.Storage
+func (s *Storage) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// Reset implements fiber.Storage; it deletes every key under the prefix
+func (s *Storage) Reset() error {
+	ctx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if err := s.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// Close implements fiber.Storage; the client is shared, so it stays open
+func (s *Storage) Close() error {
+	return nil
+}
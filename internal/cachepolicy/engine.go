@@ -0,0 +1,86 @@
+package cachepolicy
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Engine applies registered policies to responses by route name
+type Engine struct {
+	policies map[string]Policy
+}
+
+// New creates an empty policy table
+func New() *Engine {
+	return &Engine{policies: map[string]Policy{}}
+}
+
+// Route declares the policy for the route registered with .Name(name)
+func (e *Engine) Route(name string, p Policy) *Engine {
+	e.policies[name] = p
+	return e
+}
+
+// Policy returns the policy declared for a route name
+func (e *Engine) Policy(name string) (Policy, bool) {
+	p, ok := e.policies[name]
+	return p, ok
+}
+
+// Middleware sets headers after the handler runs, once the matched route is known.
+// A Cache-Control header set by the handler itself wins. Error responses on cacheable
+// routes are sent with no-store so a CDN never pins an outage.
+func (e *Engine) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		policy, ok := e.policies[c.Route().Name]
+		if !ok {
+			return err
+		}
+
+		if len(c.Response().Header.Peek(fiber.HeaderCacheControl)) == 0 {
+			status := c.Response().StatusCode()
+			if err != nil || status >= fiber.StatusBadRequest {
+				c.Set(fiber.HeaderCacheControl, NoStore().CacheControl())
+			} else {
+				c.Set(fiber.HeaderCacheControl, policy.CacheControl())
+			}
+		}
+
+		if keys := surrogateKeys(c, policy); len(keys) > 0 {
+			c.Set(HeaderSurrogateKey, strings.Join(keys, " "))
+			c.Set(HeaderCacheTag, strings.Join(keys, ","))
+		}
+		return err
+	}
+}
+
+// surrogateKeys expands the policy's keys with route parameters and appends the
+// handler's keys, dropping duplicates
+func surrogateKeys(c *fiber.Ctx, p Policy) []string {
+	extra, _ := c.Locals(localsKey).([]string)
+	if p.NoStore || len(p.SurrogateKeys)+len(extra) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	keys := make([]string, 0, len(p.SurrogateKeys)+len(extra))
+	for _, key := range append(append([]string{}, p.SurrogateKeys...), extra...) {
+		key = expand(c, key)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// expand replaces "{param}" placeholders with route parameter values
+func expand(c *fiber.Ctx, key string) string {
+	for _, param := range c.Route().Params {
+		key = strings.ReplaceAll(key, "{"+param+"}", c.Params(param))
+	}
+	return key
+}
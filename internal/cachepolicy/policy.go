@@ -0,0 +1,97 @@
+// Package cachepolicy sets Cache-Control and CDN surrogate-key headers from a declarative
+// table of per-route policies. Routes are matched by their Fiber route name, so the policy
+// lives next to the route definition as metadata instead of inside each handler:
+//
+//	app.Get("/status", statusHandler.Page).Name("status")
+//	policies.Route("status", cachepolicy.Public(30*time.Second).Keys("status"))
+package cachepolicy
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Surrogate-key header names: Fastly reads Surrogate-Key (space separated) and
+// Cloudflare reads Cache-Tag (comma separated). Both are sent so purges by key work
+// with either CDN.
+const (
+	HeaderSurrogateKey = "Surrogate-Key"
+	HeaderCacheTag     = "Cache-Tag"
+)
+
+// localsKey holds surrogate keys added by handlers for the current response
+const localsKey = "cachepolicy_keys"
+
+// Policy is the caching behaviour of a route
+type Policy struct {
+	// NoStore forbids caching anywhere
+	NoStore bool
+	// Private restricts caching to the browser
+	Private bool
+	// MaxAge is how long browsers (and CDNs without SharedMaxAge) may reuse a response
+	MaxAge time.Duration
+	// SharedMaxAge (s-maxage) overrides MaxAge for CDNs and proxies
+	SharedMaxAge time.Duration
+	// SurrogateKeys tag cached responses for purging; "{param}" is replaced with
+	// the route parameter, e.g. "incident:{id}"
+	SurrogateKeys []string
+}
+
+// NoStore is the policy for personal or sensitive responses
+func NoStore() Policy {
+	return Policy{NoStore: true}
+}
+
+// Private lets only the browser cache a response for maxAge
+func Private(maxAge time.Duration) Policy {
+	return Policy{Private: true, MaxAge: maxAge}
+}
+
+// Public lets browsers and CDNs cache a response for maxAge
+func Public(maxAge time.Duration) Policy {
+	return Policy{MaxAge: maxAge}
+}
+
+// Shared sets the CDN lifetime (s-maxage), typically longer than the browser's since
+// CDN copies can be purged by surrogate key
+func (p Policy) Shared(sMaxAge time.Duration) Policy {
+	p.SharedMaxAge = sMaxAge
+	return p
+}
+
+// Keys adds surrogate keys to the policy
+func (p Policy) Keys(keys ...string) Policy {
+	p.SurrogateKeys = append(append([]string{}, p.SurrogateKeys...), keys...)
+	return p
+}
+
+// CacheControl renders the Cache-Control header value
+func (p Policy) CacheControl() string {
+	if p.NoStore {
+		return "no-store"
+	}
+
+	directives := []string{"public"}
+	if p.Private {
+		directives[0] = "private"
+	}
+	directives = append(directives, "max-age="+seconds(p.MaxAge))
+	if p.SharedMaxAge > 0 && !p.Private {
+		directives = append(directives, "s-maxage="+seconds(p.SharedMaxAge))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// AddSurrogateKeys tags the current response with keys known only at request time,
+// such as the IDs of the records it lists
+func AddSurrogateKeys(c *fiber.Ctx, keys ...string) {
+	existing, _ := c.Locals(localsKey).([]string)
+	c.Locals(localsKey, append(existing, keys...))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(d / time.Second))
+}
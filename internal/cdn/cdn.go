@@ -0,0 +1,112 @@
+// Package cdn purges cached responses from a CDN by surrogate key. Keys match the ones
+// route policies in internal/cachepolicy attach to responses, so purging "status" drops
+// every cached copy of the status page. Drivers exist for Cloudflare (Cache-Tag),
+// Fastly (Surrogate-Key), and CloudFront (path invalidations mapped from keys).
+package cdn
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+// asyncTimeout bounds purges triggered in the background by domain events
+const asyncTimeout = 30 * time.Second
+
+var (
+	purges        = metrics.NewCounter("cdn", "purges_total", "CDN purge requests sent")
+	purgeFailures = metrics.NewCounter("cdn", "purge_failures_total", "CDN purge requests that failed")
+)
+
+// Purger is a CDN driver
+type Purger interface {
+	// Name identifies the CDN in logs and API responses
+	Name() string
+	// PurgeKeys drops cached responses tagged with any of keys
+	PurgeKeys(ctx context.Context, keys []string) error
+	// PurgeAll drops every cached response
+	PurgeAll(ctx context.Context) error
+}
+
+// Service runs purges and records their outcome. A nil *Service is valid and does
+// nothing, so callers need not check whether a CDN is configured.
+type Service struct {
+	purger Purger
+	logger *logger.Logger
+}
+
+// NewService creates a purge service for purger
+func NewService(purger Purger, l *logger.Logger) *Service {
+	return &Service{purger: purger, logger: l}
+}
+
+// Enabled reports whether a CDN is configured
+func (s *Service) Enabled() bool {
+	return s != nil
+}
+
+// Provider returns the CDN driver name
+func (s *Service) Provider() string {
+	if s == nil {
+		return ""
+	}
+	return s.purger.Name()
+}
+
+// Purge drops responses tagged with keys and waits for the CDN to accept the request
+func (s *Service) Purge(ctx context.Context, keys ...string) error {
+	if s == nil || len(keys) == 0 {
+		return nil
+	}
+	return s.record(s.purger.PurgeKeys(ctx, keys), zap.Strings("keys", keys))
+}
+
+// PurgeAll drops everything the CDN has cached
+func (s *Service) PurgeAll(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	return s.record(s.purger.PurgeAll(ctx), zap.Bool("all", true))
+}
+
+// PurgeAsync purges keys in the background; use it from handlers and hooks reacting to
+// domain changes so the response does not wait on the CDN. Failures are logged.
+func (s *Service) PurgeAsync(keys ...string) {
+	if s == nil || len(keys) == 0 {
+		return
+	}
+	safego.GoNamed("cdn-purge", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), asyncTimeout)
+		defer cancel()
+		_ = s.Purge(ctx, keys...)
+	})
+}
+
+func (s *Service) record(err error, field zap.Field) error {
+	if err != nil {
+		purgeFailures.Inc()
+		s.logger.Error("CDN purge failed", zap.String("provider", s.purger.Name()), field, zap.Error(err))
+		return err
+	}
+	purges.Inc()
+	s.logger.Info("CDN purge requested", zap.String("provider", s.purger.Name()), field)
+	return nil
+}
+
+// batches splits keys into slices of at most size, the per-request limit of each CDN API
+func batches(keys []string, size int) [][]string {
+	var out [][]string
+	for len(keys) > size {
+		out = append(out, keys[:size])
+		keys = keys[size:]
+	}
+	if len(keys) > 0 {
+		out = append(out, keys)
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// cloudflareAPI is the Cloudflare v4 API base URL
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareMaxTags is the number of cache tags accepted per purge request
+const cloudflareMaxTags = 30
+
+// Cloudflare purges by Cache-Tag through the zone purge_cache API
+type Cloudflare struct {
+	zoneID  string
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+// NewCloudflare creates a Cloudflare driver; token needs the Zone > Cache Purge permission
+func NewCloudflare(zoneID, token string) *Cloudflare {
+	return &Cloudflare{zoneID: zoneID, token: token, baseURL: cloudflareAPI, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements Purger
+func (cf *Cloudflare) Name() string {
+	return "cloudflare"
+}
+
+// PurgeKeys implements Purger
+func (cf *Cloudflare) PurgeKeys(ctx context.Context, keys []string) error {
+	for _, batch := range batches(keys, cloudflareMaxTags) {
+		if err := cf.purge(ctx, map[string]interface{}{"tags": batch}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeAll implements Purger
+func (cf *Cloudflare) PurgeAll(ctx context.Context) error {
+	return cf.purge(ctx, map[string]interface{}{"purge_everything": true})
+}
+
+func (cf *Cloudflare) purge(ctx context.Context, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cf.baseURL+"/zones/"+cf.zoneID+"/purge_cache", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cf.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cf.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudflare purge: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(raw, &result); err != nil || !result.Success {
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("cloudflare purge: %s", result.Errors[0].Message)
+		}
+		return fmt.Errorf("cloudflare purge: status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package cdn
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// cloudFrontAPI is the CloudFront API endpoint; CloudFront is global and signs as us-east-1
+const (
+	cloudFrontAPI     = "https://cloudfront.amazonaws.com"
+	cloudFrontVersion = "2020-05-31"
+	cloudFrontRegion  = "us-east-1"
+)
+
+// CloudFront has no surrogate keys, so keys are mapped to path invalidations. Keys
+// without a mapping invalidate the whole distribution ("/*").
+type CloudFront struct {
+	distributionID  string
+	accessKeyID     string
+	secretAccessKey string
+	keyPaths        map[string][]string
+	baseURL         string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewCloudFront creates a CloudFront driver. The credentials need
+// cloudfront:CreateInvalidation on the distribution; keyPaths maps surrogate keys to
+// the paths they cover, e.g. {"status": ["/status"]}.
+func NewCloudFront(distributionID, accessKeyID, secretAccessKey string, keyPaths map[string][]string) *CloudFront {
+	return &CloudFront{
+		distributionID:  distributionID,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		keyPaths:        keyPaths,
+		baseURL:         cloudFrontAPI,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		now:             time.Now,
+	}
+}
+
+// Name implements Purger
+func (cf *CloudFront) Name() string {
+	return "cloudfront"
+}
+
+// PurgeKeys implements Purger
+func (cf *CloudFront) PurgeKeys(ctx context.Context, keys []string) error {
+	seen := map[string]bool{}
+	var paths []string
+	for _, key := range keys {
+		mapped, ok := cf.keyPaths[key]
+		if !ok {
+			return cf.PurgeAll(ctx)
+		}
+		for _, path := range mapped {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return cf.invalidate(ctx, paths)
+}
+
+// PurgeAll implements Purger
+func (cf *CloudFront) PurgeAll(ctx context.Context) error {
+	return cf.invalidate(ctx, []string{"/*"})
+}
+
+// invalidationBatch is the CreateInvalidation request body
+type invalidationBatch struct {
+	XMLName xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Paths   struct {
+		Quantity int      `xml:"Quantity"`
+		Items    []string `xml:"Items>Path"`
+	} `xml:"Paths"`
+	CallerReference string `xml:"CallerReference"`
+}
+
+func (cf *CloudFront) invalidate(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	now := cf.now().UTC()
+
+	var batch invalidationBatch
+	batch.Paths.Quantity = len(paths)
+	batch.Paths.Items = paths
+	batch.CallerReference = strconv.FormatInt(now.UnixNano(), 10)
+	body, err := xml.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	path := "/" + cloudFrontVersion + "/distribution/" + cf.distributionID + "/invalidation"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cf.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	cf.sign(req, path, body, now)
+
+	resp, err := cf.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudfront invalidation: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("cloudfront invalidation: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header
+func (cf *CloudFront) sign(req *http.Request, path string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	canonical := fmt.Sprintf("%s\n%s\n\nhost:%s\nx-amz-date:%s\n\nhost;x-amz-date\n%s",
+		req.Method, path, req.URL.Host, amzDate, sha256Hex(body))
+	scope := date + "/" + cloudFrontRegion + "/cloudfront/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+cf.secretAccessKey), date)
+	key = hmacSHA256(key, cloudFrontRegion)
+	key = hmacSHA256(key, "cloudfront")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-date, Signature=%s",
+		cf.accessKeyID, scope, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// fastlyAPI is the Fastly API base URL
+const fastlyAPI = "https://api.fastly.com"
+
+// fastlyMaxKeys is the number of surrogate keys accepted per purge request
+const fastlyMaxKeys = 256
+
+// Fastly purges by Surrogate-Key through the service purge API
+type Fastly struct {
+	serviceID string
+	apiKey    string
+	// soft marks content stale instead of evicting it, so origin outages still serve it
+	soft    bool
+	baseURL string
+	client  *http.Client
+}
+
+// NewFastly creates a Fastly driver; apiKey needs the purge_select and purge_all scopes
+func NewFastly(serviceID, apiKey string, soft bool) *Fastly {
+	return &Fastly{serviceID: serviceID, apiKey: apiKey, soft: soft, baseURL: fastlyAPI, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements Purger
+func (f *Fastly) Name() string {
+	return "fastly"
+}
+
+// PurgeKeys implements Purger
+func (f *Fastly) PurgeKeys(ctx context.Context, keys []string) error {
+	for _, batch := range batches(keys, fastlyMaxKeys) {
+		header := http.Header{"Surrogate-Key": {strings.Join(batch, " ")}}
+		if err := f.post(ctx, "/service/"+f.serviceID+"/purge", header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeAll implements Purger
+func (f *Fastly) PurgeAll(ctx context.Context) error {
+	return f.post(ctx, "/service/"+f.serviceID+"/purge_all", nil)
+}
+
+func (f *Fastly) post(ctx context.Context, path string, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Fastly-Key", f.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if f.soft {
+		req.Header.Set("Fastly-Soft-Purge", "1")
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fastly purge: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fastly purge: status %d", resp.StatusCode)
+	}
+	return nil
+}
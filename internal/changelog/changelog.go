@@ -0,0 +1,166 @@
+// Package changelog serves release notes. Each release is a markdown file with a YAML
+// front matter block:
+//
+//	---
+//	version: 1.4.0
+//	title: Referral program
+//	date: 2026-10-17
+//	---
+//	Invite friends and earn credits...
+//
+// Release notes ship embedded in the binary (releases/*.md), or are read from a directory
+// (CHANGELOG_DIR) so they can be published without a rebuild. A small store remembers the
+// newest release each user has seen, so the frontend can flag what is new.
+package changelog
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"main.go/internal/markdown"
+)
+
+//go:embed releases
+var embedded embed.FS
+
+// Release is one entry in the changelog
+type Release struct {
+	Version     string    `json:"version"`
+	Title       string    `json:"title"`
+	PublishedAt time.Time `json:"published_at"`
+	// Markdown is the release notes as written; HTML is rendered and sanitized from it
+	Markdown string `json:"markdown"`
+	HTML     string `json:"html"`
+}
+
+// Changelog is the parsed release notes, newest first
+type Changelog struct {
+	releases []Release
+}
+
+type frontMatter struct {
+	Version string `yaml:"version"`
+	Title   string `yaml:"title"`
+	Date    string `yaml:"date"`
+}
+
+// renderer renders release notes once, at load, so they need no cache
+var renderer = markdown.New(markdown.Options{Policy: markdown.Trusted, Highlight: true})
+
+// Embedded loads the release notes compiled into the binary
+func Embedded() (*Changelog, error) {
+	sub, err := fs.Sub(embedded, "releases")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load parses every .md file at the root of fsys
+func Load(fsys fs.FS) (*Changelog, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release notes: %w", err)
+	}
+	log := &Changelog{}
+	versions := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		release, err := parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if other, ok := versions[release.Version]; ok {
+			return nil, fmt.Errorf("%s: version %s is also in %s", entry.Name(), release.Version, other)
+		}
+		versions[release.Version] = entry.Name()
+		log.releases = append(log.releases, *release)
+	}
+	sort.SliceStable(log.releases, func(i, j int) bool {
+		return log.releases[i].PublishedAt.After(log.releases[j].PublishedAt)
+	})
+	return log, nil
+}
+
+// parse splits the front matter from the body and renders the body
+func parse(data []byte) (*Release, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return nil, errors.New("missing front matter")
+	}
+	header, body, ok := strings.Cut(text[len("---\n"):], "\n---\n")
+	if !ok {
+		return nil, errors.New("unterminated front matter")
+	}
+
+	var meta frontMatter
+	if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	if meta.Version == "" || meta.Date == "" {
+		return nil, errors.New("front matter needs a version and a date")
+	}
+	published, err := time.Parse(time.DateOnly, meta.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", meta.Date)
+	}
+
+	body = strings.TrimSpace(body)
+	html, err := renderer.Render(body)
+	if err != nil {
+		return nil, err
+	}
+	title := meta.Title
+	if title == "" {
+		title = meta.Version
+	}
+	return &Release{
+		Version:     meta.Version,
+		Title:       title,
+		PublishedAt: published.UTC(),
+		Markdown:    body,
+		HTML:        html,
+	}, nil
+}
+
+// Releases returns the releases published at or before now, newest first, so notes can
+// be merged ahead of the release they describe
+func (l *Changelog) Releases(now time.Time) []Release {
+	releases := make([]Release, 0, len(l.releases))
+	for _, r := range l.releases {
+		if !r.PublishedAt.After(now) {
+			releases = append(releases, r)
+		}
+	}
+	return releases
+}
+
+// Unseen counts the releases in releases (newest first) published after seenAt; a nil
+// seenAt means the user has seen none
+func Unseen(releases []Release, seenAt *time.Time) int {
+	if seenAt == nil {
+		return len(releases)
+	}
+	n := 0
+	for _, r := range releases {
+		if !r.PublishedAt.After(*seenAt) {
+			break
+		}
+		n++
+	}
+	return n
+}
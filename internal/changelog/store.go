@@ -0,0 +1,47 @@
+package changelog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// Store remembers the newest release each user has seen
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new changelog store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// SeenAt returns the publish date of the newest release the user has seen, or nil
+func (s *Store) SeenAt(ctx context.Context, userID string) (*time.Time, error) {
+	var seenAt time.Time
+	err := s.db.QueryRowContext(ctx, `SELECT seen_at FROM changelog_seen WHERE user_id = $1`, userID).Scan(&seenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load changelog state: %w", err)
+	}
+	return &seenAt, nil
+}
+
+// MarkSeen records that the user has seen the releases published up to seenAt. It never
+// moves backwards, e.g. when an older tab reports in late.
+func (s *Store) MarkSeen(ctx context.Context, userID string, seenAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO changelog_seen (user_id, seen_at) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET seen_at = GREATEST(changelog_seen.seen_at, EXCLUDED.seen_at), updated_at = NOW()`, userID, seenAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark changelog seen: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/joho/godotenv"
+)
+
+// defaultEncryptedEnvFiles are tried in order when ENV_ENCRYPTED_FILE is unset
+var defaultEncryptedEnvFiles = []string{".env.age", ".env.enc"}
+
+var (
+	ageBinaryHeader = []byte("age-encryption.org/v1")
+	sopsMarker      = []byte("sops_version=")
+)
+
+// loadEncryptedEnv decrypts an age or sops encrypted env file and sets any variable
+// that is not already present in the environment. A configured file that is missing
+// or fails to decrypt is an error so the app never starts with partial secrets.
+func loadEncryptedEnv() error {
+	path := os.Getenv("ENV_ENCRYPTED_FILE")
+	if path == "" {
+		for _, candidate := range defaultEncryptedEnvFiles {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return nil
+		}
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read encrypted env file: %w", err)
+	}
+
+	plaintext, err := decryptEnv(path, contents)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	values, err := godotenv.Unmarshal(string(plaintext))
+	if err != nil {
+		return fmt.Errorf("failed to parse decrypted %s: %w", path, err)
+	}
+	for key, value := range values {
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// decryptEnv detects the file format from its contents
+func decryptEnv(path string, contents []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(contents, ageBinaryHeader), bytes.HasPrefix(bytes.TrimSpace(contents), []byte(armor.Header)):
+		return decryptAge(contents)
+	case bytes.Contains(contents, sopsMarker):
+		return decryptSops(path)
+	default:
+		return nil, fmt.Errorf("not an age or sops encrypted file")
+	}
+}
+
+// decryptAge decrypts in-process using the identities in AGE_KEY and/or AGE_KEY_FILE
+func decryptAge(contents []byte) ([]byte, error) {
+	identities, err := ageIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = bytes.NewReader(contents)
+	if !bytes.HasPrefix(contents, ageBinaryHeader) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(contents)))
+	}
+
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func ageIdentities() ([]age.Identity, error) {
+	var identities []age.Identity
+	if key := os.Getenv("AGE_KEY"); key != "" {
+		parsed, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid AGE_KEY: %w", err)
+		}
+		identities = append(identities, parsed...)
+	}
+	if path := os.Getenv("AGE_KEY_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open AGE_KEY_FILE: %w", err)
+		}
+		defer f.Close()
+		parsed, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AGE_KEY_FILE: %w", err)
+		}
+		identities = append(identities, parsed...)
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("set AGE_KEY or AGE_KEY_FILE to decrypt age files")
+	}
+	return identities, nil
+}
+
+// decryptSops shells out to the sops CLI, which handles age, PGP and cloud KMS keys
+// (AWS, GCP, Azure, Vault) using its usual environment and credential chain
+func decryptSops(path string) ([]byte, error) {
+	bin, err := exec.LookPath("sops")
+	if err != nil {
+		return nil, fmt.Errorf("sops is not installed: %w", err)
+	}
+
+	cmd := exec.Command(bin, "--decrypt", "--input-type", "dotenv", "--output-type", "dotenv", path)
+	cmd.Env = os.Environ()
+	// Reuse the app's age key names so one setting covers both formats
+	if key := os.Getenv("AGE_KEY"); key != "" && os.Getenv("SOPS_AGE_KEY") == "" {
+		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY="+key)
+	}
+	if file := os.Getenv("AGE_KEY_FILE"); file != "" && os.Getenv("SOPS_AGE_KEY_FILE") == "" {
+		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY_FILE="+file)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sops: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
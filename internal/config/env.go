@@ -1,7 +1,9 @@
 package config
 
 import (
+	"net"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -17,29 +19,159 @@ type Config struct {
 	AppEnv  string
 	AppURL  string
 	AppName string
+	// Prefork runs one process per CPU sharing the port (fiber's prefork mode)
+	Prefork bool
+	// SingleInstance declares that one process serves all traffic, so state kept in
+	// memory (rate limits, CSRF tokens) is not flagged in production
+	SingleInstance bool
+	// LogDir also writes logs to app.log and error.log in a directory; empty logs to
+	// stdout and stderr only
+	LogDir string
+	// LogAsyncQueue writes logs from a background goroutine through a queue of this many
+	// entries, dropping the oldest when it is full; 0 writes synchronously
+	LogAsyncQueue int
+	// Sandbox simulates external side effects (email, event deliveries, alert webhooks,
+	// newsletter syncs) and marks every response, for integrating against production URLs
+	Sandbox bool
+	// MemoryLimitRatio sets the Go soft memory limit to this share of the container's
+	// memory limit when GOMEMLIMIT is unset; 0 leaves the runtime without one
+	MemoryLimitRatio float64
+	// AppShortName, AppThemeColor, and AppBackgroundColor fill the web app manifest
+	AppShortName       string
+	AppThemeColor      string
+	AppBackgroundColor string
+	// PWA configures the optional service worker
+	PWA PWAConfig
+	// Canonical configures host, case, and trailing slash redirects
+	Canonical CanonicalConfig
+	// Locales lists the supported page locales (BCP 47); DefaultLocale is served unprefixed
+	Locales       []string
+	DefaultLocale string
+	// ChangelogDir reads release notes from a directory instead of the embedded ones
+	ChangelogDir string
+	// MarkdownHighlightStyle is the chroma style for code blocks in rendered markdown
+	MarkdownHighlightStyle string
 
 	// Middleware
 	CORS          bool
 	CSRF          bool
 	Compress      bool
 	CompressLevel int
+	// StaticPrecompress serves brotli/gzip variants of ./statics built at startup
+	StaticPrecompress bool
+	// WAF inspection mode (off, log, block) and limits
+	WAFMode           string
+	WAFMaxHeaderBytes int
+	WAFMaxBodyBytes   int
 
 	// Feature flags (component toggles)
 	Features FeatureFlags
 
-	// Database
-	DBURL string
+	// Database; DBDriver is pq (database/sql + lib/pq) or pgx (pgxpool) for both databases
+	DBURL    string
+	DBDriver string
+	DBPool   PoolConfig
+	// DBReconnect retries an unreachable database in the background, waiting up to
+	// DBReconnectMaxWait between attempts
+	DBReconnect        bool
+	DBReconnectMaxWait time.Duration
+
+	// Reporting database (optional read replica / analytics warehouse)
+	ReportingDBURL  string
+	ReportingDBPool PoolConfig
+
+	// Migrations
+	MigrationsDir           string
+	MigrateOnBoot           bool
+	MigrateAllowDestructive bool
+
+	// Tenancy serves several tenants from one deployment; off unless TENANCY_MODE is set
+	Tenancy TenancyConfig
+
+	// GatewayPrefix mounts REST handlers generated from protobuf annotations (newGateway in
+	// main.go) under this path, which must be /api/v1 or below it; empty leaves them off
+	GatewayPrefix string
+
+	// Observability
+	MetricsEnabled bool
+	Security       SecurityConfig
+	Heartbeat      HeartbeatConfig
+	CDN            CDNConfig
+	// ProbeCacheTTL micro-caches /health, /ready, and /live responses; 0 disables it
+	ProbeCacheTTL time.Duration
+	// StatusCheckInterval is how often component checks run for the /status page
+	StatusCheckInterval time.Duration
+	// MaintenanceRefreshInterval is how often maintenance windows are reloaded from the database
+	MaintenanceRefreshInterval time.Duration
 
 	// Authentication
 	AuthType      string
 	AuthSecret    string
 	SessionConfig SessionConfig
 	JWTConfig     JWTConfig
+	OAuth         OAuthConfig
+	// SAML configures the service provider used by AUTH=saml
+	SAML SAMLConfig
+	// RoleScopes grants API scopes to users by role; nil keeps the default (admin=*)
+	RoleScopes map[string][]string
+	// PasswordResetTTL bounds how long a password reset link stays valid
+	PasswordResetTTL time.Duration
+	// PasswordHashing holds the argon2id cost parameters and breach check toggle
+	PasswordHashing PasswordHashingConfig
+	// PasswordPolicy decides which new passwords the password validation tag accepts
+	PasswordPolicy PasswordPolicyConfig
+	// LoginLockout throttles repeated failed logins
+	LoginLockout LoginLockoutConfig
+	// InviteOnly requires an invite code to register and keeps uninvited users out of the API
+	InviteOnly bool
+	// Referrals configures the referral program (requires a database)
+	Referrals ReferralConfig
+	// Moderation screens user-generated content for spam (requires a database)
+	Moderation ModerationConfig
+	// Contact configures the public contact form; Captcha protects it when set
+	Contact ContactConfig
+	Captcha CaptchaConfig
+	// Storage selects where uploaded files are kept; Attachments configures uploads
+	Storage     StorageConfig
+	Attachments AttachmentConfig
+	// Images configures the signed /img transformations of image attachments
+	Images ImageConfig
+	// Uploads configures chunked uploads of large files; Video transcodes video attachments
+	Uploads UploadConfig
+	Video   VideoConfig
+	// Operations configures the background operation runner (requires a database)
+	Operations OperationsConfig
+	// Exports configures emailed CSV exports of data tables (requires a database and auth)
+	Exports ExportConfig
+	// Surveys configures the survey and feedback widget API (requires a database)
+	Surveys SurveyConfig
+	// Newsletter configures double opt-in signups and provider sync (requires a database)
+	Newsletter NewsletterConfig
+	// MailOutboxInterval is how often queued email is retried (requires a database)
+	MailOutboxInterval time.Duration
+	// Events configures where domain events from the event outbox are published (requires a database)
+	Events EventsConfig
+	// ContentFilter rejects or masks profanity and personal information in user-generated content
+	ContentFilter ContentFilterConfig
+	// EmailVerificationTTL bounds how long an email confirmation link stays valid
+	EmailVerificationTTL time.Duration
 
 	// Redis
 	RedisHost     string
 	RedisPassword string
 	RedisPort     string
+	// RedisRequired fails readiness while Redis is unreachable; otherwise the app keeps
+	// serving on per-instance fallbacks and only reports it
+	RedisRequired bool
+
+	// Request signing for server-to-server routes under /internal
+	SigningKeys    map[string]string
+	SigningMaxSkew time.Duration
+
+	// Column encryption keys by ID (base64, 32 bytes); values are encrypted with
+	// EncryptionActiveKey, and the other keys still decrypt values during a rotation
+	EncryptionKeys      map[string]string
+	EncryptionActiveKey string
 
 	// Mail
 	MailConfig MailConfig
@@ -62,17 +194,404 @@ type FeatureFlags struct {
 	Pusher   bool
 }
 
+// PoolConfig holds connection pool sizing and the statement timeout for a database
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// StatementTimeout bounds each statement; 0 leaves statements unbounded
+	StatementTimeout time.Duration
+}
+
+// TenancyConfig holds the multi-tenancy settings
+type TenancyConfig struct {
+	// Mode is schema (a schema per tenant), row (shared tables separated by row-level
+	// security), or empty for a single tenant
+	Mode string
+	// Resolver finds the tenant in the Header (header) or as a subdomain of Domain (subdomain)
+	Resolver string
+	Header   string
+	Domain   string
+	// Required rejects requests that name no tenant
+	Required bool
+	// CacheTTL is how long a resolved tenant is reused
+	CacheTTL time.Duration
+	// MigrationsDir holds the migrations applied to every tenant schema in schema mode
+	MigrationsDir string
+}
+
 // SessionConfig holds session-related configuration
 type SessionConfig struct {
 	HTTPOnly bool
 	SameSite string
 	Expire   time.Duration
+	// RememberExpire is the lifetime of remember-me cookies; 0 disables them
+	RememberExpire time.Duration
+	// Track records every login as an active session users can list and revoke; it
+	// applies to both AUTH modes and needs the cache or the database
+	Track bool
+	// CleanupInterval is how often expired sessions are deleted when they are kept in
+	// the database
+	CleanupInterval time.Duration
 }
 
 // JWTConfig holds JWT-related configuration
 type JWTConfig struct {
+	Issuer        string
 	Expire        time.Duration
 	RefreshExpire time.Duration
+	// KeysDir holds <kid>.pem private keys; when set tokens are signed asymmetrically
+	// and published at /.well-known/jwks.json instead of using AUTH_SECRET
+	KeysDir     string
+	ActiveKeyID string
+}
+
+// PasswordHashingConfig holds argon2id parameters. Raising them upgrades existing
+// hashes on each user's next login.
+type PasswordHashingConfig struct {
+	// Memory in KiB
+	Memory      int
+	Iterations  int
+	Parallelism int
+	// BreachCheck rejects new passwords found by the HaveIBeenPwned range API
+	BreachCheck bool
+}
+
+// PWAConfig holds service worker configuration
+type PWAConfig struct {
+	ServiceWorker bool
+	// Precache lists URLs cached when the worker installs
+	Precache []string
+	// CacheVersion names the worker's cache; defaults to the build's VCS revision
+	CacheVersion string
+}
+
+// CanonicalConfig holds URL normalization settings; the defaults depend on APP_ENV
+type CanonicalConfig struct {
+	// Host redirects requests for other hosts to APP_URL's
+	Host bool
+	// Lowercase redirects mixed-case paths to lowercase
+	Lowercase bool
+	// TrailingSlash is strip, add, or ignore
+	TrailingSlash string
+	// Exclude lists path prefixes whose case and trailing slash are left alone
+	Exclude []string
+}
+
+// PasswordPolicyConfig holds the rules for new passwords
+type PasswordPolicyConfig struct {
+	MinLength int
+	// Require lists character classes: upper, lower, digit, symbol
+	Require []string
+	// Banned passwords are rejected case-insensitively; BannedFile adds one per line
+	Banned     []string
+	BannedFile string
+}
+
+// LoginLockoutConfig holds failed-login thresholds and lockout durations
+type LoginLockoutConfig struct {
+	Enabled       bool
+	MaxAttempts   int
+	IPMaxAttempts int
+	Window        time.Duration
+	BaseLockout   time.Duration
+	MaxLockout    time.Duration
+}
+
+// ReferralConfig holds the referral program's rewards
+type ReferralConfig struct {
+	Enabled bool
+	// RewardReferrer and RewardReferred are the credits granted per qualified referral
+	RewardReferrer int
+	RewardReferred int
+	// RequireVerified holds rewards until the referred user confirms their email address
+	RequireVerified bool
+	// Interval is how often pending referrals are checked for rewards
+	Interval time.Duration
+}
+
+// ModerationConfig selects and tunes the spam classifier
+type ModerationConfig struct {
+	// Classifier is "heuristic", "akismet", or empty to disable spam checks
+	Classifier string
+	// Threshold and BannedTerms tune the heuristic scorer
+	Threshold   float64
+	BannedTerms []string
+	AkismetKey  string
+	// AkismetURL points at a compatible service instead of Akismet
+	AkismetURL string
+	// Workers and QueueSize bound background checks
+	Workers   int
+	QueueSize int
+}
+
+// ContactConfig holds the contact form settings
+type ContactConfig struct {
+	Enabled bool
+	// To receives the messages; defaults to MAIL_FROM_ADDRESS
+	To string
+	// RateLimit messages are accepted per IP in each RateWindow
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// CaptchaConfig selects a captcha provider: turnstile, hcaptcha, recaptcha, or empty for none
+type CaptchaConfig struct {
+	Provider string
+	// SiteKey is public and rendered by the page's widget; Secret verifies tokens
+	SiteKey string
+	Secret  string
+}
+
+// StorageConfig selects the file storage driver
+type StorageConfig struct {
+	// Driver is "local" or "s3"; s3 uses AWS_BUCKET, AWS_DEFAULT_REGION, and the AWS credentials
+	Driver string
+	// Path is the local driver's root directory
+	Path string
+	// S3Endpoint points the s3 driver at a compatible service such as MinIO
+	S3Endpoint string
+}
+
+// AttachmentConfig holds the file attachment settings
+type AttachmentConfig struct {
+	Enabled bool
+	// MaxBytes bounds the size of a single file
+	MaxBytes int
+	// AllowedTypes lists the accepted content types, sniffed from the contents
+	AllowedTypes []string
+	// MaxFiles bounds the attachments per resource; 0 means no limit
+	MaxFiles int
+	// CleanupInterval is how often files of removed attachments and deleted parents are deleted
+	CleanupInterval time.Duration
+	// ClamAVAddr is the clamd every upload is scanned with; empty skips scanning
+	ClamAVAddr string
+	// URLSecret signs the /files links that download attachments without signing in;
+	// defaults to AUTH_SECRET, and empty disables them. URLTTL bounds how long they work.
+	URLSecret string
+	URLTTL    time.Duration
+}
+
+// ImageConfig holds the image transformation settings
+type ImageConfig struct {
+	Enabled bool
+	// Secret signs /img URLs; defaults to AUTH_SECRET, and empty disables transformations
+	Secret string
+	// MaxDimension bounds requested widths and heights; MaxPixels bounds source images
+	MaxDimension int
+	MaxPixels    int
+	// Concurrency bounds how many images are rendered at once
+	Concurrency int
+	// CacheMaxAge is how long browsers and CDNs may cache a transformed image
+	CacheMaxAge time.Duration
+	// WebPEncoder and AVIFEncoder are the commands used for those formats; when one is not
+	// installed, requests for its format get JPEG or PNG
+	WebPEncoder string
+	AVIFEncoder string
+}
+
+// UploadConfig holds the chunked upload settings
+type UploadConfig struct {
+	Enabled bool
+	// MaxBytes bounds the size of an upload; ChunkBytes bounds each request
+	MaxBytes   int
+	ChunkBytes int
+	// TTL is how long an upload may take from its start
+	TTL time.Duration
+	// AllowedTypes lists the accepted content types, sniffed from the contents
+	AllowedTypes []string
+	// CleanupInterval is how often expired uploads and their chunks are deleted
+	CleanupInterval time.Duration
+}
+
+// VideoConfig holds the video transcoding settings
+type VideoConfig struct {
+	// Transcoder is ffmpeg, remote, or empty to keep videos as uploaded
+	Transcoder string
+	// FFmpegPath and FFprobePath are the commands the ffmpeg transcoder runs
+	FFmpegPath  string
+	FFprobePath string
+	// RemoteURL receives transcode jobs, signed with RemoteKeyID and RemoteSecret
+	RemoteURL    string
+	RemoteKeyID  string
+	RemoteSecret string
+	// Renditions names the profiles to produce (1080p, 720p, 480p, 360p)
+	Renditions []string
+	// Timeout bounds one transcode
+	Timeout time.Duration
+}
+
+// OperationsConfig holds the background operation settings
+type OperationsConfig struct {
+	// Interval is how often due operations are looked for; Workers run them concurrently
+	Interval time.Duration
+	Workers  int
+	// Retention is how long finished operations are kept; 0 keeps them
+	Retention time.Duration
+}
+
+// ExportConfig holds the data table export settings
+type ExportConfig struct {
+	Enabled bool
+	// TTL is how long an export's file and emailed link last
+	TTL time.Duration
+	// MaxRows bounds the rows of one export; 0 exports every row
+	MaxRows int
+	// Timeout bounds building one export
+	Timeout time.Duration
+	// CleanupInterval is how often the files of expired exports are deleted
+	CleanupInterval time.Duration
+	// Secret signs download links; defaults to AUTH_SECRET
+	Secret string
+}
+
+// SurveyConfig holds the survey settings
+type SurveyConfig struct {
+	Enabled bool
+	// RateLimit responses are accepted per IP in each RateWindow
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// NewsletterConfig holds the newsletter settings
+type NewsletterConfig struct {
+	Enabled bool
+	// Secret signs confirmation and unsubscribe links; defaults to AUTH_SECRET
+	Secret string
+	// ConfirmTTL bounds how long a confirmation link stays valid
+	ConfirmTTL time.Duration
+	// RateLimit signups are accepted per IP in each RateWindow
+	RateLimit  int
+	RateWindow time.Duration
+	// Driver is "mailchimp" or empty to only export subscribers as CSV
+	Driver string
+	// APIKey and ListID select the provider's audience; BaseURL points at a compatible service
+	APIKey  string
+	ListID  string
+	BaseURL string
+	// SyncInterval is how often changed subscriptions are pushed to the provider
+	SyncInterval time.Duration
+}
+
+// EventsConfig holds the event outbox's destinations. Pusher is used when the Pusher
+// feature is configured, the webhook when WebhookURL is set, and the Redis stream when
+// RedisStream is set and the cache is connected.
+type EventsConfig struct {
+	// Interval is how often pending events are retried
+	Interval time.Duration
+	// Retention is how long published events are kept; failed ones are kept until removed
+	Retention time.Duration
+	// PusherChannel receives events that do not name a channel
+	PusherChannel string
+	// WebhookKeyID and WebhookSecret sign webhook requests like calls to /internal
+	WebhookURL    string
+	WebhookKeyID  string
+	WebhookSecret string
+	// RedisStream is trimmed to about RedisMaxLen entries; 0 keeps every entry
+	RedisStream string
+	RedisMaxLen int
+}
+
+// ContentFilterConfig holds the profanity and PII filter settings
+type ContentFilterConfig struct {
+	// Mode is "reject", "mask", or "off"
+	Mode string
+	// Detectors lists built-in detectors: profanity, email, phone, credit_card
+	Detectors []string
+	// Words replace the built-in profanity list; WordsFile adds one per line
+	Words     []string
+	WordsFile string
+	// PatternsFile adds detectors, one "name: regexp" per line
+	PatternsFile string
+}
+
+// OAuthConfig holds OAuth2 social login configuration
+type OAuthConfig struct {
+	Google OAuthClient
+	GitHub OAuthClient
+	// OIDC is a generic OpenID Connect provider (Keycloak, Auth0, Okta, ...)
+	OIDC OIDCClient
+	// SuccessRedirect is where session-mode logins land after the provider callback
+	SuccessRedirect string
+}
+
+// SAMLConfig holds the SAML 2.0 service provider settings
+type SAMLConfig struct {
+	// IDPMetadata is the identity provider's metadata URL or file path
+	IDPMetadata string
+	// EntityID identifies this service provider; defaults to APP_URL/saml/metadata
+	EntityID string
+	// Attribute names to read user fields from, replacing the built-in defaults when set
+	EmailAttributes     []string
+	NameAttributes      []string
+	FirstNameAttributes []string
+	LastNameAttributes  []string
+	// SuccessRedirect is where browsers land after a successful login
+	SuccessRedirect string
+}
+
+// OAuthClient holds the credentials of a single OAuth2 provider
+type OAuthClient struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// Enabled reports whether both client credentials are configured
+func (o OAuthClient) Enabled() bool {
+	return o.ClientID != "" && o.ClientSecret != ""
+}
+
+// OIDCClient holds the settings of a generic OpenID Connect provider
+type OIDCClient struct {
+	// Name is the provider's URL segment in /auth/{name}
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	EmailClaim   string
+	NameClaim    string
+}
+
+// Enabled reports whether an issuer and client ID are configured; the secret is
+// optional for public clients
+func (o OIDCClient) Enabled() bool {
+	return o.Issuer != "" && o.ClientID != ""
+}
+
+// SecurityConfig holds the security event log and alerting configuration
+type SecurityConfig struct {
+	// LogFile receives security events as JSON lines; empty logs them with the app logger
+	LogFile string
+	// AlertThresholds overrides security.DefaultThresholds ("type=count/window,...")
+	AlertThresholds string
+	AlertWebhookURL string
+	AlertEmails     []string
+}
+
+// HeartbeatConfig configures pings to an external dead-man's-switch monitor
+type HeartbeatConfig struct {
+	// URL is the monitor's ping URL; empty disables the heartbeat
+	URL      string
+	Interval time.Duration
+	// OnShutdown is "ping", "fail", or "none"
+	OnShutdown string
+}
+
+// CDNConfig configures cache purging at the CDN in front of the app
+type CDNConfig struct {
+	// Provider is "cloudflare", "fastly", "cloudfront", or empty to disable purging
+	Provider           string
+	CloudflareZoneID   string
+	CloudflareAPIToken string
+	FastlyServiceID    string
+	FastlyAPIKey       string
+	// FastlySoftPurge marks content stale instead of evicting it
+	FastlySoftPurge          bool
+	CloudFrontDistributionID string
+	// CloudFrontKeyPaths maps surrogate keys to invalidation paths; CloudFront signs
+	// with AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+	CloudFrontKeyPaths map[string][]string
 }
 
 // MailConfig holds mail-related configuration
@@ -112,6 +631,10 @@ func LoadConfig() (*Config, error) {
 			return nil, err
 		}
 	}
+	// Encrypted secrets load after .env so it can point at the key (AGE_KEY_FILE)
+	if err := loadEncryptedEnv(); err != nil {
+		return nil, err
+	}
 
 	cfg := &Config{
 		// Server
@@ -120,6 +643,28 @@ func LoadConfig() (*Config, error) {
 		AppEnv:  getEnv("APP_ENV", "development"),
 		AppURL:  getEnv("APP_URL", "http://localhost:3000"),
 		AppName: getEnv("APP_NAME", "Fiber App"),
+		Prefork: getEnvAsBool("PREFORK", false),
+		LogDir:  getEnv("LOG_DIR", ""),
+
+		LogAsyncQueue: getEnvAsInt("LOG_ASYNC_QUEUE", 0),
+
+		SingleInstance:   getEnvAsBool("SINGLE_INSTANCE", false),
+		Sandbox:          getEnvAsBool("SANDBOX", false),
+		MemoryLimitRatio: getEnvAsFloat("MEMORY_LIMIT_RATIO", 0.9),
+
+		AppShortName:       getEnv("APP_SHORT_NAME", ""),
+		AppThemeColor:      getEnv("APP_THEME_COLOR", "#4f46e5"),
+		AppBackgroundColor: getEnv("APP_BACKGROUND_COLOR", "#ffffff"),
+		Locales:            splitList(getEnv("LOCALES", "en")),
+		DefaultLocale:      getEnv("DEFAULT_LOCALE", ""),
+		ChangelogDir:       getEnv("CHANGELOG_DIR", ""),
+
+		MarkdownHighlightStyle: getEnv("MARKDOWN_HIGHLIGHT_STYLE", "github"),
+		PWA: PWAConfig{
+			ServiceWorker: getEnvAsBool("PWA_SERVICE_WORKER", false),
+			Precache:      splitList(getEnv("PWA_PRECACHE", "/,/static/theme.css,/icons/icon-192.png")),
+			CacheVersion:  getEnv("PWA_CACHE_VERSION", ""),
+		},
 
 		// Middleware
 		CORS:          getEnvAsBool("CORS", true),
@@ -127,6 +672,12 @@ func LoadConfig() (*Config, error) {
 		Compress:      getEnvAsBool("COMPRESS", true),
 		CompressLevel: getEnvAsInt("COMPRESS_LEVEL", 0),
 
+		StaticPrecompress: getEnvAsBool("STATIC_PRECOMPRESS", true),
+
+		WAFMode:           getEnv("WAF_MODE", "off"),
+		WAFMaxHeaderBytes: getEnvAsInt("WAF_MAX_HEADER_BYTES", 3072),
+		WAFMaxBodyBytes:   getEnvAsInt("WAF_MAX_BODY_BYTES", 64*1024),
+
 		// Feature flags
 		Features: FeatureFlags{
 			Database: getEnvAsBool("FEATURE_DATABASE", false),
@@ -138,16 +689,86 @@ func LoadConfig() (*Config, error) {
 		},
 
 		// Database
-		DBURL: getEnv("DB_URL", ""),
+		DBURL:    getEnv("DB_URL", ""),
+		DBDriver: strings.ToLower(getEnv("DB_DRIVER", "pq")),
+		DBPool: PoolConfig{
+			MaxOpenConns:     getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:     getEnvAsInt("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime:  getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			StatementTimeout: getEnvAsDuration("DB_STATEMENT_TIMEOUT", 30*time.Second),
+		},
+		DBReconnect:        getEnvAsBool("DB_RECONNECT", true),
+		DBReconnectMaxWait: getEnvAsDuration("DB_RECONNECT_MAX_WAIT", time.Minute),
+
+		// Reporting database
+		ReportingDBURL: getEnv("REPORTING_DB_URL", ""),
+		ReportingDBPool: PoolConfig{
+			MaxOpenConns:     getEnvAsInt("REPORTING_DB_MAX_OPEN_CONNS", 10),
+			MaxIdleConns:     getEnvAsInt("REPORTING_DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime:  getEnvAsDuration("REPORTING_DB_CONN_MAX_LIFETIME", 15*time.Minute),
+			StatementTimeout: getEnvAsDuration("REPORTING_DB_STATEMENT_TIMEOUT", 5*time.Minute),
+		},
+
+		// Migrations
+		MigrationsDir:           getEnv("MIGRATIONS_DIR", "./sql/migrations"),
+		MigrateOnBoot:           getEnvAsBool("MIGRATE_ON_BOOT", false),
+		MigrateAllowDestructive: getEnvAsBool("MIGRATE_ALLOW_DESTRUCTIVE", false),
+
+		// Tenancy
+		Tenancy: TenancyConfig{
+			Mode:          strings.ToLower(getEnv("TENANCY_MODE", "")),
+			Resolver:      strings.ToLower(getEnv("TENANCY_RESOLVER", "header")),
+			Header:        getEnv("TENANCY_HEADER", "X-Tenant"),
+			Domain:        getEnv("TENANCY_DOMAIN", ""),
+			Required:      getEnvAsBool("TENANCY_REQUIRED", false),
+			CacheTTL:      getEnvAsDuration("TENANCY_CACHE_TTL", time.Minute),
+			MigrationsDir: getEnv("TENANCY_MIGRATIONS_DIR", getEnv("MIGRATIONS_DIR", "./sql/migrations")),
+		},
+		GatewayPrefix: getEnv("GATEWAY_PREFIX", ""),
+
+		// Observability
+		MetricsEnabled: getEnvAsBool("METRICS_ENABLED", true),
+		Security: SecurityConfig{
+			LogFile:         getEnv("SECURITY_LOG_FILE", ""),
+			AlertThresholds: getEnv("SECURITY_ALERT_THRESHOLDS", ""),
+			AlertWebhookURL: getEnv("SECURITY_ALERT_WEBHOOK_URL", ""),
+			AlertEmails:     splitList(getEnv("SECURITY_ALERT_EMAILS", "")),
+		},
+		ProbeCacheTTL:              getEnvAsDuration("PROBE_CACHE_TTL", 250*time.Millisecond),
+		StatusCheckInterval:        getEnvAsDuration("STATUS_CHECK_INTERVAL", time.Minute),
+		MaintenanceRefreshInterval: getEnvAsDuration("MAINTENANCE_REFRESH_INTERVAL", 30*time.Second),
+		Heartbeat: HeartbeatConfig{
+			URL:        getEnv("HEARTBEAT_URL", ""),
+			Interval:   getEnvAsDuration("HEARTBEAT_INTERVAL", time.Minute),
+			OnShutdown: getEnv("HEARTBEAT_ON_SHUTDOWN", "ping"),
+		},
+		CDN: CDNConfig{
+			Provider:                 strings.ToLower(getEnv("CDN_PROVIDER", "")),
+			CloudflareZoneID:         getEnv("CDN_CLOUDFLARE_ZONE_ID", ""),
+			CloudflareAPIToken:       getEnv("CDN_CLOUDFLARE_API_TOKEN", ""),
+			FastlyServiceID:          getEnv("CDN_FASTLY_SERVICE_ID", ""),
+			FastlyAPIKey:             getEnv("CDN_FASTLY_API_KEY", ""),
+			FastlySoftPurge:          getEnvAsBool("CDN_FASTLY_SOFT_PURGE", true),
+			CloudFrontDistributionID: getEnv("CDN_CLOUDFRONT_DISTRIBUTION_ID", ""),
+			CloudFrontKeyPaths:       parseListMap(getEnv("CDN_CLOUDFRONT_KEY_PATHS", "")),
+		},
 
 		// Authentication
 		AuthType:   getEnv("AUTH", "Disabled"),
 		AuthSecret: getEnv("AUTH_SECRET", ""),
+		RoleScopes: parseListMap(getEnv("AUTH_ROLE_SCOPES", "")),
 
 		// Redis
 		RedisHost:     getEnv("REDIS_HOST", "localhost"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisPort:     getEnv("REDIS_PORT", "6379"),
+		RedisRequired: getEnvAsBool("REDIS_REQUIRED", false),
+
+		SigningKeys:    parseKeyValues(getEnv("SIGNING_KEYS", "")),
+		SigningMaxSkew: getEnvAsDuration("SIGNING_MAX_SKEW", 5*time.Minute),
+
+		EncryptionKeys:      parseKeyValues(getEnv("ENCRYPTION_KEYS", "")),
+		EncryptionActiveKey: getEnv("ENCRYPTION_ACTIVE_KEY", ""),
 
 		// Mail
 		MailConfig: MailConfig{
@@ -183,17 +804,232 @@ func LoadConfig() (*Config, error) {
 		HTTPOnly: getEnvAsBool("SESSION_HTTPONLY", true),
 		SameSite: getEnv("SESSION_SAMESITE", "lax"),
 		Expire:   getEnvAsDuration("SESSION_EXPIRE", 24*time.Hour),
+
+		RememberExpire: getEnvAsDuration("SESSION_REMEMBER_EXPIRE", 30*24*time.Hour),
+		Track:          getEnvAsBool("SESSION_TRACKING", true),
+
+		CleanupInterval: getEnvAsDuration("SESSION_CLEANUP_INTERVAL", time.Hour),
 	}
 
 	// Parse JWT configuration
 	cfg.JWTConfig = JWTConfig{
+		Issuer:        getEnv("JWT_ISSUER", cfg.AppURL),
 		Expire:        getEnvAsDuration("JWT_EXPIRE", 24*time.Hour),
 		RefreshExpire: getEnvAsDuration("JWT_REFRESH_EXPIRE", 7*24*time.Hour),
+		KeysDir:       getEnv("JWT_KEYS_DIR", ""),
+		ActiveKeyID:   getEnv("JWT_ACTIVE_KEY_ID", ""),
+	}
+
+	cfg.PasswordResetTTL = getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour)
+	cfg.PasswordHashing = PasswordHashingConfig{
+		Memory:      getEnvAsInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+		Iterations:  getEnvAsInt("PASSWORD_ARGON2_ITERATIONS", 3),
+		Parallelism: getEnvAsInt("PASSWORD_ARGON2_PARALLELISM", 2),
+		BreachCheck: getEnvAsBool("PASSWORD_BREACH_CHECK", false),
+	}
+	cfg.PasswordPolicy = PasswordPolicyConfig{
+		MinLength:  getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		Require:    splitList(getEnv("PASSWORD_REQUIRE", "upper,lower,digit,symbol")),
+		Banned:     splitList(getEnv("PASSWORD_BANNED", "")),
+		BannedFile: getEnv("PASSWORD_BANNED_FILE", ""),
+	}
+	cfg.EmailVerificationTTL = getEnvAsDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	cfg.LoginLockout = LoginLockoutConfig{
+		Enabled:       getEnvAsBool("LOGIN_LOCKOUT_ENABLED", true),
+		MaxAttempts:   getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5),
+		IPMaxAttempts: getEnvAsInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		Window:        getEnvAsDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		BaseLockout:   getEnvAsDuration("LOGIN_LOCKOUT", time.Minute),
+		MaxLockout:    getEnvAsDuration("LOGIN_MAX_LOCKOUT", time.Hour),
+	}
+	cfg.InviteOnly = getEnvAsBool("INVITE_ONLY", false)
+	cfg.Moderation = ModerationConfig{
+		Classifier:  strings.ToLower(getEnv("MODERATION_CLASSIFIER", "")),
+		Threshold:   getEnvAsFloat("MODERATION_THRESHOLD", 0.6),
+		BannedTerms: splitList(getEnv("MODERATION_BANNED_TERMS", "")),
+		AkismetKey:  getEnv("AKISMET_KEY", ""),
+		AkismetURL:  getEnv("AKISMET_URL", ""),
+		Workers:     getEnvAsInt("MODERATION_WORKERS", 2),
+		QueueSize:   getEnvAsInt("MODERATION_QUEUE_SIZE", 100),
+	}
+	cfg.Contact = ContactConfig{
+		Enabled:    getEnvAsBool("CONTACT_ENABLED", true),
+		To:         getEnv("CONTACT_EMAIL", cfg.MailConfig.FromAddress),
+		RateLimit:  getEnvAsInt("CONTACT_RATE_LIMIT", 5),
+		RateWindow: getEnvAsDuration("CONTACT_RATE_WINDOW", time.Hour),
+	}
+	cfg.Captcha = CaptchaConfig{
+		Provider: strings.ToLower(getEnv("CAPTCHA_PROVIDER", "")),
+		SiteKey:  getEnv("CAPTCHA_SITE_KEY", ""),
+		Secret:   getEnv("CAPTCHA_SECRET", ""),
+	}
+	cfg.Storage = StorageConfig{
+		Driver:     strings.ToLower(getEnv("STORAGE_DRIVER", "local")),
+		Path:       getEnv("STORAGE_PATH", "storage"),
+		S3Endpoint: getEnv("AWS_ENDPOINT", ""),
+	}
+	cfg.Attachments = AttachmentConfig{
+		Enabled:         getEnvAsBool("ATTACHMENTS_ENABLED", true),
+		MaxBytes:        getEnvAsInt("ATTACHMENT_MAX_BYTES", 10*1024*1024),
+		AllowedTypes:    splitList(getEnv("ATTACHMENT_ALLOWED_TYPES", "image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain")),
+		MaxFiles:        getEnvAsInt("ATTACHMENT_MAX_FILES", 20),
+		CleanupInterval: getEnvAsDuration("ATTACHMENT_CLEANUP_INTERVAL", 10*time.Minute),
+		ClamAVAddr:      getEnv("ATTACHMENT_CLAMAV_ADDR", ""),
+		URLSecret:       getEnv("ATTACHMENT_URL_SECRET", cfg.AuthSecret),
+		URLTTL:          getEnvAsDuration("ATTACHMENT_URL_TTL", 15*time.Minute),
+	}
+	cfg.Images = ImageConfig{
+		Enabled:      getEnvAsBool("IMAGES_ENABLED", true),
+		Secret:       getEnv("IMAGE_URL_SECRET", cfg.AuthSecret),
+		MaxDimension: getEnvAsInt("IMAGE_MAX_DIMENSION", 4096),
+		MaxPixels:    getEnvAsInt("IMAGE_MAX_PIXELS", 40_000_000),
+		Concurrency:  getEnvAsInt("IMAGE_CONCURRENCY", runtime.NumCPU()),
+		CacheMaxAge:  getEnvAsDuration("IMAGE_CACHE_MAX_AGE", 30*24*time.Hour),
+		WebPEncoder:  getEnv("IMAGE_WEBP_ENCODER", "cwebp"),
+		AVIFEncoder:  getEnv("IMAGE_AVIF_ENCODER", "avifenc"),
+	}
+	cfg.Uploads = UploadConfig{
+		Enabled:         getEnvAsBool("UPLOADS_ENABLED", true),
+		MaxBytes:        getEnvAsInt("UPLOAD_MAX_BYTES", 5*1024*1024*1024),
+		ChunkBytes:      getEnvAsInt("UPLOAD_CHUNK_BYTES", 8*1024*1024),
+		TTL:             getEnvAsDuration("UPLOAD_TTL", 24*time.Hour),
+		AllowedTypes:    splitList(getEnv("UPLOAD_ALLOWED_TYPES", "video/mp4,video/webm")),
+		CleanupInterval: getEnvAsDuration("UPLOAD_CLEANUP_INTERVAL", 15*time.Minute),
+	}
+	cfg.Video = VideoConfig{
+		Transcoder:   strings.ToLower(getEnv("VIDEO_TRANSCODER", "")),
+		FFmpegPath:   getEnv("VIDEO_FFMPEG_PATH", "ffmpeg"),
+		FFprobePath:  getEnv("VIDEO_FFPROBE_PATH", "ffprobe"),
+		RemoteURL:    getEnv("VIDEO_TRANSCODER_URL", ""),
+		RemoteKeyID:  getEnv("VIDEO_TRANSCODER_KEY_ID", ""),
+		RemoteSecret: getEnv("VIDEO_TRANSCODER_SECRET", ""),
+		Renditions:   splitList(getEnv("VIDEO_RENDITIONS", "720p,480p")),
+		Timeout:      getEnvAsDuration("VIDEO_TRANSCODE_TIMEOUT", 2*time.Hour),
+	}
+	cfg.Operations = OperationsConfig{
+		Interval:  getEnvAsDuration("OPERATIONS_INTERVAL", 5*time.Second),
+		Workers:   getEnvAsInt("OPERATIONS_WORKERS", 2),
+		Retention: getEnvAsDuration("OPERATIONS_RETENTION", 30*24*time.Hour),
+	}
+	cfg.Exports = ExportConfig{
+		Enabled:         getEnvAsBool("EXPORTS_ENABLED", true),
+		TTL:             getEnvAsDuration("EXPORT_TTL", 72*time.Hour),
+		MaxRows:         getEnvAsInt("EXPORT_MAX_ROWS", 100000),
+		Timeout:         getEnvAsDuration("EXPORT_TIMEOUT", 10*time.Minute),
+		CleanupInterval: getEnvAsDuration("EXPORT_CLEANUP_INTERVAL", time.Hour),
+		Secret:          getEnv("EXPORT_SECRET", cfg.AuthSecret),
+	}
+	cfg.Surveys = SurveyConfig{
+		Enabled:    getEnvAsBool("SURVEYS_ENABLED", true),
+		RateLimit:  getEnvAsInt("SURVEY_RATE_LIMIT", 10),
+		RateWindow: getEnvAsDuration("SURVEY_RATE_WINDOW", time.Hour),
+	}
+	cfg.Newsletter = NewsletterConfig{
+		Enabled:      getEnvAsBool("NEWSLETTER_ENABLED", true),
+		Secret:       getEnv("NEWSLETTER_SECRET", cfg.AuthSecret),
+		ConfirmTTL:   getEnvAsDuration("NEWSLETTER_CONFIRM_TTL", 72*time.Hour),
+		RateLimit:    getEnvAsInt("NEWSLETTER_RATE_LIMIT", 5),
+		RateWindow:   getEnvAsDuration("NEWSLETTER_RATE_WINDOW", time.Hour),
+		Driver:       strings.ToLower(getEnv("NEWSLETTER_DRIVER", "")),
+		APIKey:       getEnv("NEWSLETTER_API_KEY", ""),
+		ListID:       getEnv("NEWSLETTER_LIST_ID", ""),
+		BaseURL:      getEnv("NEWSLETTER_API_URL", ""),
+		SyncInterval: getEnvAsDuration("NEWSLETTER_SYNC_INTERVAL", 5*time.Minute),
+	}
+	cfg.MailOutboxInterval = getEnvAsDuration("MAIL_OUTBOX_INTERVAL", 30*time.Second)
+	cfg.Events = EventsConfig{
+		Interval:      getEnvAsDuration("EVENTS_INTERVAL", 5*time.Second),
+		Retention:     getEnvAsDuration("EVENTS_RETENTION", 7*24*time.Hour),
+		PusherChannel: getEnv("EVENTS_PUSHER_CHANNEL", "events"),
+		WebhookURL:    getEnv("EVENTS_WEBHOOK_URL", ""),
+		WebhookKeyID:  getEnv("EVENTS_WEBHOOK_KEY_ID", ""),
+		WebhookSecret: getEnv("EVENTS_WEBHOOK_SECRET", ""),
+		RedisStream:   getEnv("EVENTS_REDIS_STREAM", ""),
+		RedisMaxLen:   getEnvAsInt("EVENTS_REDIS_MAXLEN", 100000),
+	}
+	cfg.ContentFilter = ContentFilterConfig{
+		Mode:         strings.ToLower(getEnv("CONTENT_FILTER_MODE", "off")),
+		Detectors:    splitList(getEnv("CONTENT_FILTER_DETECTORS", "profanity,email,phone,credit_card")),
+		Words:        splitList(getEnv("CONTENT_FILTER_WORDS", "")),
+		WordsFile:    getEnv("CONTENT_FILTER_WORDS_FILE", ""),
+		PatternsFile: getEnv("CONTENT_FILTER_PATTERNS_FILE", ""),
+	}
+	cfg.Referrals = ReferralConfig{
+		Enabled:         getEnvAsBool("REFERRALS_ENABLED", true),
+		RewardReferrer:  getEnvAsInt("REFERRAL_REWARD_REFERRER", 1),
+		RewardReferred:  getEnvAsInt("REFERRAL_REWARD_REFERRED", 0),
+		RequireVerified: getEnvAsBool("REFERRAL_REQUIRE_VERIFIED", true),
+		Interval:        getEnvAsDuration("REFERRAL_INTERVAL", time.Minute),
+	}
+
+	// Parse OAuth2 provider configuration
+	cfg.OAuth = OAuthConfig{
+		Google: OAuthClient{
+			ClientID:     getEnv("OAUTH_GOOGLE_CLIENT_ID", ""),
+			ClientSecret: getEnv("OAUTH_GOOGLE_CLIENT_SECRET", ""),
+		},
+		GitHub: OAuthClient{
+			ClientID:     getEnv("OAUTH_GITHUB_CLIENT_ID", ""),
+			ClientSecret: getEnv("OAUTH_GITHUB_CLIENT_SECRET", ""),
+		},
+		OIDC: OIDCClient{
+			Name:         getEnv("OIDC_PROVIDER_NAME", "oidc"),
+			Issuer:       getEnv("OIDC_ISSUER", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			Scopes:       splitList(getEnv("OIDC_SCOPES", "openid,email,profile")),
+			EmailClaim:   getEnv("OIDC_EMAIL_CLAIM", "email"),
+			NameClaim:    getEnv("OIDC_NAME_CLAIM", "name"),
+		},
+		SuccessRedirect: getEnv("OAUTH_SUCCESS_REDIRECT", "/"),
+	}
+
+	// URL normalization is on in production only by default, so local and preview
+	// hosts keep working
+	trailingSlash := "ignore"
+	if cfg.IsProduction() {
+		trailingSlash = "strip"
+	}
+	cfg.Canonical = CanonicalConfig{
+		Host:          getEnvAsBool("CANONICAL_HOST", cfg.IsProduction()),
+		Lowercase:     getEnvAsBool("CANONICAL_LOWERCASE", cfg.IsProduction()),
+		TrailingSlash: strings.ToLower(getEnv("CANONICAL_TRAILING_SLASH", trailingSlash)),
+		Exclude:       splitList(getEnv("CANONICAL_EXCLUDE", "/api/,/static/,/internal/,/.well-known/")),
+	}
+
+	// Parse SAML service provider configuration
+	cfg.SAML = SAMLConfig{
+		IDPMetadata:         getEnv("SAML_IDP_METADATA", ""),
+		EntityID:            getEnv("SAML_ENTITY_ID", strings.TrimRight(cfg.AppURL, "/")+"/saml/metadata"),
+		EmailAttributes:     splitList(getEnv("SAML_ATTRIBUTE_EMAIL", "")),
+		NameAttributes:      splitList(getEnv("SAML_ATTRIBUTE_NAME", "")),
+		FirstNameAttributes: splitList(getEnv("SAML_ATTRIBUTE_FIRST_NAME", "")),
+		LastNameAttributes:  splitList(getEnv("SAML_ATTRIBUTE_LAST_NAME", "")),
+		SuccessRedirect:     getEnv("SAML_SUCCESS_REDIRECT", "/"),
 	}
 
 	return cfg, nil
 }
 
+// Summary returns non-secret settings for crash reports and diagnostics
+func (c *Config) Summary() map[string]interface{} {
+	return map[string]interface{}{
+		"app_name":        c.AppName,
+		"app_env":         c.AppEnv,
+		"port":            c.Port,
+		"auth_mode":       c.AuthMode(),
+		"database":        c.DatabaseEnabled(),
+		"reporting":       c.ReportingDatabaseEnabled(),
+		"cache":           c.CacheEnabled(),
+		"auth":            c.AuthEnabled(),
+		"mail":            c.MailEnabled(),
+		"aws":             c.AWSEnabled(),
+		"pusher":          c.PusherEnabled(),
+		"sandbox":         c.Sandbox,
+		"migrate_on_boot": c.MigrateOnBoot,
+	}
+}
+
 // IsDevelopment returns true if the application is running in development mode
 func (c *Config) IsDevelopment() bool {
 	return strings.ToLower(c.AppEnv) == "development"
@@ -214,6 +1050,11 @@ func (c *Config) DatabaseEnabled() bool {
 	return c != nil && c.Features.Database && c.DBURL != ""
 }
 
+// ReportingDatabaseEnabled returns true when heavy report queries should use a separate database
+func (c *Config) ReportingDatabaseEnabled() bool {
+	return c.DatabaseEnabled() && c.ReportingDBURL != ""
+}
+
 // CacheEnabled returns true when Redis/Valkey integrations should be bootstrapped
 func (c *Config) CacheEnabled() bool {
 	return c != nil && c.Features.Cache && c.RedisHost != ""
@@ -224,7 +1065,45 @@ func (c *Config) AuthEnabled() bool {
 	if c == nil || !c.Features.Auth {
 		return false
 	}
-	return strings.ToLower(c.AuthType) != "disabled" && c.AuthSecret != ""
+	return c.AuthMode() != AuthModeDisabled && c.AuthSecret != ""
+}
+
+// Authentication modes accepted by the AUTH env var
+const (
+	AuthModeDisabled = "disabled"
+	AuthModeSession  = "session"
+	AuthModeJWT      = "jwt"
+	AuthModeCustom   = "custom"
+	AuthModeSAML     = "saml"
+)
+
+// AuthMode normalizes AUTH into one of the AuthMode* constants
+func (c *Config) AuthMode() string {
+	if c == nil {
+		return AuthModeDisabled
+	}
+	switch strings.ToLower(strings.TrimSpace(c.AuthType)) {
+	case "sess", "session", "sessions":
+		return AuthModeSession
+	case "jwt":
+		return AuthModeJWT
+	case "custom":
+		return AuthModeCustom
+	case "saml":
+		return AuthModeSAML
+	default:
+		return AuthModeDisabled
+	}
+}
+
+// RedisAddr returns the host:port of the Redis/Valkey server
+func (c *Config) RedisAddr() string {
+	return net.JoinHostPort(c.RedisHost, c.RedisPort)
+}
+
+// SigningEnabled reports whether HMAC-signed server-to-server routes are configured
+func (c *Config) SigningEnabled() bool {
+	return c != nil && len(c.SigningKeys) > 0
 }
 
 // MailEnabled indicates whether outbound mailers should be initialised
@@ -276,12 +1155,67 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsFloat gets an environment variable as a float
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 // getEnvAsDuration gets an environment variable as a duration
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := time.ParseDuration(value); err == nil {
 			return parsed
 		}
+		// time.ParseDuration has no day unit; accept values like JWT_REFRESH_EXPIRE=7d
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && strings.HasSuffix(value, "d") {
+			return time.Duration(days) * 24 * time.Hour
+		}
 	}
 	return defaultValue
 }
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseKeyValues parses "id:secret,id2:secret2" pairs, skipping malformed entries
+func parseKeyValues(value string) map[string]string {
+	pairs := map[string]string{}
+	for _, item := range splitList(value) {
+		if key, val, ok := strings.Cut(item, ":"); ok && key != "" && val != "" {
+			pairs[key] = val
+		}
+	}
+	return pairs
+}
+
+// parseListMap parses "admin=*;user=read:status read:profile" into a list per name (scopes
+// per role, CDN paths per surrogate key). Values contain colons, so names are separated
+// by ";" and values by spaces or commas.
+func parseListMap(value string) map[string][]string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	scopes := map[string][]string{}
+	for _, entry := range strings.Split(value, ";") {
+		role, list, ok := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			continue
+		}
+		scopes[role] = strings.Fields(strings.ReplaceAll(list, ",", " "))
+	}
+	return scopes
+}
@@ -0,0 +1,182 @@
+package consent
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Consent categories understood by the template. "necessary" is always granted.
+const (
+	CategoryNecessary   = "necessary"
+	CategoryPreferences = "preferences"
+	CategoryAnalytics   = "analytics"
+	CategoryMarketing   = "marketing"
+)
+
+// Categories lists every category in display order
+var Categories = []string{CategoryNecessary, CategoryPreferences, CategoryAnalytics, CategoryMarketing}
+
+const (
+	// CookieName is the name of the signed consent cookie
+	CookieName = "consent"
+	// localsKey is where the parsed preferences are stored on the request
+	localsKey = "consent_preferences"
+	// cookieMaxAge is how long a consent decision is remembered (~6 months)
+	cookieMaxAge = 180 * 24 * time.Hour
+)
+
+var errInvalidCookie = errors.New("invalid consent cookie")
+
+// Preferences is the consent decision recorded for a visitor
+type Preferences struct {
+	Categories map[string]bool `json:"categories"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Granted reports whether the visitor agreed to the given category
+func (p *Preferences) Granted(category string) bool {
+	if category == CategoryNecessary {
+		return true
+	}
+	if p == nil || p.Categories == nil {
+		return false
+	}
+	return p.Categories[category]
+}
+
+// Manager signs, verifies and stores consent preferences in a cookie
+type Manager struct {
+	key    []byte
+	secure bool
+}
+
+// NewManager creates a consent manager. The cookie is signed with secret; when
+// secret is empty a random per-process key is used (decisions reset on restart).
+func NewManager(secret string, secure bool) *Manager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &Manager{key: key, secure: secure}
+}
+
+// Middleware parses the consent cookie once per request so downstream handlers
+// can call Allowed without re-verifying the signature
+func (m *Manager) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if prefs, err := m.decode(c.Cookies(CookieName)); err == nil {
+			c.Locals(localsKey, prefs)
+		}
+		return c.Next()
+	}
+}
+
+// Load returns the visitor's preferences, or nil when no valid decision exists
+func (m *Manager) Load(c *fiber.Ctx) *Preferences {
+	if prefs, ok := c.Locals(localsKey).(*Preferences); ok {
+		return prefs
+	}
+	prefs, err := m.decode(c.Cookies(CookieName))
+	if err != nil {
+		return nil
+	}
+	return prefs
+}
+
+// Save writes the signed consent cookie and makes the decision visible to the current request
+func (m *Manager) Save(c *fiber.Ctx, prefs *Preferences) error {
+	prefs.Categories[CategoryNecessary] = true
+
+	value, err := m.encode(prefs)
+	if err != nil {
+		return err
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     CookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  prefs.UpdatedAt.Add(cookieMaxAge),
+		Secure:   m.secure,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	c.Locals(localsKey, prefs)
+	return nil
+}
+
+// Clear removes the consent cookie, withdrawing every optional category
+func (m *Manager) Clear(c *fiber.Ctx) {
+	c.ClearCookie(CookieName)
+	c.Locals(localsKey, nil)
+}
+
+// Allowed reports whether the current visitor granted the given category.
+// Analytics and marketing integrations must consult this before tracking.
+func Allowed(c *fiber.Ctx, category string) bool {
+	prefs, _ := c.Locals(localsKey).(*Preferences)
+	return prefs.Granted(category)
+}
+
+// Require returns a gate middleware that silently answers 204 No Content when the
+// visitor has not granted category, so tracking endpoints become no-ops
+func Require(category string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !Allowed(c, category) {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		return c.Next()
+	}
+}
+
+// IsCategory reports whether name is a known consent category
+func IsCategory(name string) bool {
+	for _, category := range Categories {
+		if category == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) encode(prefs *Preferences) (string, error) {
+	payload, err := json.Marshal(prefs)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + m.sign(body), nil
+}
+
+func (m *Manager) decode(value string) (*Preferences, error) {
+	body, signature, found := strings.Cut(value, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(m.sign(body))) {
+		return nil, errInvalidCookie
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, errInvalidCookie
+	}
+
+	var prefs Preferences
+	if err := json.Unmarshal(payload, &prefs); err != nil {
+		return nil, errInvalidCookie
+	}
+	return &prefs, nil
+}
+
+func (m *Manager) sign(body string) string {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
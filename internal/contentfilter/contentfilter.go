@@ -0,0 +1,360 @@
+// Package contentfilter finds profanity and personal information (emails, phone numbers,
+// credit card numbers) in user-generated text. A Filter either rejects text that matches
+// or masks the matches, depending on its Mode. Fields opt in with the clean validation
+// tag (see validation.SetContentFilter); handlers call Apply, Mask, or Scan directly for
+// anything the tag cannot express.
+package contentfilter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Mode is what a filter does with text that matches a detector
+type Mode string
+
+const (
+	// ModeReject refuses the text
+	ModeReject Mode = "reject"
+	// ModeMask replaces each match with asterisks
+	ModeMask Mode = "mask"
+)
+
+// Built-in detector names
+const (
+	DetectorProfanity  = "profanity"
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+)
+
+// DefaultDetectors are enabled when Options.Detectors is empty
+var DefaultDetectors = []string{DetectorProfanity, DetectorEmail, DetectorPhone, DetectorCreditCard}
+
+// DefaultWords is the built-in profanity list; plural and -ing/-ed/-er forms match too
+var DefaultWords = []string{
+	"fuck", "motherfucker", "shit", "bullshit", "bitch", "cunt", "asshole", "bastard",
+	"dick", "prick", "twat", "wanker", "slut", "whore",
+}
+
+// maskRune replaces masked characters
+const maskRune = '*'
+
+var (
+	emailPattern      = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)
+	phonePattern      = regexp.MustCompile(`(?:\+|\b)\d{1,4}(?:[ .-]?\(?\d{2,4}\)?){2,4}\b`)
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// Detector finds one kind of unwanted content
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Valid, when set, confirms a pattern match, e.g. with a checksum
+	Valid func(match string) bool
+}
+
+// Email detects email addresses
+func Email() *Detector {
+	return &Detector{Name: DetectorEmail, Pattern: emailPattern}
+}
+
+// Phone detects phone numbers of 9 to 15 digits, with or without a country code and
+// separators; shorter digit runs are too often years, prices, or IDs
+func Phone() *Detector {
+	return &Detector{Name: DetectorPhone, Pattern: phonePattern, Valid: func(match string) bool {
+		n := countDigits(match)
+		return n >= 9 && n <= 15
+	}}
+}
+
+// CreditCard detects card numbers of 13 to 19 digits that pass the Luhn check
+func CreditCard() *Detector {
+	return &Detector{Name: DetectorCreditCard, Pattern: creditCardPattern, Valid: luhn}
+}
+
+// Profanity detects words from the list as whole words, case-insensitively
+func Profanity(words []string) (*Detector, error) {
+	var alternatives []string
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			alternatives = append(alternatives, regexp.QuoteMeta(word))
+		}
+	}
+	if len(alternatives) == 0 {
+		return nil, fmt.Errorf("profanity detector needs at least one word")
+	}
+	// Longest first, so "motherfucker" is not cut short by "fuck"
+	sort.Slice(alternatives, func(i, j int) bool { return len(alternatives[i]) > len(alternatives[j]) })
+	pattern, err := regexp.Compile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)(?:s|es|ed|ing|er|ers)?\b`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profanity word list: %w", err)
+	}
+	return &Detector{Name: DetectorProfanity, Pattern: pattern}, nil
+}
+
+// Options configures a filter
+type Options struct {
+	// Mode defaults to ModeReject
+	Mode Mode
+	// Detectors names the built-in detectors to run; defaults to DefaultDetectors
+	Detectors []string
+	// Words replaces DefaultWords for the profanity detector
+	Words []string
+	// Patterns adds detectors by name and regular expression
+	Patterns map[string]string
+}
+
+// Match is one finding in a text; Start and End are byte offsets
+type Match struct {
+	Detector string `json:"detector"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// RejectedError is returned for text that matches a detector in reject mode
+type RejectedError struct {
+	// Detectors names what was found, e.g. "email"
+	Detectors []string
+}
+
+func (e *RejectedError) Error() string {
+	return "text contains " + strings.Join(e.Detectors, ", ")
+}
+
+// Filter applies detectors to text; it is safe for concurrent use
+type Filter struct {
+	mode      Mode
+	detectors []*Detector
+}
+
+// New creates a filter
+func New(opts Options) (*Filter, error) {
+	mode := opts.Mode
+	switch mode {
+	case "":
+		mode = ModeReject
+	case ModeReject, ModeMask:
+	default:
+		return nil, fmt.Errorf("unknown content filter mode %q (want reject or mask)", mode)
+	}
+
+	names := opts.Detectors
+	if len(names) == 0 {
+		names = DefaultDetectors
+	}
+	f := &Filter{mode: mode}
+	for _, name := range names {
+		switch name {
+		case DetectorProfanity:
+			words := opts.Words
+			if len(words) == 0 {
+				words = DefaultWords
+			}
+			d, err := Profanity(words)
+			if err != nil {
+				return nil, err
+			}
+			f.detectors = append(f.detectors, d)
+		case DetectorEmail:
+			f.detectors = append(f.detectors, Email())
+		case DetectorPhone:
+			f.detectors = append(f.detectors, Phone())
+		case DetectorCreditCard:
+			f.detectors = append(f.detectors, CreditCard())
+		default:
+			return nil, fmt.Errorf("unknown content filter detector %q", name)
+		}
+	}
+
+	// Sorted so detector order, and so error messages, do not depend on map order
+	custom := make([]string, 0, len(opts.Patterns))
+	for name := range opts.Patterns {
+		custom = append(custom, name)
+	}
+	sort.Strings(custom)
+	for _, name := range custom {
+		pattern, err := regexp.Compile(opts.Patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid content filter pattern %q: %w", name, err)
+		}
+		f.detectors = append(f.detectors, &Detector{Name: name, Pattern: pattern})
+	}
+	return f, nil
+}
+
+// Mode returns what the filter does with matching text
+func (f *Filter) Mode() Mode {
+	return f.mode
+}
+
+// Scan returns the matches in text in order. Where detectors overlap, the match starting
+// first (or the longer one) wins, so a card number is not also reported as a phone number.
+func (f *Filter) Scan(text string) []Match {
+	var found []Match
+	for _, d := range f.detectors {
+		for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+			if d.Valid == nil || d.Valid(text[loc[0]:loc[1]]) {
+				found = append(found, Match{Detector: d.Name, Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Start != found[j].Start {
+			return found[i].Start < found[j].Start
+		}
+		return found[i].End > found[j].End
+	})
+
+	matches := found[:0]
+	end := 0
+	for _, m := range found {
+		if m.Start >= end {
+			matches = append(matches, m)
+			end = m.End
+		}
+	}
+	return matches
+}
+
+// Check returns a *RejectedError when text matches any detector
+func (f *Filter) Check(text string) error {
+	matches := f.Scan(text)
+	if len(matches) == 0 {
+		return nil
+	}
+	var detectors []string
+	seen := map[string]bool{}
+	for _, m := range matches {
+		if !seen[m.Detector] {
+			seen[m.Detector] = true
+			detectors = append(detectors, m.Detector)
+		}
+	}
+	return &RejectedError{Detectors: detectors}
+}
+
+// Mask replaces every match in text with asterisks, keeping spaces so the text's shape
+// survives, e.g. "call 555 123 4567" becomes "call *** *** ****"
+func (f *Filter) Mask(text string) string {
+	matches := f.Scan(text)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		for _, r := range text[m.Start:m.End] {
+			if unicode.IsSpace(r) {
+				b.WriteRune(r)
+			} else {
+				b.WriteRune(maskRune)
+			}
+		}
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Apply filters text by the filter's mode: masked text in mask mode, or the text and a
+// *RejectedError in reject mode
+func (f *Filter) Apply(text string) (string, error) {
+	if f.mode == ModeMask {
+		return f.Mask(text), nil
+	}
+	return text, f.Check(text)
+}
+
+// Allows adapts the filter for validation.SetContentFilter. mode overrides the filter's
+// own ("reject" for fields that cannot be masked, such as usernames); text always passes
+// in mask mode, since the handler masks it.
+func (f *Filter) Allows(text, mode string) bool {
+	m := f.mode
+	if mode != "" {
+		m = Mode(mode)
+	}
+	if m == ModeMask {
+		return true
+	}
+	return len(f.Scan(text)) == 0
+}
+
+// LoadWords reads a word list, one word per line; blank lines and # comments are skipped
+func LoadWords(path string) ([]string, error) {
+	return readLines(path)
+}
+
+// LoadPatterns reads custom detectors, one "name: regexp" per line; blank lines and #
+// comments are skipped
+func LoadPatterns(path string) (map[string]string, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	patterns := map[string]string{}
+	for _, line := range lines {
+		name, pattern, ok := strings.Cut(line, ":")
+		name, pattern = strings.TrimSpace(name), strings.TrimSpace(pattern)
+		if !ok || name == "" || pattern == "" {
+			return nil, fmt.Errorf("invalid pattern line %q (want \"name: regexp\")", line)
+		}
+		patterns[name] = pattern
+	}
+	return patterns, nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func countDigits(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhn validates a card number's check digit, ignoring separators
+func luhn(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
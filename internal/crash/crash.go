@@ -0,0 +1,75 @@
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Report is written to disk when the process exits with an error or panics
+type Report struct {
+	Time      time.Time              `json:"time"`
+	ExitCode  int                    `json:"exit_code"`
+	Error     string                 `json:"error"`
+	Panic     bool                   `json:"panic"`
+	Stack     string                 `json:"stack,omitempty"`
+	GoVersion string                 `json:"go_version"`
+	OS        string                 `json:"os"`
+	Arch      string                 `json:"arch"`
+	PID       int                    `json:"pid"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+}
+
+var (
+	mu      sync.RWMutex
+	summary map[string]interface{}
+)
+
+// SetConfigSummary records the (secret-free) configuration included in crash reports
+func SetConfigSummary(s map[string]interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	summary = s
+}
+
+// NewReport builds a report for err; stack is only set for panics
+func NewReport(err error, exitCode int, stack []byte) *Report {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return &Report{
+		Time:      time.Now().UTC(),
+		ExitCode:  exitCode,
+		Error:     err.Error(),
+		Panic:     stack != nil,
+		Stack:     string(stack),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		PID:       os.Getpid(),
+		Config:    summary,
+	}
+}
+
+// Write stores the report as crash-<timestamp>-<pid>.json in dir and returns its path
+func (r *Report) Write(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create crash report directory: %w", err)
+	}
+
+	contents, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("crash-%s-%d.json", r.Time.Format("20060102T150405Z"), r.PID)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, contents, 0o640); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
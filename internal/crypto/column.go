@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	pq "github.com/lib/pq"
+
+	"main.go/internal/database"
+)
+
+// String is a text column encrypted with the default keyring. It is written and read like
+// a string; the column holds the ciphertext, so it cannot be searched or indexed:
+//
+//	db.ExecContext(ctx, `UPDATE integrations SET api_token = $2 WHERE id = $1`, id, crypto.String(token))
+//
+//	var token crypto.String
+//	db.QueryRowContext(ctx, `SELECT api_token FROM integrations WHERE id = $1`, id).Scan(&token)
+type String string
+
+// Value implements driver.Valuer
+func (s String) Value() (driver.Value, error) {
+	return Default().Encrypt([]byte(s), nil)
+}
+
+// Scan implements sql.Scanner; NULL scans as the empty string
+func (s *String) Scan(src interface{}) error {
+	var ciphertext string
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		ciphertext = v
+	case []byte:
+		ciphertext = string(v)
+	default:
+		return fmt.Errorf("crypto: cannot scan %T into String", src)
+	}
+	plaintext, err := Default().Decrypt(ciphertext, nil)
+	if err != nil {
+		return err
+	}
+	*s = String(plaintext)
+	return nil
+}
+
+// rotateBatch is how many rows RotateColumn re-encrypts per query
+const rotateBatch = 500
+
+// RotateColumn re-encrypts a String column, keyed by idColumn, with the active key of k
+// and returns the number of rows updated. Rows already under the active key are left
+// alone, so it can be run again after an interruption. Values encrypted with associated
+// data need Keyring.Rotate with the same data instead.
+func RotateColumn(ctx context.Context, db *database.DB, k *Keyring, table, idColumn, column string) (int, error) {
+	if k == nil {
+		return 0, ErrNoKeys
+	}
+	prefix := k.ActiveKey() + separator
+	col := pq.QuoteIdentifier(column)
+	id := pq.QuoteIdentifier(idColumn)
+	selectSQL := fmt.Sprintf(`SELECT %s::text, %s FROM %s WHERE %s IS NOT NULL AND left(%s, $2) <> $1 LIMIT $3`,
+		id, col, pq.QuoteIdentifier(table), col, col)
+	updateSQL := fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE %s::text = $1 AND %s = $3`,
+		pq.QuoteIdentifier(table), col, id, col)
+
+	total := 0
+	for {
+		rows, err := db.QueryContext(ctx, selectSQL, prefix, len(prefix), rotateBatch)
+		if err != nil {
+			return total, fmt.Errorf("failed to load %s.%s: %w", table, column, err)
+		}
+		type value struct{ id, ciphertext string }
+		var batch []value
+		for rows.Next() {
+			var v value
+			if err := rows.Scan(&v.id, &v.ciphertext); err != nil {
+				rows.Close()
+				return total, fmt.Errorf("failed to scan %s.%s: %w", table, column, err)
+			}
+			batch = append(batch, v)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+
+		for _, v := range batch {
+			rotated, err := k.Rotate(v.ciphertext, nil)
+			if err != nil {
+				return total, fmt.Errorf("%s.%s of %s: %w", table, column, v.id, err)
+			}
+			// A value changed since it was read is left for the next batch
+			result, err := db.ExecContext(ctx, updateSQL, v.id, rotated, v.ciphertext)
+			if err != nil {
+				return total, fmt.Errorf("failed to update %s.%s of %s: %w", table, column, v.id, err)
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				total++
+			}
+		}
+		if len(batch) < rotateBatch {
+			return total, nil
+		}
+	}
+}
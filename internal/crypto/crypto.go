@@ -0,0 +1,209 @@
+// Package crypto encrypts sensitive values, such as tokens and PII kept in database columns,
+// with AES-256-GCM. Keys live in a Keyring under short IDs (ENCRYPTION_KEYS); values are
+// encrypted with the active key and carry its ID, so keys can be rotated: add a new key,
+// make it active, re-encrypt with RotateColumn, then drop the old key.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KeySize is the length of an AES-256 key in bytes
+const KeySize = 32
+
+// separator ends the key ID at the start of a ciphertext
+const separator = "."
+
+var (
+	// ErrNoKeys is returned when encrypting or decrypting without a keyring
+	ErrNoKeys = errors.New("no encryption keys configured")
+	// ErrUnknownKey is returned for values encrypted with a key the keyring does not have
+	ErrUnknownKey = errors.New("value encrypted with an unknown key")
+	// ErrInvalid is returned for values that are not ciphertexts, or were tampered with
+	// or encrypted for other associated data
+	ErrInvalid = errors.New("invalid encrypted value")
+)
+
+// Keyring holds the keys values are encrypted with
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring from base64-encoded 32-byte keys by ID. Values are encrypted
+// with active, or with the only key when active is empty.
+func NewKeyring(keys map[string]string, active string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	k := &Keyring{active: active, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, encoded := range keys {
+		if !validKeyID(id) {
+			return nil, fmt.Errorf("encryption key ID %q: use letters, digits, - and _", id)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", id, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+
+	if k.active == "" {
+		if len(keys) > 1 {
+			return nil, errors.New("several encryption keys configured; choose the active one")
+		}
+		for id := range keys {
+			k.active = id
+		}
+	}
+	if _, ok := k.aeads[k.active]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not configured", k.active)
+	}
+	return k, nil
+}
+
+// GenerateKey returns a new random key, base64-encoded for ENCRYPTION_KEYS
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		key, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	}
+	if err != nil {
+		return nil, errors.New("not base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("want %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+func validKeyID(id string) bool {
+	if id == "" || len(id) > 32 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ActiveKey returns the ID of the key new values are encrypted with
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// Keys returns the IDs of every key, sorted
+func (k *Keyring) Keys() []string {
+	ids := make([]string, 0, len(k.aeads))
+	for id := range k.aeads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt encrypts plaintext with the active key as "<key id>.<base64 nonce and sealed
+// data>". Associated data, such as the table, column and row ID, is authenticated but
+// not stored: decrypting needs the same value, so a ciphertext copied to another row
+// does not decrypt.
+func (k *Keyring) Encrypt(plaintext, associatedData []byte) (string, error) {
+	if k == nil {
+		return "", ErrNoKeys
+	}
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, associatedData)
+	return k.active + separator + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value from Encrypt with whichever key it names
+func (k *Keyring) Decrypt(ciphertext string, associatedData []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrNoKeys
+	}
+	id, encoded, ok := strings.Cut(ciphertext, separator)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalid
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], associatedData)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return plaintext, nil
+}
+
+// NeedsRotation reports whether ciphertext was encrypted with a key other than the active one
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	return k != nil && !strings.HasPrefix(ciphertext, k.active+separator)
+}
+
+// Rotate re-encrypts ciphertext with the active key; values already under it are returned
+// unchanged
+func (k *Keyring) Rotate(ciphertext string, associatedData []byte) (string, error) {
+	if !k.NeedsRotation(ciphertext) {
+		return ciphertext, nil
+	}
+	plaintext, err := k.Decrypt(ciphertext, associatedData)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext, associatedData)
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// Default returns the process-wide keyring used by String, nil until SetDefault is called
+func Default() *Keyring {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultKeyring
+}
+
+// SetDefault replaces the process-wide keyring (main sets it from ENCRYPTION_KEYS)
+func SetDefault(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = k
+}
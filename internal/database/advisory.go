@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+)
+
+// Advisory locks let one replica at a time run a job, such as migrations or a cleanup
+// loop, when several instances of the app share a database. Locks are named; the name is
+// hashed to the 64-bit key Postgres locks on, so pick names unique to the job.
+//
+// WithAdvisoryLock and WithTryAdvisoryLock hold a session lock on a connection set aside
+// from the pool for as long as fn runs, so fn itself needs another connection from the
+// pool. If the process dies, Postgres drops the connection and with it the lock.
+
+// LockKey returns the advisory lock key of a lock name
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// WithAdvisoryLock waits for the advisory lock called name, runs fn while holding it, and
+// releases it. Waiting ends with ctx.
+func (db *DB) WithAdvisoryLock(ctx context.Context, name string, fn func(context.Context) error) error {
+	_, err := db.withAdvisoryLock(ctx, name, true, fn)
+	return err
+}
+
+// WithTryAdvisoryLock runs fn while holding the advisory lock called name if no other
+// session holds it, and reports whether fn ran. A job that runs on every replica uses it
+// to skip a round another replica is already doing.
+func (db *DB) WithTryAdvisoryLock(ctx context.Context, name string, fn func(context.Context) error) (bool, error) {
+	return db.withAdvisoryLock(ctx, name, false, fn)
+}
+
+func (db *DB) withAdvisoryLock(ctx context.Context, name string, wait bool, fn func(context.Context) error) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve a connection for lock %s: %w", name, err)
+	}
+	defer conn.Close()
+
+	key := LockKey(name)
+	if wait {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, key); err != nil {
+			return false, fmt.Errorf("failed to take lock %s: %w", name, err)
+		}
+	} else {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked); err != nil {
+			return false, fmt.Errorf("failed to take lock %s: %w", name, err)
+		}
+		if !locked {
+			return false, nil
+		}
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			// A session still holding the lock must not go back to the pool: discarding
+			// the connection ends the session, which releases it
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+	return true, fn(ctx)
+}
+
+// WithAdvisoryXactLock runs fn in a transaction that first waits for the advisory lock
+// called name; the lock is released when the transaction commits or rolls back
+func (db *DB) WithAdvisoryXactLock(ctx context.Context, name string, fn func(*sql.Tx) error) error {
+	return db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, LockKey(name)); err != nil {
+			return fmt.Errorf("failed to take lock %s: %w", name, err)
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	pq "github.com/lib/pq"
+)
+
+// DefaultBulkBatchSize is the number of rows BulkInsert copies per batch
+const DefaultBulkBatchSize = 5000
+
+// BulkOptions controls BulkInsertWith
+type BulkOptions struct {
+	// BatchSize is the number of rows copied per batch; 0 uses DefaultBulkBatchSize
+	BatchSize int
+	// StopOnError ends the import at the first failed batch instead of copying the rest
+	StopOnError bool
+}
+
+// BatchError is a batch BulkInsert could not copy; none of its rows were inserted
+type BatchError struct {
+	// Batch is the index of the batch, and Offset the index in rows of its first row
+	Batch  int
+	Offset int
+	Rows   int
+	Err    error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch %d (rows %d-%d): %v", e.Batch, e.Offset, e.Offset+e.Rows-1, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BulkError is returned by BulkInsert when some batches failed. The other batches were
+// committed and make up Inserted.
+type BulkError struct {
+	Inserted int64
+	Batches  []BatchError
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("bulk insert: %d batch(es) failed, first %v", len(e.Batches), &e.Batches[0])
+}
+
+// Unwrap returns the errors of the failed batches
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, len(e.Batches))
+	for i := range e.Batches {
+		errs[i] = &e.Batches[i]
+	}
+	return errs
+}
+
+// BulkInsert copies rows into table with COPY, in batches of DefaultBulkBatchSize, and
+// returns the number of rows inserted. Each batch commits on its own, so a bad row only
+// loses its batch; the batches that failed are reported in a *BulkError, and the import
+// stops early if ctx ends. table may be qualified with its schema ("schema.table"); each
+// row holds a value per column.
+func (db *DB) BulkInsert(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return db.BulkInsertWith(ctx, table, columns, rows, BulkOptions{})
+}
+
+// BulkInsertWith is BulkInsert with options
+func (db *DB) BulkInsertWith(ctx context.Context, table string, columns []string, rows [][]interface{}, opts BulkOptions) (int64, error) {
+	if len(columns) == 0 {
+		return 0, errors.New("bulk insert: no columns")
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = DefaultBulkBatchSize
+	}
+	schema, name := "", table
+	if i := strings.IndexByte(table, '.'); i >= 0 {
+		schema, name = table[:i], table[i+1:]
+	}
+
+	var copyBatch func(context.Context, [][]interface{}) (int64, error)
+	if db.pool == nil {
+		copyBatch = db.copyPQ(schema, name, columns)
+	} else {
+		// COPY through the pgx connection itself, reserved for the whole import. It comes
+		// from database/sql so it follows the tenant in ctx like any other query.
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("bulk insert: failed to reserve a connection: %w", err)
+		}
+		defer conn.Close()
+		copyBatch = copyPgx(conn, schema, name, columns)
+	}
+
+	var inserted int64
+	var failed []BatchError
+	var stopped error
+	for batch, offset := 0, 0; offset < len(rows); batch, offset = batch+1, offset+size {
+		if stopped = ctx.Err(); stopped != nil {
+			break
+		}
+		end := min(offset+size, len(rows))
+		n, err := copyBatch(ctx, rows[offset:end])
+		if err != nil {
+			failed = append(failed, BatchError{Batch: batch, Offset: offset, Rows: end - offset, Err: err})
+			if opts.StopOnError {
+				break
+			}
+			continue
+		}
+		inserted += n
+	}
+	if len(failed) > 0 {
+		return inserted, errors.Join(stopped, &BulkError{Inserted: inserted, Batches: failed})
+	}
+	return inserted, stopped
+}
+
+// checkRows makes sure every row of a batch has a value per column
+func checkRows(rows [][]interface{}, columns []string) error {
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d values for %d columns", i, len(row), len(columns))
+		}
+	}
+	return nil
+}
+
+// copyPQ copies a batch in its own transaction with lib/pq's COPY statement
+func (db *DB) copyPQ(schema, table string, columns []string) func(context.Context, [][]interface{}) (int64, error) {
+	query := pq.CopyIn(table, columns...)
+	if schema != "" {
+		query = pq.CopyInSchema(schema, table, columns...)
+	}
+	return func(ctx context.Context, rows [][]interface{}) (int64, error) {
+		if err := checkRows(rows, columns); err != nil {
+			return 0, err
+		}
+		err := db.WithTransaction(ctx, func(tx *sql.Tx) error {
+			stmt, err := tx.PrepareContext(ctx, query)
+			if err != nil {
+				return err
+			}
+			defer stmt.Close()
+			for _, row := range rows {
+				if _, err := stmt.ExecContext(ctx, row...); err != nil {
+					return err
+				}
+			}
+			// An empty Exec flushes the buffered rows and reports errors in any of them
+			_, err = stmt.ExecContext(ctx)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(rows)), nil
+	}
+}
+
+// copyPgx copies a batch with pgx's CopyFrom on conn; a single COPY is atomic without a
+// transaction
+func copyPgx(conn *sql.Conn, schema, table string, columns []string) func(context.Context, [][]interface{}) (int64, error) {
+	identifier := pgx.Identifier{table}
+	if schema != "" {
+		identifier = pgx.Identifier{schema, table}
+	}
+	return func(ctx context.Context, rows [][]interface{}) (n int64, err error) {
+		if err := checkRows(rows, columns); err != nil {
+			return 0, err
+		}
+		err = conn.Raw(func(driverConn interface{}) error {
+			tc, ok := driverConn.(*tenantConn)
+			if !ok {
+				return errors.New("unexpected driver connection")
+			}
+			pgxConn, ok := tc.conn.(interface{ Conn() *pgx.Conn })
+			if !ok {
+				return errors.New("connection is not a pgx connection")
+			}
+			if err := tc.use(ctx); err != nil {
+				return err
+			}
+			n, err = pgxConn.Conn().CopyFrom(ctx, identifier, columns, pgx.CopyFromRows(rows))
+			return err
+		})
+		return n, err
+	}
+}
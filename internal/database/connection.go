@@ -3,50 +3,184 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	pq "github.com/lib/pq"
+
+	"main.go/internal/metrics"
+)
+
+// Drivers accepted by Open (DB_DRIVER)
+const (
+	DriverPQ  = "pq"
+	DriverPgx = "pgx"
 )
 
 // DB represents the database connection
 type DB struct {
 	*sql.DB
+	// pool backs DB when it was opened with the pgx driver
+	pool *pgxpool.Pool
+	// connected is set once the database has answered (see Connected)
+	connected atomic.Bool
+	// statementTimeout bounds statements made without ContextWithStatementTimeout
+	statementTimeout time.Duration
+}
+
+// TxOptions configures a transaction run by WithTransactionOptions
+type TxOptions struct {
+	// Isolation is the isolation level; the zero value is the server default, read committed
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxAttempts bounds how often a transaction failing with a serialization failure or
+	// a deadlock is run; 0 uses DefaultTxAttempts and 1 never retries
+	MaxAttempts int
+	// MaxBackoff caps the wait between attempts; 0 uses DefaultTxMaxBackoff
+	MaxBackoff time.Duration
+}
+
+const (
+	// DefaultTxAttempts is how often WithTransaction runs a transaction that keeps failing
+	// with a serialization failure or a deadlock
+	DefaultTxAttempts = 3
+	// DefaultTxMaxBackoff caps the wait between transaction attempts
+	DefaultTxMaxBackoff = time.Second
+	// txBaseBackoff is the longest wait before the first retry; it doubles per attempt
+	txBaseBackoff = 20 * time.Millisecond
+)
+
+var txRetries = metrics.NewCounter("db", "tx_retries_total", "Transactions retried after a serialization failure or deadlock")
+
+// PoolOptions controls connection pool sizing and the default statement timeout
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// StatementTimeout bounds each statement made through DB; 0 leaves them unbounded
+	StatementTimeout time.Duration
+}
+
+// DefaultPoolOptions is the pool sizing used by NewConnection
+var DefaultPoolOptions = PoolOptions{
+	MaxOpenConns:    25,
+	MaxIdleConns:    25,
+	ConnMaxLifetime: 5 * time.Minute,
 }
 
 // NewConnection creates a new database connection
 func NewConnection(dbURL string) (*DB, error) {
+	return NewConnectionWithPool(dbURL, DefaultPoolOptions)
+}
+
+// NewConnectionWithPool creates a new database connection with custom pool sizing
+func NewConnectionWithPool(dbURL string, pool PoolOptions) (*DB, error) {
+	return Open(DriverPQ, dbURL, pool)
+}
+
+// ErrUnreachable is returned by Open when the database does not answer; the URL and driver
+// were valid, so OpenPending and Reconnect can retry
+var ErrUnreachable = errors.New("database unreachable")
+
+// Open connects with the given driver: pq (database/sql with lib/pq, the default) or pgx
+// (a pgxpool behind database/sql). Either way callers get the same *DB; with pgx, Pgx
+// also exposes the pool for native Postgres types, batches, and COPY.
+func Open(driver, dbURL string, pool PoolOptions) (*DB, error) {
+	db, err := OpenPending(driver, dbURL, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test the connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w: %w", ErrUnreachable, err)
+	}
+
+	db.connected.Store(true)
+	return db, nil
+}
+
+// OpenPending is Open without testing the connection, for a database that may not be
+// reachable yet. The handle is not Connected until Reconnect succeeds; queries fail in
+// the meantime.
+func OpenPending(driver, dbURL string, pool PoolOptions) (*DB, error) {
 	if dbURL == "" {
 		return nil, fmt.Errorf("database URL is required")
 	}
 
+	switch driver {
+	case "", DriverPQ:
+		return openPQ(dbURL, pool)
+	case DriverPgx:
+		return openPgx(dbURL, pool)
+	}
+	return nil, fmt.Errorf("unknown database driver %q (want pq or pgx)", driver)
+}
+
+func openPQ(dbURL string, pool PoolOptions) (*DB, error) {
 	// Parse the PostgreSQL connection string
 	connStr, err := pq.ParseURL(dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database URL: %w", err)
 	}
 
-	// Open database connection
-	db, err := sql.Open("postgres", connStr)
+	// Open database connection; connections follow the tenant of each query's context
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db := sql.OpenDB(&tenantConnector{Connector: connector})
 
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
 
-	// Test the connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	return &DB{DB: db, statementTimeout: pool.StatementTimeout}, nil
+}
 
-	if err := db.PingContext(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+// openPgx sizes the pgxpool from pool. pgxpool keeps idle connections until they have
+// been unused for 30 minutes, so MaxIdleConns does not apply.
+func openPgx(dbURL string, pool PoolOptions) (*DB, error) {
+	config, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	if pool.MaxOpenConns > 0 {
+		config.MaxConns = int32(pool.MaxOpenConns)
 	}
+	if pool.ConnMaxLifetime > 0 {
+		config.MaxConnLifetime = pool.ConnMaxLifetime
+	}
+
+	p, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// As stdlib.OpenDBFromPool does, idle connections go back to the pgxpool rather than
+	// staying in database/sql; they are switched back from any tenant on the way
+	db := sql.OpenDB(&tenantConnector{Connector: stdlib.GetPoolConnector(p), resetOnClose: true})
+	db.SetMaxIdleConns(0)
+	return &DB{DB: db, pool: p, statementTimeout: pool.StatementTimeout}, nil
+}
 
-	return &DB{db}, nil
+// Pgx returns the pgx pool when the database was opened with the pgx driver, and nil
+// with pq. Queries on it share connections with the database/sql methods.
+func (db *DB) Pgx() *pgxpool.Pool {
+	if db == nil {
+		return nil
+	}
+	return db.pool
 }
 
 // HealthCheck performs a health check on the database
@@ -70,10 +204,14 @@ func (db *DB) HealthCheck(ctx context.Context) error {
 
 // Close closes the database connection
 func (db *DB) Close() error {
-	if db != nil && db.DB != nil {
-		return db.DB.Close()
+	if db == nil || db.DB == nil {
+		return nil
 	}
-	return nil
+	err := db.DB.Close()
+	if db.pool != nil {
+		db.pool.Close()
+	}
+	return err
 }
 
 // BeginTx starts a transaction with the provided context
@@ -81,19 +219,21 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 	return db.DB.BeginTx(ctx, opts)
 }
 
-// ExecContext executes a query that doesn't return rows
+// ExecContext executes a query that doesn't return rows, within the statement timeout
 func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.withStatementTimeout(ctx)
+	defer cancel()
 	return db.DB.ExecContext(ctx, query, args...)
 }
 
-// QueryContext executes a query that returns rows
+// QueryContext executes a query that returns rows; the statement timeout covers reading them
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return db.DB.QueryContext(ctx, query, args...)
+	return db.DB.QueryContext(db.queryContext(ctx), query, args...)
 }
 
-// QueryRowContext executes a query that returns a single row
+// QueryRowContext executes a query that returns a single row, within the statement timeout
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return db.DB.QueryRowContext(ctx, query, args...)
+	return db.DB.QueryRowContext(db.queryContext(ctx), query, args...)
 }
 
 // PrepareContext creates a prepared statement
@@ -101,12 +241,57 @@ func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, erro
 	return db.DB.PrepareContext(ctx, query)
 }
 
-// WithTransaction executes a function within a transaction
+// WithTransaction executes a function within a transaction, with the default TxOptions.
+// Each statement in it is bounded by the statement timeout, applied with SET LOCAL, rather
+// than the transaction as a whole.
 func (db *DB) WithTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
-	tx, err := db.BeginTx(ctx, nil)
+	return db.WithTransactionOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTransactionOptions executes a function within a transaction. A transaction failing
+// with a serialization failure or a deadlock, which Postgres expects clients to retry, is
+// rolled back and run again after a randomized wait, so fn must only change state through
+// tx and must not keep results from an earlier attempt.
+func (db *DB) WithTransactionOptions(ctx context.Context, opts TxOptions, fn func(*sql.Tx) error) error {
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultTxAttempts
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultTxMaxBackoff
+	}
+
+	wait := min(txBaseBackoff, maxBackoff)
+	for attempt := 1; ; attempt++ {
+		err := db.transaction(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}, fn)
+		if err == nil || attempt >= attempts || !IsRetryable(err) {
+			return err
+		}
+		txRetries.Inc()
+
+		// Full jitter, so transactions that collided do not collide again
+		timer := time.NewTimer(time.Duration(rand.Int64N(int64(wait)) + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+}
+
+// transaction makes one attempt at running fn in a transaction
+func (db *DB) transaction(ctx context.Context, opts *sql.TxOptions, fn func(*sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	if err := db.setLocalStatementTimeout(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
 
 	defer func() {
 		if p := recover(); p != nil {
@@ -116,12 +301,12 @@ func (db *DB) WithTransaction(ctx context.Context, fn func(*sql.Tx) error) error
 		}
 		if err != nil {
 			_ = tx.Rollback()
-		} else {
-			err = tx.Commit()
+		} else if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed to commit transaction: %w", err)
 		}
 	}()
 
-	if err := fn(tx); err != nil {
+	if err = fn(tx); err != nil {
 		return fmt.Errorf("transaction function failed: %w", err)
 	}
 
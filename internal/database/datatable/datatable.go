@@ -0,0 +1,534 @@
+// Package datatable serves admin tables processed on the server: global and per-column
+// search, multi-column sort, offset paging, and column selection. A store declares the
+// table once, from code:
+//
+//	var usersTable = datatable.Table{
+//		From: "users",
+//		Key:  "id",
+//		Columns: []datatable.Column{
+//			{Name: "id", SQL: "id"},
+//			{Name: "email", SQL: "email", Searchable: true, Sortable: true},
+//			{Name: "created_at", SQL: "created_at", Sortable: true},
+//		},
+//		DefaultSort: []datatable.Sort{{Column: "created_at", Desc: true}},
+//	}
+//
+// and a handler reads the Request with FromQuery and answers with the Result of
+// Table.Query. FromQuery understands the server-side protocol of DataTables (draw, start,
+// length, search[value], order[i][...], columns[i][...]) as well as plain parameters
+// (q, sort=-created_at,email, offset, limit, columns=id,email, filter[email]=...), which
+// suit TanStack Table, AG Grid, MUI DataGrid and the like. Table.WriteCSV writes the same
+// selection, unpaged, as CSV.
+package datatable
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// DefaultLimit is the page size when a request does not ask for one
+	DefaultLimit = 25
+	// MaxLimit bounds the page size; DataTables' "all rows" (length=-1) gets this many
+	MaxLimit = 1000
+)
+
+var (
+	// ErrInvalidLimit is returned for page sizes outside 1..MaxLimit
+	ErrInvalidLimit = fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	// ErrInvalidOffset is returned for negative offsets
+	ErrInvalidOffset = errors.New("offset must not be negative")
+	// ErrUnknownColumn is returned for columns the table does not declare
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrNotSortable is returned for sorts by a column that is not Sortable
+	ErrNotSortable = errors.New("column is not sortable")
+	// ErrNotSearchable is returned for filters on a column that is not Searchable
+	ErrNotSearchable = errors.New("column is not searchable")
+)
+
+// Querier runs the table's queries: a *database.DB, a *sql.DB, or a *sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Column is a column a table may return. SQL is written into queries as is, so it must
+// come from code, never from a request.
+type Column struct {
+	// Name is the column's key in requests and in each row
+	Name string
+	// SQL is the expression selected, e.g. "u.email" or "COALESCE(role, 'user')"
+	SQL string
+	// Searchable columns are matched, as text and case-insensitively, by the search and
+	// by per-column filters
+	Searchable bool
+	// Sortable columns may be sorted by; they should be indexed on large tables
+	Sortable bool
+}
+
+// Sort orders rows by a column
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Table is a query served as a data table
+type Table struct {
+	// From is the FROM clause, joins included
+	From string
+	// Where optionally restricts every row served, with placeholders numbered from $1 and
+	// filled from Args
+	Where string
+	Args  []interface{}
+	// Key names the unique column. It is always returned and ends every sort, so pages
+	// are stable however the rows are sorted.
+	Key     string
+	Columns []Column
+	// DefaultSort applies when a request does not sort
+	DefaultSort []Sort
+}
+
+// Request asks for one page of a table
+type Request struct {
+	// Draw is echoed back to DataTables, which drops stale answers by it; 0 otherwise
+	Draw   int
+	Offset int
+	Limit  int
+	// Search matches rows whose searchable columns contain it
+	Search string
+	// Filters matches rows whose column contains the value, per column
+	Filters map[string]string
+	Sort    []Sort
+	// Columns selects the columns returned; empty returns every column
+	Columns []string
+}
+
+// Result is one page of a table
+type Result struct {
+	// Total counts every row, and Filtered those matching the search and filters
+	Total    int64                    `json:"total"`
+	Filtered int64                    `json:"filtered"`
+	Offset   int                      `json:"offset"`
+	Limit    int                      `json:"limit"`
+	Columns  []string                 `json:"columns"`
+	Rows     []map[string]interface{} `json:"rows"`
+
+	draw int
+}
+
+// DataTables is the result in the shape the DataTables library reads
+type DataTables struct {
+	Draw            int                      `json:"draw"`
+	RecordsTotal    int64                    `json:"recordsTotal"`
+	RecordsFiltered int64                    `json:"recordsFiltered"`
+	Data            []map[string]interface{} `json:"data"`
+}
+
+// DataTables returns the result for a DataTables request, which reads its counts from the
+// top level of the response
+func (r *Result) DataTables() DataTables {
+	return DataTables{Draw: r.draw, RecordsTotal: r.Total, RecordsFiltered: r.Filtered, Data: r.Rows}
+}
+
+// FromQuery reads a request from the query string, in the DataTables protocol when draw
+// is present and from plain parameters otherwise
+func FromQuery(c *fiber.Ctx) (Request, error) {
+	if c.Query("draw") != "" {
+		return fromDataTables(c)
+	}
+
+	req := Request{Limit: DefaultLimit, Search: strings.TrimSpace(c.Query("q")), Filters: map[string]string{}}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLimit {
+			return req, ErrInvalidLimit
+		}
+		req.Limit = n
+	}
+	if value := c.Query("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return req, ErrInvalidOffset
+		}
+		req.Offset = n
+	}
+	req.Sort = ParseSort(c.Query("sort"))
+	for _, name := range strings.Split(c.Query("columns"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			req.Columns = append(req.Columns, name)
+		}
+	}
+	for key, value := range c.Queries() {
+		if name, ok := strings.CutPrefix(key, "filter["); ok && strings.HasSuffix(name, "]") && strings.TrimSpace(value) != "" {
+			req.Filters[strings.TrimSuffix(name, "]")] = strings.TrimSpace(value)
+		}
+	}
+	return req, nil
+}
+
+// ParseSort reads a sort parameter: comma-separated column names, each prefixed with "-"
+// to sort descending
+func ParseSort(value string) []Sort {
+	var sorts []Sort
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		name, desc := strings.CutPrefix(field, "-")
+		sorts = append(sorts, Sort{Column: name, Desc: desc})
+	}
+	return sorts
+}
+
+// FormatSort writes sorts as a sort parameter, the inverse of ParseSort
+func FormatSort(sorts []Sort) string {
+	fields := make([]string, len(sorts))
+	for i, s := range sorts {
+		fields[i] = s.Column
+		if s.Desc {
+			fields[i] = "-" + s.Column
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// fromDataTables reads the parameters DataTables sends with serverSide: true
+func fromDataTables(c *fiber.Ctx) (Request, error) {
+	req := Request{Limit: DefaultLimit, Search: strings.TrimSpace(c.Query("search[value]")), Filters: map[string]string{}}
+	req.Draw, _ = strconv.Atoi(c.Query("draw"))
+	if value := c.Query("length"); value != "" {
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil || n == 0 || n < -1 || n > MaxLimit:
+			return req, ErrInvalidLimit
+		case n == -1:
+			n = MaxLimit
+		}
+		req.Limit = n
+	}
+	if value := c.Query("start"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return req, ErrInvalidOffset
+		}
+		req.Offset = n
+	}
+
+	// columns[i][data] names the columns displayed, which order[j][column] refers to by index
+	for i := 0; ; i++ {
+		prefix := "columns[" + strconv.Itoa(i) + "]"
+		name := c.Query(prefix + "[data]")
+		if name == "" {
+			break
+		}
+		req.Columns = append(req.Columns, name)
+		if value := strings.TrimSpace(c.Query(prefix + "[search][value]")); value != "" {
+			req.Filters[name] = value
+		}
+	}
+	for i := 0; ; i++ {
+		prefix := "order[" + strconv.Itoa(i) + "]"
+		value := c.Query(prefix + "[column]")
+		if value == "" {
+			break
+		}
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(req.Columns) {
+			return req, fmt.Errorf("%w: order[%d]", ErrUnknownColumn, i)
+		}
+		req.Sort = append(req.Sort, Sort{Column: req.Columns[index], Desc: c.Query(prefix+"[dir]") == "desc"})
+	}
+	return req, nil
+}
+
+// plan is a request resolved against the table's declared columns
+type plan struct {
+	selected []Column
+	orderBy  string
+	// base restricts every row and where adds the search and filters; args fill both
+	base, where string
+	args        []interface{}
+}
+
+// plan checks req and builds the clauses of its queries
+func (t Table) plan(req Request) (*plan, error) {
+	selected, err := t.selected(req.Columns)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := t.orderBy(req.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	base := "TRUE"
+	if t.Where != "" {
+		base = "(" + t.Where + ")"
+	}
+	args := append([]interface{}{}, t.Args...)
+	where, args, err := t.filter(base, args, req)
+	if err != nil {
+		return nil, err
+	}
+	return &plan{selected: selected, orderBy: orderBy, base: base, where: where, args: args}, nil
+}
+
+// selectSQL returns the query for the plan's rows, before any LIMIT
+func (t Table) selectSQL(p *plan) string {
+	expressions := make([]string, len(p.selected))
+	for i, col := range p.selected {
+		expressions[i] = col.SQL
+	}
+	return "SELECT " + strings.Join(expressions, ", ") + " FROM " + t.From + " WHERE " + p.where + " ORDER BY " + p.orderBy
+}
+
+// scanRow reads one row of the plan's columns, with text columns as strings
+func scanRow(rows *sql.Rows, n int) ([]interface{}, error) {
+	values := make([]interface{}, n)
+	pointers := make([]interface{}, n)
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+	return values, nil
+}
+
+// Query returns the page of the table req asks for
+func (t Table) Query(ctx context.Context, db Querier, req Request) (*Result, error) {
+	limit := req.Limit
+	if limit < 1 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	offset := max(req.Offset, 0)
+
+	p, err := t.plan(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Offset: offset, Limit: limit, Rows: []map[string]interface{}{}, draw: req.Draw}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+t.From+" WHERE "+p.base, t.Args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("failed to count table rows: %w", err)
+	}
+	result.Filtered = result.Total
+	if len(p.args) > len(t.Args) {
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+t.From+" WHERE "+p.where, p.args...).Scan(&result.Filtered); err != nil {
+			return nil, fmt.Errorf("failed to count table rows: %w", err)
+		}
+	}
+
+	for _, col := range p.selected {
+		result.Columns = append(result.Columns, col.Name)
+	}
+	n := len(p.args)
+	rows, err := db.QueryContext(ctx, t.selectSQL(p)+" LIMIT $"+strconv.Itoa(n+1)+" OFFSET $"+strconv.Itoa(n+2), append(p.args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table rows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		values, err := scanRow(rows, len(p.selected))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan table row: %w", err)
+		}
+		row := make(map[string]interface{}, len(p.selected))
+		for i, col := range p.selected {
+			row[col.Name] = values[i]
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
+
+// WriteCSV writes every row req selects, searched, filtered, and sorted but not paged, to
+// w as CSV under a header of column names. It stops after limit rows when limit is
+// positive and returns how many rows were written.
+func (t Table) WriteCSV(ctx context.Context, db Querier, req Request, w io.Writer, limit int) (int64, error) {
+	p, err := t.plan(req)
+	if err != nil {
+		return 0, err
+	}
+	query, args := t.selectSQL(p), p.args
+	if limit > 0 {
+		query += " LIMIT $" + strconv.Itoa(len(args)+1)
+		args = append(args, limit)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load table rows: %w", err)
+	}
+	defer rows.Close()
+
+	out := csv.NewWriter(w)
+	record := make([]string, len(p.selected))
+	for i, col := range p.selected {
+		record[i] = col.Name
+	}
+	if err := out.Write(record); err != nil {
+		return 0, err
+	}
+	var written int64
+	for rows.Next() {
+		values, err := scanRow(rows, len(p.selected))
+		if err != nil {
+			return written, fmt.Errorf("failed to scan table row: %w", err)
+		}
+		for i, v := range values {
+			record[i] = csvCell(v)
+		}
+		if err := out.Write(record); err != nil {
+			return written, err
+		}
+		written++
+	}
+	if err := rows.Err(); err != nil {
+		return written, err
+	}
+	out.Flush()
+	return written, out.Error()
+}
+
+// csvCell formats a value for a CSV cell. Text that a spreadsheet would read as a formula
+// is prefixed with a quote, so opening an export cannot run what users typed.
+func csvCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Validate checks the columns, sort, and filters of req against the table's declared
+// columns without querying, e.g. before storing them for later
+func (t Table) Validate(req Request) error {
+	_, err := t.plan(req)
+	return err
+}
+
+// column returns the declared column named name
+func (t Table) column(name string) (Column, bool) {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// selected resolves the requested columns, adding the key column
+func (t Table) selected(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		return t.Columns, nil
+	}
+	var cols []Column
+	seen := map[string]bool{}
+	for _, name := range append(names, t.Key) {
+		if seen[name] {
+			continue
+		}
+		col, ok := t.column(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, name)
+		}
+		seen[name] = true
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// orderBy returns the ORDER BY list for sorts, ending with the key column
+func (t Table) orderBy(sorts []Sort) (string, error) {
+	if len(sorts) == 0 {
+		sorts = t.DefaultSort
+	}
+	var parts []string
+	keySorted := false
+	for _, s := range sorts {
+		col, ok := t.column(s.Column)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownColumn, s.Column)
+		}
+		if !col.Sortable && col.Name != t.Key {
+			return "", fmt.Errorf("%w: %s", ErrNotSortable, s.Column)
+		}
+		part := col.SQL
+		if s.Desc {
+			part += " DESC"
+		}
+		parts = append(parts, part)
+		keySorted = keySorted || col.Name == t.Key
+	}
+	if !keySorted {
+		key, _ := t.column(t.Key)
+		parts = append(parts, key.SQL)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// filter adds the search and per-column filters to the base predicate
+func (t Table) filter(base string, args []interface{}, req Request) (string, []interface{}, error) {
+	terms := []string{base}
+	if req.Search != "" {
+		args = append(args, contains(req.Search))
+		placeholder := "$" + strconv.Itoa(len(args))
+		var matches []string
+		for _, col := range t.Columns {
+			if col.Searchable {
+				matches = append(matches, "("+col.SQL+")::text ILIKE "+placeholder)
+			}
+		}
+		if len(matches) == 0 {
+			matches = []string{"FALSE"}
+		}
+		terms = append(terms, "("+strings.Join(matches, " OR ")+")")
+	}
+	names := make([]string, 0, len(req.Filters))
+	for name := range req.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := req.Filters[name]
+		col, ok := t.column(name)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s", ErrUnknownColumn, name)
+		}
+		if !col.Searchable {
+			return "", nil, fmt.Errorf("%w: %s", ErrNotSearchable, name)
+		}
+		args = append(args, contains(value))
+		terms = append(terms, "("+col.SQL+")::text ILIKE $"+strconv.Itoa(len(args)))
+	}
+	return strings.Join(terms, " AND "), args, nil
+}
+
+// contains returns the ILIKE pattern matching value anywhere, with its wildcards escaped
+func contains(value string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+}
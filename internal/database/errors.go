@@ -0,0 +1,40 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	pq "github.com/lib/pq"
+)
+
+// PostgreSQL error codes
+const (
+	uniqueViolation      = "23505"
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// IsUniqueViolation reports whether err is a unique constraint violation, from either driver
+func IsUniqueViolation(err error) bool {
+	return errorCode(err) == uniqueViolation
+}
+
+// IsRetryable reports whether err is a serialization failure or a deadlock: the
+// transaction was rolled back and succeeds when run again, which WithTransaction does
+func IsRetryable(err error) bool {
+	code := errorCode(err)
+	return code == serializationFailure || code == deadlockDetected
+}
+
+// errorCode returns the SQLSTATE of a Postgres error from lib/pq or pgx
+func errorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Finding is a dangerous operation detected in a migration
+type Finding struct {
+	Migration string `json:"migration"`
+	Line      int    `json:"line"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+// String formats the finding for CLI and log output
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d [%s] %s", f.Migration, f.Line, f.Rule, f.Message)
+}
+
+// lintRule matches a single statement that can lose data or lock a busy table
+type lintRule struct {
+	name    string
+	pattern *regexp.Regexp
+	message string
+}
+
+var lintRules = []lintRule{
+	{
+		name:    "drop-table",
+		pattern: regexp.MustCompile(`(?i)\bDROP\s+TABLE\b`),
+		message: "dropping a table permanently deletes its data",
+	},
+	{
+		name:    "drop-column",
+		pattern: regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`),
+		message: "dropping a column permanently deletes its data and breaks running app versions that still read it",
+	},
+	{
+		name:    "drop-schema",
+		pattern: regexp.MustCompile(`(?i)\bDROP\s+SCHEMA\b`),
+		message: "dropping a schema deletes every object inside it",
+	},
+	{
+		name:    "truncate",
+		pattern: regexp.MustCompile(`(?i)\bTRUNCATE\b`),
+		message: "truncate deletes every row in the table",
+	},
+	{
+		name:    "delete-without-where",
+		pattern: regexp.MustCompile(`(?is)\bDELETE\s+FROM\s+[^;]*?;`),
+		message: "DELETE without a WHERE clause removes every row",
+	},
+	{
+		name:    "alter-column-type",
+		pattern: regexp.MustCompile(`(?i)\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b`),
+		message: "changing a column type rewrites the table under an ACCESS EXCLUSIVE lock",
+	},
+	{
+		name:    "set-not-null",
+		pattern: regexp.MustCompile(`(?i)\bSET\s+NOT\s+NULL\b`),
+		message: "SET NOT NULL scans the whole table under an ACCESS EXCLUSIVE lock",
+	},
+	{
+		name:    "rename",
+		pattern: regexp.MustCompile(`(?i)\bRENAME\s+(COLUMN\s+|TO\b)`),
+		message: "renaming breaks running app versions that still use the old name",
+	},
+}
+
+var (
+	lineComment    = regexp.MustCompile(`--[^\n]*`)
+	blockComment   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	whereClause    = regexp.MustCompile(`(?i)\bWHERE\b`)
+	createTable    = regexp.MustCompile(`(?i)\bCREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w."]+)`)
+	createIndex    = regexp.MustCompile(`(?i)\bCREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?[\w"]*\s*ON\s+(?:ONLY\s+)?([\w."]+)`)
+	identifierTrim = strings.NewReplacer(`"`, "")
+)
+
+// Lint scans a migration for destructive or table-locking operations.
+// Indexes on tables created in the same migration are allowed without CONCURRENTLY
+// because the table is still empty.
+func Lint(m Migration) []Finding {
+	sql := stripComments(m.SQL)
+	findings := []Finding{}
+
+	for _, rule := range lintRules {
+		for _, loc := range rule.pattern.FindAllStringIndex(sql, -1) {
+			statement := sql[loc[0]:loc[1]]
+			if rule.name == "delete-without-where" && whereClause.MatchString(statement) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Migration: m.Name,
+				Line:      lineOf(sql, loc[0]),
+				Rule:      rule.name,
+				Message:   rule.message,
+			})
+		}
+	}
+
+	created := map[string]bool{}
+	for _, match := range createTable.FindAllStringSubmatch(sql, -1) {
+		created[normalizeIdentifier(match[1])] = true
+	}
+
+	for _, loc := range createIndex.FindAllStringSubmatchIndex(sql, -1) {
+		concurrently := loc[2] != -1
+		table := normalizeIdentifier(sql[loc[4]:loc[5]])
+		if concurrently || created[table] {
+			continue
+		}
+		findings = append(findings, Finding{
+			Migration: m.Name,
+			Line:      lineOf(sql, loc[0]),
+			Rule:      "index-not-concurrent",
+			Message:   fmt.Sprintf("CREATE INDEX on existing table %q blocks writes; use CREATE INDEX CONCURRENTLY outside a transaction", table),
+		})
+	}
+
+	return findings
+}
+
+// LintAll lints every migration and returns the combined findings
+func LintAll(migrations []Migration) []Finding {
+	findings := []Finding{}
+	for _, m := range migrations {
+		findings = append(findings, Lint(m)...)
+	}
+	return findings
+}
+
+// stripComments blanks out SQL comments while preserving line numbers
+func stripComments(sql string) string {
+	blank := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '\n' {
+				return r
+			}
+			return ' '
+		}, s)
+	}
+	sql = blockComment.ReplaceAllStringFunc(sql, blank)
+	return lineComment.ReplaceAllStringFunc(sql, blank)
+}
+
+func lineOf(sql string, offset int) int {
+	return strings.Count(sql[:offset], "\n") + 1
+}
+
+func normalizeIdentifier(name string) string {
+	name = strings.ToLower(identifierTrim.Replace(name))
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
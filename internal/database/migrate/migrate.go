@@ -0,0 +1,212 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"main.go/internal/database"
+)
+
+// lockName is the advisory lock held while migrations are applied
+const lockName = "schema_migrations"
+
+// upSuffix matches the file naming used by cmds/migrate.sh (<timestamp>_<name>_up.sql)
+const upSuffix = "_up.sql"
+
+var (
+	// txStatement matches a file's own BEGIN; and COMMIT; lines, which the runner replaces
+	// with its transaction so the migration and its schema_migrations row commit together
+	txStatement = regexp.MustCompile(`(?im)^[ \t]*(BEGIN|COMMIT)[ \t]*;[ \t]*$`)
+	// concurrently marks migrations that cannot run inside a transaction
+	concurrently = regexp.MustCompile(`(?i)\bCONCURRENTLY\b`)
+)
+
+// Migration is a single forward migration file
+type Migration struct {
+	Name string
+	Path string
+	SQL  string
+}
+
+// Options controls how pending migrations are applied
+type Options struct {
+	// AllowDestructive applies migrations even when the linter reports findings
+	AllowDestructive bool
+	// DryRun lints and reports pending migrations without executing them
+	DryRun bool
+}
+
+// DestructiveError is returned when pending migrations contain dangerous operations
+// and Options.AllowDestructive is false
+type DestructiveError struct {
+	Findings []Finding
+}
+
+// Error implements the error interface
+func (e *DestructiveError) Error() string {
+	lines := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		lines = append(lines, f.String())
+	}
+	return fmt.Sprintf("refusing to apply destructive migrations without --allow-destructive:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// Load reads every *_up.sql file in dir, sorted by name
+func Load(dir string) ([]Migration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+upSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	sort.Strings(paths)
+
+	migrations := make([]Migration, 0, len(paths))
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", path, err)
+		}
+		migrations = append(migrations, Migration{
+			Name: strings.TrimSuffix(filepath.Base(path), upSuffix),
+			Path: path,
+			SQL:  string(contents),
+		})
+	}
+	return migrations, nil
+}
+
+// Runner applies migrations and records them in schema_migrations
+type Runner struct {
+	db *database.DB
+}
+
+// NewRunner creates a new migration runner
+func NewRunner(db *database.DB) *Runner {
+	return &Runner{db: db}
+}
+
+// Pending returns the migrations that have not been applied yet
+func (r *Runner) Pending(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	if err := r.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, m := range migrations {
+		if !applied[m.Name] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up lints and applies every pending migration in order, returning the applied ones.
+// Destructive findings abort the run before anything executes unless explicitly allowed.
+// Runs are serialized by an advisory lock, so replicas migrating on boot wait for the
+// first and then find nothing pending. Migrations are not bound by the statement timeout.
+func (r *Runner) Up(ctx context.Context, migrations []Migration, opts Options) (applied []Migration, err error) {
+	ctx = database.ContextWithStatementTimeout(ctx, 0)
+	err = r.db.WithAdvisoryLock(ctx, lockName, func(ctx context.Context) error {
+		applied, err = r.up(ctx, migrations, opts)
+		return err
+	})
+	return applied, err
+}
+
+func (r *Runner) up(ctx context.Context, migrations []Migration, opts Options) ([]Migration, error) {
+	pending, err := r.Pending(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	if findings := LintAll(pending); len(findings) > 0 && !opts.AllowDestructive {
+		return nil, &DestructiveError{Findings: findings}
+	}
+
+	if opts.DryRun {
+		return pending, nil
+	}
+
+	// Migrations run one after another on a connection of their own, as under psql
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve a connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	applied := make([]Migration, 0, len(pending))
+	for _, m := range pending {
+		if err := apply(ctx, conn, m); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// apply runs a migration and records it in one transaction, so a failure leaves neither
+// behind. Migrations using CONCURRENTLY cannot run in a transaction; they run as written
+// and are recorded afterwards.
+func apply(ctx context.Context, conn *sql.Conn, m Migration) error {
+	if concurrently.MatchString(stripComments(m.SQL)) {
+		if _, err := conn.ExecContext(ctx, m.SQL); err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.Name, err)
+		}
+		if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.Name); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+		}
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", m.Name, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, txStatement.ReplaceAllString(m.SQL, "")); err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.Name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+func (r *Runner) ensureTable(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
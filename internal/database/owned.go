@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pq "github.com/lib/pq"
+)
+
+// ErrNoOwner is returned by Owned queries made with a context naming no owner and
+// carrying no privilege, so a forgotten scope fails instead of reaching every row
+var ErrNoOwner = errors.New("no owner in context for an owned query")
+
+type privilegeContextKey struct{}
+
+// ContextWithPrivilege marks ctx as acting for every owner, e.g. for admin tools and
+// background jobs, so Owned queries made with it are not scoped
+func ContextWithPrivilege(ctx context.Context) context.Context {
+	return context.WithValue(ctx, privilegeContextKey{}, true)
+}
+
+// IsPrivileged reports whether ctx was marked by ContextWithPrivilege
+func IsPrivileged(ctx context.Context) bool {
+	privileged, _ := ctx.Value(privilegeContextKey{}).(bool)
+	return privileged
+}
+
+// OwnerFromContext returns who ctx acts for: the Scope attached to it, with the tenant
+// from ContextWithTenant when the scope names none
+func OwnerFromContext(ctx context.Context) Scope {
+	scope, _ := ScopeFromContext(ctx)
+	if scope.TenantID == "" {
+		if t, ok := TenantFromContext(ctx); ok {
+			scope.TenantID = t.ID
+		}
+	}
+	return scope
+}
+
+// Querier runs queries: a *DB or a *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Owned describes a table whose rows belong to a user, a tenant, or both. Its queries
+// only reach rows owned by the context's owner (see OwnerFromContext), so a handler
+// passing an ID from the URL cannot read or change another owner's row; unless the
+// context is privileged, a query without an owner fails with ErrNoOwner. Declare the
+// table once, from code:
+//
+//	var ownedViews = database.Owned{Table: "saved_views", UserColumn: "user_id"}
+//
+//	err := ownedViews.Get(ctx, db, "id, name", id, &v.ID, &v.Name)
+type Owned struct {
+	Table string
+	// Key is the primary key column; "id" when empty
+	Key string
+	// UserColumn holds the owning user's ID; empty when rows are not owned by users
+	UserColumn string
+	// TenantColumn holds the owning tenant's ID; empty when rows are not owned by tenants
+	TenantColumn string
+}
+
+func (o Owned) key() string {
+	if o.Key == "" {
+		return "id"
+	}
+	return o.Key
+}
+
+// Where returns the condition limiting a query to the context's owner, with placeholders
+// numbered from next, and the arguments for them. Privileged contexts get "TRUE".
+//
+//	cond, ownerArgs, err := ownedViews.Where(ctx, 2)
+//	rows, err := db.QueryContext(ctx, `SELECT ... FROM saved_views WHERE resource = $1 AND `+cond,
+//		append([]interface{}{resource}, ownerArgs...)...)
+func (o Owned) Where(ctx context.Context, next int) (string, []interface{}, error) {
+	if IsPrivileged(ctx) {
+		return "TRUE", nil, nil
+	}
+	if o.UserColumn == "" && o.TenantColumn == "" {
+		return "", nil, fmt.Errorf("%s: no owner column declared", o.Table)
+	}
+	columns, args, err := o.Owner(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	conds := make([]string, len(columns))
+	for i, column := range columns {
+		conds[i] = pq.QuoteIdentifier(column) + " = $" + strconv.Itoa(next+i)
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// Owner returns the owner columns (user, then tenant) and the context's values for them,
+// e.g. to insert a new row
+func (o Owned) Owner(ctx context.Context) ([]string, []interface{}, error) {
+	owner := OwnerFromContext(ctx)
+	var columns []string
+	var values []interface{}
+	for _, c := range []struct{ column, id string }{
+		{o.UserColumn, owner.UserID},
+		{o.TenantColumn, owner.TenantID},
+	} {
+		if c.column == "" {
+			continue
+		}
+		if c.id == "" {
+			return nil, nil, fmt.Errorf("%w: %s.%s", ErrNoOwner, o.Table, c.column)
+		}
+		columns = append(columns, c.column)
+		values = append(values, c.id)
+	}
+	return columns, values, nil
+}
+
+// Get scans columns of the row with the given key into dest. Rows of another owner
+// return sql.ErrNoRows, the same as missing ones, so their existence is not revealed.
+func (o Owned) Get(ctx context.Context, q Querier, columns string, id interface{}, dest ...interface{}) error {
+	cond, args, err := o.Where(ctx, 2)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s = $1 AND %s`,
+		columns, pq.QuoteIdentifier(o.Table), pq.QuoteIdentifier(o.key()), cond)
+	return q.QueryRowContext(ctx, query, append([]interface{}{id}, args...)...).Scan(dest...)
+}
+
+// Update applies set (e.g. "name = $1, updated_at = NOW()", numbered from $1 for args)
+// to the row with the given key and reports whether an owned row was updated
+func (o Owned) Update(ctx context.Context, q Querier, id interface{}, set string, args ...interface{}) (bool, error) {
+	cond, ownerArgs, err := o.Where(ctx, len(args)+2)
+	if err != nil {
+		return false, err
+	}
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE %s = $%d AND %s`,
+		pq.QuoteIdentifier(o.Table), set, pq.QuoteIdentifier(o.key()), len(args)+1, cond)
+	args = append(append(args, id), ownerArgs...)
+	return affected(q.ExecContext(ctx, query, args...))
+}
+
+// Delete deletes the row with the given key and reports whether an owned row was deleted
+func (o Owned) Delete(ctx context.Context, q Querier, id interface{}) (bool, error) {
+	cond, args, err := o.Where(ctx, 2)
+	if err != nil {
+		return false, err
+	}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1 AND %s`,
+		pq.QuoteIdentifier(o.Table), pq.QuoteIdentifier(o.key()), cond)
+	return affected(q.ExecContext(ctx, query, append([]interface{}{id}, args...)...))
+}
+
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,320 @@
+// Package pagination pages through large tables by keyset instead of OFFSET. A page
+// continues after the sort key of the previous page's last row, so every page costs one
+// index range scan however deep it is, and rows inserted meanwhile do not shift pages.
+//
+// A list declares its Order, ending with a unique column, and a cursor type holding that
+// row's key values:
+//
+//	var order = pagination.Order{pagination.Desc("created_at"), pagination.Desc("id")}
+//
+//	type cursor struct {
+//		CreatedAt time.Time `json:"c"`
+//		ID        string    `json:"i"`
+//	}
+//
+//	func (c cursor) Values() []interface{} { return []interface{}{c.CreatedAt, c.ID} }
+//
+// The store builds its query from a Request with Build, scans up to Query.Limit rows,
+// and hands them to NewPage; the handler reads the Request with FromQuery.
+//
+// With encryption keys configured (crypto.Default), cursors are encrypted and
+// authenticated, so clients can neither read the key values in them nor move a page
+// boundary by editing one. Without keys they are signed with the secret given to
+// SetSecret, so they stay tamper-proof though readable; with neither, Encode fails with
+// ErrNoCursorKey rather than issue a cursor clients could edit.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/crypto"
+)
+
+const (
+	// DefaultLimit is the page size when a request does not ask for one
+	DefaultLimit = 50
+	// MaxLimit bounds the page size a request may ask for
+	MaxLimit = 500
+)
+
+var (
+	// ErrInvalidCursor is returned for cursors that were not issued for the list's order
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	// ErrInvalidLimit is returned for page sizes outside 1..MaxLimit
+	ErrInvalidLimit = fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	// ErrNoCursorKey is returned by Encode and Decode when there is neither a default
+	// keyring nor a secret to protect cursors with
+	ErrNoCursorKey = errors.New("pagination cursors need ENCRYPTION_KEYS or AUTH_SECRET")
+)
+
+var (
+	secretMu sync.RWMutex
+	secret   []byte
+)
+
+// SetSecret sets the key cursors are signed with when no keyring is configured (main
+// sets it from AUTH_SECRET)
+func SetSecret(s string) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	secret = []byte(s)
+}
+
+func signingSecret() []byte {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	return secret
+}
+
+// Key is one column of a keyset order. Column is written into SQL as is, so it must come
+// from code, never from a request.
+type Key struct {
+	Column string
+	Desc   bool
+}
+
+// Asc sorts by column in ascending order
+func Asc(column string) Key {
+	return Key{Column: column}
+}
+
+// Desc sorts by column in descending order
+func Desc(column string) Key {
+	return Key{Column: column, Desc: true}
+}
+
+// Order is a keyset sort. Its last key must be unique, typically the primary key, so
+// every row has a distinct position; keyed columns should be NOT NULL and indexed in
+// this order.
+type Order []Key
+
+// OrderBy returns the ORDER BY list, e.g. "created_at DESC, id DESC"
+func (o Order) OrderBy() string {
+	parts := make([]string, len(o))
+	for i, k := range o {
+		parts[i] = k.Column
+		if k.Desc {
+			parts[i] += " DESC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// After returns the predicate for rows after values, numbering placeholders from
+// $first. A uniform direction is written as a row comparison, which Postgres answers
+// from a matching index; mixed directions expand into one branch per key.
+func (o Order) After(values []interface{}, first int) (string, []interface{}) {
+	placeholder := func(i int) string { return "$" + strconv.Itoa(first+i) }
+
+	uniform := true
+	for _, k := range o[1:] {
+		uniform = uniform && k.Desc == o[0].Desc
+	}
+	if uniform {
+		columns, params := make([]string, len(o)), make([]string, len(o))
+		for i, k := range o {
+			columns[i], params[i] = k.Column, placeholder(i)
+		}
+		op := ">"
+		if o[0].Desc {
+			op = "<"
+		}
+		return "(" + strings.Join(columns, ", ") + ") " + op + " (" + strings.Join(params, ", ") + ")", values
+	}
+
+	// (a > $1) OR (a = $1 AND b < $2) OR ...
+	branches := make([]string, len(o))
+	for i, k := range o {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, o[j].Column+" = "+placeholder(j))
+		}
+		op := ">"
+		if k.Desc {
+			op = "<"
+		}
+		terms = append(terms, k.Column+" "+op+" "+placeholder(i))
+		branches[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return "(" + strings.Join(branches, " OR ") + ")", values
+}
+
+// signature identifies the order, so a cursor from another list or sort is rejected
+func (o Order) signature() string {
+	return o.OrderBy()
+}
+
+// Cursor is the sort key of a row, in the Order's column order
+type Cursor interface {
+	Values() []interface{}
+}
+
+// envelope is the encoded form of a cursor
+type envelope[C Cursor] struct {
+	Order string `json:"o"`
+	Key   C      `json:"k"`
+}
+
+// Encode returns the opaque cursor for key, bound to the order. With the default keyring
+// it is encrypted; without one it is base64-encoded JSON followed by an HMAC-SHA256 with
+// the SetSecret key. With neither it fails with ErrNoCursorKey rather than issue a
+// cursor clients could edit.
+func Encode[C Cursor](o Order, key C) (string, error) {
+	data, err := json.Marshal(envelope[C]{Order: o.signature(), Key: key})
+	if err != nil {
+		return "", err
+	}
+	if k := crypto.Default(); k != nil {
+		return k.Encrypt(data, cursorAssociatedData(o))
+	}
+	s := signingSecret()
+	if len(s) == 0 {
+		return "", ErrNoCursorKey
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(cursorMAC(s, o, data)), nil
+}
+
+// Decode reads a cursor made by Encode for the same order. Cursors that were not
+// encrypted or signed with a current key are rejected, as are signed ones once keys
+// are configured.
+func Decode[C Cursor](o Order, cursor string) (C, error) {
+	var env envelope[C]
+	var data []byte
+	var err error
+	if k := crypto.Default(); k != nil {
+		data, err = k.Decrypt(cursor, cursorAssociatedData(o))
+	} else {
+		s := signingSecret()
+		if len(s) == 0 {
+			return env.Key, ErrNoCursorKey
+		}
+		data, err = verifyCursor(s, o, cursor)
+	}
+	if err != nil || json.Unmarshal(data, &env) != nil || env.Order != o.signature() || len(env.Key.Values()) != len(o) {
+		return env.Key, ErrInvalidCursor
+	}
+	return env.Key, nil
+}
+
+// verifyCursor returns the JSON of a signed cursor whose MAC matches
+func verifyCursor(secret []byte, o Order, cursor string) ([]byte, error) {
+	payload, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, cursorMAC(secret, o, data)) {
+		return nil, ErrInvalidCursor
+	}
+	return data, nil
+}
+
+// cursorMAC signs a cursor's JSON together with its associated data
+func cursorMAC(secret []byte, o Order, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(cursorAssociatedData(o))
+	mac.Write([]byte{0})
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// cursorAssociatedData binds cursors to pagination and to the order, so neither other
+// encrypted or signed values nor another list's cursors are accepted as one
+func cursorAssociatedData(o Order) []byte {
+	return []byte("pagination:" + o.signature())
+}
+
+// Request asks for one page: up to Limit rows after the After cursor ("" for the first page)
+type Request struct {
+	Limit int
+	After string
+}
+
+// FromQuery reads the limit and after query parameters; limit defaults to defaultLimit
+func FromQuery(c *fiber.Ctx, defaultLimit int) (Request, error) {
+	req := Request{Limit: defaultLimit, After: c.Query("after")}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MaxLimit {
+			return req, ErrInvalidLimit
+		}
+		req.Limit = n
+	}
+	return req, nil
+}
+
+// Query holds the fragments of a page's SQL
+type Query struct {
+	// Where is the keyset predicate; TRUE on the first page
+	Where   string
+	OrderBy string
+	// Limit is one more than the page size, so NewPage can tell whether another page follows
+	Limit int
+	// Args are the Where placeholders' values
+	Args []interface{}
+}
+
+// Build returns the fragments for req, numbering placeholders from $first so they can
+// follow the query's own parameters
+func Build[C Cursor](o Order, req Request, first int) (Query, error) {
+	limit := req.Limit
+	if limit < 1 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	q := Query{Where: "TRUE", OrderBy: o.OrderBy(), Limit: limit + 1}
+	if req.After == "" {
+		return q, nil
+	}
+	key, err := Decode[C](o, req.After)
+	if err != nil {
+		return q, err
+	}
+	q.Where, q.Args = o.After(key.Values(), first)
+	return q, nil
+}
+
+// Page is one page of a list
+type Page[T any] struct {
+	Items []T `json:"items" fields:"items"`
+	// NextCursor continues the list; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// NewPage trims rows fetched with Query.Limit to the page size and sets the cursor of the
+// next page from the last row kept
+func NewPage[T any, C Cursor](o Order, q Query, rows []T, key func(T) C) (*Page[T], error) {
+	page := &Page[T]{Items: rows}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(rows) < q.Limit {
+		return page, nil
+	}
+	page.Items = rows[:q.Limit-1]
+	page.HasMore = true
+	cursor, err := Encode(o, key(page.Items[len(page.Items)-1]))
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = cursor
+	return page, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectOptions controls Reconnect's retries
+type ReconnectOptions struct {
+	// Initial is the wait after the first failed ping; it doubles after each failure up
+	// to Max. Defaults to 1s and 1m.
+	Initial time.Duration
+	Max     time.Duration
+	// OnRetry, when set, is told about each failed attempt and the wait before the next
+	OnRetry func(attempt int, err error, wait time.Duration)
+	// OnConnect, when set, runs once the database answers and before it is marked
+	// connected, e.g. to apply migrations. An error ends Reconnect and leaves the
+	// database disconnected.
+	OnConnect func(ctx context.Context) error
+}
+
+// Connected reports whether the database has answered since it was opened. It is true
+// after Open, and after Reconnect succeeds for a handle from OpenPending.
+func (db *DB) Connected() bool {
+	return db != nil && db.connected.Load()
+}
+
+// Reconnect pings the database with exponential backoff until it answers, then runs
+// OnConnect and marks it connected. It returns nil once connected, OnConnect's error, or
+// ctx's error when ctx ends first.
+func (db *DB) Reconnect(ctx context.Context, opts ReconnectOptions) error {
+	if opts.Initial <= 0 {
+		opts.Initial = time.Second
+	}
+	if opts.Max < opts.Initial {
+		opts.Max = max(time.Minute, opts.Initial)
+	}
+
+	wait := opts.Initial
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Up to 20% jitter so instances started together do not retry in lockstep
+		delay := wait + time.Duration(rand.Int64N(int64(wait)/5+1))
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait = min(wait*2, opts.Max)
+	}
+
+	if opts.OnConnect != nil {
+		if err := opts.OnConnect(ctx); err != nil {
+			return err
+		}
+	}
+	db.connected.Store(true)
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Scope identifies who a transaction runs on behalf of. Its values are exposed to
+// Postgres row-level security policies as current_setting('app.tenant_id') and
+// current_setting('app.user_id').
+type Scope struct {
+	TenantID string
+	UserID   string
+}
+
+type scopeContextKey struct{}
+
+// ContextWithScope attaches a scope to ctx so WithScopedTransaction can pick it up
+func ContextWithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeContextKey{}, scope)
+}
+
+// ScopeFromContext returns the scope attached to ctx, if any
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeContextKey{}).(Scope)
+	return scope, ok
+}
+
+// SetScope applies the scope as transaction-local settings (the equivalent of
+// SET LOCAL, which cannot take bind parameters). The settings vanish on commit/rollback,
+// so pooled connections never leak one tenant's scope into another request.
+func SetScope(ctx context.Context, tx *sql.Tx, scope Scope) error {
+	_, err := tx.ExecContext(ctx,
+		`SELECT set_config('app.tenant_id', $1, true), set_config('app.user_id', $2, true)`,
+		scope.TenantID, scope.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to apply row-level security scope: %w", err)
+	}
+	return nil
+}
+
+// WithScopedTransaction runs fn in a transaction with the given RLS scope applied
+func (db *DB) WithScopedTransaction(ctx context.Context, scope Scope, fn func(*sql.Tx) error) error {
+	return db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := SetScope(ctx, tx, scope); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// WithContextScope runs fn in a transaction scoped by the Scope stored in ctx.
+// It refuses to run without a scope so RLS-protected queries never execute unscoped.
+func (db *DB) WithContextScope(ctx context.Context, fn func(*sql.Tx) error) error {
+	scope, ok := ScopeFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("no row-level security scope in context")
+	}
+	return db.WithScopedTransaction(ctx, scope, fn)
+}
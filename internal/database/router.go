@@ -0,0 +1,79 @@
+package database
+
+// Workload classifies queries so heavy reporting can be routed away from the OLTP database
+type Workload int
+
+const (
+	// WorkloadOLTP covers short transactional queries served by the primary database
+	WorkloadOLTP Workload = iota
+	// WorkloadReporting covers long-running aggregate/report queries
+	WorkloadReporting
+)
+
+// WorkloadMarker is implemented by repositories that declare which database they belong on
+type WorkloadMarker interface {
+	Workload() Workload
+}
+
+// ReportingRepository can be embedded in a repository struct to mark it as reporting-only:
+//
+//	type SalesReport struct {
+//		database.ReportingRepository
+//		db *database.DB
+//	}
+//
+//	report := SalesReport{db: router.For(SalesReport{})}
+type ReportingRepository struct{}
+
+// Workload implements WorkloadMarker
+func (ReportingRepository) Workload() Workload {
+	return WorkloadReporting
+}
+
+// Router hands out the primary or reporting connection based on a query's workload.
+// When no reporting database is configured every workload falls back to the primary.
+type Router struct {
+	primary   *DB
+	reporting *DB
+}
+
+// NewRouter creates a new database router; reporting may be nil
+func NewRouter(primary, reporting *DB) *Router {
+	return &Router{primary: primary, reporting: reporting}
+}
+
+// Primary returns the OLTP connection
+func (r *Router) Primary() *DB {
+	if r == nil {
+		return nil
+	}
+	return r.primary
+}
+
+// Reporting returns the reporting connection, or the primary when none is configured
+func (r *Router) Reporting() *DB {
+	if r == nil {
+		return nil
+	}
+	if r.reporting != nil {
+		return r.reporting
+	}
+	return r.primary
+}
+
+// ForWorkload returns the connection for the given workload
+func (r *Router) ForWorkload(w Workload) *DB {
+	if w == WorkloadReporting {
+		return r.Reporting()
+	}
+	return r.Primary()
+}
+
+// For returns the connection a repository should use based on its WorkloadMarker;
+// unmarked repositories use the primary database
+func (r *Router) For(repository interface{}) *DB {
+	if marker, ok := repository.(WorkloadMarker); ok {
+		return r.ForWorkload(marker.Workload())
+	}
+	return r.Primary()
+}
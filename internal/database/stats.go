@@ -0,0 +1,86 @@
+package database
+
+import (
+	"time"
+
+	"main.go/internal/metrics"
+)
+
+// PoolStats is a driver-neutral snapshot of the connection pool
+type PoolStats struct {
+	Driver string `json:"driver"`
+	// MaxConns is the pool size limit; 0 means unlimited
+	MaxConns int `json:"max_conns"`
+	// OpenConns counts established connections, InUse those running a query, and Idle the rest
+	OpenConns int `json:"open_conns"`
+	InUse     int `json:"in_use"`
+	Idle      int `json:"idle"`
+	// WaitCount is how many times a caller had to wait for a connection, for WaitDuration in total
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+	// IdleClosed counts connections closed for being idle too long or beyond the idle
+	// limit, and LifetimeClosed those closed for reaching their maximum lifetime
+	IdleClosed     int64 `json:"idle_closed"`
+	LifetimeClosed int64 `json:"lifetime_closed"`
+}
+
+// PoolStats reports the pool's current state, from pgxpool with the pgx driver and from
+// database/sql otherwise
+func (db *DB) PoolStats() PoolStats {
+	if db == nil || db.DB == nil {
+		return PoolStats{}
+	}
+	if db.pool != nil {
+		s := db.pool.Stat()
+		return PoolStats{
+			Driver:         DriverPgx,
+			MaxConns:       int(s.MaxConns()),
+			OpenConns:      int(s.TotalConns()),
+			InUse:          int(s.AcquiredConns()),
+			Idle:           int(s.IdleConns()),
+			WaitCount:      s.EmptyAcquireCount(),
+			WaitDuration:   s.EmptyAcquireWaitTime(),
+			IdleClosed:     s.MaxIdleDestroyCount(),
+			LifetimeClosed: s.MaxLifetimeDestroyCount(),
+		}
+	}
+	s := db.Stats()
+	return PoolStats{
+		Driver:         DriverPQ,
+		MaxConns:       s.MaxOpenConnections,
+		OpenConns:      s.OpenConnections,
+		InUse:          s.InUse,
+		Idle:           s.Idle,
+		WaitCount:      s.WaitCount,
+		WaitDuration:   s.WaitDuration,
+		IdleClosed:     s.MaxIdleClosed + s.MaxIdleTimeClosed,
+		LifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+// RegisterMetrics publishes the pool statistics under component, e.g.
+// app_db_in_use_connections: the connection counts as gauges and the running totals as
+// counters. Every value is read from the pool when /metrics is scraped.
+func (db *DB) RegisterMetrics(component string) {
+	if db == nil {
+		return
+	}
+	gauge := func(name, help string, value func(PoolStats) float64) {
+		metrics.NewGaugeFunc(component, name, help, func() float64 {
+			return value(db.PoolStats())
+		})
+	}
+	counter := func(name, help string, value func(PoolStats) float64) {
+		metrics.NewCounterFunc(component, name, help, func() float64 {
+			return value(db.PoolStats())
+		})
+	}
+	gauge("max_connections", "Connection pool size limit (0 = unlimited)", func(s PoolStats) float64 { return float64(s.MaxConns) })
+	gauge("open_connections", "Established connections", func(s PoolStats) float64 { return float64(s.OpenConns) })
+	gauge("in_use_connections", "Connections running a query", func(s PoolStats) float64 { return float64(s.InUse) })
+	gauge("idle_connections", "Idle connections", func(s PoolStats) float64 { return float64(s.Idle) })
+	counter("wait_count", "Times a caller waited for a free connection", func(s PoolStats) float64 { return float64(s.WaitCount) })
+	counter("wait_duration_seconds", "Time spent waiting for a free connection", func(s PoolStats) float64 { return s.WaitDuration.Seconds() })
+	counter("idle_closed_total", "Connections closed for being idle", func(s PoolStats) float64 { return float64(s.IdleClosed) })
+	counter("lifetime_closed_total", "Connections closed for reaching their maximum lifetime", func(s PoolStats) float64 { return float64(s.LifetimeClosed) })
+}
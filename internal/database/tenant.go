@@ -0,0 +1,326 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	pq "github.com/lib/pq"
+)
+
+// Tenant is the tenant a request runs for. Every query made with a context carrying it
+// runs on a connection switched to the tenant first: app.tenant_id is set for row-level
+// security (see app_current_tenant), and with a Schema the search_path puts the tenant's
+// schema ahead of public. Connections are switched back for queries without a tenant.
+type Tenant struct {
+	ID string
+	// Schema is the tenant's schema in schema-per-tenant mode; empty shares the public
+	// schema and separates tenants by row
+	Schema string
+}
+
+type tenantContextKey struct{}
+
+// ContextWithTenant attaches a tenant to ctx
+func ContextWithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, t)
+}
+
+// TenantFromContext returns the tenant attached to ctx, if any
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(tenantContextKey{}).(Tenant)
+	return t, ok
+}
+
+// sessionSQL returns the statement that switches a connection to t; the zero Tenant
+// switches it back to the server defaults
+func sessionSQL(t Tenant) string {
+	path := "RESET search_path"
+	if t.Schema != "" {
+		path = "SET search_path TO " + pq.QuoteIdentifier(t.Schema) + ", public"
+	}
+	if t.ID == "" {
+		return path + "; RESET app.tenant_id"
+	}
+	return path + "; SET app.tenant_id TO " + pq.QuoteLiteral(t.ID)
+}
+
+// tenantConnector hands out connections that follow the tenant of each query's context
+type tenantConnector struct {
+	driver.Connector
+	// resetOnClose switches connections back before they are closed, for drivers whose
+	// Close returns the server connection to another pool rather than closing it
+	resetOnClose bool
+}
+
+// Connect implements driver.Connector
+func (c *tenantConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantConn{conn: conn, resetOnClose: c.resetOnClose}, nil
+}
+
+// tenantConn remembers the tenant its session is switched to, so it only switches when a
+// query's tenant differs. Inside a transaction it keeps the tenant the transaction began
+// with: a SET there would be undone by a rollback.
+type tenantConn struct {
+	conn         driver.Conn
+	resetOnClose bool
+
+	mu      sync.Mutex
+	current Tenant
+	inTx    bool
+}
+
+// use switches the session to the tenant in ctx when it is not already
+func (c *tenantConn) use(ctx context.Context) error {
+	t, _ := TenantFromContext(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inTx || t == c.current {
+		return nil
+	}
+	if err := c.exec(ctx, sessionSQL(t)); err != nil {
+		return err
+	}
+	c.current = t
+	return nil
+}
+
+func (c *tenantConn) exec(ctx context.Context, query string) error {
+	if execer, ok := c.conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		return err
+	}
+	stmt, err := c.conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil) //nolint:staticcheck // fallback for drivers without ExecerContext
+	return err
+}
+
+// Prepare implements driver.Conn
+func (c *tenantConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext
+func (c *tenantConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.use(ctx); err != nil {
+		return nil, err
+	}
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tenantStmt{Stmt: stmt, conn: c}, nil
+}
+
+// Close implements driver.Conn
+func (c *tenantConn) Close() error {
+	c.mu.Lock()
+	dirty := c.current != Tenant{}
+	c.mu.Unlock()
+	if c.resetOnClose && dirty {
+		if err := c.exec(context.Background(), sessionSQL(Tenant{})); err != nil {
+			// Never hand a switched connection to the next user: closing the pgx
+			// connection makes its pool discard it
+			if pgxConn, ok := c.conn.(interface{ Conn() *pgx.Conn }); ok {
+				_ = pgxConn.Conn().Close(context.Background())
+			}
+			_ = c.conn.Close()
+			return err
+		}
+	}
+	return c.conn.Close()
+}
+
+// Begin implements driver.Conn
+func (c *tenantConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx
+func (c *tenantConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.use(ctx); err != nil {
+		return nil, err
+	}
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.conn.Begin() //nolint:staticcheck // fallback for drivers without ConnBeginTx
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.inTx = true
+	c.mu.Unlock()
+	return &tenantTx{Tx: tx, conn: c}, nil
+}
+
+// ExecContext implements driver.ExecerContext
+func (c *tenantConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.use(ctx); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+// QueryContext implements driver.QueryerContext
+func (c *tenantConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.use(ctx); err != nil {
+		return nil, err
+	}
+	return queryer.QueryContext(ctx, query, args)
+}
+
+// Ping implements driver.Pinger
+func (c *tenantConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker, so drivers keep converting their
+// own argument types
+func (c *tenantConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// ResetSession implements driver.SessionResetter
+func (c *tenantConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator
+func (c *tenantConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// tenantTx lets its connection switch tenants again once the transaction ends
+type tenantTx struct {
+	driver.Tx
+	conn *tenantConn
+}
+
+func (t *tenantTx) Commit() error {
+	defer t.done()
+	return t.Tx.Commit()
+}
+
+func (t *tenantTx) Rollback() error {
+	defer t.done()
+	return t.Tx.Rollback()
+}
+
+func (t *tenantTx) done() {
+	t.conn.mu.Lock()
+	t.conn.inTx = false
+	t.conn.mu.Unlock()
+}
+
+// tenantStmt switches its connection to the tenant of each execution's context
+type tenantStmt struct {
+	driver.Stmt
+	conn *tenantConn
+}
+
+// ExecContext implements driver.StmtExecContext
+func (s *tenantStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.conn.use(ctx); err != nil {
+		return nil, err
+	}
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values) //nolint:staticcheck // fallback for drivers without StmtExecContext
+}
+
+// QueryContext implements driver.StmtQueryContext
+func (s *tenantStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.conn.use(ctx); err != nil {
+		return nil, err
+	}
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values) //nolint:staticcheck // fallback for drivers without StmtQueryContext
+}
+
+// CheckNamedValue implements driver.NamedValueChecker for statements
+func (s *tenantStmt) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return s.conn.CheckNamedValue(v)
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("database: driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// ValidSchemaName reports whether name can be used as a tenant schema: lowercase letters,
+// digits, and underscores, starting with a letter, and not a system schema
+func ValidSchemaName(name string) bool {
+	if name == "" || len(name) > 63 || name == "public" || strings.HasPrefix(name, "pg_") || name == "information_schema" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type statementTimeoutContextKey struct{}
+
+// ContextWithStatementTimeout overrides the statement timeout (DB_STATEMENT_TIMEOUT) for
+// queries and transactions made with ctx; 0 lifts it, e.g. for exports and imports that
+// are expected to run long
+func ContextWithStatementTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutContextKey{}, d)
+}
+
+// StatementTimeout returns the timeout for statements made with ctx: its override, or
+// the pool's default
+func (db *DB) StatementTimeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(statementTimeoutContextKey{}).(time.Duration); ok {
+		return d
+	}
+	return db.statementTimeout
+}
+
+// withStatementTimeout bounds ctx by the statement timeout; a sooner deadline on ctx wins
+func (db *DB) withStatementTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	d := db.StatementTimeout(ctx)
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// queryContext is withStatementTimeout for queries whose rows are read after the call
+// returns, so the deadline cannot be cancelled when it does; it is released when it passes
+func (db *DB) queryContext(ctx context.Context) context.Context {
+	d := db.StatementTimeout(ctx)
+	if d <= 0 {
+		return ctx
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	_ = cancel // the context's own timer releases it at the deadline
+	return ctx
+}
+
+// setLocalStatementTimeout applies the statement timeout to each statement of tx (the
+// equivalent of SET LOCAL statement_timeout, which cannot take bind parameters)
+func (db *DB) setLocalStatementTimeout(ctx context.Context, tx *sql.Tx) error {
+	d := db.StatementTimeout(ctx)
+	if d <= 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`,
+		strconv.FormatInt(d.Milliseconds(), 10))
+	if err != nil {
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+// Package events publishes domain events through a transactional outbox. An event is
+// written with Enqueue inside the same database transaction as the change it describes,
+// so it exists exactly when the change commits; a background relay then publishes it to
+// every configured destination (Pusher, a webhook, a Redis stream), retrying each one
+// with exponential backoff. A request that succeeds never loses its side effects to a
+// broker outage, and a request that rolls back never announces a change that did not
+// happen. Delivery is at least once: consumers should deduplicate by event ID.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/database"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+const (
+	// MaxAttempts is how many times an event is offered to a destination before it is
+	// marked failed there
+	MaxAttempts = 8
+	// batchSize bounds the deliveries claimed per run
+	batchSize = 50
+	// lease keeps a claimed delivery from other instances while it is being published; it
+	// must exceed publishTimeout
+	lease = 5 * time.Minute
+	// publishTimeout bounds one publish call
+	publishTimeout = 30 * time.Second
+	// firstRetry is the wait after the first failure; it doubles with each attempt
+	firstRetry = 30 * time.Second
+	// pruneEvery is how often published deliveries older than the retention are deleted
+	pruneEvery = time.Hour
+)
+
+var (
+	queued    = metrics.NewCounter("events", "queued_total", "Event deliveries written to the outbox")
+	published = metrics.NewCounter("events", "published_total", "Event deliveries published")
+	retries   = metrics.NewCounter("events", "retries_total", "Event deliveries that failed and were rescheduled")
+	failed    = metrics.NewCounter("events", "failed_total", "Event deliveries given up after the maximum attempts")
+)
+
+// Event is something that happened, as published to destinations
+type Event struct {
+	// ID is shared by every destination's copy of the event
+	ID    string `json:"id"`
+	Topic string `json:"topic"`
+	// Channel is the Pusher channel; publishers without channels ignore it
+	Channel   string          `json:"channel,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Publisher sends events to one destination
+type Publisher interface {
+	// Name identifies the destination in the outbox; renaming it orphans pending events
+	Name() string
+	Publish(ctx context.Context, e Event) error
+}
+
+// Outbox records events and relays them to its publishers
+type Outbox struct {
+	store      *Store
+	db         *database.DB
+	publishers map[string]Publisher
+	// names lists the publishers' names in registration order
+	names     []string
+	retention time.Duration
+	clock     clock.Clock
+	logger    *logger.Logger
+	// wake starts a relay run right after an event is published
+	wake      chan struct{}
+	stop      chan struct{}
+	once      sync.Once
+	lastPrune time.Time
+}
+
+// New creates an outbox keeping published events for retention; add destinations with
+// Use and call Start to relay
+func New(db *database.DB, retention time.Duration, clk clock.Clock, l *logger.Logger) *Outbox {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Outbox{
+		store:      NewStore(db),
+		db:         db,
+		publishers: make(map[string]Publisher),
+		retention:  retention,
+		clock:      clk,
+		logger:     l,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Use adds a destination; events enqueued from then on are published to it
+func (o *Outbox) Use(p Publisher) {
+	if _, ok := o.publishers[p.Name()]; !ok {
+		o.names = append(o.names, p.Name())
+	}
+	o.publishers[p.Name()] = p
+}
+
+// Destinations returns the names of the configured publishers
+func (o *Outbox) Destinations() []string {
+	return append([]string(nil), o.names...)
+}
+
+// Store returns the outbox's store
+func (o *Outbox) Store() *Store {
+	return o.store
+}
+
+// Enqueue records an event in tx, once per destination. It is published after tx commits
+// and discarded if tx rolls back. data is encoded as JSON.
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, topic, channel string, data interface{}) error {
+	if len(o.names) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", topic, err)
+	}
+	if err := o.store.Enqueue(ctx, tx, topic, channel, body, o.names, o.clock.Now()); err != nil {
+		return err
+	}
+	queued.Add(int64(len(o.names)))
+	return nil
+}
+
+// Publish records an event in a transaction of its own, for changes that were not made
+// in one. Prefer Enqueue inside the transaction of the change.
+func (o *Outbox) Publish(ctx context.Context, topic, channel string, data interface{}) error {
+	err := o.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		return o.Enqueue(ctx, tx, topic, channel, data)
+	})
+	if err != nil {
+		return err
+	}
+	o.Notify()
+	return nil
+}
+
+// Notify starts a relay run without waiting for the next tick; call it after committing a
+// transaction that enqueued events
+func (o *Outbox) Notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Relay publishes the deliveries that are due and returns how many succeeded
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	if len(o.names) == 0 {
+		return 0, nil
+	}
+	deliveries, err := o.store.Claim(ctx, o.names, o.clock.Now(), lease, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, d := range deliveries {
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := o.publishers[d.Destination].Publish(publishCtx, d.Event)
+		cancel()
+
+		now := o.clock.Now()
+		switch {
+		case err == nil:
+			sent++
+			published.Inc()
+			err = o.store.MarkPublished(ctx, d.ID, now)
+		case d.Attempts >= MaxAttempts:
+			failed.Inc()
+			o.logger.Error("Giving up on event",
+				zap.String("event", d.Event.ID), zap.String("topic", d.Event.Topic), zap.String("destination", d.Destination),
+				zap.Int("attempts", d.Attempts), zap.Error(err))
+			err = o.store.MarkFailed(ctx, d.ID, err, now)
+		default:
+			retries.Inc()
+			wait := firstRetry << (d.Attempts - 1)
+			o.logger.Warn("Failed to publish event; retrying",
+				zap.String("event", d.Event.ID), zap.String("topic", d.Event.Topic), zap.String("destination", d.Destination),
+				zap.Int("attempt", d.Attempts), zap.Duration("retry_in", wait), zap.Error(err))
+			err = o.store.Retry(ctx, d.ID, err, now.Add(wait))
+		}
+		if err != nil {
+			// The lease expires and the event is offered again; it may be published twice
+			o.logger.Warn("Failed to record event delivery", zap.String("id", d.ID), zap.Error(err))
+		}
+	}
+	return sent, nil
+}
+
+// prune deletes published deliveries past the retention, at most once per pruneEvery
+func (o *Outbox) prune(ctx context.Context) {
+	now := o.clock.Now()
+	if o.retention <= 0 || now.Sub(o.lastPrune) < pruneEvery {
+		return
+	}
+	o.lastPrune = now
+	n, err := o.store.Prune(ctx, now.Add(-o.retention))
+	if err != nil {
+		o.logger.Warn("Failed to prune published events", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		o.logger.Info("Pruned published events", zap.Int("deliveries", n))
+	}
+}
+
+// Start relays due events every interval, and right after Notify, until Stop
+func (o *Outbox) Start(interval time.Duration) {
+	safego.GoNamed("event-outbox", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), lease)
+			if _, err := o.Relay(ctx); err != nil {
+				o.logger.Warn("Failed to relay events", zap.Error(err))
+			}
+			o.prune(ctx)
+			cancel()
+
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+			case <-o.wake:
+			}
+		}
+	})
+}
+
+// Stop ends background relaying; pending events are published after the next Start
+func (o *Outbox) Stop() {
+	o.once.Do(func() { close(o.stop) })
+}
@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStream appends events to a Redis stream, for workers reading it with a consumer
+// group. The stream is trimmed to about maxLen entries; 0 keeps every entry.
+type RedisStream struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisStream creates a publisher appending to stream
+func NewRedisStream(client *redis.Client, stream string, maxLen int64) *RedisStream {
+	return &RedisStream{client: client, stream: stream, maxLen: maxLen}
+}
+
+// Name implements Publisher
+func (r *RedisStream) Name() string {
+	return "redis"
+}
+
+// Publish implements Publisher
+func (r *RedisStream) Publish(ctx context.Context, e Event) error {
+	err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.stream,
+		MaxLen: r.maxLen,
+		Approx: r.maxLen > 0,
+		Values: map[string]interface{}{
+			"id":         e.ID,
+			"topic":      e.Topic,
+			"channel":    e.Channel,
+			"data":       string(e.Data),
+			"created_at": e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("redis stream: %w", err)
+	}
+	return nil
+}
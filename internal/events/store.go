@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"main.go/internal/database"
+)
+
+// Delivery is one destination's copy of an event
+type Delivery struct {
+	ID          string
+	Destination string
+	Attempts    int
+	Event       Event
+}
+
+// Store persists events awaiting publication
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new event outbox store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Enqueue records an event in tx with one delivery per destination, due from now
+func (s *Store) Enqueue(ctx context.Context, tx *sql.Tx, topic, channel string, data []byte, destinations []string, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO event_outbox (event_id, destination, topic, channel, data, next_attempt_at, created_at)
+SELECT e.id, d, $1, $2, $3, $4, $4
+FROM (SELECT gen_random_uuid() AS id) e, unnest($5::text[]) AS d`,
+		topic, channel, data, now, pq.Array(destinations))
+	if err != nil {
+		return fmt.Errorf("failed to queue %s event: %w", topic, err)
+	}
+	return nil
+}
+
+// Claim takes up to limit due deliveries to destinations and leases them until now+lease,
+// counting the attempt. Rows locked by another instance are skipped, and events are
+// claimed oldest first so each destination sees them in order while nothing fails.
+func (s *Store) Claim(ctx context.Context, destinations []string, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+UPDATE event_outbox SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id IN (
+    SELECT id FROM event_outbox
+    WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $1
+      AND destination = ANY($4::text[])
+    ORDER BY created_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, destination, attempts, event_id, topic, channel, data, created_at`,
+		now, now.Add(lease), limit, pq.Array(destinations))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim events: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.Destination, &d.Attempts, &d.Event.ID, &d.Event.Topic,
+			&d.Event.Channel, &d.Event.Data, &d.Event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkPublished records a delivered event
+func (s *Store) MarkPublished(ctx context.Context, id string, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE event_outbox SET published_at = $2, last_error = '' WHERE id = $1`, id, now); err != nil {
+		return fmt.Errorf("failed to mark event published: %w", err)
+	}
+	return nil
+}
+
+// Retry records a failed attempt and schedules the next one
+func (s *Store) Retry(ctx context.Context, id string, cause error, next time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE event_outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1`, id, cause.Error(), next); err != nil {
+		return fmt.Errorf("failed to reschedule event: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a delivery that will not be retried
+func (s *Store) MarkFailed(ctx context.Context, id string, cause error, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE event_outbox SET last_error = $2, failed_at = $3 WHERE id = $1`, id, cause.Error(), now); err != nil {
+		return fmt.Errorf("failed to mark event failed: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes deliveries published before cutoff and returns how many there were;
+// failed deliveries are kept for inspection
+func (s *Store) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM event_outbox WHERE published_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune events: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"main.go/internal/ids"
+	"main.go/internal/signing"
+)
+
+// Webhook posts each event as JSON to a URL. With a key ID and secret the request is
+// signed like calls to /internal, so the receiver can verify it with the signing package.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a publisher posting to url, signing with keyID and secret when set
+func NewWebhook(url, keyID, secret string) *Webhook {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if keyID != "" && secret != "" {
+		client = signing.NewClient(keyID, secret)
+	}
+	return &Webhook{url: url, client: client}
+}
+
+// Name implements Publisher
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Publish implements Publisher; any 2xx response acknowledges the event
+func (w *Webhook) Publish(ctx context.Context, e Event) error {
+	req, err := newWebhookRequest(ctx, w.url, e)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// newWebhookRequest builds the unsigned request delivering e to url
+func newWebhookRequest(ctx context.Context, url string, e Event) (*http.Request, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", e.ID)
+	req.Header.Set("X-Event-Topic", e.Topic)
+	return req, nil
+}
+
+// TestTopic is the topic of the sample event TestWebhook sends
+const TestTopic = "webhook.test"
+
+// testBodyLimit bounds how much of the receiver's response TestWebhook reports
+const testBodyLimit = 4 << 10
+
+// WebhookTest reports a delivery made by TestWebhook
+type WebhookTest struct {
+	Status    int               `json:"status"`
+	LatencyMS int64             `json:"latency_ms"`
+	Headers   map[string]string `json:"headers"`
+	// Body is the start of the response body, cut at 4 KiB
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated"`
+	// Request is what was sent, so a receiver whose signature check fails can compare it
+	// with what it computed
+	Request WebhookTestRequest `json:"request"`
+}
+
+// WebhookTestRequest is the request TestWebhook sent
+type WebhookTestRequest struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// StringToSign is the canonical string the signature covers; empty when unsigned
+	StringToSign string `json:"string_to_sign,omitempty"`
+}
+
+// TestWebhook sends a sample webhook.test event to url with client, signed with keyID and
+// secret as Webhook signs when they are set, and reports how the receiver answered. The
+// request is built exactly as a real delivery, so integrators can check their endpoint
+// before real events arrive. Callers pass a client safe for user-supplied URLs.
+func TestWebhook(ctx context.Context, client *http.Client, url, keyID, secret string, now time.Time) (*WebhookTest, error) {
+	data, _ := json.Marshal(map[string]string{"message": "This is a test event"})
+	e := Event{ID: ids.NewV7().String(), Topic: TestTopic, Data: data, CreatedAt: now.UTC()}
+	req, err := newWebhookRequest(ctx, url, e)
+	if err != nil {
+		return nil, err
+	}
+	body, _ := json.Marshal(e)
+
+	result := &WebhookTest{Request: WebhookTestRequest{URL: url, Body: string(body)}}
+	if keyID != "" && secret != "" {
+		if err := signing.Sign(req, keyID, secret, now); err != nil {
+			return nil, err
+		}
+		result.Request.StringToSign = signing.StringToSign(req.Method, req.URL.RequestURI(),
+			req.Header.Get(signing.HeaderTimestamp), req.Header.Get(signing.HeaderNonce), body)
+	}
+	result.Request.Headers = flattenHeader(req.Header)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	detail, err := io.ReadAll(io.LimitReader(resp.Body, testBodyLimit+1))
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		return nil, fmt.Errorf("webhook: %w", err)
+	}
+
+	result.Status = resp.StatusCode
+	result.Headers = flattenHeader(resp.Header)
+	if len(detail) > testBodyLimit {
+		detail, result.Truncated = detail[:testBodyLimit], true
+	}
+	result.Body = string(detail)
+	return result, nil
+}
+
+// flattenHeader joins repeated header values with commas
+func flattenHeader(h http.Header) map[string]string {
+	flat := make(map[string]string, len(h))
+	for name, values := range h {
+		flat[name] = strings.Join(values, ", ")
+	}
+	return flat
+}
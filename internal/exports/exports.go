@@ -0,0 +1,455 @@
+// Package exports builds CSV exports of data tables in the background. A user asks for
+// an export of a table with a search, filters, sort, and columns, as on screen; an
+// operation (see the operations package) writes every matching row to a CSV file in
+// storage and emails the user a signed link that expires with the file. A background job
+// deletes expired files, keeping the exports as history for the admin list.
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/database"
+	"main.go/internal/database/datatable"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/metrics"
+	"main.go/internal/operations"
+	"main.go/internal/safego"
+	"main.go/internal/storage"
+	"main.go/internal/users"
+)
+
+// KindExport is the operation kind of exports
+const KindExport = "export.csv"
+
+// ReadScope lets admins list every export
+const ReadScope = "exports:read"
+
+// Export statuses
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+	StatusExpired = "expired"
+)
+
+const (
+	// downloadPurpose scopes signed download tokens
+	downloadPurpose = "export-download"
+	// cleanupBatch bounds the expired exports deleted per query
+	cleanupBatch = 100
+	// mailTimeout bounds sending the email of one export
+	mailTimeout = 30 * time.Second
+)
+
+var (
+	// ErrNotFound is returned when an export does not exist
+	ErrNotFound = errors.New("export not found")
+	// ErrUnknownResource is returned for resources that were not registered
+	ErrUnknownResource = errors.New("unknown resource")
+	// ErrExpired is returned when downloading an export whose file is gone or going
+	ErrExpired = errors.New("export has expired")
+	// ErrNotReady is returned when downloading an export that is still being built or failed
+	ErrNotReady = errors.New("export is not ready")
+)
+
+var (
+	requested = metrics.NewCounter("exports", "requested_total", "Exports requested")
+	built     = metrics.NewCounter("exports", "built_total", "Exports built and stored")
+	exported  = metrics.NewCounter("exports", "rows_total", "Rows written to exports")
+)
+
+// Params is the selection of a table an export holds
+type Params struct {
+	Search  string            `json:"search"`
+	Filters map[string]string `json:"filters"`
+	// Sort is written as the sort parameter, e.g. "-created_at,email"
+	Sort    string   `json:"sort"`
+	Columns []string `json:"columns"`
+}
+
+// ParamsFrom returns the selection of a table request, dropping its page
+func ParamsFrom(req datatable.Request) Params {
+	return Params{Search: req.Search, Filters: req.Filters, Sort: datatable.FormatSort(req.Sort), Columns: req.Columns}
+}
+
+// request returns the table request of the selection
+func (p Params) request() datatable.Request {
+	return datatable.Request{Search: p.Search, Filters: p.Filters, Sort: datatable.ParseSort(p.Sort), Columns: p.Columns}
+}
+
+// Export is a CSV export of a table
+type Export struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id,omitempty"`
+	Resource    string     `json:"resource"`
+	Params      Params     `json:"params"`
+	Status      string     `json:"status"`
+	Rows        int64      `json:"rows"`
+	Bytes       int64      `json:"bytes"`
+	Error       string     `json:"error,omitempty"`
+	OperationID string     `json:"operation_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when the file and its download links go away
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL is set on ready exports returned to their owner
+	DownloadURL string `json:"download_url,omitempty"`
+
+	key string
+}
+
+// Filename is the name downloads are saved under
+func (e *Export) Filename() string {
+	return e.Resource + "-" + e.CreatedAt.UTC().Format("20060102-150405") + ".csv"
+}
+
+// Source is a data table that may be exported
+type Source struct {
+	Table datatable.Table
+	// Scope is required to export the table, usually the one it is served with
+	Scope string
+}
+
+// Options configures exports
+type Options struct {
+	// TTL is how long a built export can be downloaded
+	TTL time.Duration
+	// MaxRows bounds the rows of one export; 0 exports every row
+	MaxRows int
+	// Timeout bounds building one export
+	Timeout time.Duration
+	// Secret signs download links
+	Secret string
+	// AppURL is where emailed links point
+	AppURL string
+}
+
+// Service requests, builds, and serves exports
+type Service struct {
+	store   *Store
+	db      *database.DB
+	runner  *operations.Runner
+	storage storage.Storage
+	mailer  mail.Mailer
+	users   *users.Store
+	tokens  *auth.SignedTokens
+	opts    Options
+	clock   clock.Clock
+	logger  *logger.Logger
+	sources map[string]Source
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// New creates a service that queries tables on db, builds exports on runner, keeps the
+// files in st, and emails links through mailer
+func New(store *Store, db *database.DB, runner *operations.Runner, st storage.Storage, mailer mail.Mailer, userStore *users.Store, opts Options, clk clock.Clock, l *logger.Logger) *Service {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	s := &Service{
+		store:   store,
+		db:      db,
+		runner:  runner,
+		storage: st,
+		mailer:  mailer,
+		users:   userStore,
+		tokens:  auth.NewSignedTokens(opts.Secret),
+		opts:    opts,
+		clock:   clk,
+		logger:  l,
+		sources: map[string]Source{},
+		stop:    make(chan struct{}),
+	}
+	runner.Handle(KindExport, opts.Timeout, s.build)
+	return s
+}
+
+// Store returns the service's store
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Register lets the table called name be exported
+func (s *Service) Register(name string, src Source) {
+	s.sources[name] = src
+}
+
+// Source returns the registered table called name
+func (s *Service) Source(name string) (Source, bool) {
+	src, ok := s.sources[name]
+	return src, ok
+}
+
+// exportInput is the input of an export operation
+type exportInput struct {
+	ExportID string `json:"export_id"`
+}
+
+// Request starts an export of resource for a user. The selection is checked against the
+// table's columns first, so a bad one fails here rather than in the background.
+func (s *Service) Request(ctx context.Context, userID, resource string, params Params) (*Export, error) {
+	src, ok := s.sources[resource]
+	if !ok {
+		return nil, ErrUnknownResource
+	}
+	if params.Filters == nil {
+		params.Filters = map[string]string{}
+	}
+	if params.Columns == nil {
+		params.Columns = []string{}
+	}
+	if err := src.Table.Validate(params.request()); err != nil {
+		return nil, err
+	}
+
+	e, err := s.store.Create(ctx, userID, resource, params)
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.runner.Enqueue(ctx, KindExport, "export:"+e.ID, userID, exportInput{ExportID: e.ID})
+	if err != nil {
+		if failErr := s.store.Fail(context.WithoutCancel(ctx), e.ID, err, s.clock.Now()); failErr != nil {
+			s.logger.Warn("Failed to record export failure", zap.String("id", e.ID), zap.Error(failErr))
+		}
+		return nil, err
+	}
+	if err := s.store.SetOperation(ctx, e.ID, op.ID); err != nil {
+		return nil, err
+	}
+	e.OperationID = op.ID
+	requested.Inc()
+	return e, nil
+}
+
+// build is the operations.Handler of KindExport
+func (s *Service) build(ctx context.Context, op *operations.Operation, report func(int)) (interface{}, error) {
+	var input exportInput
+	if err := json.Unmarshal(op.Input, &input); err != nil {
+		return nil, operations.Permanent(err)
+	}
+	e, err := s.store.Find(ctx, input.ExportID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, operations.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.Status != StatusPending {
+		// Built by an earlier run whose outcome was not recorded
+		return buildResult(e), nil
+	}
+
+	if err := s.write(ctx, e); err != nil {
+		var permanent bool
+		if _, ok := s.sources[e.Resource]; !ok || isSelectionError(err) {
+			permanent, err = true, operations.Permanent(err)
+		}
+		if permanent || op.Attempts >= operations.MaxAttempts {
+			if failErr := s.store.Fail(context.WithoutCancel(ctx), e.ID, err, s.clock.Now()); failErr != nil {
+				s.logger.Warn("Failed to record export failure", zap.String("id", e.ID), zap.Error(failErr))
+			}
+		}
+		return nil, err
+	}
+	report(100)
+	built.Inc()
+	exported.Add(e.Rows)
+	s.notify(ctx, e)
+	return buildResult(e), nil
+}
+
+// write builds the CSV of e in a temporary file, stores it, and marks e ready
+func (s *Service) write(ctx context.Context, e *Export) error {
+	src, ok := s.sources[e.Resource]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownResource, e.Resource)
+	}
+	f, err := os.CreateTemp("", "export-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// One query streams the whole export, which may well outlast DB_STATEMENT_TIMEOUT
+	ctx = database.ContextWithStatementTimeout(ctx, 0)
+	rows, err := src.Table.WriteCSV(ctx, s.db, e.Params.request(), f, s.opts.MaxRows)
+	if err != nil {
+		return err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := "exports/" + e.ID + ".csv"
+	if err := s.storage.Put(ctx, key, f, size, "text/csv; charset=utf-8"); err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	expiresAt := now.Add(s.opts.TTL)
+	if err := s.store.Ready(ctx, e.ID, key, rows, size, now, expiresAt); err != nil {
+		return err
+	}
+	e.Status, e.key, e.Rows, e.Bytes, e.CompletedAt, e.ExpiresAt = StatusReady, key, rows, size, &now, &expiresAt
+	return nil
+}
+
+// isSelectionError reports whether err rejects an export's selection, e.g. a column
+// removed from the table since the export was requested
+func isSelectionError(err error) bool {
+	return errors.Is(err, datatable.ErrUnknownColumn) || errors.Is(err, datatable.ErrNotSortable) ||
+		errors.Is(err, datatable.ErrNotSearchable)
+}
+
+func buildResult(e *Export) map[string]interface{} {
+	return map[string]interface{}{"export_id": e.ID, "rows": e.Rows, "bytes": e.Bytes}
+}
+
+// notify emails the owner of a ready export its download link. A failure is only
+// logged: the export is built, and the owner can still find the link in the API.
+func (s *Service) notify(ctx context.Context, e *Export) {
+	if e.UserID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+	defer cancel()
+	user, err := s.users.FindByID(ctx, e.UserID)
+	if err != nil {
+		s.logger.Warn("Failed to load export owner", zap.String("id", e.ID), zap.Error(err))
+		return
+	}
+
+	var limited string
+	if s.opts.MaxRows > 0 && e.Rows >= int64(s.opts.MaxRows) {
+		limited = fmt.Sprintf(" Exports stop at %d rows, so narrow the filters to get the rest.", s.opts.MaxRows)
+	}
+	text := fmt.Sprintf("Hi %s,\n\nYour export of %s is ready: %d rows.%s Download it here:\n\n%s\n\n"+
+		"The link expires at %s.\n", user.FirstName, e.Resource, e.Rows, limited, s.DownloadURL(e),
+		e.ExpiresAt.UTC().Format(time.RFC1123))
+	if err := s.mailer.Send(ctx, mail.Message{To: user.Email, Subject: "Your " + e.Resource + " export is ready", Text: text}); err != nil {
+		s.logger.Warn("Failed to email export link", zap.String("id", e.ID), zap.Error(err))
+	}
+}
+
+// DownloadURL returns a link to a ready export that works until it expires, or "" for an
+// export that cannot be downloaded
+func (s *Service) DownloadURL(e *Export) string {
+	if e.Status != StatusReady || e.ExpiresAt == nil {
+		return ""
+	}
+	token := s.tokens.Sign(downloadPurpose, e.ID, e.key, *e.ExpiresAt)
+	return strings.TrimRight(s.opts.AppURL, "/") + "/api/v1/exports/" + e.ID + "/download?token=" + url.QueryEscape(token)
+}
+
+// Open checks a download token and returns the export it names with its file. The token
+// is bound to the stored file and expires with it; it is checked before anything about
+// the export is revealed.
+func (s *Service) Open(ctx context.Context, id, token string) (*Export, io.ReadCloser, error) {
+	e, err := s.store.Find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	subject, err := s.tokens.Verify(token, downloadPurpose, e.key, s.clock.Now())
+	if errors.Is(err, auth.ErrTokenExpired) {
+		return nil, nil, ErrExpired
+	}
+	if err != nil || subject != e.ID {
+		return nil, nil, auth.ErrTokenInvalid
+	}
+	switch e.Status {
+	case StatusReady:
+	case StatusExpired:
+		return nil, nil, ErrExpired
+	default:
+		return nil, nil, ErrNotReady
+	}
+	body, err := s.storage.Open(ctx, e.key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrExpired
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, body, nil
+}
+
+// Cleanup deletes the files of expired exports and returns how many were deleted
+func (s *Service) Cleanup(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		list, err := s.store.Expired(ctx, s.clock.Now(), cleanupBatch)
+		if err != nil {
+			return total, err
+		}
+		deleted := 0
+		for _, e := range list {
+			if err := s.storage.Delete(ctx, e.key); err != nil {
+				s.logger.Warn("Failed to delete export file", zap.String("id", e.ID), zap.Error(err))
+				continue
+			}
+			if err := s.store.MarkExpired(ctx, e.ID); err != nil {
+				return total, err
+			}
+			deleted++
+		}
+		total += deleted
+		// A batch with failures would be selected again straight away
+		if len(list) < cleanupBatch || deleted < len(list) {
+			return total, nil
+		}
+	}
+}
+
+// Start deletes expired export files now and then every interval until Stop
+func (s *Service) Start(interval time.Duration) {
+	safego.GoNamed("export-cleanup", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// One replica cleans up at a time; the others skip the round
+			var n int
+			_, err := s.store.db.WithTryAdvisoryLock(ctx, "export-cleanup", func(ctx context.Context) (err error) {
+				n, err = s.Cleanup(ctx)
+				return err
+			})
+			cancel()
+			if err != nil {
+				s.logger.Warn("Failed to clean up exports", zap.Error(err))
+			} else if n > 0 {
+				s.logger.Info("Expired exports deleted", zap.Int("exports", n))
+			}
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background cleanup
+func (s *Service) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
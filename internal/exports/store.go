@@ -0,0 +1,176 @@
+package exports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+	"main.go/internal/database/datatable"
+)
+
+// Store persists exports
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new export store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// AdminTable lists every export for admins, with the email of who asked for it
+var AdminTable = datatable.Table{
+	From: "exports e LEFT JOIN users u ON u.id = e.user_id",
+	Key:  "id",
+	Columns: []datatable.Column{
+		{Name: "id", SQL: "e.id"},
+		{Name: "email", SQL: "u.email", Searchable: true, Sortable: true},
+		{Name: "resource", SQL: "e.resource", Searchable: true, Sortable: true},
+		{Name: "status", SQL: "e.status", Searchable: true, Sortable: true},
+		{Name: "rows", SQL: "e.row_count", Sortable: true},
+		{Name: "bytes", SQL: "e.byte_count", Sortable: true},
+		{Name: "error", SQL: "e.error"},
+		{Name: "created_at", SQL: "e.created_at", Sortable: true},
+		{Name: "completed_at", SQL: "e.completed_at", Sortable: true},
+		{Name: "expires_at", SQL: "e.expires_at", Sortable: true},
+	},
+	DefaultSort: []datatable.Sort{{Column: "created_at", Desc: true}},
+}
+
+// Table returns a page of the admin export list
+func (s *Store) Table(ctx context.Context, req datatable.Request) (*datatable.Result, error) {
+	return AdminTable.Query(ctx, s.db, req)
+}
+
+const exportColumns = `id, COALESCE(user_id::text, ''), resource, params, status, row_count, byte_count, error,
+COALESCE(operation_id::text, ''), storage_key, created_at, completed_at, expires_at`
+
+func scanExport(row interface{ Scan(...interface{}) error }) (*Export, error) {
+	var e Export
+	var params []byte
+	if err := row.Scan(&e.ID, &e.UserID, &e.Resource, &params, &e.Status, &e.Rows, &e.Bytes, &e.Error,
+		&e.OperationID, &e.key, &e.CreatedAt, &e.CompletedAt, &e.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(params, &e.Params); err != nil {
+		return nil, fmt.Errorf("failed to decode export parameters: %w", err)
+	}
+	return &e, nil
+}
+
+// Create records a pending export of resource for a user
+func (s *Store) Create(ctx context.Context, userID, resource string, params Params) (*Export, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	e, err := scanExport(s.db.QueryRowContext(ctx, `
+INSERT INTO exports (user_id, resource, params) VALUES ($1, $2, $3)
+RETURNING `+exportColumns, userID, resource, encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	return e, nil
+}
+
+// SetOperation records the operation that builds an export
+func (s *Store) SetOperation(ctx context.Context, id, operationID string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE exports SET operation_id = $2 WHERE id = $1`, id, operationID); err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// Find returns the export with id
+func (s *Store) Find(ctx context.Context, id string) (*Export, error) {
+	e, err := scanExport(s.db.QueryRowContext(ctx, `SELECT `+exportColumns+` FROM exports WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export: %w", err)
+	}
+	return e, nil
+}
+
+// ListByUser returns a user's most recent exports first
+func (s *Store) ListByUser(ctx context.Context, userID string, limit int) ([]Export, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+exportColumns+` FROM exports WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exports: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Export{}
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		list = append(list, *e)
+	}
+	return list, rows.Err()
+}
+
+// Ready records the stored file of a built export
+func (s *Store) Ready(ctx context.Context, id, key string, rows, bytes int64, now, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE exports SET status = $2, storage_key = $3, row_count = $4, byte_count = $5, error = '',
+    completed_at = $6, expires_at = $7
+WHERE id = $1`, id, StatusReady, key, rows, bytes, now, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// Fail records that an export could not be built
+func (s *Store) Fail(ctx context.Context, id string, cause error, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE exports SET status = $2, error = $3, completed_at = $4 WHERE id = $1 AND status = $5`,
+		id, StatusFailed, cause.Error(), now, StatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// Expired returns up to limit ready exports whose download expired by now
+func (s *Store) Expired(ctx context.Context, now time.Time, limit int) ([]Export, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+exportColumns+` FROM exports
+WHERE status = $1 AND expires_at <= $2
+ORDER BY expires_at
+LIMIT $3`, StatusReady, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expired exports: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Export
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		list = append(list, *e)
+	}
+	return list, rows.Err()
+}
+
+// MarkExpired records that an export's file was deleted; the row is kept as history, and
+// its key so that old links are still recognized as expired
+func (s *Store) MarkExpired(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE exports SET status = $2 WHERE id = $1`, id, StatusExpired)
+	if err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
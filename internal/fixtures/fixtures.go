@@ -0,0 +1,335 @@
+// Package fixtures inserts related records described in YAML files.
+//
+// Each file maps table names to labelled records. A string value starting with "@"
+// references another record by label and resolves to its id (or to a specific column
+// with "@label.column"), so related rows can be declared without hard-coded keys:
+//
+//	users:
+//	  alice:
+//	    email: alice@example.com
+//	policy_acceptances:
+//	  alice_terms:
+//	    user_id: "@alice"
+//	    document_id: "@terms_2026"
+//
+// YAML reserves a bare @, so references must be quoted. Use "@@" for a literal leading @.
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+
+	"main.go/internal/database"
+)
+
+// refPrefix marks a value as a reference to another fixture
+const refPrefix = "@"
+
+// Record is a single labelled row
+type Record struct {
+	Label  string
+	Table  string
+	Source string
+	// Columns keeps the declaration order so generated SQL is stable
+	Columns []string
+	Values  map[string]interface{}
+}
+
+// Set is an ordered collection of records loaded from one or more files
+type Set struct {
+	Records []*Record
+	labels  map[string]*Record
+}
+
+// Loaded holds the rows returned by the database after Insert, keyed by label
+type Loaded struct {
+	rows map[string]map[string]interface{}
+}
+
+// NewSet creates an empty fixture set
+func NewSet() *Set {
+	return &Set{labels: map[string]*Record{}}
+}
+
+// LoadDir parses every *.yml and *.yaml file in dir, in file name order
+func LoadDir(dir string) (*Set, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fixtures: %w", err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+	return LoadFiles(paths...)
+}
+
+// LoadFiles parses the given fixture files into a single set
+func LoadFiles(paths ...string) (*Set, error) {
+	set := NewSet()
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixtures %s: %w", path, err)
+		}
+		if err := set.Parse(path, contents); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// Parse adds the records in a YAML document to the set. Labels must be unique across the set.
+func (s *Set) Parse(source string, contents []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return fmt.Errorf("failed to parse fixtures %s: %w", source, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	tables := doc.Content[0]
+	if tables.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level must map table names to records", source)
+	}
+
+	for i := 0; i < len(tables.Content); i += 2 {
+		table := tables.Content[i].Value
+		records := tables.Content[i+1]
+		if records.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s:%d: table %q must map labels to records", source, records.Line, table)
+		}
+
+		for j := 0; j < len(records.Content); j += 2 {
+			label := records.Content[j].Value
+			if existing, ok := s.labels[label]; ok {
+				return fmt.Errorf("%s:%d: duplicate fixture label %q (already defined in %s)", source, records.Content[j].Line, label, existing.Source)
+			}
+
+			record, err := parseRecord(source, table, label, records.Content[j+1])
+			if err != nil {
+				return err
+			}
+			s.Records = append(s.Records, record)
+			s.labels[label] = record
+		}
+	}
+	return nil
+}
+
+func parseRecord(source, table, label string, node *yaml.Node) (*Record, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s:%d: fixture %q must map columns to values", source, node.Line, label)
+	}
+
+	record := &Record{Label: label, Table: table, Source: source, Values: map[string]interface{}{}}
+	for k := 0; k < len(node.Content); k += 2 {
+		column := node.Content[k].Value
+		var value interface{}
+		if err := node.Content[k+1].Decode(&value); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s.%s: %w", source, node.Content[k+1].Line, label, column, err)
+		}
+		record.Columns = append(record.Columns, column)
+		record.Values[column] = value
+	}
+	return record, nil
+}
+
+// Insert writes every record in a single transaction. Records are inserted in file order,
+// except that a record is deferred until the records it references have been inserted.
+func (s *Set) Insert(ctx context.Context, db *database.DB) (*Loaded, error) {
+	var loaded *Loaded
+	err := db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// A retried transaction starts over
+		loaded = &Loaded{rows: map[string]map[string]interface{}{}}
+		remaining := s.Records
+		for len(remaining) > 0 {
+			var deferred []*Record
+			for _, record := range remaining {
+				if !s.ready(record, loaded) {
+					deferred = append(deferred, record)
+					continue
+				}
+				if err := insertRecord(ctx, tx, record, loaded); err != nil {
+					return err
+				}
+			}
+
+			if len(deferred) == len(remaining) {
+				return s.unresolvedError(deferred)
+			}
+			remaining = deferred
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// ready reports whether every record referenced by r has already been inserted
+func (s *Set) ready(r *Record, loaded *Loaded) bool {
+	for _, value := range r.Values {
+		if label, _, ok := parseRef(value); ok {
+			if _, done := loaded.rows[label]; !done {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *Set) unresolvedError(records []*Record) error {
+	problems := make([]string, 0, len(records))
+	for _, r := range records {
+		for _, column := range r.Columns {
+			label, _, ok := parseRef(r.Values[column])
+			if !ok {
+				continue
+			}
+			if _, exists := s.labels[label]; !exists {
+				problems = append(problems, fmt.Sprintf("%s (%s): %s references unknown fixture %q", r.Label, r.Source, column, label))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		labels := make([]string, 0, len(records))
+		for _, r := range records {
+			labels = append(labels, r.Label)
+		}
+		problems = append(problems, "circular references between "+strings.Join(labels, ", "))
+	}
+	return fmt.Errorf("unresolved fixture references:\n  %s", strings.Join(problems, "\n  "))
+}
+
+func insertRecord(ctx context.Context, tx *sql.Tx, r *Record, loaded *Loaded) error {
+	columns := make([]string, 0, len(r.Columns))
+	placeholders := make([]string, 0, len(r.Columns))
+	args := make([]interface{}, 0, len(r.Columns))
+
+	for i, column := range r.Columns {
+		value, err := loaded.resolve(r.Values[column])
+		if err != nil {
+			return fmt.Errorf("fixture %s (%s): %s: %w", r.Label, r.Source, column, err)
+		}
+		columns = append(columns, pq.QuoteIdentifier(column))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		args = append(args, value)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+		pq.QuoteIdentifier(r.Table), strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if len(columns) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES RETURNING *", pq.QuoteIdentifier(r.Table))
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to insert fixture %s into %s: %w", r.Label, r.Table, err)
+	}
+	defer rows.Close()
+
+	row, err := scanRow(rows)
+	if err != nil {
+		return fmt.Errorf("failed to read inserted fixture %s: %w", r.Label, err)
+	}
+	loaded.rows[r.Label] = row
+	return nil
+}
+
+func scanRow(rows *sql.Rows) (map[string]interface{}, error) {
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	values := make([]interface{}, len(names))
+	pointers := make([]interface{}, len(names))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(names))
+	for i, name := range names {
+		// lib/pq returns text-like types such as UUID as []byte
+		if b, ok := values[i].([]byte); ok {
+			row[name] = string(b)
+		} else {
+			row[name] = values[i]
+		}
+	}
+	return row, rows.Err()
+}
+
+// parseRef splits "@label" or "@label.column" into its parts
+func parseRef(value interface{}) (label, column string, ok bool) {
+	s, isString := value.(string)
+	if !isString || !strings.HasPrefix(s, refPrefix) || strings.HasPrefix(s, refPrefix+refPrefix) {
+		return "", "", false
+	}
+	label, column, _ = strings.Cut(strings.TrimPrefix(s, refPrefix), ".")
+	if column == "" {
+		column = "id"
+	}
+	return label, column, true
+}
+
+// resolve replaces references with the referenced column value
+func (l *Loaded) resolve(value interface{}) (interface{}, error) {
+	if s, ok := value.(string); ok && strings.HasPrefix(s, refPrefix+refPrefix) {
+		return strings.TrimPrefix(s, refPrefix), nil
+	}
+
+	label, column, ok := parseRef(value)
+	if !ok {
+		return value, nil
+	}
+	return l.Get(label, column)
+}
+
+// Get returns a column of an inserted fixture, e.g. Get("alice", "id")
+func (l *Loaded) Get(label, column string) (interface{}, error) {
+	row, ok := l.rows[label]
+	if !ok {
+		return nil, fmt.Errorf("fixture %q was not inserted", label)
+	}
+	value, ok := row[column]
+	if !ok {
+		return nil, fmt.Errorf("fixture %q has no column %q", label, column)
+	}
+	return value, nil
+}
+
+// ID returns the id of an inserted fixture as a string, or "" if it is unknown
+func (l *Loaded) ID(label string) string {
+	value, err := l.Get(label, "id")
+	if err != nil || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Count returns the number of inserted records
+func (l *Loaded) Count() int {
+	return len(l.rows)
+}
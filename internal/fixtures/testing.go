@@ -0,0 +1,27 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+
+	"main.go/internal/database"
+)
+
+// Load parses the given fixture files and inserts them, failing the test on any error.
+// Tests typically run it against a throwaway database or schema:
+//
+//	loaded := fixtures.Load(t, db, "testdata/users.yml")
+//	id := loaded.ID("alice")
+func Load(tb testing.TB, db *database.DB, paths ...string) *Loaded {
+	tb.Helper()
+
+	set, err := LoadFiles(paths...)
+	if err != nil {
+		tb.Fatalf("fixtures: %v", err)
+	}
+	loaded, err := set.Insert(context.Background(), db)
+	if err != nil {
+		tb.Fatalf("fixtures: %v", err)
+	}
+	return loaded
+}
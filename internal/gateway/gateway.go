@@ -0,0 +1,100 @@
+// Package gateway serves REST handlers generated from protobuf HTTP annotations, such as
+// a grpc-gateway runtime.ServeMux, from the Fiber app. They are mounted under /api/v1
+// after the handwritten routes and behind the same middleware (request IDs, rate limits,
+// WAF, tenancy, and the invite and policy gates), so a proto-first service and
+// handwritten handlers share one API surface. Requests are not required to be
+// authenticated: generated handlers check gateway.PrincipalFrom themselves. A handwritten
+// route wins over a generated one with the same path.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	"main.go/internal/auth"
+)
+
+type principalContextKey struct{}
+
+// PrincipalFrom returns the caller the Fiber authenticators found, for implementations
+// behind the generated handler; the request's context also carries the tenant and owner
+// set by the app's middleware
+func PrincipalFrom(ctx context.Context) (*auth.Principal, bool) {
+	p, ok := ctx.Value(principalContextKey{}).(*auth.Principal)
+	return p, ok && p != nil
+}
+
+// Handler serves requests with h, a net/http handler, with the request's context built
+// from c.UserContext(). Mount it on a prefix after the handwritten routes, so it gets the
+// requests none of them matched, and answers those it does not route itself with 404:
+//
+//	apiV1 := app.Group("/api/v1")
+//	// ... handwritten routes
+//	apiV1.Use(gateway.Handler(mux))
+//
+// The response is buffered, so streaming RPCs are not supported.
+func Handler(h http.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := adaptor.ConvertRequest(c, true)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Malformed request")
+		}
+		ctx := c.UserContext()
+		if p, ok := auth.PrincipalFrom(c); ok {
+			ctx = context.WithValue(ctx, principalContextKey{}, p)
+		}
+
+		w := &responseWriter{header: http.Header{}}
+		h.ServeHTTP(w, req.WithContext(ctx))
+
+		for name, values := range w.header {
+			for i, value := range values {
+				if i == 0 {
+					c.Set(name, value)
+				} else {
+					c.Response().Header.Add(name, value)
+				}
+			}
+		}
+		return c.Status(w.statusCode()).Send(w.body.Bytes())
+	}
+}
+
+// responseWriter collects a net/http response for Fiber to send
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write implements http.ResponseWriter
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// Flush implements http.Flusher; the response is sent once the handler returns
+func (w *responseWriter) Flush() {}
+
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
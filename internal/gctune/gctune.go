@@ -0,0 +1,119 @@
+// Package gctune sizes the garbage collector for the container the app runs in and
+// exports what collection costs. The runtime reads GOGC and GOMEMLIMIT itself; without
+// GOMEMLIMIT it has no memory limit, so a heap growing towards a small container's limit
+// is not collected harder and the process is OOM-killed instead.
+package gctune
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+
+	appmetrics "main.go/internal/metrics"
+)
+
+// cgroupLimitFiles hold the container's memory limit under cgroup v2 and v1
+var cgroupLimitFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// Settings are the collector's effective settings
+type Settings struct {
+	// MemoryLimit is the soft memory limit in bytes; math.MaxInt64 when there is none
+	MemoryLimit int64
+	// MemoryLimitSource is where the limit came from: "GOMEMLIMIT", "cgroup", or "none"
+	MemoryLimitSource string
+	// ContainerLimit is the container's memory limit in bytes; 0 when unlimited or unknown
+	ContainerLimit int64
+	// GCPercent is GOGC; -1 when collection is off
+	GCPercent int
+}
+
+// Apply sets the soft memory limit to ratio of the container's memory limit, split
+// between procs processes sharing it (prefork), unless GOMEMLIMIT is set or ratio is 0,
+// and returns the settings in effect
+func Apply(ratio float64, procs int) Settings {
+	s := Settings{MemoryLimitSource: "none", ContainerLimit: containerLimit()}
+	switch {
+	case os.Getenv("GOMEMLIMIT") != "":
+		s.MemoryLimitSource = "GOMEMLIMIT"
+	case ratio > 0 && s.ContainerLimit > 0:
+		if procs < 1 {
+			procs = 1
+		}
+		debug.SetMemoryLimit(int64(float64(s.ContainerLimit) * ratio / float64(procs)))
+		s.MemoryLimitSource = "cgroup"
+	}
+	s.MemoryLimit = debug.SetMemoryLimit(-1)
+	s.GCPercent = gcPercent()
+	return s
+}
+
+// containerLimit reads the cgroup memory limit, or 0 outside a limited cgroup
+func containerLimit() int64 {
+	for _, path := range cgroupLimitFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		// cgroup v1 reports no limit as a value near the maximum
+		if err != nil || limit <= 0 || limit >= math.MaxInt64/2 {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
+
+// gcPercent reads GOGC; debug.SetGCPercent is its only accessor
+func gcPercent() int {
+	percent := debug.SetGCPercent(100)
+	debug.SetGCPercent(percent)
+	return percent
+}
+
+// RegisterMetrics exports the memory limit and how much time collection takes
+func RegisterMetrics() {
+	appmetrics.NewGaugeFunc("runtime", "memory_limit_bytes", "Soft memory limit of the Go runtime (GOMEMLIMIT)", func() float64 {
+		return float64(debug.SetMemoryLimit(-1))
+	})
+	appmetrics.NewGaugeFunc("runtime", "heap_bytes", "Bytes of heap memory in use, including objects not yet collected", func() float64 {
+		return float64(readUint64("/memory/classes/heap/objects:bytes"))
+	})
+	appmetrics.NewGaugeFunc("runtime", "gc_cpu_fraction", "Share of the process's CPU time spent collecting garbage since start", func() float64 {
+		samples := []metrics.Sample{{Name: "/cpu/classes/gc/total:cpu-seconds"}, {Name: "/cpu/classes/total:cpu-seconds"}}
+		metrics.Read(samples)
+		if samples[0].Value.Kind() != metrics.KindFloat64 || samples[1].Value.Kind() != metrics.KindFloat64 ||
+			samples[1].Value.Float64() == 0 {
+			return 0
+		}
+		return samples[0].Value.Float64() / samples[1].Value.Float64()
+	})
+	appmetrics.NewCounterFunc("runtime", "gc_pause_seconds_total", "Time the program was stopped for garbage collection", func() float64 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return float64(stats.PauseTotalNs) / 1e9
+	})
+	appmetrics.NewCounterFunc("runtime", "gc_cycles_total", "Completed garbage collection cycles", func() float64 {
+		return float64(readUint64("/gc/cycles/total:gc-cycles"))
+	})
+}
+
+func readUint64(name string) uint64 {
+	sample := []metrics.Sample{{Name: name}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"time"
-
 	"github.com/gofiber/fiber/v2"
 
+	"main.go/internal/clock"
 	"main.go/internal/config"
+	"main.go/internal/middleware"
 	"main.go/internal/templates/pages"
 )
 
@@ -32,7 +32,7 @@ func (h *APIHandler) Welcome(c *fiber.Ctx) error {
 // Homepage renders the HTML landing page with health links
 func (h *APIHandler) Homepage(c *fiber.Ctx) error {
 	c.Set("Content-Type", "text/html; charset=utf-8")
-	return pages.HomePage(h.appName(), h.environment(), h.featureStatuses()).Render(c.Context(), c.Response().BodyWriter())
+	return pages.HomePage(h.appName(), h.environment(), h.featureStatuses(), middleware.CSRFToken(c)).Render(c.Context(), c.Response().BodyWriter())
 }
 
 // Status returns the API status
@@ -40,7 +40,7 @@ func (h *APIHandler) Status(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status":    "ok",
 		"service":   h.appName(),
-		"timestamp": time.Now().UTC(),
+		"timestamp": clock.Now(c).UTC(),
 		"environment": fiber.Map{
 			"env":      h.environment(),
 			"hostname": c.App().Config().ServerHeader,
@@ -53,6 +53,7 @@ func (h *APIHandler) Status(c *fiber.Ctx) error {
 			"aws":      h.cfg != nil && h.cfg.AWSEnabled(),
 			"pusher":   h.cfg != nil && h.cfg.PusherEnabled(),
 		},
+		"sandbox": h.cfg != nil && h.cfg.Sandbox,
 		"endpoints": fiber.Map{
 			"health": "/health",
 			"ready":  "/ready",
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// CaptionRequest is the payload for changing an attachment's caption
+type CaptionRequest struct {
+	Caption string `json:"caption" validate:"max=500"`
+}
+
+// ReorderAttachmentsRequest lists every attachment of a resource in its new order
+type ReorderAttachmentsRequest struct {
+	IDs []string `json:"ids" validate:"required,max=1000,dive,uuid"`
+}
+
+// AttachmentHandler lists, uploads, orders, captions, and removes the files attached to
+// resources, as allowed by each resource type's rules
+type AttachmentHandler struct {
+	manager   *attachments.Manager
+	validator *validation.Validator
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(m *attachments.Manager) *AttachmentHandler {
+	return &AttachmentHandler{manager: m, validator: validation.NewValidator()}
+}
+
+// List returns a resource's attachments in order
+func (h *AttachmentHandler) List(c *fiber.Ctx) error {
+	resourceType, resourceID := c.Params("type"), c.Params("id")
+	if !h.manager.CanRead(c, resourceType, resourceID) {
+		return utils.NotFound(c, "Resource not found")
+	}
+	list, err := h.manager.Store().List(c.UserContext(), resourceType, resourceID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load attachments")
+	}
+	return utils.SuccessResponse(c, list, "Attachments")
+}
+
+// Upload attaches the multipart "file" field, with an optional "caption", to a resource
+func (h *AttachmentHandler) Upload(c *fiber.Ctx) error {
+	resourceType, resourceID := c.Params("type"), c.Params("id")
+	if ok, err := h.authorize(c, resourceType, resourceID); !ok {
+		return err
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "File is required")
+	}
+	caption := c.FormValue("caption")
+	if err := h.validator.ValidateVar(caption, "max=500"); err != nil {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "caption", "Caption must be at most 500 characters")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return utils.BadRequest(c, "Failed to read upload")
+	}
+	defer file.Close()
+
+	attachment, err := h.manager.Attach(c.UserContext(), resourceType, resourceID, attachments.Upload{
+		Filename:   header.Filename,
+		Size:       header.Size,
+		Body:       file,
+		Caption:    caption,
+		UploadedBy: auth.UserID(c),
+	})
+	switch {
+	case errors.Is(err, attachments.ErrTooLarge):
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File is too large",
+			fiber.NewError(fiber.StatusRequestEntityTooLarge, "File is too large"))
+	case errors.Is(err, attachments.ErrTypeNotAllowed):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "This type of file is not allowed")
+	case errors.Is(err, attachments.ErrInfected):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "This file appears to contain malware")
+	case errors.Is(err, attachments.ErrTooMany):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "No more files can be attached")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to store attachment")
+	}
+
+	return utils.JSON(c, fiber.StatusCreated, attachment, "File attached")
+}
+
+// Reorder sets the order of a resource's attachments
+func (h *AttachmentHandler) Reorder(c *fiber.Ctx) error {
+	resourceType, resourceID := c.Params("type"), c.Params("id")
+	if ok, err := h.authorize(c, resourceType, resourceID); !ok {
+		return err
+	}
+
+	var req ReorderAttachmentsRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	err := h.manager.Store().Reorder(c.UserContext(), resourceType, resourceID, req.IDs)
+	if errors.Is(err, attachments.ErrOrderMismatch) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "ids", "List every attachment of the resource exactly once")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to reorder attachments")
+	}
+	list, err := h.manager.Store().List(c.UserContext(), resourceType, resourceID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load attachments")
+	}
+	return utils.SuccessResponse(c, list, "Attachments reordered")
+}
+
+// Caption changes an attachment's caption
+func (h *AttachmentHandler) Caption(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+
+	var req CaptionRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	updated, err := h.manager.Store().SetCaption(c.UserContext(), attachment.ID, req.Caption)
+	if errors.Is(err, attachments.ErrNotFound) {
+		return utils.NotFound(c, "Attachment not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to update attachment")
+	}
+	return utils.SuccessResponse(c, updated, "Caption updated")
+}
+
+// Remove detaches a file; the stored file is deleted in the background
+func (h *AttachmentHandler) Remove(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+	err = h.manager.Remove(c.UserContext(), attachment.ID)
+	if errors.Is(err, attachments.ErrNotFound) {
+		return utils.NotFound(c, "Attachment not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to remove attachment")
+	}
+	return utils.SuccessResponse(c, nil, "Attachment removed")
+}
+
+// authorize reports whether the caller may change the resource's attachments, writing
+// the error response when not. Resources the caller cannot even see are reported missing.
+func (h *AttachmentHandler) authorize(c *fiber.Ctx, resourceType, resourceID string) (bool, error) {
+	if h.manager.CanWrite(c, resourceType, resourceID) {
+		return true, nil
+	}
+	if h.manager.CanRead(c, resourceType, resourceID) {
+		return false, utils.Forbidden(c, "You cannot change these attachments")
+	}
+	return false, utils.NotFound(c, "Resource not found")
+}
+
+// find loads the attachment named by the :id parameter if the caller may change it. A nil
+// attachment means the error response has been written.
+func (h *AttachmentHandler) find(c *fiber.Ctx) (*attachments.Attachment, error) {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return nil, utils.NotFound(c, "Attachment not found")
+	}
+	attachment, err := h.manager.Store().Find(c.UserContext(), id)
+	if errors.Is(err, attachments.ErrNotFound) || (err == nil && !h.manager.CanRead(c, attachment.ResourceType, attachment.ResourceID)) {
+		return nil, utils.NotFound(c, "Attachment not found")
+	}
+	if err != nil {
+		return nil, utils.InternalServerError(c, "Failed to load attachment")
+	}
+	if ok, err := h.authorize(c, attachment.ResourceType, attachment.ResourceID); !ok {
+		return nil, err
+	}
+	return attachment, nil
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/auth"
+	"main.go/internal/auth/active"
+	"main.go/internal/auth/lockout"
+	"main.go/internal/auth/remember"
+	"main.go/internal/bodyparser"
+	"main.go/internal/clock"
+	"main.go/internal/contentfilter"
+	"main.go/internal/invites"
+	"main.go/internal/logger"
+	"main.go/internal/moderation"
+	"main.go/internal/referrals"
+	"main.go/internal/security"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// RegisterRequest is the payload for creating an account
+type RegisterRequest struct {
+	Email     string `json:"email" form:"email" validate:"required,email,max=255"`
+	Username  string `json:"username" form:"username" validate:"required,username,clean=reject"`
+	FirstName string `json:"first_name" form:"first_name" validate:"required,min=1,max=100,clean"`
+	LastName  string `json:"last_name" form:"last_name" validate:"required,min=1,max=100,clean"`
+	Password  string `json:"password" form:"password" validate:"required,max=128,password,not_breached"`
+	// InviteCode is required while registration is invite-only (INVITE_ONLY)
+	InviteCode string `json:"invite_code" form:"invite_code" validate:"max=64"`
+	// ReferralCode credits a referrer; defaults to the code captured from a referral link
+	ReferralCode string `json:"referral_code" form:"referral_code" validate:"max=32"`
+}
+
+// LoginRequest is the payload for logging in with an email or username
+type LoginRequest struct {
+	Login    string `json:"login" form:"login" validate:"required,max=255"`
+	Password string `json:"password" form:"password" validate:"required,max=128"`
+	// Remember keeps the user logged in on this device after the session expires (session mode)
+	Remember bool `json:"remember" form:"remember"`
+}
+
+// RefreshTokenRequest is the payload for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// AuthHandler exposes registration, login, logout, token refresh, and the caller's identity.
+// Exactly one of tokens (AUTH=jwt) or sessions (AUTH=session) is set.
+type AuthHandler struct {
+	tokens    *auth.TokenService
+	sessions  *auth.Sessions
+	users     *users.Store
+	validator *validation.Validator
+	// verification, when set, emails a confirmation link after registration
+	verification *EmailVerificationHandler
+	// lockout, when set, throttles repeated failed logins
+	lockout *lockout.Guard
+	// remember, when set, issues remember-me cookies for logins that ask for one
+	remember *remember.Manager
+	// active, when set, tracks logins so users can list and revoke them
+	active *active.Registry
+	// invites, when set, makes registration require an invite code
+	invites *invites.Store
+	// referrals, when set, attributes new accounts to the user who referred them
+	referrals *referrals.Program
+	// moderation, when set, screens new profiles for spam in the background
+	moderation *moderation.Moderator
+	// contentFilter, when set in mask mode, masks profanity and personal information in names
+	contentFilter *contentfilter.Filter
+	logger        *logger.Logger
+}
+
+// NewAuthHandler creates a new auth handler; userStore may be nil when no database is configured
+func NewAuthHandler(tokens *auth.TokenService, sessions *auth.Sessions, userStore *users.Store) *AuthHandler {
+	return &AuthHandler{
+		tokens:    tokens,
+		sessions:  sessions,
+		users:     userStore,
+		validator: validation.NewValidator(),
+	}
+}
+
+// UseEmailVerification sends a verification email to every newly registered account
+func (h *AuthHandler) UseEmailVerification(v *EmailVerificationHandler) {
+	h.verification = v
+}
+
+// UseLockout locks accounts and IPs after repeated failed logins
+func (h *AuthHandler) UseLockout(g *lockout.Guard, l *logger.Logger) {
+	h.lockout = g
+	h.logger = l
+}
+
+// UseRemember lets session logins opt into a remember-me cookie
+func (h *AuthHandler) UseRemember(m *remember.Manager, l *logger.Logger) {
+	h.remember = m
+	h.logger = l
+}
+
+// UseActiveSessions tracks every login as an active session
+func (h *AuthHandler) UseActiveSessions(r *active.Registry) {
+	h.active = r
+}
+
+// UseInvites requires an invite code to register
+func (h *AuthHandler) UseInvites(store *invites.Store) {
+	h.invites = store
+}
+
+// UseReferrals attributes registrations to referral codes
+func (h *AuthHandler) UseReferrals(p *referrals.Program) {
+	h.referrals = p
+}
+
+// UseModeration screens the profiles of new accounts for spam
+func (h *AuthHandler) UseModeration(m *moderation.Moderator) {
+	h.moderation = m
+}
+
+// UseContentFilter masks profanity and personal information in new accounts' names when
+// the filter is in mask mode; reject mode is enforced by the clean validation tag
+func (h *AuthHandler) UseContentFilter(f *contentfilter.Filter) {
+	h.contentFilter = f
+}
+
+// Register creates an account with an argon2id-hashed password and logs the new user in
+func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	if h.users == nil {
+		return h.storeUnavailable(c)
+	}
+
+	var req RegisterRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	maskFields(h.contentFilter, &req.FirstName, &req.LastName)
+
+	// Take a use of the invite up front so concurrent signups cannot exceed its limit
+	var inviteID string
+	if h.invites != nil {
+		if req.InviteCode == "" {
+			return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "invite_code", "An invite code is required to register")
+		}
+		id, err := h.invites.Reserve(c.UserContext(), req.InviteCode, clock.Now(c))
+		if errors.Is(err, invites.ErrInvalidCode) {
+			return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "invite_code", "Invite code is invalid, expired, or used up")
+		}
+		if err != nil {
+			return utils.InternalServerError(c, "Failed to check invite code")
+		}
+		inviteID = id
+	}
+
+	user, err := h.users.Create(c.UserContext(), users.NewUser{
+		Email:     req.Email,
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Password:  req.Password,
+	})
+	if err != nil && inviteID != "" {
+		_ = h.invites.Release(c.UserContext(), inviteID)
+	}
+	if errors.Is(err, users.ErrUserExists) {
+		return utils.NewValidationResponseBuilder(c).Conflict("An account with that email or username already exists")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to create account")
+	}
+	if inviteID != "" {
+		if err := h.invites.Record(c.UserContext(), inviteID, user.ID); err != nil {
+			return utils.InternalServerError(c, "Account created but the invite could not be recorded")
+		}
+	}
+
+	if h.referrals != nil {
+		h.referrals.Attribute(c, user.ID, req.ReferralCode)
+	}
+	if h.moderation != nil {
+		h.moderation.Submit(profileContent(c, user))
+	}
+
+	if h.verification != nil {
+		h.verification.Send(c, user)
+	}
+
+	session, err := h.startSession(c, user.Principal())
+	if err != nil {
+		return utils.InternalServerError(c, "Account created but login failed")
+	}
+
+	return utils.JSON(c, fiber.StatusCreated, fiber.Map{"user": user, "session": session}, "Account created")
+}
+
+// Login verifies credentials and starts a session or issues a token pair
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	if h.users == nil {
+		return h.storeUnavailable(c)
+	}
+
+	var req LoginRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	if h.lockout != nil {
+		if locked, ok := lockout.AsLocked(h.throttle(c, h.lockout.Check(c.UserContext(), req.Login, c.IP()))); ok {
+			return h.lockedResponse(c, locked)
+		}
+	}
+
+	principal, err := h.users.CheckCredentials(c.UserContext(), req.Login, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		security.Emit(c, security.EventLoginFailed, map[string]string{"login": req.Login})
+		if h.lockout != nil {
+			if locked, ok := lockout.AsLocked(h.throttle(c, h.lockout.Failure(c.UserContext(), req.Login, c.IP()))); ok {
+				security.Emit(c, security.EventAccountLocked, map[string]string{"login": req.Login, "scope": locked.Scope})
+				return h.lockedResponse(c, locked)
+			}
+		}
+		return utils.Unauthorized(c, "Invalid login or password")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to verify credentials")
+	}
+	if h.lockout != nil {
+		_ = h.throttle(c, h.lockout.Success(c.UserContext(), req.Login))
+	}
+
+	session, err := h.startSession(c, principal)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to log in")
+	}
+	// The session is already established, so a failure here only costs the user a later login
+	if req.Remember && h.remember != nil && h.sessions != nil {
+		if err := h.remember.Remember(c, principal); err != nil {
+			h.logger.Error("Failed to issue remember-me token", zap.String("user_id", principal.ID), zap.Error(err))
+		}
+	}
+	return utils.SuccessResponse(c, session, "Logged in")
+}
+
+// startSession logs the principal in: a server-side session in session mode, or a
+// token pair in JWT mode. The returned value is what the client needs to continue.
+func (h *AuthHandler) startSession(c *fiber.Ctx, principal *auth.Principal) (interface{}, error) {
+	if h.sessions != nil {
+		if err := h.sessions.Login(c, principal); err != nil {
+			return nil, err
+		}
+		return principal, nil
+	}
+	return h.issueTokens(c, principal)
+}
+
+// issueTokens issues a token pair and tracks it so it can be revoked by logout-all. A
+// principal without a session starts one; refreshed pairs stay in their session.
+func (h *AuthHandler) issueTokens(c *fiber.Ctx, principal *auth.Principal) (*auth.TokenPair, error) {
+	if principal.SessionID == "" {
+		principal.Method = auth.MethodJWT
+		if err := h.active.Start(c, principal, clock.Now(c).Add(h.tokens.RefreshTTL())); err != nil {
+			return nil, err
+		}
+	}
+	pair, err := h.tokens.Issue(principal)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.tokens.Revocations().Track(c.UserContext(), principal.ID, pair); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// restartSessions signs the user out everywhere after a credential change and starts a
+// fresh session for the caller: remembered devices are forgotten and, in JWT mode with
+// revocation enabled, every issued token is revoked before a new pair is issued
+func (h *AuthHandler) restartSessions(c *fiber.Ctx, principal *auth.Principal) (interface{}, error) {
+	if err := h.signOutEverywhere(c, principal.ID); err != nil {
+		return nil, err
+	}
+	return h.startSession(c, principal)
+}
+
+// signOutEverywhere forgets the user's remembered devices, revokes their tokens, and
+// ends their active sessions. Without session tracking, server-side sessions on other
+// devices end when they expire.
+func (h *AuthHandler) signOutEverywhere(c *fiber.Ctx, userID string) error {
+	if _, err := h.active.RevokeAll(c, userID); err != nil {
+		return err
+	}
+	if h.remember != nil {
+		if err := h.remember.Forget(c); err != nil {
+			return err
+		}
+		if _, err := h.remember.Store().RevokeAll(c.UserContext(), userID); err != nil {
+			return err
+		}
+	}
+	if h.tokens != nil {
+		if _, err := h.tokens.Revocations().RevokeAll(c.UserContext(), userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throttle passes lockout decisions through and logs store failures. Logins fail open
+// when the lockout store is unavailable so an outage does not lock everyone out.
+func (h *AuthHandler) throttle(c *fiber.Ctx, err error) error {
+	if _, ok := lockout.AsLocked(err); ok || err == nil {
+		return err
+	}
+	h.logger.Error("Login lockout store unavailable", zap.String("ip", c.IP()), zap.Error(err))
+	return nil
+}
+
+// lockedResponse answers 423 for a locked account and 429 for a throttled IP
+func (h *AuthHandler) lockedResponse(c *fiber.Ctx, locked *lockout.LockedError) error {
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+
+	status, title, message := fiber.StatusLocked, "Locked", "Too many failed logins; the account is temporarily locked"
+	if locked.Scope == lockout.ScopeIP {
+		status, title, message = fiber.StatusTooManyRequests, "Too Many Requests", "Too many failed logins from this address"
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error":       title,
+		"message":     message,
+		"status":      status,
+		"retry_after": seconds,
+	})
+}
+
+func (h *AuthHandler) storeUnavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error":   "Service Unavailable",
+		"message": "Accounts require FEATURE_DATABASE=true",
+		"status":  fiber.StatusServiceUnavailable,
+	})
+}
+
+// Logout ends the session and forgets the device's remember-me token. In JWT mode the presented access token, and the refresh token
+// when one is posted, are revoked if revocation is enabled; otherwise clients simply
+// discard their tokens.
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	if err := h.active.End(c); err != nil {
+		return utils.InternalServerError(c, "Failed to end session")
+	}
+	if h.sessions != nil {
+		if h.remember != nil {
+			if err := h.remember.Forget(c); err != nil {
+				return utils.InternalServerError(c, "Failed to forget device")
+			}
+		}
+		if err := h.sessions.Logout(c); err != nil {
+			return utils.InternalServerError(c, "Failed to end session")
+		}
+		return utils.SuccessResponse(c, nil, "Logged out")
+	}
+
+	revocations := h.tokens.Revocations()
+	if !revocations.Enabled() {
+		return utils.SuccessResponse(c, nil, "Logged out")
+	}
+	ctx := c.UserContext()
+
+	if p, ok := auth.PrincipalFrom(c); ok && p.Method == auth.MethodJWT {
+		if _, err := revocations.Revoke(ctx, p.ID, p.TokenID, p.TokenExpiresAt); err != nil {
+			return utils.InternalServerError(c, "Failed to revoke tokens")
+		}
+	}
+
+	// The body is optional; a refresh token that fails to parse has nothing left to revoke
+	var req RefreshTokenRequest
+	if err := bodyparser.Parse(c, &req); err == nil && req.RefreshToken != "" {
+		if claims, err := h.tokens.Parse(req.RefreshToken, auth.TokenTypeRefresh); err == nil {
+			if _, err := revocations.Revoke(ctx, claims.Subject, claims.ID, claims.ExpiresAt.Time); err != nil {
+				return utils.InternalServerError(c, "Failed to revoke tokens")
+			}
+		}
+	}
+	return utils.SuccessResponse(c, nil, "Logged out")
+}
+
+// LogoutAll revokes every access and refresh token issued to the caller
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	if h.tokens == nil || !h.tokens.Revocations().Enabled() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "Service Unavailable",
+			"message": "Logging out everywhere requires AUTH=JWT and FEATURE_CACHE=true",
+			"status":  fiber.StatusServiceUnavailable,
+		})
+	}
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+
+	revoked, err := h.tokens.Revocations().RevokeAll(c.UserContext(), userID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to revoke tokens")
+	}
+	if _, err := h.active.RevokeAll(c, userID); err != nil {
+		return utils.InternalServerError(c, "Failed to end sessions")
+	}
+	return utils.SuccessResponse(c, fiber.Map{"revoked": revoked}, "Logged out everywhere")
+}
+
+// Devices lists the caller's remembered devices; the route sits behind auth.Required()
+func (h *AuthHandler) Devices(c *fiber.Ctx) error {
+	userID := auth.MustCurrentUser[auth.Principal](c).ID
+	devices, err := h.remember.Store().List(c.UserContext(), userID, clock.Now(c))
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load devices")
+	}
+	current, err := h.remember.CurrentDevice(c)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load devices")
+	}
+	for i := range devices {
+		devices[i].Current = devices[i].ID == current
+	}
+	return utils.SuccessResponse(c, devices, "Remembered devices")
+}
+
+// RevokeDevice forgets one of the caller's remembered devices. Its current session, if
+// any, lasts until it expires.
+func (h *AuthHandler) RevokeDevice(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Device not found")
+	}
+
+	userID := auth.MustCurrentUser[auth.Principal](c).ID
+	err := h.remember.Store().Revoke(c.UserContext(), userID, id)
+	if errors.Is(err, remember.ErrDeviceNotFound) {
+		return utils.NotFound(c, "Device not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to revoke device")
+	}
+	return utils.SuccessResponse(c, nil, "Device revoked")
+}
+
+// Sessions lists the caller's active sessions; the route sits behind auth.Required()
+func (h *AuthHandler) Sessions(c *fiber.Ctx) error {
+	userID := auth.MustCurrentUser[auth.Principal](c).ID
+	sessions, err := h.active.List(c, userID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load sessions")
+	}
+	if sessions == nil {
+		sessions = []active.Session{}
+	}
+	return utils.SuccessResponse(c, sessions, "Active sessions")
+}
+
+// RevokeSession ends one of the caller's sessions: a browser session is logged out on
+// its next request and a token session can no longer be used or refreshed
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Session not found")
+	}
+
+	userID := auth.MustCurrentUser[auth.Principal](c).ID
+	err := h.active.Revoke(c, userID, id)
+	if errors.Is(err, active.ErrNotFound) {
+		return utils.NotFound(c, "Session not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to revoke session")
+	}
+	return utils.SuccessResponse(c, nil, "Session revoked")
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshTokenRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	claims, err := h.tokens.Parse(req.RefreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		return utils.Unauthorized(c, err.Error())
+	}
+
+	// Refresh tokens are single use: revoking on exchange makes a replayed token fail
+	fresh, err := h.tokens.Revocations().Revoke(c.UserContext(), claims.Subject, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to check token revocation")
+	}
+	if !fresh {
+		return utils.Unauthorized(c, auth.ErrTokenRevoked.Error())
+	}
+
+	principal := claims.Principal()
+	err = h.active.Extend(c, principal, clock.Now(c).Add(h.tokens.RefreshTTL()))
+	if errors.Is(err, active.ErrNotFound) {
+		return utils.Unauthorized(c, "Session has been revoked")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to check session")
+	}
+
+	pair, err := h.issueTokens(c, principal)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to issue tokens")
+	}
+
+	return utils.SuccessResponse(c, pair, "Tokens refreshed")
+}
+
+// Me returns the authenticated principal; the route sits behind auth.Required()
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, auth.MustCurrentUser[auth.Principal](c), "Authenticated user")
+}
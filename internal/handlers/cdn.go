@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/bodyparser"
+	"main.go/internal/cdn"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// PurgeCacheRequest is the payload for purging the CDN; set either keys or all
+type PurgeCacheRequest struct {
+	Keys []string `json:"keys" validate:"required_without=All,max=256,dive,required,max=255"`
+	All  bool     `json:"all"`
+}
+
+// CDNHandler lets admins purge cached responses by surrogate key
+type CDNHandler struct {
+	cdn       *cdn.Service
+	validator *validation.Validator
+}
+
+// NewCDNHandler creates a new CDN handler
+func NewCDNHandler(service *cdn.Service) *CDNHandler {
+	return &CDNHandler{cdn: service, validator: validation.NewValidator()}
+}
+
+// Purge drops cached responses tagged with the given keys, or everything
+func (h *CDNHandler) Purge(c *fiber.Ctx) error {
+	var req PurgeCacheRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	var err error
+	if req.All {
+		err = h.cdn.PurgeAll(c.UserContext())
+	} else {
+		err = h.cdn.Purge(c.UserContext(), req.Keys...)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "CDN rejected the purge request", err)
+	}
+	return utils.SuccessResponse(c, fiber.Map{"provider": h.cdn.Provider(), "keys": req.Keys, "all": req.All}, "Purge requested")
+}
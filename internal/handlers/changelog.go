@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/changelog"
+	"main.go/internal/clock"
+	"main.go/internal/templates/pages"
+	"main.go/internal/utils"
+)
+
+// ChangelogRelease is a release in the JSON changelog; Unseen is set for signed-in users
+type ChangelogRelease struct {
+	changelog.Release
+	Unseen bool `json:"unseen"`
+}
+
+// ChangelogHandler serves release notes as a page and as JSON
+type ChangelogHandler struct {
+	appName string
+	log     *changelog.Changelog
+	store   *changelog.Store
+}
+
+// NewChangelogHandler creates a changelog handler; store may be nil without a database,
+// in which case nothing is flagged as unseen
+func NewChangelogHandler(appName string, log *changelog.Changelog, store *changelog.Store) *ChangelogHandler {
+	return &ChangelogHandler{appName: appName, log: log, store: store}
+}
+
+// Page renders the release notes; it is the same for every visitor, so it can be cached
+func (h *ChangelogHandler) Page(c *fiber.Ctx) error {
+	view := pages.ChangelogView{AppName: h.appName}
+	for _, release := range h.log.Releases(clock.Now(c)) {
+		view.Releases = append(view.Releases, pages.ChangelogRelease{
+			Version:  release.Version,
+			Title:    release.Title,
+			Date:     release.PublishedAt.Format("Jan 2, 2006"),
+			DateTime: release.PublishedAt.Format(time.DateOnly),
+			HTML:     release.HTML,
+		})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return pages.ChangelogPage(view).Render(c.Context(), c.Response().BodyWriter())
+}
+
+// List returns the releases, newest first. For signed-in users each release says whether
+// it is new since they last marked the changelog seen, and unseen counts them.
+func (h *ChangelogHandler) List(c *fiber.Ctx) error {
+	releases := h.log.Releases(clock.Now(c))
+	list := make([]ChangelogRelease, len(releases))
+	for i, release := range releases {
+		list[i] = ChangelogRelease{Release: release}
+	}
+	data := fiber.Map{"releases": list}
+
+	if userID := auth.UserID(c); userID != "" && h.store != nil {
+		seenAt, err := h.store.SeenAt(c.UserContext(), userID)
+		if err != nil {
+			return utils.InternalServerError(c, "Failed to load changelog state")
+		}
+		unseen := changelog.Unseen(releases, seenAt)
+		for i := 0; i < unseen; i++ {
+			list[i].Unseen = true
+		}
+		data["unseen"] = unseen
+	}
+	return utils.SuccessResponse(c, data, "Changelog")
+}
+
+// MarkSeen records that the caller has seen every published release
+func (h *ChangelogHandler) MarkSeen(c *fiber.Ctx) error {
+	releases := h.log.Releases(clock.Now(c))
+	if len(releases) > 0 {
+		err := h.store.MarkSeen(c.UserContext(), auth.MustCurrentUser[auth.Principal](c).ID, releases[0].PublishedAt)
+		if err != nil {
+			return utils.InternalServerError(c, "Failed to update changelog state")
+		}
+	}
+	return utils.SuccessResponse(c, fiber.Map{"unseen": 0}, "Changelog marked as seen")
+}
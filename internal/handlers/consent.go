@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/bodyparser"
+	"main.go/internal/clock"
+	"main.go/internal/consent"
+	"main.go/internal/utils"
+)
+
+// ConsentUpdateRequest is the payload for recording consent decisions
+type ConsentUpdateRequest struct {
+	Categories map[string]bool `json:"categories"`
+}
+
+// ConsentHandler exposes the cookie consent / privacy preference API
+type ConsentHandler struct {
+	manager *consent.Manager
+}
+
+// NewConsentHandler creates a new consent handler
+func NewConsentHandler(manager *consent.Manager) *ConsentHandler {
+	return &ConsentHandler{manager: manager}
+}
+
+// Get returns the visitor's current consent decision
+func (h *ConsentHandler) Get(c *fiber.Ctx) error {
+	prefs := h.manager.Load(c)
+
+	return utils.SuccessResponse(c, fiber.Map{
+		"categories": consent.Categories,
+		"decided":    prefs != nil,
+		"consent":    h.effective(prefs),
+	}, "Consent preferences")
+}
+
+// Update records the visitor's consent decision in a signed cookie
+func (h *ConsentHandler) Update(c *fiber.Ctx) error {
+	var req ConsentUpdateRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+
+	errors := map[string]string{}
+	for category := range req.Categories {
+		if !consent.IsCategory(category) {
+			errors[category] = "unknown consent category"
+		}
+	}
+	if len(errors) > 0 {
+		return utils.NewValidationErrorHelper().HandleMultipleValidationErrors(c, errors)
+	}
+
+	prefs := &consent.Preferences{
+		Categories: map[string]bool{},
+		UpdatedAt:  clock.Now(c).UTC(),
+	}
+	for _, category := range consent.Categories {
+		prefs.Categories[category] = req.Categories[category]
+	}
+
+	if err := h.manager.Save(c, prefs); err != nil {
+		return utils.InternalServerError(c, "Failed to store consent")
+	}
+
+	return utils.SuccessResponse(c, h.effective(prefs), "Consent preferences saved")
+}
+
+// Withdraw clears the consent cookie, revoking every optional category
+func (h *ConsentHandler) Withdraw(c *fiber.Ctx) error {
+	h.manager.Clear(c)
+	return utils.SuccessResponse(c, h.effective(nil), "Consent withdrawn")
+}
+
+func (h *ConsentHandler) effective(prefs *consent.Preferences) map[string]bool {
+	effective := make(map[string]bool, len(consent.Categories))
+	for _, category := range consent.Categories {
+		effective[category] = prefs.Granted(category)
+	}
+	return effective
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/bodyparser"
+	"main.go/internal/captcha"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/metrics"
+	"main.go/internal/security"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+var (
+	contactSubmissions = metrics.NewCounter("contact", "submissions_total", "Contact form messages accepted")
+	contactHoneypot    = metrics.NewCounter("contact", "honeypot_total", "Contact form submissions dropped by the honeypot")
+	contactCaptcha     = metrics.NewCounter("contact", "captcha_failures_total", "Contact form submissions with a missing or rejected captcha")
+)
+
+// captchaFields are the form fields the providers' widgets post their token in, read when
+// captcha_token is not set
+var captchaFields = map[string]string{
+	captcha.ProviderTurnstile: "cf-turnstile-response",
+	captcha.ProviderHCaptcha:  "h-captcha-response",
+	captcha.ProviderRecaptcha: "g-recaptcha-response",
+}
+
+// ContactRequest is a message from the public contact form
+type ContactRequest struct {
+	Name    string `json:"name" form:"name" validate:"required,min=1,max=100"`
+	Email   string `json:"email" form:"email" validate:"required,email,max=255"`
+	Subject string `json:"subject" form:"subject" validate:"max=150"`
+	Message string `json:"message" form:"message" validate:"required,min=10,max=5000"`
+	// Website is a honeypot: the form hides it from people, so only bots fill it in
+	Website string `json:"website" form:"website"`
+	// CaptchaToken is required when a captcha provider is configured (CAPTCHA_PROVIDER)
+	CaptchaToken string `json:"captcha_token" form:"captcha_token"`
+}
+
+// ContactHandler accepts contact form messages and mails them to the team
+type ContactHandler struct {
+	mailer    mail.Mailer
+	to        string
+	captcha   *captcha.Verifier
+	validator *validation.Validator
+	logger    *logger.Logger
+}
+
+// NewContactHandler creates a contact handler that sends messages to the address to.
+// Pass an outbox.Outbox as mailer so messages survive mail server outages.
+func NewContactHandler(mailer mail.Mailer, to string, l *logger.Logger) *ContactHandler {
+	return &ContactHandler{mailer: mailer, to: to, validator: validation.NewValidator(), logger: l}
+}
+
+// UseCaptcha requires a solved captcha with each message
+func (h *ContactHandler) UseCaptcha(v *captcha.Verifier) {
+	h.captcha = v
+}
+
+// Submit validates a message and queues it for delivery
+func (h *ContactHandler) Submit(c *fiber.Ctx) error {
+	var req ContactRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+
+	// Bots get the same answer as people, so they have no reason to adapt
+	if req.Website != "" {
+		contactHoneypot.Inc()
+		security.Emit(c, security.EventHoneypot, nil)
+		return h.accepted(c)
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	if h.captcha != nil {
+		token := req.CaptchaToken
+		if token == "" {
+			token = c.FormValue(captchaFields[h.captcha.Provider()])
+		}
+		err := h.captcha.Verify(c.UserContext(), token, c.IP())
+		if errors.Is(err, captcha.ErrInvalid) {
+			contactCaptcha.Inc()
+			security.Emit(c, security.EventCaptchaFailed, nil)
+			return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "captcha_token", "Please complete the captcha")
+		}
+		if err != nil {
+			h.logger.Warn("Captcha verification unavailable", zap.Error(err))
+			message := "Captcha verification is unavailable; please try again shortly"
+			return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, message, fiber.NewError(fiber.StatusServiceUnavailable, message))
+		}
+	}
+
+	if err := h.mailer.Send(c.UserContext(), h.message(&req)); err != nil {
+		h.logger.Error("Failed to send contact message", zap.Error(err))
+		return utils.InternalServerError(c, "Failed to send your message")
+	}
+	contactSubmissions.Inc()
+	return h.accepted(c)
+}
+
+// message builds the email to the team. It is plain text only: the visitor's input is
+// never rendered as HTML, and replies go straight to them.
+func (h *ContactHandler) message(req *ContactRequest) mail.Message {
+	// Collapse whitespace so a subject cannot carry line breaks into the header
+	subject := strings.Join(strings.Fields(req.Subject), " ")
+	if subject == "" {
+		subject = "New message"
+	}
+	return mail.Message{
+		To:      h.to,
+		ReplyTo: req.Email,
+		Subject: "Contact form: " + subject,
+		Text:    fmt.Sprintf("From: %s <%s>\n\n%s\n", req.Name, req.Email, req.Message),
+	}
+}
+
+func (h *ContactHandler) accepted(c *fiber.Ctx) error {
+	return utils.JSON(c, fiber.StatusAccepted, nil, "Thanks! Your message has been sent")
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/database/datatable"
+	"main.go/internal/utils"
+	"main.go/internal/views"
+)
+
+// dataTable answers a data table request with the page query returns. DataTables gets
+// its own response shape; other clients get the usual envelope around the result. With
+// a view store, ?view=<id> starts from one of the caller's saved views of resource, and
+// the request's own parameters take precedence over the view's.
+func dataTable(c *fiber.Ctx, saved *views.Store, resource string, query func(context.Context, datatable.Request) (*datatable.Result, error)) error {
+	req, err := datatable.FromQuery(c)
+	if err == nil {
+		if id := c.Query("view"); id != "" && saved != nil {
+			view, findErr := findView(c, saved, id)
+			if view == nil {
+				return findErr
+			}
+			if view.Resource != resource {
+				return utils.NotFound(c, "View not found")
+			}
+			view.Apply(&req)
+		}
+
+		var result *datatable.Result
+		if result, err = query(c.UserContext(), req); err == nil {
+			if req.Draw > 0 {
+				return c.JSON(result.DataTables())
+			}
+			return utils.SuccessResponse(c, result, "Table")
+		}
+	}
+
+	if tableError(c, err) {
+		return nil
+	}
+	return utils.InternalServerError(c, "Failed to load table")
+}
+
+// tableError answers a request whose table parameters err rejects with a validation
+// error, reporting whether it did
+func tableError(c *fiber.Ctx, err error) bool {
+	field := ""
+	switch {
+	case errors.Is(err, datatable.ErrInvalidLimit):
+		field = "limit"
+	case errors.Is(err, datatable.ErrInvalidOffset):
+		field = "offset"
+	case errors.Is(err, datatable.ErrUnknownColumn):
+		field = "columns"
+	case errors.Is(err, datatable.ErrNotSortable):
+		field = "sort"
+	case errors.Is(err, datatable.ErrNotSearchable):
+		field = "filter"
+	default:
+		return false
+	}
+	_ = utils.NewValidationErrorHelper().HandleCustomValidationError(c, field, err.Error())
+	return true
+}
+
+// findView loads one of the caller's saved views. A nil view means the error response
+// has been written.
+func findView(c *fiber.Ctx, saved *views.Store, id string) (*views.View, error) {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return nil, utils.NotFound(c, "View not found")
+	}
+	view, err := saved.Find(c.UserContext(), userID, id)
+	if errors.Is(err, views.ErrNotFound) {
+		return nil, utils.NotFound(c, "View not found")
+	}
+	if err != nil {
+		return nil, utils.InternalServerError(c, "Failed to load view")
+	}
+	return view, nil
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/utils"
+)
+
+// DebugRoute is one entry of the route table
+type DebugRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Name   string `json:"name,omitempty"`
+}
+
+// DebugHandler serves development-only diagnostics
+type DebugHandler struct {
+	app     *fiber.App
+	skipped func() []string
+}
+
+// NewDebugHandler creates a debug handler for app's routes; skipped lists the routes left
+// out of this environment
+func NewDebugHandler(app *fiber.App, skipped func() []string) *DebugHandler {
+	return &DebugHandler{app: app, skipped: skipped}
+}
+
+// Routes lists the registered routes, without middleware, and the routes skipped in this
+// environment
+func (h *DebugHandler) Routes(c *fiber.Ctx) error {
+	routes := []DebugRoute{}
+	for _, route := range h.app.GetRoutes(true) {
+		routes = append(routes, DebugRoute{Method: route.Method, Path: route.Path, Name: route.Name})
+	}
+	return utils.SuccessResponse(c, fiber.Map{"routes": routes, "skipped": h.skipped()}, "Routes")
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/database/datatable"
+	"main.go/internal/exports"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+	"main.go/internal/views"
+)
+
+// exportListLimit bounds the exports listed for a user
+const exportListLimit = 100
+
+// ExportRequest is the payload for exporting a data table. ViewID starts from one of the
+// caller's saved views of the resource; the other fields take precedence over the view's.
+type ExportRequest struct {
+	Resource string            `json:"resource" validate:"required,max=63"`
+	ViewID   string            `json:"view_id" validate:"omitempty,uuid"`
+	Search   string            `json:"search" validate:"max=255"`
+	Filters  map[string]string `json:"filters" validate:"max=50,dive,keys,max=63,endkeys,max=255"`
+	Sort     string            `json:"sort" validate:"max=255"`
+	Columns  []string          `json:"columns" validate:"max=50,dive,max=63"`
+}
+
+// ExportHandler starts CSV exports of data tables, lists them, and serves their files
+type ExportHandler struct {
+	exports   *exports.Service
+	views     *views.Store
+	validator *validation.Validator
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(service *exports.Service) *ExportHandler {
+	return &ExportHandler{exports: service, validator: validation.NewValidator()}
+}
+
+// UseViews lets exports start from a saved view
+func (h *ExportHandler) UseViews(store *views.Store) {
+	h.views = store
+}
+
+// Create starts an export of a table the caller can read; the link is emailed once the
+// file is built
+func (h *ExportHandler) Create(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Sign in to export data")
+	}
+	var body ExportRequest
+	if err := bodyparser.Parse(c, &body); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&body); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	src, ok := h.exports.Source(body.Resource)
+	if p, _ := auth.PrincipalFrom(c); !ok || (src.Scope != "" && !p.HasScope(src.Scope)) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "resource", "Unknown resource")
+	}
+
+	req := datatable.Request{Search: body.Search, Filters: body.Filters, Sort: datatable.ParseSort(body.Sort), Columns: body.Columns}
+	if body.ViewID != "" && h.views != nil {
+		view, err := findView(c, h.views, body.ViewID)
+		if view == nil {
+			return err
+		}
+		if view.Resource != body.Resource {
+			return utils.NotFound(c, "View not found")
+		}
+		view.Apply(&req)
+	}
+
+	export, err := h.exports.Request(c.UserContext(), userID, body.Resource, exports.ParamsFrom(req))
+	if err != nil {
+		if tableError(c, err) {
+			return nil
+		}
+		return utils.InternalServerError(c, "Failed to start export")
+	}
+	return utils.JSON(c, fiber.StatusAccepted, export, "Export started; the download link will be emailed to you")
+}
+
+// List returns the caller's recent exports, with download links for those that are ready
+func (h *ExportHandler) List(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Sign in to export data")
+	}
+	list, err := h.exports.Store().ListByUser(c.UserContext(), userID, exportListLimit)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load exports")
+	}
+	for i := range list {
+		list[i].DownloadURL = h.exports.DownloadURL(&list[i])
+	}
+	return utils.SuccessResponse(c, list, "Exports")
+}
+
+// Show returns one of the caller's exports
+func (h *ExportHandler) Show(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID := auth.UserID(c)
+	if userID == "" || h.validator.ValidateVar(id, "uuid") != nil {
+		return utils.NotFound(c, "Export not found")
+	}
+	export, err := h.exports.Store().Find(c.UserContext(), id)
+	if errors.Is(err, exports.ErrNotFound) || (err == nil && export.UserID != userID) {
+		return utils.NotFound(c, "Export not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load export")
+	}
+	export.DownloadURL = h.exports.DownloadURL(export)
+	return utils.SuccessResponse(c, export, "Export")
+}
+
+// Download serves an export's CSV to whoever holds its signed link, so the emailed link
+// works without signing in
+func (h *ExportHandler) Download(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if h.validator.ValidateVar(id, "uuid") != nil {
+		return utils.NotFound(c, "Export not found")
+	}
+	export, body, err := h.exports.Open(c.UserContext(), id, c.Query("token"))
+	switch {
+	case errors.Is(err, exports.ErrExpired):
+		return utils.ErrorResponse(c, fiber.StatusGone, "Export has expired", fiber.NewError(fiber.StatusGone, "Export has expired; request a new one"))
+	case errors.Is(err, exports.ErrNotReady):
+		return utils.NewValidationResponseBuilder(c).Conflict("Export is not ready")
+	case errors.Is(err, exports.ErrNotFound), errors.Is(err, auth.ErrTokenInvalid):
+		return utils.NotFound(c, "Export not found")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to open export")
+	}
+	defer body.Close()
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+export.Filename()+`"`)
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	if _, err := io.Copy(c.Response().BodyWriter(), body); err != nil {
+		c.Response().ResetBody()
+		return utils.InternalServerError(c, "Failed to read export")
+	}
+	return nil
+}
+
+// Table lists every export for admins, as a data table
+func (h *ExportHandler) Table(c *fiber.Ctx) error {
+	return dataTable(c, nil, "", h.exports.Store().Table)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/storage"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// inlineFileTypes are displayed by the browser; every other file is downloaded
+var inlineFileTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+	"text/plain": true,
+	"video/mp4":  true,
+	"video/webm": true,
+}
+
+// fileSecurityPolicy keeps a served file from running script or loading anything, even if
+// a browser renders it as a document
+const fileSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+
+// FileHandler serves attachment contents through the app, so storage URLs are never
+// exposed. Files are read with a signed link or by callers who may read the attachment.
+type FileHandler struct {
+	manager   *attachments.Manager
+	validator *validation.Validator
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(m *attachments.Manager) *FileHandler {
+	return &FileHandler{manager: m, validator: validation.NewValidator()}
+}
+
+// URL returns a signed link to an attachment the caller may read, for <img> tags and
+// downloads that cannot send credentials
+func (h *FileHandler) URL(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+	if !h.manager.CanRead(c, attachment.ResourceType, attachment.ResourceID) {
+		return utils.NotFound(c, "File not found")
+	}
+	link, expiresAt := h.manager.URL(attachment)
+	return utils.SuccessResponse(c, fiber.Map{"url": link, "expires_at": expiresAt}, "File link")
+}
+
+// Serve streams an attachment's contents, honouring single byte ranges. ?download=1
+// downloads files the browser would otherwise display.
+func (h *FileHandler) Serve(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+	if token := c.Query("token"); token != "" {
+		err := h.manager.VerifyURL(attachment, token)
+		if errors.Is(err, auth.ErrTokenExpired) {
+			return utils.Forbidden(c, "This link has expired")
+		}
+		if err != nil {
+			return utils.NotFound(c, "File not found")
+		}
+	} else if !h.manager.CanRead(c, attachment.ResourceType, attachment.ResourceID) {
+		return utils.NotFound(c, "File not found")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentSecurityPolicy, fileSecurityPolicy)
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(attachment, c.QueryBool("download")))
+	return sendRanged(c, `"`+attachment.Checksum+`"`, attachment.ContentType, attachment.Size,
+		func(offset, length int64, partial bool) (io.ReadCloser, error) {
+			if partial {
+				return h.manager.OpenRange(c.UserContext(), attachment, offset, length)
+			}
+			return h.manager.Open(c.UserContext(), attachment)
+		})
+}
+
+// sendRanged answers conditional and single byte range requests for a stored file of
+// size bytes, reading it with open. Other headers, such as caching, are the caller's.
+func sendRanged(c *fiber.Ctx, etag, contentType string, size int64, open func(offset, length int64, partial bool) (io.ReadCloser, error)) error {
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	offset, length, partial := int64(0), size, false
+	// If-Range asks for the range only while the file is unchanged
+	if header := c.Get(fiber.HeaderRange); header != "" && (c.Get(fiber.HeaderIfRange) == "" || c.Get(fiber.HeaderIfRange) == etag) {
+		var ok bool
+		offset, length, partial, ok = parseByteRange(header, size)
+		if !ok {
+			c.Set(fiber.HeaderContentRange, "bytes */"+strconv.FormatInt(size, 10))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+	}
+
+	status := fiber.StatusOK
+	if partial {
+		status = fiber.StatusPartialContent
+		c.Set(fiber.HeaderContentRange, "bytes "+strconv.FormatInt(offset, 10)+"-"+
+			strconv.FormatInt(offset+length-1, 10)+"/"+strconv.FormatInt(size, 10))
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	if c.Method() == fiber.MethodHead {
+		c.Status(status)
+		c.Response().Header.SetContentLength(int(length))
+		return nil
+	}
+
+	body, err := open(offset, length, partial)
+	if errors.Is(err, storage.ErrNotFound) {
+		return utils.NotFound(c, "File not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to read file")
+	}
+	// The body is closed once it has been sent
+	return c.Status(status).SendStream(body, int(length))
+}
+
+// find loads the attachment named by the :id parameter. A nil attachment means the error
+// response has been written.
+func (h *FileHandler) find(c *fiber.Ctx) (*attachments.Attachment, error) {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return nil, utils.NotFound(c, "File not found")
+	}
+	attachment, err := h.manager.Store().Find(c.UserContext(), id)
+	if errors.Is(err, attachments.ErrNotFound) {
+		return nil, utils.NotFound(c, "File not found")
+	}
+	if err != nil {
+		return nil, utils.InternalServerError(c, "Failed to load file")
+	}
+	return attachment, nil
+}
+
+// contentDisposition displays safe types inline and downloads everything else, under the
+// attachment's sanitized name (RFC 2231-encoded when it is not ASCII)
+func contentDisposition(a *attachments.Attachment, download bool) string {
+	disposition := "attachment"
+	if mediaType, _, err := mime.ParseMediaType(a.ContentType); err == nil && inlineFileTypes[mediaType] && !download {
+		disposition = "inline"
+	}
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}); value != "" {
+		return value
+	}
+	return disposition
+}
+
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseByteRange reads a single "bytes=" range against size. ok is false when the range
+// cannot be satisfied. Headers it does not serve (other units, several ranges, or
+// malformed) yield the whole file with partial false, as RFC 9110 allows.
+func parseByteRange(header string, size int64) (offset, length int64, partial, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size, false, true
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, size, false, true
+	}
+
+	if first == "" {
+		// A suffix range: the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, false, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, false, true
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, size, false, true
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, false
+	}
+	return start, end - start + 1, true, true
+}
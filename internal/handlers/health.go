@@ -1,58 +1,145 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 
+	"main.go/internal/clock"
 	"main.go/internal/config"
 	"main.go/internal/database"
+	"main.go/internal/metrics"
 )
 
+// cacheSlowPing is the Redis round trip above which the cache is reported as degraded
+const cacheSlowPing = 100 * time.Millisecond
+
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	cfg *config.Config
-	db  *database.DB
+	cfg         *config.Config
+	db          *database.DB
+	reportingDB *database.DB
+	cache       *redis.Client
+	warnings    []string
+}
+
+// NewHealthHandler creates a new health handler; reportingDB may be nil
+func NewHealthHandler(cfg *config.Config, db *database.DB, reportingDB *database.DB) *HealthHandler {
+	return &HealthHandler{cfg: cfg, db: db, reportingDB: reportingDB}
+}
+
+// UseCache pings client in DetailedCheck and Ready; nil when the cache feature is on but
+// Redis failed to connect
+func (h *HealthHandler) UseCache(client *redis.Client) {
+	h.cache = client
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(cfg *config.Config, db *database.DB) *HealthHandler {
-	return &HealthHandler{cfg: cfg, db: db}
+// UseWarnings reports misconfigurations that do not stop the app from serving: Check and
+// DetailedCheck list them and answer "degraded", still with 200 so instances stay in
+// rotation
+func (h *HealthHandler) UseWarnings(warnings []string) {
+	h.warnings = warnings
 }
 
-// Check returns a basic health check handler
+// warn adds the configuration warnings to a healthy response
+func (h *HealthHandler) warn(response fiber.Map) {
+	if len(h.warnings) == 0 {
+		return
+	}
+	response["status"] = "degraded"
+	response["message"] = "Service is running with configuration warnings"
+	response["warnings"] = h.warnings
+}
+
+// Check returns a basic health check handler. It pings the database when one is enabled
+// and responds 503 when the ping fails.
 func (h *HealthHandler) Check(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"status":      "ok",
 		"message":     "Service is healthy",
-		"timestamp":   time.Now().UTC(),
+		"timestamp":   clock.Now(c).UTC(),
 		"environment": h.environment(),
-	})
+	}
+	if h.cfg != nil && h.cfg.DatabaseEnabled() {
+		database, ok := checkDatabase(c.UserContext(), h.db)
+		response["database"] = database
+		if !ok {
+			response["status"] = "degraded"
+			response["message"] = "Database is unreachable"
+			return c.Status(http.StatusServiceUnavailable).JSON(response)
+		}
+	}
+	h.warn(response)
+	return c.JSON(response)
 }
 
 // DetailedCheck returns a detailed health check handler
 func (h *HealthHandler) DetailedCheck(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{
+	checks, problem := h.featureStatus(c.UserContext())
+	response := fiber.Map{
 		"status":      "ok",
 		"message":     "Service is healthy",
-		"timestamp":   time.Now().UTC(),
+		"timestamp":   clock.Now(c).UTC(),
 		"environment": h.environment(),
-		"checks":      h.featureStatus(),
-	})
+		"checks":      checks,
+		"metrics":     metrics.Default.Snapshot(),
+	}
+	if problem != "" {
+		response["status"] = "degraded"
+		response["message"] = problem
+		return c.Status(http.StatusServiceUnavailable).JSON(response)
+	}
+	h.warn(response)
+	return c.JSON(response)
 }
 
-// Ready returns a readiness check handler
+// Ready returns a readiness check handler; it responds 503 while a required database
+// is not connected or does not answer a ping
 func (h *HealthHandler) Ready(c *fiber.Ctx) error {
 	status := fiber.Map{
 		"status":    "ready",
-		"timestamp": time.Now().UTC(),
+		"timestamp": clock.Now(c).UTC(),
 	}
 
-	if h.cfg != nil && h.cfg.DatabaseEnabled() && h.db == nil {
-		status["status"] = "degraded"
-		status["details"] = "database required but not connected"
-		return c.Status(http.StatusServiceUnavailable).JSON(status)
+	if h.cfg != nil && h.cfg.DatabaseEnabled() {
+		database, ok := checkDatabase(c.UserContext(), h.db)
+		status["database"] = database
+		if !ok {
+			status["status"] = "degraded"
+			if h.db == nil {
+				status["details"] = "database required but not connected"
+			} else if !h.db.Connected() {
+				status["details"] = "database unreachable at startup; reconnecting"
+			} else {
+				status["details"] = "database ping failed"
+			}
+			return c.Status(http.StatusServiceUnavailable).JSON(status)
+		}
+	}
+
+	// Redis fails readiness only when REDIS_REQUIRED is set; otherwise features fall back
+	// to per-instance state
+	if h.cfg != nil && h.cfg.CacheEnabled() {
+		cache, ok := h.checkCache(c.UserContext())
+		status["cache"] = cache
+		if !ok && h.cfg.RedisRequired {
+			status["status"] = "degraded"
+			status["details"] = "cache required but unreachable"
+			return c.Status(http.StatusServiceUnavailable).JSON(status)
+		}
+	}
+
+	// The reporting database is optional: reports fall back to the primary, so it
+	// is surfaced without failing readiness
+	if h.cfg != nil && h.cfg.ReportingDatabaseEnabled() {
+		reporting, ok := checkDatabase(c.UserContext(), h.reportingDB)
+		if !ok {
+			reporting["status"] = "unavailable"
+		}
+		status["reporting_database"] = reporting
 	}
 
 	return c.JSON(status)
@@ -62,7 +149,7 @@ func (h *HealthHandler) Ready(c *fiber.Ctx) error {
 func (h *HealthHandler) Live(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"status":    "alive",
-		"timestamp": time.Now().UTC(),
+		"timestamp": clock.Now(c).UTC(),
 	})
 }
 
@@ -73,23 +160,37 @@ func (h *HealthHandler) environment() string {
 	return h.cfg.AppEnv
 }
 
-func (h *HealthHandler) featureStatus() fiber.Map {
-	checks := fiber.Map{}
+// featureStatus describes each enabled feature; problem says why the service is
+// unhealthy: the primary database, or a required cache, does not answer a ping
+func (h *HealthHandler) featureStatus(ctx context.Context) (checks fiber.Map, problem string) {
+	checks = fiber.Map{}
 
 	if h.cfg == nil {
-		return checks
+		return checks, ""
 	}
 
 	if h.cfg.DatabaseEnabled() {
-		if h.db != nil {
-			checks["database"] = "connected"
-		} else {
-			checks["database"] = "unavailable"
+		database, ok := checkDatabase(ctx, h.db)
+		checks["database"] = database
+		if !ok {
+			problem = "Database is unreachable"
+		}
+	}
+
+	if h.cfg.ReportingDatabaseEnabled() {
+		reporting, reachable := checkDatabase(ctx, h.reportingDB)
+		if !reachable {
+			reporting["status"] = "unavailable (falling back to primary)"
 		}
+		checks["reporting_database"] = reporting
 	}
 
 	if h.cfg.CacheEnabled() {
-		checks["cache"] = "configured"
+		cache, ok := h.checkCache(ctx)
+		checks["cache"] = cache
+		if !ok && h.cfg.RedisRequired && problem == "" {
+			problem = "Cache is unreachable"
+		}
 	}
 
 	if h.cfg.AuthEnabled() {
@@ -108,5 +209,61 @@ func (h *HealthHandler) featureStatus() fiber.Map {
 		checks["pusher"] = h.cfg.PusherConfig.Cluster
 	}
 
-	return checks
+	return checks, problem
+}
+
+// checkDatabase pings db and reports the result with the round trip and pool statistics.
+// Errors are not included, since they can name internal hosts.
+func checkDatabase(ctx context.Context, db *database.DB) (fiber.Map, bool) {
+	if db == nil {
+		return fiber.Map{"status": "not connected"}, false
+	}
+	if !db.Connected() {
+		return fiber.Map{"status": "connecting"}, false
+	}
+	start := time.Now()
+	err := db.HealthCheck(ctx)
+	result := fiber.Map{
+		"status":     "connected",
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"pool":       db.PoolStats(),
+	}
+	if err != nil {
+		result["status"] = "unreachable"
+		return result, false
+	}
+	return result, true
+}
+
+// checkCache pings Redis and reports the result with the round trip and pool statistics.
+// A ping slower than cacheSlowPing is reported as degraded but still counts as reachable.
+func (h *HealthHandler) checkCache(ctx context.Context) (fiber.Map, bool) {
+	if h.cache == nil {
+		return fiber.Map{"status": "not connected"}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := h.cache.Ping(ctx).Err()
+	latency := time.Since(start)
+	stats := h.cache.PoolStats()
+	result := fiber.Map{
+		"status":     "connected",
+		"latency_ms": float64(latency.Microseconds()) / 1000,
+		"pool": fiber.Map{
+			"total_conns": stats.TotalConns,
+			"idle_conns":  stats.IdleConns,
+			"hits":        stats.Hits,
+			"misses":      stats.Misses,
+			"timeouts":    stats.Timeouts,
+		},
+	}
+	switch {
+	case err != nil:
+		result["status"] = "unreachable"
+		return result, false
+	case latency > cacheSlowPing:
+		result["status"] = "degraded"
+	}
+	return result, true
 }
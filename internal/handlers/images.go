@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/imaging"
+	"main.go/internal/storage"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// imageOptionKeys are the query parameters URL turns into an options segment
+var imageOptionKeys = []string{"w", "h", "fit", "format", "q"}
+
+// ImageHandler serves signed transformations of image attachments
+type ImageHandler struct {
+	images    *imaging.Service
+	manager   *attachments.Manager
+	validator *validation.Validator
+	// maxAge is how long browsers and CDNs may cache a transformed image, in seconds
+	maxAge int
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(images *imaging.Service, m *attachments.Manager, maxAge int) *ImageHandler {
+	return &ImageHandler{images: images, manager: m, validator: validation.NewValidator(), maxAge: maxAge}
+}
+
+// URL returns a signed image URL for an attachment the caller may read, built from the
+// w, h, fit, format, and q query parameters
+func (h *ImageHandler) URL(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Image not found")
+	}
+	var parts []string
+	for _, key := range imageOptionKeys {
+		if value := c.Query(key); value != "" {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	opts, err := imaging.ParseOptions(strings.Join(parts, ","), h.images.MaxDimension())
+	if err != nil {
+		return utils.BadRequest(c, err.Error())
+	}
+
+	attachment, err := h.manager.Store().Find(c.UserContext(), id)
+	if errors.Is(err, attachments.ErrNotFound) || (err == nil && !h.manager.CanRead(c, attachment.ResourceType, attachment.ResourceID)) {
+		return utils.NotFound(c, "Image not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load image")
+	}
+	if !imaging.Transformable(attachment) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "id", "This file cannot be transformed")
+	}
+	return utils.SuccessResponse(c, fiber.Map{"url": h.images.URL(id, opts)}, "Image URL")
+}
+
+// Serve renders, or reads from the cache, the transformation a signed URL names. Any
+// other URL is reported missing, so unsigned sizes never reach the renderer.
+func (h *ImageHandler) Serve(c *fiber.Ctx) error {
+	signature, options, id := c.Params("signature"), c.Params("options"), c.Params("id")
+	if !h.images.Verify(signature, options, id) {
+		return utils.NotFound(c, "Image not found")
+	}
+	opts, err := imaging.ParseOptions(options, h.images.MaxDimension())
+	if err != nil || opts.String() != options {
+		return utils.NotFound(c, "Image not found")
+	}
+	attachment, err := h.manager.Store().Find(c.UserContext(), id)
+	if errors.Is(err, attachments.ErrNotFound) {
+		return utils.NotFound(c, "Image not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load image")
+	}
+
+	variant, err := h.images.Render(c.UserContext(), attachment, opts)
+	if errors.Is(err, imaging.ErrUnsupportedImage) {
+		return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "This image cannot be transformed",
+			fiber.NewError(fiber.StatusUnprocessableEntity, "This image cannot be transformed"))
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return utils.NotFound(c, "Image not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to render image")
+	}
+
+	etag := variant.ETag()
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(h.maxAge)+", immutable")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentSecurityPolicy, fileSecurityPolicy)
+	c.Set(fiber.HeaderContentDisposition, "inline")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	body, err := h.images.Open(c.UserContext(), variant)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to read image")
+	}
+	c.Set(fiber.HeaderContentType, variant.ContentType)
+	return c.SendStream(body, int(variant.Size))
+}
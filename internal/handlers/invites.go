@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/clock"
+	"main.go/internal/invites"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// CreateInviteRequest is the payload for minting an invite code. ExpiresAt is RFC 3339;
+// omit it for a code that never expires.
+type CreateInviteRequest struct {
+	Note      string     `json:"note" validate:"max=255"`
+	MaxUses   int        `json:"max_uses" validate:"omitempty,min=1,max=100000"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RedeemInviteRequest is the payload for redeeming an invite code as a signed-in user
+type RedeemInviteRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+// InviteHandler lets admins mint and revoke invite codes and users redeem them
+type InviteHandler struct {
+	store     *invites.Store
+	validator *validation.Validator
+}
+
+// NewInviteHandler creates a new invite handler
+func NewInviteHandler(store *invites.Store) *InviteHandler {
+	return &InviteHandler{store: store, validator: validation.NewValidator()}
+}
+
+// List returns the minted codes with their use counts; codes themselves are not stored
+func (h *InviteHandler) List(c *fiber.Ctx) error {
+	list, err := h.store.List(c.UserContext())
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load invites")
+	}
+	return utils.SuccessResponse(c, list, "Invites")
+}
+
+// Create mints a code; the response is the only time the code is shown
+func (h *InviteHandler) Create(c *fiber.Ctx) error {
+	var req CreateInviteRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(clock.Now(c)) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "expires_at", "Expires at must be in the future")
+	}
+
+	invite, err := h.store.Create(c.UserContext(), invites.NewInvite{
+		Note:      req.Note,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: auth.MustCurrentUser[auth.Principal](c).ID,
+	})
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to create invite")
+	}
+
+	return utils.JSON(c, fiber.StatusCreated, invite, "Invite created")
+}
+
+// Revoke stops a code from being redeemed
+func (h *InviteHandler) Revoke(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Invite not found")
+	}
+
+	err := h.store.Revoke(c.UserContext(), id, clock.Now(c))
+	if errors.Is(err, invites.ErrNotFound) {
+		return utils.NotFound(c, "Invite not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to revoke invite")
+	}
+	return utils.SuccessResponse(c, nil, "Invite revoked")
+}
+
+// Redeem lets a signed-in user without access in with a code, e.g. an account created
+// before the beta or through a social login
+func (h *InviteHandler) Redeem(c *fiber.Ctx) error {
+	var req RedeemInviteRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	err := h.store.Redeem(c.UserContext(), req.Code, auth.MustCurrentUser[auth.Principal](c).ID, clock.Now(c))
+	switch {
+	case errors.Is(err, invites.ErrInvalidCode):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "code", "Invite code is invalid, expired, or used up")
+	case errors.Is(err, invites.ErrAlreadyInvited):
+		return utils.SuccessResponse(c, nil, "You already have access")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to redeem invite")
+	}
+	return utils.SuccessResponse(c, nil, "Invite redeemed")
+}
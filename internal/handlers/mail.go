@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/markdown"
+	"main.go/internal/safego"
+)
+
+// mailTimeout bounds background delivery of a single message
+const mailTimeout = 30 * time.Second
+
+// emailMarkdown renders email bodies; mail clients ignore stylesheets, so code is not highlighted
+var emailMarkdown = markdown.New(markdown.Options{Policy: markdown.Trusted})
+
+// markdownMessage builds a message whose body is written in markdown: the source doubles
+// as the plain-text part, and the rendered HTML is the alternative part
+func markdownMessage(to, subject, body string) mail.Message {
+	msg := mail.Message{To: to, Subject: subject, Text: body}
+	if html, err := emailMarkdown.Render(body); err == nil {
+		msg.HTML = html
+	}
+	return msg
+}
+
+// sendMailAsync delivers msg in the background so SMTP latency never blocks (or, for
+// account lookups, reveals anything through) the response time
+func sendMailAsync(mailer mail.Mailer, l *logger.Logger, name string, msg mail.Message) {
+	safego.GoNamed(name, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			l.Error("Failed to send email", zap.String("mail", name), zap.Error(err))
+		}
+	})
+}
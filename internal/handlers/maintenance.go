@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/cdn"
+	"main.go/internal/clock"
+	"main.go/internal/maintenance"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// ScheduleMaintenanceRequest is the payload for scheduling a maintenance window.
+// Times are RFC 3339, e.g. "2026-11-01T02:00:00Z".
+type ScheduleMaintenanceRequest struct {
+	Title       string    `json:"title" validate:"required,max=255"`
+	Description string    `json:"description" validate:"max=2000"`
+	StartsAt    time.Time `json:"starts_at" validate:"required"`
+	EndsAt      time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+}
+
+// MaintenanceHandler lets admins schedule and cancel maintenance windows
+type MaintenanceHandler struct {
+	store     *maintenance.Store
+	schedule  *maintenance.Schedule
+	cdn       *cdn.Service
+	validator *validation.Validator
+}
+
+// NewMaintenanceHandler creates a new maintenance handler; changes purge the cached
+// status page through purger, which may be nil
+func NewMaintenanceHandler(store *maintenance.Store, schedule *maintenance.Schedule, purger *cdn.Service) *MaintenanceHandler {
+	return &MaintenanceHandler{store: store, schedule: schedule, cdn: purger, validator: validation.NewValidator()}
+}
+
+// List returns the windows in progress or scheduled
+func (h *MaintenanceHandler) List(c *fiber.Ctx) error {
+	windows, err := h.store.Upcoming(c.UserContext(), clock.Now(c))
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load maintenance windows")
+	}
+	return utils.SuccessResponse(c, windows, "Maintenance windows")
+}
+
+// Create schedules a window; it takes effect on this instance immediately and on
+// others at their next schedule refresh
+func (h *MaintenanceHandler) Create(c *fiber.Ctx) error {
+	var req ScheduleMaintenanceRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if !req.EndsAt.After(clock.Now(c)) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "ends_at", "Ends at must be in the future")
+	}
+
+	window, err := h.store.Create(c.UserContext(), maintenance.NewWindow{
+		Title:       req.Title,
+		Description: req.Description,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		CreatedBy:   auth.MustCurrentUser[auth.Principal](c).ID,
+	})
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to schedule maintenance")
+	}
+	_ = h.schedule.Refresh(c.UserContext())
+	h.cdn.PurgeAsync("status")
+
+	return utils.JSON(c, fiber.StatusCreated, window, "Maintenance scheduled")
+}
+
+// Delete cancels a scheduled window or ends one in progress
+func (h *MaintenanceHandler) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Maintenance window not found")
+	}
+
+	err := h.store.Delete(c.UserContext(), id)
+	if errors.Is(err, maintenance.ErrWindowNotFound) {
+		return utils.NotFound(c, "Maintenance window not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to cancel maintenance")
+	}
+	_ = h.schedule.Refresh(c.UserContext())
+	h.cdn.PurgeAsync("status")
+
+	return utils.SuccessResponse(c, nil, "Maintenance cancelled")
+}
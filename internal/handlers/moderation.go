@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/moderation"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// ModerationHandler lets admins review content quarantined as spam
+type ModerationHandler struct {
+	moderator *moderation.Moderator
+	validator *validation.Validator
+}
+
+// NewModerationHandler creates a new moderation handler
+func NewModerationHandler(m *moderation.Moderator) *ModerationHandler {
+	return &ModerationHandler{moderator: m, validator: validation.NewValidator()}
+}
+
+// List returns items by review status (?status=quarantined, approved, or rejected)
+func (h *ModerationHandler) List(c *fiber.Ctx) error {
+	status := c.Query("status", moderation.StatusQuarantined)
+	if err := h.validator.ValidateVar(status, "oneof=quarantined approved rejected"); err != nil {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "status", "Status must be quarantined, approved, or rejected")
+	}
+	items, err := h.moderator.Store().List(c.UserContext(), status)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load moderation queue")
+	}
+	return utils.SuccessResponse(c, items, "Moderation queue")
+}
+
+// Approve releases an item wrongly flagged as spam
+func (h *ModerationHandler) Approve(c *fiber.Ctx) error {
+	return h.review(c, true, "Content approved")
+}
+
+// Reject confirms an item is spam; it stays hidden
+func (h *ModerationHandler) Reject(c *fiber.Ctx) error {
+	return h.review(c, false, "Content rejected as spam")
+}
+
+func (h *ModerationHandler) review(c *fiber.Ctx, approve bool, message string) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Moderation item not found")
+	}
+	item, err := h.moderator.Review(c.UserContext(), id, approve, auth.MustCurrentUser[auth.Principal](c).ID)
+	if errors.Is(err, moderation.ErrNotFound) {
+		return utils.NotFound(c, "Moderation item not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to review content")
+	}
+	return utils.SuccessResponse(c, item, message)
+}
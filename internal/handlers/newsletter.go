@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/database/pagination"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/newsletter"
+	"main.go/internal/security"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// SubscribeRequest is a newsletter signup
+type SubscribeRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email,max=255"`
+	// Source records where the form was, e.g. "footer"
+	Source string `json:"source" form:"source" validate:"max=64"`
+	// Website is a honeypot: the form hides it from people, so only bots fill it in
+	Website string `json:"website" form:"website"`
+}
+
+// SuppressRequest is the payload for suppressing an address by hand or after a provider
+// reported a bounce or complaint
+type SuppressRequest struct {
+	Email  string `json:"email" validate:"required,email,max=255"`
+	Reason string `json:"reason" validate:"required,oneof=bounced complained manual"`
+}
+
+// NewsletterHandler runs double opt-in signups, confirmation and unsubscribe links, and
+// the admin export and suppression list
+type NewsletterHandler struct {
+	newsletter *newsletter.Newsletter
+	mailer     mail.Mailer
+	appName    string
+	validator  *validation.Validator
+	logger     *logger.Logger
+}
+
+// NewNewsletterHandler creates a newsletter handler that sends confirmation emails through
+// mailer; pass an outbox.Outbox so they survive mail server outages
+func NewNewsletterHandler(n *newsletter.Newsletter, mailer mail.Mailer, appName string, l *logger.Logger) *NewsletterHandler {
+	return &NewsletterHandler{newsletter: n, mailer: mailer, appName: appName, validator: validation.NewValidator(), logger: l}
+}
+
+// Subscribe records a signup and emails a confirmation link. The answer is the same
+// whether the address is new, pending, subscribed, or suppressed, so the endpoint cannot
+// be used to find out who is on the list.
+func (h *NewsletterHandler) Subscribe(c *fiber.Ctx) error {
+	var req SubscribeRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if req.Website != "" {
+		security.Emit(c, security.EventHoneypot, nil)
+		return h.accepted(c)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	sub, err := h.newsletter.Subscribe(c.UserContext(), req.Email, req.Source, c.IP())
+	if errors.Is(err, newsletter.ErrSuppressed) {
+		return h.accepted(c)
+	}
+	if err != nil {
+		h.logger.Error("Failed to record newsletter signup", zap.Error(err))
+		return utils.InternalServerError(c, "Failed to subscribe")
+	}
+	if sub.Status != newsletter.StatusPending {
+		return h.accepted(c)
+	}
+
+	if err := h.mailer.Send(c.UserContext(), h.confirmation(sub.Email)); err != nil {
+		h.logger.Error("Failed to send newsletter confirmation", zap.Error(err))
+		return utils.InternalServerError(c, "Failed to subscribe")
+	}
+	return h.accepted(c)
+}
+
+// Confirm subscribes the address from an emailed confirmation link
+func (h *NewsletterHandler) Confirm(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return utils.BadRequest(c, "Confirmation token is required")
+	}
+	_, err := h.newsletter.Confirm(c.UserContext(), token)
+	if errors.Is(err, auth.ErrTokenExpired) {
+		return utils.BadRequest(c, "Confirmation link has expired; subscribe again")
+	}
+	if errors.Is(err, auth.ErrTokenInvalid) {
+		return utils.BadRequest(c, "Confirmation link is invalid")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to confirm subscription")
+	}
+	return utils.SuccessResponse(c, nil, "Subscription confirmed")
+}
+
+// Unsubscribe removes the address from an emailed unsubscribe link. It answers GET for
+// the link and POST for one-click unsubscribe from mail clients (RFC 8058).
+func (h *NewsletterHandler) Unsubscribe(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return utils.BadRequest(c, "Unsubscribe token is required")
+	}
+	_, err := h.newsletter.Unsubscribe(c.UserContext(), token)
+	if errors.Is(err, auth.ErrTokenExpired) || errors.Is(err, auth.ErrTokenInvalid) {
+		return utils.BadRequest(c, "Unsubscribe link is invalid")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to unsubscribe")
+	}
+	return utils.SuccessResponse(c, nil, "You have been unsubscribed")
+}
+
+// Export streams confirmed, unsuppressed subscribers as CSV, e.g. for a manual import
+// into a provider without a sync driver
+func (h *NewsletterHandler) Export(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="newsletter-subscribers.csv"`)
+
+	w := csv.NewWriter(c.Response().BodyWriter())
+	w.Write([]string{"email", "source", "subscribed_at", "confirmed_at"})
+	err := h.newsletter.Store().EachSubscribed(c.UserContext(), func(sub *newsletter.Subscriber) error {
+		confirmedAt := ""
+		if sub.ConfirmedAt != nil {
+			confirmedAt = sub.ConfirmedAt.UTC().Format(time.RFC3339)
+		}
+		return w.Write([]string{sub.Email, sub.Source, sub.CreatedAt.UTC().Format(time.RFC3339), confirmedAt})
+	})
+	w.Flush()
+	if err == nil {
+		err = w.Error()
+	}
+	if err != nil {
+		h.logger.Error("Failed to export newsletter subscribers", zap.Error(err))
+		c.Response().ResetBody()
+		return utils.InternalServerError(c, "Failed to export subscribers")
+	}
+	return nil
+}
+
+// Suppressions lists the addresses that are never mailed, a page at a time (?limit=&after=)
+func (h *NewsletterHandler) Suppressions(c *fiber.Ctx) error {
+	req, err := pagination.FromQuery(c, pagination.DefaultLimit)
+	if err != nil {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "limit", err.Error())
+	}
+	page, err := h.newsletter.Store().Suppressions(c.UserContext(), req)
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "after", "Invalid cursor")
+	}
+	if errors.Is(err, pagination.ErrNoCursorKey) {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Pagination is not configured; set ENCRYPTION_KEYS or AUTH_SECRET", err)
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load suppressions")
+	}
+	return utils.SuccessResponse(c, page, "Suppressions")
+}
+
+// Suppress unsubscribes an address and keeps it from being mailed or signing up again
+func (h *NewsletterHandler) Suppress(c *fiber.Ctx) error {
+	var req SuppressRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if err := h.newsletter.Suppress(c.UserContext(), req.Email, req.Reason); err != nil {
+		return utils.InternalServerError(c, "Failed to suppress address")
+	}
+	return utils.SuccessResponse(c, nil, "Address suppressed")
+}
+
+// Unsuppress lifts a suppression; the address has to subscribe and confirm again
+func (h *NewsletterHandler) Unsuppress(c *fiber.Ctx) error {
+	err := h.newsletter.Store().Unsuppress(c.UserContext(), newsletter.Normalize(c.Params("email")))
+	if errors.Is(err, newsletter.ErrNotFound) {
+		return utils.NotFound(c, "Suppression not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to lift suppression")
+	}
+	return utils.SuccessResponse(c, nil, "Suppression lifted")
+}
+
+// confirmation builds the double opt-in email with its confirmation and unsubscribe links
+func (h *NewsletterHandler) confirmation(email string) mail.Message {
+	link, expiresAt := h.newsletter.ConfirmURL(email)
+	return markdownMessage(email,
+		"Confirm your subscription to "+h.appName,
+		fmt.Sprintf("Please confirm your subscription to the %s newsletter by opening the link below:\n\n<%s>\n\n"+
+			"The link expires at %s. If you did not sign up, ignore this email and you will not hear from us again.\n\n"+
+			"Changed your mind later? Unsubscribe at any time: <%s>\n",
+			h.appName, link, expiresAt.UTC().Format(time.RFC1123), h.newsletter.UnsubscribeURL(email)))
+}
+
+func (h *NewsletterHandler) accepted(c *fiber.Ctx) error {
+	return utils.JSON(c, fiber.StatusAccepted, nil, "Check your inbox to confirm your subscription")
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth/oauth"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+)
+
+// OAuthHandler runs social and OIDC login redirects and callbacks
+type OAuthHandler struct {
+	manager         *oauth.Manager
+	users           *users.Store
+	auth            *AuthHandler
+	successRedirect string
+}
+
+// NewOAuthHandler creates a new OAuth handler. Logins are completed through authHandler,
+// so they start a session or issue tokens depending on AUTH.
+func NewOAuthHandler(manager *oauth.Manager, userStore *users.Store, authHandler *AuthHandler, successRedirect string) *OAuthHandler {
+	return &OAuthHandler{
+		manager:         manager,
+		users:           userStore,
+		auth:            authHandler,
+		successRedirect: successRedirect,
+	}
+}
+
+// Providers lists the enabled social login providers
+func (h *OAuthHandler) Providers(c *fiber.Ctx) error {
+	providers := make([]fiber.Map, 0, len(h.manager.Names()))
+	for _, name := range h.manager.Names() {
+		providers = append(providers, fiber.Map{
+			"name":      name,
+			"login_url": "/auth/" + name,
+		})
+	}
+	return utils.SuccessResponse(c, providers, "OAuth providers")
+}
+
+// Begin redirects to the provider's consent screen
+func (h *OAuthHandler) Begin(c *fiber.Ctx) error {
+	provider, ok := h.manager.Provider(c.Params("provider"))
+	if !ok {
+		return utils.NotFound(c, "Unknown login provider")
+	}
+	err := h.manager.Begin(c, provider)
+	if errors.Is(err, oauth.ErrProviderUnavailable) {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Login provider is unavailable", err)
+	}
+	return err
+}
+
+// Callback completes the provider login, links or creates the user, and logs them in
+func (h *OAuthHandler) Callback(c *fiber.Ctx) error {
+	provider, ok := h.manager.Provider(c.Params("provider"))
+	if !ok {
+		return utils.NotFound(c, "Unknown login provider")
+	}
+	if h.users == nil {
+		return h.auth.storeUnavailable(c)
+	}
+
+	identity, err := h.manager.Complete(c, provider)
+	switch {
+	case errors.Is(err, oauth.ErrInvalidState):
+		return utils.BadRequest(c, "Login session expired or was tampered with; please try again")
+	case errors.Is(err, oauth.ErrAccessDenied):
+		return utils.Forbidden(c, "Login was cancelled at the provider")
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Failed to complete login with provider", err)
+	}
+
+	user, err := h.users.FindOrCreateExternal(c.UserContext(), users.ExternalIdentity{
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+	})
+	switch {
+	case errors.Is(err, users.ErrUnverifiedEmail):
+		return utils.NewValidationResponseBuilder(c).Conflict("An account with this email exists; log in with your password to link " + provider.Name())
+	case errors.Is(err, users.ErrUnverifiedAccount):
+		return utils.NewValidationResponseBuilder(c).Conflict("An account with this email exists but is not verified; verify your email, then log in with your password to link " + provider.Name())
+	case errors.Is(err, users.ErrAccountDisabled):
+		return utils.Forbidden(c, "Account disabled")
+	case errors.Is(err, users.ErrEmailRequired):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "email", provider.Name()+" did not share an email address")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to load account")
+	}
+
+	session, err := h.auth.startSession(c, user.Principal())
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to log in")
+	}
+
+	// Session logins are browser flows; token pairs are returned to the calling client
+	if h.auth.sessions != nil {
+		return c.Redirect(h.successRedirect, fiber.StatusSeeOther)
+	}
+	return utils.SuccessResponse(c, session, "Logged in")
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/operations"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// OperationHandler reports on background operations to the user who started them and to
+// holders of operations:read
+type OperationHandler struct {
+	store     *operations.Store
+	validator *validation.Validator
+}
+
+// NewOperationHandler creates a new operation handler
+func NewOperationHandler(store *operations.Store) *OperationHandler {
+	return &OperationHandler{store: store, validator: validation.NewValidator()}
+}
+
+// Show returns an operation's status, progress, and, once done, its result or error
+func (h *OperationHandler) Show(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Operation not found")
+	}
+	op, err := h.store.Find(c.UserContext(), id)
+	if errors.Is(err, operations.ErrNotFound) || (err == nil && !h.canRead(c, op)) {
+		return utils.NotFound(c, "Operation not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load operation")
+	}
+	return utils.SuccessResponse(c, op, "Operation")
+}
+
+func (h *OperationHandler) canRead(c *fiber.Ctx, op *operations.Operation) bool {
+	if p, ok := auth.PrincipalFrom(c); ok && p.HasScope(operations.ReadScope) {
+		return true
+	}
+	userID := auth.UserID(c)
+	return userID != "" && userID == op.OwnerID
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// passwordResetPurpose scopes signed tokens so they cannot be replayed for other flows
+const passwordResetPurpose = "password-reset"
+
+// ForgotPasswordRequest is the payload for requesting a reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email" form:"email" validate:"required,email,max=255"`
+}
+
+// ResetPasswordRequest is the payload for choosing a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token" validate:"required,max=512"`
+	Password string `json:"password" form:"password" validate:"required,max=128,password,not_breached"`
+}
+
+// PasswordResetHandler emails time-limited reset links and applies new passwords.
+// Tokens are signed with the current password hash, so each link works only once.
+type PasswordResetHandler struct {
+	users     *users.Store
+	tokens    *auth.SignedTokens
+	mailer    mail.Mailer
+	logger    *logger.Logger
+	ttl       time.Duration
+	resetURL  string
+	validator *validation.Validator
+}
+
+// NewPasswordResetHandler creates a new password reset handler; appURL is used to build the emailed link
+func NewPasswordResetHandler(userStore *users.Store, tokens *auth.SignedTokens, mailer mail.Mailer, l *logger.Logger, ttl time.Duration, appURL string) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		users:     userStore,
+		tokens:    tokens,
+		mailer:    mailer,
+		logger:    l,
+		ttl:       ttl,
+		resetURL:  strings.TrimRight(appURL, "/") + "/reset-password",
+		validator: validation.NewValidator(),
+	}
+}
+
+// ForgotPassword emails a reset link. The response is identical whether or not the
+// account exists so the endpoint cannot be used to enumerate users.
+func (h *PasswordResetHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	const message = "If an account exists for that email, a reset link has been sent"
+
+	user, err := h.users.FindByLogin(c.UserContext(), req.Email)
+	if errors.Is(err, users.ErrUserNotFound) || (err == nil && (!user.IsActive || !strings.EqualFold(user.Email, req.Email))) {
+		return utils.SuccessResponse(c, nil, message)
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to process request")
+	}
+
+	expiresAt := clock.Now(c).Add(h.ttl)
+	token := h.tokens.Sign(passwordResetPurpose, user.ID, user.PasswordHash, expiresAt)
+	msg := h.resetMessage(user, token, expiresAt)
+
+	sendMailAsync(h.mailer, h.logger, "password-reset-mail", msg)
+
+	return utils.SuccessResponse(c, nil, message)
+}
+
+// ResetPassword validates the token and new password, then replaces the password hash
+func (h *PasswordResetHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	// The subject is not verified until the user is loaded, so only user IDs are looked up
+	userID, err := h.tokens.Subject(req.Token)
+	if err == nil {
+		_, err = ids.Parse(userID)
+	}
+	if err != nil {
+		return utils.BadRequest(c, "Reset link is invalid or has already been used")
+	}
+
+	user, err := h.users.FindByID(c.UserContext(), userID)
+	if errors.Is(err, users.ErrUserNotFound) {
+		return utils.BadRequest(c, "Reset link is invalid or has already been used")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to process request")
+	}
+
+	_, err = h.tokens.Verify(req.Token, passwordResetPurpose, user.PasswordHash, clock.Now(c))
+	if errors.Is(err, auth.ErrTokenExpired) {
+		return utils.BadRequest(c, "Reset link has expired; request a new one")
+	}
+	if err != nil || !user.IsActive {
+		return utils.BadRequest(c, "Reset link is invalid or has already been used")
+	}
+
+	if err := h.users.SetPassword(c.UserContext(), user.ID, req.Password); err != nil {
+		return utils.InternalServerError(c, "Failed to update password")
+	}
+	return utils.SuccessResponse(c, nil, "Password updated; log in with your new password")
+}
+
+func (h *PasswordResetHandler) resetMessage(user *users.User, token string, expiresAt time.Time) mail.Message {
+	link := h.resetURL + "?token=" + url.QueryEscape(token)
+	return markdownMessage(user.Email, "Reset your password",
+		fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. "+
+			"Open the link below to choose a new one:\n\n<%s>\n\n"+
+			"The link expires at %s. If you did not ask for this, you can ignore this email.\n",
+			user.FirstName, link, expiresAt.UTC().Format(time.RFC1123)))
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/bodyparser"
+	"main.go/internal/policy"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// PolicyAcceptRequest is the payload for accepting a policy version
+type PolicyAcceptRequest struct {
+	Version string `json:"version" validate:"required,max=50"`
+}
+
+// PolicyHandler exposes versioned policy documents and records acceptances
+type PolicyHandler struct {
+	store       *policy.Store
+	resolveUser policy.UserResolver
+	validator   *validation.Validator
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(store *policy.Store, resolveUser policy.UserResolver) *PolicyHandler {
+	return &PolicyHandler{
+		store:       store,
+		resolveUser: resolveUser,
+		validator:   validation.NewValidator(),
+	}
+}
+
+// List returns the current version of every policy, plus the caller's pending ones
+func (h *PolicyHandler) List(c *fiber.Ctx) error {
+	docs, err := h.store.Current(c.UserContext())
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load policies")
+	}
+
+	response := fiber.Map{"policies": docs}
+
+	if userID := h.resolveUser(c); userID != "" {
+		pending, err := h.store.Pending(c.UserContext(), userID)
+		if err != nil {
+			return utils.InternalServerError(c, "Failed to load policies")
+		}
+		response["pending"] = pending
+	}
+
+	return utils.SuccessResponse(c, response, "Current policies")
+}
+
+// Accept records that the authenticated user accepted a policy version
+func (h *PolicyHandler) Accept(c *fiber.Ctx) error {
+	userID := h.resolveUser(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Authentication required to accept policies")
+	}
+
+	var req PolicyAcceptRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+
+	doc, err := h.store.Find(c.UserContext(), c.Params("kind"), req.Version)
+	if errors.Is(err, policy.ErrDocumentNotFound) {
+		return utils.NotFound(c, "Policy version not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load policy")
+	}
+
+	if err := h.store.Accept(c.UserContext(), policy.Acceptance{
+		UserID:     userID,
+		DocumentID: doc.ID,
+		IPAddress:  c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+	}); err != nil {
+		return utils.InternalServerError(c, "Failed to record acceptance")
+	}
+
+	return utils.SuccessResponse(c, doc, "Policy accepted")
+}
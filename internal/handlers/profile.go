@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/contentfilter"
+	"main.go/internal/middleware"
+	"main.go/internal/moderation"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+)
+
+// UpdateProfileRequest is the payload for changing profile fields; omitted fields are kept
+type UpdateProfileRequest struct {
+	Username  *string `json:"username" form:"username" validate:"omitempty,username,clean=reject"`
+	FirstName *string `json:"first_name" form:"first_name" validate:"omitempty,min=1,max=100,clean"`
+	LastName  *string `json:"last_name" form:"last_name" validate:"omitempty,min=1,max=100,clean"`
+}
+
+// ChangePasswordRequest is the payload for replacing the caller's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" form:"current_password" validate:"required,max=128"`
+	NewPassword     string `json:"new_password" form:"new_password" validate:"required,max=128,password,not_breached,nefield=CurrentPassword"`
+}
+
+// DeleteAccountRequest confirms account deletion with the caller's password
+type DeleteAccountRequest struct {
+	Password string `json:"password" form:"password" validate:"required,max=128"`
+}
+
+// ProfileHandler is the caller's own account: a reference for authenticated resources.
+// Routes sit behind auth.Required() and users.LoadCurrentUser, and request bodies are
+// parsed and validated by middleware.ValidationMiddleware before the handler runs.
+type ProfileHandler struct {
+	users *users.Store
+	auth  *AuthHandler
+	// moderation, when set, screens profile changes for spam in the background
+	moderation *moderation.Moderator
+	// contentFilter, when set in mask mode, masks profanity and personal information in names
+	contentFilter *contentfilter.Filter
+	// attachments, when set, has the files attached to deleted accounts cleaned up
+	attachments *attachments.Manager
+}
+
+// NewProfileHandler creates a profile handler; authHandler ends sessions after password
+// changes and account deletion
+func NewProfileHandler(userStore *users.Store, authHandler *AuthHandler) *ProfileHandler {
+	return &ProfileHandler{users: userStore, auth: authHandler}
+}
+
+// UseModeration screens profile changes for spam
+func (h *ProfileHandler) UseModeration(m *moderation.Moderator) {
+	h.moderation = m
+}
+
+// UseAttachments removes the files attached to an account when it is deleted
+func (h *ProfileHandler) UseAttachments(m *attachments.Manager) {
+	h.attachments = m
+}
+
+// UseContentFilter masks profanity and personal information in names when the filter is
+// in mask mode; reject mode is enforced by the clean validation tag
+func (h *ProfileHandler) UseContentFilter(f *contentfilter.Filter) {
+	h.contentFilter = f
+}
+
+// Get returns the caller's account
+func (h *ProfileHandler) Get(c *fiber.Ctx) error {
+	user, ok := auth.CurrentUser[users.User](c)
+	if !ok {
+		return h.noAccount(c)
+	}
+	return utils.SuccessResponse(c, user, "Profile")
+}
+
+// Update changes the caller's username or name
+func (h *ProfileHandler) Update(c *fiber.Ctx) error {
+	user, ok := auth.CurrentUser[users.User](c)
+	if !ok {
+		return h.noAccount(c)
+	}
+	req, _ := middleware.GetValidatedBody[UpdateProfileRequest](c)
+	maskFields(h.contentFilter, req.FirstName, req.LastName)
+
+	updated, err := h.users.UpdateProfile(c.UserContext(), user.ID, users.ProfileUpdate{
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if errors.Is(err, users.ErrUserExists) {
+		return utils.NewValidationResponseBuilder(c).Conflict("That username is already taken")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to update profile")
+	}
+	auth.SetCurrentUser(c, updated)
+	if h.moderation != nil {
+		h.moderation.Submit(profileContent(c, updated))
+	}
+	return utils.SuccessResponse(c, updated, "Profile updated")
+}
+
+// ChangePassword replaces the caller's password after checking the current one. Every
+// other session is signed out and the response carries the caller's new session.
+func (h *ProfileHandler) ChangePassword(c *fiber.Ctx) error {
+	user, ok := auth.CurrentUser[users.User](c)
+	if !ok {
+		return h.noAccount(c)
+	}
+	req, _ := middleware.GetValidatedBody[ChangePasswordRequest](c)
+
+	if !h.users.VerifyPassword(user, req.CurrentPassword) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "current_password", "Current password is incorrect")
+	}
+	if err := h.users.SetPassword(c.UserContext(), user.ID, req.NewPassword); err != nil {
+		return utils.InternalServerError(c, "Failed to update password")
+	}
+
+	session, err := h.auth.restartSessions(c, user.Principal())
+	if err != nil {
+		return utils.InternalServerError(c, "Password updated but other sessions could not be signed out")
+	}
+	return utils.SuccessResponse(c, session, "Password updated")
+}
+
+// Delete removes the caller's account after confirming their password and logs them out
+func (h *ProfileHandler) Delete(c *fiber.Ctx) error {
+	user, ok := auth.CurrentUser[users.User](c)
+	if !ok {
+		return h.noAccount(c)
+	}
+	req, _ := middleware.GetValidatedBody[DeleteAccountRequest](c)
+
+	if !h.users.VerifyPassword(user, req.Password) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "password", "Password is incorrect")
+	}
+	if err := h.users.Delete(c.UserContext(), user.ID); err != nil {
+		return utils.InternalServerError(c, "Failed to delete account")
+	}
+	// Best effort: the cleanup job's sweep catches anything left behind
+	if h.attachments != nil {
+		_ = h.attachments.DetachAll(c.UserContext(), attachments.TypeUser, user.ID)
+	}
+
+	if err := h.auth.signOutEverywhere(c, user.ID); err != nil {
+		return utils.InternalServerError(c, "Account deleted but sessions could not be signed out")
+	}
+	if h.auth.sessions != nil {
+		if err := h.auth.sessions.Logout(c); err != nil {
+			return utils.InternalServerError(c, "Account deleted but the session could not be ended")
+		}
+	}
+	return utils.SuccessResponse(c, nil, "Account deleted")
+}
+
+// noAccount rejects machine principals (API keys), which have no profile
+func (h *ProfileHandler) noAccount(c *fiber.Ctx) error {
+	return utils.Forbidden(c, "API keys have no profile")
+}
+
+// maskFields masks profanity and personal information in the fields when the filter is in
+// mask mode; nil fields are skipped
+func maskFields(f *contentfilter.Filter, fields ...*string) {
+	if f == nil || f.Mode() != contentfilter.ModeMask {
+		return
+	}
+	for _, field := range fields {
+		if field != nil {
+			*field = f.Mask(*field)
+		}
+	}
+}
+
+// profileContent is the user's public profile as submitted for a spam check
+func profileContent(c *fiber.Ctx, user *users.User) moderation.Content {
+	return moderation.FromRequest(c, moderation.Content{
+		Kind:        moderation.KindProfile,
+		SubjectID:   user.ID,
+		AuthorID:    user.ID,
+		AuthorName:  user.FirstName + " " + user.LastName,
+		AuthorEmail: user.Email,
+		Text:        user.Username + "\n" + user.FirstName + " " + user.LastName,
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/referrals"
+	"main.go/internal/utils"
+)
+
+// topReferrers is the length of the admin leaderboard
+const topReferrers = 10
+
+// ReferralHandler shows users their referral link and progress, and admins the program totals
+type ReferralHandler struct {
+	store   *referrals.Store
+	baseURL string
+}
+
+// NewReferralHandler creates a new referral handler; baseURL (APP_URL) prefixes share links
+func NewReferralHandler(store *referrals.Store, baseURL string) *ReferralHandler {
+	return &ReferralHandler{store: store, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Me returns the caller's code, share link, and stats; the code is created on first use
+func (h *ReferralHandler) Me(c *fiber.Ctx) error {
+	userID := auth.MustCurrentUser[auth.Principal](c).ID
+	code, err := h.store.Code(c.UserContext(), userID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load referral code")
+	}
+	stats, err := h.store.Stats(c.UserContext(), userID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load referral stats")
+	}
+	return utils.SuccessResponse(c, fiber.Map{
+		"code":  code,
+		"link":  h.baseURL + "/?ref=" + code,
+		"stats": stats,
+	}, "Referral")
+}
+
+// List returns the signups attributed to the caller
+func (h *ReferralHandler) List(c *fiber.Ctx) error {
+	list, err := h.store.List(c.UserContext(), auth.MustCurrentUser[auth.Principal](c).ID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load referrals")
+	}
+	return utils.SuccessResponse(c, list, "Referrals")
+}
+
+// Stats returns program-wide totals and the top referrers
+func (h *ReferralHandler) Stats(c *fiber.Ctx) error {
+	overview, err := h.store.Overview(c.UserContext(), topReferrers)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load referral stats")
+	}
+	return utils.SuccessResponse(c, overview, "Referral stats")
+}
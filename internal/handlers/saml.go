@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth/saml"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+)
+
+// SAMLHandler runs SAML single sign-on: service provider metadata, the redirect to the
+// identity provider, and the assertion consumer service
+type SAMLHandler struct {
+	sp              *saml.ServiceProvider
+	users           *users.Store
+	auth            *AuthHandler
+	successRedirect string
+}
+
+// NewSAMLHandler creates a SAML handler; logins start a session through authHandler
+func NewSAMLHandler(sp *saml.ServiceProvider, userStore *users.Store, authHandler *AuthHandler, successRedirect string) *SAMLHandler {
+	return &SAMLHandler{
+		sp:              sp,
+		users:           userStore,
+		auth:            authHandler,
+		successRedirect: successRedirect,
+	}
+}
+
+// Metadata serves the service provider metadata to register with the identity provider
+func (h *SAMLHandler) Metadata(c *fiber.Ctx) error {
+	return h.sp.Metadata(c)
+}
+
+// Login redirects to the identity provider's sign-in page
+func (h *SAMLHandler) Login(c *fiber.Ctx) error {
+	return h.sp.Begin(c)
+}
+
+// ACS verifies the identity provider's response, links or creates the user, and logs them in
+func (h *SAMLHandler) ACS(c *fiber.Ctx) error {
+	identity, err := h.sp.Complete(c)
+	switch {
+	case errors.Is(err, saml.ErrInvalidState):
+		return utils.BadRequest(c, "Login session expired or was not started here; please try again")
+	case errors.Is(err, saml.ErrLoginFailed):
+		return utils.Forbidden(c, "The identity provider did not sign you in")
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid SAML response", err)
+	}
+
+	// The IdP is the source of truth for the accounts it asserts, so its email counts as verified
+	user, err := h.users.FindOrCreateExternal(c.UserContext(), users.ExternalIdentity{
+		Provider:      "saml",
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: true,
+		Name:          identity.Name,
+	})
+	switch {
+	case errors.Is(err, users.ErrEmailRequired):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "email", "The identity provider did not share an email address")
+	case errors.Is(err, users.ErrUnverifiedAccount):
+		return utils.NewValidationResponseBuilder(c).Conflict("An account with this email exists but is not verified; verify your email before signing in with SAML")
+	case errors.Is(err, users.ErrAccountDisabled):
+		return utils.Forbidden(c, "Account disabled")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to load account")
+	}
+
+	if _, err := h.auth.startSession(c, user.Principal()); err != nil {
+		return utils.InternalServerError(c, "Failed to log in")
+	}
+	return c.Redirect(h.successRedirect, fiber.StatusSeeOther)
+}
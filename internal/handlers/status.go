@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/health"
+	"main.go/internal/incidents"
+	"main.go/internal/logger"
+	"main.go/internal/maintenance"
+	"main.go/internal/templates/pages"
+)
+
+// statusIncidentWindow is how far back resolved incidents are listed
+const statusIncidentWindow = 14 * 24 * time.Hour
+
+// StatusPageHandler renders the public uptime page
+type StatusPageHandler struct {
+	appName     string
+	registry    *health.Registry
+	incidents   *incidents.Store
+	maintenance *maintenance.Schedule
+	logger      *logger.Logger
+}
+
+// NewStatusPageHandler creates a status page handler; incidentStore and schedule may be nil
+// without a database
+func NewStatusPageHandler(appName string, registry *health.Registry, incidentStore *incidents.Store, schedule *maintenance.Schedule, l *logger.Logger) *StatusPageHandler {
+	return &StatusPageHandler{appName: appName, registry: registry, incidents: incidentStore, maintenance: schedule, logger: l}
+}
+
+// Page renders component health, uptime history, and recent incidents
+func (h *StatusPageHandler) Page(c *fiber.Ctx) error {
+	now := clock.Now(c)
+	view := pages.StatusView{
+		AppName:     h.appName,
+		Operational: h.registry.Healthy(),
+		UpdatedAt:   now.UTC().Format("Jan 2, 2006 15:04 MST"),
+	}
+
+	for _, component := range h.registry.Components() {
+		view.Components = append(view.Components, h.component(component))
+	}
+
+	if h.maintenance != nil {
+		for _, window := range h.maintenance.Upcoming() {
+			view.Maintenance = append(view.Maintenance, pages.StatusMaintenance{
+				Title:       window.Title,
+				Description: window.Description,
+				StartsAt:    window.StartsAt.UTC().Format("Jan 2, 15:04 MST"),
+				EndsAt:      window.EndsAt.UTC().Format("Jan 2, 15:04 MST"),
+				Active:      window.Active(now),
+			})
+		}
+	}
+
+	if h.incidents != nil {
+		recent, err := h.incidents.Recent(c.UserContext(), now.Add(-statusIncidentWindow), 20)
+		if err != nil {
+			h.logger.Error("Failed to load incidents for status page", zap.Error(err))
+		} else {
+			view.Incidents = []pages.StatusIncident{}
+			for _, incident := range recent {
+				view.Incidents = append(view.Incidents, statusIncident(incident))
+			}
+		}
+	}
+
+	// Cache-Control comes from the "status" route policy in newCachePolicies
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return pages.StatusPage(view).Render(c.Context(), c.Response().BodyWriter())
+}
+
+func (h *StatusPageHandler) component(component health.Component) pages.StatusComponent {
+	view := pages.StatusComponent{
+		Name:      component.Name,
+		Healthy:   component.Healthy,
+		Checked:   !component.CheckedAt.IsZero(),
+		Uptime24h: formatUptime(h.registry.Uptime(component.Name, 24*time.Hour)),
+		Uptime7d:  formatUptime(h.registry.Uptime(component.Name, health.Retention)),
+	}
+	for _, day := range h.registry.Daily(component.Name, 7) {
+		view.Days = append(view.Days, pages.StatusDay{Label: day.Date.Format("Jan 2"), Uptime: day.Uptime})
+	}
+	return view
+}
+
+func statusIncident(incident incidents.Incident) pages.StatusIncident {
+	view := pages.StatusIncident{
+		Title:       incident.Title,
+		Description: incident.Description,
+		Impact:      incident.Impact,
+		Status:      incident.Status,
+		StartedAt:   incident.StartedAt.UTC().Format("Jan 2, 15:04 MST"),
+	}
+	if incident.Resolved() {
+		view.ResolvedAt = incident.ResolvedAt.UTC().Format("Jan 2, 15:04 MST")
+	}
+	return view
+}
+
+func formatUptime(uptime float64, ok bool) string {
+	if !ok {
+		return "—"
+	}
+	return fmt.Sprintf("%.2f%%", uptime)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/surveys"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// SurveyRequest is the payload for defining or replacing a survey
+type SurveyRequest struct {
+	Slug        string             `json:"slug" validate:"required,slug,max=100"`
+	Title       string             `json:"title" validate:"required,max=200"`
+	Description string             `json:"description" validate:"max=2000"`
+	Questions   []surveys.Question `json:"questions" validate:"required,min=1,max=50,dive"`
+	Active      *bool              `json:"active"`
+}
+
+// SurveyHandler publishes surveys, collects responses, and reports results to admins
+type SurveyHandler struct {
+	store     *surveys.Store
+	validator *validation.Validator
+}
+
+// NewSurveyHandler creates a new survey handler
+func NewSurveyHandler(store *surveys.Store) *SurveyHandler {
+	return &SurveyHandler{store: store, validator: validation.NewValidator()}
+}
+
+// Show returns an active survey with the JSON Schema its responses must match
+func (h *SurveyHandler) Show(c *fiber.Ctx) error {
+	survey, err := h.active(c)
+	if survey == nil {
+		return err
+	}
+	return utils.SuccessResponse(c, fiber.Map{
+		"slug":        survey.Slug,
+		"title":       survey.Title,
+		"description": survey.Description,
+		"questions":   survey.Questions,
+		"schema":      survey.Schema(),
+	}, "Survey")
+}
+
+// Respond validates a response against the survey's schema and stores it. Signed-in
+// users are recorded with their response; anyone else responds anonymously.
+func (h *SurveyHandler) Respond(c *fiber.Ctx) error {
+	survey, err := h.active(c)
+	if survey == nil {
+		return err
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if errs := survey.Schema().Validate(body); errs != nil {
+		return utils.ValidationError(c, errs)
+	}
+
+	answers := body.(map[string]interface{})["answers"].(map[string]interface{})
+	if err := h.store.Respond(c.UserContext(), survey.ID, auth.UserID(c), answers); err != nil {
+		return utils.InternalServerError(c, "Failed to submit response")
+	}
+	return utils.JSON(c, fiber.StatusCreated, nil, "Thanks for your feedback")
+}
+
+// List returns every survey with its response count
+func (h *SurveyHandler) List(c *fiber.Ctx) error {
+	list, err := h.store.List(c.UserContext())
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load surveys")
+	}
+	return utils.SuccessResponse(c, list, "Surveys")
+}
+
+// Create defines a survey
+func (h *SurveyHandler) Create(c *fiber.Ctx) error {
+	in, err := h.parse(c)
+	if in == nil {
+		return err
+	}
+	survey, err := h.store.Create(c.UserContext(), *in)
+	if errors.Is(err, surveys.ErrSlugTaken) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "slug", "Slug is already taken")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to create survey")
+	}
+	return utils.JSON(c, fiber.StatusCreated, survey, "Survey created")
+}
+
+// Update replaces a survey's definition; responses already collected are kept
+func (h *SurveyHandler) Update(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Survey not found")
+	}
+	in, err := h.parse(c)
+	if in == nil {
+		return err
+	}
+	survey, err := h.store.Update(c.UserContext(), id, *in)
+	switch {
+	case errors.Is(err, surveys.ErrNotFound):
+		return utils.NotFound(c, "Survey not found")
+	case errors.Is(err, surveys.ErrSlugTaken):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "slug", "Slug is already taken")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to update survey")
+	}
+	return utils.SuccessResponse(c, survey, "Survey updated")
+}
+
+// Delete removes a survey and its responses
+func (h *SurveyHandler) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Survey not found")
+	}
+	err := h.store.Delete(c.UserContext(), id)
+	if errors.Is(err, surveys.ErrNotFound) {
+		return utils.NotFound(c, "Survey not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to delete survey")
+	}
+	return utils.SuccessResponse(c, nil, "Survey deleted")
+}
+
+// Report aggregates a survey's responses per question
+func (h *SurveyHandler) Report(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return utils.NotFound(c, "Survey not found")
+	}
+	survey, err := h.store.Find(c.UserContext(), id)
+	if errors.Is(err, surveys.ErrNotFound) {
+		return utils.NotFound(c, "Survey not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load survey")
+	}
+	report, err := h.store.Report(c.UserContext(), survey)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to build survey report")
+	}
+	return utils.SuccessResponse(c, report, "Survey report")
+}
+
+// active loads the active survey named by the :slug parameter; inactive surveys are not
+// found. A nil survey means the error response has been written.
+func (h *SurveyHandler) active(c *fiber.Ctx) (*surveys.Survey, error) {
+	survey, err := h.store.FindBySlug(c.UserContext(), c.Params("slug"))
+	if errors.Is(err, surveys.ErrNotFound) || (err == nil && !survey.Active) {
+		return nil, utils.NotFound(c, "Survey not found")
+	}
+	if err != nil {
+		return nil, utils.InternalServerError(c, "Failed to load survey")
+	}
+	return survey, nil
+}
+
+// parse reads and validates a survey definition. A nil definition means the error
+// response has been written.
+func (h *SurveyHandler) parse(c *fiber.Ctx) (*surveys.NewSurvey, error) {
+	var req SurveyRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return nil, utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return nil, utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if field, message := surveys.Check(req.Questions); field != "" {
+		return nil, utils.NewValidationErrorHelper().HandleCustomValidationError(c, field, message)
+	}
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return &surveys.NewSurvey{
+		Slug:        req.Slug,
+		Title:       req.Title,
+		Description: req.Description,
+		Questions:   req.Questions,
+		Active:      active,
+	}, nil
+}
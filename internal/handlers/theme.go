@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/bodyparser"
+	"main.go/internal/clock"
+	"main.go/internal/templates/components"
+	"main.go/internal/utils"
+)
+
+// themeCookieMaxAge keeps the theme preference for a year
+const themeCookieMaxAge = 365 * 24 * time.Hour
+
+// ThemeRequest is the payload for switching the colour theme
+type ThemeRequest struct {
+	Theme string `json:"theme" form:"theme"`
+}
+
+// ThemeHandler stores the visitor's colour theme preference in a cookie
+type ThemeHandler struct {
+	secure bool
+}
+
+// NewThemeHandler creates a new theme handler; secure marks the cookie HTTPS-only
+func NewThemeHandler(secure bool) *ThemeHandler {
+	return &ThemeHandler{secure: secure}
+}
+
+// Set saves the theme preference. HTML form posts are redirected back to the
+// referring page, JSON clients receive the stored value.
+func (h *ThemeHandler) Set(c *fiber.Ctx) error {
+	var req ThemeRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+
+	if !components.IsTheme(req.Theme) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "theme", "theme must be one of: light dark system")
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     components.ThemeCookie,
+		Value:    req.Theme,
+		Path:     "/",
+		Expires:  clock.Now(c).Add(themeCookieMaxAge),
+		Secure:   h.secure,
+		HTTPOnly: false, // read by the head script to avoid a flash of the wrong theme
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	if c.Is("json") {
+		return utils.SuccessResponse(c, fiber.Map{"theme": req.Theme}, "Theme updated")
+	}
+
+	return c.Redirect(h.returnTo(c), fiber.StatusSeeOther)
+}
+
+// returnTo only follows same-origin referers to avoid an open redirect. Paths starting
+// with // or /\ are refused too, since browsers read them as links to another host.
+func (h *ThemeHandler) returnTo(c *fiber.Ctx) string {
+	origin, err := url.Parse(c.BaseURL())
+	if err != nil {
+		return "/"
+	}
+	referer, err := url.Parse(c.Get(fiber.HeaderReferer))
+	if err != nil || referer.Scheme != origin.Scheme || referer.Host != origin.Host {
+		return "/"
+	}
+	path := referer.RequestURI()
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/uploads"
+	"main.go/internal/utils"
+)
+
+// tusVersion is the only version of the tus resumable upload protocol spoken
+const tusVersion = "1.0.0"
+
+// tusExtensions lists the tus extensions supported
+const tusExtensions = "creation,expiration,termination"
+
+// TusResumable rejects tus requests for another protocol version and marks every response
+// with the version spoken. OPTIONS requests need no version, so clients can discover it.
+func (h *UploadHandler) TusResumable(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	if c.Method() != fiber.MethodOptions && c.Get("Tus-Resumable") != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return utils.ErrorResponse(c, fiber.StatusPreconditionFailed, "Unsupported tus version",
+			fiber.NewError(fiber.StatusPreconditionFailed, "Tus-Resumable must be "+tusVersion))
+	}
+	return c.Next()
+}
+
+// TusOptions describes the tus server: its version, extensions, and size limit
+func (h *UploadHandler) TusOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	if max := h.uploads.MaxBytes(); max > 0 {
+		c.Set("Tus-Max-Size", strconv.FormatInt(max, 10))
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// TusCreate starts an upload of Upload-Length bytes. Upload-Metadata must name the
+// filename, resource_type, and resource_id, and may give a caption.
+func (h *UploadHandler) TusCreate(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Sign in to upload files")
+	}
+	if c.Get("Upload-Defer-Length") != "" {
+		return utils.BadRequest(c, "Upload-Length must be known when the upload starts")
+	}
+	size, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 1 {
+		return utils.BadRequest(c, "Upload-Length must be a positive number of bytes")
+	}
+	meta, ok := parseTusMetadata(c.Get("Upload-Metadata"))
+	if !ok {
+		return utils.BadRequest(c, "Upload-Metadata is malformed")
+	}
+	req := CreateUploadRequest{
+		ResourceType: meta["resource_type"],
+		ResourceID:   meta["resource_id"],
+		Filename:     meta["filename"],
+		Caption:      meta["caption"],
+		Size:         size,
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if !h.uploads.CanWrite(c, req.ResourceType, req.ResourceID) {
+		return utils.NotFound(c, "Resource not found")
+	}
+
+	upload, err := h.uploads.Create(c.UserContext(), uploads.Upload{
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Filename:     req.Filename,
+		Caption:      req.Caption,
+		Size:         req.Size,
+		CreatedBy:    userID,
+	})
+	if errors.Is(err, uploads.ErrTooLarge) {
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File is too large",
+			fiber.NewError(fiber.StatusRequestEntityTooLarge, "File is too large"))
+	}
+	if errors.Is(err, attachments.ErrUnknownType) {
+		return utils.NotFound(c, "Resource not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to start upload")
+	}
+	c.Set(fiber.HeaderLocation, strings.TrimSuffix(c.Path(), "/")+"/"+upload.ID)
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+// TusHead reports how much of an upload has been received, so a client resumes there
+func (h *UploadHandler) TusHead(c *fiber.Ctx) error {
+	upload, err := h.findTus(c)
+	if upload == nil {
+		return err
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// TusPatch stores the request body at Upload-Offset. The chunk that completes the upload
+// also attaches it; the response then names the attachment in X-Attachment-Id and any
+// transcode of a video in X-Operation-Id. An empty PATCH at the end retries an attach
+// that failed.
+func (h *UploadHandler) TusPatch(c *fiber.Ctx) error {
+	upload, err := h.findTus(c)
+	if upload == nil {
+		return err
+	}
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return utils.ErrorResponse(c, fiber.StatusUnsupportedMediaType, "Unsupported content type",
+			fiber.NewError(fiber.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream"))
+	}
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return utils.BadRequest(c, "Upload-Offset must be a number of bytes")
+	}
+
+	body := c.Body()
+	updated, err := h.uploads.Append(c.UserContext(), upload, offset, bytes.NewReader(body), int64(len(body)))
+	switch {
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		return utils.NewValidationResponseBuilder(c).Conflict("The next chunk starts at byte " + strconv.FormatInt(upload.Offset, 10))
+	case errors.Is(err, uploads.ErrChunkTooLarge):
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "Chunk is too large",
+			fiber.NewError(fiber.StatusRequestEntityTooLarge, "Chunks may be at most "+strconv.FormatInt(h.uploads.ChunkBytes(), 10)+" bytes"))
+	case errors.Is(err, uploads.ErrExpired):
+		return utils.ErrorResponse(c, fiber.StatusGone, "Upload has expired", fiber.NewError(fiber.StatusGone, "Upload has expired"))
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to store chunk")
+	}
+	c.Set("Upload-Offset", strconv.FormatInt(updated.Offset, 10))
+	c.Set("Upload-Expires", updated.ExpiresAt.UTC().Format(http.TimeFormat))
+	if updated.Offset < updated.Size {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	attachment, err := h.uploads.Complete(c.UserContext(), updated)
+	if err != nil {
+		if rejected(err) {
+			// The file will never be accepted, so the upload is dropped rather than left
+			// for the client to resume
+			_ = h.uploads.Abort(c.UserContext(), updated)
+		}
+		return h.completeFailed(c, updated, err)
+	}
+	c.Set("X-Attachment-Id", attachment.ID)
+	if op := h.transcode(c, attachment, updated.CreatedBy); op != nil {
+		c.Set("X-Operation-Id", op.ID)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// TusDelete terminates an upload that has not been completed and deletes its chunks
+func (h *UploadHandler) TusDelete(c *fiber.Ctx) error {
+	upload, err := h.findTus(c)
+	if upload == nil {
+		return err
+	}
+	if err := h.uploads.Abort(c.UserContext(), upload); errors.Is(err, uploads.ErrCompleted) {
+		return utils.NewValidationResponseBuilder(c).Conflict("This upload is already complete")
+	} else if err != nil {
+		return utils.InternalServerError(c, "Failed to delete upload")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findTus loads the caller's upload like find, answering 410 for one that has expired and
+// 409 for changes to one that was completed. A nil upload means the error response has
+// been written.
+func (h *UploadHandler) findTus(c *fiber.Ctx) (*uploads.Upload, error) {
+	upload, err := h.find(c)
+	if upload == nil {
+		return nil, err
+	}
+	if h.uploads.Expired(upload) {
+		return nil, utils.ErrorResponse(c, fiber.StatusGone, "Upload has expired", fiber.NewError(fiber.StatusGone, "Upload has expired"))
+	}
+	if upload.AttachmentID != "" && c.Method() != fiber.MethodHead {
+		return nil, utils.NewValidationResponseBuilder(c).Conflict("This upload is already complete")
+	}
+	return upload, nil
+}
+
+// rejected reports whether a completion failed because of the file itself
+func rejected(err error) bool {
+	return errors.Is(err, attachments.ErrTooLarge) || errors.Is(err, attachments.ErrTypeNotAllowed) ||
+		errors.Is(err, attachments.ErrInfected) || errors.Is(err, attachments.ErrTooMany)
+}
+
+// parseTusMetadata reads an Upload-Metadata header: comma-separated pairs of a key and
+// its base64 value, where a key alone has an empty value
+func parseTusMetadata(header string) (map[string]string, bool) {
+	meta := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return meta, true
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, false
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, false
+		}
+		meta[key] = string(value)
+	}
+	return meta, true
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/operations"
+	"main.go/internal/uploads"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+	"main.go/internal/video"
+)
+
+// CreateUploadRequest declares a file that will be sent in chunks
+type CreateUploadRequest struct {
+	ResourceType string `json:"resource_type" validate:"required,max=50"`
+	ResourceID   string `json:"resource_id" validate:"required,max=255"`
+	Filename     string `json:"filename" validate:"required,max=255"`
+	Caption      string `json:"caption" validate:"max=500"`
+	Size         int64  `json:"size" validate:"required,min=1"`
+}
+
+// uploadResponse is an upload with the chunk size the client should send
+type uploadResponse struct {
+	*uploads.Upload
+	ChunkSize int64 `json:"chunk_size"`
+}
+
+// UploadHandler receives files in chunks for the signed-in user and attaches them once
+// complete; videos are then transcoded when a video service is set
+type UploadHandler struct {
+	uploads   *uploads.Manager
+	videos    *video.Service
+	validator *validation.Validator
+}
+
+// NewUploadHandler creates a new upload handler
+func NewUploadHandler(m *uploads.Manager) *UploadHandler {
+	return &UploadHandler{uploads: m, validator: validation.NewValidator()}
+}
+
+// UseVideos transcodes completed video uploads with v
+func (h *UploadHandler) UseVideos(v *video.Service) {
+	h.videos = v
+}
+
+// Create starts an upload to a resource the caller may attach files to
+func (h *UploadHandler) Create(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Sign in to upload files")
+	}
+	var req CreateUploadRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if !h.uploads.CanWrite(c, req.ResourceType, req.ResourceID) {
+		return utils.NotFound(c, "Resource not found")
+	}
+
+	upload, err := h.uploads.Create(c.UserContext(), uploads.Upload{
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Filename:     req.Filename,
+		Caption:      req.Caption,
+		Size:         req.Size,
+		CreatedBy:    userID,
+	})
+	if errors.Is(err, uploads.ErrTooLarge) {
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File is too large",
+			fiber.NewError(fiber.StatusRequestEntityTooLarge, "File is too large"))
+	}
+	if errors.Is(err, attachments.ErrUnknownType) {
+		return utils.NotFound(c, "Resource not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to start upload")
+	}
+	return utils.JSON(c, fiber.StatusCreated, h.response(upload), "Upload started")
+}
+
+// Show returns an upload; its offset is where an interrupted upload resumes
+func (h *UploadHandler) Show(c *fiber.Ctx) error {
+	upload, err := h.find(c)
+	if upload == nil {
+		return err
+	}
+	return utils.SuccessResponse(c, h.response(upload), "Upload")
+}
+
+// Append stores the request body as the chunk the Content-Range header places, e.g.
+// "bytes 0-8388607/52428800". Chunks must be sent in order, starting at the upload's offset.
+func (h *UploadHandler) Append(c *fiber.Ctx) error {
+	upload, err := h.find(c)
+	if upload == nil {
+		return err
+	}
+	body := c.Body()
+	start, end, total, ok := parseContentRange(c.Get(fiber.HeaderContentRange))
+	if !ok || total != upload.Size || end-start+1 != int64(len(body)) {
+		return utils.BadRequest(c, "Content-Range must give the chunk's bytes and the upload's size")
+	}
+
+	updated, err := h.uploads.Append(c.UserContext(), upload, start, bytes.NewReader(body), int64(len(body)))
+	switch {
+	case errors.Is(err, uploads.ErrOffsetMismatch):
+		return utils.NewValidationResponseBuilder(c).Conflict("The next chunk starts at byte " + strconv.FormatInt(upload.Offset, 10))
+	case errors.Is(err, uploads.ErrChunkTooLarge):
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "Chunk is too large",
+			fiber.NewError(fiber.StatusRequestEntityTooLarge, "Chunks may be at most "+strconv.FormatInt(h.uploads.ChunkBytes(), 10)+" bytes"))
+	case errors.Is(err, uploads.ErrCompleted):
+		return utils.NewValidationResponseBuilder(c).Conflict("This upload is already complete")
+	case errors.Is(err, uploads.ErrExpired):
+		return utils.NotFound(c, "Upload not found")
+	case err != nil:
+		return utils.InternalServerError(c, "Failed to store chunk")
+	}
+	return utils.SuccessResponse(c, h.response(updated), "Chunk received")
+}
+
+// Complete attaches a fully received upload. A video is also queued for transcoding, and
+// the response includes the operation to follow.
+func (h *UploadHandler) Complete(c *fiber.Ctx) error {
+	upload, err := h.find(c)
+	if upload == nil {
+		return err
+	}
+
+	attachment, err := h.uploads.Complete(c.UserContext(), upload)
+	if err != nil {
+		return h.completeFailed(c, upload, err)
+	}
+	op := h.transcode(c, attachment, upload.CreatedBy)
+	return utils.JSON(c, fiber.StatusCreated, fiber.Map{"attachment": attachment, "operation": op}, "File attached")
+}
+
+// completeFailed writes the response for an upload that could not be attached
+func (h *UploadHandler) completeFailed(c *fiber.Ctx, upload *uploads.Upload, err error) error {
+	switch {
+	case errors.Is(err, uploads.ErrIncomplete):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "size",
+			"Only "+strconv.FormatInt(upload.Offset, 10)+" of "+strconv.FormatInt(upload.Size, 10)+" bytes have been received")
+	case errors.Is(err, uploads.ErrCompleted):
+		return utils.NewValidationResponseBuilder(c).Conflict("This upload is already complete")
+	case errors.Is(err, uploads.ErrExpired):
+		return utils.NotFound(c, "Upload not found")
+	case errors.Is(err, attachments.ErrTooLarge):
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "File is too large",
+			fiber.NewError(fiber.StatusRequestEntityTooLarge, "File is too large"))
+	case errors.Is(err, attachments.ErrTypeNotAllowed):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "This type of file is not allowed")
+	case errors.Is(err, attachments.ErrInfected):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "This file appears to contain malware")
+	case errors.Is(err, attachments.ErrTooMany):
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "file", "No more files can be attached")
+	default:
+		return utils.InternalServerError(c, "Failed to attach upload")
+	}
+}
+
+// transcode queues a completed video upload for transcoding and returns the operation to
+// follow; it returns nil for other files, without a video service, or on failure
+func (h *UploadHandler) transcode(c *fiber.Ctx, attachment *attachments.Attachment, ownerID string) *operations.Operation {
+	if h.videos == nil || !video.Transcodable(attachment) {
+		return nil
+	}
+	// The file is attached either way; a failure here can be retried with /videos/:id/transcode
+	op, err := h.videos.Transcode(c.UserContext(), attachment, ownerID)
+	if err != nil {
+		return nil
+	}
+	return op
+}
+
+// find loads the caller's upload named by the :id parameter. A nil upload means the error
+// response has been written.
+func (h *UploadHandler) find(c *fiber.Ctx) (*uploads.Upload, error) {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return nil, utils.NotFound(c, "Upload not found")
+	}
+	upload, err := h.uploads.Store().Find(c.UserContext(), id)
+	if errors.Is(err, uploads.ErrNotFound) || (err == nil && (upload.CreatedBy == "" || upload.CreatedBy != auth.UserID(c))) {
+		return nil, utils.NotFound(c, "Upload not found")
+	}
+	if err != nil {
+		return nil, utils.InternalServerError(c, "Failed to load upload")
+	}
+	return upload, nil
+}
+
+func (h *UploadHandler) response(u *uploads.Upload) uploadResponse {
+	return uploadResponse{Upload: u, ChunkSize: h.uploads.ChunkBytes()}
+}
+
+// parseContentRange reads a "bytes start-end/total" header
+func parseContentRange(header string) (start, end, total int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+	span, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	first, last, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	var err error
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil || start < 0 {
+		return 0, 0, 0, false
+	}
+	if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+		return 0, 0, 0, false
+	}
+	if total, err = strconv.ParseInt(size, 10, 64); err != nil || end >= total {
+		return 0, 0, 0, false
+	}
+	return start, end, total, true
+}
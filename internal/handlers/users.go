@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/users"
+	"main.go/internal/views"
+)
+
+// UserHandler serves the admin user list
+type UserHandler struct {
+	users *users.Store
+	views *views.Store
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(store *users.Store) *UserHandler {
+	return &UserHandler{users: store}
+}
+
+// UseViews lets the user list start from a saved view with ?view=<id>
+func (h *UserHandler) UseViews(store *views.Store) {
+	h.views = store
+}
+
+// Table returns a page of accounts for an admin table, searched, sorted, and with the
+// columns the client asks for
+func (h *UserHandler) Table(c *fiber.Ctx) error {
+	return dataTable(c, h.views, users.Resource, h.users.Table)
+}
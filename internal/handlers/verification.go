@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+)
+
+// emailVerificationPurpose scopes signed tokens so they cannot be replayed for other flows
+const emailVerificationPurpose = "email-verification"
+
+// EmailVerificationHandler emails confirmation links to new accounts and marks addresses
+// verified. Tokens are bound to the address they were sent to, so changing the email
+// invalidates outstanding links.
+type EmailVerificationHandler struct {
+	users     *users.Store
+	tokens    *auth.SignedTokens
+	mailer    mail.Mailer
+	logger    *logger.Logger
+	ttl       time.Duration
+	verifyURL string
+}
+
+// NewEmailVerificationHandler creates a new email verification handler; appURL is used to build the emailed link
+func NewEmailVerificationHandler(userStore *users.Store, tokens *auth.SignedTokens, mailer mail.Mailer, l *logger.Logger, ttl time.Duration, appURL string) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		users:     userStore,
+		tokens:    tokens,
+		mailer:    mailer,
+		logger:    l,
+		ttl:       ttl,
+		verifyURL: strings.TrimRight(appURL, "/") + "/api/v1/auth/verify",
+	}
+}
+
+// Send emails a verification link to user in the background
+func (h *EmailVerificationHandler) Send(c *fiber.Ctx, user *users.User) {
+	expiresAt := clock.Now(c).Add(h.ttl)
+	token := h.tokens.Sign(emailVerificationPurpose, user.ID, strings.ToLower(user.Email), expiresAt)
+	link := h.verifyURL + "?token=" + url.QueryEscape(token)
+
+	sendMailAsync(h.mailer, h.logger, "email-verification-mail", markdownMessage(user.Email,
+		"Confirm your email address",
+		fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n<%s>\n\n"+
+			"The link expires at %s. If you did not create an account, you can ignore this email.\n",
+			user.FirstName, link, expiresAt.UTC().Format(time.RFC1123))))
+}
+
+// Verify confirms the address in the token from the emailed link
+func (h *EmailVerificationHandler) Verify(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return utils.BadRequest(c, "Verification token is required")
+	}
+
+	// The subject is not verified until the user is loaded, so only user IDs are looked up
+	userID, err := h.tokens.Subject(token)
+	if err == nil {
+		_, err = ids.Parse(userID)
+	}
+	if err != nil {
+		return utils.BadRequest(c, "Verification link is invalid")
+	}
+
+	user, err := h.users.FindByID(c.UserContext(), userID)
+	if errors.Is(err, users.ErrUserNotFound) {
+		return utils.BadRequest(c, "Verification link is invalid")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to process request")
+	}
+
+	_, err = h.tokens.Verify(token, emailVerificationPurpose, strings.ToLower(user.Email), clock.Now(c))
+	if errors.Is(err, auth.ErrTokenExpired) {
+		return utils.BadRequest(c, "Verification link has expired; request a new one")
+	}
+	if err != nil {
+		return utils.BadRequest(c, "Verification link is invalid")
+	}
+
+	if user.EmailVerified() {
+		return utils.SuccessResponse(c, nil, "Email address already verified")
+	}
+	if err := h.users.MarkEmailVerified(c.UserContext(), user.ID); err != nil {
+		return utils.InternalServerError(c, "Failed to verify email address")
+	}
+	return utils.SuccessResponse(c, nil, "Email address verified")
+}
+
+// Resend emails a new verification link to the authenticated user
+func (h *EmailVerificationHandler) Resend(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+
+	user, err := h.users.FindByID(c.UserContext(), userID)
+	if errors.Is(err, users.ErrUserNotFound) {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to process request")
+	}
+
+	if user.EmailVerified() {
+		return utils.SuccessResponse(c, nil, "Email address already verified")
+	}
+	h.Send(c, user)
+	return utils.SuccessResponse(c, nil, "Verification email sent")
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/operations"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+	"main.go/internal/video"
+)
+
+// renditionResponse is a rendition with the URL it is streamed from
+type renditionResponse struct {
+	video.Rendition
+	URL string `json:"url"`
+}
+
+// VideoHandler starts transcodes of video attachments and serves their renditions to
+// callers who may read the attachment
+type VideoHandler struct {
+	videos    *video.Service
+	manager   *attachments.Manager
+	validator *validation.Validator
+}
+
+// NewVideoHandler creates a new video handler
+func NewVideoHandler(videos *video.Service, m *attachments.Manager) *VideoHandler {
+	return &VideoHandler{videos: videos, manager: m, validator: validation.NewValidator()}
+}
+
+// Show returns a video's renditions and its latest transcode
+func (h *VideoHandler) Show(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+	if !h.manager.CanRead(c, attachment.ResourceType, attachment.ResourceID) {
+		return utils.NotFound(c, "Video not found")
+	}
+
+	renditions, err := h.videos.Store().List(c.UserContext(), attachment.ID)
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load renditions")
+	}
+	op, err := h.videos.Status(c.UserContext(), attachment.ID)
+	if err != nil && !errors.Is(err, operations.ErrNotFound) {
+		return utils.InternalServerError(c, "Failed to load transcode status")
+	}
+
+	list := make([]renditionResponse, len(renditions))
+	for i, r := range renditions {
+		list[i] = renditionResponse{Rendition: r, URL: "/api/v1/videos/" + attachment.ID + "/renditions/" + r.Profile}
+	}
+	return utils.SuccessResponse(c, fiber.Map{"attachment_id": attachment.ID, "operation": op, "renditions": list}, "Video")
+}
+
+// Transcode starts transcoding a video again, e.g. after a failure or a change of
+// renditions; it answers 202 with the operation to follow
+func (h *VideoHandler) Transcode(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+	if !h.manager.CanWrite(c, attachment.ResourceType, attachment.ResourceID) {
+		return utils.NotFound(c, "Video not found")
+	}
+	op, err := h.videos.Transcode(c.UserContext(), attachment, auth.UserID(c))
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to start transcoding")
+	}
+	return utils.JSON(c, fiber.StatusAccepted, op, "Transcoding started")
+}
+
+// Rendition streams a rendition, honouring single byte ranges so players can seek
+func (h *VideoHandler) Rendition(c *fiber.Ctx) error {
+	attachment, err := h.find(c)
+	if attachment == nil {
+		return err
+	}
+	if !h.manager.CanRead(c, attachment.ResourceType, attachment.ResourceID) {
+		return utils.NotFound(c, "Video not found")
+	}
+	rendition, err := h.videos.Store().Find(c.UserContext(), attachment.ID, c.Params("profile"))
+	if errors.Is(err, video.ErrNotFound) {
+		return utils.NotFound(c, "Rendition not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load rendition")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentSecurityPolicy, fileSecurityPolicy)
+	c.Set(fiber.HeaderContentDisposition, "inline")
+	return sendRanged(c, rendition.ETag(), rendition.ContentType, rendition.Size,
+		func(offset, length int64, partial bool) (io.ReadCloser, error) {
+			if partial {
+				return h.videos.OpenRange(c.UserContext(), rendition, offset, length)
+			}
+			return h.videos.Open(c.UserContext(), rendition)
+		})
+}
+
+// find loads the video attachment named by the :id parameter. A nil attachment means the
+// error response has been written.
+func (h *VideoHandler) find(c *fiber.Ctx) (*attachments.Attachment, error) {
+	id := c.Params("id")
+	if err := h.validator.ValidateVar(id, "uuid"); err != nil {
+		return nil, utils.NotFound(c, "Video not found")
+	}
+	attachment, err := h.manager.Store().Find(c.UserContext(), id)
+	if errors.Is(err, attachments.ErrNotFound) || (err == nil && !video.Transcodable(attachment)) {
+		return nil, utils.NotFound(c, "Video not found")
+	}
+	if err != nil {
+		return nil, utils.InternalServerError(c, "Failed to load video")
+	}
+	return attachment, nil
+}
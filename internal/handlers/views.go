@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/bodyparser"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+	"main.go/internal/views"
+)
+
+// ViewRequest is the payload for saving a view. Sort is written as the table's sort
+// parameter, e.g. "-created_at,email"; Filters and Columns use the table's column names.
+type ViewRequest struct {
+	Resource string            `json:"resource" validate:"omitempty,max=63"`
+	Name     string            `json:"name" validate:"required,max=100"`
+	Search   string            `json:"search" validate:"max=255"`
+	Filters  map[string]string `json:"filters" validate:"max=50,dive,keys,max=63,endkeys,max=255"`
+	Sort     string            `json:"sort" validate:"max=255"`
+	Columns  []string          `json:"columns" validate:"max=50,dive,max=63"`
+}
+
+// ViewHandler lets users keep named views of the data tables they can read
+type ViewHandler struct {
+	store     *views.Store
+	validator *validation.Validator
+}
+
+// NewViewHandler creates a new view handler
+func NewViewHandler(store *views.Store) *ViewHandler {
+	return &ViewHandler{store: store, validator: validation.NewValidator()}
+}
+
+// List returns the caller's views, of the resource in ?resource= or of every resource
+func (h *ViewHandler) List(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Sign in to use saved views")
+	}
+	list, err := h.store.List(c.UserContext(), userID, c.Query("resource"))
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to load views")
+	}
+	return utils.SuccessResponse(c, list, "Views")
+}
+
+// Show returns one of the caller's views
+func (h *ViewHandler) Show(c *fiber.Ctx) error {
+	view, err := findView(c, h.store, c.Params("id"))
+	if view == nil {
+		return err
+	}
+	return utils.SuccessResponse(c, view, "View")
+}
+
+// Create saves a view of a resource the caller can read
+func (h *ViewHandler) Create(c *fiber.Ctx) error {
+	in, err := h.parse(c)
+	if in == nil {
+		return err
+	}
+	if in.Resource == "" {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "resource", "Resource is required")
+	}
+	if !h.canRead(c, in.Resource) {
+		return utils.NewValidationErrorHelper().HandleCustomValidationError(c, "resource", "Unknown resource")
+	}
+
+	view, err := h.store.Create(c.UserContext(), auth.UserID(c), *in)
+	if err != nil {
+		return h.saveFailed(c, err)
+	}
+	return utils.JSON(c, fiber.StatusCreated, view, "View saved")
+}
+
+// Update replaces one of the caller's views; its resource stays the same
+func (h *ViewHandler) Update(c *fiber.Ctx) error {
+	in, err := h.parse(c)
+	if in == nil {
+		return err
+	}
+	view, err := h.store.Update(c.UserContext(), auth.UserID(c), c.Params("id"), *in)
+	if err != nil {
+		return h.saveFailed(c, err)
+	}
+	return utils.SuccessResponse(c, view, "View saved")
+}
+
+// Delete removes one of the caller's views
+func (h *ViewHandler) Delete(c *fiber.Ctx) error {
+	userID := auth.UserID(c)
+	if userID == "" {
+		return utils.Unauthorized(c, "Sign in to use saved views")
+	}
+	err := h.store.Delete(c.UserContext(), userID, c.Params("id"))
+	if errors.Is(err, views.ErrNotFound) {
+		return utils.NotFound(c, "View not found")
+	}
+	if err != nil {
+		return utils.InternalServerError(c, "Failed to delete view")
+	}
+	return utils.SuccessResponse(c, nil, "View deleted")
+}
+
+// parse reads and validates a view from the body. A nil view means the error response
+// has been written.
+func (h *ViewHandler) parse(c *fiber.Ctx) (*views.NewView, error) {
+	if auth.UserID(c) == "" {
+		return nil, utils.Unauthorized(c, "Sign in to use saved views")
+	}
+	var req ViewRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return nil, utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return nil, utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	return &views.NewView{
+		Resource: req.Resource,
+		Name:     req.Name,
+		Search:   req.Search,
+		Filters:  req.Filters,
+		Sort:     req.Sort,
+		Columns:  req.Columns,
+	}, nil
+}
+
+// canRead reports whether the caller holds the scope of a registered resource
+func (h *ViewHandler) canRead(c *fiber.Ctx, resource string) bool {
+	r, ok := h.store.Resource(resource)
+	if !ok {
+		return false
+	}
+	p, ok := auth.PrincipalFrom(c)
+	return ok && (r.Scope == "" || p.HasScope(r.Scope))
+}
+
+// saveFailed answers a failed create or update
+func (h *ViewHandler) saveFailed(c *fiber.Ctx, err error) error {
+	helper := utils.NewValidationErrorHelper()
+	switch {
+	case errors.Is(err, views.ErrNotFound):
+		return utils.NotFound(c, "View not found")
+	case errors.Is(err, views.ErrUnknownResource):
+		return helper.HandleCustomValidationError(c, "resource", "Unknown resource")
+	case errors.Is(err, views.ErrNameTaken):
+		return utils.NewValidationResponseBuilder(c).Conflict("You already have a view with this name")
+	case errors.Is(err, views.ErrTooMany):
+		return helper.HandleCustomValidationError(c, "name", err.Error())
+	case tableError(c, err):
+		return nil
+	default:
+		return utils.InternalServerError(c, "Failed to save view")
+	}
+}
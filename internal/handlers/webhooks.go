@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/bodyparser"
+	"main.go/internal/clock"
+	"main.go/internal/events"
+	"main.go/internal/safehttp"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+)
+
+// TestWebhookRequest is the payload for sending a test webhook
+type TestWebhookRequest struct {
+	URL string `json:"url" validate:"required,url,max=2048"`
+}
+
+// WebhookHandler sends sample webhooks to integrators' endpoints
+type WebhookHandler struct {
+	keyID     string
+	secret    string
+	client    *http.Client
+	validator *validation.Validator
+}
+
+// NewWebhookHandler creates a webhook handler signing with keyID and secret. Its client
+// only reaches public addresses and reports redirects instead of following them, as a
+// real delivery would not follow them either.
+func NewWebhookHandler(keyID, secret string) *WebhookHandler {
+	client := safehttp.NewClient(10 * time.Second)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &WebhookHandler{keyID: keyID, secret: secret, client: client, validator: validation.NewValidator()}
+}
+
+// Test sends a signed webhook.test event to the given URL and reports the receiver's
+// status, latency, and response, with the request sent and its string to sign
+func (h *WebhookHandler) Test(c *fiber.Ctx) error {
+	var req TestWebhookRequest
+	if err := bodyparser.Parse(c, &req); err != nil {
+		return utils.InvalidBody(c, err)
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return utils.NewValidationErrorHelper().HandleValidationError(c, err)
+	}
+	if _, err := safehttp.CheckURL(req.URL); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Webhook URL is not allowed", err)
+	}
+
+	result, err := events.TestWebhook(c.UserContext(), h.client, req.URL, h.keyID, h.secret, clock.Now(c))
+	if errors.Is(err, safehttp.ErrBlocked) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Webhook URL is not allowed", err)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Webhook receiver could not be reached", err)
+	}
+	if result.Status/100 != 2 {
+		return utils.SuccessResponse(c, result, "Receiver rejected the test webhook")
+	}
+	return utils.SuccessResponse(c, result, "Test webhook delivered")
+}
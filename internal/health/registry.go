@@ -0,0 +1,236 @@
+// Package health samples component checks (database, Redis, ...) on a schedule and keeps
+// a short in-memory history so uptime can be reported without an external monitor.
+// History is per instance and starts over on restart.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"main.go/internal/clock"
+	"main.go/internal/safego"
+)
+
+// Check reports whether a component is healthy
+type Check func(ctx context.Context) error
+
+// Retention is how much sample history is kept per component
+const Retention = 7 * 24 * time.Hour
+
+// sample is the outcome of one check
+type sample struct {
+	at time.Time
+	ok bool
+}
+
+// Component is the latest state of a registered check
+type Component struct {
+	Name      string
+	Healthy   bool
+	CheckedAt time.Time
+	// LastError is the most recent failure; it may contain internal details, so it is
+	// never shown on public pages
+	LastError string
+}
+
+// Day is the uptime of a component over one calendar day (UTC)
+type Day struct {
+	Date time.Time
+	// Uptime is the percentage of passing samples, or -1 when there were none
+	Uptime float64
+}
+
+// Registry runs registered checks and records their results
+type Registry struct {
+	mu      sync.RWMutex
+	order   []string
+	checks  map[string]Check
+	latest  map[string]Component
+	history map[string][]sample
+	clock   clock.Clock
+	stop    chan struct{}
+	once    sync.Once
+	// onChange is called when a component turns healthy or unhealthy
+	onChange []func(Component)
+}
+
+// NewRegistry creates an empty registry; clk defaults to the system clock
+func NewRegistry(clk clock.Clock) *Registry {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Registry{
+		checks:  map[string]Check{},
+		latest:  map[string]Component{},
+		history: map[string][]sample{},
+		clock:   clk,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Register adds a named check; components are reported in registration order
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.checks[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.checks[name] = check
+}
+
+// OnChange registers fn to run when a component's health flips, e.g. to purge cached
+// copies of the status page. The first sample of each component is not a change.
+// Register hooks before Start.
+func (r *Registry) OnChange(fn func(Component)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// Start runs every check immediately and then on each interval until Stop
+func (r *Registry) Start(interval time.Duration) {
+	safego.GoNamed("health-sampler", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			r.RunChecks(context.Background(), interval)
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background sampling
+func (r *Registry) Stop() {
+	r.once.Do(func() { close(r.stop) })
+}
+
+// RunChecks runs every check once, each bounded by timeout, and records the results
+func (r *Registry) RunChecks(ctx context.Context, timeout time.Duration) {
+	r.mu.RLock()
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	for name, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := check(checkCtx)
+		cancel()
+		r.record(name, err)
+	}
+}
+
+func (r *Registry) record(name string, err error) {
+	now := r.clock.Now()
+	component := Component{Name: name, Healthy: err == nil, CheckedAt: now}
+	if err != nil {
+		component.LastError = err.Error()
+	}
+
+	r.mu.Lock()
+	previous, seen := r.latest[name]
+	r.latest[name] = component
+
+	samples := append(r.history[name], sample{at: now, ok: err == nil})
+	cutoff := now.Add(-Retention)
+	trim := 0
+	for trim < len(samples) && samples[trim].at.Before(cutoff) {
+		trim++
+	}
+	r.history[name] = samples[trim:]
+	hooks := r.onChange
+	r.mu.Unlock()
+
+	if seen && previous.Healthy != component.Healthy {
+		for _, fn := range hooks {
+			fn(component)
+		}
+	}
+}
+
+// Components returns the latest state of every check in registration order.
+// Checks that have not run yet are reported as unhealthy with a zero CheckedAt.
+func (r *Registry) Components() []Component {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	components := make([]Component, 0, len(r.order))
+	for _, name := range r.order {
+		component, ok := r.latest[name]
+		if !ok {
+			component = Component{Name: name}
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// Healthy reports whether every component passed its latest check
+func (r *Registry) Healthy() bool {
+	for _, component := range r.Components() {
+		if !component.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// Uptime returns the percentage of passing samples for name within window.
+// ok is false when there are no samples in the window.
+func (r *Registry) Uptime(name string, window time.Duration) (uptime float64, ok bool) {
+	cutoff := r.clock.Now().Add(-window)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var total, passed int
+	for _, s := range r.history[name] {
+		if s.at.Before(cutoff) {
+			continue
+		}
+		total++
+		if s.ok {
+			passed++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(passed) * 100 / float64(total), true
+}
+
+// Daily returns per-day uptime for name over the last days days, oldest first
+func (r *Registry) Daily(name string, days int) []Day {
+	today := r.clock.Now().UTC().Truncate(24 * time.Hour)
+	result := make([]Day, days)
+	totals := make([]int, days)
+	passed := make([]int, days)
+	for i := range result {
+		result[i].Date = today.AddDate(0, 0, i-days+1)
+	}
+
+	r.mu.RLock()
+	for _, s := range r.history[name] {
+		i := days - 1 - int(today.Sub(s.at.UTC().Truncate(24*time.Hour))/(24*time.Hour))
+		if i < 0 || i >= days {
+			continue
+		}
+		totals[i]++
+		if s.ok {
+			passed[i]++
+		}
+	}
+	r.mu.RUnlock()
+
+	for i := range result {
+		result[i].Uptime = -1
+		if totals[i] > 0 {
+			result[i].Uptime = float64(passed[i]) * 100 / float64(totals[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+// Package heartbeat pings an external dead-man's-switch monitor (healthchecks.io, Cronitor,
+// Better Stack, ...) on a schedule, so the monitor alerts when the app stops checking in.
+// Pings follow the healthchecks.io URL conventions: the base URL reports success and
+// "<url>/fail" reports a failure.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+// What to send when the app shuts down gracefully
+const (
+	// ShutdownPing sends a final success ping so a quick restart never alerts
+	ShutdownPing = "ping"
+	// ShutdownFail reports a failure so the monitor alerts right away
+	ShutdownFail = "fail"
+	// ShutdownNone sends nothing; the monitor alerts once the grace period passes
+	ShutdownNone = "none"
+)
+
+var (
+	pings    = metrics.NewCounter("heartbeat", "pings_total", "Heartbeat pings delivered")
+	failures = metrics.NewCounter("heartbeat", "ping_failures_total", "Heartbeat pings that could not be delivered")
+)
+
+// Options configures a Heartbeat
+type Options struct {
+	URL      string
+	Interval time.Duration
+	// OnShutdown is ShutdownPing (default), ShutdownFail, or ShutdownNone
+	OnShutdown string
+	// Check, when set, runs before each ping; an error is reported as a failure ping
+	Check func(ctx context.Context) error
+	// Muted, when set and true, reports success even if Check fails (maintenance windows)
+	Muted func() bool
+	// Client defaults to an http.Client with a 10 second timeout
+	Client *http.Client
+}
+
+// Heartbeat pings the monitor every interval until stopped
+type Heartbeat struct {
+	opts   Options
+	logger *logger.Logger
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New creates a heartbeat; call Start to begin pinging
+func New(opts Options, l *logger.Logger) *Heartbeat {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.OnShutdown == "" {
+		opts.OnShutdown = ShutdownPing
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	opts.URL = strings.TrimRight(opts.URL, "/")
+	return &Heartbeat{opts: opts, logger: l, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Start pings immediately and then on every interval in a background goroutine
+func (h *Heartbeat) Start() {
+	safego.GoNamed("heartbeat", func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			h.beat()
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends the schedule and sends the configured shutdown ping. ctx bounds the final ping.
+func (h *Heartbeat) Stop(ctx context.Context) {
+	h.once.Do(func() {
+		close(h.stop)
+		<-h.done
+
+		switch h.opts.OnShutdown {
+		case ShutdownFail:
+			h.send(ctx, "/fail", "shutting down")
+		case ShutdownPing:
+			h.send(ctx, "", "shutting down")
+		}
+	})
+}
+
+// beat runs the optional check and reports its outcome
+func (h *Heartbeat) beat() {
+	ctx, cancel := context.WithTimeout(context.Background(), h.opts.Interval)
+	defer cancel()
+
+	if h.opts.Check != nil {
+		if err := h.opts.Check(ctx); err != nil && !h.muted() {
+			h.send(ctx, "/fail", err.Error())
+			return
+		}
+	}
+	h.send(ctx, "", "")
+}
+
+func (h *Heartbeat) muted() bool {
+	return h.opts.Muted != nil && h.opts.Muted()
+}
+
+// send posts body to the monitor URL with suffix; failures are logged and counted
+func (h *Heartbeat) send(ctx context.Context, suffix, body string) {
+	if err := h.post(ctx, h.opts.URL+suffix, body); err != nil {
+		failures.Inc()
+		h.logger.Warn("Heartbeat ping failed", zap.Error(err))
+		return
+	}
+	pings.Inc()
+}
+
+func (h *Heartbeat) post(ctx context.Context, url, body string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := h.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("monitor returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Package i18n routes pages by locale. The default locale is served without a prefix
+// (/about) and every other supported locale under its own (/de/about, /pt-br/about), so
+// each language has a stable, crawlable URL. The middleware strips the prefix before
+// routing, so handlers are registered once and read the locale with Locale(c). Layouts
+// link the translations of a page with Alternates.
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/text/language"
+)
+
+// localsKey is the c.Locals key holding the request's locale state
+const localsKey = "i18n"
+
+// XDefault is the hreflang value for the page users without a matching locale get
+const XDefault = "x-default"
+
+// Options configures locale routing
+type Options struct {
+	// Locales lists the supported BCP 47 tags, e.g. en, de, pt-BR
+	Locales []string
+	// Default is served without a prefix; defaults to the first locale
+	Default string
+	// BaseURL makes alternate links absolute, as search engines require (APP_URL)
+	BaseURL string
+}
+
+// Alternate is one translation of the current page
+type Alternate struct {
+	// HrefLang is the locale tag, or x-default
+	HrefLang string
+	URL      string
+}
+
+// Router maps locale prefixes to supported locales
+type Router struct {
+	locales  []string
+	prefixes map[string]string
+	def      string
+	baseURL  string
+}
+
+// state is what the middleware records for the layouts
+type state struct {
+	router *Router
+	locale string
+	// path is the request path without its locale prefix
+	path string
+}
+
+// New validates the locales and creates a router
+func New(opts Options) (*Router, error) {
+	if len(opts.Locales) == 0 {
+		return nil, errors.New("i18n: no locales configured")
+	}
+	r := &Router{
+		prefixes: map[string]string{},
+		baseURL:  strings.TrimRight(opts.BaseURL, "/"),
+	}
+	for _, raw := range opts.Locales {
+		tag, err := language.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("i18n: invalid locale %q: %w", raw, err)
+		}
+		locale := tag.String()
+		prefix := strings.ToLower(locale)
+		if _, ok := r.prefixes[prefix]; ok {
+			return nil, fmt.Errorf("i18n: duplicate locale %q", raw)
+		}
+		r.prefixes[prefix] = locale
+		r.locales = append(r.locales, locale)
+	}
+
+	r.def = r.locales[0]
+	if opts.Default != "" {
+		locale, ok := r.prefixes[strings.ToLower(opts.Default)]
+		if !ok {
+			return nil, fmt.Errorf("i18n: default locale %q is not in the supported locales", opts.Default)
+		}
+		r.def = locale
+	}
+	return r, nil
+}
+
+// Locales returns the supported locales, as configured
+func (r *Router) Locales() []string {
+	return append([]string(nil), r.locales...)
+}
+
+// Default returns the locale served without a prefix
+func (r *Router) Default() string {
+	return r.def
+}
+
+// Path returns p as served in locale: unprefixed for the default locale, otherwise under
+// the locale's lowercase prefix. Unsupported locales get the default.
+func (r *Router) Path(locale, p string) string {
+	prefix := strings.ToLower(locale)
+	if _, ok := r.prefixes[prefix]; !ok || r.prefixes[prefix] == r.def {
+		return p
+	}
+	if p == "/" {
+		return "/" + prefix
+	}
+	return "/" + prefix + p
+}
+
+// Middleware resolves the request's locale from its first path segment and strips the
+// prefix so the remaining path routes as usual. A prefix naming the default locale, or a
+// supported locale in another case (/DE/), is redirected (308) to its canonical URL.
+// Unprefixed paths are in the default locale.
+func (r *Router) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		current := c.Path()
+		segment, rest := splitPrefix(current)
+		locale, ok := r.prefixes[strings.ToLower(segment)]
+		if !ok {
+			c.Locals(localsKey, &state{router: r, locale: r.def, path: current})
+			return c.Next()
+		}
+
+		if prefix := strings.ToLower(locale); locale == r.def || segment != prefix {
+			target := "/" + prefix + strings.TrimPrefix(current, "/"+segment)
+			if locale == r.def {
+				// Collapse slashes so /en//evil.example cannot become a protocol-relative URL
+				target = "/" + strings.TrimLeft(rest, "/")
+			}
+			if query := c.Request().URI().QueryString(); len(query) > 0 {
+				target += "?" + string(query)
+			}
+			return c.Redirect(target, fiber.StatusPermanentRedirect)
+		}
+
+		c.Locals(localsKey, &state{router: r, locale: locale, path: rest})
+		c.Path(rest)
+		return c.Next()
+	}
+}
+
+// splitPrefix splits "/de/about" into "de" and "/about", and "/de" into "de" and "/"
+func splitPrefix(p string) (string, string) {
+	trimmed := strings.TrimPrefix(p, "/")
+	segment, rest, found := strings.Cut(trimmed, "/")
+	if !found {
+		return segment, "/"
+	}
+	return segment, "/" + rest
+}
+
+// Locale returns the request's locale. Without the middleware it is empty.
+func Locale(c *fiber.Ctx) string {
+	if s, ok := c.Locals(localsKey).(*state); ok {
+		return s.locale
+	}
+	return ""
+}
+
+// FromContext returns the locale of the request behind ctx, for templ components rendered
+// with c.Context(); it defaults to "en"
+func FromContext(ctx context.Context) string {
+	if s := fromContext(ctx); s != nil {
+		return s.locale
+	}
+	return "en"
+}
+
+// Alternates lists the current page in every supported locale plus an x-default entry
+// pointing at the default locale, for <link rel="alternate" hreflang> tags. It is empty
+// with a single locale or without the middleware.
+func Alternates(ctx context.Context) []Alternate {
+	s := fromContext(ctx)
+	if s == nil || len(s.router.locales) < 2 {
+		return nil
+	}
+	r := s.router
+	alternates := make([]Alternate, 0, len(r.locales)+1)
+	for _, locale := range r.locales {
+		alternates = append(alternates, Alternate{HrefLang: locale, URL: r.baseURL + r.Path(locale, s.path)})
+	}
+	return append(alternates, Alternate{HrefLang: XDefault, URL: r.baseURL + s.path})
+}
+
+func fromContext(ctx context.Context) *state {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(localsKey).(*state)
+	return s
+}
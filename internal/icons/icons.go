@@ -0,0 +1,189 @@
+// Package icons serves the favicon, touch icons, and web app manifest. The icons are
+// generated from source.png by cmd/icons and embedded in the binary; after replacing
+// source.png run:
+//
+//	go generate ./internal/icons
+package icons
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:generate go run ../../cmd/icons -src source.png -out generated -background "#4f46e5"
+
+//go:embed generated
+var generated embed.FS
+
+// Kind is how an icon is rendered from the source image
+type Kind int
+
+const (
+	// KindAny is the source image scaled down, keeping transparency
+	KindAny Kind = iota
+	// KindTouch is flattened onto the background color; iOS fills transparency with black
+	KindTouch
+	// KindMaskable pads the image onto the background color so launchers can crop it to
+	// any shape without cutting into the artwork
+	KindMaskable
+)
+
+// Icon is one generated PNG
+type Icon struct {
+	File string
+	Size int
+	Kind Kind
+}
+
+// Set lists the generated PNG icons
+var Set = []Icon{
+	{File: "favicon-16x16.png", Size: 16, Kind: KindAny},
+	{File: "favicon-32x32.png", Size: 32, Kind: KindAny},
+	{File: "apple-touch-icon.png", Size: 180, Kind: KindTouch},
+	{File: "icon-192.png", Size: 192, Kind: KindAny},
+	{File: "icon-512.png", Size: 512, Kind: KindAny},
+	{File: "icon-maskable-192.png", Size: 192, Kind: KindMaskable},
+	{File: "icon-maskable-512.png", Size: 512, Kind: KindMaskable},
+}
+
+// Favicon is the multi-resolution ICO file
+const Favicon = "favicon.ico"
+
+// FaviconSizes are the resolutions packed into the favicon
+var FaviconSizes = []int{16, 32, 48}
+
+// ManifestPath is where the web app manifest is served
+const ManifestPath = "/manifest.webmanifest"
+
+// Options describe the app in the web app manifest
+type Options struct {
+	Name            string
+	ShortName       string
+	ThemeColor      string
+	BackgroundColor string
+	// MaxAge is the Cache-Control max-age in seconds for icons and the manifest
+	MaxAge int
+}
+
+// asset is a file ready to send
+type asset struct {
+	body        []byte
+	contentType string
+	etag        string
+}
+
+// New serves /favicon.ico and /apple-touch-icon.png at the root, where browsers look
+// for them without a <link>, the remaining icons under /icons/, and the web app manifest
+// at ManifestPath (also at /manifest.json for older links)
+func New(opts Options) (fiber.Handler, error) {
+	if opts.ShortName == "" {
+		opts.ShortName = opts.Name
+	}
+
+	assets := map[string]*asset{}
+	add := func(urlPath, contentType string, body []byte) {
+		sum := sha256.Sum256(body)
+		assets[urlPath] = &asset{body: body, contentType: contentType, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+	}
+
+	ico, err := fs.ReadFile(generated, path.Join("generated", Favicon))
+	if err != nil {
+		return nil, err
+	}
+	add("/"+Favicon, "image/x-icon", ico)
+
+	for _, icon := range Set {
+		body, err := fs.ReadFile(generated, path.Join("generated", icon.File))
+		if err != nil {
+			return nil, err
+		}
+		add(URL(icon), "image/png", body)
+	}
+
+	manifest, err := json.Marshal(newManifest(opts))
+	if err != nil {
+		return nil, err
+	}
+	add(ManifestPath, "application/manifest+json", manifest)
+	assets["/manifest.json"] = assets[ManifestPath]
+
+	cacheControl := "public, max-age=" + strconv.Itoa(opts.MaxAge)
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+		a, ok := assets[c.Path()]
+		if !ok {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+		c.Set(fiber.HeaderETag, a.etag)
+		if c.Get(fiber.HeaderIfNoneMatch) == a.etag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		c.Set(fiber.HeaderContentType, a.contentType)
+		return c.Send(a.body)
+	}, nil
+}
+
+// URL is the path an icon is served at
+func URL(icon Icon) string {
+	if icon.Kind == KindTouch {
+		return "/" + icon.File
+	}
+	return "/icons/" + icon.File
+}
+
+// manifest is the web app manifest (https://www.w3.org/TR/appmanifest/)
+type manifest struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	ShortName       string         `json:"short_name"`
+	StartURL        string         `json:"start_url"`
+	Scope           string         `json:"scope"`
+	Display         string         `json:"display"`
+	ThemeColor      string         `json:"theme_color,omitempty"`
+	BackgroundColor string         `json:"background_color,omitempty"`
+	Icons           []manifestIcon `json:"icons"`
+}
+
+type manifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+func newManifest(opts Options) manifest {
+	m := manifest{
+		ID:              "/",
+		Name:            opts.Name,
+		ShortName:       opts.ShortName,
+		StartURL:        "/",
+		Scope:           "/",
+		Display:         "standalone",
+		ThemeColor:      opts.ThemeColor,
+		BackgroundColor: opts.BackgroundColor,
+	}
+	// Launchers pick from the large icons; favicons and the touch icon have their own tags
+	for _, icon := range Set {
+		if icon.Size < 192 || icon.Kind == KindTouch {
+			continue
+		}
+		purpose := "any"
+		if icon.Kind == KindMaskable {
+			purpose = "maskable"
+		}
+		size := strconv.Itoa(icon.Size)
+		m.Icons = append(m.Icons, manifestIcon{Src: URL(icon), Sizes: size + "x" + size, Type: "image/png", Purpose: purpose})
+	}
+	return m
+}
@@ -0,0 +1,109 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// localsKey is the c.Locals key holding the request's Generator
+const localsKey = "ids"
+
+// Generator produces identifiers and random tokens. Production code uses Random;
+// tests use Sequence so snapshots of responses and emails stay stable.
+type Generator interface {
+	// NewID returns a new UUID-formatted identifier
+	NewID() string
+	// RandomString returns a URL-safe random string of length n
+	RandomString(n int) string
+}
+
+// Random generates IDs and strings from crypto/rand
+type Random struct{}
+
+// NewID returns a time-sortable UUIDv7, the primary key strategy for rows whose IDs are
+// generated in Go (tables default to the matching uuid_generate_v7() in Postgres)
+func (Random) NewID() string {
+	return NewV7().String()
+}
+
+// RandomString returns n URL-safe characters from crypto/rand
+func (Random) RandomString(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	buf := make([]byte, base64.RawURLEncoding.DecodedLen(n)+1)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to UUID entropy
+		return strings.Repeat(strings.ReplaceAll(utils.UUIDv4(), "-", ""), n/32+1)[:n]
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)[:n]
+}
+
+// Sequence is a deterministic generator for tests. IDs count up from 1
+// ("00000000-0000-4000-8000-000000000001") and random strings are derived from the same counter.
+type Sequence struct {
+	n uint64
+}
+
+// NewSequence creates a deterministic generator
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+// NewID returns the next sequential UUID-formatted ID
+func (s *Sequence) NewID() string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", atomic.AddUint64(&s.n, 1))
+}
+
+// RandomString returns the next counter value, zero-padded or truncated to n characters
+func (s *Sequence) RandomString(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("%0*d", n, atomic.AddUint64(&s.n, 1))
+	return value[len(value)-n:]
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultGenerator Generator = Random{}
+)
+
+// Default returns the process-wide generator used where no request is available
+func Default() Generator {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultGenerator
+}
+
+// SetDefault replaces the process-wide generator (main sets it from Services.IDs)
+func SetDefault(gen Generator) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultGenerator = gen
+}
+
+// Middleware makes gen available to handlers via From
+func Middleware(gen Generator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(localsKey, gen)
+		return c.Next()
+	}
+}
+
+// From returns the request's generator, falling back to Default()
+func From(c *fiber.Ctx) Generator {
+	if c != nil {
+		if gen, ok := c.Locals(localsKey).(Generator); ok && gen != nil {
+			return gen
+		}
+	}
+	return Default()
+}
@@ -0,0 +1,171 @@
+package ids
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UUID is a 16-byte UUID. It is written to and scanned from uuid columns (the zero UUID
+// is NULL) and marshals to the canonical text form, so stores and JSON payloads can use it
+// in place of a string.
+type UUID [16]byte
+
+// Nil is the zero UUID
+var Nil UUID
+
+// ErrInvalidUUID is returned when parsing text that is not a UUID
+var ErrInvalidUUID = errors.New("invalid UUID")
+
+// v7 keeps UUIDv7 values from one process in order: within a millisecond, the 12 bits
+// after the version count up from a random start (RFC 9562, method 3)
+var v7 struct {
+	mu     sync.Mutex
+	lastMS int64
+	seq    uint16
+}
+
+// NewV7 returns a time-sortable UUIDv7: 48 bits of Unix milliseconds, a 12-bit sequence,
+// and 62 random bits. Values from this process sort in the order they were created, which
+// keeps B-tree primary key inserts at the end of the index.
+func NewV7() UUID {
+	return newV7(time.Now())
+}
+
+func newV7(now time.Time) UUID {
+	var u UUID
+	if _, err := rand.Read(u[6:]); err != nil {
+		// crypto/rand never fails on supported platforms
+		panic(fmt.Sprintf("ids: failed to read random bytes: %v", err))
+	}
+
+	v7.mu.Lock()
+	ms := now.UnixMilli()
+	if ms > v7.lastMS {
+		v7.lastMS = ms
+		// A random start below 2048 leaves room to count up within the millisecond
+		v7.seq = binary.BigEndian.Uint16(u[6:8]) & 0x07ff
+	} else {
+		// Same millisecond, or the clock went back: count up, borrowing the next
+		// millisecond when the sequence runs out
+		v7.seq++
+		if v7.seq > 0x0fff {
+			v7.lastMS++
+			v7.seq = 0
+		}
+		ms = v7.lastMS
+	}
+	seq := v7.seq
+	v7.mu.Unlock()
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = 0x80 | u[8]&0x3f
+	return u
+}
+
+// Parse parses a UUID in canonical form (8-4-4-4-12 hex digits, either case)
+func Parse(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+	hexDigits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(u[:], []byte(hexDigits)); err != nil {
+		return Nil, ErrInvalidUUID
+	}
+	return u, nil
+}
+
+// String returns the canonical lowercase form
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// IsZero reports whether u is the zero UUID
+func (u UUID) IsZero() bool {
+	return u == Nil
+}
+
+// Version returns the version number from u's version bits
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// Time returns when a UUIDv7 was created, to the millisecond; zero for other versions
+func (u UUID) Time() time.Time {
+	if u.Version() != 7 {
+		return time.Time{}
+	}
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5])
+	return time.UnixMilli(ms)
+}
+
+// Value implements driver.Valuer; the zero UUID is written as NULL
+func (u UUID) Value() (driver.Value, error) {
+	if u.IsZero() {
+		return nil, nil
+	}
+	return u.String(), nil
+}
+
+// Scan implements sql.Scanner; NULL scans as the zero UUID
+func (u *UUID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*u = Nil
+		return nil
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*u = parsed
+		return nil
+	case []byte:
+		// Text from a uuid column, or the raw bytes of a bytea one
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		return u.Scan(string(v))
+	default:
+		return fmt.Errorf("ids: cannot scan %T into UUID", src)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
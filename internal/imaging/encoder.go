@@ -0,0 +1,76 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes images in a format the standard library cannot encode
+type Encoder interface {
+	Encode(ctx context.Context, w io.Writer, img *image.NRGBA, quality int) error
+}
+
+// Command encodes with an external tool such as cwebp or avifenc. The image is handed over
+// as a temporary PNG; in Args, {in} and {out} name the input and output files and
+// {quality} is the requested quality.
+type Command struct {
+	Path string
+	Args []string
+}
+
+// NewCommand looks up name on PATH and returns an encoder running it with args
+func NewCommand(name string, args ...string) (*Command, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Command{Path: path, Args: args}, nil
+}
+
+// Encode implements Encoder
+func (c *Command) Encode(ctx context.Context, w io.Writer, img *image.NRGBA, quality int) error {
+	dir, err := os.MkdirTemp("", "imaging-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out")
+	var buf bytes.Buffer
+	// Fast compression: the file only lives until the tool has read it
+	fast := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := fast.Encode(&buf, img); err != nil {
+		return err
+	}
+	if err := os.WriteFile(in, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = strings.NewReplacer("{in}", in, "{out}", out, "{quality}", strconv.Itoa(quality)).Replace(arg)
+	}
+	cmd := exec.CommandContext(ctx, c.Path, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", filepath.Base(c.Path), err, strings.TrimSpace(stderr.String()))
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,243 @@
+// Package imaging serves resized and converted image attachments at CDN-style URLs,
+// /img/<signature>/<options>/<attachment id>. The options segment (w, h, fit, format, q)
+// is HMAC-signed with the attachment ID, so only URLs the app generated are transformed
+// and clients cannot make the server render arbitrary sizes. Results are stored next to
+// the originals and reused; re-encoding drops EXIF and other metadata, after applying the
+// orientation it carried.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"main.go/internal/attachments"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+)
+
+// cachePrefix is the storage folder of transformed images
+const cachePrefix = "image-cache/"
+
+var (
+	rendered = metrics.NewCounter("images", "rendered_total", "Image transformations rendered")
+	hits     = metrics.NewCounter("images", "cache_hits_total", "Image transformations served from the cache")
+)
+
+// sourceFormats maps the content types that can be transformed to their decoder's format
+var sourceFormats = map[string]string{
+	"image/jpeg": FormatJPEG,
+	"image/png":  FormatPNG,
+	"image/gif":  "gif",
+}
+
+var contentTypes = map[string]string{
+	FormatJPEG: "image/jpeg",
+	FormatPNG:  "image/png",
+	FormatWebP: "image/webp",
+	FormatAVIF: "image/avif",
+}
+
+// Config bounds the work a transformation may do
+type Config struct {
+	// Secret signs URLs
+	Secret string
+	// MaxDimension bounds the requested width and height
+	MaxDimension int
+	// MaxPixels bounds the size of source images; larger ones are not transformed
+	MaxPixels int
+	// Concurrency bounds how many images are rendered at once
+	Concurrency int
+	AppURL      string
+}
+
+// Service renders, caches, and signs image transformations of attachments
+type Service struct {
+	manager  *attachments.Manager
+	store    *Store
+	cfg      Config
+	encoders map[string]Encoder
+	// slots limits concurrent renders; group merges concurrent requests for one variant
+	slots  chan struct{}
+	group  singleflight.Group
+	logger *logger.Logger
+}
+
+// New creates a service transforming m's attachments. It deletes cached variants when
+// their attachment is cleaned up.
+func New(m *attachments.Manager, store *Store, cfg Config, l *logger.Logger) *Service {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	s := &Service{
+		manager:  m,
+		store:    store,
+		cfg:      cfg,
+		encoders: map[string]Encoder{},
+		slots:    make(chan struct{}, cfg.Concurrency),
+		logger:   l,
+	}
+	m.OnPurge(s.purge)
+	return s
+}
+
+// UseEncoder enables an output format the standard library cannot encode (webp, avif).
+// Without one, such requests get JPEG, or PNG for sources that may be transparent.
+func (s *Service) UseEncoder(format string, e Encoder) {
+	s.encoders[format] = e
+}
+
+// MaxDimension returns the largest width or height a URL may request
+func (s *Service) MaxDimension() int {
+	return s.cfg.MaxDimension
+}
+
+// Sign returns the signature of an options segment for an attachment
+func (s *Service) Sign(options, id string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.Secret))
+	mac.Write([]byte("img\x00" + options + "\x00" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature was made by Sign for options and id
+func (s *Service) Verify(signature, options, id string) bool {
+	return hmac.Equal([]byte(signature), []byte(s.Sign(options, id)))
+}
+
+// URL returns the signed URL of a transformation of an attachment. It does not expire, so
+// hand it only to callers that may read the attachment.
+func (s *Service) URL(id string, o Options) string {
+	options := o.String()
+	return strings.TrimRight(s.cfg.AppURL, "/") + "/img/" + s.Sign(options, id) + "/" + options + "/" + id
+}
+
+// Transformable reports whether an attachment's content type can be transformed
+func Transformable(a *attachments.Attachment) bool {
+	_, ok := sourceFormats[a.ContentType]
+	return ok
+}
+
+// Render returns the cached variant of a for o, rendering and storing it first if needed
+func (s *Service) Render(ctx context.Context, a *attachments.Attachment, o Options) (*Variant, error) {
+	source, ok := sourceFormats[a.ContentType]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+	format := s.outputFormat(source, o.Format)
+	o.Format = format
+	sum := sha256.Sum256([]byte(a.Checksum + "|" + o.String()))
+	key := cachePrefix + a.ID + "/" + hex.EncodeToString(sum[:16]) + "." + format
+
+	v, err := s.store.Find(ctx, key)
+	if err == nil {
+		hits.Inc()
+		return v, nil
+	}
+	if !errors.Is(err, errNoVariant) {
+		return nil, err
+	}
+
+	result, err, _ := s.group.Do(key, func() (interface{}, error) {
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return s.render(ctx, a, o, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Variant), nil
+}
+
+// outputFormat resolves the requested format against the available encoders
+func (s *Service) outputFormat(source, requested string) string {
+	switch requested {
+	case FormatJPEG, FormatPNG:
+		return requested
+	case FormatWebP, FormatAVIF:
+		if s.encoders[requested] != nil {
+			return requested
+		}
+	}
+	if source == FormatJPEG {
+		return FormatJPEG
+	}
+	return FormatPNG
+}
+
+func (s *Service) render(ctx context.Context, a *attachments.Attachment, o Options, key string) (*Variant, error) {
+	r, err := s.manager.Open(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, a.Size+1))
+	r.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	src, _, err := decode(data, s.cfg.MaxPixels)
+	if err != nil {
+		return nil, err
+	}
+	img := transform(src, o)
+
+	var buf bytes.Buffer
+	switch o.Format {
+	case FormatJPEG:
+		err = encodeJPEG(&buf, img, o.Quality)
+	case FormatPNG:
+		err = encodePNG(&buf, img)
+	default:
+		err = s.encoders[o.Format].Encode(ctx, &buf, img, o.Quality)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	v := &Variant{Key: key, ContentType: contentTypes[o.Format], Size: int64(buf.Len())}
+	if err := s.manager.Storage().Put(ctx, key, bytes.NewReader(buf.Bytes()), v.Size, v.ContentType); err != nil {
+		return nil, err
+	}
+	if err := s.store.Insert(ctx, a.ID, v); err != nil {
+		return nil, err
+	}
+	rendered.Inc()
+	return v, nil
+}
+
+// Open returns the contents of a variant
+func (s *Service) Open(ctx context.Context, v *Variant) (io.ReadCloser, error) {
+	return s.manager.Storage().Open(ctx, v.Key)
+}
+
+// purge deletes the cached variants of an attachment that is being cleaned up
+func (s *Service) purge(ctx context.Context, a attachments.Attachment) error {
+	keys, err := s.store.Keys(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.manager.Storage().Delete(ctx, key); err != nil {
+			s.logger.Warn("Failed to delete image variant", zap.String("key", key), zap.Error(err))
+			return err
+		}
+		if err := s.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fit modes
+const (
+	// FitContain scales the image to fit inside the box, keeping its aspect ratio
+	FitContain = "contain"
+	// FitCover scales the image to cover the box and crops the overflow around the center
+	FitCover = "cover"
+	// FitFill stretches the image to the box
+	FitFill = "fill"
+)
+
+// Output formats; FormatAuto keeps JPEG and PNG sources as they are and turns GIFs into PNG
+const (
+	FormatAuto = ""
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+)
+
+// DefaultQuality is the encoder quality used when the options do not set one
+const DefaultQuality = 80
+
+// ErrInvalidOptions is returned for option strings that cannot be parsed or are out of range
+var ErrInvalidOptions = errors.New("invalid image options")
+
+// Options describes one transformation. Its String form is the canonical options segment
+// of an image URL, so equal options always share a signature and a cached result.
+type Options struct {
+	// Width and Height bound the result in pixels; 0 derives one from the other, and both
+	// 0 keeps the source size. Images are never enlarged.
+	Width  int
+	Height int
+	Fit    string
+	Format string
+	// Quality is 1-100 for lossy formats
+	Quality int
+}
+
+// ParseOptions reads a comma-separated options segment such as
+// "w=300,h=200,fit=cover,format=webp" and checks it against maxDimension
+func ParseOptions(segment string, maxDimension int) (Options, error) {
+	o := Options{Fit: FitContain, Quality: DefaultQuality}
+	if segment == "" || segment == "_" {
+		return o, nil
+	}
+	for _, part := range strings.Split(segment, ",") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return o, fmt.Errorf("%w: %q", ErrInvalidOptions, part)
+		}
+		var err error
+		switch key {
+		case "w":
+			o.Width, err = dimension(value, maxDimension)
+		case "h":
+			o.Height, err = dimension(value, maxDimension)
+		case "q":
+			o.Quality, err = strconv.Atoi(value)
+			if err == nil && (o.Quality < 1 || o.Quality > 100) {
+				err = errors.New("out of range")
+			}
+		case "fit":
+			o.Fit = value
+			if value != FitContain && value != FitCover && value != FitFill {
+				err = errors.New("unknown fit")
+			}
+		case "format":
+			o.Format = value
+			if value != FormatJPEG && value != FormatPNG && value != FormatWebP && value != FormatAVIF {
+				err = errors.New("unknown format")
+			}
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return o, fmt.Errorf("%w: %s: %v", ErrInvalidOptions, part, err)
+		}
+	}
+	return o, nil
+}
+
+func dimension(value string, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n > max {
+		return 0, fmt.Errorf("must be between 1 and %d", max)
+	}
+	return n, nil
+}
+
+// String returns the canonical options segment; "_" stands for the defaults
+func (o Options) String() string {
+	var parts []string
+	if o.Width > 0 {
+		parts = append(parts, "w="+strconv.Itoa(o.Width))
+	}
+	if o.Height > 0 {
+		parts = append(parts, "h="+strconv.Itoa(o.Height))
+	}
+	if o.Fit != "" && o.Fit != FitContain {
+		parts = append(parts, "fit="+o.Fit)
+	}
+	if o.Format != FormatAuto {
+		parts = append(parts, "format="+o.Format)
+	}
+	if o.Quality != 0 && o.Quality != DefaultQuality {
+		parts = append(parts, "q="+strconv.Itoa(o.Quality))
+	}
+	if len(parts) == 0 {
+		return "_"
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"main.go/internal/database"
+)
+
+// errNoVariant is returned when a transformation has not been cached yet
+var errNoVariant = errors.New("image variant not found")
+
+// Variant is a cached transformation of an attachment
+type Variant struct {
+	Key         string
+	ContentType string
+	Size        int64
+}
+
+// ETag returns a validator for the variant. Its key is derived from the source's checksum
+// and the options, so it already names the bytes.
+func (v *Variant) ETag() string {
+	name := path.Base(v.Key)
+	return `"` + strings.TrimSuffix(name, path.Ext(name)) + `"`
+}
+
+// Store records cached variants so they are deleted with their attachment
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new image variant store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Find returns the variant stored under key
+func (s *Store) Find(ctx context.Context, key string) (*Variant, error) {
+	var v Variant
+	err := s.db.QueryRowContext(ctx,
+		`SELECT storage_key, content_type, size FROM image_variants WHERE storage_key = $1`, key).
+		Scan(&v.Key, &v.ContentType, &v.Size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNoVariant
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image variant: %w", err)
+	}
+	return &v, nil
+}
+
+// Insert records a stored variant of an attachment
+func (s *Store) Insert(ctx context.Context, attachmentID string, v *Variant) error {
+	if _, err := s.db.ExecContext(ctx, `
+INSERT INTO image_variants (storage_key, attachment_id, content_type, size) VALUES ($1, $2, $3, $4)
+ON CONFLICT (storage_key) DO NOTHING`, v.Key, attachmentID, v.ContentType, v.Size); err != nil {
+		return fmt.Errorf("failed to record image variant: %w", err)
+	}
+	return nil
+}
+
+// Keys returns the storage keys of an attachment's variants
+func (s *Store) Keys(ctx context.Context, attachmentID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT storage_key FROM image_variants WHERE attachment_id = $1`, attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image variants: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan image variant: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Delete forgets a variant whose file has been deleted
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM image_variants WHERE storage_key = $1`, key); err != nil {
+		return fmt.Errorf("failed to delete image variant: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// ErrUnsupportedImage is returned for sources that cannot be decoded or are too large to
+var ErrUnsupportedImage = errors.New("image cannot be transformed")
+
+// decode reads an image, refusing sources over maxPixels before decoding them so a small
+// file cannot expand into gigabytes. JPEG orientation is applied, since re-encoding drops
+// the EXIF data that carried it.
+func decode(data []byte, maxPixels int) (*image.NRGBA, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d is over the pixel limit", ErrUnsupportedImage, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	if format == "jpeg" {
+		src = orient(src, jpegOrientation(data))
+	}
+	return src, format, nil
+}
+
+// transform resizes src according to o
+func transform(src *image.NRGBA, o Options) *image.NRGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	w, h := o.Width, o.Height
+	switch {
+	case w == 0 && h == 0:
+		return src
+	case w == 0:
+		w = int(math.Round(float64(sw) * float64(h) / float64(sh)))
+	case h == 0:
+		h = int(math.Round(float64(sh) * float64(w) / float64(sw)))
+	}
+
+	crop := src.Bounds()
+	switch o.Fit {
+	case FitContain:
+		scale := math.Min(float64(w)/float64(sw), float64(h)/float64(sh))
+		w, h = int(math.Round(float64(sw)*scale)), int(math.Round(float64(sh)*scale))
+	case FitCover:
+		// Crop the source to the box's aspect ratio around its center
+		scale := math.Max(float64(w)/float64(sw), float64(h)/float64(sh))
+		cw, ch := int(math.Round(float64(w)/scale)), int(math.Round(float64(h)/scale))
+		x, y := (sw-cw)/2, (sh-ch)/2
+		crop = image.Rect(x, y, x+cw, y+ch)
+	}
+
+	// Never enlarge: a box bigger than the (cropped) source keeps the source size
+	if w > crop.Dx() || h > crop.Dy() {
+		scale := math.Min(float64(crop.Dx())/float64(w), float64(crop.Dy())/float64(h))
+		w, h = int(math.Round(float64(w)*scale)), int(math.Round(float64(h)*scale))
+	}
+	return resize(src, crop, max(w, 1), max(h, 1))
+}
+
+// resize scales the crop of src to w x h with an area average, weighting colors by alpha so
+// transparent edges do not darken
+func resize(src *image.NRGBA, crop image.Rectangle, w, h int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	sx, sy := float64(crop.Dx())/float64(w), float64(crop.Dy())/float64(h)
+	ox, oy := float64(crop.Min.X), float64(crop.Min.Y)
+
+	for dy := 0; dy < h; dy++ {
+		y0, y1 := oy+float64(dy)*sy, oy+float64(dy+1)*sy
+		for dx := 0; dx < w; dx++ {
+			x0, x1 := ox+float64(dx)*sx, ox+float64(dx+1)*sx
+
+			var r, g, b, a, total float64
+			for py := int(y0); float64(py) < y1; py++ {
+				wy := math.Min(y1, float64(py+1)) - math.Max(y0, float64(py))
+				for px := int(x0); float64(px) < x1; px++ {
+					wx := math.Min(x1, float64(px+1)) - math.Max(x0, float64(px))
+					weight := wx * wy
+					c := src.NRGBAAt(px, py)
+					alpha := float64(c.A) / 255 * weight
+					r += float64(c.R) * alpha
+					g += float64(c.G) * alpha
+					b += float64(c.B) * alpha
+					a += alpha
+					total += weight
+				}
+			}
+			if a > 0 {
+				dst.SetNRGBA(dx, dy, color.NRGBA{
+					R: uint8(math.Round(r / a)),
+					G: uint8(math.Round(g / a)),
+					B: uint8(math.Round(b / a)),
+					A: uint8(math.Round(a / total * 255)),
+				})
+			}
+		}
+	}
+	return dst
+}
+
+// opaque reports whether every pixel of img is fully opaque
+func opaque(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeJPEG writes img as a baseline JPEG, flattening transparency onto white
+func encodeJPEG(w io.Writer, img *image.NRGBA, quality int) error {
+	if !opaque(img) {
+		flat := image.NewNRGBA(img.Bounds())
+		draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, image.Point{}, draw.Over)
+		img = flat
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// encodePNG writes img as a PNG
+func encodePNG(w io.Writer, img *image.NRGBA) error {
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	return enc.Encode(w, img)
+}
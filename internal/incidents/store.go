@@ -0,0 +1,80 @@
+package incidents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// Incident impacts, from least to most severe
+const (
+	ImpactNone     = "none"
+	ImpactMinor    = "minor"
+	ImpactMajor    = "major"
+	ImpactCritical = "critical"
+)
+
+// Incident statuses; only StatusResolved closes an incident
+const (
+	StatusInvestigating = "investigating"
+	StatusIdentified    = "identified"
+	StatusMonitoring    = "monitoring"
+	StatusResolved      = "resolved"
+)
+
+// Incident is an outage or degradation shown on the status page
+type Incident struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Impact      string     `json:"impact"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Resolved reports whether the incident is over
+func (i Incident) Resolved() bool {
+	return i.ResolvedAt != nil
+}
+
+// Store persists incidents
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new incident store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Recent returns unresolved incidents plus those that started after since, newest first
+func (s *Store) Recent(ctx context.Context, since time.Time, limit int) ([]Incident, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, title, description, impact, status, started_at, resolved_at
+FROM incidents
+WHERE resolved_at IS NULL OR started_at >= $1
+ORDER BY resolved_at IS NULL DESC, started_at DESC
+LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load incidents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIncidents(rows)
+}
+
+func scanIncidents(rows *sql.Rows) ([]Incident, error) {
+	incidents := []Incident{}
+	for rows.Next() {
+		var i Incident
+		if err := rows.Scan(&i.ID, &i.Title, &i.Description, &i.Impact, &i.Status, &i.StartedAt, &i.ResolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, rows.Err()
+}
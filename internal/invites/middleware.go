@@ -0,0 +1,50 @@
+package invites
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/security"
+)
+
+// ManageScope lets admins mint and revoke codes; holders also pass the gate
+const ManageScope = "invites:write"
+
+// Gate returns a middleware that keeps signed-in users who never redeemed an invite out
+// of the routes after it. Anonymous requests (so login and public pages keep working),
+// API keys, holders of ManageScope, and paths under exemptPrefixes (e.g. the auth and
+// redeem endpoints) pass through. Checks fail closed: if the store is unreachable,
+// requests get 503.
+func Gate(store *Store, exemptPrefixes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if store == nil || !ok || principal.IsMachine() || principal.HasScope(ManageScope) {
+			return c.Next()
+		}
+		for _, prefix := range exemptPrefixes {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		invited, err := store.Invited(c.UserContext(), principal.ID)
+		if err != nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":   "Service Unavailable",
+				"message": "Invite check unavailable",
+				"status":  fiber.StatusServiceUnavailable,
+			})
+		}
+		if !invited {
+			security.Emit(c, security.EventPermissionDenied, map[string]string{"reason": "not invited"})
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Forbidden",
+				"message": "An invite is required during the beta; redeem your invite code to continue",
+				"status":  fiber.StatusForbidden,
+			})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,244 @@
+// Package invites gates a soft launch: while INVITE_ONLY is on, accounts can only be
+// registered with an invite code, and signed-in users who never redeemed one are kept
+// out of the API. Admins mint codes with a use limit and an optional expiry.
+package invites
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main.go/internal/database"
+)
+
+var (
+	// ErrInvalidCode is returned for codes that do not exist, were revoked, expired, or are used up
+	ErrInvalidCode = errors.New("invalid or expired invite code")
+	// ErrNotFound is returned when an invite does not exist
+	ErrNotFound = errors.New("invite not found")
+	// ErrAlreadyInvited is returned when a user who already has access redeems another code
+	ErrAlreadyInvited = errors.New("user has already redeemed an invite")
+)
+
+// codeAlphabet is Crockford's base32: no I, L, O, or U, so codes survive being read aloud
+const codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// codeLength is the number of characters in a code (60 bits), shown in groups of four
+const codeLength = 12
+
+// Invite is a minted invite code. The code itself is only returned when it is created;
+// the database keeps its hash.
+type Invite struct {
+	ID        string     `json:"id"`
+	Code      string     `json:"code,omitempty"`
+	Note      string     `json:"note"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewInvite holds the fields needed to mint a code
+type NewInvite struct {
+	Note      string
+	MaxUses   int
+	ExpiresAt *time.Time
+	CreatedBy string
+}
+
+// Store persists invite codes and redemptions
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new invite store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create mints a code
+func (s *Store) Create(ctx context.Context, n NewInvite) (*Invite, error) {
+	code, err := generateCode()
+	if err != nil {
+		return nil, err
+	}
+	if n.MaxUses <= 0 {
+		n.MaxUses = 1
+	}
+	invite := &Invite{Code: code, Note: n.Note, MaxUses: n.MaxUses, ExpiresAt: n.ExpiresAt, CreatedBy: n.CreatedBy}
+	err = s.db.QueryRowContext(ctx, `
+INSERT INTO invite_codes (code_hash, note, max_uses, expires_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at`, hashCode(code), n.Note, n.MaxUses, n.ExpiresAt, n.CreatedBy).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+	return invite, nil
+}
+
+// List returns the most recently minted codes first
+func (s *Store) List(ctx context.Context) ([]Invite, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, note, max_uses, uses, expires_at, revoked_at, created_by, created_at
+FROM invite_codes
+ORDER BY created_at DESC
+LIMIT 500`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []Invite{}
+	for rows.Next() {
+		var i Invite
+		if err := rows.Scan(&i.ID, &i.Note, &i.MaxUses, &i.Uses, &i.ExpiresAt, &i.RevokedAt, &i.CreatedBy, &i.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		invites = append(invites, i)
+	}
+	return invites, rows.Err()
+}
+
+// Revoke stops a code from being redeemed; users it already let in keep their access
+func (s *Store) Revoke(ctx context.Context, id string, now time.Time) error {
+	result, err := s.db.ExecContext(ctx, `
+UPDATE invite_codes SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1`, id, now)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Reserve takes one use of a code, e.g. before creating the account it is for, and
+// returns the invite ID. Give the use back with Release if the account is not created.
+func (s *Store) Reserve(ctx context.Context, code string, now time.Time) (string, error) {
+	return reserve(ctx, s.db, code, now)
+}
+
+// Release returns a use taken by Reserve
+func (s *Store) Release(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE invite_codes SET uses = uses - 1 WHERE id = $1 AND uses > 0`, id)
+	if err != nil {
+		return fmt.Errorf("failed to release invite: %w", err)
+	}
+	return nil
+}
+
+// Record lets the user in through the reserved invite
+func (s *Store) Record(ctx context.Context, id, userID string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO invite_redemptions (user_id, invite_id) VALUES ($1, $2)`, userID, id)
+	if database.IsUniqueViolation(err) {
+		return ErrAlreadyInvited
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record invite: %w", err)
+	}
+	return nil
+}
+
+// Redeem reserves and records a code for an existing user in one transaction, e.g. for
+// accounts created before the launch or through a social login
+func (s *Store) Redeem(ctx context.Context, code, userID string, now time.Time) error {
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM invite_redemptions WHERE user_id = $1)`, userID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check invite: %w", err)
+		}
+		if exists {
+			return ErrAlreadyInvited
+		}
+		id, err := reserve(ctx, tx, code, now)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO invite_redemptions (user_id, invite_id) VALUES ($1, $2)`, userID, id); err != nil {
+			return fmt.Errorf("failed to record invite: %w", err)
+		}
+		return nil
+	})
+}
+
+// Invited reports whether the user has redeemed a code
+func (s *Store) Invited(ctx context.Context, userID string) (bool, error) {
+	var invited bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM invite_redemptions WHERE user_id = $1)`, userID).Scan(&invited)
+	if err != nil {
+		return false, fmt.Errorf("failed to check invite: %w", err)
+	}
+	return invited, nil
+}
+
+// queryer is satisfied by both the pool and a transaction
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// reserve increments a usable code's use count in a single statement, so concurrent
+// redemptions cannot exceed max_uses
+func reserve(ctx context.Context, q queryer, code string, now time.Time) (string, error) {
+	normalized := Normalize(code)
+	if len(normalized) != codeLength {
+		return "", ErrInvalidCode
+	}
+	var id string
+	err := q.QueryRowContext(ctx, `
+UPDATE invite_codes SET uses = uses + 1
+WHERE code_hash = $1 AND revoked_at IS NULL AND uses < max_uses
+  AND (expires_at IS NULL OR expires_at > $2)
+RETURNING id`, hashCode(normalized), now).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalidCode
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem invite: %w", err)
+	}
+	return id, nil
+}
+
+// Normalize uppercases a code and drops separators and whitespace, so "abcd-efgh-jkmn"
+// and "ABCDEFGHJKMN" are the same code
+func Normalize(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == ' ' || r == '\t':
+			return -1
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return r
+	}, strings.TrimSpace(code))
+}
+
+// generateCode returns a random code formatted as XXXX-XXXX-XXXX
+func generateCode() (string, error) {
+	raw := make([]byte, codeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate invite code: %w", err)
+	}
+	var b strings.Builder
+	for i, v := range raw {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(codeAlphabet[int(v)%len(codeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// hashCode returns the hex SHA-256 of a normalized code. Codes are random, so a fast
+// hash is sufficient (unlike passwords).
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(Normalize(code)))
+	return hex.EncodeToString(sum[:])
+}
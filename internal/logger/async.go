@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"main.go/internal/metrics"
+)
+
+const (
+	// asyncScheme is the zap sink scheme of the asynchronous writer; its "path" query
+	// parameters are the outputs it writes to
+	asyncScheme = "async"
+	// asyncBatchBytes bounds how much the writer sends to its outputs in one write
+	asyncBatchBytes = 64 << 10
+	// asyncSyncTimeout bounds how long Sync waits for queued entries, so shutdown does
+	// not hang on an output that stopped accepting writes
+	asyncSyncTimeout = 5 * time.Second
+)
+
+// errAsyncClosed is returned by writes made after the writer was closed
+var errAsyncClosed = errors.New("logger: asynchronous writer is closed")
+
+var (
+	asyncMu    sync.Mutex
+	asyncQueue int
+	asyncOnce  sync.Once
+
+	droppedEntries = metrics.NewCounter("logger", "dropped_total", "Log entries dropped because the asynchronous log queue was full")
+)
+
+// SetAsync makes loggers created afterwards write through a queue of up to queueSize
+// entries, so a request never waits for stdout or a log file: entries are encoded by
+// the caller and written in batches by a background goroutine. When the queue is full
+// the oldest entries are dropped (and counted in app_logger_dropped_total) rather than
+// slowing callers down. Logger.Sync writes what is queued, so call it before exiting.
+// A queueSize of 0 writes synchronously.
+func SetAsync(queueSize int) {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	asyncQueue = queueSize
+	if queueSize > 0 {
+		asyncOnce.Do(func() {
+			_ = zap.RegisterSink(asyncScheme, newAsyncSink)
+		})
+	}
+}
+
+// outputPaths routes paths through the asynchronous writer when SetAsync enabled it
+func outputPaths(paths []string) []string {
+	asyncMu.Lock()
+	size := asyncQueue
+	asyncMu.Unlock()
+	if size <= 0 || len(paths) == 0 {
+		return paths
+	}
+	query := url.Values{"path": paths, "queue": {strconv.Itoa(size)}}
+	return []string{asyncScheme + ":?" + query.Encode()}
+}
+
+func newAsyncSink(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	size, err := strconv.Atoi(query.Get("queue"))
+	if err != nil || size <= 0 {
+		size = 1
+	}
+	out, closeOut, err := zap.Open(query["path"]...)
+	if err != nil {
+		return nil, err
+	}
+	w := &asyncWriter{out: out, closeOut: closeOut, queue: make(chan []byte, size), done: make(chan struct{})}
+	go w.run()
+	return w, nil
+}
+
+// asyncWriter is a zap.Sink queueing entries for a goroutine writing them to out
+type asyncWriter struct {
+	out      zapcore.WriteSyncer
+	closeOut func()
+	queue    chan []byte
+	// done is closed once run has written the last entry of the closed queue
+	done chan struct{}
+	// pending counts entries queued or being written
+	pending atomic.Int64
+	// mu is held for reading while an entry is queued and for writing while the queue
+	// is closed, so no write sends on a closed queue
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+// Write queues a copy of p (zap reuses its buffer), dropping the oldest entry when the
+// queue is full. Writes after Close fail.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, errAsyncClosed
+	}
+
+	entry := append([]byte(nil), p...)
+	w.pending.Add(1)
+	for {
+		select {
+		case w.queue <- entry:
+			return len(p), nil
+		default:
+		}
+		select {
+		case <-w.queue:
+			w.pending.Add(-1)
+			droppedEntries.Inc()
+		default:
+		}
+	}
+}
+
+// run writes queued entries, joining those already waiting into one write, until the
+// queue is closed and drained
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	var batch []byte
+	for entry := range w.queue {
+		batch = append(batch[:0], entry...)
+		n := int64(1)
+	fill:
+		for len(batch) < asyncBatchBytes {
+			select {
+			case entry, ok := <-w.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, entry...)
+				n++
+			default:
+				break fill
+			}
+		}
+		_, _ = w.out.Write(batch)
+		w.pending.Add(-n)
+	}
+}
+
+// Sync waits for the queued entries to be written, then syncs the outputs
+func (w *asyncWriter) Sync() error {
+	deadline := time.Now().Add(asyncSyncTimeout)
+	for w.pending.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return w.out.Sync()
+}
+
+// Close stops accepting entries, writes what is queued, and closes the outputs
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	timer := time.NewTimer(asyncSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+	case <-timer.C:
+	}
+	err := w.out.Sync()
+	w.closeOnce.Do(w.closeOut)
+	return err
+}
@@ -3,7 +3,9 @@ package logger
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 
+	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -49,6 +51,7 @@ func New(environment string) (*Logger, error) {
 	}
 
 	// Create the logger
+	config.OutputPaths = outputPaths(config.OutputPaths)
 	logger, err := config.Build()
 	if err != nil {
 		return nil, err
@@ -59,22 +62,44 @@ func New(environment string) (*Logger, error) {
 
 // NewWithFile creates a new logger that also writes to a file
 func NewWithFile(environment, logDir string) (*Logger, error) {
+	return newWithFile(environment, logDir, "")
+}
+
+// NewWithProcessFile is NewWithFile for fiber's prefork mode, whose child processes would
+// otherwise all write app.log and error.log at once. The parent keeps those files; each
+// child writes app.<pid>.log and error.<pid>.log, and tags its entries with its pid.
+func NewWithProcessFile(environment, logDir string) (*Logger, error) {
+	if !fiber.IsChild() {
+		return NewWithFile(environment, logDir)
+	}
+	pid := os.Getpid()
+	l, err := newWithFile(environment, logDir, "."+strconv.Itoa(pid))
+	if err != nil {
+		return nil, err
+	}
+	return l.WithFields(zap.Int("pid", pid)), nil
+}
+
+// newWithFile writes to app<suffix>.log and error<suffix>.log in logDir
+func newWithFile(environment, logDir, suffix string) (*Logger, error) {
 	// Ensure log directory exists
 	if err := os.MkdirAll(logDir, 0750); err != nil {
 		return nil, err
 	}
+	appLog := filepath.Join(logDir, "app"+suffix+".log")
+	errorLog := filepath.Join(logDir, "error"+suffix+".log")
 
 	var config zap.Config
 
 	switch environment {
 	case "production":
 		config = zap.NewProductionConfig()
-		config.OutputPaths = []string{"stdout", filepath.Join(logDir, "app.log")}
-		config.ErrorOutputPaths = []string{"stderr", filepath.Join(logDir, "error.log")}
+		config.OutputPaths = []string{"stdout", appLog}
+		config.ErrorOutputPaths = []string{"stderr", errorLog}
 	case "development":
 		config = zap.NewDevelopmentConfig()
-		config.OutputPaths = []string{"stdout", filepath.Join(logDir, "app.log")}
-		config.ErrorOutputPaths = []string{"stderr", filepath.Join(logDir, "error.log")}
+		config.OutputPaths = []string{"stdout", appLog}
+		config.ErrorOutputPaths = []string{"stderr", errorLog}
 	default:
 		config = zap.NewDevelopmentConfig()
 		config.OutputPaths = []string{"stdout"}
@@ -98,6 +123,7 @@ func NewWithFile(environment, logDir string) (*Logger, error) {
 	}
 
 	// Create the logger
+	config.OutputPaths = outputPaths(config.OutputPaths)
 	logger, err := config.Build()
 	if err != nil {
 		return nil, err
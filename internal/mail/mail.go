@@ -0,0 +1,176 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+)
+
+// Message is a single outgoing email
+type Message struct {
+	To string
+	// ReplyTo, when set, is where replies go instead of the sender, e.g. a contact form's visitor
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPOptions configures an SMTPMailer
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Encryption is "tls" (implicit TLS, usually port 465), "starttls", or empty for plain
+	// connections upgraded opportunistically (e.g. mailpit in development)
+	Encryption  string
+	FromAddress string
+	FromName    string
+}
+
+// SMTPMailer sends mail through an SMTP server
+type SMTPMailer struct {
+	opts SMTPOptions
+}
+
+// NewSMTPMailer creates an SMTP mailer
+func NewSMTPMailer(opts SMTPOptions) *SMTPMailer {
+	opts.Encryption = strings.ToLower(opts.Encryption)
+	if opts.Encryption == "null" {
+		opts.Encryption = ""
+	}
+	return &SMTPMailer{opts: opts}
+}
+
+// Send delivers msg, honoring ctx for the connection deadline
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(m.opts.Port))
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+
+	var conn net.Conn
+	var err error
+	if m.opts.Encryption == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.opts.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && m.opts.Encryption != "tls" {
+		if err := client.StartTLS(&tls.Config{ServerName: m.opts.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	} else if m.opts.Encryption == "starttls" {
+		return errors.New("mail server does not support STARTTLS")
+	}
+
+	if m.opts.Username != "" && m.opts.Username != "null" {
+		if err := client.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.opts.FromAddress); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(m.build(msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return client.Quit()
+}
+
+// build renders the RFC 5322 message, multipart/alternative when both bodies are set
+func (m *SMTPMailer) build(msg Message) []byte {
+	from := mail.Address{Name: m.opts.FromName, Address: m.opts.FromAddress}
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	if msg.ReplyTo != "" {
+		fmt.Fprintf(&b, "Reply-To: %s\r\n", (&mail.Address{Address: msg.ReplyTo}).String())
+	}
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	switch {
+	case msg.HTML == "":
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		b.WriteString(msg.Text)
+	case msg.Text == "":
+		b.WriteString("Content-Type: text/html; charset=utf-8\r\n\r\n")
+		b.WriteString(msg.HTML)
+	default:
+		boundary := fmt.Sprintf("boundary-%d", time.Now().UnixNano())
+		fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+		fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, msg.Text)
+		fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=utf-8\r\n\r\n%s\r\n", boundary, msg.HTML)
+		fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	}
+	return []byte(b.String())
+}
+
+// LogMailer logs messages instead of sending them; used when FEATURE_MAIL is off
+type LogMailer struct {
+	logger *logger.Logger
+}
+
+// NewLogMailer creates a mailer that writes messages to the log
+func NewLogMailer(l *logger.Logger) *LogMailer {
+	return &LogMailer{logger: l}
+}
+
+// Send logs msg
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Info("Mail not sent (FEATURE_MAIL disabled)",
+		zap.String("to", msg.To),
+		zap.String("reply_to", msg.ReplyTo),
+		zap.String("subject", msg.Subject),
+		zap.String("text", msg.Text),
+	)
+	return nil
+}
@@ -0,0 +1,46 @@
+package maintenance
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExemptPrefixes keep working during maintenance: probes, the status page, static
+// assets, login, and the admin API used to end a window early
+var ExemptPrefixes = []string{
+	"/health", "/ready", "/live", "/metrics", "/status",
+	"/static/", "/.well-known/",
+	"/auth/", "/api/v1/auth/", "/api/v1/admin/maintenance",
+}
+
+// Middleware answers 503 with Retry-After while a window is in progress
+func Middleware(schedule *Schedule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		window, active := schedule.Active()
+		if !active || exempt(c.Path()) {
+			return c.Next()
+		}
+
+		seconds := int(math.Ceil(window.EndsAt.Sub(schedule.clock.Now()).Seconds()))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":       "Service Unavailable",
+			"message":     "Down for scheduled maintenance: " + window.Title,
+			"status":      fiber.StatusServiceUnavailable,
+			"ends_at":     window.EndsAt,
+			"retry_after": seconds,
+		})
+	}
+}
+
+func exempt(path string) bool {
+	for _, prefix := range ExemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+// Package maintenance schedules planned downtime. During a window the middleware answers
+// 503, the status page shows a banner, and alert notifiers are muted.
+package maintenance
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/safego"
+)
+
+// Schedule caches upcoming windows in memory so the per-request check never touches
+// the database. It reloads on an interval, so windows scheduled by other instances
+// take effect within one refresh.
+type Schedule struct {
+	store   *Store
+	clock   clock.Clock
+	logger  *logger.Logger
+	mu      sync.RWMutex
+	windows []Window
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewSchedule creates a schedule backed by store; call Refresh or Start to load it
+func NewSchedule(store *Store, clk clock.Clock, l *logger.Logger) *Schedule {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Schedule{store: store, clock: clk, logger: l, stop: make(chan struct{})}
+}
+
+// Refresh reloads upcoming windows from the store
+func (s *Schedule) Refresh(ctx context.Context) error {
+	windows, err := s.store.Upcoming(ctx, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.windows = windows
+	s.mu.Unlock()
+	return nil
+}
+
+// Start loads the schedule now and then every interval until Stop. Failed reloads keep
+// the previous schedule.
+func (s *Schedule) Start(interval time.Duration) {
+	safego.GoNamed("maintenance-schedule", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if err := s.Refresh(ctx); err != nil {
+				s.logger.Warn("Failed to refresh maintenance schedule", zap.Error(err))
+			}
+			cancel()
+
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background refreshes
+func (s *Schedule) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// Active returns the window in progress, if any
+func (s *Schedule) Active() (*Window, bool) {
+	now := s.clock.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range s.windows {
+		if s.windows[i].Active(now) {
+			w := s.windows[i]
+			return &w, true
+		}
+	}
+	return nil, false
+}
+
+// InWindow reports whether a maintenance window is in progress; it is the mute
+// check for alert notifiers and the heartbeat
+func (s *Schedule) InWindow() bool {
+	_, ok := s.Active()
+	return ok
+}
+
+// Upcoming returns the windows in progress or not yet started, soonest first
+func (s *Schedule) Upcoming() []Window {
+	now := s.clock.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	windows := make([]Window, 0, len(s.windows))
+	for _, w := range s.windows {
+		if now.Before(w.EndsAt) {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
@@ -0,0 +1,96 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// ErrWindowNotFound is returned when a maintenance window does not exist
+var ErrWindowNotFound = errors.New("maintenance window not found")
+
+// Window is a scheduled period of planned downtime
+type Window struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+}
+
+// Active reports whether now falls inside the window
+func (w Window) Active(now time.Time) bool {
+	return !now.Before(w.StartsAt) && now.Before(w.EndsAt)
+}
+
+// NewWindow holds the fields needed to schedule a window
+type NewWindow struct {
+	Title       string
+	Description string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	CreatedBy   string
+}
+
+// Store persists maintenance windows
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new maintenance window store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create schedules a window
+func (s *Store) Create(ctx context.Context, w NewWindow) (*Window, error) {
+	window := &Window{Title: w.Title, Description: w.Description, StartsAt: w.StartsAt, EndsAt: w.EndsAt, CreatedBy: w.CreatedBy}
+	err := s.db.QueryRowContext(ctx, `
+INSERT INTO maintenance_windows (title, description, starts_at, ends_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id`, w.Title, w.Description, w.StartsAt, w.EndsAt, w.CreatedBy).Scan(&window.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+	return window, nil
+}
+
+// Upcoming returns windows that have not ended yet, soonest first
+func (s *Store) Upcoming(ctx context.Context, now time.Time) ([]Window, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT id, title, description, starts_at, ends_at, created_by
+FROM maintenance_windows
+WHERE ends_at > $1
+ORDER BY starts_at
+LIMIT 100`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []Window{}
+	for rows.Next() {
+		var w Window
+		if err := rows.Scan(&w.ID, &w.Title, &w.Description, &w.StartsAt, &w.EndsAt, &w.CreatedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance window: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// Delete cancels a window, or ends it early if it is in progress
+func (s *Store) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance window: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrWindowNotFound
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Package markdown renders markdown to HTML that is safe to embed in a page. Input is
+// parsed as GitHub-flavored markdown with raw HTML dropped, and the output is passed
+// through an allowlist sanitizer regardless, so a parser bug cannot become an XSS hole.
+// Two policies cover the app's sources:
+//
+//   - Strict, for user-generated content such as comments and profile bios: text
+//     formatting, lists, tables, code, and nofollow links; no images or IDs.
+//   - Trusted, for text the team writes (release notes, emails): Strict plus images and
+//     heading anchors.
+//
+// Fenced code blocks can be syntax highlighted with CSS classes; serve the stylesheet
+// from CSS. Rendered documents are cached by content, since the same comment or release
+// note is rendered on every view.
+package markdown
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+
+	"main.go/internal/metrics"
+)
+
+// Policy selects what the sanitizer lets through
+type Policy int
+
+const (
+	// Strict is for user-generated content
+	Strict Policy = iota
+	// Trusted is for content written by the team
+	Trusted
+)
+
+// CSSPath is where the app serves the highlighting stylesheet
+const CSSPath = "/markdown.css"
+
+// DefaultStyle is the chroma style CSS uses when none is given
+const DefaultStyle = "github"
+
+// highlightClass matches the class names chroma emits, and nothing an attacker could use
+// to borrow the page's own layout classes
+var highlightClass = regexp.MustCompile(`^(chroma|line|cl|ln|lnt|lntd|lntable|hl|[a-z]{1,3}[0-9]?)$`)
+
+var (
+	cacheHits   = metrics.NewCounter("markdown", "cache_hits_total", "Markdown documents served from the render cache")
+	cacheMisses = metrics.NewCounter("markdown", "cache_misses_total", "Markdown documents rendered")
+)
+
+// Options configures a renderer
+type Options struct {
+	Policy Policy
+	// Highlight marks up fenced code blocks with chroma classes; style them with CSS
+	Highlight bool
+	// CacheSize bounds the rendered documents kept in memory; 0 disables the cache
+	CacheSize int
+}
+
+// Renderer converts markdown to sanitized HTML; it is safe for concurrent use
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+	cache  *cache
+}
+
+// New creates a renderer
+func New(opts Options) *Renderer {
+	// GFM, with table alignment as attributes since the sanitizer drops style attributes
+	extensions := []goldmark.Extender{
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough, extension.Linkify, extension.TaskList,
+	}
+	if opts.Highlight {
+		extensions = append(extensions, highlighting.NewHighlighting(
+			highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+		))
+	}
+	parserOptions := []parser.Option{}
+	if opts.Policy == Trusted {
+		parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+	}
+
+	r := &Renderer{
+		md:     goldmark.New(goldmark.WithExtensions(extensions...), goldmark.WithParserOptions(parserOptions...)),
+		policy: newPolicy(opts.Policy),
+	}
+	if opts.CacheSize > 0 {
+		r.cache = newCache(opts.CacheSize)
+	}
+	return r
+}
+
+// Render converts src to sanitized HTML
+func (r *Renderer) Render(src string) (string, error) {
+	var key [sha256.Size]byte
+	if r.cache != nil {
+		key = sha256.Sum256([]byte(src))
+		if html, ok := r.cache.get(key); ok {
+			cacheHits.Inc()
+			return html, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(src), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	html := r.policy.Sanitize(buf.String())
+	cacheMisses.Inc()
+
+	if r.cache != nil {
+		r.cache.put(key, html)
+	}
+	return html, nil
+}
+
+// CSS returns the stylesheet for highlighted code blocks in the named chroma style, e.g.
+// github or monokai; unknown names fall back to chroma's default
+func CSS(style string) (string, error) {
+	if style == "" {
+		style = DefaultStyle
+	}
+	var buf strings.Builder
+	if err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, styles.Get(style)); err != nil {
+		return "", fmt.Errorf("failed to write highlighting CSS: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// newPolicy builds the sanitizer allowlist
+func newPolicy(policy Policy) *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "hr", "strong", "em", "del", "blockquote", "ul", "ol", "li",
+		"pre", "code", "span", "h1", "h2", "h3", "h4", "h5", "h6",
+		"table", "thead", "tbody", "tr", "th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(highlightClass).OnElements("pre", "code", "span")
+	// GFM task lists
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowAttrs("title").OnElements("a")
+
+	switch policy {
+	case Trusted:
+		p.AllowImages()
+		p.RequireNoFollowOnLinks(false)
+		p.AllowAttrs("id").Matching(regexp.MustCompile(`^[a-z0-9-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	default:
+		// Search engines should not credit links spammers post
+		p.RequireNoFollowOnLinks(true)
+		p.RequireNoReferrerOnLinks(true)
+	}
+	return p
+}
+
+// cache is a fixed-size LRU of rendered documents keyed by the source's hash
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	html string
+}
+
+func newCache(size int) *cache {
+	return &cache{size: size, order: list.New(), entries: map[[sha256.Size]byte]*list.Element{}}
+}
+
+func (c *cache) get(key [sha256.Size]byte) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*cacheEntry).html, true
+	}
+	return "", false
+}
+
+func (c *cache) put(key [sha256.Size]byte, html string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, html: html})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,180 @@
+// Package metrics is a small in-process metrics registry. Components (middlewares,
+// pools, workers) register their counters and gauges when they are constructed, and
+// the registry exports them in Prometheus text format and in the detailed health check.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// namespace prefixes every exported metric name
+const namespace = "app"
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	value atomic.Int64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n int64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+// metric is a registered counter or gauge
+type metric struct {
+	component string
+	name      string
+	help      string
+	kind      string
+	counter   *Counter
+	// gauge reads the value of gauges and of counters kept elsewhere
+	gauge func() float64
+}
+
+func (m *metric) value() float64 {
+	if m.counter != nil {
+		return float64(m.counter.Value())
+	}
+	return m.gauge()
+}
+
+// Registry holds every registered metric, grouped by component
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]*metric{}}
+}
+
+// Default is the process-wide registry used by the package-level helpers
+var Default = NewRegistry()
+
+// Counter registers (or returns the already registered) counter component_name
+func (r *Registry) Counter(component, name, help string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := component + "_" + name
+	if existing, ok := r.metrics[key]; ok && existing.counter != nil {
+		return existing.counter
+	}
+	counter := &Counter{}
+	r.metrics[key] = &metric{component: component, name: name, help: help, kind: "counter", counter: counter}
+	return counter
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at export time.
+// Registering the same name again replaces the previous function.
+func (r *Registry) GaugeFunc(component, name, help string, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics[component+"_"+name] = &metric{component: component, name: name, help: help, kind: "gauge", gauge: fn}
+}
+
+// CounterFunc registers a counter whose value is read from fn at export time, for totals
+// another component already keeps. Registering the same name again replaces the previous
+// function.
+func (r *Registry) CounterFunc(component, name, help string, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics[component+"_"+name] = &metric{component: component, name: name, help: help, kind: "counter", gauge: fn}
+}
+
+// Snapshot returns every metric value grouped by component, for health output
+func (r *Registry) Snapshot() map[string]map[string]float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := map[string]map[string]float64{}
+	for _, m := range r.metrics {
+		if snapshot[m.component] == nil {
+			snapshot[m.component] = map[string]float64{}
+		}
+		snapshot[m.component][m.name] = m.value()
+	}
+	return snapshot
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.RLock()
+	keys := make([]string, 0, len(r.metrics))
+	for key := range r.metrics {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	metrics := make([]*metric, 0, len(keys))
+	for _, key := range keys {
+		metrics = append(metrics, r.metrics[key])
+	}
+	r.mu.RUnlock()
+
+	for _, m := range metrics {
+		name := sanitize(namespace + "_" + m.component + "_" + m.name)
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n",
+			name, m.help, name, m.kind, name, formatValue(m.value())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the registry in Prometheus text format
+func (r *Registry) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return r.WritePrometheus(c.Response().BodyWriter())
+	}
+}
+
+// NewCounter registers a counter in the Default registry
+func NewCounter(component, name, help string) *Counter {
+	return Default.Counter(component, name, help)
+}
+
+// NewGaugeFunc registers a gauge in the Default registry
+func NewGaugeFunc(component, name, help string, fn func() float64) {
+	Default.GaugeFunc(component, name, help, fn)
+}
+
+// NewCounterFunc registers a counter read from fn in the Default registry
+func NewCounterFunc(component, name, help string, fn func() float64) {
+	Default.CounterFunc(component, name, help, fn)
+}
+
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func formatValue(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%d", int64(v))
+	}
+	return fmt.Sprintf("%g", v)
+}
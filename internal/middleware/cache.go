@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+
+	"main.go/internal/auth"
+	"main.go/internal/auth/apikey"
+	"main.go/internal/metrics"
+)
+
+const (
+	// cachePrefix namespaces cached responses in Redis
+	cachePrefix = "httpcache:"
+	// cacheLockTTL bounds how long one request may hold a key's refill lock
+	cacheLockTTL = 10 * time.Second
+	// cacheWait is how long requests for a key being refilled wait for the response
+	// before rendering it themselves
+	cacheWait = 2 * time.Second
+	// cachePoll is how often waiting requests look for the refilled response
+	cachePoll = 50 * time.Millisecond
+	// cacheTimeout bounds each Redis call; the cache fails open
+	cacheTimeout = time.Second
+)
+
+var (
+	cacheHits   = metrics.NewCounter("httpcache", "hits_total", "GET responses served from the response cache")
+	cacheMisses = metrics.NewCounter("httpcache", "misses_total", "GET responses rendered and stored in the response cache")
+	cacheErrors = metrics.NewCounter("httpcache", "errors_total", "Response cache lookups or stores that failed, served uncached")
+)
+
+// cachedResponse is a response as stored in Redis
+type cachedResponse struct {
+	Status int               `json:"s"`
+	Header map[string]string `json:"h"`
+	Body   []byte            `json:"b"`
+}
+
+// uncachedHeaders are response headers that belong to one request, not to the response
+var uncachedHeaders = map[string]bool{
+	fiber.HeaderSetCookie:     true,
+	fiber.HeaderContentLength: true,
+	fiber.HeaderDate:          true,
+	fiber.HeaderConnection:    true,
+	fiber.HeaderXRequestID:    true,
+	"X-Cache":                 true,
+}
+
+// Cache stores successful GET responses in Redis for ttl, keyed by path, query string
+// (in any parameter order) and the request headers named in vary, and answers repeated
+// requests from it with X-Cache: HIT. Attach it to a route or group:
+//
+//	apiV1.Get("/changelog", middleware.Cache(services.Redis, time.Minute, "Accept-Language"), handler)
+//
+// When a response expires, one request renders it while the others for the same key
+// wait for it, so a popular key does not stampede the handler. Requests with an
+// Authorization, Cookie, or X-API-Key header bypass the cache unless that header is in
+// vary, as do requests an authenticator has already identified, so personal responses
+// are never shared; so do responses that set cookies or are marked
+// private or no-store. Without a client (Redis disabled) the middleware does nothing,
+// and Redis errors serve the request uncached.
+func Cache(client *redis.Client, ttl time.Duration, vary ...string) fiber.Handler {
+	if client == nil || ttl <= 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+	varyHeaders := make([]string, len(vary))
+	for i, h := range vary {
+		varyHeaders[i] = http.CanonicalHeaderKey(h)
+	}
+
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet || bypassCache(c, varyHeaders) {
+			return c.Next()
+		}
+		key := cacheKey(c, varyHeaders)
+
+		if hit, err := loadResponse(c.UserContext(), client, key); err != nil {
+			cacheErrors.Inc()
+			return c.Next()
+		} else if hit != nil {
+			return serveCached(c, hit)
+		}
+
+		// Only the request holding the lock refills the key; the rest wait for it
+		lock := cachePrefix + "lock:" + key
+		ctx, cancel := context.WithTimeout(c.UserContext(), cacheTimeout)
+		locked, err := client.SetNX(ctx, lock, 1, cacheLockTTL).Result()
+		cancel()
+		if err != nil {
+			cacheErrors.Inc()
+			return c.Next()
+		}
+		if !locked {
+			if hit := waitForResponse(c.UserContext(), client, key); hit != nil {
+				return serveCached(c, hit)
+			}
+			return c.Next()
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+			defer cancel()
+			client.Del(ctx, lock)
+		}()
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		c.Set("X-Cache", "MISS")
+		if entry, ok := cacheable(c); ok {
+			cacheMisses.Inc()
+			if err := storeResponse(c.UserContext(), client, key, entry, ttl); err != nil {
+				cacheErrors.Inc()
+			}
+		}
+		return nil
+	}
+}
+
+// credentialHeaders carry a caller's identity, in canonical form to match vary
+var credentialHeaders = []string{
+	fiber.HeaderAuthorization,
+	fiber.HeaderCookie,
+	http.CanonicalHeaderKey(apikey.Header),
+}
+
+// bypassCache reports whether the request carries credentials the cache does not vary on,
+// or was authenticated without one it varies on. Bypassed requests neither read nor store
+// the cache nor take a refill lock, so a personal response is never shared.
+func bypassCache(c *fiber.Ctx, vary []string) bool {
+	varied := false
+	for _, h := range credentialHeaders {
+		if c.Get(h) == "" {
+			continue
+		}
+		if !containsHeader(vary, h) {
+			return true
+		}
+		varied = true
+	}
+	_, authenticated := auth.PrincipalFrom(c)
+	return authenticated && !varied
+}
+
+func containsHeader(headers []string, h string) bool {
+	for _, v := range headers {
+		if v == h {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey hashes the path, the sorted query string, and the vary headers
+func cacheKey(c *fiber.Ctx, vary []string) string {
+	var params []string
+	c.Request().URI().QueryArgs().VisitAll(func(k, v []byte) {
+		params = append(params, string(k)+"="+string(v))
+	})
+	sort.Strings(params)
+
+	h := sha256.New()
+	h.Write([]byte(c.Path() + "?" + strings.Join(params, "&")))
+	for _, name := range vary {
+		h.Write([]byte("\n" + name + ":" + c.Get(name)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheable returns the response for storing, unless it must not be shared
+func cacheable(c *fiber.Ctx) (*cachedResponse, bool) {
+	resp := c.Response()
+	if resp.StatusCode() != fiber.StatusOK {
+		return nil, false
+	}
+	setsCookie := false
+	resp.Header.VisitAllCookie(func(_, _ []byte) { setsCookie = true })
+	if setsCookie {
+		return nil, false
+	}
+	control := strings.ToLower(string(resp.Header.Peek(fiber.HeaderCacheControl)))
+	if strings.Contains(control, "no-store") || strings.Contains(control, "private") {
+		return nil, false
+	}
+	entry := &cachedResponse{Status: resp.StatusCode(), Header: map[string]string{}, Body: append([]byte(nil), resp.Body()...)}
+	resp.Header.VisitAll(func(k, v []byte) {
+		if name := http.CanonicalHeaderKey(string(k)); !uncachedHeaders[name] {
+			entry.Header[name] = string(v)
+		}
+	})
+	return entry, true
+}
+
+func loadResponse(ctx context.Context, client *redis.Client, key string) (*cachedResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, cacheTimeout)
+	defer cancel()
+	data, err := client.Get(ctx, cachePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func storeResponse(ctx context.Context, client *redis.Client, key string, entry *cachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cacheTimeout)
+	defer cancel()
+	return client.Set(ctx, cachePrefix+key, data, ttl).Err()
+}
+
+// waitForResponse polls for a key another request is refilling, giving up after cacheWait
+func waitForResponse(ctx context.Context, client *redis.Client, key string) *cachedResponse {
+	deadline := time.Now().Add(cacheWait)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cachePoll):
+		}
+		hit, err := loadResponse(ctx, client, key)
+		if err != nil {
+			cacheErrors.Inc()
+			return nil
+		}
+		if hit != nil {
+			return hit
+		}
+	}
+	return nil
+}
+
+func serveCached(c *fiber.Ctx, entry *cachedResponse) error {
+	cacheHits.Inc()
+	for name, value := range entry.Header {
+		c.Set(name, value)
+	}
+	c.Set("X-Cache", "HIT")
+	return c.Status(entry.Status).Send(entry.Body)
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Trailing slash policies
+const (
+	TrailingSlashStrip  = "strip"
+	TrailingSlashAdd    = "add"
+	TrailingSlashIgnore = "ignore"
+)
+
+// CanonicalOptions configures URL normalization
+type CanonicalOptions struct {
+	// URL is the canonical scheme and host (APP_URL); requests for any other host are
+	// redirected there. Empty leaves hosts alone.
+	URL string
+	// Lowercase redirects paths with uppercase letters to their lowercase form
+	Lowercase bool
+	// TrailingSlash is strip (/about/ -> /about), add (/about -> /about/), or ignore.
+	// Add leaves paths ending in a file name, like /robots.txt, alone.
+	TrailingSlash string
+	// Exclude lists path prefixes whose case and trailing slash are left alone, e.g.
+	// /api/ for clients that sign request paths or /static/ for mixed-case file names
+	Exclude []string
+}
+
+// Canonical returns a middleware that permanently redirects (308) GET and HEAD requests
+// to the canonical form of their URL: the canonical host, then a lowercase path with the
+// configured trailing slash. Everything is fixed in a single redirect and the query
+// string is kept. Other methods pass through untouched, so forms and API calls are never
+// bounced. Register it early, and skip health probes, which arrive on internal hosts.
+func Canonical(opts CanonicalOptions) (fiber.Handler, error) {
+	var host, origin string
+	if opts.URL != "" {
+		u, err := url.Parse(opts.URL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid canonical URL %q", opts.URL)
+		}
+		host = strings.ToLower(u.Host)
+		origin = u.Scheme + "://" + host
+	}
+	switch opts.TrailingSlash {
+	case "":
+		opts.TrailingSlash = TrailingSlashIgnore
+	case TrailingSlashStrip, TrailingSlashAdd, TrailingSlashIgnore:
+	default:
+		return nil, fmt.Errorf("invalid trailing slash policy %q (want strip, add, or ignore)", opts.TrailingSlash)
+	}
+
+	if host == "" && !opts.Lowercase && opts.TrailingSlash == TrailingSlashIgnore {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}, nil
+	}
+
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+
+		current := c.Path()
+		target := current
+		if !hasAnyPrefix(current, opts.Exclude) {
+			target = canonicalPath(current, opts.Lowercase, opts.TrailingSlash)
+		}
+		wrongHost := host != "" && !strings.EqualFold(c.Hostname(), host)
+		if target == current && !wrongHost {
+			return c.Next()
+		}
+
+		location := target
+		if wrongHost {
+			location = origin + target
+		}
+		if query := c.Request().URI().QueryString(); len(query) > 0 {
+			location += "?" + string(query)
+		}
+		return c.Redirect(location, fiber.StatusPermanentRedirect)
+	}, nil
+}
+
+// canonicalPath applies the case and trailing slash rules to p. Leading slashes are
+// collapsed so the result can never become a protocol-relative URL like //evil.example.
+func canonicalPath(p string, lowercase bool, trailingSlash string) string {
+	p = "/" + strings.TrimLeft(p, "/")
+	if lowercase {
+		p = strings.ToLower(p)
+	}
+	switch {
+	case p == "/":
+	case trailingSlash == TrailingSlashStrip:
+		p = "/" + strings.Trim(p, "/")
+	case trailingSlash == TrailingSlashAdd && !strings.HasSuffix(p, "/") && path.Ext(p) == "":
+		p += "/"
+	}
+	return p
+}
+
+func hasAnyPrefix(p string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
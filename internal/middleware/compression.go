@@ -3,6 +3,8 @@ package middleware
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
+
+	"main.go/internal/metrics"
 )
 
 // Compression returns a compression middleware with configurable options
@@ -28,10 +30,25 @@ func Compression(enabled bool, level int) fiber.Handler {
 		},
 	}
 
-	return compress.New(config)
+	return withCompressionMetrics(compress.New(config))
+}
+
+// withCompressionMetrics counts responses and how many of them were compressed
+func withCompressionMetrics(next fiber.Handler) fiber.Handler {
+	responses := metrics.NewCounter("compression", "responses_total", "Responses passed through the compression middleware")
+	compressed := metrics.NewCounter("compression", "compressed_total", "Responses sent with a Content-Encoding")
+
+	return func(c *fiber.Ctx) error {
+		err := next(c)
+		responses.Inc()
+		if len(c.Response().Header.Peek(fiber.HeaderContentEncoding)) > 0 {
+			compressed.Inc()
+		}
+		return err
+	}
 }
 
 // CompressionWithConfig returns a compression middleware with custom configuration
 func CompressionWithConfig(config compress.Config) fiber.Handler {
-	return compress.New(config)
+	return withCompressionMetrics(compress.New(config))
 }
@@ -5,6 +5,18 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
+const (
+	// corsAllowHeaders are the request headers cross-origin clients may send, including
+	// the default tenant header and the range and tus headers of resumable uploads
+	corsAllowHeaders = "Origin, Content-Type, Accept, Authorization, X-Requested-With, X-CSRF-Token, X-Tenant, " +
+		"Content-Range, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata"
+	// corsExposeHeaders are the response headers cross-origin clients may read
+	corsExposeHeaders = "Content-Length, Content-Type, Authorization, Location, " +
+		"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, " +
+		"X-Attachment-Id, X-Operation-Id"
+	corsAllowMethods = "GET, HEAD, POST, PUT, DELETE, OPTIONS, PATCH"
+)
+
 // CORS returns a CORS middleware with configurable options
 func CORS(enabled bool) fiber.Handler {
 	if !enabled {
@@ -17,9 +29,9 @@ func CORS(enabled bool) fiber.Handler {
 	return cors.New(cors.Config{
 		AllowOrigins:     "*",
 		AllowCredentials: false,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Requested-With, X-CSRF-Token",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS, PATCH",
-		ExposeHeaders:    "Content-Length, Content-Type, Authorization",
+		AllowHeaders:     corsAllowHeaders,
+		AllowMethods:     corsAllowMethods,
+		ExposeHeaders:    corsExposeHeaders,
 		MaxAge:           86400, // 24 hours
 		Next: func(c *fiber.Ctx) bool {
 			// Skip CORS for specific routes if needed
@@ -36,15 +48,15 @@ func CORSWithConfig(config cors.Config) fiber.Handler {
 	}
 
 	if len(config.AllowHeaders) == 0 {
-		config.AllowHeaders = "Origin, Content-Type, Accept, Authorization, X-Requested-With, X-CSRF-Token"
+		config.AllowHeaders = corsAllowHeaders
 	}
 
 	if len(config.AllowMethods) == 0 {
-		config.AllowMethods = "GET, POST, PUT, DELETE, OPTIONS, PATCH"
+		config.AllowMethods = corsAllowMethods
 	}
 
 	if len(config.ExposeHeaders) == 0 {
-		config.ExposeHeaders = "Content-Length, Content-Type, Authorization"
+		config.ExposeHeaders = corsExposeHeaders
 	}
 
 	if config.MaxAge == 0 {
@@ -1,14 +1,33 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/csrf"
+
+	"main.go/internal/metrics"
+	"main.go/internal/security"
 	"main.go/internal/utils"
 )
 
-// CSRF returns a CSRF middleware with configurable options
+const (
+	// CSRFContextKey is the c.Locals key holding the current request's CSRF token
+	CSRFContextKey = "csrf"
+	// CSRFFormField is the hidden form field server-rendered forms submit the token in
+	CSRFFormField = "_csrf"
+	// csrfHeaderName is the header JavaScript clients submit the token in
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// CSRFExemptPrefixes lists path prefixes whose callers authenticate every request
+// themselves (HMAC-signed server-to-server routes, signed SAML responses) and therefore
+// skip CSRF checks
+var CSRFExemptPrefixes = []string{"/internal/", "/saml/acs"}
+
+// CSRF returns a CSRF middleware with configurable options. Tokens are kept in process
+// memory, so a token is only accepted by the instance that issued it.
 func CSRF(enabled bool) fiber.Handler {
 	if !enabled {
 		// Return a no-op middleware if CSRF is disabled
@@ -16,14 +35,22 @@ func CSRF(enabled bool) fiber.Handler {
 			return c.Next()
 		}
 	}
+	UsesMemoryStorage("csrf")
+
+	failures := metrics.NewCounter("csrf", "token_failures_total", "Requests rejected for a missing or invalid CSRF token")
 
 	return csrf.New(csrf.Config{
-		KeyLookup:      "header:X-CSRF-Token",
+		Next:           csrfExempt,
+		KeyLookup:      "header:" + csrfHeaderName,
+		Extractor:      csrfFromHeaderOrForm,
+		ContextKey:     CSRFContextKey,
 		CookieName:     "csrf_",
 		CookieSameSite: "Lax",
 		Expiration:     1 * time.Hour,
 		KeyGenerator:   utils.GenerateRandomString,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			failures.Inc()
+			security.Emit(c, security.EventCSRFFailure, map[string]string{"reason": err.Error()})
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error":   "Forbidden",
 				"message": "CSRF token invalid or missing",
@@ -31,3 +58,29 @@ func CSRF(enabled bool) fiber.Handler {
 		},
 	})
 }
+
+// CSRFToken returns the CSRF token for the current request so templates can embed it
+func CSRFToken(c *fiber.Ctx) string {
+	if token, ok := c.Locals(CSRFContextKey).(string); ok {
+		return token
+	}
+	return ""
+}
+
+func csrfExempt(c *fiber.Ctx) bool {
+	for _, prefix := range CSRFExemptPrefixes {
+		if strings.HasPrefix(c.Path(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// csrfFromHeaderOrForm accepts the token from the X-CSRF-Token header (fetch/HTMX)
+// or from the hidden _csrf field (plain HTML forms)
+func csrfFromHeaderOrForm(c *fiber.Ctx) (string, error) {
+	if token := c.Get(csrfHeaderName); token != "" {
+		return token, nil
+	}
+	return csrf.CsrfFromForm(CSRFFormField)(c)
+}
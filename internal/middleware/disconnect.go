@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/metrics"
+)
+
+// StatusClientClosedRequest is recorded for requests whose client went away before the
+// response (nginx's 499); nothing is sent
+const StatusClientClosedRequest = 499
+
+// ErrClientDisconnected is the cause of a request context cancelled by ClientDisconnect
+var ErrClientDisconnected = errors.New("client disconnected")
+
+var clientDisconnects = metrics.NewCounter("http", "client_disconnected_total", "Requests abandoned because the client closed the connection")
+
+// ClientDisconnect cancels the request context (c.UserContext()) when the client closes
+// its connection, so database queries and other work made with it stop instead of
+// finishing for nobody. fasthttp does not report closed connections while a handler
+// runs, so requests still running after interval check the connection, and again every
+// interval; quick requests cost nothing. An abandoned request is counted in
+// http_client_disconnected_total and recorded as 499, and the error it ended with is
+// dropped rather than handled as a failure. context.Cause(ctx) is ErrClientDisconnected.
+func ClientDisconnect(interval time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		conn := c.Context().Conn()
+		if conn == nil || interval <= 0 {
+			return c.Next()
+		}
+		// The context is not cancelled when the handler returns: a streamed body, such as
+		// an object from storage, is read with it after that
+		ctx, cancel := context.WithCancelCause(c.UserContext())
+		c.SetUserContext(ctx)
+
+		var mu sync.Mutex
+		stopped := false
+		var timer *time.Timer
+		check := func() {
+			if peerClosed(conn) {
+				cancel(ErrClientDisconnected)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !stopped {
+				timer.Reset(interval)
+			}
+		}
+		mu.Lock()
+		timer = time.AfterFunc(interval, check)
+		mu.Unlock()
+
+		err := c.Next()
+
+		mu.Lock()
+		stopped = true
+		timer.Stop()
+		mu.Unlock()
+
+		if errors.Is(context.Cause(ctx), ErrClientDisconnected) {
+			clientDisconnects.Inc()
+			c.Status(StatusClientClosedRequest)
+			return nil
+		}
+		return err
+	}
+}
+
+// underlyingConn unwraps TLS connections to the TCP connection beneath
+func underlyingConn(conn net.Conn) net.Conn {
+	if wrapped, ok := conn.(interface{ NetConn() net.Conn }); ok {
+		return wrapped.NetConn()
+	}
+	return conn
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package middleware
+
+import "net"
+
+// peerClosed cannot peek at connections on this platform, so requests run to the end
+func peerClosed(net.Conn) bool {
+	return false
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package middleware
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerClosed peeks at conn without consuming anything: a read of zero bytes means the
+// client closed it. Data waiting to be read (a pipelined request) means it is open.
+func peerClosed(conn net.Conn) bool {
+	sc, ok := underlyingConn(conn).(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+	closed := false
+	var buf [1]byte
+	err = raw.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		closed = (n == 0 && err == nil) || errors.Is(err, syscall.ECONNRESET)
+		// Never wait for the connection to become readable
+		return true
+	})
+	return closed || errors.Is(err, net.ErrClosed)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Envs registers routes meant for some environments only, such as debugging endpoints in
+// development. The routes are always compiled in, so they keep building, but outside
+// their environments they are never registered and answer 404 like any unknown path:
+//
+//	dev := envs.Only(app, "development")
+//	dev.Get("/debug/routes", handler)
+type Envs struct {
+	env string
+
+	mu      sync.Mutex
+	skipped []string
+}
+
+// NewEnvs gates routes for the environment the app runs in (APP_ENV)
+func NewEnvs(env string) *Envs {
+	return &Envs{env: env}
+}
+
+// Only returns r in the listed environments. Elsewhere it returns a router on which
+// routes, middleware, and groups are dropped instead of registered.
+func (e *Envs) Only(r fiber.Router, envs ...string) fiber.Router {
+	if slices.Contains(envs, e.env) {
+		return r
+	}
+	return &skippedRouter{envs: e}
+}
+
+// Skipped lists the routes that were not registered in this environment, as
+// "METHOD /path"
+func (e *Envs) Skipped() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.skipped)
+}
+
+func (e *Envs) skip(method, path string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.skipped = append(e.skipped, method+" "+path)
+}
+
+// skippedRouter is a fiber.Router that registers nothing; it remembers the group prefix
+// so skipped routes are listed under their full path
+type skippedRouter struct {
+	envs   *Envs
+	prefix string
+}
+
+var _ fiber.Router = (*skippedRouter)(nil)
+
+func (r *skippedRouter) path(path string) string {
+	if r.prefix == "" {
+		return path
+	}
+	return strings.TrimSuffix(r.prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (r *skippedRouter) add(method, path string) fiber.Router {
+	r.envs.skip(method, r.path(path))
+	return r
+}
+
+func (r *skippedRouter) Use(args ...interface{}) fiber.Router {
+	prefix := "/"
+	if len(args) > 0 {
+		if p, ok := args[0].(string); ok {
+			prefix = p
+		}
+	}
+	return r.add("USE", prefix)
+}
+
+func (r *skippedRouter) Get(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodGet, path)
+}
+
+func (r *skippedRouter) Head(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodHead, path)
+}
+
+func (r *skippedRouter) Post(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodPost, path)
+}
+
+func (r *skippedRouter) Put(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodPut, path)
+}
+
+func (r *skippedRouter) Delete(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodDelete, path)
+}
+
+func (r *skippedRouter) Connect(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodConnect, path)
+}
+
+func (r *skippedRouter) Options(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodOptions, path)
+}
+
+func (r *skippedRouter) Trace(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodTrace, path)
+}
+
+func (r *skippedRouter) Patch(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(fiber.MethodPatch, path)
+}
+
+func (r *skippedRouter) Add(method, path string, _ ...fiber.Handler) fiber.Router {
+	return r.add(method, path)
+}
+
+func (r *skippedRouter) Static(prefix, _ string, _ ...fiber.Static) fiber.Router {
+	return r.add(fiber.MethodGet, prefix)
+}
+
+func (r *skippedRouter) All(path string, _ ...fiber.Handler) fiber.Router {
+	return r.add("ALL", path)
+}
+
+func (r *skippedRouter) Group(prefix string, _ ...fiber.Handler) fiber.Router {
+	return &skippedRouter{envs: r.envs, prefix: r.path(prefix)}
+}
+
+// Route calls fn with a skipped group, so the routes it declares are listed as skipped
+func (r *skippedRouter) Route(prefix string, fn func(router fiber.Router), _ ...string) fiber.Router {
+	group := r.Group(prefix)
+	fn(group)
+	return group
+}
+
+func (r *skippedRouter) Mount(prefix string, _ *fiber.App) fiber.Router {
+	return r.add("MOUNT", prefix)
+}
+
+func (r *skippedRouter) Name(string) fiber.Router {
+	return r
+}
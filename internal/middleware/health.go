@@ -1,61 +1,65 @@
 package middleware
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-)
 
-// HealthCheck returns a health check middleware
-func HealthCheck(db interface{}) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		start := time.Now()
-
-		// Basic health check
-		health := fiber.Map{
-			"status":    "ok",
-			"timestamp": time.Now().UTC(),
-			"uptime":    time.Since(start),
-		}
+	"main.go/internal/database"
+)
 
-		// Check database if provided
-		if db != nil {
-			// Database connection check would go here
-			// For now, we'll just add a database status
-			health["database"] = "connected"
-		}
+// startedAt is when the process started, for the uptime reported by health checks
+var startedAt = time.Now()
 
-		// Add more health checks as needed (Redis, external APIs, etc.)
+// HealthCheck returns a health check middleware. It pings db when one is given and
+// responds 503 when the ping fails.
+func HealthCheck(db *database.DB) fiber.Handler {
+	return healthCheck(db, false)
+}
 
-		return c.JSON(health)
-	}
+// HealthCheckWithDB returns a health check middleware that requires the database: it
+// pings db and responds 503 when the ping fails or db is nil
+func HealthCheckWithDB(db *database.DB) fiber.Handler {
+	return healthCheck(db, true)
 }
 
-// HealthCheckWithDB returns a health check middleware that checks database connectivity
-func HealthCheckWithDB(db interface{}) fiber.Handler {
+func healthCheck(db *database.DB, required bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		start := time.Now()
-
 		health := fiber.Map{
 			"status":    "ok",
 			"timestamp": time.Now().UTC(),
-			"uptime":    time.Since(start),
+			"uptime":    time.Since(startedAt).Round(time.Second).String(),
 		}
 
-		// Check database connectivity
-		if db != nil {
-			// Perform actual database health check
-			// This would be your actual database health check
-			// For example: err := dbConn.HealthCheck(ctx)
-			// if err != nil {
-			//     health["database"] = "disconnected"
-			//     health["status"] = "degraded"
-			// } else {
-			//     health["database"] = "connected"
-			// }
-			health["database"] = "connected" // Placeholder
+		if db == nil {
+			if required {
+				health["status"] = "degraded"
+				health["database"] = fiber.Map{"status": "not connected"}
+				return c.Status(http.StatusServiceUnavailable).JSON(health)
+			}
+			return c.JSON(health)
 		}
 
+		if !db.Connected() {
+			health["status"] = "degraded"
+			health["database"] = fiber.Map{"status": "connecting"}
+			return c.Status(http.StatusServiceUnavailable).JSON(health)
+		}
+
+		start := time.Now()
+		err := db.HealthCheck(c.UserContext())
+		database := fiber.Map{
+			"status":     "connected",
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"pool":       db.PoolStats(),
+		}
+		health["database"] = database
+		if err != nil {
+			database["status"] = "unreachable"
+			health["status"] = "degraded"
+			return c.Status(http.StatusServiceUnavailable).JSON(health)
+		}
 		return c.JSON(health)
 	}
 }
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+
+	"main.go/internal/metrics"
+	"main.go/internal/security"
+)
+
+// RateLimiter returns a sliding-window limiter allowing max requests per window per IP.
+// It registers limiter_requests_total and limiter_rejections_total metrics. Counters are
+// kept in storage under name, so every instance sharing it (Redis) enforces one limit;
+// with a nil storage they are kept in process memory and each instance limits on its own.
+func RateLimiter(name string, max int, window time.Duration, storage fiber.Storage) fiber.Handler {
+	if storage == nil {
+		UsesMemoryStorage("rate limiter")
+	}
+	requests := metrics.NewCounter("limiter", "requests_total", "Requests checked by the rate limiter")
+	rejections := metrics.NewCounter("limiter", "rejections_total", "Requests rejected with 429 by the rate limiter")
+
+	limit := limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		// Limiters sharing a storage count separately
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return name + ":" + c.IP()
+		},
+		Storage:           storage,
+		LimiterMiddleware: limiter.SlidingWindow{},
+		LimitReached: func(c *fiber.Ctx) error {
+			rejections.Inc()
+			security.Emit(c, security.EventRateLimited, nil)
+			return c.SendStatus(fiber.StatusTooManyRequests)
+		},
+	})
+
+	return func(c *fiber.Ctx) error {
+		requests.Inc()
+		return limit(c)
+	}
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gofiber/fiber/v2"
+)
+
+// precompressMinSize skips files too small for compression to pay off
+const precompressMinSize = 256
+
+// precompressedEncodings in order of preference, with the file suffix a build step
+// may already have produced
+var precompressedEncodings = []struct {
+	name   string
+	suffix string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// precompressedAsset is one static file with its compressed variants
+type precompressedAsset struct {
+	contentType  string
+	etag         string
+	lastModified string
+	variants     map[string][]byte
+}
+
+// PrecompressedStatic serves brotli and gzip variants of the compressible files under root,
+// at prefix, to clients that accept them. Variants are built once at startup: a ".br" or
+// ".gz" file next to the original (from a build step) is used as is, otherwise the file is
+// compressed in memory. Requests for other files, or from clients that accept neither
+// encoding, fall through to the static handler registered after it. Every response for a
+// compressible file carries "Vary: Accept-Encoding" so CDNs and proxies cache each
+// encoding separately. Changes on disk after startup are not picked up.
+func PrecompressedStatic(prefix, root string) (fiber.Handler, error) {
+	assets := map[string]*precompressedAsset{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !compressible(path) {
+			return err
+		}
+		asset, err := loadPrecompressed(path)
+		if err != nil || asset == nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		assets[strings.TrimRight(prefix, "/")+"/"+filepath.ToSlash(rel)] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+		asset, ok := assets[c.Path()]
+		if !ok {
+			return c.Next()
+		}
+
+		c.Vary(fiber.HeaderAcceptEncoding)
+		encoding := negotiateEncoding(c.Get(fiber.HeaderAcceptEncoding), asset.variants)
+		if encoding == "" {
+			return c.Next()
+		}
+
+		etag := `"` + asset.etag + "-" + encoding + `"`
+		c.Set(fiber.HeaderETag, etag)
+		c.Set(fiber.HeaderLastModified, asset.lastModified)
+		if match := c.Get(fiber.HeaderIfNoneMatch); match != "" && strings.Contains(match, etag) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		c.Set(fiber.HeaderContentType, asset.contentType)
+		c.Set(fiber.HeaderContentEncoding, encoding)
+		return c.Send(asset.variants[encoding])
+	}, nil
+}
+
+// loadPrecompressed reads a file and its variants; nil when no variant is smaller
+func loadPrecompressed(path string) (*precompressedAsset, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	original, err := os.ReadFile(path)
+	if err != nil || len(original) < precompressMinSize {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(original)
+	asset := &precompressedAsset{
+		contentType:  contentType(path),
+		etag:         hex.EncodeToString(sum[:8]),
+		lastModified: info.ModTime().UTC().Format(http.TimeFormat),
+		variants:     map[string][]byte{},
+	}
+	for _, enc := range precompressedEncodings {
+		variant, err := buildVariant(path, info.ModTime(), enc.name, enc.suffix, original)
+		if err != nil {
+			return nil, err
+		}
+		if len(variant) < len(original) {
+			asset.variants[enc.name] = variant
+		}
+	}
+	if len(asset.variants) == 0 {
+		return nil, nil
+	}
+	return asset, nil
+}
+
+// buildVariant prefers a variant file at least as new as the original over compressing it
+func buildVariant(path string, modTime time.Time, encoding, suffix string, original []byte) ([]byte, error) {
+	if info, err := os.Stat(path + suffix); err == nil && !info.ModTime().Before(modTime) {
+		return os.ReadFile(path + suffix)
+	}
+
+	var buf bytes.Buffer
+	switch encoding {
+	case "br":
+		w := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+		if _, err := w.Write(original); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case "gzip":
+		w, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if _, err := w.Write(original); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// negotiateEncoding picks the preferred available encoding the client accepts,
+// honoring q=0 exclusions and the "*" wildcard
+func negotiateEncoding(header string, variants map[string][]byte) string {
+	accepted := map[string]bool{}
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q > 0
+			continue
+		}
+		accepted[name] = q > 0
+	}
+
+	for _, enc := range precompressedEncodings {
+		ok, listed := accepted[enc.name]
+		if _, available := variants[enc.name]; available && (ok || (!listed && wildcard)) {
+			return enc.name
+		}
+	}
+	return ""
+}
+
+// compressible reports whether a file is text-like; images, fonts, and archives are
+// already compressed
+func compressible(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".br" || ext == ".gz" {
+		return false
+	}
+	switch ext {
+	case ".js", ".mjs", ".css", ".html", ".htm", ".json", ".map", ".svg", ".xml", ".txt", ".wasm", ".ico":
+		return true
+	}
+	return strings.HasPrefix(mime.TypeByExtension(ext), "text/")
+}
+
+func contentType(path string) string {
+	if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+		return ct
+	}
+	return fiber.MIMEOctetStream
+}
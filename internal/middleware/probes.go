@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProbeRoutePrefix marks a route as a health probe through its name:
+//
+//	app.Get("/live", healthHandler.Live).Name(middleware.ProbeRoutePrefix + "live")
+const ProbeRoutePrefix = "probe."
+
+// Probes recognizes requests to routes named as probes, so Kubernetes and load balancer
+// checks can be micro-cached and kept out of rate limiting, WAF inspection, and metrics
+type Probes struct {
+	app   *fiber.App
+	once  sync.Once
+	paths map[string]bool
+}
+
+// NewProbes creates a probe matcher for app's routes
+func NewProbes(app *fiber.App) *Probes {
+	return &Probes{app: app}
+}
+
+// Is reports whether the request targets a probe route. Routes are read on the first
+// request, once all of them are registered.
+func (p *Probes) Is(c *fiber.Ctx) bool {
+	p.once.Do(func() {
+		p.paths = map[string]bool{}
+		for _, route := range p.app.GetRoutes(true) {
+			if strings.HasPrefix(route.Name, ProbeRoutePrefix) {
+				p.paths[route.Path] = true
+			}
+		}
+	})
+	return p.paths[c.Path()]
+}
+
+// probeEntry is the last response of one probe route
+type probeEntry struct {
+	mu          sync.Mutex
+	expires     time.Time
+	status      int
+	body        []byte
+	contentType string
+}
+
+// MicroCache answers probe requests from a response cached for ttl (a few hundred
+// milliseconds). Concurrent requests during a probe storm wait for the one in flight
+// instead of each running the checks. Errors are not cached; ttl <= 0 disables it.
+func (p *Probes) MicroCache(ttl time.Duration) fiber.Handler {
+	if ttl <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	var mu sync.Mutex
+	entries := map[string]*probeEntry{}
+
+	return func(c *fiber.Ctx) error {
+		if (c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead) || !p.Is(c) {
+			return c.Next()
+		}
+
+		mu.Lock()
+		entry, ok := entries[c.Path()]
+		if !ok {
+			entry = &probeEntry{}
+			entries[c.Path()] = entry
+		}
+		mu.Unlock()
+
+		entry.mu.Lock()
+		defer entry.mu.Unlock()
+
+		now := time.Now()
+		if now.Before(entry.expires) {
+			c.Set(fiber.HeaderContentType, entry.contentType)
+			return c.Status(entry.status).Send(entry.body)
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		entry.status = c.Response().StatusCode()
+		entry.body = append([]byte(nil), c.Response().Body()...)
+		entry.contentType = string(c.Response().Header.ContentType())
+		entry.expires = now.Add(ttl)
+		return nil
+	}
+}
+
+// Unless skips handler for requests matching skip, e.g. Unless(probes.Is, RateLimiter(...))
+func Unless(skip func(*fiber.Ctx) bool, handler fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip(c) {
+			return c.Next()
+		}
+		return handler(c)
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderRobotsTag carries indexing directives for any response type, not just HTML
+const HeaderRobotsTag = "X-Robots-Tag"
+
+// NoIndex adds "X-Robots-Tag: noindex, nofollow" to every response so staging and
+// development deployments stay out of search engines. Register it early so responses
+// from later middleware (static files, icons, errors) carry the header too.
+func NoIndex(enabled bool) fiber.Handler {
+	if !enabled {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set(HeaderRobotsTag, "noindex, nofollow")
+		return c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"slices"
+	"sync"
+)
+
+// memoryState lists the middleware keeping their state (rate limit counters, CSRF tokens,
+// sessions) in process memory. Behind a load balancer, or with prefork, each instance
+// then enforces its own limits and knows only its own tokens and sessions.
+var memoryState struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// UsesMemoryStorage records that the named middleware keeps its state in process memory
+func UsesMemoryStorage(name string) {
+	memoryState.mu.Lock()
+	defer memoryState.mu.Unlock()
+	if !slices.Contains(memoryState.names, name) {
+		memoryState.names = append(memoryState.names, name)
+	}
+}
+
+// MemoryStorage returns the middleware recorded by UsesMemoryStorage
+func MemoryStorage() []string {
+	memoryState.mu.Lock()
+	defer memoryState.mu.Unlock()
+	return slices.Clone(memoryState.names)
+}
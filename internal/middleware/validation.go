@@ -8,6 +8,8 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	"main.go/internal/bodyparser"
+	"main.go/internal/utils"
 	"main.go/internal/validation"
 )
 
@@ -23,16 +25,15 @@ func NewValidationMiddleware() *ValidationMiddleware {
 	}
 }
 
-// ValidateBody validates the request body against a struct
+// ValidateBody validates the request body against a struct. model is a pointer to the
+// struct type; every request is parsed into a fresh value of that type.
 func (vm *ValidationMiddleware) ValidateBody(model interface{}) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		model := newModel(model)
+
 		// Parse request body
-		if err := c.BodyParser(model); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "Invalid request body",
-				"message": "Failed to parse request body",
-				"details": err.Error(),
-			})
+		if err := bodyparser.Parse(c, model); err != nil {
+			return utils.InvalidBody(c, err)
 		}
 
 		// Validate the struct
@@ -53,6 +54,8 @@ func (vm *ValidationMiddleware) ValidateBody(model interface{}) fiber.Handler {
 // ValidateQuery validates query parameters against a struct
 func (vm *ValidationMiddleware) ValidateQuery(model interface{}) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		model := newModel(model)
+
 		// Parse query parameters
 		if err := c.QueryParser(model); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -80,6 +83,8 @@ func (vm *ValidationMiddleware) ValidateQuery(model interface{}) fiber.Handler {
 // ValidateParams validates route parameters against a struct
 func (vm *ValidationMiddleware) ValidateParams(model interface{}) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		model := newModel(model)
+
 		// Parse route parameters
 		if err := c.ParamsParser(model); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -107,6 +112,8 @@ func (vm *ValidationMiddleware) ValidateParams(model interface{}) fiber.Handler
 // ValidateHeaders validates request headers against a struct
 func (vm *ValidationMiddleware) ValidateHeaders(model interface{}) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		model := newModel(model)
+
 		// Get all headers
 		headers := c.GetReqHeaders()
 
@@ -164,6 +171,16 @@ func (vm *ValidationMiddleware) ValidateCustom(validatorFunc func(*fiber.Ctx) er
 	}
 }
 
+// newModel allocates a zero value of the struct model points to, so concurrent requests
+// never share (or see leftovers in) the value registered with the route
+func newModel(model interface{}) interface{} {
+	t := reflect.TypeOf(model)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return model
+	}
+	return reflect.New(t.Elem()).Interface()
+}
+
 // formatValidationErrors formats validation errors consistently
 func (vm *ValidationMiddleware) formatValidationErrors(err error) map[string]string {
 	if validationErrors, ok := err.(*validation.ValidationErrors); ok {
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/metrics"
+	"main.go/internal/security"
+)
+
+// WAF modes
+const (
+	WAFModeOff   = "off"
+	WAFModeLog   = "log"
+	WAFModeBlock = "block"
+)
+
+// WAFOptions configures the request inspection middleware
+type WAFOptions struct {
+	// Mode is off, log (record matches and continue), or block (reject matches with 403)
+	Mode string
+	// MaxHeaderBytes is the largest combined size of request header names and values.
+	// Fiber already answers 431 once headers exceed its 4KB read buffer, so keep this below that.
+	MaxHeaderBytes int
+	// MaxBodyBytes caps how much of a form or JSON body is inspected
+	MaxBodyBytes int
+}
+
+// wafRule is a heuristic applied to the decoded query string and body
+type wafRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var wafPayloadRules = []wafRule{
+	{name: "sqli_union", pattern: regexp.MustCompile(`(?i)\bunion\b(\s|/\*.*?\*/)+(all\s+)?select\b`)},
+	{name: "sqli_tautology", pattern: regexp.MustCompile(`(?i)['"]\s*(or|and)\s+['"]?\w+['"]?\s*(=|like)\s*['"]?\w+`)},
+	{name: "sqli_stacked", pattern: regexp.MustCompile(`(?i);\s*(drop|delete|insert|update|alter|truncate|exec)\s`)},
+	{name: "sqli_timing", pattern: regexp.MustCompile(`(?i)\b(sleep|benchmark|pg_sleep)\s*\(|\bwaitfor\s+delay\b`)},
+	{name: "sqli_schema", pattern: regexp.MustCompile(`(?i)\b(information_schema|pg_catalog|sysobjects)\b`)},
+	{name: "xss_script", pattern: regexp.MustCompile(`(?i)<\s*/?\s*script\b`)},
+	{name: "xss_handler", pattern: regexp.MustCompile(`(?i)<[^>]*\bon[a-z]+\s*=`)},
+	{name: "xss_uri", pattern: regexp.MustCompile(`(?i)\b(javascript|vbscript)\s*:|data\s*:\s*text/html`)},
+	{name: "xss_embed", pattern: regexp.MustCompile(`(?i)<\s*(iframe|object|embed|svg|math)\b`)},
+}
+
+// scannerUserAgent matches well-known vulnerability scanners and attack tools
+var scannerUserAgent = regexp.MustCompile(`(?i)\b(sqlmap|nikto|nmap|masscan|acunetix|wpscan|zgrab|dirbuster|gobuster|nuclei|havij|w3af|fimap|hydra|netsparker|openvas)\b`)
+
+// WAF returns a lightweight pattern-based request inspector for deployments without an
+// upstream WAF. It flags SQL injection and XSS heuristics in the query string and body,
+// scanner user agents, and oversized headers. Matches are recorded as security events and
+// counted per rule as waf_<rule>_total; block mode also rejects the request with 403.
+// These are heuristics: expect false positives on free-text fields and run in log mode first.
+func WAF(opts WAFOptions) fiber.Handler {
+	mode := strings.ToLower(opts.Mode)
+	if mode != WAFModeLog && mode != WAFModeBlock {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	if opts.MaxHeaderBytes <= 0 {
+		opts.MaxHeaderBytes = 3072
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 64 * 1024
+	}
+
+	inspected := metrics.NewCounter("waf", "requests_total", "Requests inspected by the WAF")
+	blocked := metrics.NewCounter("waf", "blocked_total", "Requests rejected with 403 by the WAF")
+	ruleCounters := map[string]*metrics.Counter{}
+	for _, name := range wafRuleNames() {
+		ruleCounters[name] = metrics.NewCounter("waf", name+"_total", "Requests matching WAF rule "+name)
+	}
+
+	return func(c *fiber.Ctx) error {
+		inspected.Inc()
+
+		matches := inspectRequest(c, opts)
+		if len(matches) == 0 {
+			return c.Next()
+		}
+
+		for _, m := range matches {
+			ruleCounters[m.rule].Inc()
+			security.Emit(c, security.EventWAFMatch, map[string]string{
+				"rule":     m.rule,
+				"location": m.location,
+				"mode":     mode,
+			})
+		}
+
+		if mode != WAFModeBlock {
+			return c.Next()
+		}
+		blocked.Inc()
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "Forbidden",
+			"message": "Request blocked",
+			"status":  fiber.StatusForbidden,
+		})
+	}
+}
+
+type wafMatch struct {
+	rule     string
+	location string
+}
+
+func inspectRequest(c *fiber.Ctx, opts WAFOptions) []wafMatch {
+	var matches []wafMatch
+
+	headerBytes := 0
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		headerBytes += len(key) + len(value)
+	})
+	if headerBytes > opts.MaxHeaderBytes {
+		matches = append(matches, wafMatch{rule: "oversized_headers", location: "headers:" + strconv.Itoa(headerBytes)})
+	}
+
+	if scannerUserAgent.MatchString(c.Get(fiber.HeaderUserAgent)) {
+		matches = append(matches, wafMatch{rule: "scanner_user_agent", location: "user-agent"})
+	}
+
+	targets := map[string]string{
+		"path":  decodeForInspection(c.Path()),
+		"query": decodeForInspection(string(c.Request().URI().QueryString())),
+	}
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+	if strings.HasPrefix(contentType, fiber.MIMEApplicationForm) || strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+		body := c.Body()
+		if len(body) > opts.MaxBodyBytes {
+			body = body[:opts.MaxBodyBytes]
+		}
+		targets["body"] = decodeForInspection(string(body))
+	}
+
+	for _, rule := range wafPayloadRules {
+		for _, location := range []string{"path", "query", "body"} {
+			if value := targets[location]; value != "" && rule.pattern.MatchString(value) {
+				matches = append(matches, wafMatch{rule: rule.name, location: location})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// decodeForInspection undoes URL encoding (twice, to catch double-encoded payloads)
+// so patterns see what the application will see
+func decodeForInspection(value string) string {
+	for i := 0; i < 2 && strings.ContainsAny(value, "%+"); i++ {
+		decoded, err := url.QueryUnescape(value)
+		if err != nil {
+			break
+		}
+		value = decoded
+	}
+	return value
+}
+
+func wafRuleNames() []string {
+	names := []string{"oversized_headers", "scanner_user_agent"}
+	for _, rule := range wafPayloadRules {
+		names = append(names, rule.name)
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Akismet checks content with the Akismet API (or a compatible service) and reports
+// review decisions back to it
+type Akismet struct {
+	key string
+	// site is the "blog" parameter: the app's front page URL, registered with the key
+	site    string
+	baseURL string
+	client  *http.Client
+}
+
+// NewAkismet creates an Akismet classifier; baseURL defaults to the Akismet REST API and
+// can point at a compatible service
+func NewAkismet(key, site, baseURL string) *Akismet {
+	if baseURL == "" {
+		baseURL = "https://rest.akismet.com"
+	}
+	return &Akismet{key: key, site: site, baseURL: strings.TrimRight(baseURL, "/"), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name implements Classifier
+func (a *Akismet) Name() string {
+	return "akismet"
+}
+
+// Check implements Classifier
+func (a *Akismet) Check(ctx context.Context, content Content) (Verdict, error) {
+	resp, body, err := a.post(ctx, "/1.1/comment-check", content)
+	if err != nil {
+		return Verdict{}, err
+	}
+	switch body {
+	case "true":
+		verdict := Verdict{Spam: true, Score: 1, Reason: "akismet: spam"}
+		// Blatant spam can be discarded without review; it is still quarantined here
+		if resp.Header.Get("X-akismet-pro-tip") == "discard" {
+			verdict.Reason = "akismet: blatant spam"
+		}
+		return verdict, nil
+	case "false":
+		return Verdict{Score: 0}, nil
+	}
+	return Verdict{}, fmt.Errorf("akismet: unexpected response %q (%s)", body, resp.Header.Get("X-akismet-debug-help"))
+}
+
+// ReportSpam implements Trainer
+func (a *Akismet) ReportSpam(ctx context.Context, content Content) error {
+	_, _, err := a.post(ctx, "/1.1/submit-spam", content)
+	return err
+}
+
+// ReportHam implements Trainer
+func (a *Akismet) ReportHam(ctx context.Context, content Content) error {
+	_, _, err := a.post(ctx, "/1.1/submit-ham", content)
+	return err
+}
+
+func (a *Akismet) post(ctx context.Context, path string, content Content) (*http.Response, string, error) {
+	form := url.Values{
+		"api_key":              {a.key},
+		"blog":                 {a.site},
+		"user_ip":              {content.IP},
+		"user_agent":           {content.UserAgent},
+		"referrer":             {content.Referrer},
+		"comment_type":         {akismetType(content.Kind)},
+		"comment_author":       {content.AuthorName},
+		"comment_author_email": {content.AuthorEmail},
+		"comment_content":      {content.Text},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("akismet: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return nil, "", fmt.Errorf("akismet: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("akismet: status %d", resp.StatusCode)
+	}
+	return resp, strings.TrimSpace(string(body)), nil
+}
+
+// akismetType maps content kinds to Akismet's comment_type values
+func akismetType(kind string) string {
+	switch kind {
+	case KindComment:
+		return "comment"
+	case KindProfile:
+		return "signup"
+	}
+	return kind
+}
@@ -0,0 +1,102 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// linkPattern matches URLs and bare domains with common spam TLDs
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(com|net|ru|xyz|top|click|info|biz)\b`)
+
+// DefaultBannedTerms are phrases common in spam and rare elsewhere
+var DefaultBannedTerms = []string{
+	"viagra", "cialis", "casino", "payday loan", "crypto giveaway", "forex signals",
+	"work from home", "click here", "buy followers", "free money",
+}
+
+// Heuristic scores content with simple signals (links, banned terms, shouting, repeated
+// characters) and needs no external service. It catches unsophisticated spam; use
+// Akismet for more.
+type Heuristic struct {
+	// Threshold is the score from which content is spam; defaults to 0.6
+	Threshold float64
+	// BannedTerms are matched case-insensitively; defaults to DefaultBannedTerms
+	BannedTerms []string
+}
+
+// Name implements Classifier
+func (h *Heuristic) Name() string {
+	return "heuristic"
+}
+
+// Check implements Classifier
+func (h *Heuristic) Check(ctx context.Context, content Content) (Verdict, error) {
+	threshold := h.Threshold
+	if threshold <= 0 {
+		threshold = 0.6
+	}
+	terms := h.BannedTerms
+	if terms == nil {
+		terms = DefaultBannedTerms
+	}
+
+	text := content.Text
+	lower := strings.ToLower(text)
+	score := 0.0
+	var reasons []string
+
+	if links := len(linkPattern.FindAllStringIndex(text, -1)); links > 0 {
+		score += 0.2 * float64(min(links, 4))
+		reasons = append(reasons, fmt.Sprintf("%d links", links))
+	}
+	for _, term := range terms {
+		if term != "" && strings.Contains(lower, strings.ToLower(term)) {
+			score += 0.5
+			reasons = append(reasons, fmt.Sprintf("banned term %q", term))
+		}
+	}
+	if shouting(text) {
+		score += 0.2
+		reasons = append(reasons, "mostly uppercase")
+	}
+	if repeatedRun(text) >= 6 {
+		score += 0.2
+		reasons = append(reasons, "repeated characters")
+	}
+
+	score = min(score, 1)
+	return Verdict{Spam: score >= threshold, Score: score, Reason: strings.Join(reasons, ", ")}, nil
+}
+
+// shouting reports whether most letters of a longer text are uppercase
+func shouting(text string) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= 20 && upper*10 >= letters*7
+}
+
+// repeatedRun returns the longest run of one repeated non-space character
+func repeatedRun(text string) int {
+	longest, run := 0, 0
+	var prev rune
+	for _, r := range text {
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		longest = max(longest, run)
+	}
+	return longest
+}
@@ -0,0 +1,229 @@
+// Package moderation screens user-generated content for spam. Submissions are queued and
+// checked in the background by a Classifier (the built-in heuristic scorer or Akismet),
+// so a slow or unavailable spam service never delays the request. Content classified as
+// spam is quarantined: features hide it while Quarantined reports true, and admins
+// approve or reject it through the review endpoints. Review decisions are reported back
+// to classifiers that learn from them.
+package moderation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+// Content kinds
+const (
+	KindProfile = "profile"
+	KindComment = "comment"
+)
+
+// ManageScope lets admins review quarantined content
+const ManageScope = "moderation:write"
+
+// checkTimeout bounds a single classification
+const checkTimeout = 15 * time.Second
+
+var (
+	checked     = metrics.NewCounter("moderation", "checked_total", "Submissions checked for spam")
+	quarantined = metrics.NewCounter("moderation", "quarantined_total", "Submissions quarantined as spam")
+	failures    = metrics.NewCounter("moderation", "failures_total", "Spam checks that failed")
+	dropped     = metrics.NewCounter("moderation", "dropped_total", "Submissions skipped because the check queue was full")
+)
+
+// Content is one submission to screen
+type Content struct {
+	// Kind and SubjectID identify the content, e.g. KindProfile and the user's ID
+	Kind      string
+	SubjectID string
+	AuthorID  string
+	// AuthorName and AuthorEmail help classifiers that track known spammers
+	AuthorName  string
+	AuthorEmail string
+	Text        string
+	IP          string
+	UserAgent   string
+	Referrer    string
+}
+
+// FromRequest fills in the request details classifiers use
+func FromRequest(c *fiber.Ctx, content Content) Content {
+	content.IP = c.IP()
+	content.UserAgent = c.Get(fiber.HeaderUserAgent)
+	content.Referrer = c.Get(fiber.HeaderReferer)
+	return content
+}
+
+// Verdict is a classifier's decision
+type Verdict struct {
+	Spam bool
+	// Score is the spam likelihood from 0 to 1; classifiers with yes/no answers use 0 or 1
+	Score  float64
+	Reason string
+}
+
+// Classifier decides whether content is spam
+type Classifier interface {
+	// Name identifies the classifier in logs and review records
+	Name() string
+	Check(ctx context.Context, content Content) (Verdict, error)
+}
+
+// Trainer is implemented by classifiers that learn from review decisions
+type Trainer interface {
+	ReportSpam(ctx context.Context, content Content) error
+	ReportHam(ctx context.Context, content Content) error
+}
+
+// Moderator queues submissions and quarantines the ones classified as spam
+type Moderator struct {
+	classifier Classifier
+	store      *Store
+	clock      clock.Clock
+	logger     *logger.Logger
+	queue      chan Content
+	stop       chan struct{}
+	once       sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewModerator creates a moderator; queueSize bounds the submissions waiting for a check
+func NewModerator(classifier Classifier, store *Store, queueSize int, clk clock.Clock, l *logger.Logger) *Moderator {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+	return &Moderator{
+		classifier: classifier,
+		store:      store,
+		clock:      clk,
+		logger:     l,
+		queue:      make(chan Content, queueSize),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Store returns the moderator's store
+func (m *Moderator) Store() *Store {
+	return m.store
+}
+
+// Classifier returns the configured classifier
+func (m *Moderator) Classifier() Classifier {
+	return m.classifier
+}
+
+// Submit queues content for a background check. It never blocks: when the queue is full
+// the submission is let through unchecked and counted.
+func (m *Moderator) Submit(content Content) {
+	select {
+	case m.queue <- content:
+	default:
+		dropped.Inc()
+		m.logger.Warn("Moderation queue full; content not checked",
+			zap.String("kind", content.Kind), zap.String("subject_id", content.SubjectID))
+	}
+}
+
+// Check classifies content now and quarantines it if it is spam
+func (m *Moderator) Check(ctx context.Context, content Content) (Verdict, error) {
+	verdict, err := m.classifier.Check(ctx, content)
+	if err != nil {
+		failures.Inc()
+		return verdict, err
+	}
+	checked.Inc()
+	if !verdict.Spam {
+		return verdict, nil
+	}
+	if _, err := m.store.Quarantine(ctx, content, m.classifier.Name(), verdict); err != nil {
+		return verdict, err
+	}
+	quarantined.Inc()
+	m.logger.Info("Content quarantined as spam",
+		zap.String("kind", content.Kind),
+		zap.String("subject_id", content.SubjectID),
+		zap.Float64("score", verdict.Score),
+		zap.String("reason", verdict.Reason))
+	return verdict, nil
+}
+
+// Review records an admin's decision on a quarantined item and reports it to classifiers
+// that learn from it; approve means the content is not spam
+func (m *Moderator) Review(ctx context.Context, id string, approve bool, reviewer string) (*Item, error) {
+	status := StatusRejected
+	if approve {
+		status = StatusApproved
+	}
+	item, err := m.store.Resolve(ctx, id, status, reviewer, m.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if trainer, ok := m.classifier.(Trainer); ok {
+		content := item.Content()
+		safego.GoNamed("moderation-feedback", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+			defer cancel()
+			report := trainer.ReportSpam
+			if approve {
+				report = trainer.ReportHam
+			}
+			if err := report(ctx, content); err != nil {
+				m.logger.Warn("Failed to report review decision to classifier",
+					zap.String("classifier", m.classifier.Name()), zap.Error(err))
+			}
+		})
+	}
+	return item, nil
+}
+
+// Quarantined reports whether the content is held for review or was rejected as spam
+func (m *Moderator) Quarantined(ctx context.Context, kind, subjectID string) (bool, error) {
+	return m.store.Quarantined(ctx, kind, subjectID)
+}
+
+// Start runs workers that check queued submissions until Stop
+func (m *Moderator) Start(workers int) {
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		safego.GoNamed("moderation-worker", func() {
+			defer m.wg.Done()
+			for {
+				select {
+				case <-m.stop:
+					return
+				case content := <-m.queue:
+					ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+					if _, err := m.Check(ctx, content); err != nil {
+						m.logger.Warn("Spam check failed; content let through",
+							zap.String("classifier", m.classifier.Name()),
+							zap.String("kind", content.Kind),
+							zap.String("subject_id", content.SubjectID),
+							zap.Error(err))
+					}
+					cancel()
+				}
+			}
+		})
+	}
+}
+
+// Stop ends the workers after their current check; queued submissions are not checked
+func (m *Moderator) Stop() {
+	m.once.Do(func() { close(m.stop) })
+	m.wg.Wait()
+}
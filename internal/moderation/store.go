@@ -0,0 +1,145 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// Review statuses
+const (
+	StatusQuarantined = "quarantined"
+	StatusApproved    = "approved"
+	StatusRejected    = "rejected"
+)
+
+// ErrNotFound is returned when an item does not exist
+var ErrNotFound = errors.New("moderation item not found")
+
+// Item is a quarantined submission and its review
+type Item struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	SubjectID  string     `json:"subject_id"`
+	AuthorID   *string    `json:"author_id"`
+	Text       string     `json:"content"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	Classifier string     `json:"classifier"`
+	Score      float64    `json:"score"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	ReviewedBy *string    `json:"reviewed_by"`
+}
+
+// Content rebuilds the submission, for reporting review decisions to a classifier
+func (i *Item) Content() Content {
+	content := Content{Kind: i.Kind, SubjectID: i.SubjectID, Text: i.Text, IP: i.IP, UserAgent: i.UserAgent}
+	if i.AuthorID != nil {
+		content.AuthorID = *i.AuthorID
+	}
+	return content
+}
+
+// Store persists quarantined submissions
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new moderation store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const itemColumns = `id, kind, subject_id, author_id, content, ip, user_agent, classifier, score, reason, status, created_at, reviewed_at, reviewed_by`
+
+func scanItem(row interface{ Scan(...interface{}) error }) (*Item, error) {
+	var i Item
+	err := row.Scan(&i.ID, &i.Kind, &i.SubjectID, &i.AuthorID, &i.Text, &i.IP, &i.UserAgent,
+		&i.Classifier, &i.Score, &i.Reason, &i.Status, &i.CreatedAt, &i.ReviewedAt, &i.ReviewedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// Quarantine holds content classified as spam for review
+func (s *Store) Quarantine(ctx context.Context, content Content, classifier string, verdict Verdict) (*Item, error) {
+	var authorID *string
+	if content.AuthorID != "" {
+		authorID = &content.AuthorID
+	}
+	item, err := scanItem(s.db.QueryRowContext(ctx, `
+INSERT INTO moderation_items (kind, subject_id, author_id, content, ip, user_agent, classifier, score, reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING `+itemColumns,
+		content.Kind, content.SubjectID, authorID, content.Text, content.IP, content.UserAgent,
+		classifier, verdict.Score, verdict.Reason))
+	if err != nil {
+		return nil, fmt.Errorf("failed to quarantine content: %w", err)
+	}
+	return item, nil
+}
+
+// List returns items with status, most recent first
+func (s *Store) List(ctx context.Context, status string) ([]Item, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+itemColumns+`
+FROM moderation_items
+WHERE status = $1
+ORDER BY created_at DESC
+LIMIT 500`, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load moderation items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []Item{}
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan moderation item: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// Resolve records a review decision
+func (s *Store) Resolve(ctx context.Context, id, status, reviewer string, now time.Time) (*Item, error) {
+	item, err := scanItem(s.db.QueryRowContext(ctx, `
+UPDATE moderation_items SET status = $2, reviewed_by = $3, reviewed_at = $4
+WHERE id = $1
+RETURNING `+itemColumns, id, status, reviewer, now))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to review moderation item: %w", err)
+	}
+	return item, nil
+}
+
+// Quarantined reports whether the latest check of the content is held or was rejected;
+// content approved on review, or never flagged, is visible
+func (s *Store) Quarantined(ctx context.Context, kind, subjectID string) (bool, error) {
+	var status string
+	err := s.db.QueryRowContext(ctx, `
+SELECT status FROM moderation_items
+WHERE kind = $1 AND subject_id = $2
+ORDER BY created_at DESC
+LIMIT 1`, kind, subjectID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check moderation status: %w", err)
+	}
+	return status != StatusApproved, nil
+}
@@ -0,0 +1,99 @@
+package newsletter
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Driver pushes subscription changes to an email provider that sends the campaigns
+type Driver interface {
+	// Name identifies the driver in logs
+	Name() string
+	// Push upserts subscribers: subscribed ones join the provider's list, unsubscribed
+	// ones are marked unsubscribed there so the provider stops mailing them
+	Push(ctx context.Context, subs []Subscriber) error
+}
+
+// Mailchimp syncs an audience through the Mailchimp Marketing API (or a compatible service)
+type Mailchimp struct {
+	key    string
+	listID string
+	// baseURL is the API root, e.g. https://us6.api.mailchimp.com/3.0
+	baseURL string
+	client  *http.Client
+}
+
+// NewMailchimp creates a driver for audience listID. baseURL defaults to the data center
+// in the key's suffix (KEY-us6) and can point at a compatible service.
+func NewMailchimp(key, listID, baseURL string) (*Mailchimp, error) {
+	if baseURL == "" {
+		_, dc, ok := strings.Cut(key, "-")
+		if !ok || dc == "" {
+			return nil, fmt.Errorf("mailchimp: API key has no data center suffix; set the base URL")
+		}
+		baseURL = "https://" + dc + ".api.mailchimp.com/3.0"
+	}
+	return &Mailchimp{key: key, listID: listID, baseURL: strings.TrimRight(baseURL, "/"), client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Name implements Driver
+func (m *Mailchimp) Name() string {
+	return "mailchimp"
+}
+
+// Push implements Driver. Members are upserted one by one; status_if_new keeps the
+// provider from creating a member just to unsubscribe it.
+func (m *Mailchimp) Push(ctx context.Context, subs []Subscriber) error {
+	for _, sub := range subs {
+		status := "subscribed"
+		if sub.Status == StatusUnsubscribed {
+			status = "unsubscribed"
+		}
+		body, err := json.Marshal(map[string]string{
+			"email_address": sub.Email,
+			"status":        status,
+			"status_if_new": status,
+		})
+		if err != nil {
+			return err
+		}
+		if err := m.put(ctx, "/lists/"+m.listID+"/members/"+memberHash(sub.Email), body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mailchimp) put(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, m.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("app", m.key)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mailchimp: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mailchimp: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// memberHash is Mailchimp's member ID: the MD5 of the lowercased address
+func memberHash(email string) string {
+	sum := md5.Sum([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,256 @@
+// Package newsletter runs a double opt-in mailing list. A signup is stored as pending and
+// sent a confirmation email with a signed link; only opening that link subscribes the
+// address. Every confirmation email also carries a signed unsubscribe link. Unsubscribes,
+// bounces, and complaints suppress the address so it is never mailed again until it opts
+// in afresh (unsubscribes) or an admin lifts the suppression (everything else). Confirmed
+// subscribers are exported as CSV or pushed to an email provider through a Driver.
+package newsletter
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/auth"
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+// ManageScope lets admins export the list and manage suppressions
+const ManageScope = "newsletter:write"
+
+const (
+	// confirmPurpose and unsubscribePurpose scope signed tokens to their link
+	confirmPurpose     = "newsletter-confirm"
+	unsubscribePurpose = "newsletter-unsubscribe"
+	// unsubscribeTTL keeps unsubscribe links in old emails working
+	unsubscribeTTL = 5 * 365 * 24 * time.Hour
+	// syncBatch bounds the subscribers pushed to the provider per call
+	syncBatch = 100
+)
+
+// ErrSuppressed is returned by Subscribe for addresses that bounced, complained, or were
+// suppressed by an admin; callers answer as if the signup succeeded
+var ErrSuppressed = errors.New("address is suppressed")
+
+var (
+	requested    = metrics.NewCounter("newsletter", "requested_total", "Newsletter signups awaiting confirmation")
+	confirmed    = metrics.NewCounter("newsletter", "confirmed_total", "Newsletter subscriptions confirmed")
+	unsubscribed = metrics.NewCounter("newsletter", "unsubscribed_total", "Newsletter unsubscribes, bounces, and complaints")
+	suppressed   = metrics.NewCounter("newsletter", "suppressed_signups_total", "Newsletter signups ignored because the address is suppressed")
+	synced       = metrics.NewCounter("newsletter", "synced_total", "Subscribers pushed to the email provider")
+	syncFailures = metrics.NewCounter("newsletter", "sync_failures_total", "Failed pushes to the email provider")
+)
+
+// Options configures the list
+type Options struct {
+	// Secret signs confirmation and unsubscribe links
+	Secret string
+	// ConfirmTTL bounds how long a confirmation link stays valid
+	ConfirmTTL time.Duration
+	// AppURL prefixes the emailed links
+	AppURL string
+}
+
+// Newsletter manages subscriptions and syncs them to a provider
+type Newsletter struct {
+	store  *Store
+	tokens *auth.SignedTokens
+	driver Driver
+	opts   Options
+	clock  clock.Clock
+	logger *logger.Logger
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// New creates a newsletter backed by store; call UseDriver and Start to sync to a provider
+func New(store *Store, opts Options, clk clock.Clock, l *logger.Logger) *Newsletter {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	opts.AppURL = strings.TrimRight(opts.AppURL, "/")
+	return &Newsletter{
+		store:  store,
+		tokens: auth.NewSignedTokens(opts.Secret),
+		opts:   opts,
+		clock:  clk,
+		logger: l,
+		stop:   make(chan struct{}),
+	}
+}
+
+// UseDriver pushes subscription changes to an email provider
+func (n *Newsletter) UseDriver(d Driver) {
+	n.driver = d
+}
+
+// Store returns the newsletter's store
+func (n *Newsletter) Store() *Store {
+	return n.store
+}
+
+// Normalize trims and lowercases an email address
+func Normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Subscribe records a signup. The returned subscriber is pending when a confirmation email
+// should be sent, and subscribed when the address is already on the list.
+func (n *Newsletter) Subscribe(ctx context.Context, email, source, ip string) (*Subscriber, error) {
+	email = Normalize(email)
+	sup, err := n.store.Suppression(ctx, email)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	// Unsubscribing is undone by opting in again; bounces and complaints need an admin
+	if sup != nil && sup.Reason != ReasonUnsubscribed {
+		suppressed.Inc()
+		return nil, ErrSuppressed
+	}
+
+	sub, err := n.store.Request(ctx, email, source, ip, n.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status == StatusPending {
+		requested.Inc()
+	}
+	return sub, nil
+}
+
+// Confirm subscribes the address in a confirmation token and returns it. Opening the link
+// again after confirming succeeds without changing anything.
+func (n *Newsletter) Confirm(ctx context.Context, token string) (string, error) {
+	email, err := n.tokens.Verify(token, confirmPurpose, "", n.clock.Now())
+	if err != nil {
+		return "", err
+	}
+	err = n.store.Confirm(ctx, email, n.clock.Now())
+	if errors.Is(err, ErrNotFound) {
+		// Already confirmed, or unsubscribed since the link was sent
+		sub, findErr := n.store.Find(ctx, email)
+		if findErr == nil && sub.Status == StatusSubscribed {
+			return email, nil
+		}
+		return "", auth.ErrTokenInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	confirmed.Inc()
+	return email, nil
+}
+
+// Unsubscribe removes the address in an unsubscribe token from the list and returns it
+func (n *Newsletter) Unsubscribe(ctx context.Context, token string) (string, error) {
+	email, err := n.tokens.Verify(token, unsubscribePurpose, "", n.clock.Now())
+	if err != nil {
+		return "", err
+	}
+	if err := n.Suppress(ctx, email, ReasonUnsubscribed); err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+// Suppress unsubscribes email and keeps it from being mailed, e.g. after a bounce reported
+// by the provider
+func (n *Newsletter) Suppress(ctx context.Context, email, reason string) error {
+	if err := n.store.Unsubscribe(ctx, Normalize(email), reason, n.clock.Now()); err != nil {
+		return err
+	}
+	unsubscribed.Inc()
+	return nil
+}
+
+// ConfirmURL returns the link that confirms a pending signup, and when it expires
+func (n *Newsletter) ConfirmURL(email string) (string, time.Time) {
+	expiresAt := n.clock.Now().Add(n.opts.ConfirmTTL)
+	token := n.tokens.Sign(confirmPurpose, Normalize(email), "", expiresAt)
+	return n.opts.AppURL + "/api/v1/newsletter/confirm?token=" + url.QueryEscape(token), expiresAt
+}
+
+// UnsubscribeURL returns the link that unsubscribes email, for the footer of every mailing
+func (n *Newsletter) UnsubscribeURL(email string) string {
+	token := n.tokens.Sign(unsubscribePurpose, Normalize(email), "", n.clock.Now().Add(unsubscribeTTL))
+	return n.opts.AppURL + "/api/v1/newsletter/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+// Sync pushes every subscription changed since the last sync to the driver, in batches,
+// and returns how many were pushed
+func (n *Newsletter) Sync(ctx context.Context) (int, error) {
+	if n.driver == nil {
+		return 0, nil
+	}
+	total := 0
+	for {
+		// Rows changed while the batch is in flight keep a newer updated_at and are pushed again
+		at := n.clock.Now()
+		subs, err := n.store.Unsynced(ctx, syncBatch)
+		if err != nil || len(subs) == 0 {
+			return total, err
+		}
+		if err := n.driver.Push(ctx, subs); err != nil {
+			syncFailures.Inc()
+			return total, err
+		}
+		ids := make([]string, len(subs))
+		for i := range subs {
+			ids[i] = subs[i].ID
+		}
+		if err := n.store.MarkSynced(ctx, ids, at); err != nil {
+			return total, err
+		}
+		total += len(subs)
+		synced.Add(int64(len(subs)))
+		if len(subs) < syncBatch {
+			return total, nil
+		}
+	}
+}
+
+// Start syncs to the driver now and then every interval until Stop; it does nothing
+// without a driver
+func (n *Newsletter) Start(interval time.Duration) {
+	if n.driver == nil {
+		return
+	}
+	safego.GoNamed("newsletter-sync", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// One replica syncs at a time; the others skip the round
+			var count int
+			_, err := n.store.db.WithTryAdvisoryLock(ctx, "newsletter-sync", func(ctx context.Context) (err error) {
+				count, err = n.Sync(ctx)
+				return err
+			})
+			cancel()
+			if err != nil {
+				n.logger.Warn("Failed to sync newsletter subscribers", zap.String("driver", n.driver.Name()), zap.Error(err))
+			} else if count > 0 {
+				n.logger.Info("Newsletter subscribers synced", zap.String("driver", n.driver.Name()), zap.Int("subscribers", count))
+			}
+
+			select {
+			case <-n.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background syncing
+func (n *Newsletter) Stop() {
+	n.once.Do(func() { close(n.stop) })
+}
@@ -0,0 +1,289 @@
+package newsletter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"main.go/internal/database"
+	"main.go/internal/database/pagination"
+)
+
+// Subscriber statuses
+const (
+	StatusPending      = "pending"
+	StatusSubscribed   = "subscribed"
+	StatusUnsubscribed = "unsubscribed"
+)
+
+// Suppression reasons
+const (
+	ReasonUnsubscribed = "unsubscribed"
+	ReasonBounced      = "bounced"
+	ReasonComplained   = "complained"
+	ReasonManual       = "manual"
+)
+
+// ErrNotFound is returned when a subscriber or suppression does not exist
+var ErrNotFound = errors.New("newsletter subscriber not found")
+
+// Subscriber is one address on the list
+type Subscriber struct {
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	Status         string     `json:"status"`
+	Source         string     `json:"source"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ConfirmedAt    *time.Time `json:"confirmed_at"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at"`
+}
+
+// Suppression is an address that is never mailed
+type Suppression struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists subscribers and suppressions; emails are expected lowercased
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new newsletter store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const subscriberColumns = `id, email, status, source, created_at, confirmed_at, unsubscribed_at`
+
+func scanSubscriber(row interface{ Scan(...interface{}) error }) (*Subscriber, error) {
+	var s Subscriber
+	if err := row.Scan(&s.ID, &s.Email, &s.Status, &s.Source, &s.CreatedAt, &s.ConfirmedAt, &s.UnsubscribedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Find returns the subscriber with email
+func (s *Store) Find(ctx context.Context, email string) (*Subscriber, error) {
+	sub, err := scanSubscriber(s.db.QueryRowContext(ctx,
+		`SELECT `+subscriberColumns+` FROM newsletter_subscribers WHERE email = $1`, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriber: %w", err)
+	}
+	return sub, nil
+}
+
+// Request records a signup awaiting confirmation. Confirmed subscribers are left as they
+// are; unsubscribed ones go back to pending.
+func (s *Store) Request(ctx context.Context, email, source, ip string, now time.Time) (*Subscriber, error) {
+	sub, err := scanSubscriber(s.db.QueryRowContext(ctx, `
+INSERT INTO newsletter_subscribers (email, source, ip, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $4)
+ON CONFLICT (email) DO UPDATE SET
+    status = CASE WHEN newsletter_subscribers.status = 'subscribed' THEN 'subscribed' ELSE 'pending' END,
+    source = CASE WHEN newsletter_subscribers.status = 'subscribed' THEN newsletter_subscribers.source ELSE EXCLUDED.source END,
+    ip = EXCLUDED.ip,
+    updated_at = CASE WHEN newsletter_subscribers.status = 'unsubscribed' THEN EXCLUDED.updated_at ELSE newsletter_subscribers.updated_at END
+RETURNING `+subscriberColumns, email, source, ip, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// Confirm subscribes a pending address and lifts an unsubscribe suppression, since
+// confirming is a fresh opt-in
+func (s *Store) Confirm(ctx context.Context, email string, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE newsletter_subscribers
+SET status = 'subscribed', confirmed_at = $2, unsubscribed_at = NULL, updated_at = $2
+WHERE email = $1 AND status = 'pending'`, email, now)
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM newsletter_suppressions WHERE email = $1 AND reason = 'unsubscribed'`, email); err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	return nil
+}
+
+// Unsubscribe removes an address from the list and suppresses it with reason; it is a
+// no-op for addresses that never subscribed, apart from the suppression
+func (s *Store) Unsubscribe(ctx context.Context, email, reason string, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE newsletter_subscribers
+SET status = 'unsubscribed', unsubscribed_at = $2, updated_at = $2
+WHERE email = $1 AND status <> 'unsubscribed'`, email, now); err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	// A harder reason (bounce, complaint) replaces an unsubscribe, never the other way round
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO newsletter_suppressions (email, reason, created_at) VALUES ($1, $2, $3)
+ON CONFLICT (email) DO UPDATE SET reason = EXCLUDED.reason
+WHERE newsletter_suppressions.reason = 'unsubscribed'`, email, reason, now); err != nil {
+		return fmt.Errorf("failed to suppress address: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+	return nil
+}
+
+// Suppression returns the suppression for email, or ErrNotFound
+func (s *Store) Suppression(ctx context.Context, email string) (*Suppression, error) {
+	var sup Suppression
+	err := s.db.QueryRowContext(ctx,
+		`SELECT email, reason, created_at FROM newsletter_suppressions WHERE email = $1`, email).
+		Scan(&sup.Email, &sup.Reason, &sup.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check suppression: %w", err)
+	}
+	return &sup, nil
+}
+
+// suppressionOrder lists suppressions most recent first
+var suppressionOrder = pagination.Order{pagination.Desc("created_at"), pagination.Desc("email")}
+
+// suppressionCursor is the position of a suppression in suppressionOrder
+type suppressionCursor struct {
+	CreatedAt time.Time `json:"c"`
+	Email     string    `json:"e"`
+}
+
+func (c suppressionCursor) Values() []interface{} {
+	return []interface{}{c.CreatedAt, c.Email}
+}
+
+// Suppressions returns a page of suppressed addresses, most recent first
+func (s *Store) Suppressions(ctx context.Context, req pagination.Request) (*pagination.Page[Suppression], error) {
+	q, err := pagination.Build[suppressionCursor](suppressionOrder, req, 2)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT email, reason, created_at FROM newsletter_suppressions
+WHERE `+q.Where+`
+ORDER BY `+q.OrderBy+`
+LIMIT $1`, append([]interface{}{q.Limit}, q.Args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load suppressions: %w", err)
+	}
+	defer rows.Close()
+
+	var suppressions []Suppression
+	for rows.Next() {
+		var sup Suppression
+		if err := rows.Scan(&sup.Email, &sup.Reason, &sup.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suppression: %w", err)
+		}
+		suppressions = append(suppressions, sup)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pagination.NewPage(suppressionOrder, q, suppressions, func(sup Suppression) suppressionCursor {
+		return suppressionCursor{CreatedAt: sup.CreatedAt, Email: sup.Email}
+	})
+}
+
+// Unsuppress lifts a suppression; the address must opt in again to be mailed
+func (s *Store) Unsuppress(ctx context.Context, email string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM newsletter_suppressions WHERE email = $1`, email)
+	if err != nil {
+		return fmt.Errorf("failed to lift suppression: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// EachSubscribed calls fn for every confirmed subscriber that is not suppressed, oldest first
+func (s *Store) EachSubscribed(ctx context.Context, fn func(*Subscriber) error) error {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+subscriberColumns+` FROM newsletter_subscribers s
+WHERE status = 'subscribed'
+  AND NOT EXISTS (SELECT 1 FROM newsletter_suppressions x WHERE x.email = s.email)
+ORDER BY created_at`)
+	if err != nil {
+		return fmt.Errorf("failed to load subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Unsynced returns up to limit subscribed or unsubscribed addresses changed since their
+// last sync; pending signups are never synced
+func (s *Store) Unsynced(ctx context.Context, limit int) ([]Subscriber, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+subscriberColumns+` FROM newsletter_subscribers
+WHERE status <> 'pending' AND (synced_at IS NULL OR synced_at < updated_at)
+ORDER BY updated_at
+LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unsynced subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []Subscriber
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// MarkSynced records that the subscribers were pushed as of at; rows changed after at
+// stay unsynced
+func (s *Store) MarkSynced(ctx context.Context, ids []string, at time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `
+UPDATE newsletter_subscribers SET synced_at = $2
+WHERE id = ANY($1) AND updated_at <= $2`, pq.Array(ids), at); err != nil {
+		return fmt.Errorf("failed to mark subscribers synced: %w", err)
+	}
+	return nil
+}
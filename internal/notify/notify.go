@@ -0,0 +1,169 @@
+// Package notify delivers operator notifications (alerts, digests) to one or more
+// channels: the application log, email, or a chat webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+)
+
+// Severity levels
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notification is a single message for operators
+type Notification struct {
+	Title    string
+	Body     string
+	Severity string
+	Fields   map[string]string
+}
+
+// Text renders the notification as plain text
+func (n Notification) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s\n", strings.ToUpper(n.Severity), n.Title)
+	if n.Body != "" {
+		b.WriteString(n.Body + "\n")
+	}
+	keys := make([]string, 0, len(n.Fields))
+	for k := range n.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", k, n.Fields[k])
+	}
+	return b.String()
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct {
+	logger *logger.Logger
+}
+
+// NewLogNotifier creates a notifier that logs at warn level
+func NewLogNotifier(l *logger.Logger) *LogNotifier {
+	return &LogNotifier{logger: l}
+}
+
+// Notify logs n
+func (l *LogNotifier) Notify(ctx context.Context, n Notification) error {
+	fields := []zap.Field{zap.String("severity", n.Severity), zap.String("body", n.Body)}
+	for k, v := range n.Fields {
+		fields = append(fields, zap.String(k, v))
+	}
+	l.logger.Warn("Notification: "+n.Title, fields...)
+	return nil
+}
+
+// MailNotifier emails notifications to a fixed list of recipients
+type MailNotifier struct {
+	mailer mail.Mailer
+	to     []string
+}
+
+// NewMailNotifier creates a notifier that emails every recipient in to
+func NewMailNotifier(mailer mail.Mailer, to []string) *MailNotifier {
+	return &MailNotifier{mailer: mailer, to: to}
+}
+
+// Notify sends n to each recipient
+func (m *MailNotifier) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, to := range m.to {
+		err := m.mailer.Send(ctx, mail.Message{
+			To:      to,
+			Subject: fmt.Sprintf("[%s] %s", strings.ToUpper(n.Severity), n.Title),
+			Text:    n.Text(),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", to, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WebhookNotifier posts notifications as {"text": "..."}, the payload accepted by
+// Slack, Mattermost, and Discord (via /slack) incoming webhooks
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier that posts to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify posts n to the webhook
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(map[string]string{"text": n.Text()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Multi fans a notification out to several notifiers, returning every delivery error
+type Multi []Notifier
+
+// Notify delivers n through each notifier
+func (m Multi) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Muted drops notifications while muted reports true, e.g. during a maintenance window.
+// Wrap only external notifiers so muted alerts still reach the log.
+type Muted struct {
+	Notifier Notifier
+	Muted    func() bool
+}
+
+// Notify delivers n unless muted
+func (m Muted) Notify(ctx context.Context, n Notification) error {
+	if m.Muted != nil && m.Muted() {
+		return nil
+	}
+	return m.Notifier.Notify(ctx, n)
+}
@@ -0,0 +1,319 @@
+// Package operations runs slow work, such as transcoding a video, in the background and
+// reports on it. A request that starts such work creates an Operation and answers at once
+// with its ID; clients poll GET /api/v1/operations/:id for its status, progress, and
+// result. Operations are rows in the database claimed under a lease that the running
+// instance keeps renewing, so they survive restarts and run once across instances. A
+// failed run is retried with exponential backoff until MaxAttempts.
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+// ReadScope lets admins read every operation; others only read their own
+const ReadScope = "operations:read"
+
+// Operation statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	// MaxAttempts is how many times an operation runs before it is marked failed
+	MaxAttempts = 5
+	// lease keeps a claimed operation from other instances; the runner renews it every
+	// lease/3 while the handler works, so it only lapses when the instance dies
+	lease = 2 * time.Minute
+	// firstRetry is the wait after the first failure; it doubles with each attempt
+	firstRetry = time.Minute
+	// pruneEvery bounds how often finished operations past the retention are deleted
+	pruneEvery = time.Hour
+)
+
+var (
+	started   = metrics.NewCounter("operations", "started_total", "Operations created")
+	succeeded = metrics.NewCounter("operations", "succeeded_total", "Operations completed")
+	retries   = metrics.NewCounter("operations", "retries_total", "Operation runs that failed and were rescheduled")
+	failed    = metrics.NewCounter("operations", "failed_total", "Operations given up after the maximum attempts or a permanent error")
+)
+
+// Operation is a unit of background work and its outcome
+type Operation struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Subject names what the operation works on, e.g. "attachment:<id>"
+	Subject string `json:"subject,omitempty"`
+	OwnerID string `json:"owner_id,omitempty"`
+	Status  string `json:"status"`
+	// Progress is a percentage, reported by the handler while it runs
+	Progress int `json:"progress"`
+	// Input is what the handler was given; it is not exposed
+	Input     json.RawMessage `json:"-"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DoneAt    *time.Time      `json:"done_at,omitempty"`
+}
+
+// Handler does the work of an operation and returns its result, which is stored as JSON.
+// Long handlers call report with a percentage as they go.
+type Handler func(ctx context.Context, op *Operation, report func(progress int)) (interface{}, error)
+
+// permanentError is a failure that running again cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure retries cannot fix, such as unreadable input; the
+// operation fails at once
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type kind struct {
+	handler Handler
+	timeout time.Duration
+}
+
+// Runner creates operations and runs them with the handlers registered for their kind
+type Runner struct {
+	store     *Store
+	retention time.Duration
+	clock     clock.Clock
+	logger    *logger.Logger
+
+	mu    sync.RWMutex
+	kinds map[string]kind
+
+	lastPrune time.Time
+	pruneMu   sync.Mutex
+	// wake starts a run right after an operation is created
+	wake   chan struct{}
+	stop   chan struct{}
+	cancel context.CancelFunc
+	ctx    context.Context
+	once   sync.Once
+}
+
+// New creates a runner; finished operations are deleted after retention (0 keeps them).
+// Register handlers, then call Start.
+func New(store *Store, retention time.Duration, clk clock.Clock, l *logger.Logger) *Runner {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		store:     store,
+		retention: retention,
+		clock:     clk,
+		logger:    l,
+		kinds:     map[string]kind{},
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Store returns the runner's store
+func (r *Runner) Store() *Store {
+	return r.store
+}
+
+// Handle registers the handler of a kind; each run is cancelled after timeout
+func (r *Runner) Handle(name string, timeout time.Duration, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kinds[name] = kind{handler: h, timeout: timeout}
+}
+
+func (r *Runner) kindNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.kinds))
+	for name := range r.kinds {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Enqueue creates a pending operation of a registered kind; input is encoded as JSON and
+// handed to the handler
+func (r *Runner) Enqueue(ctx context.Context, kindName, subject, ownerID string, input interface{}) (*Operation, error) {
+	r.mu.RLock()
+	_, ok := r.kinds[kindName]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no handler for %s operations", kindName)
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s input: %w", kindName, err)
+	}
+	op, err := r.store.Create(ctx, kindName, subject, ownerID, data, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	started.Inc()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return op, nil
+}
+
+// RunOne claims the oldest due operation and runs it. It reports false when nothing was due.
+func (r *Runner) RunOne(ctx context.Context) (bool, error) {
+	names := r.kindNames()
+	if len(names) == 0 {
+		return false, nil
+	}
+	op, err := r.store.Claim(ctx, names, r.clock.Now(), lease)
+	if err != nil || op == nil {
+		return false, err
+	}
+	r.mu.RLock()
+	k := r.kinds[op.Kind]
+	r.mu.RUnlock()
+
+	result, err := r.run(ctx, op, k)
+	now := r.clock.Now()
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		succeeded.Inc()
+		var data []byte
+		if data, err = json.Marshal(result); err == nil {
+			err = r.store.Succeed(ctx, op.ID, data, now)
+		}
+	case ctx.Err() != nil:
+		// Shutting down: offer the operation again straight away
+		r.logger.Info("Operation interrupted; it will run again", zap.String("id", op.ID), zap.String("kind", op.Kind))
+		err = r.store.Retry(context.WithoutCancel(ctx), op.ID, err, now)
+	case errors.As(err, &permanent) || op.Attempts >= MaxAttempts:
+		failed.Inc()
+		r.logger.Error("Operation failed", zap.String("id", op.ID), zap.String("kind", op.Kind),
+			zap.Int("attempts", op.Attempts), zap.Error(err))
+		err = r.store.Fail(ctx, op.ID, err, now)
+	default:
+		retries.Inc()
+		wait := firstRetry << (op.Attempts - 1)
+		r.logger.Warn("Operation failed; retrying", zap.String("id", op.ID), zap.String("kind", op.Kind),
+			zap.Int("attempt", op.Attempts), zap.Duration("retry_in", wait), zap.Error(err))
+		err = r.store.Retry(ctx, op.ID, err, now.Add(wait))
+	}
+	if err != nil {
+		// The lease expires and the operation runs again
+		r.logger.Warn("Failed to record operation outcome", zap.String("id", op.ID), zap.Error(err))
+	}
+	return true, nil
+}
+
+// run calls the handler, renewing the lease and saving progress until it returns
+func (r *Runner) run(ctx context.Context, op *Operation, k kind) (result interface{}, err error) {
+	runCtx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+
+	var progress atomic.Int64
+	progress.Store(-1)
+	done := make(chan struct{})
+	defer close(done)
+	safego.GoNamed("operation-lease", func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := r.store.Renew(ctx, op.ID, int(progress.Load()), r.clock.Now().Add(lease)); err != nil {
+				r.logger.Warn("Failed to renew operation lease", zap.String("id", op.ID), zap.Error(err))
+			}
+		}
+	})
+
+	report := func(p int) {
+		progress.Store(int64(min(max(p, 0), 100)))
+	}
+	return k.handler(runCtx, op, report)
+}
+
+// prune deletes finished operations past the retention, at most once per pruneEvery
+func (r *Runner) prune(ctx context.Context) {
+	r.pruneMu.Lock()
+	defer r.pruneMu.Unlock()
+	now := r.clock.Now()
+	if r.retention <= 0 || now.Sub(r.lastPrune) < pruneEvery {
+		return
+	}
+	r.lastPrune = now
+	n, err := r.store.Prune(ctx, now.Add(-r.retention))
+	if err != nil {
+		r.logger.Warn("Failed to prune finished operations", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		r.logger.Info("Pruned finished operations", zap.Int("operations", n))
+	}
+}
+
+// Start runs due operations on workers goroutines, polling every interval and waking
+// when one is created, until Stop
+func (r *Runner) Start(interval time.Duration, workers int) {
+	for i := 0; i < max(workers, 1); i++ {
+		safego.GoNamed("operations", func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				for {
+					ran, err := r.RunOne(r.ctx)
+					if err != nil {
+						r.logger.Warn("Failed to run operations", zap.Error(err))
+					}
+					if !ran || r.ctx.Err() != nil {
+						break
+					}
+				}
+				r.prune(r.ctx)
+
+				select {
+				case <-r.stop:
+					return
+				case <-ticker.C:
+				case <-r.wake:
+				}
+			}
+		})
+	}
+}
+
+// Stop ends background work. Running operations are cancelled and run again after the
+// next Start.
+func (r *Runner) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+		r.cancel()
+	})
+}
@@ -0,0 +1,162 @@
+package operations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"main.go/internal/database"
+)
+
+// ErrNotFound is returned when an operation does not exist
+var ErrNotFound = errors.New("operation not found")
+
+// Store persists operations
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new operation store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const operationColumns = `id, kind, subject, COALESCE(owner_id::text, ''), status, progress, input,
+    result, last_error, attempts, created_at, updated_at, done_at`
+
+func scanOperation(row interface{ Scan(...interface{}) error }) (*Operation, error) {
+	var op Operation
+	var result []byte
+	var doneAt sql.NullTime
+	if err := row.Scan(&op.ID, &op.Kind, &op.Subject, &op.OwnerID, &op.Status, &op.Progress, &op.Input,
+		&result, &op.Error, &op.Attempts, &op.CreatedAt, &op.UpdatedAt, &doneAt); err != nil {
+		return nil, err
+	}
+	op.Result = result
+	if doneAt.Valid {
+		op.DoneAt = &doneAt.Time
+	}
+	return &op, nil
+}
+
+// Create records a pending operation, due from now
+func (s *Store) Create(ctx context.Context, kind, subject, ownerID string, input []byte, now time.Time) (*Operation, error) {
+	op, err := scanOperation(s.db.QueryRowContext(ctx, `
+INSERT INTO operations (kind, subject, owner_id, input, next_attempt_at, created_at, updated_at)
+VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $5, $5)
+RETURNING `+operationColumns, kind, subject, ownerID, input, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation: %w", err)
+	}
+	return op, nil
+}
+
+// Find returns the operation with id
+func (s *Store) Find(ctx context.Context, id string) (*Operation, error) {
+	op, err := scanOperation(s.db.QueryRowContext(ctx, `SELECT `+operationColumns+` FROM operations WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load operation: %w", err)
+	}
+	return op, nil
+}
+
+// Latest returns the most recent operation of a kind on subject
+func (s *Store) Latest(ctx context.Context, kind, subject string) (*Operation, error) {
+	op, err := scanOperation(s.db.QueryRowContext(ctx, `
+SELECT `+operationColumns+` FROM operations
+WHERE kind = $1 AND subject = $2
+ORDER BY created_at DESC
+LIMIT 1`, kind, subject))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load operation: %w", err)
+	}
+	return op, nil
+}
+
+// Claim takes the oldest due operation of one of kinds, marks it running, and leases it
+// until now+lease, counting the attempt. It returns nil when none is due; rows locked by
+// another instance are skipped.
+func (s *Store) Claim(ctx context.Context, kinds []string, now time.Time, lease time.Duration) (*Operation, error) {
+	op, err := scanOperation(s.db.QueryRowContext(ctx, `
+UPDATE operations SET status = 'running', attempts = attempts + 1, next_attempt_at = $2, updated_at = $1
+WHERE id = (
+    SELECT id FROM operations
+    WHERE status IN ('pending', 'running') AND next_attempt_at <= $1 AND kind = ANY($3::text[])
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING `+operationColumns, now, now.Add(lease), pq.Array(kinds)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim operation: %w", err)
+	}
+	return op, nil
+}
+
+// Renew extends the lease of a running operation to until and saves its progress; a
+// negative progress leaves it unchanged
+func (s *Store) Renew(ctx context.Context, id string, progress int, until time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE operations SET next_attempt_at = $3, progress = CASE WHEN $2 < 0 THEN progress ELSE $2 END, updated_at = NOW()
+WHERE id = $1 AND status = 'running'`, id, progress, until)
+	if err != nil {
+		return fmt.Errorf("failed to renew operation: %w", err)
+	}
+	return nil
+}
+
+// Succeed records the result of a completed operation
+func (s *Store) Succeed(ctx context.Context, id string, result []byte, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE operations SET status = 'succeeded', progress = 100, result = $2, last_error = '', updated_at = $3, done_at = $3
+WHERE id = $1`, id, result, now)
+	if err != nil {
+		return fmt.Errorf("failed to complete operation: %w", err)
+	}
+	return nil
+}
+
+// Retry records a failed run and schedules the next one
+func (s *Store) Retry(ctx context.Context, id string, cause error, next time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE operations SET status = 'pending', last_error = $2, next_attempt_at = $3, updated_at = NOW()
+WHERE id = $1`, id, cause.Error(), next)
+	if err != nil {
+		return fmt.Errorf("failed to reschedule operation: %w", err)
+	}
+	return nil
+}
+
+// Fail records an operation that will not run again
+func (s *Store) Fail(ctx context.Context, id string, cause error, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+UPDATE operations SET status = 'failed', last_error = $2, updated_at = $3, done_at = $3
+WHERE id = $1`, id, cause.Error(), now)
+	if err != nil {
+		return fmt.Errorf("failed to mark operation failed: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes operations that finished before cutoff and returns how many were deleted
+func (s *Store) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM operations WHERE done_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune operations: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,147 @@
+// Package outbox queues outgoing email in the database and delivers it in the background.
+// A message accepted by a request survives SMTP outages and restarts: failed deliveries
+// are retried with exponential backoff until MaxAttempts. Outbox implements mail.Mailer,
+// so a handler written against mail.Mailer switches to queued delivery by being given an
+// Outbox instead of the SMTP mailer.
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+const (
+	// MaxAttempts is how many times a message is tried before it is marked failed
+	MaxAttempts = 8
+	// batchSize bounds the messages claimed per run
+	batchSize = 20
+	// lease keeps a claimed message from other instances while it is being sent; it must
+	// exceed sendTimeout
+	lease = 5 * time.Minute
+	// sendTimeout bounds the delivery of one message
+	sendTimeout = 30 * time.Second
+	// firstRetry is the wait after the first failure; it doubles with each attempt
+	firstRetry = 30 * time.Second
+)
+
+var (
+	queued  = metrics.NewCounter("outbox", "queued_total", "Emails queued for delivery")
+	sent    = metrics.NewCounter("outbox", "sent_total", "Queued emails delivered")
+	retries = metrics.NewCounter("outbox", "retries_total", "Queued email deliveries that failed and were rescheduled")
+	failed  = metrics.NewCounter("outbox", "failed_total", "Queued emails given up after the maximum attempts")
+)
+
+// Outbox queues email and delivers it through a mailer
+type Outbox struct {
+	store  *Store
+	mailer mail.Mailer
+	clock  clock.Clock
+	logger *logger.Logger
+	// wake starts a delivery run right after a message is queued
+	wake chan struct{}
+	stop chan struct{}
+	once sync.Once
+}
+
+// New creates an outbox that delivers through mailer; call Start to deliver
+func New(store *Store, mailer mail.Mailer, clk clock.Clock, l *logger.Logger) *Outbox {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Outbox{
+		store:  store,
+		mailer: mailer,
+		clock:  clk,
+		logger: l,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Send implements mail.Mailer by queueing msg; it returns once the message is stored
+func (o *Outbox) Send(ctx context.Context, msg mail.Message) error {
+	if err := o.store.Enqueue(ctx, msg, o.clock.Now()); err != nil {
+		return err
+	}
+	queued.Inc()
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Deliver sends the messages that are due and returns how many were delivered
+func (o *Outbox) Deliver(ctx context.Context) (int, error) {
+	messages, err := o.store.Claim(ctx, o.clock.Now(), lease, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, msg := range messages {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := o.mailer.Send(sendCtx, msg.Message)
+		cancel()
+
+		now := o.clock.Now()
+		switch {
+		case err == nil:
+			delivered++
+			sent.Inc()
+			err = o.store.MarkSent(ctx, msg.ID, now)
+		case msg.Attempts >= MaxAttempts:
+			failed.Inc()
+			o.logger.Error("Giving up on queued email",
+				zap.String("id", msg.ID), zap.Int("attempts", msg.Attempts), zap.Error(err))
+			err = o.store.MarkFailed(ctx, msg.ID, err, now)
+		default:
+			retries.Inc()
+			wait := firstRetry << (msg.Attempts - 1)
+			o.logger.Warn("Failed to send queued email; retrying",
+				zap.String("id", msg.ID), zap.Int("attempt", msg.Attempts), zap.Duration("retry_in", wait), zap.Error(err))
+			err = o.store.Retry(ctx, msg.ID, err, now.Add(wait))
+		}
+		if err != nil {
+			// The lease expires and the message is retried; it may be sent twice
+			o.logger.Warn("Failed to record queued email delivery", zap.String("id", msg.ID), zap.Error(err))
+		}
+	}
+	return delivered, nil
+}
+
+// Start delivers due messages every interval, and right after a message is queued, until Stop
+func (o *Outbox) Start(interval time.Duration) {
+	safego.GoNamed("mail-outbox", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), lease)
+			if _, err := o.Deliver(ctx); err != nil {
+				o.logger.Warn("Failed to deliver queued email", zap.Error(err))
+			}
+			cancel()
+
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+			case <-o.wake:
+			}
+		}
+	})
+}
+
+// Stop ends background delivery; queued messages are delivered after the next Start
+func (o *Outbox) Stop() {
+	o.once.Do(func() { close(o.stop) })
+}
@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+	"main.go/internal/mail"
+)
+
+// Message is a queued email
+type Message struct {
+	ID       string
+	Attempts int
+	mail.Message
+}
+
+// Store persists queued email
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new outbox store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Enqueue queues msg for delivery from now
+func (s *Store) Enqueue(ctx context.Context, msg mail.Message, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO mail_outbox (to_address, reply_to, subject, text_body, html_body, next_attempt_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		msg.To, msg.ReplyTo, msg.Subject, msg.Text, msg.HTML, now)
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	return nil
+}
+
+// Claim takes up to limit due messages and leases them until now+lease, counting the
+// attempt. Rows locked by another instance are skipped.
+func (s *Store) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Message, error) {
+	rows, err := s.db.QueryContext(ctx, `
+UPDATE mail_outbox SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id IN (
+    SELECT id FROM mail_outbox
+    WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $1
+    ORDER BY next_attempt_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, attempts, to_address, reply_to, subject, text_body, html_body`,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim queued email: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.ID, &m.Attempts, &m.To, &m.ReplyTo, &m.Subject, &m.Text, &m.HTML); err != nil {
+			return nil, fmt.Errorf("failed to scan queued email: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// MarkSent records a delivered message
+func (s *Store) MarkSent(ctx context.Context, id string, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE mail_outbox SET sent_at = $2, last_error = '' WHERE id = $1`, id, now); err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+	return nil
+}
+
+// Retry records a failed attempt and schedules the next one
+func (s *Store) Retry(ctx context.Context, id string, cause error, next time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE mail_outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1`, id, cause.Error(), next); err != nil {
+		return fmt.Errorf("failed to reschedule email: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a message that will not be retried
+func (s *Store) MarkFailed(ctx context.Context, id string, cause error, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE mail_outbox SET last_error = $2, failed_at = $3 WHERE id = $1`, id, cause.Error(), now); err != nil {
+		return fmt.Errorf("failed to mark email failed: %w", err)
+	}
+	return nil
+}
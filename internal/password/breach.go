@@ -0,0 +1,73 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pwnedRangeURL is the HaveIBeenPwned k-anonymity range API
+const pwnedRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// BreachChecker looks passwords up in the HaveIBeenPwned corpus without sending them:
+// only the first five characters of the SHA-1 hash leave the process, and the response
+// is padded so its size does not reveal the prefix.
+type BreachChecker struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewBreachChecker creates a checker using the public HaveIBeenPwned API
+func NewBreachChecker() *BreachChecker {
+	return &BreachChecker{client: &http.Client{Timeout: 5 * time.Second}, baseURL: pwnedRangeURL}
+}
+
+// Breached reports how many times password appears in known breaches
+func (b *BreachChecker) Breached(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("breach lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("breach lookup returned %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || candidate != suffix {
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscanf(count, "%d", &n); err != nil {
+			return 0, fmt.Errorf("invalid breach count %q", count)
+		}
+		// Padding entries have a count of zero
+		return n, nil
+	}
+	return 0, scanner.Err()
+}
+
+// IsBreached adapts Breached for validation.SetBreachCheck
+func (b *BreachChecker) IsBreached(password string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n, err := b.Breached(ctx, password)
+	return n > 0, err
+}
@@ -0,0 +1,161 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character classes a policy can require
+const (
+	ClassUpper  = "upper"
+	ClassLower  = "lower"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// classNames describe each class in error messages, in a fixed order; single is used
+// when the class is the only one required
+var classNames = []struct {
+	class  string
+	name   string
+	single string
+}{
+	{ClassUpper, "uppercase", "an uppercase letter"},
+	{ClassLower, "lowercase", "a lowercase letter"},
+	{ClassDigit, "number", "a number"},
+	{ClassSymbol, "special character", "a special character"},
+}
+
+// Policy decides which new passwords are acceptable: a minimum length in characters,
+// required character classes, and a case-insensitive list of banned passwords
+type Policy struct {
+	minLength int
+	require   map[string]bool
+	banned    map[string]bool
+}
+
+// NewPolicy creates a policy; classes are any of "upper", "lower", "digit", and "symbol"
+func NewPolicy(minLength int, classes, banned []string) (*Policy, error) {
+	if minLength < 1 {
+		return nil, fmt.Errorf("password minimum length must be at least 1, got %d", minLength)
+	}
+	p := &Policy{minLength: minLength, require: map[string]bool{}, banned: map[string]bool{}}
+	for _, class := range classes {
+		class = strings.ToLower(strings.TrimSpace(class))
+		switch class {
+		case ClassUpper, ClassLower, ClassDigit, ClassSymbol:
+			p.require[class] = true
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown password character class %q", class)
+		}
+	}
+	for _, word := range banned {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			p.banned[word] = true
+		}
+	}
+	return p, nil
+}
+
+// DefaultPolicy requires 8 characters with upper and lower case letters, a number, and a symbol
+func DefaultPolicy() *Policy {
+	p, _ := NewPolicy(8, []string{ClassUpper, ClassLower, ClassDigit, ClassSymbol}, nil)
+	return p
+}
+
+// Allows reports whether password satisfies the policy
+func (p *Policy) Allows(password string) bool {
+	if utf8.RuneCountInString(password) < p.minLength || p.banned[strings.ToLower(password)] {
+		return false
+	}
+
+	found := map[string]bool{}
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			found[ClassUpper] = true
+		case unicode.IsLower(r):
+			found[ClassLower] = true
+		case unicode.IsDigit(r):
+			found[ClassDigit] = true
+		case !unicode.IsLetter(r):
+			found[ClassSymbol] = true
+		}
+	}
+	for class := range p.require {
+		if !found[class] {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe explains the policy for validation error messages
+func (p *Policy) Describe() string {
+	var classes []string
+	single := ""
+	for _, c := range classNames {
+		if p.require[c.class] {
+			classes = append(classes, c.name)
+			single = c.single
+		}
+	}
+
+	msg := fmt.Sprintf("password must be at least %d characters", p.minLength)
+	switch len(classes) {
+	case 0:
+	case 1:
+		msg += " and contain " + single
+	default:
+		msg += " and contain " + strings.Join(classes[:len(classes)-1], ", ") + ", and " + classes[len(classes)-1]
+	}
+	if len(p.banned) > 0 {
+		msg += ", and must not be a commonly used password"
+	}
+	return msg
+}
+
+// LoadBannedList reads one banned password per line; blank lines and lines starting
+// with "#" are skipped
+func LoadBannedList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words, scanner.Err()
+}
+
+var (
+	policyMu      sync.RWMutex
+	defaultPolicy = DefaultPolicy()
+)
+
+// CurrentPolicy returns the process-wide policy used by the password validation tag
+func CurrentPolicy() *Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return defaultPolicy
+}
+
+// SetPolicy replaces the process-wide policy, e.g. with settings from configuration
+func SetPolicy(p *Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	defaultPolicy = p
+}
@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UserResolver extracts the authenticated user ID from the request, returning
+// an empty string for anonymous requests
+type UserResolver func(c *fiber.Ctx) string
+
+// RequireAcceptance returns a middleware that blocks authenticated users until they
+// have accepted the latest version of every published policy. Anonymous requests and
+// paths under exemptPrefixes are always let through. The first prefix should be the
+// policy endpoints, which blocked clients are pointed at; the rest typically cover
+// logout, token refresh, and cookie consent, so users are never stuck signed in.
+func RequireAcceptance(store *Store, resolveUser UserResolver, exemptPrefixes ...string) fiber.Handler {
+	var accept string
+	if len(exemptPrefixes) > 0 {
+		accept = exemptPrefixes[0]
+	}
+
+	return func(c *fiber.Ctx) error {
+		if store == nil || resolveUser == nil {
+			return c.Next()
+		}
+
+		for _, prefix := range exemptPrefixes {
+			if prefix != "" && strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		userID := resolveUser(c)
+		if userID == "" {
+			return c.Next()
+		}
+
+		pending, err := store.Pending(c.UserContext(), userID)
+		if err != nil {
+			// Fail open: an outage of the policy tables must not take the API down
+			return c.Next()
+		}
+
+		if len(pending) > 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":   "Policy acceptance required",
+				"message": "Updated policies must be accepted before continuing",
+				"pending": pending,
+				"accept":  accept,
+				"status":  fiber.StatusConflict,
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"main.go/internal/database"
+)
+
+// maxUserAgent bounds the stored user agent to the column size
+const maxUserAgent = 512
+
+// ErrDocumentNotFound is returned when a policy kind/version pair does not exist
+var ErrDocumentNotFound = errors.New("policy document not found")
+
+// Document represents a single published version of a policy (terms, privacy, ...)
+type Document struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Version     string    `json:"version"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Acceptance records that a user accepted a specific document version
+type Acceptance struct {
+	UserID     string
+	DocumentID string
+	IPAddress  string
+	UserAgent  string
+}
+
+// Store persists policy documents and acceptances
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new policy store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// currentDocumentsQuery selects the latest published version of every policy kind
+const currentDocumentsQuery = `
+SELECT DISTINCT ON (kind) id, kind, version, title, url, published_at
+FROM policy_documents
+WHERE published_at <= NOW()
+ORDER BY kind, published_at DESC`
+
+// Current returns the latest published version of every policy kind
+func (s *Store) Current(ctx context.Context) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx, currentDocumentsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy documents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// Pending returns the current documents the user has not accepted yet
+func (s *Store) Pending(ctx context.Context, userID string) ([]Document, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT d.id, d.kind, d.version, d.title, d.url, d.published_at
+FROM (`+currentDocumentsQuery+`) d
+LEFT JOIN policy_acceptances a ON a.document_id = d.id AND a.user_id = $1
+WHERE a.id IS NULL
+ORDER BY d.kind`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending policies: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// Find returns the document for the given kind and version
+func (s *Store) Find(ctx context.Context, kind, version string) (*Document, error) {
+	var d Document
+	err := s.db.QueryRowContext(ctx, `
+SELECT id, kind, version, title, url, published_at
+FROM policy_documents
+WHERE kind = $1 AND version = $2`, kind, version).
+		Scan(&d.ID, &d.Kind, &d.Version, &d.Title, &d.URL, &d.PublishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy document: %w", err)
+	}
+	return &d, nil
+}
+
+// Accept records an acceptance; accepting the same document twice is a no-op
+func (s *Store) Accept(ctx context.Context, a Acceptance) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO policy_acceptances (user_id, document_id, ip_address, user_agent)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, document_id) DO NOTHING`, a.UserID, a.DocumentID, a.IPAddress, truncate(a.UserAgent, maxUserAgent))
+	if err != nil {
+		return fmt.Errorf("failed to record policy acceptance: %w", err)
+	}
+	return nil
+}
+
+func scanDocuments(rows *sql.Rows) ([]Document, error) {
+	docs := []Document{}
+	for rows.Next() {
+		var d Document
+		if err := rows.Scan(&d.ID, &d.Kind, &d.Version, &d.Title, &d.URL, &d.PublishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan policy document: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
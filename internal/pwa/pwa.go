@@ -0,0 +1,90 @@
+// Package pwa serves an optional service worker so the app can be installed and keeps
+// working offline. It pairs with the web app manifest served by internal/icons.
+//
+// The worker is rendered from sw.js.tmpl: pages are fetched network first with the
+// cached copy as an offline fallback, /static/ and /icons/ are served from the cache
+// and refreshed in the background, and /api/ is never cached.
+package pwa
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"runtime/debug"
+	"text/template"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Path is where the service worker is served; at the root so its scope covers every page
+const Path = "/sw.js"
+
+//go:embed sw.js.tmpl
+var workerTemplate string
+
+// Options configures the service worker
+type Options struct {
+	// Precache lists URLs cached on install, e.g. "/" and "/static/theme.css"
+	Precache []string
+	// Version names the cache; a new version drops the previous cache on activation.
+	// Defaults to the VCS revision the binary was built from.
+	Version string
+}
+
+// ServiceWorker renders the service worker and returns a handler serving it at Path
+func ServiceWorker(opts Options) (fiber.Handler, error) {
+	if opts.Version == "" {
+		opts.Version = buildRevision()
+	}
+	if opts.Precache == nil {
+		opts.Precache = []string{}
+	}
+
+	cacheName, err := json.Marshal("app-" + opts.Version)
+	if err != nil {
+		return nil, err
+	}
+	precache, err := json.Marshal(opts.Precache)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("sw.js").Parse(workerTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, map[string]string{
+		"CacheName": string(cacheName),
+		"Precache":  string(precache),
+	}); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	return func(c *fiber.Ctx) error {
+		// Browsers check for worker updates on navigation; no-cache makes them revalidate
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderETag, etag)
+		if c.Get(fiber.HeaderIfNoneMatch) == etag {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		c.Set(fiber.HeaderContentType, "text/javascript; charset=utf-8")
+		return c.Send(body.Bytes())
+	}, nil
+}
+
+// buildRevision is the VCS revision in the binary's build info, or "1" when the binary
+// was built outside a repository
+func buildRevision() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				return setting.Value[:12]
+			}
+		}
+	}
+	return "1"
+}
@@ -0,0 +1,164 @@
+// Package referrals runs a referral program. Every user gets a short code to share as a
+// link (/?ref=CODE); the code is remembered in a cookie until the visitor registers, and
+// the new account is attributed to the referrer. A background job qualifies referrals
+// once the referred user has confirmed their email address and grants the configured
+// credits to the referrer and, optionally, the referred user.
+package referrals
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+)
+
+// CookieName holds the code from the last referral link the visitor followed
+const CookieName = "referral"
+
+// cookieTTL is the attribution window: a visitor who registers within it is referred
+const cookieTTL = 30 * 24 * time.Hour
+
+// batchSize bounds the referrals qualified per statement
+const batchSize = 500
+
+// ReadScope lets admins read program-wide stats
+const ReadScope = "referrals:read"
+
+var (
+	signups   = metrics.NewCounter("referrals", "signups_total", "Registrations attributed to a referral code")
+	qualified = metrics.NewCounter("referrals", "qualified_total", "Referrals qualified for rewards")
+	credits   = metrics.NewCounter("referrals", "credits_total", "Referral credits granted")
+)
+
+// Options configures rewards
+type Options struct {
+	// RewardReferrer is the credits the referrer earns per qualified referral
+	RewardReferrer int
+	// RewardReferred is the credits the referred user earns; 0 disables the reward
+	RewardReferred int
+	// RequireVerified holds rewards until the referred user confirms their email address
+	RequireVerified bool
+	// Secure marks the referral cookie Secure (production)
+	Secure bool
+}
+
+// Program attributes signups and grants rewards
+type Program struct {
+	store  *Store
+	opts   Options
+	clock  clock.Clock
+	logger *logger.Logger
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewProgram creates a program backed by store; call Start to grant rewards
+func NewProgram(store *Store, opts Options, clk clock.Clock, l *logger.Logger) *Program {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Program{store: store, opts: opts, clock: clk, logger: l, stop: make(chan struct{})}
+}
+
+// Store returns the program's store
+func (p *Program) Store() *Store {
+	return p.store
+}
+
+// Capture remembers the code from a ?ref= query parameter in a cookie, so it survives
+// browsing until the visitor registers. The most recent link wins.
+func (p *Program) Capture() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if ref := c.Query("ref"); ref != "" && c.Method() == fiber.MethodGet {
+			if code := Normalize(ref); Valid(code) {
+				c.Cookie(&fiber.Cookie{
+					Name:     CookieName,
+					Value:    code,
+					Path:     "/",
+					MaxAge:   int(cookieTTL.Seconds()),
+					Secure:   p.opts.Secure,
+					HTTPOnly: true,
+					SameSite: fiber.CookieSameSiteLaxMode,
+				})
+			}
+		}
+		return c.Next()
+	}
+}
+
+// Attribute credits the new account userID to the referrer whose code was submitted with
+// the registration, or else captured in the cookie. It is best effort: a bad code or a
+// failed write never blocks the signup, and is only logged.
+func (p *Program) Attribute(c *fiber.Ctx, userID, submitted string) {
+	code := submitted
+	if code == "" {
+		code = c.Cookies(CookieName)
+	}
+	if code == "" {
+		return
+	}
+	c.Cookie(&fiber.Cookie{Name: CookieName, Path: "/", MaxAge: -1, Secure: p.opts.Secure, HTTPOnly: true})
+
+	ok, err := p.store.Attribute(c.UserContext(), userID, code)
+	if err != nil {
+		p.logger.Warn("Failed to attribute referral", zap.String("user_id", userID), zap.Error(err))
+		return
+	}
+	if ok {
+		signups.Inc()
+	}
+}
+
+// Qualify grants the rewards of every referral that qualifies now, in batches
+func (p *Program) Qualify(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, granted, err := p.store.Qualify(ctx, p.opts.RewardReferrer, p.opts.RewardReferred,
+			p.opts.RequireVerified, p.clock.Now(), batchSize)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		qualified.Add(int64(n))
+		credits.Add(int64(granted))
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// Start qualifies referrals now and then every interval until Stop
+func (p *Program) Start(interval time.Duration) {
+	safego.GoNamed("referral-rewards", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			n, err := p.Qualify(ctx)
+			cancel()
+			if err != nil {
+				p.logger.Warn("Failed to qualify referrals", zap.Error(err))
+			} else if n > 0 {
+				p.logger.Info("Referral rewards granted", zap.Int("referrals", n))
+			}
+
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background qualification
+func (p *Program) Stop() {
+	p.once.Do(func() { close(p.stop) })
+}
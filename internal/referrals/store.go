@@ -0,0 +1,270 @@
+package referrals
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// codeAlphabet is Crockford's base32, as for invite codes: no I, L, O, or U
+const codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// codeLength is the number of characters in a referral code (40 bits)
+const codeLength = 8
+
+// codeAttempts bounds retries when a generated code collides with an existing one
+const codeAttempts = 5
+
+// Stats summarizes one user's referrals
+type Stats struct {
+	// Signups is the number of accounts registered with the user's code
+	Signups int `json:"signups"`
+	// Qualified is the number of those that earned rewards
+	Qualified int `json:"qualified"`
+	// Credits is the user's total reward, as referrer and as referred user
+	Credits int `json:"credits"`
+}
+
+// Referral is one signup attributed to a referrer
+type Referral struct {
+	ID string `json:"id"`
+	// Username identifies the referred user; their email is not shown to the referrer
+	Username    string     `json:"username"`
+	CreatedAt   time.Time  `json:"created_at"`
+	QualifiedAt *time.Time `json:"qualified_at"`
+}
+
+// Referrer is a leaderboard entry
+type Referrer struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Signups   int    `json:"signups"`
+	Qualified int    `json:"qualified"`
+}
+
+// Overview is the program-wide summary for admins
+type Overview struct {
+	Codes     int        `json:"codes"`
+	Signups   int        `json:"signups"`
+	Qualified int        `json:"qualified"`
+	Credits   int        `json:"credits"`
+	Top       []Referrer `json:"top_referrers"`
+}
+
+// Store persists referral codes, attributed signups, and rewards
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new referral store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// Code returns the user's referral code, generating one on first use
+func (s *Store) Code(ctx context.Context, userID string) (string, error) {
+	var code string
+	err := s.db.QueryRowContext(ctx, `SELECT code FROM referral_codes WHERE user_id = $1`, userID).Scan(&code)
+	if err == nil {
+		return code, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to load referral code: %w", err)
+	}
+
+	for attempt := 0; attempt < codeAttempts; attempt++ {
+		candidate, err := generateCode()
+		if err != nil {
+			return "", err
+		}
+		// A concurrent request may have created the user's code first; the no-op update
+		// returns that one instead
+		err = s.db.QueryRowContext(ctx, `
+INSERT INTO referral_codes (user_id, code) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+RETURNING code`, userID, candidate).Scan(&code)
+		if database.IsUniqueViolation(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create referral code: %w", err)
+		}
+		return code, nil
+	}
+	return "", errors.New("failed to create referral code: too many collisions")
+}
+
+// Attribute records that referredID signed up with code. Unknown codes, the user's own
+// code, and users who were already referred are ignored; the result reports whether a
+// referral was recorded.
+func (s *Store) Attribute(ctx context.Context, referredID, code string) (bool, error) {
+	code = Normalize(code)
+	if !Valid(code) {
+		return false, nil
+	}
+	result, err := s.db.ExecContext(ctx, `
+INSERT INTO referrals (referrer_id, referred_id)
+SELECT user_id, $1 FROM referral_codes WHERE code = $2 AND user_id <> $1
+ON CONFLICT (referred_id) DO NOTHING`, referredID, code)
+	if err != nil {
+		return false, fmt.Errorf("failed to record referral: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Qualify marks up to limit pending referrals as qualified and grants their rewards in
+// one statement. With requireVerified, a referral qualifies once the referred user has
+// confirmed their email address. It returns the number of referrals qualified and the
+// credits granted; rows locked by another instance are skipped.
+func (s *Store) Qualify(ctx context.Context, rewardReferrer, rewardReferred int, requireVerified bool, now time.Time, limit int) (int, int, error) {
+	var qualified, credits int
+	err := s.db.QueryRowContext(ctx, `
+WITH qualified AS (
+    UPDATE referrals SET qualified_at = $1
+    WHERE id IN (
+        SELECT r.id FROM referrals r
+        JOIN users u ON u.id = r.referred_id
+        WHERE r.qualified_at IS NULL
+          AND COALESCE(u.is_active, true)
+          AND (NOT $2::boolean OR u.email_verified_at IS NOT NULL)
+        ORDER BY r.created_at
+        LIMIT $5
+        FOR UPDATE OF r SKIP LOCKED
+    )
+    RETURNING id, referrer_id, referred_id
+), rewards AS (
+    INSERT INTO referral_rewards (referral_id, user_id, credits)
+    SELECT id, referrer_id, $3::integer FROM qualified WHERE $3::integer > 0
+    UNION ALL
+    SELECT id, referred_id, $4::integer FROM qualified WHERE $4::integer > 0
+    ON CONFLICT (referral_id, user_id) DO NOTHING
+    RETURNING credits
+)
+SELECT (SELECT COUNT(*) FROM qualified), COALESCE((SELECT SUM(credits) FROM rewards), 0)`,
+		now, requireVerified, rewardReferrer, rewardReferred, limit).Scan(&qualified, &credits)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to qualify referrals: %w", err)
+	}
+	return qualified, credits, nil
+}
+
+// Stats summarizes the user's referrals and rewards
+func (s *Store) Stats(ctx context.Context, userID string) (*Stats, error) {
+	var stats Stats
+	err := s.db.QueryRowContext(ctx, `
+SELECT
+    (SELECT COUNT(*) FROM referrals WHERE referrer_id = $1),
+    (SELECT COUNT(*) FROM referrals WHERE referrer_id = $1 AND qualified_at IS NOT NULL),
+    (SELECT COALESCE(SUM(credits), 0) FROM referral_rewards WHERE user_id = $1)`, userID).
+		Scan(&stats.Signups, &stats.Qualified, &stats.Credits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load referral stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// List returns the user's most recent referrals first
+func (s *Store) List(ctx context.Context, userID string) ([]Referral, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT r.id, u.username, r.created_at, r.qualified_at
+FROM referrals r
+JOIN users u ON u.id = r.referred_id
+WHERE r.referrer_id = $1
+ORDER BY r.created_at DESC
+LIMIT 500`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load referrals: %w", err)
+	}
+	defer rows.Close()
+
+	referrals := []Referral{}
+	for rows.Next() {
+		var r Referral
+		if err := rows.Scan(&r.ID, &r.Username, &r.CreatedAt, &r.QualifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan referral: %w", err)
+		}
+		referrals = append(referrals, r)
+	}
+	return referrals, rows.Err()
+}
+
+// Overview returns program-wide totals and the top referrers by signups
+func (s *Store) Overview(ctx context.Context, top int) (*Overview, error) {
+	overview := &Overview{Top: []Referrer{}}
+	err := s.db.QueryRowContext(ctx, `
+SELECT
+    (SELECT COUNT(*) FROM referral_codes),
+    (SELECT COUNT(*) FROM referrals),
+    (SELECT COUNT(*) FROM referrals WHERE qualified_at IS NOT NULL),
+    (SELECT COALESCE(SUM(credits), 0) FROM referral_rewards)`).
+		Scan(&overview.Codes, &overview.Signups, &overview.Qualified, &overview.Credits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load referral totals: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+SELECT r.referrer_id, u.username, COUNT(*), COUNT(r.qualified_at)
+FROM referrals r
+JOIN users u ON u.id = r.referrer_id
+GROUP BY r.referrer_id, u.username
+ORDER BY COUNT(*) DESC, COUNT(r.qualified_at) DESC
+LIMIT $1`, top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load top referrers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Referrer
+		if err := rows.Scan(&r.UserID, &r.Username, &r.Signups, &r.Qualified); err != nil {
+			return nil, fmt.Errorf("failed to scan referrer: %w", err)
+		}
+		overview.Top = append(overview.Top, r)
+	}
+	return overview, rows.Err()
+}
+
+// Normalize uppercases a code and drops separators and whitespace, so links and typed
+// codes match
+func Normalize(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == ' ' || r == '\t':
+			return -1
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return r
+	}, strings.TrimSpace(code))
+}
+
+// Valid reports whether a normalized code is well formed, so junk never reaches the database
+func Valid(code string) bool {
+	if len(code) != codeLength {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(codeAlphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// generateCode returns a random code
+func generateCode() (string, error) {
+	raw := make([]byte, codeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate referral code: %w", err)
+	}
+	for i, v := range raw {
+		raw[i] = codeAlphabet[int(v)%len(codeAlphabet)]
+	}
+	return string(raw), nil
+}
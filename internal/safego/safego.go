@@ -0,0 +1,81 @@
+package safego
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+)
+
+// PanicHook is notified after a goroutine panic has been recovered
+type PanicHook func(name string, recovered interface{})
+
+var (
+	mu     sync.RWMutex
+	log    *logger.Logger
+	hooks  []PanicHook
+	panics = metrics.NewCounter("goroutines", "panics_total", "Goroutine panics recovered by safego")
+)
+
+// SetLogger sets the logger used to report recovered panics
+func SetLogger(l *logger.Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	log = l
+}
+
+// OnPanic registers a hook (e.g. a metrics counter) called for every recovered panic
+func OnPanic(hook PanicHook) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, hook)
+}
+
+// Panics returns the number of goroutine panics recovered since startup
+func Panics() int64 {
+	return panics.Value()
+}
+
+// Go runs fn in a goroutine, converting a panic into a logged error instead of
+// crashing the process
+func Go(fn func()) {
+	GoNamed("anonymous", fn)
+}
+
+// GoNamed is Go with a name that identifies the goroutine in logs and metrics
+func GoNamed(name string, fn func()) {
+	go func() {
+		defer Recover(name)
+		fn()
+	}()
+}
+
+// Recover reports a panic in the current goroutine. It must be deferred directly:
+//
+//	defer safego.Recover("worker")
+func Recover(name string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	panics.Inc()
+
+	mu.RLock()
+	l, registered := log, append([]PanicHook(nil), hooks...)
+	mu.RUnlock()
+
+	if l != nil {
+		l.Error("Recovered panic in goroutine",
+			zap.String("goroutine", name),
+			zap.String("panic", fmt.Sprint(recovered)),
+			zap.ByteString("stack", debug.Stack()),
+		)
+	}
+	for _, hook := range registered {
+		hook(name, recovered)
+	}
+}
@@ -0,0 +1,127 @@
+// Package sandbox implements test mode (SANDBOX=true): email, event deliveries, alert
+// webhooks, and newsletter syncs are logged instead of sent, and every response is
+// marked, so integrators can build against production URLs without reaching real
+// people or partners. main wraps each external destination in the types below.
+package sandbox
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/events"
+	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/metrics"
+	"main.go/internal/newsletter"
+	"main.go/internal/notify"
+)
+
+// Header marks responses served in sandbox mode
+const Header = "X-Sandbox"
+
+// localsKey is the c.Locals key set on requests served in sandbox mode
+const localsKey = "sandbox"
+
+var simulated = metrics.NewCounter("sandbox", "simulated_total", "External side effects simulated instead of performed in sandbox mode")
+
+// Middleware marks every response with X-Sandbox: true, and lets Active report the mode
+// to response helpers so JSON envelopes carry "sandbox": true
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(localsKey, true)
+		c.Set(Header, "true")
+		return c.Next()
+	}
+}
+
+// Active reports whether the request is served in sandbox mode
+func Active(c *fiber.Ctx) bool {
+	active, _ := c.Locals(localsKey).(bool)
+	return active
+}
+
+// Mailer logs messages instead of sending them
+type Mailer struct {
+	logger *logger.Logger
+}
+
+// NewMailer creates a mailer simulating delivery
+func NewMailer(l *logger.Logger) *Mailer {
+	return &Mailer{logger: l}
+}
+
+// Send implements mail.Mailer
+func (m *Mailer) Send(ctx context.Context, msg mail.Message) error {
+	simulated.Inc()
+	m.logger.Info("Mail simulated (SANDBOX)",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+	)
+	return nil
+}
+
+// Publisher logs events instead of publishing them to the destination it wraps. It keeps
+// the destination's name, so events queued in sandbox mode are not re-sent by it later.
+type Publisher struct {
+	events.Publisher
+	logger *logger.Logger
+}
+
+// NewPublisher wraps a destination to simulate its deliveries
+func NewPublisher(p events.Publisher, l *logger.Logger) *Publisher {
+	return &Publisher{Publisher: p, logger: l}
+}
+
+// Publish implements events.Publisher
+func (p *Publisher) Publish(ctx context.Context, e events.Event) error {
+	simulated.Inc()
+	p.logger.Info("Event delivery simulated (SANDBOX)",
+		zap.String("destination", p.Name()),
+		zap.String("event_id", e.ID),
+		zap.String("topic", e.Topic),
+	)
+	return nil
+}
+
+// Notifier logs notifications instead of delivering them
+type Notifier struct {
+	logger *logger.Logger
+}
+
+// NewNotifier creates a notifier simulating delivery
+func NewNotifier(l *logger.Logger) *Notifier {
+	return &Notifier{logger: l}
+}
+
+// Notify implements notify.Notifier
+func (n *Notifier) Notify(ctx context.Context, note notify.Notification) error {
+	simulated.Inc()
+	n.logger.Info("Notification simulated (SANDBOX)",
+		zap.String("title", note.Title),
+		zap.String("severity", note.Severity),
+	)
+	return nil
+}
+
+// Driver logs newsletter syncs instead of pushing them to the provider it wraps
+type Driver struct {
+	newsletter.Driver
+	logger *logger.Logger
+}
+
+// NewDriver wraps a newsletter driver to simulate its syncs
+func NewDriver(d newsletter.Driver, l *logger.Logger) *Driver {
+	return &Driver{Driver: d, logger: l}
+}
+
+// Push implements newsletter.Driver
+func (d *Driver) Push(ctx context.Context, subs []newsletter.Subscriber) error {
+	simulated.Inc()
+	d.logger.Info("Newsletter sync simulated (SANDBOX)",
+		zap.String("driver", d.Name()),
+		zap.Int("subscribers", len(subs)),
+	)
+	return nil
+}
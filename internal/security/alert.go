@@ -0,0 +1,145 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+	"main.go/internal/notify"
+	"main.go/internal/safego"
+)
+
+// DefaultThresholds is used when SECURITY_ALERT_THRESHOLDS is unset
+const DefaultThresholds = "login_failed=20/5m,csrf_failure=20/5m,rate_limited=100/5m,permission_denied=50/5m,api_key_invalid=10/5m"
+
+// Threshold raises an alert when Count events of Type occur within Window
+type Threshold struct {
+	Type   string
+	Count  int
+	Window time.Duration
+}
+
+// ParseThresholds parses "type=count/window" pairs separated by commas, e.g. "login_failed=20/5m"
+func ParseThresholds(spec string) ([]Threshold, error) {
+	var thresholds []Threshold
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		typ, rule, ok := strings.Cut(part, "=")
+		count, window, ok2 := strings.Cut(rule, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid alert threshold %q: want type=count/window", part)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid alert threshold %q: count must be a positive integer", part)
+		}
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid alert threshold %q: window must be a positive duration", part)
+		}
+		thresholds = append(thresholds, Threshold{Type: strings.TrimSpace(typ), Count: n, Window: d})
+	}
+	return thresholds, nil
+}
+
+// Alerter counts events per type in a sliding window and notifies once a threshold is
+// reached. After alerting, a type stays quiet for one window so a sustained attack
+// produces one notification per window rather than one per event.
+type Alerter struct {
+	mu         sync.Mutex
+	thresholds map[string]Threshold
+	recent     map[string][]Event
+	lastAlert  map[string]time.Time
+	notifier   notify.Notifier
+	logger     *logger.Logger
+}
+
+// NewAlerter creates an alerter that delivers through notifier
+func NewAlerter(notifier notify.Notifier, l *logger.Logger, thresholds []Threshold) *Alerter {
+	a := &Alerter{
+		thresholds: map[string]Threshold{},
+		recent:     map[string][]Event{},
+		lastAlert:  map[string]time.Time{},
+		notifier:   notifier,
+		logger:     l,
+	}
+	for _, t := range thresholds {
+		a.thresholds[t.Type] = t
+	}
+	return a
+}
+
+// Observe adds an event and sends an alert if its type crossed the threshold
+func (a *Alerter) Observe(e Event) {
+	threshold, ok := a.thresholds[e.Type]
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	cutoff := e.Time.Add(-threshold.Window)
+	events := append(a.recent[e.Type], e)
+	for len(events) > 0 && !events[0].Time.After(cutoff) {
+		events = events[1:]
+	}
+	a.recent[e.Type] = events
+
+	if len(events) < threshold.Count || e.Time.Sub(a.lastAlert[e.Type]) < threshold.Window {
+		a.mu.Unlock()
+		return
+	}
+	a.lastAlert[e.Type] = e.Time
+	n := a.notification(threshold, events)
+	a.recent[e.Type] = nil
+	a.mu.Unlock()
+
+	safego.GoNamed("security-alert", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := a.notifier.Notify(ctx, n); err != nil {
+			a.logger.Error("Failed to deliver security alert", zap.String("type", threshold.Type), zap.Error(err))
+		}
+	})
+}
+
+func (a *Alerter) notification(t Threshold, events []Event) notify.Notification {
+	perIP := map[string]int{}
+	for _, e := range events {
+		perIP[e.IP]++
+	}
+	ips := make([]string, 0, len(perIP))
+	for ip := range perIP {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool { return perIP[ips[i]] > perIP[ips[j]] })
+
+	top := make([]string, 0, 5)
+	for i, ip := range ips {
+		if i == 5 {
+			break
+		}
+		top = append(top, fmt.Sprintf("%s (%d)", ip, perIP[ip]))
+	}
+
+	return notify.Notification{
+		Title:    fmt.Sprintf("Security alert: %d %s events in %s", len(events), t.Type, t.Window),
+		Body:     fmt.Sprintf("The %s threshold of %d events per %s was reached.", t.Type, t.Count, t.Window),
+		Severity: notify.SeverityWarning,
+		Fields: map[string]string{
+			"type":    t.Type,
+			"count":   strconv.Itoa(len(events)),
+			"top_ips": strings.Join(top, ", "),
+			"last":    events[len(events)-1].Time.UTC().Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,110 @@
+// Package security records structured security events (failed logins, CSRF failures,
+// rate-limit rejections, permission denials) to a dedicated sink and raises alerts
+// through the notify package when an event type crosses its threshold.
+package security
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/clock"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+)
+
+// Event types
+const (
+	EventLoginFailed      = "login_failed"
+	EventAccountLocked    = "account_locked"
+	EventCSRFFailure      = "csrf_failure"
+	EventRateLimited      = "rate_limited"
+	EventPermissionDenied = "permission_denied"
+	EventAPIKeyInvalid    = "api_key_invalid"
+	EventWAFMatch         = "waf_match"
+	EventSignatureInvalid = "signature_invalid"
+	EventRememberMismatch = "remember_token_mismatch"
+	EventHoneypot         = "honeypot_triggered"
+	EventCaptchaFailed    = "captcha_failed"
+)
+
+// Event is a single security-relevant occurrence
+type Event struct {
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	IP        string            `json:"ip,omitempty"`
+	Method    string            `json:"method,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Sink persists security events
+type Sink interface {
+	Write(e Event) error
+}
+
+// Recorder writes events to its sinks, counts them, and feeds the alerter
+type Recorder struct {
+	logger  *logger.Logger
+	alerter *Alerter
+	sinks   []Sink
+}
+
+// NewRecorder creates a recorder; alerter may be nil to disable alerts
+func NewRecorder(l *logger.Logger, alerter *Alerter, sinks ...Sink) *Recorder {
+	return &Recorder{logger: l, alerter: alerter, sinks: sinks}
+}
+
+// Record handles a single event
+func (r *Recorder) Record(e Event) {
+	metrics.NewCounter("security", e.Type+"_total", "Security events of type "+e.Type).Inc()
+
+	for _, sink := range r.sinks {
+		if err := sink.Write(e); err != nil && r.logger != nil {
+			r.logger.Error("Failed to write security event", zap.String("type", e.Type), zap.Error(err))
+		}
+	}
+	if r.alerter != nil {
+		r.alerter.Observe(e)
+	}
+}
+
+var (
+	defaultMu       sync.RWMutex
+	defaultRecorder = NewRecorder(nil, nil)
+)
+
+// Default returns the process-wide recorder; until SetDefault is called events are only counted
+func Default() *Recorder {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultRecorder
+}
+
+// SetDefault replaces the process-wide recorder used by Emit
+func SetDefault(r *Recorder) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultRecorder = r
+}
+
+// Emit records an event of type typ for the current request
+func Emit(c *fiber.Ctx, typ string, details map[string]string) {
+	userID, _ := c.Locals("user_id").(string)
+	Default().Record(Event{
+		Type:      typ,
+		Time:      clock.Now(c),
+		IP:        c.IP(),
+		Method:    c.Method(),
+		Path:      c.Path(),
+		UserID:    userID,
+		RequestID: c.GetRespHeader(fiber.HeaderXRequestID),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Details:   details,
+	})
+}
@@ -0,0 +1,68 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/logger"
+)
+
+// FileSink appends events as JSON lines to a dedicated file
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens (or creates) path for appending
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open security log: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends e as a single JSON line
+func (s *FileSink) Write(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// LogSink writes events to the application log under the "security" logger name
+type LogSink struct {
+	logger *logger.Logger
+}
+
+// NewLogSink creates a sink backed by the application logger
+func NewLogSink(l *logger.Logger) *LogSink {
+	return &LogSink{logger: &logger.Logger{Logger: l.Named("security")}}
+}
+
+// Write logs e at warn level
+func (s *LogSink) Write(e Event) error {
+	s.logger.Warn("Security event",
+		zap.String("type", e.Type),
+		zap.String("ip", e.IP),
+		zap.String("method", e.Method),
+		zap.String("path", e.Path),
+		zap.String("user_id", e.UserID),
+		zap.String("request_id", e.RequestID),
+		zap.Any("details", e.Details),
+	)
+	return nil
+}
@@ -0,0 +1,167 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/clock"
+	"main.go/internal/security"
+)
+
+// keyIDLocal is the c.Locals key holding the verified signing key ID
+const keyIDLocal = "signing_key_id"
+
+// errNonceStore wraps nonce store failures, which are answered with 503 rather than 401
+var errNonceStore = errors.New("nonce store unavailable")
+
+// Options configures signature verification
+type Options struct {
+	// Keys maps key IDs to shared secrets; several keys allow rotation
+	Keys map[string]string
+	// MaxSkew is how far the timestamp may drift from the server clock (default 5 minutes)
+	MaxSkew time.Duration
+	// Nonces rejects replays inside the skew window; in-memory when nil
+	Nonces NonceStore
+}
+
+// Middleware verifies the HMAC signature of every request and rejects unsigned,
+// tampered, stale, or replayed requests with 401
+func Middleware(opts Options) fiber.Handler {
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = 5 * time.Minute
+	}
+	if opts.Nonces == nil {
+		opts.Nonces = NewMemoryNonceStore()
+	}
+
+	return func(c *fiber.Ctx) error {
+		keyID, err := verify(c, opts)
+		if errors.Is(err, errNonceStore) {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error":   "Service Unavailable",
+				"message": "Failed to verify request signature",
+				"status":  fiber.StatusServiceUnavailable,
+			})
+		}
+		if err != nil {
+			security.Emit(c, security.EventSignatureInvalid, map[string]string{"reason": err.Error()})
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Unauthorized",
+				"message": err.Error(),
+				"status":  fiber.StatusUnauthorized,
+			})
+		}
+
+		c.Locals(keyIDLocal, keyID)
+		return c.Next()
+	}
+}
+
+// KeyID returns the key ID that signed the current request
+func KeyID(c *fiber.Ctx) string {
+	keyID, _ := c.Locals(keyIDLocal).(string)
+	return keyID
+}
+
+func verify(c *fiber.Ctx, opts Options) (string, error) {
+	keyID, nonce, err := checkHeaders(c.Get(HeaderKeyID), c.Get(HeaderTimestamp), c.Get(HeaderNonce), c.Get(HeaderSignature),
+		c.Method(), string(c.Request().RequestURI()), c.Body(), clock.Now(c), opts)
+	if err != nil {
+		return "", err
+	}
+	if err := remember(c.UserContext(), opts, keyID, nonce); err != nil {
+		return "", err
+	}
+	return keyID, nil
+}
+
+// checkHeaders verifies the signing headers of a request, returning its key ID and nonce
+func checkHeaders(keyID, timestamp, nonce, signature, method, uri string, body []byte, now time.Time, opts Options) (string, string, error) {
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", "", ErrMissingSignature
+	}
+
+	secret, ok := opts.Keys[keyID]
+	if !ok {
+		return "", "", ErrUnknownKey
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", "", ErrStaleTimestamp
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > opts.MaxSkew || skew < -opts.MaxSkew {
+		return "", "", ErrStaleTimestamp
+	}
+
+	expected := Compute(secret, method, uri, timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", "", ErrBadSignature
+	}
+	return keyID, nonce, nil
+}
+
+// remember records a verified request's nonce, failing if it was seen before.
+// Only valid signatures consume a nonce, so forged requests cannot burn legitimate ones.
+// Nonces are remembered for twice the skew so any timestamp still accepted is covered.
+func remember(ctx context.Context, opts Options, keyID, nonce string) error {
+	fresh, err := opts.Nonces.Remember(ctx, keyID+":"+nonce, 2*opts.MaxSkew)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNonceStore, err)
+	}
+	if !fresh {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// Verify checks the signature of a request received with net/http, such as a webhook
+// from this app, and returns the key ID that signed it. It is the middleware's check for
+// receivers that do not use Fiber:
+//
+//	keyID, err := signing.Verify(r, signing.Options{Keys: map[string]string{"webhooks": secret}})
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusUnauthorized)
+//		return
+//	}
+//
+// The body is read and replaced so the handler can still read it. Replays are rejected
+// when opts.Nonces is set; without it, deduplicate by the event's id instead.
+func Verify(req *http.Request, opts Options) (string, error) {
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = 5 * time.Minute
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	keyID, nonce, err := checkHeaders(req.Header.Get(HeaderKeyID), req.Header.Get(HeaderTimestamp),
+		req.Header.Get(HeaderNonce), req.Header.Get(HeaderSignature),
+		req.Method, req.URL.RequestURI(), body, time.Now(), opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.Nonces != nil {
+		if err := remember(req.Context(), opts, keyID, nonce); err != nil {
+			return "", err
+		}
+	}
+	return keyID, nil
+}
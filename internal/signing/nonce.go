@@ -0,0 +1,67 @@
+package signing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore remembers nonces for the replay window
+type NonceStore interface {
+	// Remember records nonce and reports whether it was unseen
+	Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// RedisNonceStore shares seen nonces across instances using SET NX with an expiry
+type RedisNonceStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisNonceStore creates a Redis-backed nonce store
+func NewRedisNonceStore(client *redis.Client) *RedisNonceStore {
+	return &RedisNonceStore{client: client, prefix: "signing:nonce:"}
+}
+
+// Remember implements NonceStore
+func (s *RedisNonceStore) Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+nonce, 1, ttl).Result()
+}
+
+// MemoryNonceStore keeps nonces in process memory. It only protects a single instance,
+// so use Redis when running more than one.
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	lastGC  time.Time
+	gcEvery time.Duration
+}
+
+// NewMemoryNonceStore creates an in-memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{seen: map[string]time.Time{}, gcEvery: time.Minute}
+}
+
+// Remember implements NonceStore
+func (s *MemoryNonceStore) Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastGC) > s.gcEvery {
+		for n, expires := range s.seen {
+			if now.After(expires) {
+				delete(s.seen, n)
+			}
+		}
+		s.lastGC = now
+	}
+
+	if expires, ok := s.seen[nonce]; ok && now.Before(expires) {
+		return false, nil
+	}
+	s.seen[nonce] = now.Add(ttl)
+	return true, nil
+}
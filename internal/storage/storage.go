@@ -0,0 +1,59 @@
+// Package storage keeps file contents under opaque keys, on local disk or in S3 (or an
+// S3-compatible service). Callers decide the keys and keep their own metadata; a Storage
+// only stores bytes.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores and retrieves objects by key. Keys are slash-separated paths made of
+// letters, digits, '-', '_', and '.'.
+type Storage interface {
+	// Name identifies the driver in logs
+	Name() string
+	// Put stores size bytes from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the object under key, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// OpenRange returns length bytes of the object under key starting at offset, or
+	// ErrNotFound. The caller keeps the range within the object's size.
+	OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape the storage root or need escaping
+func validKey(key string) bool {
+	if key == "" || key[0] == '/' || key[len(key)-1] == '/' {
+		return false
+	}
+	prev := byte('/')
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9', ch == '-', ch == '_':
+		case ch == '.':
+			// No "." or ".." segments
+			if prev == '/' && (i+1 == len(key) || key[i+1] == '/' || key[i+1] == '.') {
+				return false
+			}
+		case ch == '/':
+			if prev == '/' {
+				return false
+			}
+		default:
+			return false
+		}
+		prev = ch
+	}
+	return true
+}
+
+// errInvalidKey is returned for keys validKey rejects
+var errInvalidKey = errors.New("invalid storage key")
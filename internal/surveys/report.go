@@ -0,0 +1,122 @@
+package surveys
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Report aggregates a survey's responses per question
+type Report struct {
+	SurveyID  string           `json:"survey_id"`
+	Responses int              `json:"responses"`
+	Questions []QuestionReport `json:"questions"`
+}
+
+// QuestionReport summarizes the answers to one question. Counts holds the tally per
+// rating value, option, or true/false; Average is set for ratings and Samples holds the
+// most recent text answers.
+type QuestionReport struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Label    string         `json:"label"`
+	Answered int            `json:"answered"`
+	Counts   map[string]int `json:"counts,omitempty"`
+	Average  *float64       `json:"average,omitempty"`
+	Samples  []string       `json:"samples,omitempty"`
+}
+
+// aggregator builds a report one response at a time, so responses can be streamed from
+// the database
+type aggregator struct {
+	report Report
+	sums   []float64
+}
+
+func newAggregator(s *Survey) *aggregator {
+	a := &aggregator{report: Report{SurveyID: s.ID}, sums: make([]float64, len(s.Questions))}
+	for _, q := range s.Questions {
+		qr := QuestionReport{ID: q.ID, Type: q.Type, Label: q.Label}
+		switch q.Type {
+		case TypeRating:
+			// Every point of the scale is listed, including those nobody picked
+			qr.Counts = map[string]int{}
+			low, high := q.scale()
+			for v := low; v <= high && v-low < 100; v++ {
+				qr.Counts[strconv.Itoa(v)] = 0
+			}
+		case TypeChoice, TypeMultiChoice:
+			qr.Counts = map[string]int{}
+			for _, o := range q.Options {
+				qr.Counts[o] = 0
+			}
+		case TypeBoolean:
+			qr.Counts = map[string]int{"true": 0, "false": 0}
+		}
+		a.report.Questions = append(a.report.Questions, qr)
+	}
+	return a
+}
+
+// add counts one response. Answers to questions added or retyped after the response was
+// submitted are skipped rather than miscounted.
+func (a *aggregator) add(answers map[string]interface{}) {
+	a.report.Responses++
+	for i := range a.report.Questions {
+		qr := &a.report.Questions[i]
+		answer, ok := answers[qr.ID]
+		if !ok {
+			continue
+		}
+		switch qr.Type {
+		case TypeRating:
+			v, ok := answer.(float64)
+			if !ok {
+				continue
+			}
+			a.sums[i] += v
+			qr.Counts[fmt.Sprint(v)]++
+		case TypeChoice:
+			v, ok := answer.(string)
+			if !ok {
+				continue
+			}
+			qr.Counts[v]++
+		case TypeMultiChoice:
+			picked, ok := answer.([]interface{})
+			if !ok {
+				continue
+			}
+			for _, p := range picked {
+				if v, ok := p.(string); ok {
+					qr.Counts[v]++
+				}
+			}
+		case TypeBoolean:
+			v, ok := answer.(bool)
+			if !ok {
+				continue
+			}
+			qr.Counts[strconv.FormatBool(v)]++
+		case TypeText:
+			v, ok := answer.(string)
+			if !ok || v == "" {
+				continue
+			}
+			if len(qr.Samples) < sampleSize {
+				qr.Samples = append(qr.Samples, v)
+			}
+		}
+		qr.Answered++
+	}
+}
+
+func (a *aggregator) result() *Report {
+	for i := range a.report.Questions {
+		qr := &a.report.Questions[i]
+		if qr.Type == TypeRating && qr.Answered > 0 {
+			avg := a.sums[i] / float64(qr.Answered)
+			qr.Average = &avg
+		}
+	}
+	return &a.report
+}
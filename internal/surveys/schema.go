@@ -0,0 +1,185 @@
+package surveys
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaDialect is the JSON Schema draft the generated schemas declare
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema that survey definitions compile to. It marshals to
+// a standard schema document, so clients can validate answers before submitting them
+// with any JSON Schema library, and Validate applies the same rules on the server.
+type Schema struct {
+	Dialect              string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	UniqueItems          bool               `json:"uniqueItems,omitempty"`
+}
+
+// Validate checks a decoded JSON document (as produced by encoding/json into an
+// interface{}) and returns an error message per failing location, keyed by a dotted path
+// such as "answers.rating". A nil map means the document is valid.
+func (s *Schema) Validate(doc interface{}) map[string]string {
+	errs := map[string]string{}
+	s.validate(doc, "", errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (s *Schema) validate(v interface{}, path string, errs map[string]string) {
+	if s.Type != "" && !hasType(v, s.Type) {
+		errs[field(path)] = "Must be " + article(s.Type)
+		return
+	}
+	if len(s.Enum) > 0 && !contains(s.Enum, v) {
+		errs[field(path)] = "Must be one of " + enumList(s.Enum)
+		return
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := len([]rune(v))
+		if s.MinLength != nil && n < *s.MinLength {
+			errs[field(path)] = fmt.Sprintf("Must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs[field(path)] = fmt.Sprintf("Must be at most %d characters", *s.MaxLength)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			errs[field(path)] = fmt.Sprintf("Must be at least %g", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			errs[field(path)] = fmt.Sprintf("Must be at most %g", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs[field(path)] = fmt.Sprintf("Must have at least %d items", *s.MinItems)
+			return
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs[field(path)] = fmt.Sprintf("Must have at most %d items", *s.MaxItems)
+			return
+		}
+		if s.UniqueItems {
+			for i := range v {
+				if contains(v[:i], v[i]) {
+					errs[field(path)] = "Must not repeat items"
+					return
+				}
+			}
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, join(path, fmt.Sprint(i)), errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs[join(path, name)] = "Is required"
+			}
+		}
+		for name, value := range v {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs[join(path, name)] = "Is not a known field"
+				}
+				continue
+			}
+			prop.validate(value, join(path, name), errs)
+		}
+	}
+}
+
+// hasType reports whether v is of the JSON type t
+func hasType(v interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, v := range enum {
+		b, _ := json.Marshal(v)
+		values[i] = string(b)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
+
+func article(t string) string {
+	switch t {
+	case "object", "array", "integer":
+		return "an " + t
+	case "null":
+		return "null"
+	}
+	return "a " + t
+}
+
+// field names the document itself "body" so every error has a key
+func field(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return path
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,223 @@
+package surveys
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"main.go/internal/database"
+	"main.go/internal/events"
+)
+
+// RespondedTopic is the event published with each survey response
+const RespondedTopic = "survey.responded"
+
+var (
+	// ErrNotFound is returned when a survey does not exist
+	ErrNotFound = errors.New("survey not found")
+	// ErrSlugTaken is returned when another survey already uses the slug
+	ErrSlugTaken = errors.New("survey slug is already taken")
+)
+
+// NewSurvey holds the fields of a survey definition
+type NewSurvey struct {
+	Slug        string
+	Title       string
+	Description string
+	Questions   []Question
+	Active      bool
+}
+
+// Store persists surveys and their responses
+type Store struct {
+	db *database.DB
+	// events publishes RespondedTopic when set
+	events *events.Outbox
+}
+
+// NewStore creates a new survey store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+// UseEvents publishes a RespondedTopic event, in the transaction that stores it, with
+// each response. The event carries the IDs, not the answers.
+func (s *Store) UseEvents(o *events.Outbox) {
+	s.events = o
+}
+
+const surveyColumns = `id, slug, title, description, questions, active, created_at, updated_at,
+    (SELECT COUNT(*) FROM survey_responses r WHERE r.survey_id = surveys.id)`
+
+func scanSurvey(row interface{ Scan(...interface{}) error }) (*Survey, error) {
+	var s Survey
+	var questions []byte
+	if err := row.Scan(&s.ID, &s.Slug, &s.Title, &s.Description, &questions, &s.Active,
+		&s.CreatedAt, &s.UpdatedAt, &s.Responses); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(questions, &s.Questions); err != nil {
+		return nil, fmt.Errorf("failed to decode survey questions: %w", err)
+	}
+	return &s, nil
+}
+
+// Create stores a new survey
+func (s *Store) Create(ctx context.Context, in NewSurvey) (*Survey, error) {
+	questions, err := json.Marshal(in.Questions)
+	if err != nil {
+		return nil, err
+	}
+	survey, err := scanSurvey(s.db.QueryRowContext(ctx, `
+INSERT INTO surveys (slug, title, description, questions, active)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING `+surveyColumns, in.Slug, in.Title, in.Description, questions, in.Active))
+	if database.IsUniqueViolation(err) {
+		return nil, ErrSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create survey: %w", err)
+	}
+	return survey, nil
+}
+
+// Update replaces a survey's definition. Earlier responses are kept; reports skip answers
+// to questions that were removed or changed type.
+func (s *Store) Update(ctx context.Context, id string, in NewSurvey) (*Survey, error) {
+	questions, err := json.Marshal(in.Questions)
+	if err != nil {
+		return nil, err
+	}
+	survey, err := scanSurvey(s.db.QueryRowContext(ctx, `
+UPDATE surveys SET slug = $2, title = $3, description = $4, questions = $5, active = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING `+surveyColumns, id, in.Slug, in.Title, in.Description, questions, in.Active))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if database.IsUniqueViolation(err) {
+		return nil, ErrSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update survey: %w", err)
+	}
+	return survey, nil
+}
+
+// Delete removes a survey and its responses
+func (s *Store) Delete(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM surveys WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete survey: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Find returns the survey with id
+func (s *Store) Find(ctx context.Context, id string) (*Survey, error) {
+	return s.findBy(ctx, "id", id)
+}
+
+// FindBySlug returns the survey with slug
+func (s *Store) FindBySlug(ctx context.Context, slug string) (*Survey, error) {
+	return s.findBy(ctx, "slug", slug)
+}
+
+func (s *Store) findBy(ctx context.Context, column, value string) (*Survey, error) {
+	survey, err := scanSurvey(s.db.QueryRowContext(ctx,
+		`SELECT `+surveyColumns+` FROM surveys WHERE `+column+` = $1`, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load survey: %w", err)
+	}
+	return survey, nil
+}
+
+// List returns every survey, newest first
+func (s *Store) List(ctx context.Context) ([]Survey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+surveyColumns+` FROM surveys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load surveys: %w", err)
+	}
+	defer rows.Close()
+
+	surveys := []Survey{}
+	for rows.Next() {
+		survey, err := scanSurvey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan survey: %w", err)
+		}
+		surveys = append(surveys, *survey)
+	}
+	return surveys, rows.Err()
+}
+
+// Respond stores a response; answers must already be validated against the survey's schema
+func (s *Store) Respond(ctx context.Context, surveyID, userID string, answers map[string]interface{}) error {
+	body, err := json.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	if s.events == nil {
+		if _, err := s.db.ExecContext(ctx, `
+INSERT INTO survey_responses (survey_id, user_id, answers) VALUES ($1, NULLIF($2, '')::uuid, $3)`,
+			surveyID, userID, body); err != nil {
+			return fmt.Errorf("failed to store survey response: %w", err)
+		}
+		return nil
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var id string
+		if err := tx.QueryRowContext(ctx, `
+INSERT INTO survey_responses (survey_id, user_id, answers) VALUES ($1, NULLIF($2, '')::uuid, $3)
+RETURNING id`, surveyID, userID, body).Scan(&id); err != nil {
+			return fmt.Errorf("failed to store survey response: %w", err)
+		}
+		return s.events.Enqueue(ctx, tx, RespondedTopic, "", map[string]string{
+			"survey_id":   surveyID,
+			"response_id": id,
+			"user_id":     userID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.events.Notify()
+	return nil
+}
+
+// Report aggregates every response to survey, streaming them newest first so text
+// samples are the most recent
+func (s *Store) Report(ctx context.Context, survey *Survey) (*Report, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT answers FROM survey_responses WHERE survey_id = $1 ORDER BY created_at DESC`, survey.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load survey responses: %w", err)
+	}
+	defer rows.Close()
+
+	agg := newAggregator(survey)
+	for rows.Next() {
+		var body []byte
+		if err := rows.Scan(&body); err != nil {
+			return nil, fmt.Errorf("failed to scan survey response: %w", err)
+		}
+		var answers map[string]interface{}
+		if err := json.Unmarshal(body, &answers); err != nil {
+			return nil, fmt.Errorf("failed to decode survey response: %w", err)
+		}
+		agg.add(answers)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load survey responses: %w", err)
+	}
+	return agg.result(), nil
+}
@@ -0,0 +1,177 @@
+// Package surveys defines simple surveys and feedback widgets, collects responses, and
+// aggregates them into reports. A survey's questions compile to a JSON Schema for its
+// response body, built at runtime from the stored definition: it is published with the
+// survey for client-side checks and enforced on every submitted response.
+package surveys
+
+import (
+	"fmt"
+	"time"
+)
+
+// ManageScope lets admins define surveys and read their reports
+const ManageScope = "surveys:write"
+
+// Question types
+const (
+	TypeText        = "text"
+	TypeRating      = "rating"
+	TypeChoice      = "choice"
+	TypeMultiChoice = "multi_choice"
+	TypeBoolean     = "boolean"
+)
+
+const (
+	// defaultRatingMin and defaultRatingMax are the scale of a rating without bounds
+	defaultRatingMin = 1
+	defaultRatingMax = 5
+	// defaultTextMax bounds text answers without a max
+	defaultTextMax = 2000
+	// sampleSize is how many recent text answers a report shows per question
+	sampleSize = 20
+)
+
+// Survey is a published set of questions
+type Survey struct {
+	ID          string     `json:"id"`
+	Slug        string     `json:"slug"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Questions   []Question `json:"questions"`
+	// Active surveys accept responses
+	Active    bool      `json:"active"`
+	Responses int       `json:"responses"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Question is one field of a survey. Min and Max bound a rating's scale, a text answer's
+// length, or the number of options picked in a multi_choice question.
+type Question struct {
+	ID       string   `json:"id" validate:"required,slug,max=64"`
+	Type     string   `json:"type" validate:"required,oneof=text rating choice multi_choice boolean"`
+	Label    string   `json:"label" validate:"required,max=500"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty" validate:"max=50,dive,required,max=200"`
+	Min      int      `json:"min,omitempty" validate:"min=0,max=10000"`
+	Max      int      `json:"max,omitempty" validate:"min=0,max=10000"`
+}
+
+// Check returns the field and message of the first inconsistency in a set of questions
+// that validation tags cannot express; field is empty when there is none
+func Check(questions []Question) (field, message string) {
+	seen := map[string]bool{}
+	for i, q := range questions {
+		prefix := fmt.Sprintf("questions[%d].", i)
+		if seen[q.ID] {
+			return prefix + "id", "Question IDs must be unique"
+		}
+		seen[q.ID] = true
+
+		switch q.Type {
+		case TypeChoice, TypeMultiChoice:
+			if len(q.Options) < 2 {
+				return prefix + "options", "Choice questions need at least two options"
+			}
+			options := map[string]bool{}
+			for _, o := range q.Options {
+				if options[o] {
+					return prefix + "options", "Options must be unique"
+				}
+				options[o] = true
+			}
+		default:
+			if len(q.Options) > 0 {
+				return prefix + "options", "Only choice questions have options"
+			}
+		}
+		if q.Max > 0 && q.Min > q.Max {
+			return prefix + "min", "Min must not exceed max"
+		}
+	}
+	return "", ""
+}
+
+// Schema compiles the survey into the JSON Schema for its response body:
+// {"answers": {"<question id>": <answer>, ...}}
+func (s *Survey) Schema() *Schema {
+	answers := &Schema{
+		Type:                 "object",
+		Properties:           map[string]*Schema{},
+		Required:             []string{},
+		AdditionalProperties: boolPtr(false),
+	}
+	for _, q := range s.Questions {
+		answers.Properties[q.ID] = q.schema()
+		if q.Required {
+			answers.Required = append(answers.Required, q.ID)
+		}
+	}
+	return &Schema{
+		Dialect:              SchemaDialect,
+		Title:                s.Title,
+		Description:          s.Description,
+		Type:                 "object",
+		Properties:           map[string]*Schema{"answers": answers},
+		Required:             []string{"answers"},
+		AdditionalProperties: boolPtr(false),
+	}
+}
+
+func (q *Question) schema() *Schema {
+	switch q.Type {
+	case TypeRating:
+		low, high := q.scale()
+		return &Schema{Title: q.Label, Type: "integer", Minimum: floatPtr(float64(low)), Maximum: floatPtr(float64(high))}
+	case TypeChoice:
+		return &Schema{Title: q.Label, Type: "string", Enum: q.enum()}
+	case TypeMultiChoice:
+		s := &Schema{Title: q.Label, Type: "array", Items: &Schema{Type: "string", Enum: q.enum()}, UniqueItems: true}
+		if q.Required {
+			s.MinItems = intPtr(1)
+		}
+		if q.Min > 0 {
+			s.MinItems = intPtr(q.Min)
+		}
+		if q.Max > 0 {
+			s.MaxItems = intPtr(q.Max)
+		}
+		return s
+	case TypeBoolean:
+		return &Schema{Title: q.Label, Type: "boolean"}
+	}
+	s := &Schema{Title: q.Label, Type: "string", MaxLength: intPtr(defaultTextMax)}
+	if q.Max > 0 {
+		s.MaxLength = intPtr(q.Max)
+	}
+	if q.Min > 0 {
+		s.MinLength = intPtr(q.Min)
+	} else if q.Required {
+		s.MinLength = intPtr(1)
+	}
+	return s
+}
+
+// scale returns the bounds of a rating question
+func (q *Question) scale() (int, int) {
+	low, high := defaultRatingMin, defaultRatingMax
+	if q.Min > 0 {
+		low = q.Min
+	}
+	if q.Max > 0 {
+		high = q.Max
+	}
+	return low, high
+}
+
+func (q *Question) enum() []interface{} {
+	enum := make([]interface{}, len(q.Options))
+	for i, o := range q.Options {
+		enum[i] = o
+	}
+	return enum
+}
+
+func boolPtr(b bool) *bool        { return &b }
+func intPtr(n int) *int           { return &n }
+func floatPtr(f float64) *float64 { return &f }
@@ -8,6 +8,8 @@ package components
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
+import "main.go/internal/i18n"
+
 func containsPlugin(plugins []string, target string) bool {
 	for _, candidate := range plugins {
 		if candidate == target {
@@ -44,13 +46,13 @@ func HeadMain(jsLevel string, title string) templ.Component {
 			return templ_7745c5c3_Err
 		}
 		if jsLevel == "full" || jsLevel == "alpine" {
-			templ_7745c5c3_Err = templ.Raw("<html lang=\"en\" x-data=\"{}\">").Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = templ.Raw("<html lang=\""+templ.EscapeString(i18n.FromContext(ctx))+"\" x-data=\"{}\">").Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if jsLevel != "full" && jsLevel != "alpine" {
-			templ_7745c5c3_Err = templ.Raw("<html lang=\"en\">").Render(ctx, templ_7745c5c3_Buffer)
+			templ_7745c5c3_Err = templ.Raw("<html lang=\""+templ.EscapeString(i18n.FromContext(ctx))+"\">").Render(ctx, templ_7745c5c3_Buffer)
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -62,29 +64,142 @@ func HeadMain(jsLevel string, title string) templ.Component {
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(title)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/components/head.templ`, Line: 24, Col: 16}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `head.templ`, Line: 26, Col: 16}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</title><!-- Favicon --><link rel=\"icon\" href=\"/static/favicon.ico\" type=\"image/x-icon\"><!-- Tailwind CSS --><script src=\"https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4\"></script><!-- Fonts --><link rel=\"preconnect\" href=\"https://fonts.googleapis.com\"><link rel=\"preconnect\" href=\"https://fonts.gstatic.com\" crossorigin=\"\"><link href=\"https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap\" rel=\"stylesheet\"><style>\nbody { font-family: 'Inter', sans-serif; }\n\t\t</style><!-- CDN preconnects --><link rel=\"preconnect\" href=\"https://cdn.jsdelivr.net\"><link rel=\"preconnect\" href=\"https://unpkg.com\"><link rel=\"preconnect\" href=\"https://esm.sh\"><!-- Optional Alpine + HTMX -->")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</title><meta name=\"color-scheme\" content=\"light dark\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if site.NoIndex {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<meta name=\"robots\" content=\"noindex, nofollow\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<!-- Translations of this page (internal/i18n) -->")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		for _, alt := range i18n.Alternates(ctx) {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<link rel=\"alternate\" hreflang=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var3 string
+			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(alt.HrefLang)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `head.templ`, Line: 33, Col: 48}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "\" href=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 templ.SafeURL
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL(alt.URL))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `head.templ`, Line: 33, Col: 80}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<!-- Theme: apply the saved preference (or the OS setting) before first paint -->")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = ThemeScript().Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<link rel=\"stylesheet\" href=\"/static/theme.css\"><!-- Icons and web app manifest (internal/icons) --><link rel=\"icon\" href=\"/favicon.ico\" sizes=\"48x48\"><link rel=\"icon\" href=\"/icons/favicon-32x32.png\" type=\"image/png\" sizes=\"32x32\"><link rel=\"icon\" href=\"/icons/favicon-16x16.png\" type=\"image/png\" sizes=\"16x16\"><link rel=\"apple-touch-icon\" href=\"/apple-touch-icon.png\"><link rel=\"manifest\" href=\"/manifest.webmanifest\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if site.ThemeColor != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<meta name=\"theme-color\" content=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var5 string
+			templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(site.ThemeColor)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `head.templ`, Line: 47, Col: 53}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if site.ServiceWorker {
+			templ_7745c5c3_Err = ServiceWorkerScript().Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<!-- Tailwind CSS --><script src=\"https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4\"></script><!-- Fonts --><link rel=\"preconnect\" href=\"https://fonts.googleapis.com\"><link rel=\"preconnect\" href=\"https://fonts.gstatic.com\" crossorigin=\"\"><link href=\"https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap\" rel=\"stylesheet\"><style>\nbody { font-family: 'Inter', sans-serif; }\n\t\t</style><style type=\"text/tailwindcss\">\n@custom-variant dark (&:where(.dark, .dark *));\n\t\t</style><!-- CDN preconnects --><link rel=\"preconnect\" href=\"https://cdn.jsdelivr.net\"><link rel=\"preconnect\" href=\"https://unpkg.com\"><link rel=\"preconnect\" href=\"https://esm.sh\"><!-- Optional Alpine + HTMX -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if jsLevel == "alpine" || jsLevel == "full" {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<script defer src=\"https://cdn.jsdelivr.net/npm/@imacrayon/alpine-ajax@0.12.6/dist/cdn.min.js\"></script> <script defer src=\"https://unpkg.com/alpinejs@3.13.5/dist/cdn.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<script defer src=\"https://cdn.jsdelivr.net/npm/@imacrayon/alpine-ajax@0.12.6/dist/cdn.min.js\"></script> <script defer src=\"https://unpkg.com/alpinejs@3.13.5/dist/cdn.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if jsLevel == "full" {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<script src=\"https://unpkg.com/htmx.org@1.9.12\"></script> <script src=\"https://unpkg.com/htmx.org/dist/ext/sse.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<script src=\"https://unpkg.com/htmx.org@1.9.12\"></script> <script src=\"https://unpkg.com/htmx.org/dist/ext/sse.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</head>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "</head>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// ServiceWorkerScript registers the service worker served by internal/pwa once the page has loaded
+func ServiceWorkerScript() templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var6 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var6 == nil {
+			templ_7745c5c3_Var6 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<script>\n\t\tif (\"serviceWorker\" in navigator) {\n\t\t\twindow.addEventListener(\"load\", function () {\n\t\t\t\tnavigator.serviceWorker.register(\"/sw.js\").catch(function (err) {\n\t\t\t\t\tconsole.warn(\"Service worker registration failed\", err);\n\t\t\t\t});\n\t\t\t});\n\t\t}\n\t</script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -109,107 +224,111 @@ func HeadPlugins(enabledPlugins []string) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var3 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var3 == nil {
-			templ_7745c5c3_Var3 = templ.NopComponent
+		templ_7745c5c3_Var7 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var7 == nil {
+			templ_7745c5c3_Var7 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
 		if containsPlugin(enabledPlugins, "datepicker") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/vanillajs-datepicker@1.3.3/dist/css/datepicker.min.css\"><script type=\"module\" src=\"https://cdn.skypack.dev/vanillajs-datepicker@1.3.3\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "<link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/vanillajs-datepicker@1.3.3/dist/css/datepicker.min.css\"><script type=\"module\" src=\"https://cdn.skypack.dev/vanillajs-datepicker@1.3.3\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "tus") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/@uppy/drag-drop@3/dist/style.min.css\"><script src=\"https://cdn.jsdelivr.net/npm/tus-js-client@2.5.0/tus.min.js\"></script> <script src=\"https://cdn.jsdelivr.net/npm/@uppy/core@3/+esm\"></script> <script src=\"https://cdn.jsdelivr.net/npm/@uppy/tus@3/+esm\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "<link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/@uppy/drag-drop@3/dist/style.min.css\"><script src=\"https://cdn.jsdelivr.net/npm/tus-js-client@2.5.0/tus.min.js\"></script> <script src=\"https://cdn.jsdelivr.net/npm/@uppy/core@3/+esm\"></script> <script src=\"https://cdn.jsdelivr.net/npm/@uppy/tus@3/+esm\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "sonner") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<script type=\"module\" src=\"https://esm.sh/sonner@1\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "<script type=\"module\" src=\"https://esm.sh/sonner@1\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "floating") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<script type=\"module\" src=\"https://cdn.jsdelivr.net/npm/@floating-ui/dom@1.6.3/+esm\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "<script type=\"module\" src=\"https://cdn.jsdelivr.net/npm/@floating-ui/dom@1.6.3/+esm\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "alpine-fusion") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<script src=\"https://unpkg.com/@yaireo/fuse.js@7.0.0/dist/fuse.min.js\"></script> <script defer src=\"https://unpkg.com/alpinejs-fusion@latest/dist/cdn.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "<script src=\"https://unpkg.com/@yaireo/fuse.js@7.0.0/dist/fuse.min.js\"></script> <script defer src=\"https://unpkg.com/alpinejs-fusion@latest/dist/cdn.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "sortablejs") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<script defer src=\"https://cdn.jsdelivr.net/npm/sortablejs@1.15.2/Sortable.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "<script defer src=\"https://cdn.jsdelivr.net/npm/sortablejs@1.15.2/Sortable.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "clipboard") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<script defer src=\"https://cdn.jsdelivr.net/npm/clipboard@2/dist/clipboard.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "<script defer src=\"https://cdn.jsdelivr.net/npm/clipboard@2/dist/clipboard.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "password-score") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<script src=\"https://cdn.jsdelivr.net/npm/zxcvbn@4.4.2/dist/zxcvbn.umd.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "<script src=\"https://cdn.jsdelivr.net/npm/zxcvbn@4.4.2/dist/zxcvbn.umd.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "qrcode") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<script defer src=\"https://cdn.jsdelivr.net/npm/qrcode@1.5.3/build/qrcode.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "<script defer src=\"https://cdn.jsdelivr.net/npm/qrcode@1.5.3/build/qrcode.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "prosemirror") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "<script src=\"https://cdn.jsdelivr.net/npm/prosemirror-state@1.4.3/dist/index.cjs.js\"></script> <script src=\"https://cdn.jsdelivr.net/npm/prosemirror-view@1.4.3/dist/index.cjs.js\"></script> <script src=\"https://cdn.jsdelivr.net/npm/prosemirror-model@1.22.1/dist/index.cjs.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "<script src=\"https://cdn.jsdelivr.net/npm/prosemirror-state@1.4.3/dist/index.cjs.js\"></script> <script src=\"https://cdn.jsdelivr.net/npm/prosemirror-view@1.4.3/dist/index.cjs.js\"></script> <script src=\"https://cdn.jsdelivr.net/npm/prosemirror-model@1.22.1/dist/index.cjs.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "lucide") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "<!-- Lucide static assets --> <link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/lucide-static@latest/dist/umd/lucide-static.min.css\"><link rel=\"preload\" href=\"https://cdn.jsdelivr.net/npm/lucide-static@latest/dist/icon-sprite.svg\" as=\"fetch\" crossorigin=\"anonymous\"><script>\n\t\t\tdocument.addEventListener(\"DOMContentLoaded\", function () {\n\t\t\t\tif (typeof lucide !== \"undefined\") {\n\t\t\t\t\tlucide.createIcons({\n\t\t\t\t\t\tattrs: { strokeWidth: 1.5, class: \"w-5 h-5\" },\n\t\t\t\t\t});\n\t\t\t\t}\n\t\t\t});\n\t\t</script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "<!-- Lucide static assets --> <link rel=\"stylesheet\" href=\"https://cdn.jsdelivr.net/npm/lucide-static@latest/dist/umd/lucide-static.min.css\"><link rel=\"preload\" href=\"https://cdn.jsdelivr.net/npm/lucide-static@latest/dist/icon-sprite.svg\" as=\"fetch\" crossorigin=\"anonymous\"><script>\n\t\t\tdocument.addEventListener(\"DOMContentLoaded\", function () {\n\t\t\t\tif (typeof lucide !== \"undefined\") {\n\t\t\t\t\tlucide.createIcons({\n\t\t\t\t\t\tattrs: { strokeWidth: 1.5, class: \"w-5 h-5\" },\n\t\t\t\t\t});\n\t\t\t\t}\n\t\t\t});\n\t\t</script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "htmx-ws") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "<script src=\"https://cdn.jsdelivr.net/npm/htmx-ext-ws@2.0.4\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "<script src=\"https://cdn.jsdelivr.net/npm/htmx-ext-ws@2.0.4\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "htmx-ws-json") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "<script src=\"https://cdn.jsdelivr.net/npm/htmx-json@1/dist/htmx-json.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "<script src=\"https://cdn.jsdelivr.net/npm/htmx-json@1/dist/htmx-json.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "htmx-sse") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "<script src=\"https://cdn.jsdelivr.net/npm/htmx-ext-sse@2.2.4\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "<script src=\"https://cdn.jsdelivr.net/npm/htmx-ext-sse@2.2.4\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "loading-states") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "<script src=\"https://unpkg.com/htmx-ext-loading-states@2.0.0/loading-states.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "<script src=\"https://unpkg.com/htmx-ext-loading-states@2.0.0/loading-states.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if containsPlugin(enabledPlugins, "alpine-typewriter") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "<script defer src=\"https://cdn.jsdelivr.net/npm/@marcreichel/alpine-typewriter/dist/alpine-typewriter.min.js\"></script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "<script defer src=\"https://cdn.jsdelivr.net/npm/@marcreichel/alpine-typewriter/dist/alpine-typewriter.min.js\"></script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "<!-- Console warning to prevent script pasting - executes last --><script>\n\t\t// Wait for all other scripts to load first\n\t\twindow.addEventListener('load', function() {\n\t\t\tsetTimeout(function() {\n\t\t\t\tconsole.log('%c⚠️ WARNING! ⚠️', 'color: #ff0000; font-size: 24px; font-weight: bold;');\n\t\t\t\tconsole.log('%cPasting scripts into the console can be dangerous and may compromise your account security.', 'color: #ff6600; font-size: 14px;');\n\t\t\t\tconsole.log('%cNever paste code from untrusted sources into this console.', 'color: #ff6600; font-size: 14px;');\n\t\t\t\tconsole.log('%cIf you were told to paste something here to \"get free money/credits\", \"enable a feature\" or \"fix an error\", this is a scam.', 'color: #ff6600; font-size: 14px;');\n\t\t\t\tconsole.log('%cIf you need help, please contact support through official channels.', 'color: #0066ff; font-size: 14px;');\n\t\t\t}, 1000); // Delay to ensure it runs after other scripts\n\t\t});\n\t</script>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
 		return nil
 	})
 }
@@ -231,23 +350,23 @@ func BodyStart(jsLevel string, enabledPlugins []string) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var4 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var4 == nil {
-			templ_7745c5c3_Var4 = templ.NopComponent
+		templ_7745c5c3_Var8 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var8 == nil {
+			templ_7745c5c3_Var8 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templ.Raw("<body class=\"antialiased bg-gray-50 text-gray-900\">").Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = templ.Raw("<body class=\"antialiased bg-gray-50 text-gray-900 dark:bg-gray-950 dark:text-gray-100\">").Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if containsPlugin(enabledPlugins, "sonner") {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "<div id=\"sonner-portal\"></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, "<div id=\"sonner-portal\"></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
 		if jsLevel == "full" {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "<div id=\"htmx-indicator\" class=\"fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center z-50 hidden\"><div class=\"bg-white p-4 rounded-lg shadow-xl flex items-center gap-2\"><div class=\"animate-spin rounded-full h-5 w-5 border-b-2 border-blue-500\"></div>Loading...</div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "<div id=\"htmx-indicator\" class=\"fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center z-50 hidden\"><div class=\"bg-white dark:bg-gray-900 p-4 rounded-lg shadow-xl flex items-center gap-2\"><div class=\"animate-spin rounded-full h-5 w-5 border-b-2 border-blue-500\"></div>Loading...</div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
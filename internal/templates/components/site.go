@@ -0,0 +1,19 @@
+package components
+
+// Site holds site-wide settings the layouts read; set it once at startup with Configure
+type Site struct {
+	// ThemeColor tints the browser UI, matching the web app manifest
+	ThemeColor string
+	// ServiceWorker registers /sw.js from every page (see internal/pwa)
+	ServiceWorker bool
+	// NoIndex adds a robots meta tag keeping the page out of search engines, for
+	// non-production environments
+	NoIndex bool
+}
+
+var site Site
+
+// Configure applies site-wide layout settings
+func Configure(s Site) {
+	site = s
+}
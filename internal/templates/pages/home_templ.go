@@ -18,16 +18,16 @@ type FeatureStatus struct {
 
 func badgeClass(enabled bool) string {
 	if enabled {
-		return "inline-flex items-center gap-1.5 rounded-full bg-green-100 px-2.5 py-1 text-xs font-semibold text-green-800 ring-1 ring-inset ring-green-200"
+		return "inline-flex items-center gap-1.5 rounded-full bg-green-100 dark:bg-green-900/40 px-2.5 py-1 text-xs font-semibold text-green-800 dark:text-green-300 ring-1 ring-inset ring-green-200 dark:ring-green-800"
 	}
-	return "inline-flex items-center gap-1.5 rounded-full bg-gray-100 px-2.5 py-1 text-xs font-semibold text-gray-700 ring-1 ring-inset ring-gray-200"
+	return "inline-flex items-center gap-1.5 rounded-full bg-gray-100 dark:bg-gray-800 px-2.5 py-1 text-xs font-semibold text-gray-700 dark:text-gray-300 ring-1 ring-inset ring-gray-200 dark:ring-gray-700"
 }
 
 func cardRing(enabled bool) string {
 	if enabled {
-		return "rounded-2xl border border-green-100 bg-white/70 p-5 shadow-sm ring-1 ring-green-100"
+		return "rounded-2xl border border-green-100 dark:border-green-900 bg-white/70 dark:bg-gray-900/70 p-5 shadow-sm ring-1 ring-green-100 dark:ring-green-900"
 	}
-	return "rounded-2xl border border-gray-100 bg-white/70 p-5 shadow-sm ring-1 ring-gray-100"
+	return "rounded-2xl border border-gray-100 dark:border-gray-800 bg-white/70 dark:bg-gray-900/70 p-5 shadow-sm ring-1 ring-gray-100 dark:ring-gray-800"
 }
 
 func badgeLabel(enabled bool) string {
@@ -38,7 +38,7 @@ func badgeLabel(enabled bool) string {
 }
 
 // HomePage is a polished landing page with hero, quick links, and a feature matrix.
-func HomePage(appName string, env string, featureStatuses []FeatureStatus) templ.Component {
+func HomePage(appName string, env string, featureStatuses []FeatureStatus, csrfToken string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -67,85 +67,93 @@ func HomePage(appName string, env string, featureStatuses []FeatureStatus) templ
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<nav class=\"sticky top-0 z-40 w-full border-b border-gray-200 bg-white/80 backdrop-blur\"><div class=\"mx-auto flex h-14 max-w-7xl items-center justify-between px-6\"><a href=\"/\" class=\"text-base font-semibold tracking-tight text-gray-900 hover:opacity-80\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<nav class=\"sticky top-0 z-40 w-full border-b border-gray-200 dark:border-gray-800 bg-white/80 dark:bg-gray-900/80 backdrop-blur\"><div class=\"mx-auto flex h-14 max-w-7xl items-center justify-between px-6\"><a href=\"/\" class=\"text-base font-semibold tracking-tight text-gray-900 dark:text-gray-100 hover:opacity-80\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(appName)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 39, Col: 102}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 39, Col: 121}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</a><div class=\"flex items-center gap-2\"><span class=\"rounded-full bg-gray-100 px-2.5 py-1 text-xs font-semibold text-gray-700 ring-1 ring-inset ring-gray-200\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</a><div class=\"flex items-center gap-2\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = components.ThemeSwitcher(csrfToken).Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "<span class=\"rounded-full bg-gray-100 dark:bg-gray-800 px-2.5 py-1 text-xs font-semibold text-gray-700 dark:text-gray-300 ring-1 ring-inset ring-gray-200 dark:ring-gray-700\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var3 string
 		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(env)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 41, Col: 128}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 42, Col: 183}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "</span> <a href=\"/api/v1/status\" class=\"rounded-full bg-gray-900 px-3 py-1.5 text-sm font-medium text-white hover:bg-gray-800\">JSON</a></div></div></nav><section class=\"relative overflow-hidden\"><div class=\"pointer-events-none absolute inset-0 bg-gradient-to-br from-indigo-50 via-white to-cyan-50\"></div><div class=\"relative mx-auto max-w-7xl px-6 py-16\"><div class=\"max-w-3xl\"><h1 class=\"text-4xl font-semibold tracking-tight text-gray-900 md:text-5xl\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</span> <a href=\"/api/v1/status\" class=\"rounded-full bg-gray-900 dark:bg-gray-100 px-3 py-1.5 text-sm font-medium text-white hover:bg-gray-800 dark:hover:bg-gray-200 dark:text-gray-900\">JSON</a></div></div></nav><section class=\"relative overflow-hidden\"><div class=\"pointer-events-none absolute inset-0 bg-gradient-to-br from-indigo-50 dark:from-gray-950 via-white dark:via-gray-950 to-cyan-50 dark:to-gray-900\"></div><div class=\"relative mx-auto max-w-7xl px-6 py-16\"><div class=\"max-w-3xl\"><h1 class=\"text-4xl font-semibold tracking-tight text-gray-900 dark:text-gray-100 md:text-5xl\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var4 string
 		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(appName)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 52, Col: 14}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 53, Col: 14}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " status dashboard</h1><p class=\"mt-4 text-lg leading-7 text-gray-600\">Environment-driven, feature-flagged Fiber template. Toggle database, cache, auth, mail, and more with <code class=\"rounded bg-gray-100 px-1.5 py-0.5 text-sm text-gray-800 ring-1 ring-gray-200\">FEATURE_*</code> variables—no code edits.</p><div class=\"mt-6 flex flex-wrap gap-3\"><a href=\"/health\" class=\"rounded-lg bg-gray-900 px-4 py-2 text-sm font-medium text-white hover:bg-gray-800\">/health</a> <a href=\"/ready\" class=\"rounded-lg bg-white px-4 py-2 text-sm font-medium text-gray-800 ring-1 ring-gray-200 hover:bg-gray-50\">/ready</a> <a href=\"/live\" class=\"rounded-lg bg-white px-4 py-2 text-sm font-medium text-gray-800 ring-1 ring-gray-200 hover:bg-gray-50\">/live</a> <a href=\"/api/v1\" class=\"rounded-lg bg-white px-4 py-2 text-sm font-medium text-gray-800 ring-1 ring-gray-200 hover:bg-gray-50\">/api/v1</a></div></div></div></section><main class=\"bg-gradient-to-b from-white via-white to-gray-50\"><section class=\"mx-auto flex max-w-7xl flex-col gap-8 px-6 py-10\"><div class=\"grid gap-6 md:grid-cols-3\"><article class=\"rounded-2xl border border-gray-200 bg-white p-5 shadow-sm ring-1 ring-gray-100\"><h2 class=\"text-xs font-semibold uppercase tracking-wider text-gray-500\">Runtime</h2><p class=\"mt-2 text-3xl font-semibold text-gray-900\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, " status dashboard</h1><p class=\"mt-4 text-lg leading-7 text-gray-600 dark:text-gray-300\">Environment-driven, feature-flagged Fiber template. Toggle database, cache, auth, mail, and more with <code class=\"rounded bg-gray-100 dark:bg-gray-800 px-1.5 py-0.5 text-sm text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700\">FEATURE_*</code> variables—no code edits.</p><div class=\"mt-6 flex flex-wrap gap-3\"><a href=\"/health\" class=\"rounded-lg bg-gray-900 dark:bg-gray-100 px-4 py-2 text-sm font-medium text-white hover:bg-gray-800 dark:hover:bg-gray-200 dark:text-gray-900\">/health</a> <a href=\"/ready\" class=\"rounded-lg bg-white dark:bg-gray-900 px-4 py-2 text-sm font-medium text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700 hover:bg-gray-50 dark:hover:bg-gray-800\">/ready</a> <a href=\"/live\" class=\"rounded-lg bg-white dark:bg-gray-900 px-4 py-2 text-sm font-medium text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700 hover:bg-gray-50 dark:hover:bg-gray-800\">/live</a> <a href=\"/api/v1\" class=\"rounded-lg bg-white dark:bg-gray-900 px-4 py-2 text-sm font-medium text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700 hover:bg-gray-50 dark:hover:bg-gray-800\">/api/v1</a></div></div></div></section><main class=\"bg-gradient-to-b from-white dark:from-gray-950 via-white dark:via-gray-950 to-gray-50 dark:to-gray-950\"><section class=\"mx-auto flex max-w-7xl flex-col gap-8 px-6 py-10\"><div class=\"grid gap-6 md:grid-cols-3\"><article class=\"rounded-2xl border border-gray-200 dark:border-gray-800 bg-white dark:bg-gray-900 p-5 shadow-sm ring-1 ring-gray-100 dark:ring-gray-800\"><h2 class=\"text-xs font-semibold uppercase tracking-wider text-gray-500 dark:text-gray-400\">Runtime</h2><p class=\"mt-2 text-3xl font-semibold text-gray-900 dark:text-gray-100\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var5 string
 		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(env)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 74, Col: 63}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 75, Col: 82}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</p><p class=\"mt-2 text-sm leading-6 text-gray-600\">Switch <code class=\"rounded bg-gray-100 px-1.5 py-0.5 text-gray-800 ring-1 ring-gray-200\">.env</code> presets to flip features without code deploys.</p></article><article class=\"rounded-2xl border border-gray-200 bg-white p-5 shadow-sm ring-1 ring-gray-100\"><h2 class=\"text-xs font-semibold uppercase tracking-wider text-gray-500\">Health endpoints</h2><ul class=\"mt-3 space-y-2 text-sm text-blue-600\"><li><a class=\"hover:underline\" href=\"/health\">GET /health</a></li><li><a class=\"hover:underline\" href=\"/ready\">GET /ready</a></li><li><a class=\"hover:underline\" href=\"/live\">GET /live</a></li></ul></article><article class=\"rounded-2xl border border-gray-200 bg-white p-5 shadow-sm ring-1 ring-gray-100\"><h2 class=\"text-xs font-semibold uppercase tracking-wider text-gray-500\">API</h2><p class=\"mt-2 text-sm text-gray-600\">Use <code class=\"rounded bg-gray-100 px-1.5 py-0.5 text-gray-800 ring-1 ring-gray-200\">/api/v1</code> ")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</p><p class=\"mt-2 text-sm leading-6 text-gray-600 dark:text-gray-300\">Switch <code class=\"rounded bg-gray-100 dark:bg-gray-800 px-1.5 py-0.5 text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700\">.env</code> presets to flip features without code deploys.</p></article><article class=\"rounded-2xl border border-gray-200 dark:border-gray-800 bg-white dark:bg-gray-900 p-5 shadow-sm ring-1 ring-gray-100 dark:ring-gray-800\"><h2 class=\"text-xs font-semibold uppercase tracking-wider text-gray-500 dark:text-gray-400\">Health endpoints</h2><ul class=\"mt-3 space-y-2 text-sm text-blue-600 dark:text-blue-400\"><li><a class=\"hover:underline\" href=\"/health\">GET /health</a></li><li><a class=\"hover:underline\" href=\"/ready\">GET /ready</a></li><li><a class=\"hover:underline\" href=\"/live\">GET /live</a></li></ul></article><article class=\"rounded-2xl border border-gray-200 dark:border-gray-800 bg-white dark:bg-gray-900 p-5 shadow-sm ring-1 ring-gray-100 dark:ring-gray-800\"><h2 class=\"text-xs font-semibold uppercase tracking-wider text-gray-500 dark:text-gray-400\">API</h2><p class=\"mt-2 text-sm text-gray-600 dark:text-gray-300\">Use <code class=\"rounded bg-gray-100 dark:bg-gray-800 px-1.5 py-0.5 text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700\">/api/v1</code> ")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var6 string
 		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs("for")
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 92, Col: 114}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 93, Col: 169}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, " JSON responses.</p><p class=\"mt-3 text-xs uppercase tracking-wide text-gray-400\">Routes</p><ul class=\"mt-2 space-y-1 text-sm text-gray-700\"><li><code>/api/v1/</code> · welcome</li><li><code>/api/v1/status</code> · feature matrix (JSON)</li></ul></article></div><section class=\"rounded-3xl border border-gray-200 bg-white p-6 shadow-sm ring-1 ring-gray-100\"><div class=\"flex flex-col gap-2 md:flex-row md:items-center md:justify-between\"><div><h2 class=\"text-xl font-semibold text-gray-900\">Feature toggles</h2><p class=\"text-sm text-gray-600\">Backed by <code class=\"rounded bg-gray-100 px-1.5 py-0.5 text-gray-800 ring-1 ring-gray-200\">FEATURE_*</code> flags in your \".env\".</p></div><span class=\"rounded-full bg-gray-100 px-3 py-1 text-xs font-semibold text-gray-700 ring-1 ring-inset ring-gray-200\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, " JSON responses.</p><p class=\"mt-3 text-xs uppercase tracking-wide text-gray-400 dark:text-gray-500\">Routes</p><ul class=\"mt-2 space-y-1 text-sm text-gray-700 dark:text-gray-300\"><li><code>/api/v1/</code> · welcome</li><li><code>/api/v1/status</code> · feature matrix (JSON)</li></ul></article></div><section class=\"rounded-3xl border border-gray-200 dark:border-gray-800 bg-white dark:bg-gray-900 p-6 shadow-sm ring-1 ring-gray-100 dark:ring-gray-800\"><div class=\"flex flex-col gap-2 md:flex-row md:items-center md:justify-between\"><div><h2 class=\"text-xl font-semibold text-gray-900 dark:text-gray-100\">Feature toggles</h2><p class=\"text-sm text-gray-600 dark:text-gray-300\">Backed by <code class=\"rounded bg-gray-100 dark:bg-gray-800 px-1.5 py-0.5 text-gray-800 dark:text-gray-200 ring-1 ring-gray-200 dark:ring-gray-700\">FEATURE_*</code> flags in your \".env\".</p></div><span class=\"rounded-full bg-gray-100 dark:bg-gray-800 px-3 py-1 text-xs font-semibold text-gray-700 dark:text-gray-300 ring-1 ring-inset ring-gray-200 dark:ring-gray-700\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var7 string
 		templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(len(featureStatuses))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 111, Col: 28}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 112, Col: 28}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, " modules</span></div><div class=\"mt-6 grid gap-4 md:grid-cols-2 lg:grid-cols-3\">")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, " modules</span></div><div class=\"mt-6 grid gap-4 md:grid-cols-2 lg:grid-cols-3\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -155,7 +163,7 @@ func HomePage(appName string, env string, featureStatuses []FeatureStatus) templ
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "<div class=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<div class=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -168,33 +176,33 @@ func HomePage(appName string, env string, featureStatuses []FeatureStatus) templ
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "\"><div class=\"flex items-start justify-between\"><div class=\"pr-3\"><p class=\"text-sm font-semibold text-gray-900\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "\"><div class=\"flex items-start justify-between\"><div class=\"pr-3\"><p class=\"text-sm font-semibold text-gray-900 dark:text-gray-100\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			var templ_7745c5c3_Var10 string
 			templ_7745c5c3_Var10, templ_7745c5c3_Err = templ.JoinStringErrs(feature.Label)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 120, Col: 71}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 121, Col: 90}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var10))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</p><p class=\"mt-1 text-xs leading-5 text-gray-500\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</p><p class=\"mt-1 text-xs leading-5 text-gray-500 dark:text-gray-400\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			var templ_7745c5c3_Var11 string
 			templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(feature.Description)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 121, Col: 78}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 122, Col: 97}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "</p></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "</p></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -203,7 +211,7 @@ func HomePage(appName string, env string, featureStatuses []FeatureStatus) templ
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<span class=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "<span class=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -216,38 +224,38 @@ func HomePage(appName string, env string, featureStatuses []FeatureStatus) templ
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\">")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "\">")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 			var templ_7745c5c3_Var14 string
 			templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(badgeLabel(feature.Enabled))
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 124, Col: 38}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 125, Col: 38}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "</span></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</span></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "</div></section></section></main><footer class=\"border-t border-gray-200 bg-white/80 backdrop-blur\"><div class=\"mx-auto flex max-w-7xl items-center justify-between px-6 py-6\"><p class=\"text-sm text-gray-500\">© ")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "</div></section></section></main><footer class=\"border-t border-gray-200 dark:border-gray-800 bg-white/80 dark:bg-gray-900/80 backdrop-blur\"><div class=\"mx-auto flex max-w-7xl items-center justify-between px-6 py-6\"><p class=\"text-sm text-gray-500 dark:text-gray-400\">© ")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var15 string
 		templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(appName)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 136, Col: 48}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/templates/pages/home.templ`, Line: 137, Col: 67}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, " · Powered by Fiber, Templ, and Tailwind</p><div class=\"flex items-center gap-3 text-sm\"><a class=\"text-gray-600 hover:text-gray-900\" href=\"/api/v1/status\">Status JSON</a> <a class=\"text-gray-600 hover:text-gray-900\" href=\"/health\">Health</a> <a class=\"text-gray-600 hover:text-gray-900\" href=\"/ready\">Ready</a> <a class=\"text-gray-600 hover:text-gray-900\" href=\"/live\">Live</a></div></div></footer>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, " · Powered by Fiber, Templ, and Tailwind</p><div class=\"flex items-center gap-3 text-sm\"><a class=\"text-gray-600 dark:text-gray-300 hover:text-gray-900 dark:hover:text-white\" href=\"/api/v1/status\">Status JSON</a> <a class=\"text-gray-600 dark:text-gray-300 hover:text-gray-900 dark:hover:text-white\" href=\"/health\">Health</a> <a class=\"text-gray-600 dark:text-gray-300 hover:text-gray-900 dark:hover:text-white\" href=\"/ready\">Ready</a> <a class=\"text-gray-600 dark:text-gray-300 hover:text-gray-900 dark:hover:text-white\" href=\"/live\">Live</a></div></div></footer>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
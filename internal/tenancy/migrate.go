@@ -0,0 +1,45 @@
+package tenancy
+
+import (
+	"context"
+	"fmt"
+
+	pq "github.com/lib/pq"
+
+	"main.go/internal/database"
+	"main.go/internal/database/migrate"
+)
+
+// Migrate creates a tenant's schema when it is missing and applies the pending
+// migrations to it. The migrations run with the tenant's search_path, so unqualified
+// names create and alter the tenant's tables, and the tenant's own schema_migrations
+// records what was applied.
+func Migrate(ctx context.Context, db *database.DB, t *Tenant, migrations []migrate.Migration, opts migrate.Options) ([]migrate.Migration, error) {
+	if !database.ValidSchemaName(t.Schema) {
+		return nil, fmt.Errorf("tenant %s has an invalid schema name %q", t.Slug, t.Schema)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+pq.QuoteIdentifier(t.Schema)); err != nil {
+		return nil, fmt.Errorf("failed to create schema for tenant %s: %w", t.Slug, err)
+	}
+	return migrate.NewRunner(db).Up(Context(ctx, t, ModeSchema), migrations, opts)
+}
+
+// MigrateAll applies the pending migrations to every tenant's schema, calling applied
+// with each tenant's migrations. It stops at the first tenant that fails.
+func MigrateAll(ctx context.Context, db *database.DB, migrations []migrate.Migration, opts migrate.Options,
+	applied func(t Tenant, done []migrate.Migration)) error {
+	tenants, err := NewStore(db).List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range tenants {
+		done, err := Migrate(ctx, db, &t, migrations, opts)
+		if applied != nil {
+			applied(t, done)
+		}
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", t.Slug, err)
+		}
+	}
+	return nil
+}
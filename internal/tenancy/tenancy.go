@@ -0,0 +1,266 @@
+// Package tenancy serves several tenants from one deployment. A middleware resolves the
+// tenant of each request, from a header or a subdomain, and attaches it to the request
+// context; the database layer then runs every query made with that context for the
+// tenant. In schema mode each tenant has its own schema, put ahead of public on the
+// search_path, and migrations are applied to every tenant schema. In row mode tenants
+// share the tables and Postgres row-level security policies separate them by
+// app_current_tenant().
+package tenancy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/database"
+	"main.go/internal/utils"
+)
+
+// Modes (TENANCY_MODE)
+const (
+	ModeSchema = "schema"
+	ModeRow    = "row"
+)
+
+// Resolvers (TENANCY_RESOLVER)
+const (
+	ResolverHeader    = "header"
+	ResolverSubdomain = "subdomain"
+)
+
+// SchemaPrefix starts the name of every tenant schema
+const SchemaPrefix = "tenant_"
+
+var (
+	// ErrNotFound is returned when a tenant does not exist
+	ErrNotFound = errors.New("tenant not found")
+	// ErrExists is returned when creating a tenant whose slug is taken
+	ErrExists = errors.New("tenant already exists")
+	// ErrInvalidSlug is returned for slugs that cannot name a subdomain and a schema
+	ErrInvalidSlug = errors.New("tenant slugs are 1-40 lowercase letters, digits, and hyphens, starting with a letter")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,39}$`)
+
+// reservedSlug is never a tenant, so www.<domain> serves the untenanted site
+const reservedSlug = "www"
+
+// Tenant is a row in the tenants table
+type Tenant struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	// Schema holds the tenant's tables in schema mode
+	Schema    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SchemaFor returns the schema name of the tenant with slug
+func SchemaFor(slug string) string {
+	return SchemaPrefix + strings.ReplaceAll(slug, "-", "_")
+}
+
+// Store persists tenants. The tenants table lives in the public schema whatever the mode.
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new tenant store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const tenantColumns = `id, slug, name, schema_name, created_at`
+
+func scanTenant(row interface{ Scan(...interface{}) error }) (*Tenant, error) {
+	var t Tenant
+	if err := row.Scan(&t.ID, &t.Slug, &t.Name, &t.Schema, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create records a tenant; its schema is created by Migrate
+func (s *Store) Create(ctx context.Context, slug, name string) (*Tenant, error) {
+	if !slugPattern.MatchString(slug) || slug == reservedSlug {
+		return nil, ErrInvalidSlug
+	}
+	t, err := scanTenant(s.db.QueryRowContext(ctx, `
+INSERT INTO public.tenants (slug, name, schema_name) VALUES ($1, $2, $3)
+RETURNING `+tenantColumns, slug, name, SchemaFor(slug)))
+	if database.IsUniqueViolation(err) {
+		return nil, ErrExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return t, nil
+}
+
+// FindBySlug returns the tenant with slug
+func (s *Store) FindBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	t, err := scanTenant(s.db.QueryRowContext(ctx, `SELECT `+tenantColumns+` FROM public.tenants WHERE slug = $1`, slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant: %w", err)
+	}
+	return t, nil
+}
+
+// List returns every tenant, oldest first
+func (s *Store) List(ctx context.Context) ([]Tenant, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+tenantColumns+` FROM public.tenants ORDER BY created_at, slug`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, *t)
+	}
+	return tenants, rows.Err()
+}
+
+// Options configures how requests are assigned to tenants
+type Options struct {
+	// Mode is ModeSchema or ModeRow
+	Mode string
+	// Resolver is ResolverHeader or ResolverSubdomain
+	Resolver string
+	// Header carries the tenant slug with ResolverHeader
+	Header string
+	// Domain is the parent domain of tenant subdomains with ResolverSubdomain, e.g.
+	// example.com for acme.example.com
+	Domain string
+	// Required rejects requests that name no tenant instead of serving them untenanted
+	Required bool
+	// CacheTTL is how long a resolved tenant is reused before it is looked up again
+	CacheTTL time.Duration
+}
+
+// localsKey stores the request's tenant in fiber locals
+const localsKey = "tenancy.tenant"
+
+// Middleware resolves each request's tenant and attaches it to the request context, so
+// queries made with c.UserContext() run for it. A slug that names no tenant gets 404.
+func Middleware(store *Store, opts Options) fiber.Handler {
+	cache := &cache{ttl: opts.CacheTTL, entries: map[string]cacheEntry{}}
+	return func(c *fiber.Ctx) error {
+		slug := identify(c, opts)
+		if slug == "" {
+			if opts.Required {
+				return utils.NotFound(c, "Tenant not found")
+			}
+			return c.Next()
+		}
+
+		if !slugPattern.MatchString(slug) {
+			return utils.NotFound(c, "Tenant not found")
+		}
+
+		tenant, ok := cache.get(slug)
+		if !ok {
+			found, err := store.FindBySlug(c.UserContext(), slug)
+			if errors.Is(err, ErrNotFound) {
+				return utils.NotFound(c, "Tenant not found")
+			}
+			if err != nil {
+				return utils.InternalServerError(c, "Failed to load tenant")
+			}
+			tenant = found
+			cache.put(slug, tenant)
+		}
+
+		c.Locals(localsKey, tenant)
+		c.SetUserContext(database.ContextWithTenant(c.UserContext(), dbTenant(tenant, opts.Mode)))
+		return c.Next()
+	}
+}
+
+// FromContext returns the request's tenant, if it has one
+func FromContext(c *fiber.Ctx) (*Tenant, bool) {
+	t, ok := c.Locals(localsKey).(*Tenant)
+	return t, ok
+}
+
+// Context returns ctx running queries for t, e.g. for background work started on a
+// tenant's behalf
+func Context(ctx context.Context, t *Tenant, mode string) context.Context {
+	return database.ContextWithTenant(ctx, dbTenant(t, mode))
+}
+
+func dbTenant(t *Tenant, mode string) database.Tenant {
+	if mode == ModeSchema {
+		return database.Tenant{ID: t.ID, Schema: t.Schema}
+	}
+	return database.Tenant{ID: t.ID}
+}
+
+// identify returns the slug the request names, or "" when it names none
+func identify(c *fiber.Ctx, opts Options) string {
+	var slug string
+	switch opts.Resolver {
+	case ResolverSubdomain:
+		host := strings.ToLower(c.Hostname())
+		if i := strings.LastIndexByte(host, ':'); i >= 0 {
+			host = host[:i]
+		}
+		label, ok := strings.CutSuffix(host, "."+strings.ToLower(opts.Domain))
+		if !ok || strings.Contains(label, ".") {
+			return ""
+		}
+		slug = label
+	default:
+		slug = strings.ToLower(strings.TrimSpace(c.Get(opts.Header)))
+	}
+	if slug == reservedSlug {
+		return ""
+	}
+	return slug
+}
+
+type cacheEntry struct {
+	tenant  *Tenant
+	expires time.Time
+}
+
+// cache keeps resolved tenants; unknown slugs are not cached, so it only grows with the
+// tenants that exist
+type cache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func (c *cache) get(slug string) (*Tenant, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[slug]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.tenant, true
+}
+
+func (c *cache) put(slug string, t *Tenant) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[slug] = cacheEntry{tenant: t, expires: time.Now().Add(c.ttl)}
+}
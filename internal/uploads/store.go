@@ -0,0 +1,157 @@
+package uploads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// ErrNotFound is returned when an upload does not exist
+var ErrNotFound = errors.New("upload not found")
+
+// Store persists uploads and the chunks they have received
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new upload store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const uploadColumns = `id, resource_type, resource_id, filename, caption, size, received,
+    COALESCE(created_by::text, ''), expires_at, COALESCE(attachment_id::text, ''), created_at`
+
+func scanUpload(row interface{ Scan(...interface{}) error }) (*Upload, error) {
+	var u Upload
+	if err := row.Scan(&u.ID, &u.ResourceType, &u.ResourceID, &u.Filename, &u.Caption, &u.Size, &u.Offset,
+		&u.CreatedBy, &u.ExpiresAt, &u.AttachmentID, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Create records a new upload
+func (s *Store) Create(ctx context.Context, u *Upload, now time.Time) (*Upload, error) {
+	created, err := scanUpload(s.db.QueryRowContext(ctx, `
+INSERT INTO uploads (id, resource_type, resource_id, filename, caption, size, created_by, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::uuid, $8, $9)
+RETURNING `+uploadColumns,
+		u.ID, u.ResourceType, u.ResourceID, u.Filename, u.Caption, u.Size, u.CreatedBy, u.ExpiresAt, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	return created, nil
+}
+
+// Find returns the upload with id
+func (s *Store) Find(ctx context.Context, id string) (*Upload, error) {
+	u, err := scanUpload(s.db.QueryRowContext(ctx, `SELECT `+uploadColumns+` FROM uploads WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload: %w", err)
+	}
+	return u, nil
+}
+
+// AddPart records the chunk stored under key and advances the upload's offset past it.
+// It returns ErrOffsetMismatch when another chunk was recorded at offset first.
+func (s *Store) AddPart(ctx context.Context, id string, offset, size int64, key string) (*Upload, error) {
+	var updated *Upload
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		u, err := scanUpload(tx.QueryRowContext(ctx, `
+UPDATE uploads SET received = received + $3
+WHERE id = $1 AND received = $2 AND attachment_id IS NULL
+RETURNING `+uploadColumns, id, offset, size))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOffsetMismatch
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record upload chunk: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO upload_parts (upload_id, byte_offset, size, storage_key) VALUES ($1, $2, $3, $4)`,
+			id, offset, size, key); err != nil {
+			return fmt.Errorf("failed to record upload chunk: %w", err)
+		}
+		updated = u
+		return nil
+	})
+	return updated, err
+}
+
+// Parts returns the storage keys of an upload's chunks in order
+func (s *Store) Parts(ctx context.Context, id string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT storage_key FROM upload_parts WHERE upload_id = $1 ORDER BY byte_offset`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan upload chunk: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Finish records the attachment a completed upload became. It returns ErrCompleted when
+// the upload was already finished.
+func (s *Store) Finish(ctx context.Context, id, attachmentID string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE uploads SET attachment_id = $2 WHERE id = $1 AND attachment_id IS NULL`, id, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCompleted
+	}
+	return nil
+}
+
+// DeleteParts forgets the chunks of an upload once their objects are deleted
+func (s *Store) DeleteParts(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM upload_parts WHERE upload_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete upload chunks: %w", err)
+	}
+	return nil
+}
+
+// Expired returns up to limit uploads that expired before cutoff
+func (s *Store) Expired(ctx context.Context, cutoff time.Time, limit int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM uploads WHERE expires_at < $1 ORDER BY expires_at LIMIT $2`, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expired uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Delete removes an upload and the record of its chunks
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM uploads WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
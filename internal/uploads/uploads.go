@@ -0,0 +1,370 @@
+// Package uploads accepts files too large for a single request. A client declares the
+// file, sends it in chunks at increasing offsets, and completes the upload, which attaches
+// the assembled file through the attachments manager with its usual sniffing, scanning,
+// and permissions. Each chunk is stored as its own object until then, so uploads work the
+// same on local disk and S3, and an interrupted upload resumes from the offset the server
+// reports. The HTTP layer speaks either the app's JSON API or the tus protocol; a
+// background job deletes uploads, and their chunks, once they expire.
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"main.go/internal/attachments"
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/safego"
+	"main.go/internal/storage"
+)
+
+const (
+	// cleanupBatch bounds the expired uploads deleted per query
+	cleanupBatch = 100
+	// cleanupGrace delays the deletion of expired uploads, so a completion that started
+	// just before expiry can finish reading the chunks
+	cleanupGrace = time.Hour
+)
+
+var (
+	// ErrTooLarge is returned for uploads over the size limit
+	ErrTooLarge = errors.New("upload is too large")
+	// ErrChunkTooLarge is returned for chunks over the chunk size limit or past the declared size
+	ErrChunkTooLarge = errors.New("chunk is too large")
+	// ErrOffsetMismatch is returned for chunks that do not continue at the upload's offset
+	ErrOffsetMismatch = errors.New("chunk does not start at the upload offset")
+	// ErrIncomplete is returned when completing an upload that has not received every byte
+	ErrIncomplete = errors.New("upload is incomplete")
+	// ErrCompleted is returned for chunks sent to an upload that was already completed
+	ErrCompleted = errors.New("upload is already complete")
+	// ErrExpired is returned for uploads past their expiry
+	ErrExpired = errors.New("upload has expired")
+)
+
+var (
+	created   = metrics.NewCounter("uploads", "created_total", "Chunked uploads started")
+	chunks    = metrics.NewCounter("uploads", "chunks_total", "Chunks received")
+	completed = metrics.NewCounter("uploads", "completed_total", "Chunked uploads attached")
+	expired   = metrics.NewCounter("uploads", "expired_total", "Expired or abandoned uploads deleted")
+)
+
+// Upload is a file being received in chunks
+type Upload struct {
+	ID           string `json:"id"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	Filename     string `json:"filename"`
+	Caption      string `json:"caption"`
+	Size         int64  `json:"size"`
+	// Offset is how many bytes have been received; the next chunk starts there
+	Offset    int64     `json:"offset"`
+	CreatedBy string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// AttachmentID is set once the upload is complete
+	AttachmentID string    `json:"attachment_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Options bounds uploads
+type Options struct {
+	// MaxBytes bounds the size of an upload
+	MaxBytes int64
+	// ChunkBytes bounds the size of one chunk
+	ChunkBytes int64
+	// TTL is how long an upload may take from its start
+	TTL time.Duration
+	// AllowedTypes lists the content types accepted, as in attachments.Options
+	AllowedTypes []string
+}
+
+// Manager receives chunked uploads and attaches them when complete
+type Manager struct {
+	store       *Store
+	attachments *attachments.Manager
+	opts        Options
+	clock       clock.Clock
+	logger      *logger.Logger
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// New creates a manager attaching completed uploads through m; chunks are kept in m's storage
+func New(store *Store, m *attachments.Manager, opts Options, clk clock.Clock, l *logger.Logger) *Manager {
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &Manager{store: store, attachments: m, opts: opts, clock: clk, logger: l, stop: make(chan struct{})}
+}
+
+// Store returns the manager's store
+func (m *Manager) Store() *Store {
+	return m.store
+}
+
+// MaxBytes returns the largest upload accepted
+func (m *Manager) MaxBytes() int64 {
+	return m.opts.MaxBytes
+}
+
+// ChunkBytes returns the largest chunk accepted
+func (m *Manager) ChunkBytes() int64 {
+	return m.opts.ChunkBytes
+}
+
+// CanWrite reports whether the request may upload files to a resource
+func (m *Manager) CanWrite(c *fiber.Ctx, resourceType, resourceID string) bool {
+	return m.attachments.CanWrite(c, resourceType, resourceID)
+}
+
+// Create starts an upload of size bytes for a resource
+func (m *Manager) Create(ctx context.Context, u Upload) (*Upload, error) {
+	if _, ok := m.attachments.Type(u.ResourceType); !ok {
+		return nil, attachments.ErrUnknownType
+	}
+	if m.opts.MaxBytes > 0 && u.Size > m.opts.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	now := m.clock.Now()
+	u.ID = ids.Default().NewID()
+	u.Offset = 0
+	u.ExpiresAt = now.Add(m.opts.TTL)
+	upload, err := m.store.Create(ctx, &u, now)
+	if err != nil {
+		return nil, err
+	}
+	created.Inc()
+	return upload, nil
+}
+
+// Append stores length bytes from r as the chunk at offset and returns the updated
+// upload. The chunk must start at the upload's current offset; an empty chunk changes
+// nothing.
+func (m *Manager) Append(ctx context.Context, u *Upload, offset int64, r io.Reader, length int64) (*Upload, error) {
+	switch {
+	case u.AttachmentID != "":
+		return nil, ErrCompleted
+	case m.Expired(u):
+		return nil, ErrExpired
+	case offset != u.Offset:
+		return nil, ErrOffsetMismatch
+	case length > m.opts.ChunkBytes || offset+length > u.Size:
+		return nil, ErrChunkTooLarge
+	case length == 0:
+		return u, nil
+	}
+
+	// Parts get unique keys, so a chunk sent twice at once cannot overwrite the one recorded
+	key := "uploads/" + u.ID + "/" + ids.Default().NewID()
+	if err := m.storage().Put(ctx, key, io.LimitReader(r, length), length, "application/octet-stream"); err != nil {
+		return nil, err
+	}
+	updated, err := m.store.AddPart(ctx, u.ID, offset, length, key)
+	if err != nil {
+		if delErr := m.storage().Delete(context.WithoutCancel(ctx), key); delErr != nil {
+			m.logger.Warn("Failed to delete unrecorded upload chunk", zap.String("key", key), zap.Error(delErr))
+		}
+		return nil, err
+	}
+	chunks.Inc()
+	return updated, nil
+}
+
+// Complete attaches a fully received upload and deletes its chunks. Completing an upload
+// twice returns ErrCompleted.
+func (m *Manager) Complete(ctx context.Context, u *Upload) (*attachments.Attachment, error) {
+	if u.AttachmentID != "" {
+		return nil, ErrCompleted
+	}
+	if m.Expired(u) {
+		return nil, ErrExpired
+	}
+	if u.Offset != u.Size {
+		return nil, ErrIncomplete
+	}
+	parts, err := m.store.Parts(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &partReader{ctx: ctx, storage: m.storage(), keys: parts}
+	attachment, err := m.attachments.Attach(ctx, u.ResourceType, u.ResourceID, attachments.Upload{
+		Filename:     u.Filename,
+		Size:         u.Size,
+		Body:         body,
+		Caption:      u.Caption,
+		UploadedBy:   u.CreatedBy,
+		MaxBytes:     m.opts.MaxBytes,
+		AllowedTypes: m.opts.AllowedTypes,
+	})
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.store.Finish(ctx, u.ID, attachment.ID); err != nil {
+		// Another request completed it first; keep only its attachment
+		if rmErr := m.attachments.Remove(context.WithoutCancel(ctx), attachment.ID); rmErr != nil {
+			m.logger.Warn("Failed to remove duplicate upload attachment", zap.String("id", attachment.ID), zap.Error(rmErr))
+		}
+		return nil, err
+	}
+	completed.Inc()
+	m.deleteParts(ctx, u.ID, parts)
+	return attachment, nil
+}
+
+// Expired reports whether an upload can no longer receive chunks or be completed
+func (m *Manager) Expired(u *Upload) bool {
+	return !m.clock.Now().Before(u.ExpiresAt)
+}
+
+// Abort deletes an upload that has not been completed, with its chunks
+func (m *Manager) Abort(ctx context.Context, u *Upload) error {
+	if u.AttachmentID != "" {
+		return ErrCompleted
+	}
+	return m.delete(ctx, u.ID)
+}
+
+// delete removes an upload's chunks and then the upload. A chunk that fails to delete
+// keeps the upload, so the cleanup job tries again.
+func (m *Manager) delete(ctx context.Context, id string) error {
+	keys, err := m.store.Parts(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := m.storage().Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return m.store.Delete(ctx, id)
+}
+
+// Cleanup deletes uploads that expired, completed or not, with any chunks left, and
+// returns how many were deleted
+func (m *Manager) Cleanup(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		ids, err := m.store.Expired(ctx, m.clock.Now().Add(-cleanupGrace), cleanupBatch)
+		if err != nil {
+			return total, err
+		}
+		deleted := 0
+		for _, id := range ids {
+			if err := m.delete(ctx, id); err != nil {
+				m.logger.Warn("Failed to delete expired upload", zap.String("id", id), zap.Error(err))
+				continue
+			}
+			deleted++
+		}
+		total += deleted
+		expired.Add(int64(deleted))
+		// A batch with failures would be selected again straight away
+		if len(ids) < cleanupBatch || deleted < len(ids) {
+			return total, nil
+		}
+	}
+}
+
+// Start deletes expired uploads now and then every interval until Stop
+func (m *Manager) Start(interval time.Duration) {
+	safego.GoNamed("upload-cleanup", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// One replica cleans up at a time; the others skip the round
+			var n int
+			_, err := m.store.db.WithTryAdvisoryLock(ctx, "upload-cleanup", func(ctx context.Context) (err error) {
+				n, err = m.Cleanup(ctx)
+				return err
+			})
+			cancel()
+			if err != nil {
+				m.logger.Warn("Failed to clean up uploads", zap.Error(err))
+			} else if n > 0 {
+				m.logger.Info("Expired uploads deleted", zap.Int("uploads", n))
+			}
+
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop ends background cleanup
+func (m *Manager) Stop() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// deleteParts removes the chunks of a completed upload. Failures are only logged: the
+// chunks are no longer read, and the cleanup job deletes them when the upload expires.
+func (m *Manager) deleteParts(ctx context.Context, id string, keys []string) {
+	for _, key := range keys {
+		if err := m.storage().Delete(ctx, key); err != nil {
+			m.logger.Warn("Failed to delete upload chunk", zap.String("key", key), zap.Error(err))
+			return
+		}
+	}
+	if err := m.store.DeleteParts(ctx, id); err != nil {
+		m.logger.Warn("Failed to forget upload chunks", zap.String("upload", id), zap.Error(err))
+	}
+}
+
+func (m *Manager) storage() storage.Storage {
+	return m.attachments.Storage()
+}
+
+// partReader reads stored chunks one after another, opening each only when it is reached
+type partReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *partReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			body, err := r.storage.Open(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("failed to read upload chunk: %w", err)
+			}
+			r.current, r.keys = body, r.keys[1:]
+		}
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *partReader) Close() {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
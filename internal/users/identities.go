@@ -0,0 +1,137 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"main.go/internal/ids"
+	"main.go/internal/password"
+)
+
+// ErrUnverifiedEmail is returned when an external account's email matches an existing
+// user but the provider has not verified it, so linking could hijack the account
+var ErrUnverifiedEmail = errors.New("external account email is not verified")
+
+// ErrUnverifiedAccount is returned when an external account's email matches an existing
+// user who has not verified it. Whoever registered the address may not own it, and
+// linking would leave their password able to sign in to the provider's account.
+var ErrUnverifiedAccount = errors.New("existing account email is not verified")
+
+// ErrAccountDisabled is returned when the external account belongs to a deactivated user
+var ErrAccountDisabled = errors.New("account disabled")
+
+// ErrEmailRequired is returned when an external account exposes no email address
+var ErrEmailRequired = errors.New("external account has no email address")
+
+// ExternalIdentity is an account at an OAuth2 provider
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// FindOrCreateExternal returns the user linked to the external identity. Unknown identities
+// are linked to the user with the same email when both sides have verified it, or a new
+// user is created. Deactivated users are refused with ErrAccountDisabled.
+func (s *Store) FindOrCreateExternal(ctx context.Context, ext ExternalIdentity) (*User, error) {
+	var user *User
+
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var linkedID string
+		err := tx.QueryRowContext(ctx, `
+UPDATE user_identities SET last_login_at = NOW()
+WHERE provider = $1 AND subject = $2
+RETURNING user_id`, ext.Provider, ext.Subject).Scan(&linkedID)
+		if err == nil {
+			user, err = scanUser(tx.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, linkedID))
+			if err == nil && !user.IsActive {
+				return ErrAccountDisabled
+			}
+			return err
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to load %s identity: %w", ext.Provider, err)
+		}
+
+		if strings.TrimSpace(ext.Email) == "" {
+			return ErrEmailRequired
+		}
+
+		user, err = scanUser(tx.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER($1)`, ext.Email))
+		switch {
+		case err == nil && !user.IsActive:
+			return ErrAccountDisabled
+		case err == nil && !ext.EmailVerified:
+			return ErrUnverifiedEmail
+		case err == nil && !user.EmailVerified():
+			return ErrUnverifiedAccount
+		case errors.Is(err, ErrUserNotFound):
+			user, err = createExternal(ctx, tx, ext)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4)`, user.ID, ext.Provider, ext.Subject, ext.Email)
+		if err != nil {
+			return fmt.Errorf("failed to link %s identity: %w", ext.Provider, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createExternal inserts a user for an external identity. The password is random, so the
+// account can only sign in through the provider until a password is set.
+func createExternal(ctx context.Context, tx *sql.Tx, ext ExternalIdentity) (*User, error) {
+	hash, err := password.Default().Hash(ids.Default().RandomString(32))
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	firstName, lastName := splitName(ext.Name)
+	return scanUser(tx.QueryRowContext(ctx, `
+INSERT INTO users (email, username, first_name, last_name, password_hash, email_verified_at)
+VALUES ($1, $2, $3, $4, $5, CASE WHEN $6 THEN NOW() END)
+RETURNING `+userColumns,
+		ext.Email, externalUsername(ext.Email), firstName, lastName, hash, ext.EmailVerified))
+}
+
+// externalUsername derives a unique username from the email's local part
+func externalUsername(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	base := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-') {
+			return r
+		}
+		return -1
+	}, local)
+	if len(base) > 20 {
+		base = base[:20]
+	}
+	if base == "" {
+		base = "user"
+	}
+	return base + "-" + strings.ToLower(ids.Default().RandomString(6))
+}
+
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	if first == "" {
+		first = "User"
+	}
+	return first, strings.TrimSpace(last)
+}
@@ -0,0 +1,88 @@
+package users
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/auth"
+	"main.go/internal/security"
+)
+
+// LoadCurrentUser loads the account of the authenticated caller so handlers can use
+// auth.CurrentUser[users.User](c). It must run after authentication; anonymous callers
+// get 401 and machine principals (API keys), which have no account, pass through.
+func LoadCurrentUser(store *Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return auth.Required()(c)
+		}
+		if principal.IsMachine() {
+			return c.Next()
+		}
+		if _, err := loadCurrentUser(c, store, principal); err != nil {
+			return userLoadError(c, err)
+		}
+		return c.Next()
+	}
+}
+
+// RequireVerified rejects callers whose email address is not confirmed with 403.
+// It must run after authentication; anonymous callers get 401 and machine
+// principals (API keys), which have no email, pass through. The loaded account is
+// stored like LoadCurrentUser does.
+func RequireVerified(store *Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return auth.Required()(c)
+		}
+		if principal.IsMachine() {
+			return c.Next()
+		}
+
+		user, err := loadCurrentUser(c, store, principal)
+		if err != nil {
+			return userLoadError(c, err)
+		}
+
+		if !user.EmailVerified() {
+			security.Emit(c, security.EventPermissionDenied, map[string]string{"reason": "email not verified"})
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":   "Forbidden",
+				"message": "Confirm your email address to continue",
+				"status":  fiber.StatusForbidden,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// loadCurrentUser reuses an account already loaded for this request
+func loadCurrentUser(c *fiber.Ctx, store *Store, principal *auth.Principal) (*User, error) {
+	if user, ok := auth.CurrentUser[User](c); ok {
+		return user, nil
+	}
+	user, err := store.FindByID(c.UserContext(), principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	auth.SetCurrentUser(c, user)
+	return user, nil
+}
+
+func userLoadError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, ErrUserNotFound) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":   "Unauthorized",
+			"message": "Authentication required",
+			"status":  fiber.StatusUnauthorized,
+		})
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error":   "Service Unavailable",
+		"message": "Failed to load account",
+		"status":  fiber.StatusServiceUnavailable,
+	})
+}
@@ -0,0 +1,244 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"main.go/internal/auth"
+	"main.go/internal/database"
+	"main.go/internal/password"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches a lookup
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when the email or username is already registered
+	ErrUserExists = errors.New("user already exists")
+)
+
+// dummyHash is compared against when a login does not exist so unknown and known
+// users take the same time to reject; it is created on first use with the configured parameters
+var (
+	dummyOnce sync.Once
+	dummyHash string
+)
+
+// User is a row in the users table
+type User struct {
+	ID           string `json:"id"`
+	Email        string `json:"email"`
+	Username     string `json:"username"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	PasswordHash string `json:"-"`
+	IsActive     bool   `json:"is_active"`
+	Role         string `json:"role"`
+	// EmailVerifiedAt is nil until the user confirms their email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// EmailVerified reports whether the user has confirmed their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Principal converts the user into an auth principal
+func (u *User) Principal() *auth.Principal {
+	return &auth.Principal{ID: u.ID, Email: u.Email, Role: u.Role}
+}
+
+// NewUser holds the fields needed to register a user; Password is plaintext and hashed by Create
+type NewUser struct {
+	Email     string
+	Username  string
+	FirstName string
+	LastName  string
+	Password  string
+}
+
+// ProfileUpdate holds the profile fields a user may change; nil fields are left as they are
+type ProfileUpdate struct {
+	Username  *string
+	FirstName *string
+	LastName  *string
+}
+
+// Store persists users
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new user store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const userColumns = `id, email, username, first_name, last_name, password_hash, COALESCE(is_active, true), COALESCE(role, 'user'), email_verified_at, created_at, updated_at`
+
+// Create hashes the password with argon2id and inserts the user
+func (s *Store) Create(ctx context.Context, nu NewUser) (*User, error) {
+	hash, err := password.Default().Hash(nu.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+INSERT INTO users (email, username, first_name, last_name, password_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING `+userColumns,
+		strings.TrimSpace(nu.Email), strings.TrimSpace(nu.Username),
+		strings.TrimSpace(nu.FirstName), strings.TrimSpace(nu.LastName), hash)
+
+	user, err := scanUser(row)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrUserExists
+	}
+	return user, err
+}
+
+// FindByLogin looks a user up by email (case-insensitive) or username
+func (s *Store) FindByLogin(ctx context.Context, login string) (*User, error) {
+	row := s.db.QueryRowContext(ctx, `
+SELECT `+userColumns+`
+FROM users
+WHERE LOWER(email) = LOWER($1) OR username = $1
+LIMIT 1`, strings.TrimSpace(login))
+
+	return scanUser(row)
+}
+
+// FindByID loads a user by primary key
+func (s *Store) FindByID(ctx context.Context, id string) (*User, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+	return scanUser(row)
+}
+
+// SetPassword replaces the user's password with a new argon2id hash
+func (s *Store) SetPassword(ctx context.Context, id, plaintext string) error {
+	hash, err := password.Default().Hash(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`, hash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateProfile applies the non-nil fields of update and returns the updated user
+func (s *Store) UpdateProfile(ctx context.Context, id string, update ProfileUpdate) (*User, error) {
+	row := s.db.QueryRowContext(ctx, `
+UPDATE users SET
+    username = COALESCE($2, username),
+    first_name = COALESCE($3, first_name),
+    last_name = COALESCE($4, last_name),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING `+userColumns,
+		id, trimmed(update.Username), trimmed(update.FirstName), trimmed(update.LastName))
+
+	user, err := scanUser(row)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrUserExists
+	}
+	return user, err
+}
+
+// VerifyPassword reports whether plaintext is the user's current password
+func (s *Store) VerifyPassword(user *User, plaintext string) bool {
+	match, _, err := password.Default().Verify(plaintext, user.PasswordHash)
+	return err == nil && match
+}
+
+// Delete removes the user. Identities and remembered devices are removed by their
+// foreign keys; policy acceptances, which are keyed by plain user ID, are removed here.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM policy_acceptances WHERE user_id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete policy acceptances: %w", err)
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// MarkEmailVerified records that the user confirmed their email address. It is a no-op
+// for users who are already verified.
+func (s *Store) MarkEmailVerified(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, `
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// CheckCredentials implements auth.CredentialChecker. Hashes using bcrypt or outdated
+// argon2id parameters are upgraded transparently after a successful login.
+func (s *Store) CheckCredentials(ctx context.Context, login, plaintext string) (*auth.Principal, error) {
+	hasher := password.Default()
+
+	user, err := s.FindByLogin(ctx, login)
+	if errors.Is(err, ErrUserNotFound) {
+		dummyOnce.Do(func() { dummyHash, _ = hasher.Hash("dummy-password") })
+		_, _, _ = hasher.Verify(plaintext, dummyHash)
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	match, needsRehash, err := hasher.Verify(plaintext, user.PasswordHash)
+	if err != nil || !match || !user.IsActive {
+		return nil, auth.ErrInvalidCredentials
+	}
+	if needsRehash {
+		// Best effort: a failed upgrade is retried on the next login
+		_ = s.SetPassword(ctx, user.ID, plaintext)
+	}
+	return user.Principal(), nil
+}
+
+// trimmed returns nil for nil and the trimmed value otherwise
+func trimmed(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	v := strings.TrimSpace(*value)
+	return &v
+}
+
+func scanUser(row *sql.Row) (*User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Email, &u.Username, &u.FirstName, &u.LastName, &u.PasswordHash,
+		&u.IsActive, &u.Role, &u.EmailVerifiedAt, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &u, nil
+}
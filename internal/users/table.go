@@ -0,0 +1,36 @@
+package users
+
+import (
+	"context"
+
+	"main.go/internal/database/datatable"
+)
+
+// ReadScope lets admins list every account
+const ReadScope = "users:read"
+
+// Resource names the admin user list among saved views
+const Resource = "users"
+
+// AdminTable is the admin user list; password hashes are never selectable
+var AdminTable = datatable.Table{
+	From: "users",
+	Key:  "id",
+	Columns: []datatable.Column{
+		{Name: "id", SQL: "id"},
+		{Name: "email", SQL: "email", Searchable: true, Sortable: true},
+		{Name: "username", SQL: "username", Searchable: true, Sortable: true},
+		{Name: "first_name", SQL: "first_name", Searchable: true, Sortable: true},
+		{Name: "last_name", SQL: "last_name", Searchable: true, Sortable: true},
+		{Name: "role", SQL: "COALESCE(role, 'user')", Searchable: true, Sortable: true},
+		{Name: "is_active", SQL: "COALESCE(is_active, true)", Sortable: true},
+		{Name: "email_verified_at", SQL: "email_verified_at", Sortable: true},
+		{Name: "created_at", SQL: "created_at", Sortable: true},
+	},
+	DefaultSort: []datatable.Sort{{Column: "created_at", Desc: true}},
+}
+
+// Table returns a page of the admin user list
+func (s *Store) Table(ctx context.Context, req datatable.Request) (*datatable.Result, error) {
+	return AdminTable.Query(ctx, s.db, req)
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrUnknownField is returned by SelectFields for fields the data does not offer
+var ErrUnknownField = errors.New("unknown field")
+
+// FieldsFromQuery returns the fields asked for with ?fields=id,name,created_at, or nil
+// when the request asks for every field
+func FieldsFromQuery(c *fiber.Ctx) []string {
+	var fields []string
+	for _, f := range strings.Split(c.Query("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// SelectFields trims data, a struct or a slice of structs (or pointers to them), to the
+// JSON fields named, as JSON:API sparse fieldsets do. Any field with a JSON name may be
+// asked for; struct tags refine that:
+//
+//	type Project struct {
+//		ID       string `json:"id" fields:"always"` // returned whatever is asked for
+//		Name     string `json:"name"`
+//		Internal string `json:"internal" fields:"-"` // never selectable, so never returned
+//	}
+//
+// A struct wrapping a list, such as pagination.Page, tags the list `fields:"items"`: its
+// elements are trimmed and its other fields are kept. Other data is returned unchanged.
+func SelectFields(data interface{}, fields []string) (interface{}, error) {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return data, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if marshalsItself(v.Type()) {
+			return data, nil
+		}
+		set := fieldSetOf(v.Type())
+		if set.items != nil {
+			wrapper, err := toObject(data)
+			if err != nil {
+				return nil, err
+			}
+			items, err := SelectFields(v.FieldByIndex(set.items).Interface(), fields)
+			if err != nil {
+				return nil, err
+			}
+			if wrapper[set.itemsName], err = json.Marshal(items); err != nil {
+				return nil, err
+			}
+			return wrapper, nil
+		}
+		if err := set.check(fields); err != nil {
+			return nil, err
+		}
+		obj, err := toObject(data)
+		if err != nil {
+			return nil, err
+		}
+		return set.trim(obj, fields), nil
+
+	case reflect.Slice, reflect.Array:
+		elem := v.Type().Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct || marshalsItself(elem) {
+			return data, nil
+		}
+		set := fieldSetOf(elem)
+		if err := set.check(fields); err != nil {
+			return nil, err
+		}
+		out := make([]map[string]json.RawMessage, v.Len())
+		for i := range out {
+			obj, err := toObject(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			out[i] = set.trim(obj, fields)
+		}
+		return out, nil
+	}
+	return data, nil
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// marshalsItself reports whether t has its own JSON form, such as time.Time or ids.UUID,
+// which need not be an object with fields
+func marshalsItself(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return t.Implements(jsonMarshaler) || pt.Implements(jsonMarshaler) ||
+		t.Implements(textMarshaler) || pt.Implements(textMarshaler)
+}
+
+// toObject marshals v and reads it back as its top-level JSON fields, so custom
+// MarshalJSON methods and omitempty apply as they do to a full response
+func toObject(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// fieldSet is what a struct type offers to SelectFields
+type fieldSet struct {
+	selectable map[string]bool
+	always     []string
+	// items is the index of the field tagged `fields:"items"`, if any
+	items     []int
+	itemsName string
+}
+
+var fieldSets sync.Map // reflect.Type -> *fieldSet
+
+func fieldSetOf(t reflect.Type) *fieldSet {
+	if set, ok := fieldSets.Load(t); ok {
+		return set.(*fieldSet)
+	}
+	set := &fieldSet{selectable: map[string]bool{}}
+	set.add(t, nil)
+	fieldSets.Store(t, set)
+	return set
+}
+
+func (s *fieldSet) add(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		// Fields of embedded structs are promoted, as encoding/json does
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.add(ft, fieldIndex)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		switch f.Tag.Get("fields") {
+		case "-":
+			continue
+		case "always":
+			s.always = append(s.always, name)
+		case "items":
+			if f.Type.Kind() == reflect.Slice && len(index) == 0 {
+				s.items, s.itemsName = fieldIndex, name
+			}
+		}
+		s.selectable[name] = true
+	}
+}
+
+// check rejects fields the struct does not offer
+func (s *fieldSet) check(fields []string) error {
+	var unknown []string
+	for _, f := range fields {
+		if !s.selectable[f] {
+			unknown = append(unknown, f)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("%w: %s", ErrUnknownField, strings.Join(unknown, ", "))
+}
+
+// trim keeps the asked-for and always-returned fields of obj
+func (s *fieldSet) trim(obj map[string]json.RawMessage, fields []string) map[string]json.RawMessage {
+	if obj == nil {
+		return nil
+	}
+	keep := make(map[string]json.RawMessage, len(fields)+len(s.always))
+	for _, names := range [][]string{fields, s.always} {
+		for _, f := range names {
+			if value, ok := obj[f]; ok {
+				keep[f] = value
+			}
+		}
+	}
+	return keep
+}
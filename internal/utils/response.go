@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"errors"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/utils"
+
+	"main.go/internal/clock"
+	"main.go/internal/ids"
+	"main.go/internal/sandbox"
 )
 
 // Response represents a standard API response
@@ -16,67 +21,136 @@ type Response struct {
 	Error     string      `json:"error,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 	RequestID string      `json:"request_id,omitempty"`
+	// Sandbox is set in sandbox mode, where external side effects are simulated
+	Sandbox bool `json:"sandbox,omitempty"`
+}
+
+// responsePool recycles envelopes: c.JSON serializes one before returning, so it is not
+// referenced once the response is written
+var responsePool = sync.Pool{New: func() interface{} { return new(Response) }}
+
+// acquireResponse returns a pooled envelope stamped with the request's time and ID
+func acquireResponse(c *fiber.Ctx, success bool, message string) *Response {
+	r := responsePool.Get().(*Response)
+	r.Success = success
+	r.Message = message
+	r.Timestamp = clock.Now(c)
+	r.RequestID = c.Get("X-Request-ID")
+	r.Sandbox = sandbox.Active(c)
+	return r
+}
+
+// sendResponse writes r as the response body and returns it to the pool
+func sendResponse(c *fiber.Ctx, r *Response) error {
+	err := c.JSON(r)
+	*r = Response{}
+	responsePool.Put(r)
+	return err
 }
 
-// SuccessResponse creates a success response
+// SuccessResponse creates a success response. Requests with ?fields= get data trimmed to
+// those fields (see SelectFields), or 400 when it does not offer one of them.
 func SuccessResponse(c *fiber.Ctx, data interface{}, message string) error {
-	return c.JSON(Response{
-		Success:   true,
-		Message:   message,
-		Data:      data,
-		Timestamp: time.Now(),
-		RequestID: c.Get("X-Request-ID"),
-	})
+	if fields := FieldsFromQuery(c); fields != nil {
+		selected, err := SelectFields(data, fields)
+		if errors.Is(err, ErrUnknownField) {
+			return BadRequest(c, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		data = selected
+	}
+	r := acquireResponse(c, true, message)
+	r.Data = data
+	return sendResponse(c, r)
+}
+
+// JSON creates a success response with another status than 200, such as 201 Created or
+// 202 Accepted
+func JSON(c *fiber.Ctx, statusCode int, data interface{}, message string) error {
+	r := acquireResponse(c, true, message)
+	r.Data = data
+	c.Status(statusCode)
+	return sendResponse(c, r)
 }
 
 // ErrorResponse creates an error response
 func ErrorResponse(c *fiber.Ctx, statusCode int, message string, err error) error {
-	return c.Status(statusCode).JSON(Response{
-		Success:   false,
-		Message:   message,
-		Error:     err.Error(),
-		Timestamp: time.Now(),
-		RequestID: c.Get("X-Request-ID"),
-	})
+	return errorResponse(c, statusCode, message, err.Error())
+}
+
+func errorResponse(c *fiber.Ctx, statusCode int, message, errText string) error {
+	r := acquireResponse(c, false, message)
+	r.Error = errText
+	c.Status(statusCode)
+	return sendResponse(c, r)
 }
 
 // BadRequest creates a bad request response
 func BadRequest(c *fiber.Ctx, message string) error {
-	return ErrorResponse(c, http.StatusBadRequest, message, fiber.NewError(http.StatusBadRequest, message))
+	return errorResponse(c, http.StatusBadRequest, message, message)
 }
 
 // Unauthorized creates an unauthorized response
 func Unauthorized(c *fiber.Ctx, message string) error {
-	return ErrorResponse(c, http.StatusUnauthorized, message, fiber.NewError(http.StatusUnauthorized, message))
+	return errorResponse(c, http.StatusUnauthorized, message, message)
 }
 
 // Forbidden creates a forbidden response
 func Forbidden(c *fiber.Ctx, message string) error {
-	return ErrorResponse(c, http.StatusForbidden, message, fiber.NewError(http.StatusForbidden, message))
+	return errorResponse(c, http.StatusForbidden, message, message)
 }
 
 // NotFound creates a not found response
 func NotFound(c *fiber.Ctx, message string) error {
-	return ErrorResponse(c, http.StatusNotFound, message, fiber.NewError(http.StatusNotFound, message))
+	return errorResponse(c, http.StatusNotFound, message, message)
 }
 
 // InternalServerError creates an internal server error response
 func InternalServerError(c *fiber.Ctx, message string) error {
-	return ErrorResponse(c, http.StatusInternalServerError, message, fiber.NewError(http.StatusInternalServerError, message))
+	return errorResponse(c, http.StatusInternalServerError, message, message)
+}
+
+// InvalidBody responds 400 to a request body that could not be parsed, with the parser's
+// error in details, so a client of a strict route learns which key or value was rejected
+func InvalidBody(c *fiber.Ctx, err error) error {
+	return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+		"error":   "Invalid request body",
+		"message": "Failed to parse request body",
+		"details": err.Error(),
+	})
 }
 
+// validationFailure is the body ValidationError sends
+type validationFailure struct {
+	Success   bool              `json:"success"`
+	Message   string            `json:"message"`
+	Errors    map[string]string `json:"errors"`
+	Timestamp time.Time         `json:"timestamp"`
+	RequestID string            `json:"request_id"`
+	Sandbox   bool              `json:"sandbox,omitempty"`
+}
+
+var validationFailurePool = sync.Pool{New: func() interface{} { return new(validationFailure) }}
+
 // ValidationError creates a validation error response
 func ValidationError(c *fiber.Ctx, errors map[string]string) error {
-	return c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
-		"success":    false,
-		"message":    "Validation failed",
-		"errors":     errors,
-		"timestamp":  time.Now(),
-		"request_id": c.Get("X-Request-ID"),
-	})
+	body := validationFailurePool.Get().(*validationFailure)
+	*body = validationFailure{
+		Message:   "Validation failed",
+		Errors:    errors,
+		Timestamp: clock.Now(c),
+		RequestID: c.Get("X-Request-ID"),
+		Sandbox:   sandbox.Active(c),
+	}
+	err := c.Status(http.StatusUnprocessableEntity).JSON(body)
+	*body = validationFailure{}
+	validationFailurePool.Put(body)
+	return err
 }
 
-// GenerateRandomString generates a random string of the specified length
+// GenerateRandomString generates a random identifier using the default ID generator
 func GenerateRandomString() string {
-	return utils.UUIDv4()
+	return ids.Default().NewID()
 }
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"main.go/internal/clock"
+)
+
+// The Unpooled benchmarks build responses as these helpers did before envelopes were
+// pooled, so both variants can be compared with:
+//
+//	go test ./internal/utils -run '^$' -bench . -benchmem
+
+// benchCtx returns a request context reused across iterations, so only the response
+// helpers' own allocations are measured
+func benchCtx(b *testing.B) *fiber.Ctx {
+	b.Helper()
+	app := fiber.New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	b.Cleanup(func() { app.ReleaseCtx(c) })
+	return c
+}
+
+var benchErrors = map[string]string{
+	"email":    "Email must be a valid email address",
+	"password": "Password must be at least 8 characters",
+}
+
+func BenchmarkSuccessResponse(b *testing.B) {
+	c := benchCtx(b)
+	data := map[string]string{"id": "42"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = SuccessResponse(c, data, "OK")
+	}
+}
+
+func BenchmarkSuccessResponseUnpooled(b *testing.B) {
+	c := benchCtx(b)
+	data := map[string]string{"id": "42"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.JSON(Response{
+			Success:   true,
+			Message:   "OK",
+			Data:      data,
+			Timestamp: clock.Now(c),
+			RequestID: c.Get("X-Request-ID"),
+		})
+	}
+}
+
+func BenchmarkNotFound(b *testing.B) {
+	c := benchCtx(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = NotFound(c, "User not found")
+	}
+}
+
+func BenchmarkNotFoundUnpooled(b *testing.B) {
+	c := benchCtx(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := fiber.NewError(http.StatusNotFound, "User not found")
+		_ = c.Status(http.StatusNotFound).JSON(Response{
+			Message:   "User not found",
+			Error:     err.Error(),
+			Timestamp: clock.Now(c),
+			RequestID: c.Get("X-Request-ID"),
+		})
+	}
+}
+
+func BenchmarkValidationError(b *testing.B) {
+	c := benchCtx(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ValidationError(c, benchErrors)
+	}
+}
+
+func BenchmarkValidationErrorUnpooled(b *testing.B) {
+	c := benchCtx(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+			"success":    false,
+			"message":    "Validation failed",
+			"errors":     benchErrors,
+			"timestamp":  clock.Now(c),
+			"request_id": c.Get("X-Request-ID"),
+		})
+	}
+}
+
+func BenchmarkValidationErrorBuilder(b *testing.B) {
+	c := benchCtx(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = NewValidationErrorBuilder().WithDetails(benchErrors).Send(c)
+	}
+}
+
+func BenchmarkValidationErrorBuilderUnpooled(b *testing.B) {
+	c := benchCtx(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp := &ValidationErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed",
+			Details: benchErrors,
+			Status:  fiber.StatusUnprocessableEntity,
+		}
+		_ = c.Status(resp.Status).JSON(resp)
+	}
+}
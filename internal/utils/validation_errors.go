@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 
@@ -17,20 +18,29 @@ type ValidationErrorResponse struct {
 	Status  int               `json:"status"`
 }
 
-// ValidationErrorBuilder helps build consistent validation error responses
+// ValidationErrorBuilder helps build consistent validation error responses. Builders are
+// pooled: one must not be used after Send.
 type ValidationErrorBuilder struct {
-	response *ValidationErrorResponse
+	response ValidationErrorResponse
+	// general holds the detail of errors that are not validation errors, kept for reuse
+	general map[string]string
+	// built is set once Build hands the response out, so the builder is not recycled
+	built bool
 }
 
+var builderPool = sync.Pool{New: func() interface{} {
+	return &ValidationErrorBuilder{general: make(map[string]string, 1)}
+}}
+
 // NewValidationErrorBuilder creates a new validation error builder
 func NewValidationErrorBuilder() *ValidationErrorBuilder {
-	return &ValidationErrorBuilder{
-		response: &ValidationErrorResponse{
-			Error:   "Validation failed",
-			Message: "Request validation failed",
-			Status:  fiber.StatusUnprocessableEntity,
-		},
+	b := builderPool.Get().(*ValidationErrorBuilder)
+	b.response = ValidationErrorResponse{
+		Error:   "Validation failed",
+		Message: "Request validation failed",
+		Status:  fiber.StatusUnprocessableEntity,
 	}
+	return b
 }
 
 // WithError sets the error type
@@ -62,21 +72,27 @@ func (b *ValidationErrorBuilder) WithValidationErrors(err error) *ValidationErro
 	if validationErrors, ok := err.(*validation.ValidationErrors); ok {
 		b.response.Details = validationErrors.GetAllErrors()
 	} else {
-		b.response.Details = map[string]string{
-			"general": err.Error(),
-		}
+		clear(b.general)
+		b.general["general"] = err.Error()
+		b.response.Details = b.general
 	}
 	return b
 }
 
 // Build creates the final validation error response
 func (b *ValidationErrorBuilder) Build() *ValidationErrorResponse {
-	return b.response
+	b.built = true
+	return &b.response
 }
 
-// Send sends the validation error response as JSON
+// Send sends the validation error response as JSON and returns the builder to the pool
 func (b *ValidationErrorBuilder) Send(c *fiber.Ctx) error {
-	return c.Status(b.response.Status).JSON(b.response)
+	err := c.Status(b.response.Status).JSON(&b.response)
+	if !b.built {
+		b.response = ValidationErrorResponse{}
+		builderPool.Put(b)
+	}
+	return err
 }
 
 // ValidationErrorHelper provides utility functions for validation errors
@@ -4,10 +4,39 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/go-playground/validator/v10"
+
+	"main.go/internal/ids"
+	"main.go/internal/password"
+)
+
+var (
+	breachMu    sync.RWMutex
+	breachCheck func(password string) (bool, error)
+
+	contentMu     sync.RWMutex
+	contentFilter func(text, mode string) bool
 )
 
+// SetBreachCheck enables the not_breached tag with a lookup such as
+// password.BreachChecker.IsBreached. Without one the tag always passes.
+func SetBreachCheck(check func(password string) (bool, error)) {
+	breachMu.Lock()
+	defer breachMu.Unlock()
+	breachCheck = check
+}
+
+// SetContentFilter enables the clean tag with a check such as contentfilter.Filter.Allows,
+// which gets the field and the tag's parameter ("clean=reject" forces reject mode). Without
+// one the tag always passes.
+func SetContentFilter(check func(text, mode string) bool) {
+	contentMu.Lock()
+	defer contentMu.Unlock()
+	contentFilter = check
+}
+
 // Validator wraps the go-playground validator
 type Validator struct {
 	validate *validator.Validate
@@ -27,6 +56,15 @@ func NewValidator() *Validator {
 	if err := v.RegisterValidation("slug", validateSlug); err != nil {
 		return &Validator{validate: v}
 	}
+	if err := v.RegisterValidation("not_breached", validateNotBreached); err != nil {
+		return &Validator{validate: v}
+	}
+	if err := v.RegisterValidation("clean", validateClean); err != nil {
+		return &Validator{validate: v}
+	}
+	if err := v.RegisterValidation("uuid7", validateUUID7); err != nil {
+		return &Validator{validate: v}
+	}
 
 	// Register custom field name extractor
 	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -59,7 +97,7 @@ func (v *Validator) ValidateVar(field interface{}, tag string) error {
 // formatValidationError formats validation errors into a consistent format
 func (v *Validator) formatValidationError(err error) error {
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		formattedErrors := make(map[string]string)
+		formattedErrors := make(map[string]string, len(validationErrors))
 		for _, e := range validationErrors {
 			formattedErrors[e.Field()] = v.getErrorMessage(e)
 		}
@@ -91,12 +129,18 @@ func (v *Validator) getErrorMessage(e validator.FieldError) string {
 		return fmt.Sprintf("%s must be a valid URL", e.Field())
 	case "uuid":
 		return fmt.Sprintf("%s must be a valid UUID", e.Field())
+	case "uuid7":
+		return fmt.Sprintf("%s must be a valid UUIDv7", e.Field())
 	case "password":
-		return "password must be at least 8 characters and contain uppercase, lowercase, number, and special character"
+		return password.CurrentPolicy().Describe()
 	case "username":
 		return "username must be 3-30 characters, alphanumeric with optional underscores and hyphens"
 	case "slug":
 		return "slug must contain only lowercase letters, numbers, and hyphens"
+	case "not_breached":
+		return fmt.Sprintf("%s has appeared in a data breach; choose a different one", e.Field())
+	case "clean":
+		return fmt.Sprintf("%s must not contain profanity or personal information such as emails, phone numbers, or card numbers", e.Field())
 	case "oneof":
 		return fmt.Sprintf("%s must be one of: %s", e.Field(), e.Param())
 	case "gte":
@@ -114,32 +158,34 @@ func (v *Validator) getErrorMessage(e validator.FieldError) string {
 
 // Custom validators
 
-// validatePassword validates password strength
+// validatePassword checks the password against the configured password.Policy
 func validatePassword(fl validator.FieldLevel) bool {
-	password := fl.Field().String()
-	if len(password) < 8 {
-		return false
-	}
+	return password.CurrentPolicy().Allows(fl.Field().String())
+}
 
-	hasUpper := false
-	hasLower := false
-	hasNumber := false
-	hasSpecial := false
-
-	for _, char := range password {
-		switch {
-		case char >= 'A' && char <= 'Z':
-			hasUpper = true
-		case char >= 'a' && char <= 'z':
-			hasLower = true
-		case char >= '0' && char <= '9':
-			hasNumber = true
-		case strings.ContainsRune("!@#$%^&*()_+-=[]{}|;:,.<>?", char):
-			hasSpecial = true
-		}
+// validateNotBreached rejects passwords found in known breaches. Lookup failures
+// pass so an unreachable breach API never blocks sign-ups.
+func validateNotBreached(fl validator.FieldLevel) bool {
+	breachMu.RLock()
+	check := breachCheck
+	breachMu.RUnlock()
+	if check == nil {
+		return true
 	}
 
-	return hasUpper && hasLower && hasNumber && hasSpecial
+	breached, err := check(fl.Field().String())
+	return err != nil || !breached
+}
+
+// validateClean rejects profanity and personal information found by the content filter
+func validateClean(fl validator.FieldLevel) bool {
+	contentMu.RLock()
+	check := contentFilter
+	contentMu.RUnlock()
+	if check == nil {
+		return true
+	}
+	return check(fl.Field().String(), fl.Param())
 }
 
 // validateUsername validates username format
@@ -161,6 +207,18 @@ func validateUsername(fl validator.FieldLevel) bool {
 	return true
 }
 
+// validateUUID7 accepts a UUIDv7, as a string or an ids.UUID
+func validateUUID7(fl validator.FieldLevel) bool {
+	switch v := fl.Field().Interface().(type) {
+	case ids.UUID:
+		return v.Version() == 7
+	case string:
+		u, err := ids.Parse(v)
+		return err == nil && u.Version() == 7
+	}
+	return false
+}
+
 // validateSlug validates slug format
 func validateSlug(fl validator.FieldLevel) bool {
 	slug := fl.Field().String()
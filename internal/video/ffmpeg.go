@@ -0,0 +1,220 @@
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"main.go/internal/operations"
+)
+
+// FFmpeg transcodes on the local machine with the ffmpeg and ffprobe commands
+type FFmpeg struct {
+	ffmpeg  string
+	ffprobe string
+}
+
+// NewFFmpeg looks up ffmpeg and ffprobe, given as names on PATH or as paths
+func NewFFmpeg(ffmpeg, ffprobe string) (*FFmpeg, error) {
+	ffmpegPath, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return nil, err
+	}
+	ffprobePath, err := exec.LookPath(ffprobe)
+	if err != nil {
+		return nil, err
+	}
+	return &FFmpeg{ffmpeg: ffmpegPath, ffprobe: ffprobePath}, nil
+}
+
+// Name implements Transcoder
+func (f *FFmpeg) Name() string {
+	return "ffmpeg"
+}
+
+// probe is what ffprobe reports about a file
+type probe struct {
+	width, height int
+	durationMS    int64
+}
+
+// Transcode implements Transcoder. The source is copied to a temporary file, each profile
+// no taller than the source is encoded in turn, and a poster is taken from the first
+// second. Metadata such as the recording location is not copied.
+func (f *FFmpeg) Transcode(ctx context.Context, job *Job) error {
+	dir, err := os.MkdirTemp("", "video-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "source")
+	if err := f.download(ctx, job, in); err != nil {
+		return err
+	}
+	source, err := f.probe(ctx, in)
+	if err != nil {
+		return operations.Permanent(fmt.Errorf("not a readable video: %w", err))
+	}
+
+	var selected []Profile
+	for _, p := range job.Profiles {
+		if p.Height <= source.height {
+			selected = append(selected, p)
+		}
+	}
+	if len(selected) == 0 && len(job.Profiles) > 0 {
+		// A source smaller than every profile keeps its height in the smallest one
+		p := job.Profiles[len(job.Profiles)-1]
+		p.Height = source.height &^ 1
+		selected = append(selected, p)
+	}
+
+	for i, p := range selected {
+		out := filepath.Join(dir, p.Name+".mp4")
+		args := []string{"-nostdin", "-y", "-v", "error", "-i", in,
+			"-map", "0:v:0", "-map", "0:a:0?", "-map_metadata", "-1",
+			"-vf", "scale=-2:" + strconv.Itoa(p.Height),
+			"-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p",
+			"-b:v", strconv.Itoa(p.VideoBitrate) + "k",
+			"-maxrate", strconv.Itoa(p.VideoBitrate*107/100) + "k",
+			"-bufsize", strconv.Itoa(p.VideoBitrate*2) + "k",
+			"-c:a", "aac", "-b:a", strconv.Itoa(p.AudioBitrate) + "k",
+			"-movflags", "+faststart", "-progress", "pipe:1", out}
+		report := func(doneMS int64) {
+			if source.durationMS > 0 && job.Report != nil {
+				// The poster takes the last few percent
+				job.Report(int((int64(i)*source.durationMS + min(doneMS, source.durationMS)) * 95 / (int64(len(selected)) * source.durationMS)))
+			}
+		}
+		if err := f.run(ctx, args, report); err != nil {
+			return err
+		}
+		if err := f.save(ctx, job, out, p.Name, "video/mp4"); err != nil {
+			return err
+		}
+	}
+
+	poster := filepath.Join(dir, "poster.jpg")
+	at := "0"
+	if source.durationMS > 2000 {
+		at = "1"
+	}
+	args := []string{"-nostdin", "-y", "-v", "error", "-ss", at, "-i", in, "-map_metadata", "-1",
+		"-frames:v", "1", "-vf", "scale=-2:" + strconv.Itoa(min(720, source.height&^1)), "-q:v", "3", poster}
+	if err := f.run(ctx, args, nil); err != nil {
+		return err
+	}
+	return f.save(ctx, job, poster, PosterProfile, "image/jpeg")
+}
+
+// download copies the source to path
+func (f *FFmpeg) download(ctx context.Context, job *Job, path string) error {
+	src, err := job.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, src); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to copy video: %w", err)
+	}
+	return file.Close()
+}
+
+// save stores a produced file with the dimensions ffprobe reads from it
+func (f *FFmpeg) save(ctx context.Context, job *Job, path, profile, contentType string) error {
+	info, err := f.probe(ctx, path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return job.Save(ctx, Output{
+		Profile:     profile,
+		ContentType: contentType,
+		Width:       info.width,
+		Height:      info.height,
+		DurationMS:  info.durationMS,
+	}, file, stat.Size())
+}
+
+// probe reads the first video stream's dimensions and the duration of a file
+func (f *FFmpeg) probe(ctx context.Context, path string) (*probe, error) {
+	cmd := exec.CommandContext(ctx, f.ffprobe, "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration", "-of", "json", path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var result struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+	if len(result.Streams) == 0 || result.Streams[0].Height < 2 {
+		return nil, errors.New("no video stream")
+	}
+	p := &probe{width: result.Streams[0].Width, height: result.Streams[0].Height}
+	if seconds, err := strconv.ParseFloat(result.Format.Duration, 64); err == nil {
+		p.durationMS = int64(seconds * 1000)
+	}
+	return p, nil
+}
+
+// run runs ffmpeg, passing the encoded time in milliseconds to progress as it advances
+func (f *FFmpeg) run(ctx context.Context, args []string, progress func(doneMS int64)) error {
+	cmd := exec.CommandContext(ctx, f.ffmpeg, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "out_time_us=")
+		if !ok || progress == nil {
+			continue
+		}
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			progress(us / 1000)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package video
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"main.go/internal/operations"
+	"main.go/internal/signing"
+)
+
+// Remote hands transcoding to an HTTP service. It POSTs the job, signed like outgoing
+// webhooks, and the service answers once the renditions are ready:
+//
+//	{"id": "<attachment id>", "source_url": "...", "content_type": "video/mp4",
+//	 "profiles": [{"name": "720p", "height": 720, "video_bitrate": 2800, "audio_bitrate": 128}]}
+//
+// is answered with
+//
+//	{"renditions": [{"profile": "720p", "url": "...", "content_type": "video/mp4",
+//	  "width": 1280, "height": 720, "duration_ms": 61000}]}
+//
+// where each url can be downloaded without credentials; include one with the profile
+// "poster" for a still image. A 4xx answer fails the operation; anything else is retried.
+type Remote struct {
+	url    string
+	client *http.Client
+	// download fetches the renditions; they are not signed requests
+	download *http.Client
+}
+
+// NewRemote creates a transcoder calling url, signing requests with keyID and secret
+func NewRemote(url, keyID, secret string) *Remote {
+	client := signing.NewClient(keyID, secret)
+	// A transcode takes minutes; the operation's timeout bounds it instead
+	client.Timeout = 0
+	return &Remote{url: url, client: client, download: &http.Client{}}
+}
+
+// Name implements Transcoder
+func (r *Remote) Name() string {
+	return "remote"
+}
+
+type remoteRendition struct {
+	Profile     string `json:"profile"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	DurationMS  int64  `json:"duration_ms"`
+}
+
+// Transcode implements Transcoder
+func (r *Remote) Transcode(ctx context.Context, job *Job) error {
+	if job.SourceURL == "" {
+		return operations.Permanent(errors.New("remote transcoding needs ATTACHMENT_URL_SECRET for source links"))
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"id":           job.Attachment.ID,
+		"source_url":   job.SourceURL,
+		"content_type": job.Attachment.ContentType,
+		"profiles":     job.Profiles,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("transcoder: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("transcoder: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+		if resp.StatusCode/100 == 4 {
+			return operations.Permanent(err)
+		}
+		return err
+	}
+	var result struct {
+		Renditions []remoteRendition `json:"renditions"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("transcoder: %w", err)
+	}
+
+	for i, rendition := range result.Renditions {
+		if err := r.fetch(ctx, job, rendition); err != nil {
+			return err
+		}
+		if job.Report != nil {
+			job.Report((i + 1) * 100 / len(result.Renditions))
+		}
+	}
+	return nil
+}
+
+// fetch downloads a rendition to a temporary file, since storage needs its size, and saves it
+func (r *Remote) fetch(ctx context.Context, job *Job, rendition remoteRendition) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rendition.URL, nil)
+	if err != nil {
+		return operations.Permanent(fmt.Errorf("transcoder: rendition %s: %w", rendition.Profile, err))
+	}
+	resp, err := r.download.Do(req)
+	if err != nil {
+		return fmt.Errorf("transcoder: rendition %s: %w", rendition.Profile, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("transcoder: rendition %s: status %d", rendition.Profile, resp.StatusCode)
+	}
+
+	file, err := os.CreateTemp("", "rendition-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	size, err := io.Copy(file, resp.Body)
+	if err != nil {
+		return fmt.Errorf("transcoder: rendition %s: %w", rendition.Profile, err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return job.Save(ctx, Output{
+		Profile:     rendition.Profile,
+		ContentType: rendition.ContentType,
+		Width:       rendition.Width,
+		Height:      rendition.Height,
+		DurationMS:  rendition.DurationMS,
+	}, file, size)
+}
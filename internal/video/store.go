@@ -0,0 +1,121 @@
+package video
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"main.go/internal/database"
+)
+
+// ErrNotFound is returned when a video has no rendition with the requested profile
+var ErrNotFound = errors.New("rendition not found")
+
+// Rendition is a transcoded version, or the poster, of a video attachment
+type Rendition struct {
+	AttachmentID string    `json:"attachment_id"`
+	Profile      string    `json:"profile"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	DurationMS   int64     `json:"duration_ms,omitempty"`
+	Size         int64     `json:"size"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Key locates the file in storage; it is never exposed
+	Key string `json:"-"`
+}
+
+// ETag returns a validator for the rendition. Every transcode stores its files under new
+// keys, so the key already names the bytes.
+func (r *Rendition) ETag() string {
+	return `"` + strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(r.Key, renditionPrefix), path.Ext(r.Key)), "/", ".") + `"`
+}
+
+// Store records the renditions of video attachments
+type Store struct {
+	db *database.DB
+}
+
+// NewStore creates a new rendition store
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db}
+}
+
+const renditionColumns = `attachment_id, profile, content_type, width, height, duration_ms, size, created_at, storage_key`
+
+func scanRendition(row interface{ Scan(...interface{}) error }) (*Rendition, error) {
+	var r Rendition
+	if err := row.Scan(&r.AttachmentID, &r.Profile, &r.ContentType, &r.Width, &r.Height, &r.DurationMS,
+		&r.Size, &r.CreatedAt, &r.Key); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Save records a rendition, replacing the one with the same profile, and returns the
+// storage key of the file it replaced, if any
+func (s *Store) Save(ctx context.Context, r *Rendition) (string, error) {
+	var previous sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+WITH old AS (SELECT storage_key FROM video_renditions WHERE attachment_id = $1 AND profile = $2)
+INSERT INTO video_renditions (attachment_id, profile, content_type, width, height, duration_ms, size, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (attachment_id, profile) DO UPDATE SET
+    content_type = EXCLUDED.content_type, width = EXCLUDED.width, height = EXCLUDED.height,
+    duration_ms = EXCLUDED.duration_ms, size = EXCLUDED.size, storage_key = EXCLUDED.storage_key,
+    created_at = NOW()
+RETURNING (SELECT storage_key FROM old)`,
+		r.AttachmentID, r.Profile, r.ContentType, r.Width, r.Height, r.DurationMS, r.Size, r.Key).Scan(&previous)
+	if err != nil {
+		return "", fmt.Errorf("failed to record rendition: %w", err)
+	}
+	return previous.String, nil
+}
+
+// List returns the renditions of a video, tallest first with the poster last
+func (s *Store) List(ctx context.Context, attachmentID string) ([]Rendition, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+renditionColumns+` FROM video_renditions
+WHERE attachment_id = $1
+ORDER BY profile = 'poster', height DESC`, attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load renditions: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Rendition{}
+	for rows.Next() {
+		r, err := scanRendition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rendition: %w", err)
+		}
+		list = append(list, *r)
+	}
+	return list, rows.Err()
+}
+
+// Find returns a video's rendition with profile
+func (s *Store) Find(ctx context.Context, attachmentID, profile string) (*Rendition, error) {
+	r, err := scanRendition(s.db.QueryRowContext(ctx,
+		`SELECT `+renditionColumns+` FROM video_renditions WHERE attachment_id = $1 AND profile = $2`, attachmentID, profile))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rendition: %w", err)
+	}
+	return r, nil
+}
+
+// Delete forgets a rendition whose file has been deleted
+func (s *Store) Delete(ctx context.Context, attachmentID, profile string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM video_renditions WHERE attachment_id = $1 AND profile = $2`, attachmentID, profile); err != nil {
+		return fmt.Errorf("failed to delete rendition: %w", err)
+	}
+	return nil
+}
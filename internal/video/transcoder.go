@@ -0,0 +1,86 @@
+package video
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"main.go/internal/attachments"
+)
+
+// Profile is a rendition to produce: an H.264/AAC MP4 scaled to Height
+type Profile struct {
+	Name   string `json:"name"`
+	Height int    `json:"height"`
+	// VideoBitrate and AudioBitrate are in kbit/s
+	VideoBitrate int `json:"video_bitrate"`
+	AudioBitrate int `json:"audio_bitrate"`
+}
+
+// PosterProfile names the still image taken from every video
+const PosterProfile = "poster"
+
+// profiles are the renditions that can be configured, by name
+var profiles = map[string]Profile{
+	"1080p": {Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+	"720p":  {Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	"480p":  {Name: "480p", Height: 480, VideoBitrate: 1400, AudioBitrate: 128},
+	"360p":  {Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+}
+
+// ParseProfiles returns the named profiles, tallest first
+func ParseProfiles(names []string) ([]Profile, error) {
+	list := make([]Profile, 0, len(names))
+	for _, name := range names {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown video rendition %q (want 1080p, 720p, 480p, or 360p)", name)
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Height > list[j].Height })
+	return list, nil
+}
+
+// Output describes a file a transcoder produced
+type Output struct {
+	// Profile is the profile's name, or PosterProfile
+	Profile     string
+	ContentType string
+	Width       int
+	Height      int
+	DurationMS  int64
+}
+
+// Job is a video for a Transcoder to convert
+type Job struct {
+	Attachment *attachments.Attachment
+	Profiles   []Profile
+	// SourceURL is a signed link the source can be downloaded from without credentials;
+	// empty when attachment links are disabled
+	SourceURL string
+	// Report records progress as a percentage
+	Report func(progress int)
+
+	open func(ctx context.Context) (io.ReadCloser, error)
+	save func(ctx context.Context, out Output, r io.Reader, size int64) error
+}
+
+// Open returns the source video
+func (j *Job) Open(ctx context.Context) (io.ReadCloser, error) {
+	return j.open(ctx)
+}
+
+// Save stores size bytes from r as the output described by out
+func (j *Job) Save(ctx context.Context, out Output, r io.Reader, size int64) error {
+	return j.save(ctx, out, r, size)
+}
+
+// Transcoder converts a video into its renditions and a poster, saving each with Job.Save.
+// Profiles taller than the source may be skipped. Errors that retrying cannot fix, such
+// as a source that is not a video, should be wrapped with operations.Permanent.
+type Transcoder interface {
+	Name() string
+	Transcode(ctx context.Context, job *Job) error
+}
@@ -0,0 +1,204 @@
+// Package video transcodes video attachments into streaming-friendly MP4 renditions and
+// a poster image. Transcoding runs as an operation (see the operations package), so the
+// request that asks for it returns at once and clients follow its progress at
+// /api/v1/operations/:id. The work is done by a Transcoder: ffmpeg on the same machine,
+// or a remote service for deployments that should not encode video themselves.
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"main.go/internal/attachments"
+	"main.go/internal/logger"
+	"main.go/internal/metrics"
+	"main.go/internal/operations"
+)
+
+// KindTranscode is the operation kind of transcodes
+const KindTranscode = "video.transcode"
+
+// renditionPrefix is the storage folder of renditions
+const renditionPrefix = "video-renditions/"
+
+// renditionTypes are the content types a transcoder may return; renditions are served
+// inline, so nothing a browser would run as a document is accepted
+var renditionTypes = map[string]bool{
+	"video/mp4":  true,
+	"video/webm": true,
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+var transcoded = metrics.NewCounter("video", "transcoded_total", "Videos transcoded")
+
+// Config selects what transcodes produce
+type Config struct {
+	// Profiles are the renditions to produce, tallest first
+	Profiles []Profile
+	// Timeout bounds one transcode
+	Timeout time.Duration
+}
+
+// Service transcodes video attachments and serves their renditions
+type Service struct {
+	manager    *attachments.Manager
+	store      *Store
+	runner     *operations.Runner
+	transcoder Transcoder
+	cfg        Config
+	logger     *logger.Logger
+}
+
+// New creates a service transcoding m's videos with t on runner. It deletes renditions
+// when their attachment is cleaned up.
+func New(m *attachments.Manager, store *Store, runner *operations.Runner, t Transcoder, cfg Config, l *logger.Logger) *Service {
+	s := &Service{manager: m, store: store, runner: runner, transcoder: t, cfg: cfg, logger: l}
+	runner.Handle(KindTranscode, cfg.Timeout, s.transcode)
+	m.OnPurge(s.purge)
+	return s
+}
+
+// Store returns the service's store
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Transcodable reports whether an attachment is a video
+func Transcodable(a *attachments.Attachment) bool {
+	return strings.HasPrefix(a.ContentType, "video/")
+}
+
+// subject names the attachment an operation transcodes
+func subject(attachmentID string) string {
+	return "attachment:" + attachmentID
+}
+
+// transcodeInput is the input of a transcode operation
+type transcodeInput struct {
+	AttachmentID string `json:"attachment_id"`
+}
+
+// Transcode starts transcoding a video on behalf of ownerID, who may follow the operation
+func (s *Service) Transcode(ctx context.Context, a *attachments.Attachment, ownerID string) (*operations.Operation, error) {
+	return s.runner.Enqueue(ctx, KindTranscode, subject(a.ID), ownerID, transcodeInput{AttachmentID: a.ID})
+}
+
+// Status returns the latest transcode of a video, or operations.ErrNotFound
+func (s *Service) Status(ctx context.Context, attachmentID string) (*operations.Operation, error) {
+	return s.runner.Store().Latest(ctx, KindTranscode, subject(attachmentID))
+}
+
+// Open returns the contents of a rendition
+func (s *Service) Open(ctx context.Context, r *Rendition) (io.ReadCloser, error) {
+	return s.manager.Storage().Open(ctx, r.Key)
+}
+
+// OpenRange returns length bytes of a rendition starting at offset
+func (s *Service) OpenRange(ctx context.Context, r *Rendition, offset, length int64) (io.ReadCloser, error) {
+	return s.manager.Storage().OpenRange(ctx, r.Key, offset, length)
+}
+
+// transcode is the operations.Handler of KindTranscode
+func (s *Service) transcode(ctx context.Context, op *operations.Operation, report func(int)) (interface{}, error) {
+	var input transcodeInput
+	if err := json.Unmarshal(op.Input, &input); err != nil {
+		return nil, operations.Permanent(err)
+	}
+	a, err := s.manager.Store().Find(ctx, input.AttachmentID)
+	if errors.Is(err, attachments.ErrNotFound) {
+		return nil, operations.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[string]bool{PosterProfile: true}
+	for _, p := range s.cfg.Profiles {
+		allowed[p.Name] = true
+	}
+	job := &Job{
+		Attachment: a,
+		Profiles:   s.cfg.Profiles,
+		Report:     report,
+		open: func(ctx context.Context) (io.ReadCloser, error) {
+			return s.manager.Open(ctx, a)
+		},
+		save: func(ctx context.Context, out Output, r io.Reader, size int64) error {
+			if !allowed[out.Profile] || !renditionTypes[out.ContentType] {
+				return operations.Permanent(fmt.Errorf("transcoder returned unexpected rendition %q", out.Profile))
+			}
+			return s.save(ctx, op, a, out, r, size)
+		},
+	}
+	if s.manager.SignsURLs() {
+		job.SourceURL, _ = s.manager.URL(a)
+	}
+
+	if err := s.transcoder.Transcode(ctx, job); err != nil {
+		return nil, err
+	}
+	transcoded.Inc()
+	renditions, err := s.store.List(ctx, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"attachment_id": a.ID, "renditions": renditions}, nil
+}
+
+// save stores a rendition under a key of this operation, then deletes the file it replaces
+func (s *Service) save(ctx context.Context, op *operations.Operation, a *attachments.Attachment, out Output, r io.Reader, size int64) error {
+	ext := ".mp4"
+	if out.Profile == PosterProfile {
+		ext = ".jpg"
+	}
+	rendition := &Rendition{
+		AttachmentID: a.ID,
+		Profile:      out.Profile,
+		ContentType:  out.ContentType,
+		Width:        out.Width,
+		Height:       out.Height,
+		DurationMS:   out.DurationMS,
+		Size:         size,
+		Key:          renditionPrefix + a.ID + "/" + op.ID + "/" + out.Profile + ext,
+	}
+	if err := s.manager.Storage().Put(ctx, rendition.Key, r, size, out.ContentType); err != nil {
+		return err
+	}
+	previous, err := s.store.Save(ctx, rendition)
+	if err != nil {
+		return err
+	}
+	if previous != "" && previous != rendition.Key {
+		if err := s.manager.Storage().Delete(ctx, previous); err != nil {
+			s.logger.Warn("Failed to delete replaced rendition", zap.String("key", previous), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// purge deletes the renditions of an attachment that is being cleaned up
+func (s *Service) purge(ctx context.Context, a attachments.Attachment) error {
+	renditions, err := s.store.List(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	for _, r := range renditions {
+		if err := s.manager.Storage().Delete(ctx, r.Key); err != nil {
+			s.logger.Warn("Failed to delete rendition", zap.String("key", r.Key), zap.Error(err))
+			return err
+		}
+		if err := s.store.Delete(ctx, a.ID, r.Profile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
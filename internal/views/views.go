@@ -0,0 +1,284 @@
+// Package views stores saved views: named combinations of search, filters, sort, and
+// columns that a user keeps for a data table, such as "Unverified admins, newest first".
+// Each resource served as a data table is registered with its datatable.Table, so views are
+// checked against the columns the table declares searchable and sortable when they are
+// saved, and a stale view fails the same way a request with those parameters would.
+package views
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"main.go/internal/database"
+	"main.go/internal/database/datatable"
+)
+
+// MaxPerResource bounds the views one user keeps for one resource
+const MaxPerResource = 100
+
+var (
+	// ErrNotFound is returned when a view does not exist, belongs to another user, or
+	// the ID is malformed
+	ErrNotFound = errors.New("view not found")
+	// ErrUnknownResource is returned for resources that were not registered
+	ErrUnknownResource = errors.New("unknown resource")
+	// ErrNameTaken is returned when the user already has a view of that name for the resource
+	ErrNameTaken = errors.New("a view with this name already exists")
+	// ErrTooMany is returned when the user already keeps MaxPerResource views for the resource
+	ErrTooMany = fmt.Errorf("at most %d views may be saved per resource", MaxPerResource)
+)
+
+// View is a saved view of a data table
+type View struct {
+	ID       string `json:"id"`
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	Search   string `json:"search"`
+	// Filters matches rows whose column contains the value, per column
+	Filters map[string]string `json:"filters"`
+	// Sort is written as the sort parameter, e.g. "-created_at,email"
+	Sort      string    `json:"sort"`
+	Columns   []string  `json:"columns"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewView holds the fields of a view being saved
+type NewView struct {
+	Resource string
+	Name     string
+	Search   string
+	Filters  map[string]string
+	Sort     string
+	Columns  []string
+}
+
+// request returns the table request the view stands for
+func (v NewView) request() datatable.Request {
+	return datatable.Request{Search: v.Search, Filters: v.Filters, Sort: datatable.ParseSort(v.Sort), Columns: v.Columns}
+}
+
+// Apply fills in what req leaves unset from the view: the search, the sort, and the
+// columns when the request has none, and the filters on columns the request does not
+// filter itself
+func (v *View) Apply(req *datatable.Request) {
+	if req.Search == "" {
+		req.Search = v.Search
+	}
+	if len(req.Sort) == 0 {
+		req.Sort = datatable.ParseSort(v.Sort)
+	}
+	if len(req.Columns) == 0 {
+		req.Columns = v.Columns
+	}
+	if req.Filters == nil {
+		req.Filters = map[string]string{}
+	}
+	for name, value := range v.Filters {
+		if _, ok := req.Filters[name]; !ok {
+			req.Filters[name] = value
+		}
+	}
+}
+
+// Resource is a data table views can be saved for
+type Resource struct {
+	Table datatable.Table
+	// Scope is required to save views of the resource, usually the one its table is served with
+	Scope string
+}
+
+// Store persists saved views
+type Store struct {
+	db        *database.DB
+	resources map[string]Resource
+}
+
+// NewStore creates a new view store; register resources before saving views of them
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db, resources: map[string]Resource{}}
+}
+
+// Register lets views be saved for the resource called name
+func (s *Store) Register(name string, r Resource) {
+	s.resources[name] = r
+}
+
+// Resource returns the registered resource called name
+func (s *Store) Resource(name string) (Resource, bool) {
+	r, ok := s.resources[name]
+	return r, ok
+}
+
+// validate checks a view against its resource's table and normalizes its empty fields
+func (s *Store) validate(v *NewView) error {
+	r, ok := s.resources[v.Resource]
+	if !ok {
+		return ErrUnknownResource
+	}
+	if v.Filters == nil {
+		v.Filters = map[string]string{}
+	}
+	if v.Columns == nil {
+		v.Columns = []string{}
+	}
+	req := v.request()
+	if err := r.Table.Validate(req); err != nil {
+		return err
+	}
+	v.Sort = datatable.FormatSort(req.Sort)
+	return nil
+}
+
+const viewColumns = `id, resource, name, search, filters, sort, columns, created_at, updated_at`
+
+func scanView(row interface{ Scan(...interface{}) error }) (*View, error) {
+	var v View
+	var filters, columns []byte
+	if err := row.Scan(&v.ID, &v.Resource, &v.Name, &v.Search, &filters, &v.Sort, &columns, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filters, &v.Filters); err != nil {
+		return nil, fmt.Errorf("failed to decode view filters: %w", err)
+	}
+	if err := json.Unmarshal(columns, &v.Columns); err != nil {
+		return nil, fmt.Errorf("failed to decode view columns: %w", err)
+	}
+	return &v, nil
+}
+
+// List returns the user's views, of one resource or of every resource when it is empty,
+// by name
+func (s *Store) List(ctx context.Context, userID, resource string) ([]View, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+viewColumns+` FROM saved_views
+WHERE user_id = $1 AND ($2 = '' OR resource = $2)
+ORDER BY resource, name`, userID, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load views: %w", err)
+	}
+	defer rows.Close()
+
+	list := []View{}
+	for rows.Next() {
+		v, err := scanView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		list = append(list, *v)
+	}
+	return list, rows.Err()
+}
+
+// Find returns the user's view with id
+func (s *Store) Find(ctx context.Context, userID, id string) (*View, error) {
+	if uuid.Validate(id) != nil {
+		return nil, ErrNotFound
+	}
+	v, err := scanView(s.db.QueryRowContext(ctx, `
+SELECT `+viewColumns+` FROM saved_views WHERE id = $1 AND user_id = $2`, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load view: %w", err)
+	}
+	return v, nil
+}
+
+// Create saves a view for the user
+func (s *Store) Create(ctx context.Context, userID string, in NewView) (*View, error) {
+	if err := s.validate(&in); err != nil {
+		return nil, err
+	}
+	filters, columns, err := encode(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM saved_views WHERE user_id = $1 AND resource = $2`, userID, in.Resource).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count views: %w", err)
+	}
+	if count >= MaxPerResource {
+		return nil, ErrTooMany
+	}
+
+	v, err := scanView(s.db.QueryRowContext(ctx, `
+INSERT INTO saved_views (user_id, resource, name, search, filters, sort, columns)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING `+viewColumns, userID, in.Resource, in.Name, in.Search, filters, in.Sort, columns))
+	if database.IsUniqueViolation(err) {
+		return nil, ErrNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create view: %w", err)
+	}
+	return v, nil
+}
+
+// Update replaces the user's view with id; its resource cannot change
+func (s *Store) Update(ctx context.Context, userID, id string, in NewView) (*View, error) {
+	current, err := s.Find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	in.Resource = current.Resource
+	if err := s.validate(&in); err != nil {
+		return nil, err
+	}
+	filters, columns, err := encode(in)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := scanView(s.db.QueryRowContext(ctx, `
+UPDATE saved_views SET name = $3, search = $4, filters = $5, sort = $6, columns = $7, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING `+viewColumns, id, userID, in.Name, in.Search, filters, in.Sort, columns))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if database.IsUniqueViolation(err) {
+		return nil, ErrNameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update view: %w", err)
+	}
+	return v, nil
+}
+
+// Delete removes the user's view with id
+func (s *Store) Delete(ctx context.Context, userID, id string) error {
+	if uuid.Validate(id) != nil {
+		return ErrNotFound
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM saved_views WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete view: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// encode returns a view's filters and columns as the JSON stored for them
+func encode(v NewView) ([]byte, []byte, error) {
+	filters, err := json.Marshal(v.Filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns, err := json.Marshal(v.Columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filters, columns, nil
+}
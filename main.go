@@ -1,30 +1,132 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/favicon"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 
+	"main.go/internal/attachments"
+	"main.go/internal/auth"
+	"main.go/internal/auth/active"
+	"main.go/internal/auth/apikey"
+	"main.go/internal/auth/lockout"
+	"main.go/internal/auth/oauth"
+	"main.go/internal/auth/remember"
+	"main.go/internal/auth/saml"
+	"main.go/internal/auth/sessionstore"
+	"main.go/internal/bodyparser"
+	"main.go/internal/cache"
+	"main.go/internal/cachepolicy"
+	"main.go/internal/captcha"
+	"main.go/internal/cdn"
+	"main.go/internal/changelog"
+	"main.go/internal/clock"
 	"main.go/internal/config"
+	"main.go/internal/consent"
+	"main.go/internal/contentfilter"
+	"main.go/internal/crash"
+	"main.go/internal/crypto"
 	"main.go/internal/database"
+	"main.go/internal/database/migrate"
+	"main.go/internal/database/pagination"
+	"main.go/internal/events"
+	"main.go/internal/exports"
+	"main.go/internal/gateway"
+	"main.go/internal/gctune"
 	"main.go/internal/handlers"
+	"main.go/internal/health"
+	"main.go/internal/heartbeat"
+	"main.go/internal/i18n"
+	"main.go/internal/icons"
+	"main.go/internal/ids"
+	"main.go/internal/imaging"
+	"main.go/internal/incidents"
+	"main.go/internal/invites"
 	"main.go/internal/logger"
+	"main.go/internal/mail"
+	"main.go/internal/maintenance"
+	"main.go/internal/markdown"
+	"main.go/internal/metrics"
 	"main.go/internal/middleware"
+	"main.go/internal/moderation"
+	"main.go/internal/newsletter"
+	"main.go/internal/notify"
+	"main.go/internal/operations"
+	"main.go/internal/outbox"
+	"main.go/internal/password"
+	"main.go/internal/policy"
+	"main.go/internal/pwa"
+	"main.go/internal/referrals"
+	"main.go/internal/safego"
+	"main.go/internal/sandbox"
+	"main.go/internal/security"
+	"main.go/internal/signing"
+	"main.go/internal/storage"
+	"main.go/internal/surveys"
+	"main.go/internal/templates/components"
+	"main.go/internal/tenancy"
+	"main.go/internal/uploads"
+	"main.go/internal/users"
+	"main.go/internal/utils"
+	"main.go/internal/validation"
+	"main.go/internal/video"
+	"main.go/internal/views"
 )
 
 type Services struct {
-	Config *config.Config
-	Logger *logger.Logger
-	DB     *database.DB
+	Config      *config.Config
+	Logger      *logger.Logger
+	DB          *database.DB
+	ReportingDB *database.DB
+	DBRouter    *database.Router
+	Clock       clock.Clock
+	IDs         ids.Generator
+	Mailer      mail.Mailer
+	Redis       *redis.Client
+	// Outbox queues email in the database for delivery through Mailer; nil without a database
+	Outbox *outbox.Outbox
+	// Events publishes domain events after their transactions commit; nil without a
+	// database or without destinations
+	Events *events.Outbox
+	// Maintenance is nil without a database
+	Maintenance *maintenance.Schedule
+	// Referrals is nil without a database, with auth disabled, or with REFERRALS_ENABLED=false
+	Referrals *referrals.Program
+	// Moderation is nil without MODERATION_CLASSIFIER
+	Moderation *moderation.Moderator
+	// Attachments is nil without a database or with ATTACHMENTS_ENABLED=false
+	Attachments *attachments.Manager
+	// Operations runs slow work in the background; nil without a database
+	Operations *operations.Runner
+	// Videos is nil without attachments or without VIDEO_TRANSCODER
+	Videos *video.Service
+	// Exports is nil without a database, without auth, or with EXPORTS_ENABLED=false
+	Exports *exports.Service
+	// Uploads is nil without attachments, with UPLOADS_ENABLED=false, or without auth
+	Uploads *uploads.Manager
+	// Tenants is nil unless TENANCY_MODE is set
+	Tenants *tenancy.Store
+	// Newsletter is nil without a database or with NEWSLETTER_ENABLED=false
+	Newsletter *newsletter.Newsletter
+	// ContentFilter is nil with CONTENT_FILTER_MODE=off
+	ContentFilter *contentfilter.Filter
+	// CDN is nil without CDN_PROVIDER; its methods are no-ops then
+	CDN *cdn.Service
 }
 
 func (s *Services) Close() {
@@ -34,48 +136,341 @@ func (s *Services) Close() {
 	if s.DB != nil {
 		_ = s.DB.Close()
 	}
+	if s.ReportingDB != nil {
+		_ = s.ReportingDB.Close()
+	}
+	if s.Redis != nil {
+		_ = s.Redis.Close()
+	}
 	if s.Logger != nil {
 		_ = s.Logger.Sync()
 	}
 }
 
+// Process exit codes, so supervisors and scripts can tell failure modes apart
+const (
+	ExitFailure   = 1
+	ExitConfig    = 2
+	ExitMigration = 3
+	ExitListen    = 4
+	ExitPanic     = 5
+)
+
+// exitError attaches a process exit code to an error returned by run
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
 func main() {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			exit(fmt.Errorf("panic: %v", recovered), ExitPanic, debug.Stack())
+		}
+	}()
+
+	if err := run(); err != nil {
+		code := ExitFailure
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		}
+		exit(err, code, nil)
+	}
+}
+
+// exit prints the error, writes a crash report when CRASH_REPORT_DIR is set, and exits
+func exit(err error, code int, stack []byte) {
+	fmt.Fprintf(os.Stderr, "%v\n", err)
+	if stack != nil {
+		os.Stderr.Write(stack)
+	}
+
+	if dir := os.Getenv("CRASH_REPORT_DIR"); dir != "" {
+		if path, writeErr := crash.NewReport(err, code, stack).Write(dir); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", writeErr)
+		} else {
+			fmt.Fprintf(os.Stderr, "Crash report written to %s\n", path)
+		}
+	}
+	os.Exit(code)
+}
+
+// run wires and serves the application until shutdown. Returned errors carry an exit
+// code (see withExitCode); deferred cleanup always runs before main exits.
+func run() error {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load configuration: %w", err))
 	}
+	crash.SetConfigSummary(cfg.Summary())
 
-	// Initialize logger
-	zapLogger, err := logger.New(cfg.AppEnv)
+	// Initialize logger; with prefork each process writes its own log files
+	logger.SetAsync(cfg.LogAsyncQueue)
+	var zapLogger *logger.Logger
+	switch {
+	case cfg.LogDir != "" && cfg.Prefork:
+		zapLogger, err = logger.NewWithProcessFile(cfg.AppEnv, cfg.LogDir)
+	case cfg.LogDir != "":
+		zapLogger, err = logger.NewWithFile(cfg.AppEnv, cfg.LogDir)
+	default:
+		zapLogger, err = logger.New(cfg.AppEnv)
+	}
 	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+		return withExitCode(ExitConfig, fmt.Errorf("failed to initialize logger: %w", err))
 	}
 
-	services := &Services{Config: cfg, Logger: zapLogger}
+	// Size the garbage collector for the container; prefork children share its memory
+	gcProcs := 1
+	if cfg.Prefork {
+		gcProcs = runtime.GOMAXPROCS(0)
+	}
+	gc := gctune.Apply(cfg.MemoryLimitRatio, gcProcs)
+	zapLogger.Info("Garbage collector configured",
+		zap.Int64("memory_limit_bytes", gc.MemoryLimit),
+		zap.String("memory_limit_source", gc.MemoryLimitSource),
+		zap.Int64("container_limit_bytes", gc.ContainerLimit),
+		zap.Int("gogc", gc.GCPercent))
+	gctune.RegisterMetrics()
+	if cfg.Sandbox {
+		zapLogger.Warn("Sandbox mode: email, event deliveries, alert webhooks, and newsletter syncs are simulated")
+	}
+
+	services := &Services{Config: cfg, Logger: zapLogger, Clock: clock.System{}, IDs: ids.Random{}}
+	ids.SetDefault(services.IDs)
+	password.SetDefault(password.NewHasher(password.Params{
+		Memory:      uint32(cfg.PasswordHashing.Memory),
+		Iterations:  uint32(cfg.PasswordHashing.Iterations),
+		Parallelism: uint8(cfg.PasswordHashing.Parallelism),
+	}))
+	passwordPolicy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
+	// Column encryption (crypto.String) with ENCRYPTION_KEYS; without keys encrypted
+	// columns cannot be read or written
+	if len(cfg.EncryptionKeys) > 0 {
+		keyring, err := crypto.NewKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKey)
+		if err != nil {
+			return withExitCode(ExitConfig, fmt.Errorf("invalid ENCRYPTION_KEYS: %w", err))
+		}
+		crypto.SetDefault(keyring)
+	}
+	// Pagination cursors are encrypted with the keyring, or signed with AUTH_SECRET without one
+	pagination.SetSecret(cfg.AuthSecret)
+	password.SetPolicy(passwordPolicy)
+	if cfg.PasswordHashing.BreachCheck {
+		validation.SetBreachCheck(password.NewBreachChecker().IsBreached)
+	}
+	services.ContentFilter, err = newContentFilter(cfg)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	if services.ContentFilter != nil {
+		validation.SetContentFilter(services.ContentFilter.Allows)
+	}
+	safego.SetLogger(zapLogger)
 	defer services.Close()
 
 	logFeatureMatrix(services)
 
-	// Initialize optional database connection
+	// Initialize optional database connection. An unreachable database is retried in the
+	// background (DB_RECONNECT): features that need it are wired up front, start working
+	// once it answers, and readiness reports 503 until then.
 	if cfg.DatabaseEnabled() {
-		services.DB, err = database.NewConnection(cfg.DBURL)
-		if err != nil {
-			services.Logger.Warn("Database feature enabled but connection failed; continuing without DB")
-		} else {
-			services.Logger.Info("Database connected successfully")
+		services.DB, err = database.Open(cfg.DBDriver, cfg.DBURL, database.PoolOptions(cfg.DBPool))
+		switch {
+		case err == nil:
+			services.DB.RegisterMetrics("db")
+			services.Logger.Info("Database connected successfully", zap.String("driver", services.DB.PoolStats().Driver))
+		case errors.Is(err, database.ErrUnreachable) && cfg.DBReconnect:
+			services.Logger.Warn("Database unreachable; retrying in the background", zap.Error(err))
+			services.DB, err = database.OpenPending(cfg.DBDriver, cfg.DBURL, database.PoolOptions(cfg.DBPool))
+			if err != nil {
+				return withExitCode(ExitConfig, err)
+			}
+			services.DB.RegisterMetrics("db")
+			defer reconnectDatabase(services)()
+		default:
+			services.Logger.Warn("Database feature enabled but connection failed; continuing without DB", zap.Error(err))
 		}
 	} else {
 		services.Logger.Info("Database feature disabled or DB_URL not provided")
 	}
 
+	// Initialize optional reporting database so heavy report queries stay off the primary
+	if cfg.ReportingDatabaseEnabled() {
+		services.ReportingDB, err = database.Open(cfg.DBDriver, cfg.ReportingDBURL, database.PoolOptions(cfg.ReportingDBPool))
+		if err != nil {
+			services.Logger.Warn("Reporting database configured but connection failed; reports will use the primary database", zap.Error(err))
+		} else {
+			services.ReportingDB.RegisterMetrics("reporting_db")
+			services.Logger.Info("Reporting database connected successfully")
+		}
+	}
+	services.DBRouter = database.NewRouter(services.DB, services.ReportingDB)
+
+	// Multi-tenancy (requires database): requests run their queries for the tenant they
+	// name, in its own schema (TENANCY_MODE=schema) or in shared tables under RLS (row)
+	services.Tenants, err = newTenancy(services)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
+	// Initialize optional Redis/Valkey connection for state shared across instances
+	if cfg.CacheEnabled() {
+		services.Redis, err = cache.NewRedis(cfg.RedisAddr(), cfg.RedisPassword)
+		if err != nil {
+			services.Logger.Warn("Cache feature enabled but Redis connection failed; continuing without Redis", zap.Error(err))
+		} else {
+			services.Logger.Info("Redis connected successfully")
+			cache.SetDefault(services.Redis)
+		}
+	}
+	services.Mailer = newMailer(services)
+	if services.DB != nil {
+		services.Outbox = outbox.New(outbox.NewStore(services.DB), services.Mailer, services.Clock, services.Logger)
+		services.Outbox.Start(cfg.MailOutboxInterval)
+		defer services.Outbox.Stop()
+	}
+
+	// Transactional event outbox (requires database): events written with the change they
+	// describe are published to Pusher, EVENTS_WEBHOOK_URL, and EVENTS_REDIS_STREAM
+	services.Events = newEvents(services)
+	if services.Events != nil {
+		services.Events.Start(cfg.Events.Interval)
+		defer services.Events.Stop()
+	}
+
+	// Scheduled maintenance windows (requires database): 503s, a status page banner, and
+	// muted alerts while a window is in progress
+	if services.DB != nil {
+		services.Maintenance = maintenance.NewSchedule(maintenance.NewStore(services.DB), services.Clock, services.Logger)
+		services.Maintenance.Start(cfg.MaintenanceRefreshInterval)
+		defer services.Maintenance.Stop()
+	}
+
+	// Referral program (requires database and auth): a background job grants rewards once
+	// referred users confirm their email address
+	if services.DB != nil && cfg.AuthEnabled() && cfg.Referrals.Enabled {
+		services.Referrals = referrals.NewProgram(referrals.NewStore(services.DB), referrals.Options{
+			RewardReferrer:  cfg.Referrals.RewardReferrer,
+			RewardReferred:  cfg.Referrals.RewardReferred,
+			RequireVerified: cfg.Referrals.RequireVerified,
+			Secure:          cfg.IsProduction(),
+		}, services.Clock, services.Logger)
+		services.Referrals.Start(cfg.Referrals.Interval)
+		defer services.Referrals.Stop()
+	}
+
+	// Spam checks on user-generated content run in the background (requires database)
+	services.Moderation, err = newModerator(services)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	if services.Moderation != nil {
+		services.Moderation.Start(cfg.Moderation.Workers)
+		defer services.Moderation.Stop()
+	}
+
+	// File attachments (requires database): contents go to STORAGE_DRIVER, and a background
+	// job deletes the files of removed attachments and of deleted parents
+	services.Attachments, err = newAttachments(services)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	if services.Attachments != nil {
+		if cfg.AuthEnabled() {
+			services.Attachments.Register(attachments.UserType(cfg.Attachments.MaxFiles))
+		}
+		services.Attachments.Start(cfg.Attachments.CleanupInterval)
+		defer services.Attachments.Stop()
+	}
+
+	// Background operations (requires database): slow work such as video transcoding runs
+	// on a worker pool and is followed at /api/v1/operations/:id
+	if services.DB != nil {
+		services.Operations = operations.New(operations.NewStore(services.DB), cfg.Operations.Retention, services.Clock, services.Logger)
+		services.Videos, err = newVideos(services)
+		if err != nil {
+			return withExitCode(ExitConfig, err)
+		}
+		services.Exports, err = newExports(services)
+		if err != nil {
+			return withExitCode(ExitConfig, err)
+		}
+		if services.Exports != nil {
+			services.Exports.Start(cfg.Exports.CleanupInterval)
+			defer services.Exports.Stop()
+		}
+		services.Operations.Start(cfg.Operations.Interval, cfg.Operations.Workers)
+		defer services.Operations.Stop()
+	}
+
+	// Chunked and tus uploads of large files (requires attachments and auth); a background
+	// job deletes expired uploads and the chunks they left
+	if services.Attachments != nil && cfg.Uploads.Enabled && cfg.AuthEnabled() {
+		services.Uploads = uploads.New(uploads.NewStore(services.DB), services.Attachments, uploads.Options{
+			MaxBytes:     int64(cfg.Uploads.MaxBytes),
+			ChunkBytes:   int64(cfg.Uploads.ChunkBytes),
+			TTL:          cfg.Uploads.TTL,
+			AllowedTypes: cfg.Uploads.AllowedTypes,
+		}, services.Clock, services.Logger)
+		services.Uploads.Start(cfg.Uploads.CleanupInterval)
+		defer services.Uploads.Stop()
+	}
+
+	// Double opt-in newsletter (requires database); a background job pushes changes to
+	// the provider NEWSLETTER_DRIVER selects
+	services.Newsletter, err = newNewsletter(services)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	if services.Newsletter != nil {
+		services.Newsletter.Start(cfg.Newsletter.SyncInterval)
+		defer services.Newsletter.Stop()
+	}
+
+	// CDN purging by the surrogate keys route cache policies attach to responses
+	services.CDN, err = newCDN(services)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+
+	// Security events (failed logins, CSRF failures, rate limiting, denials) go to a
+	// dedicated sink and raise alerts when an event type crosses its threshold
+	recorder, err := newSecurityRecorder(services)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	security.SetDefault(recorder)
+
+	// Apply pending migrations on boot; destructive ones need MIGRATE_ALLOW_DESTRUCTIVE=true
+	// (a database still reconnecting applies them once it answers)
+	if services.DB.Connected() && cfg.MigrateOnBoot {
+		if err := runMigrations(services); err != nil {
+			services.Logger.Error("Failed to apply migrations", zap.Error(err))
+			return withExitCode(ExitMigration, fmt.Errorf("failed to apply migrations: %w", err))
+		}
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		Prefork:       false, // multi-process(uses mutiple cores/vcpus)=faster; only use if cpu demanding like dealing with image processing, harsh hashing, etc
+		Prefork:       cfg.Prefork, // multi-process(uses mutiple cores/vcpus)=faster; only use if cpu demanding like dealing with image processing, harsh hashing, etc
 		CaseSensitive: true,
 		StrictRouting: false,
 		ServerHeader:  "Fiber Server",
 		AppName:       cfg.AppName,
+		BodyLimit:     bodyLimit(cfg),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -92,34 +487,149 @@ func main() {
 
 	// Global middleware
 	app.Use(middleware.Recover())
-	app.Use(requestid.New())
+	// Cancel the request context when the client hangs up, so abandoned queries stop
+	app.Use(middleware.ClientDisconnect(time.Second))
+	app.Use(clock.Middleware(services.Clock))
+	app.Use(ids.Middleware(services.IDs))
+	app.Use(requestid.New(requestid.Config{Generator: services.IDs.NewID}))
+	// Mark every response of a sandbox deployment (X-Sandbox header, "sandbox" in JSON envelopes)
+	if cfg.Sandbox {
+		app.Use(sandbox.Middleware())
+	}
 	app.Use(helmet.New())
-	app.Use(favicon.New(favicon.Config{
-		File: "./statics/favicon.ico",
-		URL:  "favicon.ico",
-	}))
-	app.Use(limiter.New(limiter.Config{
-		Max:               20,
-		Expiration:        30 * time.Second,
-		LimiterMiddleware: limiter.SlidingWindow{},
-	}))
+	// Keep non-production deployments out of search engines (pages also get a robots meta tag)
+	app.Use(middleware.NoIndex(!cfg.IsProduction()))
+	// One URL per page: redirect to APP_URL's host, lowercase paths, and the trailing slash policy
+	probes := middleware.NewProbes(app)
+	canonicalURL := ""
+	if cfg.Canonical.Host {
+		canonicalURL = cfg.AppURL
+	}
+	canonical, err := middleware.Canonical(middleware.CanonicalOptions{
+		URL:           canonicalURL,
+		Lowercase:     cfg.Canonical.Lowercase,
+		TrailingSlash: cfg.Canonical.TrailingSlash,
+		Exclude:       cfg.Canonical.Exclude,
+	})
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	app.Use(middleware.Unless(probes.Is, canonical))
+	// Locale prefixes (/de/about) resolve before routing; the default locale is unprefixed
+	locales, err := i18n.New(i18n.Options{
+		Locales: cfg.Locales,
+		Default: cfg.DefaultLocale,
+		BaseURL: cfg.AppURL,
+	})
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	app.Use(locales.Middleware())
+	// Favicon, touch icons, and /manifest.webmanifest, generated from internal/icons/source.png
+	iconSet, err := icons.New(icons.Options{
+		Name:            cfg.AppName,
+		ShortName:       cfg.AppShortName,
+		ThemeColor:      cfg.AppThemeColor,
+		BackgroundColor: cfg.AppBackgroundColor,
+		MaxAge:          86400,
+	})
+	if err != nil {
+		return withExitCode(ExitConfig, fmt.Errorf("failed to load icons: %w", err))
+	}
+	app.Use(iconSet)
+	components.Configure(components.Site{
+		ThemeColor:    cfg.AppThemeColor,
+		ServiceWorker: cfg.PWA.ServiceWorker,
+		NoIndex:       !cfg.IsProduction(),
+	})
+	// Optional service worker making the app installable and usable offline
+	if cfg.PWA.ServiceWorker {
+		serviceWorker, err := pwa.ServiceWorker(pwa.Options{
+			Precache: cfg.PWA.Precache,
+			Version:  cfg.PWA.CacheVersion,
+		})
+		if err != nil {
+			return withExitCode(ExitConfig, fmt.Errorf("failed to render service worker: %w", err))
+		}
+		app.Get(pwa.Path, serviceWorker)
+	}
+	// Health probes (routes named "probe.*") are micro-cached and skip rate limiting,
+	// WAF inspection, and compression so probe storms stay cheap and out of the metrics
+	app.Use(probes.MicroCache(cfg.ProbeCacheTTL))
+	limiterStorage := newLimiterStorage(services)
+	app.Use(middleware.Unless(probes.Is, middleware.RateLimiter("global", 20, 30*time.Second, limiterStorage)))
+	app.Use(middleware.Unless(probes.Is, middleware.WAF(middleware.WAFOptions{
+		Mode:           cfg.WAFMode,
+		MaxHeaderBytes: cfg.WAFMaxHeaderBytes,
+		MaxBodyBytes:   cfg.WAFMaxBodyBytes,
+	})))
+	if services.Maintenance != nil {
+		app.Use(maintenance.Middleware(services.Maintenance))
+	}
 
 	// Conditional middleware based on configuration
 	if cfg.CORS {
 		app.Use(middleware.CORS(true))
 	}
 
+	// Resolve the tenant before anything reads the database for the request
+	if services.Tenants != nil {
+		app.Use(middleware.Unless(probes.Is, tenancy.Middleware(services.Tenants, tenancy.Options{
+			Mode:     cfg.Tenancy.Mode,
+			Resolver: cfg.Tenancy.Resolver,
+			Header:   cfg.Tenancy.Header,
+			Domain:   cfg.Tenancy.Domain,
+			Required: cfg.Tenancy.Required,
+			CacheTTL: cfg.Tenancy.CacheTTL,
+		})))
+	}
+
 	if cfg.Compress {
-		app.Use(middleware.Compression(true, cfg.CompressLevel))
+		// Files are skipped so byte ranges match the stored contents
+		skip := func(c *fiber.Ctx) bool {
+			return probes.Is(c) || strings.HasPrefix(c.Path(), "/files/") || strings.HasPrefix(c.Path(), "/api/v1/files/") ||
+				strings.HasPrefix(c.Path(), "/api/v1/videos/")
+		}
+		app.Use(middleware.Unless(skip, middleware.Compression(true, cfg.CompressLevel)))
 	}
 
 	if cfg.CSRF {
 		app.Use(middleware.CSRF(true))
 	}
 
+	// Cookie consent: parse the signed preference cookie so analytics features can
+	// consult consent.Allowed(c, consent.CategoryAnalytics) before tracking
+	consentManager := consent.NewManager(cfg.AuthSecret, cfg.IsProduction())
+	app.Use(consentManager.Middleware())
+
+	// Referral links (?ref=CODE) on any page are remembered until the visitor registers
+	if services.Referrals != nil {
+		app.Use(services.Referrals.Capture())
+	}
+
+	// Cache-Control and CDN surrogate keys for named routes (see newCachePolicies)
+	app.Use(newCachePolicies().Middleware())
+	// Strict JSON parsing for the routes declared in newBodyRules
+	app.Use(newBodyRules().Middleware())
+
 	// Initialize handlers with configuration-aware dependencies
-	healthHandler := handlers.NewHealthHandler(cfg, services.DB)
+	healthHandler := handlers.NewHealthHandler(cfg, services.DB, services.ReportingDB)
+	healthHandler.UseCache(services.Redis)
+
+	// Component checks sampled in the background feed the uptime history on /status
+	healthRegistry := newHealthRegistry(services)
+	// A component going up or down changes the status page, so drop CDN copies right away
+	healthRegistry.OnChange(func(health.Component) { services.CDN.PurgeAsync("status") })
+	healthRegistry.Start(cfg.StatusCheckInterval)
+	defer healthRegistry.Stop()
+	var incidentStore *incidents.Store
+	if services.DB != nil {
+		incidentStore = incidents.NewStore(services.DB)
+	}
+	statusHandler := handlers.NewStatusPageHandler(cfg.AppName, healthRegistry, incidentStore, services.Maintenance, services.Logger)
 	apiHandler := handlers.NewAPIHandler(cfg)
+	consentHandler := handlers.NewConsentHandler(consentManager)
+	themeHandler := handlers.NewThemeHandler(cfg.IsProduction())
 	// validationExamples := handlers.NewValidationExamples()
 
 	// Register validation example routes
@@ -127,18 +637,507 @@ func main() {
 
 	// Routes
 	app.Get("/", apiHandler.Homepage)
+	app.Post("/theme", themeHandler.Set)
 	apiV1 := app.Group("/api/v1")
 
+	// Policy acceptance tracking (requires database)
+	var policyStore *policy.Store
+	if services.DB != nil {
+		policyStore = policy.NewStore(services.DB)
+	}
+
+	// Authentication: the authenticators populate the principal for every API request
+	// and run before the invite and policy gates, which key their checks by user ID
+	if cfg.AuthEnabled() {
+		var userStore *users.Store
+		if services.DB != nil {
+			userStore = users.NewStore(services.DB)
+		}
+
+		if cfg.RoleScopes != nil {
+			auth.SetRoleScopes(cfg.RoleScopes)
+		}
+
+		// Machine clients authenticate with X-API-Key regardless of AUTH mode
+		if services.DB != nil {
+			apiV1.Use(apikey.Middleware(apikey.NewStore(services.DB)))
+		}
+
+		var authHandler *handlers.AuthHandler
+		var rememberManager *remember.Manager
+		activeSessions := newActiveSessions(services)
+		switch cfg.AuthMode() {
+		case config.AuthModeJWT:
+			var keys *auth.KeySet
+			if cfg.JWTConfig.KeysDir != "" {
+				keys, err = auth.LoadKeySet(cfg.JWTConfig.KeysDir, cfg.JWTConfig.ActiveKeyID)
+				if err != nil {
+					return withExitCode(ExitConfig, fmt.Errorf("failed to load JWT signing keys: %w", err))
+				}
+				services.Logger.Info("JWT signing with key " + keys.Active().ID)
+			}
+
+			// Logout and logout-all revoke tokens in Redis; without it JWTs stay valid until expiry
+			var revocations *auth.Revocations
+			if services.Redis != nil {
+				revocations = auth.NewRevocations(services.Redis, services.Clock)
+			}
+
+			tokens := auth.NewTokenService(auth.TokenOptions{
+				Secret:      cfg.AuthSecret,
+				Keys:        keys,
+				Revocations: revocations,
+				Issuer:      cfg.JWTConfig.Issuer,
+				AccessTTL:   cfg.JWTConfig.Expire,
+				RefreshTTL:  cfg.JWTConfig.RefreshExpire,
+				Clock:       services.Clock,
+				IDs:         services.IDs,
+			})
+			authHandler = handlers.NewAuthHandler(tokens, nil, userStore)
+
+			apiV1.Use(auth.JWT(tokens))
+			apiV1.Use(activeSessions.Middleware())
+
+			// Public keys for downstream services verifying our tokens (empty with HS256)
+			app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+				return c.JSON(tokens.JWKS())
+			}).Name("jwks")
+		case config.AuthModeSession, config.AuthModeSAML:
+			sessionStorage := newSessionStorage(services)
+			if sessionStorage != nil {
+				defer sessionStorage.Close()
+			} else {
+				middleware.UsesMemoryStorage("sessions")
+			}
+			sessions := auth.NewSessions(auth.SessionOptions{
+				HTTPOnly: cfg.SessionConfig.HTTPOnly,
+				SameSite: cfg.SessionConfig.SameSite,
+				Expire:   cfg.SessionConfig.Expire,
+				Secure:   cfg.IsProduction(),
+				Storage:  sessionStorage,
+			})
+			authHandler = handlers.NewAuthHandler(nil, sessions, userStore)
+
+			app.Use(sessions.Middleware())
+
+			// Revoked sessions are logged out before remember-me can restore them
+			if activeSessions.Enabled() {
+				activeSessions.OnRevoked(sessions.Logout)
+				sessions.OnLogin(activeSessions.LoginHook(cfg.SessionConfig.Expire))
+				app.Use(activeSessions.Middleware())
+			}
+
+			// Enterprise SSO: users sign in at the SAML identity provider instead of with a password
+			if cfg.AuthMode() == config.AuthModeSAML {
+				if userStore == nil {
+					return withExitCode(ExitConfig, errors.New("AUTH=saml requires FEATURE_DATABASE=true"))
+				}
+				sp, err := newSAMLServiceProvider(context.Background(), cfg)
+				if err != nil {
+					return withExitCode(ExitConfig, err)
+				}
+				samlHandler := handlers.NewSAMLHandler(sp, userStore, authHandler, cfg.SAML.SuccessRedirect)
+				app.Get("/saml/metadata", samlHandler.Metadata)
+				app.Get("/saml/login", samlHandler.Login)
+				app.Post("/saml/acs", samlHandler.ACS)
+				services.Logger.Info("SAML login via " + sp.IdentityProvider().EntityID)
+			} else if userStore != nil && cfg.SessionConfig.RememberExpire > 0 {
+				// Remember-me cookies restore expired sessions (requires database)
+				rememberManager = remember.NewManager(remember.NewStore(services.DB), sessions,
+					func(ctx context.Context, userID string) (*auth.Principal, error) {
+						user, err := userStore.FindByID(ctx, userID)
+						if err != nil {
+							return nil, err
+						}
+						if !user.IsActive {
+							return nil, users.ErrAccountDisabled
+						}
+						return user.Principal(), nil
+					},
+					remember.Options{
+						Expire:   cfg.SessionConfig.RememberExpire,
+						SameSite: cfg.SessionConfig.SameSite,
+						Secure:   cfg.IsProduction(),
+					})
+				authHandler.UseRemember(rememberManager, services.Logger)
+				app.Use(rememberManager.Middleware())
+			}
+		}
+
+		// Gates for every API route. In Fiber a Use only runs for routes registered after it,
+		// so they come after the authenticators and before any /api/v1 route.
+		var inviteStore *invites.Store
+		if authHandler != nil && userStore != nil {
+			inviteStore = invites.NewStore(services.DB)
+		}
+		if cfg.InviteOnly && authHandler != nil {
+			// INVITE_ONLY makes registration require an invite and keeps uninvited users
+			// out of the API
+			if inviteStore == nil {
+				return withExitCode(ExitConfig, errors.New("INVITE_ONLY requires FEATURE_DATABASE=true"))
+			}
+			authHandler.UseInvites(inviteStore)
+			apiV1.Use(invites.Gate(inviteStore, "/api/v1/auth/", "/api/v1/invites/"))
+		}
+		apiV1.Use(policy.RequireAcceptance(policyStore, auth.UserID, "/api/v1/policies",
+			"/api/v1/auth/logout", "/api/v1/auth/refresh", "/api/v1/consent"))
+
+		if authHandler != nil {
+			if cfg.AuthMode() == config.AuthModeJWT {
+				apiV1.Post("/auth/refresh", authHandler.Refresh)
+				apiV1.Post("/auth/logout-all", auth.Required(), authHandler.LogoutAll)
+			}
+			if guard := newLoginLockout(services); guard != nil {
+				authHandler.UseLockout(guard, services.Logger)
+			}
+			authHandler.UseActiveSessions(activeSessions)
+			if services.ContentFilter != nil {
+				authHandler.UseContentFilter(services.ContentFilter)
+			}
+
+			// Invite codes: admins can mint them whenever a database is configured
+			if inviteStore != nil {
+				inviteHandler := handlers.NewInviteHandler(inviteStore)
+				apiV1.Post("/invites/redeem", auth.Required(), inviteHandler.Redeem)
+				admin := apiV1.Group("/admin/invites", auth.Scopes(invites.ManageScope))
+				admin.Get("/", inviteHandler.List)
+				admin.Post("/", inviteHandler.Create)
+				admin.Delete("/:id", inviteHandler.Revoke)
+			}
+
+			// Admin user list, served as a server-side data table (users:read scope), and the
+			// saved views users keep of it
+			if userStore != nil {
+				viewStore := views.NewStore(services.DB)
+				viewStore.Register(users.Resource, views.Resource{Table: users.AdminTable, Scope: users.ReadScope})
+				userHandler := handlers.NewUserHandler(userStore)
+				userHandler.UseViews(viewStore)
+				apiV1.Get("/admin/users", auth.Scopes(users.ReadScope), userHandler.Table)
+
+				viewHandler := handlers.NewViewHandler(viewStore)
+				saved := apiV1.Group("/views", auth.Required())
+				saved.Get("/", viewHandler.List)
+				saved.Post("/", viewHandler.Create)
+				saved.Get("/:id", viewHandler.Show)
+				saved.Put("/:id", viewHandler.Update)
+				saved.Delete("/:id", viewHandler.Delete)
+
+				// CSV exports of the tables above, built in the background and emailed as a
+				// signed link; the download itself needs only the link
+				if services.Exports != nil {
+					exportHandler := handlers.NewExportHandler(services.Exports)
+					exportHandler.UseViews(viewStore)
+					apiV1.Get("/exports/:id/download", exportHandler.Download)
+					apiV1.Get("/exports", auth.Required(), exportHandler.List)
+					apiV1.Post("/exports", auth.Required(), exportHandler.Create)
+					apiV1.Get("/exports/:id", auth.Required(), exportHandler.Show)
+					apiV1.Get("/admin/exports", auth.Scopes(exports.ReadScope), exportHandler.Table)
+				}
+			}
+
+			if cfg.AuthMode() != config.AuthModeSAML {
+				apiV1.Post("/auth/register", authHandler.Register).Name("auth.register")
+				apiV1.Post("/auth/login", authHandler.Login).Name("auth.login")
+			}
+			apiV1.Post("/auth/logout", authHandler.Logout)
+
+			// Protected routes: everything under /api/v1/me requires an authenticated caller
+			protected := apiV1.Group("/me", auth.Required())
+			protected.Get("/", authHandler.Me).Name("me")
+			if rememberManager != nil {
+				protected.Get("/devices", authHandler.Devices).Name("me.devices")
+				protected.Delete("/devices/:id", authHandler.RevokeDevice)
+			}
+			if activeSessions.Enabled() {
+				protected.Get("/sessions", authHandler.Sessions).Name("me.sessions")
+				protected.Delete("/sessions/:id", authHandler.RevokeSession)
+			}
+
+			// Profile: the reference authenticated resource (validated bodies, current user)
+			if userStore != nil {
+				profileHandler := handlers.NewProfileHandler(userStore, authHandler)
+				if services.Moderation != nil {
+					profileHandler.UseModeration(services.Moderation)
+				}
+				if services.ContentFilter != nil {
+					profileHandler.UseContentFilter(services.ContentFilter)
+				}
+				if services.Attachments != nil {
+					profileHandler.UseAttachments(services.Attachments)
+				}
+				validate := middleware.NewValidationMiddleware()
+				loadUser := users.LoadCurrentUser(userStore)
+				protected.Get("/profile", loadUser, profileHandler.Get).Name("me.profile")
+				protected.Patch("/profile", loadUser, validate.ValidateBody(&handlers.UpdateProfileRequest{}), profileHandler.Update).Name("me.profile.update")
+				protected.Post("/password", loadUser, validate.ValidateBody(&handlers.ChangePasswordRequest{}), profileHandler.ChangePassword).Name("me.password")
+				protected.Delete("/", loadUser, validate.ValidateBody(&handlers.DeleteAccountRequest{}), profileHandler.Delete)
+			}
+
+			// Spam review: new and changed profiles are screened; admins release or confirm
+			// what was quarantined (moderation:write scope)
+			if services.Moderation != nil {
+				authHandler.UseModeration(services.Moderation)
+				moderationHandler := handlers.NewModerationHandler(services.Moderation)
+				admin := apiV1.Group("/admin/moderation", auth.Scopes(moderation.ManageScope))
+				admin.Get("/", moderationHandler.List)
+				admin.Post("/:id/approve", moderationHandler.Approve)
+				admin.Post("/:id/reject", moderationHandler.Reject)
+			}
+
+			// Referral program: users share their link and follow its progress; admins see
+			// totals (referrals:read scope)
+			if services.Referrals != nil {
+				authHandler.UseReferrals(services.Referrals)
+				referralHandler := handlers.NewReferralHandler(services.Referrals.Store(), cfg.AppURL)
+				protected.Get("/referral", referralHandler.Me).Name("me.referral")
+				protected.Get("/referrals", referralHandler.List).Name("me.referrals")
+				apiV1.Get("/admin/referrals/stats", auth.Scopes(referrals.ReadScope), referralHandler.Stats)
+			}
+
+			// Password reset and email verification: links are emailed through the configured mailer.
+			// Wrap routes in users.RequireVerified(userStore) to require a confirmed email address.
+			if userStore != nil {
+				signedTokens := auth.NewSignedTokens(cfg.AuthSecret)
+
+				resetHandler := handlers.NewPasswordResetHandler(userStore, signedTokens,
+					services.Mailer, services.Logger, cfg.PasswordResetTTL, cfg.AppURL)
+				apiV1.Post("/auth/forgot-password", resetHandler.ForgotPassword)
+				apiV1.Post("/auth/reset-password", resetHandler.ResetPassword)
+
+				verificationHandler := handlers.NewEmailVerificationHandler(userStore, signedTokens,
+					services.Mailer, services.Logger, cfg.EmailVerificationTTL, cfg.AppURL)
+				authHandler.UseEmailVerification(verificationHandler)
+				apiV1.Get("/auth/verify", verificationHandler.Verify)
+				apiV1.Post("/auth/verify/resend", auth.Required(), verificationHandler.Resend)
+			}
+
+			// Social login: providers are enabled by setting their client credentials
+			if oauthManager := newOAuthManager(cfg); len(oauthManager.Names()) > 0 {
+				oauthHandler := handlers.NewOAuthHandler(oauthManager, userStore, authHandler, cfg.OAuth.SuccessRedirect)
+				apiV1.Get("/auth/providers", oauthHandler.Providers)
+				app.Get("/auth/:provider", oauthHandler.Begin)
+				app.Get("/auth/:provider/callback", oauthHandler.Callback)
+			}
+		}
+	}
+
+	// Stylesheet for syntax-highlighted code in rendered markdown (MARKDOWN_HIGHLIGHT_STYLE)
+	highlightCSS, err := markdown.CSS(cfg.MarkdownHighlightStyle)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	app.Get(markdown.CSSPath, func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/css; charset=utf-8")
+		return c.SendString(highlightCSS)
+	}).Name("markdown.css")
+
+	// Release notes: a cacheable page and JSON with per-user unseen flags (requires database)
+	releaseNotes, err := loadChangelog(cfg)
+	if err != nil {
+		return withExitCode(ExitConfig, err)
+	}
+	var changelogStore *changelog.Store
+	if services.DB != nil && cfg.AuthEnabled() {
+		changelogStore = changelog.NewStore(services.DB)
+	}
+	changelogHandler := handlers.NewChangelogHandler(cfg.AppName, releaseNotes, changelogStore)
+	app.Get("/changelog", changelogHandler.Page).Name("changelog")
+	// Anonymous requests share the rendered list for a minute; signed-in ones bypass it
+	apiV1.Get("/changelog", middleware.Cache(services.Redis, time.Minute), changelogHandler.List).Name("changelog.api")
+	if changelogStore != nil {
+		apiV1.Post("/me/changelog/seen", auth.Required(), changelogHandler.MarkSeen)
+	}
+
+	// Public contact form: validated, honeypot and optional captcha, throttled per IP, and
+	// delivered through the outbox when there is a database
+	if cfg.Contact.Enabled {
+		var mailer mail.Mailer = services.Mailer
+		if services.Outbox != nil {
+			mailer = services.Outbox
+		}
+		contactHandler := handlers.NewContactHandler(mailer, cfg.Contact.To, services.Logger)
+		verifier, err := newCaptcha(cfg)
+		if err != nil {
+			return withExitCode(ExitConfig, err)
+		}
+		if verifier != nil {
+			contactHandler.UseCaptcha(verifier)
+		}
+		apiV1.Post("/contact", middleware.RateLimiter("contact", cfg.Contact.RateLimit, cfg.Contact.RateWindow, limiterStorage), contactHandler.Submit)
+	}
+
+	// Newsletter signups, confirmation and unsubscribe links, and for admins the CSV export
+	// and suppression list (newsletter:write scope)
+	if services.Newsletter != nil {
+		newsletterHandler := handlers.NewNewsletterHandler(services.Newsletter, services.Outbox, cfg.AppName, services.Logger)
+		apiV1.Post("/newsletter/subscribe", middleware.RateLimiter("newsletter", cfg.Newsletter.RateLimit, cfg.Newsletter.RateWindow, limiterStorage), newsletterHandler.Subscribe)
+		apiV1.Get("/newsletter/confirm", newsletterHandler.Confirm)
+		apiV1.Get("/newsletter/unsubscribe", newsletterHandler.Unsubscribe)
+		apiV1.Post("/newsletter/unsubscribe", newsletterHandler.Unsubscribe)
+		if cfg.AuthEnabled() {
+			admin := apiV1.Group("/admin/newsletter", auth.Scopes(newsletter.ManageScope))
+			admin.Get("/export", newsletterHandler.Export)
+			admin.Get("/suppressions", newsletterHandler.Suppressions)
+			admin.Post("/suppressions", newsletterHandler.Suppress)
+			admin.Delete("/suppressions/:email", newsletterHandler.Unsuppress)
+		}
+	}
+
+	// Files attached to any registered resource type; each type decides who may read and
+	// change its attachments, and attachments:write holders manage all of them
+	if services.Attachments != nil {
+		attachmentHandler := handlers.NewAttachmentHandler(services.Attachments)
+		apiV1.Get("/attachments/:type/:id", attachmentHandler.List)
+		apiV1.Post("/attachments/:type/:id", attachmentHandler.Upload)
+		apiV1.Put("/attachments/:type/:id/order", attachmentHandler.Reorder)
+		apiV1.Patch("/attachments/:id", attachmentHandler.Caption)
+		apiV1.Delete("/attachments/:id", attachmentHandler.Remove)
+
+		// Contents are only served through /files, never from storage URLs. Signed links
+		// let <img> tags and downloads read files without sending credentials.
+		fileHandler := handlers.NewFileHandler(services.Attachments)
+		app.Get("/files/:id", fileHandler.Serve)
+		apiV1.Get("/files/:id", fileHandler.Serve)
+		if cfg.Attachments.URLSecret != "" {
+			apiV1.Get("/files/:id/url", fileHandler.URL)
+		}
+
+		// Resized and converted images at signed /img URLs, cached in storage
+		if images := newImages(services); images != nil {
+			imageHandler := handlers.NewImageHandler(images, services.Attachments, int(cfg.Images.CacheMaxAge.Seconds()))
+			app.Get("/img/:signature/:options/:id", imageHandler.Serve)
+			apiV1.Get("/images/:id/url", imageHandler.URL)
+		}
+
+		// Large files are sent in chunks, through the JSON API or the tus protocol, and
+		// attached once complete; videos are then transcoded in the background
+		if services.Uploads != nil {
+			uploadHandler := handlers.NewUploadHandler(services.Uploads)
+			if services.Videos != nil {
+				uploadHandler.UseVideos(services.Videos)
+			}
+			apiV1.Post("/uploads", uploadHandler.Create)
+			apiV1.Get("/uploads/:id", uploadHandler.Show)
+			apiV1.Put("/uploads/:id", uploadHandler.Append)
+			apiV1.Post("/uploads/:id/complete", uploadHandler.Complete)
+
+			tus := apiV1.Group("/tus", uploadHandler.TusResumable)
+			tus.Options("/", uploadHandler.TusOptions)
+			tus.Post("/", uploadHandler.TusCreate)
+			tus.Head("/:id", uploadHandler.TusHead)
+			tus.Patch("/:id", uploadHandler.TusPatch)
+			tus.Delete("/:id", uploadHandler.TusDelete)
+		}
+		if services.Videos != nil {
+			videoHandler := handlers.NewVideoHandler(services.Videos, services.Attachments)
+			apiV1.Get("/videos/:id", videoHandler.Show)
+			apiV1.Post("/videos/:id/transcode", videoHandler.Transcode)
+			apiV1.Get("/videos/:id/renditions/:profile", videoHandler.Rendition)
+		}
+	}
+
+	// Status of background operations, for the user who started them and operations:read holders
+	if services.Operations != nil {
+		apiV1.Get("/operations/:id", handlers.NewOperationHandler(services.Operations.Store()).Show)
+	}
+
+	// Surveys and feedback widgets (requires database): responses are checked against a JSON
+	// Schema compiled from each survey; admins define surveys and read reports (surveys:write scope)
+	if services.DB != nil && cfg.Surveys.Enabled {
+		surveyStore := surveys.NewStore(services.DB)
+		if services.Events != nil {
+			surveyStore.UseEvents(services.Events)
+		}
+		surveyHandler := handlers.NewSurveyHandler(surveyStore)
+		apiV1.Get("/surveys/:slug", surveyHandler.Show)
+		apiV1.Post("/surveys/:slug/responses", middleware.RateLimiter("surveys", cfg.Surveys.RateLimit, cfg.Surveys.RateWindow, limiterStorage), surveyHandler.Respond)
+		if cfg.AuthEnabled() {
+			admin := apiV1.Group("/admin/surveys", auth.Scopes(surveys.ManageScope))
+			admin.Get("/", surveyHandler.List)
+			admin.Post("/", surveyHandler.Create).Name("admin.surveys.create")
+			admin.Put("/:id", surveyHandler.Update).Name("admin.surveys.update")
+			admin.Delete("/:id", surveyHandler.Delete)
+			admin.Get("/:id/report", surveyHandler.Report)
+		}
+	}
+
+	// Maintenance window scheduling for admins (maintenance:write scope; admins hold "*")
+	if services.Maintenance != nil && cfg.AuthEnabled() {
+		maintenanceHandler := handlers.NewMaintenanceHandler(maintenance.NewStore(services.DB), services.Maintenance, services.CDN)
+		admin := apiV1.Group("/admin/maintenance", auth.Scopes("maintenance:write"))
+		admin.Get("/", maintenanceHandler.List)
+		admin.Post("/", maintenanceHandler.Create).Name("admin.maintenance.create")
+		admin.Delete("/:id", maintenanceHandler.Delete)
+	}
+
+	// CDN purges for admins (cache:purge scope)
+	if services.CDN.Enabled() && cfg.AuthEnabled() {
+		cdnHandler := handlers.NewCDNHandler(services.CDN)
+		apiV1.Post("/admin/cache/purge", auth.Scopes("cache:purge"), cdnHandler.Purge)
+	}
+
+	// Test webhooks signed with the event webhook key, for integrators checking their
+	// receivers (webhooks:test scope)
+	if cfg.AuthEnabled() && cfg.Events.WebhookKeyID != "" && cfg.Events.WebhookSecret != "" {
+		webhookHandler := handlers.NewWebhookHandler(cfg.Events.WebhookKeyID, cfg.Events.WebhookSecret)
+		apiV1.Post("/admin/webhooks/test", auth.Scopes("webhooks:test"), webhookHandler.Test).Name("admin.webhooks.test")
+	}
+
+	// Published policies and their acceptance; the re-acceptance gate is registered with
+	// the authenticators above
+	if policyStore != nil {
+		policyHandler := handlers.NewPolicyHandler(policyStore, auth.UserID)
+		apiV1.Get("/policies", policyHandler.List)
+		apiV1.Post("/policies/:kind/accept", policyHandler.Accept)
+	}
+
+	// Server-to-server routes authenticated by HMAC request signatures (SIGNING_KEYS);
+	// outbound calls are signed with signing.NewClient
+	if cfg.SigningEnabled() {
+		var nonces signing.NonceStore = signing.NewMemoryNonceStore()
+		if services.Redis != nil {
+			nonces = signing.NewRedisNonceStore(services.Redis)
+		}
+		internal := app.Group("/internal", signing.Middleware(signing.Options{
+			Keys:    cfg.SigningKeys,
+			MaxSkew: cfg.SigningMaxSkew,
+			Nonces:  nonces,
+		}))
+		internal.Get("/ping", func(c *fiber.Ctx) error {
+			return utils.SuccessResponse(c, fiber.Map{"key_id": signing.KeyID(c)}, "Signature verified")
+		})
+	}
+
 	// Health check routes
-	app.Get("/health", healthHandler.Check)
-	app.Get("/ready", healthHandler.Ready)
-	app.Get("/live", healthHandler.Live)
+	app.Get("/health", healthHandler.Check).Name(middleware.ProbeRoutePrefix + "health")
+	app.Get("/ready", healthHandler.Ready).Name(middleware.ProbeRoutePrefix + "ready")
+	app.Get("/live", healthHandler.Live).Name(middleware.ProbeRoutePrefix + "live")
+	app.Get("/health/details", healthHandler.DetailedCheck)
+	app.Get("/status", statusHandler.Page).Name("status")
+
+	// Prometheus metrics registered by middlewares and services
+	if cfg.MetricsEnabled {
+		app.Get("/metrics", metrics.Default.Handler())
+	}
 
 	// API routes
 	apiV1.Get("/", apiHandler.Welcome)
 	apiV1.Get("/status", apiHandler.Status)
 
-	// Static files
+	// Privacy preference routes
+	apiV1.Get("/consent", consentHandler.Get)
+	apiV1.Post("/consent", consentHandler.Update)
+	apiV1.Delete("/consent", consentHandler.Withdraw)
+
+	// Static files. Compressible assets are pre-compressed once at startup instead of per
+	// request; skipped in development so edits to ./statics show up without a restart.
+	if cfg.StaticPrecompress && !cfg.IsDevelopment() {
+		precompressed, err := middleware.PrecompressedStatic("/static", "./statics")
+		if err != nil {
+			return withExitCode(ExitConfig, fmt.Errorf("failed to pre-compress static assets: %w", err))
+		}
+		app.Use("/static", precompressed)
+	}
 	app.Static("/static", "./statics", fiber.Static{
 		CacheDuration: time.Hour * 1,
 	})
@@ -146,7 +1145,7 @@ func main() {
 	// Security and SEO files from root
 	app.Get("/robots.txt", func(c *fiber.Ctx) error {
 		return c.SendFile("./statics/robots.txt")
-	})
+	}).Name("robots")
 
 	app.Get("/security.txt", func(c *fiber.Ctx) error {
 		return c.SendFile("./statics/security.txt")
@@ -154,41 +1153,867 @@ func main() {
 
 	app.Get("/sitemap.xml", func(c *fiber.Ctx) error {
 		return c.SendFile("./statics/sitemap.xml")
-	})
+	}).Name("sitemap")
 
 	// Security.txt in .well-known directory (RFC 9116 standard)
 	app.Get("/.well-known/security.txt", func(c *fiber.Ctx) error {
 		return c.SendFile("./statics/.well-known/security.txt")
 	})
 
+	// REST handlers generated from protobuf annotations get the requests under the prefix
+	// that no route above matched, behind the same middleware and /api/v1 gates
+	if cfg.GatewayPrefix != "" {
+		if cfg.GatewayPrefix != "/api/v1" && !strings.HasPrefix(cfg.GatewayPrefix, "/api/v1/") {
+			return withExitCode(ExitConfig, fmt.Errorf("GATEWAY_PREFIX must be /api/v1 or a path under it, got %q", cfg.GatewayPrefix))
+		}
+		if mux := newGateway(services); mux != nil {
+			apiV1.Use(strings.TrimPrefix(cfg.GatewayPrefix, "/api/v1"), gateway.Handler(mux))
+		} else {
+			services.Logger.Warn("GATEWAY_PREFIX is set but newGateway serves no services")
+		}
+	}
+
+	// Development-only routes are compiled in everywhere but registered in development only;
+	// elsewhere they are skipped and answer 404
+	envs := registerDevRoutes(app, cfg.AppEnv)
+	if skipped := envs.Skipped(); len(skipped) > 0 {
+		services.Logger.Info("Routes skipped in "+cfg.AppEnv+" mode", zap.Strings("routes", skipped))
+	}
+
+	// Middleware state kept in memory is per instance; flag it where instances are shared
+	if warning := memoryStorageWarning(cfg); warning != "" {
+		services.Logger.Warn(warning,
+			zap.Strings("middleware", middleware.MemoryStorage()),
+			zap.Bool("prefork", cfg.Prefork),
+			zap.String("fix", "enable FEATURE_CACHE (Redis) for rate limits and sessions, or set SINGLE_INSTANCE=true when only one process serves traffic"))
+		healthHandler.UseWarnings([]string{warning})
+	}
+
 	// 404 handler
 	app.Use(func(c *fiber.Ctx) error {
 		return apiHandler.NotFoundPage(c)
 	})
 
-	// Start server in a goroutine
+	// Start server in a goroutine; listen failures are reported back so main can
+	// clean up and exit instead of the goroutine killing the process
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	go func() {
+	serverErr := make(chan error, 1)
+	safego.GoNamed("http-server", func() {
+		// Closing the channel also unblocks main if Listen panics
+		defer close(serverErr)
 		services.Logger.Info("Starting server on " + addr + " in " + cfg.AppEnv + " mode")
 
 		if err := app.Listen(addr); err != nil {
-			services.Logger.Fatal("Failed to start server")
+			serverErr <- err
 		}
-	}()
+	})
+
+	// Dead-man's-switch monitoring: the monitor alerts when pings stop arriving
+	beat := newHeartbeat(services)
+	if beat != nil {
+		beat.Start()
+	}
 
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+
+	select {
+	case err, ok := <-serverErr:
+		if !ok {
+			err = errors.New("server stopped unexpectedly")
+		}
+		services.Logger.Error("Failed to start server", zap.Error(err))
+		return withExitCode(ExitListen, err)
+	case <-quit:
+	}
 
 	services.Logger.Info("Shutting down server...")
 
 	// Shutdown Fiber app
 	if err := app.Shutdown(); err != nil {
-		services.Logger.Fatal("Server forced to shutdown")
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	if beat != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		beat.Stop(ctx)
+		cancel()
 	}
 
 	services.Logger.Info("Server exited")
+	return nil
+}
+
+// runMigrations lints and applies pending migrations from cfg.MigrationsDir
+func runMigrations(s *Services) error {
+	migrations, err := migrate.Load(s.Config.MigrationsDir)
+	if err != nil {
+		return err
+	}
+
+	opts := migrate.Options{AllowDestructive: s.Config.MigrateAllowDestructive}
+	applied, err := migrate.NewRunner(s.DB).Up(context.Background(), migrations, opts)
+	for _, m := range applied {
+		s.Logger.Info("Applied migration " + m.Name)
+	}
+	if err != nil || s.Config.Tenancy.Mode != tenancy.ModeSchema {
+		return err
+	}
+
+	// Then every tenant schema
+	migrations, err = migrate.Load(s.Config.Tenancy.MigrationsDir)
+	if err != nil {
+		return err
+	}
+	return tenancy.MigrateAll(context.Background(), s.DB, migrations, opts, func(t tenancy.Tenant, done []migrate.Migration) {
+		for _, m := range done {
+			s.Logger.Info("Applied migration "+m.Name, zap.String("tenant", t.Slug))
+		}
+	})
+}
+
+// reconnectDatabase retries the primary database in the background until it answers,
+// then applies pending migrations (MIGRATE_ON_BOOT) before it is reported ready. The
+// returned function stops retrying.
+func reconnectDatabase(s *Services) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	safego.GoNamed("db-reconnect", func() {
+		defer close(done)
+		err := s.DB.Reconnect(ctx, database.ReconnectOptions{
+			Max: s.Config.DBReconnectMaxWait,
+			OnRetry: func(attempt int, err error, wait time.Duration) {
+				s.Logger.Warn("Database still unreachable",
+					zap.Int("attempt", attempt), zap.Duration("retry_in", wait), zap.Error(err))
+			},
+			OnConnect: func(ctx context.Context) error {
+				if !s.Config.MigrateOnBoot {
+					return nil
+				}
+				if err := runMigrations(s); err != nil {
+					return fmt.Errorf("failed to apply migrations: %w", err)
+				}
+				return nil
+			},
+		})
+		switch {
+		case err == nil:
+			s.Logger.Info("Database connected successfully", zap.String("driver", s.DB.PoolStats().Driver))
+		case ctx.Err() == nil:
+			s.Logger.Error("Database connected but not ready", zap.Error(err))
+		}
+	})
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// registerDevRoutes registers the debugging routes on app when env is development, and
+// records them as skipped elsewhere
+func registerDevRoutes(app *fiber.App, env string) *middleware.Envs {
+	envs := middleware.NewEnvs(env)
+	dev := envs.Only(app, "development")
+	debugHandler := handlers.NewDebugHandler(app, envs.Skipped)
+	dev.Get("/debug/routes", debugHandler.Routes)
+	dev.Use("/debug/pprof", pprof.New())
+	return envs
+}
+
+// newMailer returns an SMTP mailer when FEATURE_MAIL is configured, otherwise one that logs
+// messages; in sandbox mode mail is always simulated
+func newMailer(s *Services) mail.Mailer {
+	if s.Config.Sandbox {
+		return sandbox.NewMailer(s.Logger)
+	}
+	if !s.Config.MailEnabled() {
+		return mail.NewLogMailer(s.Logger)
+	}
+	mc := s.Config.MailConfig
+	return mail.NewSMTPMailer(mail.SMTPOptions{
+		Host:        mc.Host,
+		Port:        mc.Port,
+		Username:    mc.Username,
+		Password:    mc.Password,
+		Encryption:  mc.Encryption,
+		FromAddress: mc.FromAddress,
+		FromName:    mc.FromName,
+	})
+}
+
+// newPasswordPolicy builds the rules for new passwords from PASSWORD_MIN_LENGTH,
+// PASSWORD_REQUIRE, PASSWORD_BANNED, and PASSWORD_BANNED_FILE
+func newPasswordPolicy(cfg *config.Config) (*password.Policy, error) {
+	banned := cfg.PasswordPolicy.Banned
+	if cfg.PasswordPolicy.BannedFile != "" {
+		words, err := password.LoadBannedList(cfg.PasswordPolicy.BannedFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load banned passwords: %w", err)
+		}
+		banned = append(banned, words...)
+	}
+	return password.NewPolicy(cfg.PasswordPolicy.MinLength, cfg.PasswordPolicy.Require, banned)
+}
+
+// newContentFilter builds the profanity and PII filter from the CONTENT_FILTER_* settings,
+// or returns nil when CONTENT_FILTER_MODE is off
+func newContentFilter(cfg *config.Config) (*contentfilter.Filter, error) {
+	fc := cfg.ContentFilter
+	if fc.Mode == "off" {
+		return nil, nil
+	}
+	words := fc.Words
+	if fc.WordsFile != "" {
+		list, err := contentfilter.LoadWords(fc.WordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load content filter words: %w", err)
+		}
+		words = append(words, list...)
+	}
+	var patterns map[string]string
+	if fc.PatternsFile != "" {
+		var err error
+		if patterns, err = contentfilter.LoadPatterns(fc.PatternsFile); err != nil {
+			return nil, fmt.Errorf("failed to load content filter patterns: %w", err)
+		}
+	}
+	return contentfilter.New(contentfilter.Options{
+		Mode:      contentfilter.Mode(fc.Mode),
+		Detectors: fc.Detectors,
+		Words:     words,
+		Patterns:  patterns,
+	})
+}
+
+// newCachePolicies declares response caching per route name. Name a route with
+// .Name("...") and add its policy here; unnamed routes send no Cache-Control header.
+func newCachePolicies() *cachepolicy.Engine {
+	return cachepolicy.New().
+		// Short browser cache so a traffic spike during an outage does not hammer the app;
+		// CDNs keep it longer and are purged by the "status" key when incidents change
+		Route("status", cachepolicy.Public(30*time.Second).Shared(5*time.Minute).Keys("status")).
+		Route("jwks", cachepolicy.Public(5*time.Minute).Keys("jwks")).
+		Route("robots", cachepolicy.Public(time.Hour).Keys("seo")).
+		Route("sitemap", cachepolicy.Public(time.Hour).Keys("seo")).
+		Route("changelog", cachepolicy.Public(5*time.Minute).Keys("changelog")).
+		Route("markdown.css", cachepolicy.Public(24*time.Hour)).
+		Route("changelog.api", cachepolicy.NoStore()).
+		Route("me", cachepolicy.NoStore()).
+		Route("me.devices", cachepolicy.NoStore()).
+		Route("me.profile", cachepolicy.NoStore()).
+		Route("me.referral", cachepolicy.NoStore()).
+		Route("me.referrals", cachepolicy.NoStore()).
+		Route("me.sessions", cachepolicy.NoStore())
+}
+
+// newBodyRules declares how routes parse JSON bodies (see bodyparser.Parse). Strict
+// routes reject unknown keys, so a misspelled field fails instead of being ignored;
+// undeclared routes parse leniently.
+func newBodyRules() *bodyparser.Rules {
+	return bodyparser.New().
+		Route("auth.register", bodyparser.Strict()).
+		Route("auth.login", bodyparser.Strict()).
+		Route("me.profile.update", bodyparser.Strict()).
+		Route("me.password", bodyparser.Strict()).
+		Route("admin.maintenance.create", bodyparser.Strict()).
+		Route("admin.surveys.create", bodyparser.Strict()).
+		Route("admin.surveys.update", bodyparser.Strict()).
+		Route("admin.webhooks.test", bodyparser.Strict())
+}
+
+// loadChangelog reads release notes from CHANGELOG_DIR, or the ones embedded in the binary
+func loadChangelog(cfg *config.Config) (*changelog.Changelog, error) {
+	if cfg.ChangelogDir == "" {
+		return changelog.Embedded()
+	}
+	return changelog.Load(os.DirFS(cfg.ChangelogDir))
+}
+
+// newHealthRegistry registers a check for every enabled dependency. A dependency that
+// is enabled but failed to connect is reported as down rather than left off the page.
+func newHealthRegistry(s *Services) *health.Registry {
+	registry := health.NewRegistry(s.Clock)
+	registry.Register("Application", func(ctx context.Context) error { return nil })
+	if s.Config.DatabaseEnabled() {
+		registry.Register("Database", databaseCheck(s.DB))
+	}
+	if s.Config.ReportingDatabaseEnabled() {
+		registry.Register("Reporting database", databaseCheck(s.ReportingDB))
+	}
+	if s.Config.CacheEnabled() {
+		registry.Register("Cache", func(ctx context.Context) error {
+			if s.Redis == nil {
+				return errNotConnected
+			}
+			return s.Redis.Ping(ctx).Err()
+		})
+	}
+	return registry
+}
+
+var errNotConnected = errors.New("not connected")
+
+func databaseCheck(db *database.DB) health.Check {
+	if db == nil {
+		return func(ctx context.Context) error { return errNotConnected }
+	}
+	return db.HealthCheck
+}
+
+// newCDN returns the purge service for CDN_PROVIDER, or nil when it is unset
+func newCDN(s *Services) (*cdn.Service, error) {
+	cfg := s.Config.CDN
+	var purger cdn.Purger
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "cloudflare":
+		if cfg.CloudflareZoneID == "" || cfg.CloudflareAPIToken == "" {
+			return nil, errors.New("CDN_PROVIDER=cloudflare requires CDN_CLOUDFLARE_ZONE_ID and CDN_CLOUDFLARE_API_TOKEN")
+		}
+		purger = cdn.NewCloudflare(cfg.CloudflareZoneID, cfg.CloudflareAPIToken)
+	case "fastly":
+		if cfg.FastlyServiceID == "" || cfg.FastlyAPIKey == "" {
+			return nil, errors.New("CDN_PROVIDER=fastly requires CDN_FASTLY_SERVICE_ID and CDN_FASTLY_API_KEY")
+		}
+		purger = cdn.NewFastly(cfg.FastlyServiceID, cfg.FastlyAPIKey, cfg.FastlySoftPurge)
+	case "cloudfront":
+		aws := s.Config.AWSConfig
+		if cfg.CloudFrontDistributionID == "" || aws.AccessKeyID == "" || aws.SecretAccessKey == "" {
+			return nil, errors.New("CDN_PROVIDER=cloudfront requires CDN_CLOUDFRONT_DISTRIBUTION_ID, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		purger = cdn.NewCloudFront(cfg.CloudFrontDistributionID, aws.AccessKeyID, aws.SecretAccessKey, cfg.CloudFrontKeyPaths)
+	default:
+		return nil, fmt.Errorf("unknown CDN_PROVIDER %q (want cloudflare, fastly, or cloudfront)", cfg.Provider)
+	}
+	s.Logger.Info("CDN purging enabled via " + purger.Name())
+	return cdn.NewService(purger, s.Logger), nil
+}
+
+// newTenancy returns the tenant store TENANCY_MODE enables, or nil
+func newTenancy(s *Services) (*tenancy.Store, error) {
+	cfg := s.Config.Tenancy
+	switch cfg.Mode {
+	case "":
+		return nil, nil
+	case tenancy.ModeSchema, tenancy.ModeRow:
+	default:
+		return nil, fmt.Errorf("unknown TENANCY_MODE %q (want schema or row)", cfg.Mode)
+	}
+	if s.DB == nil {
+		return nil, errors.New("TENANCY_MODE requires FEATURE_DATABASE=true")
+	}
+	switch cfg.Resolver {
+	case tenancy.ResolverHeader:
+		if cfg.Header == "" {
+			return nil, errors.New("TENANCY_RESOLVER=header requires TENANCY_HEADER")
+		}
+	case tenancy.ResolverSubdomain:
+		if cfg.Domain == "" {
+			return nil, errors.New("TENANCY_RESOLVER=subdomain requires TENANCY_DOMAIN")
+		}
+	default:
+		return nil, fmt.Errorf("unknown TENANCY_RESOLVER %q (want header or subdomain)", cfg.Resolver)
+	}
+	s.Logger.Info("Tenancy enabled", zap.String("mode", cfg.Mode), zap.String("resolver", cfg.Resolver))
+	return tenancy.NewStore(s.DB), nil
+}
+
+// bodyLimit raises Fiber's 4MB request body limit to fit the largest attachment upload
+// and upload chunk
+func bodyLimit(cfg *config.Config) int {
+	limit := fiber.DefaultBodyLimit
+	if cfg.Attachments.Enabled && cfg.Attachments.MaxBytes+64*1024 > limit {
+		// Room for the multipart framing and the other form fields
+		limit = cfg.Attachments.MaxBytes + 64*1024
+	}
+	if cfg.Attachments.Enabled && cfg.Uploads.Enabled && cfg.Uploads.ChunkBytes > limit {
+		limit = cfg.Uploads.ChunkBytes
+	}
+	return limit
+}
+
+// newCaptcha returns the verifier CAPTCHA_PROVIDER selects, or nil when unset
+func newCaptcha(cfg *config.Config) (*captcha.Verifier, error) {
+	if cfg.Captcha.Provider == "" {
+		return nil, nil
+	}
+	return captcha.NewVerifier(cfg.Captcha.Provider, cfg.Captcha.Secret)
+}
+
+// newModerator returns the spam checker MODERATION_CLASSIFIER selects, or nil when unset
+func newModerator(s *Services) (*moderation.Moderator, error) {
+	cfg := s.Config.Moderation
+	var classifier moderation.Classifier
+	switch cfg.Classifier {
+	case "":
+		return nil, nil
+	case "heuristic":
+		classifier = &moderation.Heuristic{Threshold: cfg.Threshold, BannedTerms: cfg.BannedTerms}
+	case "akismet":
+		if cfg.AkismetKey == "" {
+			return nil, errors.New("MODERATION_CLASSIFIER=akismet requires AKISMET_KEY")
+		}
+		classifier = moderation.NewAkismet(cfg.AkismetKey, s.Config.AppURL, cfg.AkismetURL)
+	default:
+		return nil, fmt.Errorf("unknown MODERATION_CLASSIFIER %q (want heuristic or akismet)", cfg.Classifier)
+	}
+	if s.DB == nil {
+		return nil, errors.New("MODERATION_CLASSIFIER requires FEATURE_DATABASE=true")
+	}
+	s.Logger.Info("Spam checks enabled via " + classifier.Name())
+	return moderation.NewModerator(classifier, moderation.NewStore(s.DB), cfg.QueueSize, s.Clock, s.Logger), nil
+}
+
+// newEvents returns the event outbox with every configured destination, or nil without a
+// database or without destinations
+func newEvents(s *Services) *events.Outbox {
+	cfg := s.Config
+	if s.DB == nil {
+		return nil
+	}
+	o := events.New(s.DB, cfg.Events.Retention, s.Clock, s.Logger)
+	// Destinations outside the app only log their deliveries in sandbox mode
+	external := func(p events.Publisher) events.Publisher {
+		if cfg.Sandbox {
+			return sandbox.NewPublisher(p, s.Logger)
+		}
+		return p
+	}
+	if cfg.PusherEnabled() {
+		p := cfg.PusherConfig
+		o.Use(external(events.NewPusher(p.AppID, p.AppKey, p.AppSecret, p.Cluster, cfg.Events.PusherChannel)))
+	}
+	if cfg.Events.WebhookURL != "" {
+		o.Use(external(events.NewWebhook(cfg.Events.WebhookURL, cfg.Events.WebhookKeyID, cfg.Events.WebhookSecret)))
+	}
+	if cfg.Events.RedisStream != "" {
+		if s.Redis == nil {
+			s.Logger.Warn("EVENTS_REDIS_STREAM is set but Redis is unavailable; events will not be streamed")
+		} else {
+			o.Use(events.NewRedisStream(s.Redis, cfg.Events.RedisStream, int64(cfg.Events.RedisMaxLen)))
+		}
+	}
+	if len(o.Destinations()) == 0 {
+		return nil
+	}
+	s.Logger.Info("Publishing events to " + strings.Join(o.Destinations(), ", "))
+	return o
+}
+
+// newAttachments returns the attachment manager storing files with STORAGE_DRIVER, or nil
+// without a database or when disabled
+func newAttachments(s *Services) (*attachments.Manager, error) {
+	cfg := s.Config
+	if !cfg.Attachments.Enabled || s.DB == nil {
+		return nil, nil
+	}
+	st, err := newStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.Info("Attachments stored via " + st.Name())
+	opts := attachments.Options{
+		MaxBytes:     int64(cfg.Attachments.MaxBytes),
+		AllowedTypes: cfg.Attachments.AllowedTypes,
+		Secret:       cfg.Attachments.URLSecret,
+		URLTTL:       cfg.Attachments.URLTTL,
+		AppURL:       cfg.AppURL,
+	}
+	if cfg.Attachments.ClamAVAddr != "" {
+		opts.Scanner = attachments.NewClamAV(cfg.Attachments.ClamAVAddr)
+		s.Logger.Info("Uploads scanned via " + opts.Scanner.Name())
+	}
+	return attachments.New(attachments.NewStore(s.DB), st, opts, s.Clock, s.Logger), nil
+}
+
+// newImages returns the image transformation service with the WebP and AVIF encoders that
+// are installed, or nil when disabled or without a secret to sign URLs
+func newImages(s *Services) *imaging.Service {
+	cfg := s.Config.Images
+	if !cfg.Enabled || cfg.Secret == "" {
+		return nil
+	}
+	images := imaging.New(s.Attachments, imaging.NewStore(s.DB), imaging.Config{
+		Secret:       cfg.Secret,
+		MaxDimension: cfg.MaxDimension,
+		MaxPixels:    cfg.MaxPixels,
+		Concurrency:  cfg.Concurrency,
+		AppURL:       s.Config.AppURL,
+	}, s.Logger)
+	encoders := []struct {
+		format, command string
+		args            []string
+	}{
+		{imaging.FormatWebP, cfg.WebPEncoder, []string{"-quiet", "-metadata", "none", "-q", "{quality}", "{in}", "-o", "{out}"}},
+		{imaging.FormatAVIF, cfg.AVIFEncoder, []string{"-q", "{quality}", "{in}", "{out}"}},
+	}
+	for _, e := range encoders {
+		if e.command == "" {
+			continue
+		}
+		encoder, err := imaging.NewCommand(e.command, e.args...)
+		if err != nil {
+			s.Logger.Info("Image format " + e.format + " unavailable; falling back to JPEG or PNG (" + e.command + " not found)")
+			continue
+		}
+		images.UseEncoder(e.format, encoder)
+	}
+	return images
+}
+
+// newVideos returns the video service with the transcoder VIDEO_TRANSCODER selects, or nil
+// without attachments or a transcoder
+func newVideos(s *Services) (*video.Service, error) {
+	cfg := s.Config.Video
+	if s.Attachments == nil || cfg.Transcoder == "" {
+		return nil, nil
+	}
+	profiles, err := video.ParseProfiles(cfg.Renditions)
+	if err != nil {
+		return nil, err
+	}
+	var transcoder video.Transcoder
+	switch cfg.Transcoder {
+	case "ffmpeg":
+		transcoder, err = video.NewFFmpeg(cfg.FFmpegPath, cfg.FFprobePath)
+		if err != nil {
+			return nil, fmt.Errorf("VIDEO_TRANSCODER=ffmpeg: %w", err)
+		}
+	case "remote":
+		if cfg.RemoteURL == "" {
+			return nil, errors.New("VIDEO_TRANSCODER=remote requires VIDEO_TRANSCODER_URL")
+		}
+		if !s.Attachments.SignsURLs() {
+			return nil, errors.New("VIDEO_TRANSCODER=remote requires ATTACHMENT_URL_SECRET (or AUTH_SECRET) for source links")
+		}
+		transcoder = video.NewRemote(cfg.RemoteURL, cfg.RemoteKeyID, cfg.RemoteSecret)
+	default:
+		return nil, fmt.Errorf("unknown VIDEO_TRANSCODER %q (want ffmpeg or remote)", cfg.Transcoder)
+	}
+	s.Logger.Info("Videos transcoded via " + transcoder.Name())
+	return video.New(s.Attachments, video.NewStore(s.DB), s.Operations, transcoder, video.Config{
+		Profiles: profiles,
+		Timeout:  cfg.Timeout,
+	}, s.Logger), nil
+}
+
+// newExports returns the export service, keeping files with the attachments' storage or
+// STORAGE_DRIVER, or nil without a database or auth or when disabled
+func newExports(s *Services) (*exports.Service, error) {
+	cfg := s.Config
+	if !cfg.Exports.Enabled || s.DB == nil || !cfg.AuthEnabled() {
+		return nil, nil
+	}
+	if cfg.Exports.Secret == "" {
+		return nil, errors.New("EXPORTS_ENABLED requires EXPORT_SECRET (or AUTH_SECRET) to sign download links")
+	}
+	var st storage.Storage
+	if s.Attachments != nil {
+		st = s.Attachments.Storage()
+	} else {
+		var err error
+		if st, err = newStorage(cfg); err != nil {
+			return nil, err
+		}
+	}
+	var mailer mail.Mailer = s.Mailer
+	if s.Outbox != nil {
+		mailer = s.Outbox
+	}
+	service := exports.New(exports.NewStore(s.DB), s.DB, s.Operations, st, mailer, users.NewStore(s.DB), exports.Options{
+		TTL:     cfg.Exports.TTL,
+		MaxRows: cfg.Exports.MaxRows,
+		Timeout: cfg.Exports.Timeout,
+		Secret:  cfg.Exports.Secret,
+		AppURL:  cfg.AppURL,
+	}, s.Clock, s.Logger)
+	// Tables are registered before the runner starts, so exports left pending by a
+	// restart find theirs
+	service.Register(users.Resource, exports.Source{Table: users.AdminTable, Scope: users.ReadScope})
+	return service, nil
+}
+
+// newStorage returns the file storage STORAGE_DRIVER selects
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		return storage.NewLocal(cfg.Storage.Path)
+	case "s3":
+		aws := cfg.AWSConfig
+		if aws.Bucket == "" || aws.AccessKeyID == "" || aws.SecretAccessKey == "" {
+			return nil, errors.New("STORAGE_DRIVER=s3 requires AWS_BUCKET, AWS_ACCESS_KEY_ID, and AWS_SECRET_ACCESS_KEY")
+		}
+		return storage.NewS3(aws.Bucket, aws.DefaultRegion, aws.AccessKeyID, aws.SecretAccessKey, cfg.Storage.S3Endpoint), nil
+	}
+	return nil, fmt.Errorf("unknown STORAGE_DRIVER %q (want local or s3)", cfg.Storage.Driver)
+}
+
+// newNewsletter returns the newsletter with the sync driver NEWSLETTER_DRIVER selects, or
+// nil without a database or when disabled
+func newNewsletter(s *Services) (*newsletter.Newsletter, error) {
+	cfg := s.Config.Newsletter
+	if !cfg.Enabled || s.DB == nil {
+		return nil, nil
+	}
+	if cfg.Secret == "" {
+		return nil, errors.New("NEWSLETTER_ENABLED requires NEWSLETTER_SECRET or AUTH_SECRET to sign links")
+	}
+	n := newsletter.New(newsletter.NewStore(s.DB), newsletter.Options{
+		Secret:     cfg.Secret,
+		ConfirmTTL: cfg.ConfirmTTL,
+		AppURL:     s.Config.AppURL,
+	}, s.Clock, s.Logger)
+
+	switch cfg.Driver {
+	case "":
+	case "mailchimp":
+		if cfg.APIKey == "" || cfg.ListID == "" {
+			return nil, errors.New("NEWSLETTER_DRIVER=mailchimp requires NEWSLETTER_API_KEY and NEWSLETTER_LIST_ID")
+		}
+		driver, err := newsletter.NewMailchimp(cfg.APIKey, cfg.ListID, cfg.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		if s.Config.Sandbox {
+			n.UseDriver(sandbox.NewDriver(driver, s.Logger))
+		} else {
+			n.UseDriver(driver)
+		}
+		s.Logger.Info("Newsletter sync enabled via " + driver.Name())
+	default:
+		return nil, fmt.Errorf("unknown NEWSLETTER_DRIVER %q (want mailchimp)", cfg.Driver)
+	}
+	return n, nil
+}
+
+// newHeartbeat returns a heartbeat pinging HEARTBEAT_URL, or nil when it is unset.
+// Pings report a failure while a required database is unreachable.
+func newHeartbeat(s *Services) *heartbeat.Heartbeat {
+	cfg := s.Config.Heartbeat
+	if cfg.URL == "" {
+		return nil
+	}
+
+	var check func(ctx context.Context) error
+	if s.DB != nil {
+		check = s.DB.HealthCheck
+	}
+	var muted func() bool
+	if s.Maintenance != nil {
+		muted = s.Maintenance.InWindow
+	}
+	s.Logger.Info("Heartbeat pings every " + cfg.Interval.String())
+	return heartbeat.New(heartbeat.Options{
+		URL:        cfg.URL,
+		Interval:   cfg.Interval,
+		OnShutdown: cfg.OnShutdown,
+		Check:      check,
+		Muted:      muted,
+	}, s.Logger)
+}
+
+// newLoginLockout returns a failed-login guard backed by Redis when available, otherwise
+// by the database; nil when disabled or there is nowhere to keep the counters
+func newLoginLockout(s *Services) *lockout.Guard {
+	lc := s.Config.LoginLockout
+	if !lc.Enabled {
+		return nil
+	}
+
+	var store lockout.Store
+	switch {
+	case s.Redis != nil:
+		store = lockout.NewRedisStore(s.Redis)
+	case s.DB != nil:
+		store = lockout.NewDBStore(s.DB)
+	default:
+		return nil
+	}
+
+	return lockout.NewGuard(store, s.Logger, lockout.Options{
+		MaxAttempts:   lc.MaxAttempts,
+		IPMaxAttempts: lc.IPMaxAttempts,
+		Window:        lc.Window,
+		BaseLockout:   lc.BaseLockout,
+		MaxLockout:    lc.MaxLockout,
+	})
+}
+
+// memoryStorageWarning describes middleware state kept in process memory in a production
+// deployment that may run several processes: always with prefork, and otherwise unless
+// SINGLE_INSTANCE is set. It is empty when there is nothing to warn about.
+func memoryStorageWarning(cfg *config.Config) string {
+	names := middleware.MemoryStorage()
+	if len(names) == 0 || !cfg.IsProduction() || (cfg.SingleInstance && !cfg.Prefork) {
+		return ""
+	}
+	return "Per-instance memory storage for " + strings.Join(names, ", ") +
+		": limits, CSRF tokens, and sessions are not shared between instances"
+}
+
+// newGateway returns the REST handlers generated from your protobuf definitions, served
+// under GATEWAY_PREFIX; nil serves none. With grpc-gateway, register each service on a
+// ServeMux, either in process or against a gRPC server:
+//
+//	mux := runtime.NewServeMux()
+//	if err := projectsv1.RegisterProjectsHandlerServer(context.Background(), mux, projects.NewServer(s.DB)); err != nil {
+//		...
+//	}
+//	return mux
+//
+// Implementations read the caller with gateway.PrincipalFrom(ctx).
+func newGateway(s *Services) http.Handler {
+	return nil
+}
+
+// newLimiterStorage shares rate limit counters between instances through Redis when the
+// cache is enabled; nil keeps them in memory
+func newLimiterStorage(s *Services) fiber.Storage {
+	if s.Redis != nil {
+		return cache.NewStorage(s.Redis, "limiter:")
+	}
+	return nil
+}
+
+// newSessionStorage keeps sessions in Redis when the cache is enabled and otherwise in the
+// database, deleting expired rows in the background; nil keeps them in memory
+func newSessionStorage(s *Services) fiber.Storage {
+	switch {
+	case s.Redis != nil:
+		return sessionstore.NewRedisStorage(s.Redis)
+	case s.DB != nil:
+		storage := sessionstore.NewDBStorage(s.DB, s.Logger)
+		storage.Start(s.Config.SessionConfig.CleanupInterval)
+		return storage
+	}
+	return nil
+}
+
+// newActiveSessions builds the active session registry, kept in Redis when the cache is
+// enabled and otherwise in the database; nil when disabled or there is nowhere to keep sessions
+func newActiveSessions(s *Services) *active.Registry {
+	if !s.Config.SessionConfig.Track {
+		return nil
+	}
+
+	var store active.Store
+	switch {
+	case s.Redis != nil:
+		store = active.NewRedisStore(s.Redis)
+	case s.DB != nil:
+		store = active.NewDBStore(s.DB)
+	default:
+		return nil
+	}
+
+	return active.NewRegistry(store, active.Options{})
+}
+
+// newSecurityRecorder builds the security event sink and threshold alerter from configuration
+func newSecurityRecorder(s *Services) (*security.Recorder, error) {
+	sc := s.Config.Security
+
+	var sink security.Sink = security.NewLogSink(s.Logger)
+	if sc.LogFile != "" {
+		fileSink, err := security.NewFileSink(sc.LogFile)
+		if err != nil {
+			return nil, err
+		}
+		sink = fileSink
+	}
+
+	spec := sc.AlertThresholds
+	if spec == "" {
+		spec = security.DefaultThresholds
+	}
+	thresholds, err := security.ParseThresholds(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var external notify.Multi
+	switch {
+	case sc.AlertWebhookURL != "" && s.Config.Sandbox:
+		external = append(external, sandbox.NewNotifier(s.Logger))
+	case sc.AlertWebhookURL != "":
+		external = append(external, notify.NewWebhookNotifier(sc.AlertWebhookURL))
+	}
+	if len(sc.AlertEmails) > 0 && s.Config.MailEnabled() {
+		external = append(external, notify.NewMailNotifier(s.Mailer, sc.AlertEmails))
+	}
+	// Alerts during a maintenance window are logged but not sent
+	notifiers := notify.Multi{notify.NewLogNotifier(s.Logger)}
+	if s.Maintenance != nil {
+		notifiers = append(notifiers, notify.Muted{Notifier: external, Muted: s.Maintenance.InWindow})
+	} else {
+		notifiers = append(notifiers, external)
+	}
+
+	alerter := security.NewAlerter(notifiers, s.Logger, thresholds)
+	return security.NewRecorder(s.Logger, alerter, sink), nil
+}
+
+// newSAMLServiceProvider loads the IdP metadata and configures the SAML service provider
+func newSAMLServiceProvider(ctx context.Context, cfg *config.Config) (*saml.ServiceProvider, error) {
+	sc := cfg.SAML
+	if sc.IDPMetadata == "" {
+		return nil, errors.New("AUTH=saml requires SAML_IDP_METADATA")
+	}
+	idp, err := saml.LoadIdentityProvider(ctx, sc.IDPMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := saml.DefaultAttributes
+	if len(sc.EmailAttributes) > 0 {
+		attributes.Email = sc.EmailAttributes
+	}
+	if len(sc.NameAttributes) > 0 {
+		attributes.Name = sc.NameAttributes
+	}
+	if len(sc.FirstNameAttributes) > 0 {
+		attributes.FirstName = sc.FirstNameAttributes
+	}
+	if len(sc.LastNameAttributes) > 0 {
+		attributes.LastName = sc.LastNameAttributes
+	}
+
+	return saml.New(idp, saml.Options{
+		EntityID:   sc.EntityID,
+		ACSURL:     strings.TrimRight(cfg.AppURL, "/") + "/saml/acs",
+		Attributes: &attributes,
+		Secret:     cfg.AuthSecret,
+	}), nil
+}
+
+// newOAuthManager registers every social login and OIDC provider with configured credentials
+func newOAuthManager(cfg *config.Config) *oauth.Manager {
+	callbackURL := func(provider string) string {
+		return strings.TrimRight(cfg.AppURL, "/") + "/auth/" + provider + "/callback"
+	}
+
+	var providers []oauth.Provider
+	if cfg.OAuth.Google.Enabled() {
+		providers = append(providers, oauth.NewGoogle(cfg.OAuth.Google.ClientID, cfg.OAuth.Google.ClientSecret, callbackURL("google")))
+	}
+	if cfg.OAuth.GitHub.Enabled() {
+		providers = append(providers, oauth.NewGitHub(cfg.OAuth.GitHub.ClientID, cfg.OAuth.GitHub.ClientSecret, callbackURL("github")))
+	}
+	if oidc := cfg.OAuth.OIDC; oidc.Enabled() {
+		providers = append(providers, oauth.NewOIDC(oauth.OIDCOptions{
+			Name:         oidc.Name,
+			Issuer:       oidc.Issuer,
+			ClientID:     oidc.ClientID,
+			ClientSecret: oidc.ClientSecret,
+			RedirectURL:  callbackURL(oidc.Name),
+			Scopes:       oidc.Scopes,
+			EmailClaim:   oidc.EmailClaim,
+			NameClaim:    oidc.NameClaim,
+		}))
+	}
+	return oauth.NewManager(cfg.AuthSecret, cfg.IsProduction(), providers...)
 }
 
 func logFeatureMatrix(s *Services) {
@@ -213,6 +2038,9 @@ func logFeatureMatrix(s *Services) {
 	if cfg.Features.Auth && cfg.AuthSecret == "" {
 		s.Logger.Warn("FEATURE_AUTH is true but AUTH_SECRET is missing")
 	}
+	if cfg.Features.Database && cfg.AuthSecret == "" && len(cfg.EncryptionKeys) == 0 {
+		s.Logger.Warn("Neither AUTH_SECRET nor ENCRYPTION_KEYS is set; paginated lists longer than one page will fail")
+	}
 	if cfg.Features.Mail && cfg.MailConfig.Host == "" {
 		s.Logger.Warn("FEATURE_MAIL is true but MAIL_HOST is missing")
 	}
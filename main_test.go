@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"main.go/internal/config"
+)
+
+// devOnlyPrefixes are the paths registerDevRoutes serves in development only
+var devOnlyPrefixes = []string{"/debug/routes", "/debug/pprof"}
+
+func routeTable(t *testing.T, env string) (*fiber.App, []string) {
+	t.Helper()
+	t.Setenv("APP_ENV", env)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	app := fiber.New()
+	envs := registerDevRoutes(app, cfg.AppEnv)
+	return app, envs.Skipped()
+}
+
+func devRoutes(app *fiber.App) []string {
+	var found []string
+	for _, route := range app.GetRoutes() {
+		for _, prefix := range devOnlyPrefixes {
+			if strings.HasPrefix(route.Path, prefix) {
+				found = append(found, route.Method+" "+route.Path)
+			}
+		}
+	}
+	return found
+}
+
+func TestProductionRouteTable(t *testing.T) {
+	app, skipped := routeTable(t, "production")
+	if found := devRoutes(app); len(found) > 0 {
+		t.Errorf("development-only routes registered in production: %v", found)
+	}
+	for _, prefix := range devOnlyPrefixes {
+		listed := false
+		for _, route := range skipped {
+			listed = listed || strings.HasSuffix(route, " "+prefix)
+		}
+		if !listed {
+			t.Errorf("%s is not listed as skipped in production: %v", prefix, skipped)
+		}
+	}
+}
+
+func TestDevelopmentRouteTable(t *testing.T) {
+	app, skipped := routeTable(t, "development")
+	if len(skipped) > 0 {
+		t.Errorf("routes skipped in development: %v", skipped)
+	}
+	for _, prefix := range devOnlyPrefixes {
+		registered := false
+		for _, route := range devRoutes(app) {
+			registered = registered || strings.HasSuffix(route, " "+prefix)
+		}
+		if !registered {
+			t.Errorf("%s is not registered in development", prefix)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+-- Rollback: policy acceptance
+
+BEGIN;
+
+DROP TABLE IF EXISTS policy_acceptances;
+DROP TABLE IF EXISTS policy_documents;
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- Migration: policy acceptance
+-- Description: Versioned policy documents (terms, privacy, ...) and per-user acceptance records
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS policy_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(50) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    url VARCHAR(2048) NOT NULL DEFAULT '',
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (kind, version)
+);
+
+CREATE TABLE IF NOT EXISTS policy_acceptances (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id VARCHAR(255) NOT NULL,
+    document_id UUID NOT NULL REFERENCES policy_documents(id) ON DELETE CASCADE,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    accepted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, document_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_documents_kind_published ON policy_documents(kind, published_at DESC);
+CREATE INDEX IF NOT EXISTS idx_policy_acceptances_user_id ON policy_acceptances(user_id);
+
+COMMIT;
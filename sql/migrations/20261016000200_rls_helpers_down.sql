@@ -0,0 +1,8 @@
+-- Rollback: row-level security helpers
+
+BEGIN;
+
+DROP FUNCTION IF EXISTS app_current_user();
+DROP FUNCTION IF EXISTS app_current_tenant();
+
+COMMIT;
@@ -0,0 +1,38 @@
+-- Migration: row-level security helpers
+-- Description: Functions exposing the scope set by database.SetScope to RLS policies
+
+BEGIN;
+
+-- Returns the tenant applied by database.SetScope, or NULL outside a scoped transaction
+CREATE OR REPLACE FUNCTION app_current_tenant() RETURNS TEXT AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '');
+$$ LANGUAGE sql STABLE;
+
+-- Returns the user applied by database.SetScope, or NULL outside a scoped transaction
+CREATE OR REPLACE FUNCTION app_current_user() RETURNS TEXT AS $$
+    SELECT NULLIF(current_setting('app.user_id', true), '');
+$$ LANGUAGE sql STABLE;
+
+-- Example policies (adapt to your own tenant-owned tables):
+--
+-- CREATE TABLE projects (
+--     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+--     tenant_id TEXT NOT NULL,
+--     owner_id TEXT NOT NULL,
+--     name VARCHAR(255) NOT NULL
+-- );
+--
+-- ALTER TABLE projects ENABLE ROW LEVEL SECURITY;
+-- -- FORCE applies the policies to the table owner too (the app usually connects as owner)
+-- ALTER TABLE projects FORCE ROW LEVEL SECURITY;
+--
+-- -- Tenants only ever see and write their own rows
+-- CREATE POLICY projects_tenant_isolation ON projects
+--     USING (tenant_id = app_current_tenant())
+--     WITH CHECK (tenant_id = app_current_tenant());
+--
+-- -- Only the owner may delete a project
+-- CREATE POLICY projects_owner_delete ON projects AS RESTRICTIVE FOR DELETE
+--     USING (owner_id = app_current_user());
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: users
+
+BEGIN;
+
+DROP TABLE IF EXISTS users;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: user identities
+
+BEGIN;
+
+DROP TABLE IF EXISTS user_identities;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: user email verification
+
+BEGIN;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Migration: user email verification
+-- Description: Records when a user confirmed their email address; NULL means unverified
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
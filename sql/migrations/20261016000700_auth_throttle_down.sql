@@ -0,0 +1,7 @@
+-- Rollback: auth throttle
+
+BEGIN;
+
+DROP TABLE IF EXISTS auth_throttle;
+
+COMMIT;
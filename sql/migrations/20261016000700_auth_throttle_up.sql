@@ -0,0 +1,14 @@
+-- Migration: auth throttle
+-- Description: Expiring counters for failed logins and lockouts when Redis is not configured
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS auth_throttle (
+    key VARCHAR(320) PRIMARY KEY,
+    count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_throttle_expires_at ON auth_throttle(expires_at);
+
+COMMIT;
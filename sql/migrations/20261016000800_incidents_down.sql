@@ -0,0 +1,7 @@
+-- Rollback: incidents
+
+BEGIN;
+
+DROP TABLE IF EXISTS incidents;
+
+COMMIT;
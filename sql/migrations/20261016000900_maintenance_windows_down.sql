@@ -0,0 +1,7 @@
+-- Rollback: maintenance windows
+
+BEGIN;
+
+DROP TABLE IF EXISTS maintenance_windows;
+
+COMMIT;
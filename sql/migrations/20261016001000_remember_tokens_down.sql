@@ -0,0 +1,7 @@
+-- Rollback: remember tokens
+
+BEGIN;
+
+DROP TABLE IF EXISTS remember_tokens;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: active sessions
+
+BEGIN;
+
+DROP TABLE IF EXISTS active_sessions;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Rollback: invite codes
+
+BEGIN;
+
+DROP TABLE IF EXISTS invite_redemptions;
+DROP TABLE IF EXISTS invite_codes;
+
+COMMIT;
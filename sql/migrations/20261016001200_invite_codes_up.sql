@@ -0,0 +1,27 @@
+-- Migration: invite codes
+-- Description: Invite codes for invite-only launches and the users who redeemed them
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    max_uses INTEGER NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0 CHECK (uses >= 0),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- One row per user let in; revoking a code stops new redemptions but keeps these
+CREATE TABLE IF NOT EXISTS invite_redemptions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    invite_id UUID NOT NULL REFERENCES invite_codes(id) ON DELETE CASCADE,
+    redeemed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invite_redemptions_invite_id ON invite_redemptions(invite_id);
+
+COMMIT;
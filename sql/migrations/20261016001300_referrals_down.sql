@@ -0,0 +1,9 @@
+-- Rollback: referrals
+
+BEGIN;
+
+DROP TABLE IF EXISTS referral_rewards;
+DROP TABLE IF EXISTS referrals;
+DROP TABLE IF EXISTS referral_codes;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: changelog_seen
+
+BEGIN;
+
+DROP TABLE IF EXISTS changelog_seen;
+
+COMMIT;
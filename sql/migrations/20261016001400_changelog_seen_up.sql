@@ -0,0 +1,13 @@
+-- Migration: changelog_seen
+-- Description: The newest release each user has seen, for the changelog's unseen flag
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS changelog_seen (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- published_at of the newest release the user has seen
+    seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
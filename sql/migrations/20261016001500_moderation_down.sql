@@ -0,0 +1,7 @@
+-- Rollback: moderation
+
+BEGIN;
+
+DROP TABLE IF EXISTS moderation_items;
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Migration: moderation
+-- Description: User-generated content flagged as spam, held for admin review
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS moderation_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- kind and subject_id identify the content, e.g. ('profile', <user id>)
+    kind VARCHAR(32) NOT NULL,
+    subject_id VARCHAR(255) NOT NULL,
+    author_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    -- request details, kept so review decisions can be reported back to the classifier
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    classifier VARCHAR(32) NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    -- quarantined until an admin approves (not spam) or rejects (spam) it
+    status VARCHAR(16) NOT NULL DEFAULT 'quarantined' CHECK (status IN ('quarantined', 'approved', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_items_status ON moderation_items(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_moderation_items_subject ON moderation_items(kind, subject_id);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: mail_outbox
+
+BEGIN;
+
+DROP TABLE IF EXISTS mail_outbox;
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Migration: mail_outbox
+-- Description: Outgoing email queued in the database and delivered in the background with retries
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS mail_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    to_address VARCHAR(255) NOT NULL,
+    reply_to VARCHAR(255) NOT NULL DEFAULT '',
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL DEFAULT '',
+    html_body TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- a message is due from next_attempt_at; claiming it pushes this forward as a lease,
+    -- so another instance does not send it while the first is still trying
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    -- set when the message gave up after the maximum number of attempts
+    failed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_due ON mail_outbox(next_attempt_at) WHERE sent_at IS NULL AND failed_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Rollback: newsletter
+
+BEGIN;
+
+DROP TABLE IF EXISTS newsletter_suppressions;
+DROP TABLE IF EXISTS newsletter_subscribers;
+
+COMMIT;
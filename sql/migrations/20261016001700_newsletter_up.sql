@@ -0,0 +1,31 @@
+-- Migration: newsletter
+-- Description: Double opt-in newsletter subscribers and the addresses that must never be mailed
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS newsletter_subscribers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- stored lowercased
+    email VARCHAR(255) NOT NULL UNIQUE,
+    -- pending until the emailed confirmation link is opened
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'subscribed', 'unsubscribed')),
+    -- where the signup came from, e.g. 'footer' or 'blog'
+    source VARCHAR(64) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    unsubscribed_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- last push to the email provider; rows updated since are synced again
+    synced_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_newsletter_subscribers_status ON newsletter_subscribers(status, created_at);
+
+CREATE TABLE IF NOT EXISTS newsletter_suppressions (
+    email VARCHAR(255) PRIMARY KEY,
+    reason VARCHAR(16) NOT NULL CHECK (reason IN ('unsubscribed', 'bounced', 'complained', 'manual')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
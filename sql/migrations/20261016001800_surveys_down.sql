@@ -0,0 +1,8 @@
+-- Rollback: surveys
+
+BEGIN;
+
+DROP TABLE IF EXISTS survey_responses;
+DROP TABLE IF EXISTS surveys;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: attachments
+
+BEGIN;
+
+DROP TABLE IF EXISTS attachments;
+
+COMMIT;
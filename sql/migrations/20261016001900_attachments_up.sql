@@ -0,0 +1,30 @@
+-- Migration: attachments
+-- Description: Files attached to any resource by type and ID, with order and captions
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY,
+    -- the parent resource, e.g. ('user', <user id>); no foreign key, so orphans are swept
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    caption VARCHAR(500) NOT NULL DEFAULT '',
+    filename VARCHAR(255) NOT NULL,
+    -- sniffed from the contents, not taken from the upload
+    content_type VARCHAR(127) NOT NULL,
+    size BIGINT NOT NULL,
+    -- hex SHA-256 of the contents
+    checksum VARCHAR(64) NOT NULL,
+    storage_key VARCHAR(512) NOT NULL,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    -- set when the attachment is removed or its parent is gone; the cleanup job then
+    -- deletes the stored file and the row
+    orphaned_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_resource ON attachments(resource_type, resource_id, position) WHERE orphaned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_orphaned ON attachments(orphaned_at) WHERE orphaned_at IS NOT NULL;
+
+COMMIT;
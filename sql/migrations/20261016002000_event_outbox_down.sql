@@ -0,0 +1,7 @@
+-- Rollback: event_outbox
+
+BEGIN;
+
+DROP TABLE IF EXISTS event_outbox;
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- Migration: event_outbox
+-- Description: Domain events written in the transaction of the change they describe and published in the background
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- one row per destination; they share the event's id, which consumers deduplicate by
+    event_id UUID NOT NULL,
+    destination VARCHAR(50) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    channel VARCHAR(200) NOT NULL DEFAULT '',
+    data JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- a delivery is due from next_attempt_at; claiming it pushes this forward as a lease,
+    -- so another instance does not publish it while the first is still trying
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP WITH TIME ZONE,
+    -- set when the delivery gave up after the maximum number of attempts
+    failed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, destination)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_due ON event_outbox(created_at) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_published ON event_outbox(published_at) WHERE published_at IS NOT NULL;
+
+COMMIT;
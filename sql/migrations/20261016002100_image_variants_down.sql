@@ -0,0 +1,7 @@
+-- Rollback: image_variants
+
+BEGIN;
+
+DROP TABLE IF EXISTS image_variants;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Migration: image_variants
+-- Description: Cached resizes and format conversions of image attachments
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS image_variants (
+    -- the variant's file in storage, derived from the attachment, its checksum, and the options
+    storage_key VARCHAR(255) PRIMARY KEY,
+    attachment_id UUID NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_variants_attachment ON image_variants(attachment_id);
+
+COMMIT;
@@ -0,0 +1,3 @@
+-- Rollback: newsletter_suppressions_keyset
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_newsletter_suppressions_created;
@@ -0,0 +1,6 @@
+-- Migration: newsletter_suppressions_keyset
+-- Description: Index the suppression list in its keyset page order
+-- CONCURRENTLY cannot run inside a transaction, so this migration has no BEGIN/COMMIT
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_newsletter_suppressions_created
+    ON newsletter_suppressions(created_at DESC, email DESC);
@@ -0,0 +1,10 @@
+-- Rollback: video_uploads
+
+BEGIN;
+
+DROP TABLE IF EXISTS video_renditions;
+DROP TABLE IF EXISTS upload_parts;
+DROP TABLE IF EXISTS uploads;
+DROP TABLE IF EXISTS operations;
+
+COMMIT;
@@ -0,0 +1,70 @@
+-- Migration: video_uploads
+-- Description: Background operations, chunked uploads, and transcoded video renditions
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS operations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(100) NOT NULL,
+    -- what the operation works on, e.g. attachment:<id>
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    progress INTEGER NOT NULL DEFAULT 0,
+    input JSONB NOT NULL DEFAULT '{}',
+    result JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- a pending operation is due from next_attempt_at; while running it is the lease the
+    -- worker keeps renewing, so another instance takes over only if the worker dies
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    done_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_operations_due ON operations(created_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_operations_subject ON operations(kind, subject, created_at);
+CREATE INDEX IF NOT EXISTS idx_operations_done ON operations(done_at) WHERE done_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id UUID PRIMARY KEY,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    caption VARCHAR(500) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL CHECK (size > 0),
+    -- bytes received so far; the next chunk starts here
+    received BIGINT NOT NULL DEFAULT 0 CHECK (received <= size),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- set once the upload is complete
+    attachment_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_expires ON uploads(expires_at) WHERE attachment_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS upload_parts (
+    upload_id UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    byte_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    PRIMARY KEY (upload_id, byte_offset)
+);
+
+CREATE TABLE IF NOT EXISTS video_renditions (
+    attachment_id UUID NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    -- 1080p, 720p, 480p, 360p, or poster
+    profile VARCHAR(20) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (attachment_id, profile)
+);
+
+COMMIT;
@@ -0,0 +1,3 @@
+-- Rollback: upload_expiry
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_uploads_expires_all;
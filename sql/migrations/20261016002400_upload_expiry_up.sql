@@ -0,0 +1,5 @@
+-- Migration: upload_expiry
+-- Description: Index every upload by expiry, completed or not, for the cleanup job
+-- CONCURRENTLY cannot run inside a transaction, so this migration has no BEGIN/COMMIT
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_uploads_expires_all ON uploads(expires_at);
@@ -0,0 +1,8 @@
+-- Rollback: tenants
+-- Tenant schemas are left in place; drop them with DROP SCHEMA tenant_<slug> CASCADE
+
+BEGIN;
+
+DROP TABLE IF EXISTS public.tenants;
+
+COMMIT;
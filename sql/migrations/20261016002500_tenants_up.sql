@@ -0,0 +1,16 @@
+-- Migration: tenants
+-- Description: Tenant registry for TENANCY_MODE; always in public, also when migrating tenant schemas
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS public.tenants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- names the tenant in the X-Tenant header or its subdomain
+    slug VARCHAR(40) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    -- holds the tenant's tables in schema mode (tenant_<slug>)
+    schema_name VARCHAR(63) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Rollback: saved views
+
+BEGIN;
+
+DROP TABLE IF EXISTS saved_views;
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- Migration: saved views
+-- Description: Named search, filter, sort, and column combinations users keep for data tables
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- the data table the view belongs to, e.g. users
+    resource VARCHAR(63) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    search VARCHAR(255) NOT NULL DEFAULT '',
+    -- column name -> value, as filter[column]=value
+    filters JSONB NOT NULL DEFAULT '{}',
+    -- as the sort parameter, e.g. -created_at,email
+    sort VARCHAR(255) NOT NULL DEFAULT '',
+    columns JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, resource, name)
+);
+
+COMMIT;
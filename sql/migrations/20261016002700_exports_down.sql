@@ -0,0 +1,8 @@
+-- Rollback: exports
+-- Stored export files are left in place; they are under exports/ in storage
+
+BEGIN;
+
+DROP TABLE IF EXISTS exports;
+
+COMMIT;
@@ -0,0 +1,27 @@
+-- Migration: exports
+-- Description: CSV exports of data tables, built in the background and emailed as signed links
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- kept when the user is deleted, so the cleanup job still deletes the file
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    resource VARCHAR(63) NOT NULL,
+    -- search, filters, sort, and columns of the table
+    params JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed', 'expired')),
+    row_count BIGINT NOT NULL DEFAULT 0,
+    byte_count BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    operation_id UUID,
+    storage_key VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_exports_user_id ON exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_exports_expires_at ON exports(expires_at) WHERE status = 'ready';
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Rollback: sessions
+-- Everyone signed in with a stored session is signed out
+
+BEGIN;
+
+DROP TABLE IF EXISTS sessions;
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Migration: sessions
+-- Description: Server-side sessions (AUTH=session) for deployments without Redis, keyed by the hashed session ID
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    key VARCHAR(64) PRIMARY KEY,
+    data BYTEA NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+COMMIT;
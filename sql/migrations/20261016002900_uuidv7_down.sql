@@ -0,0 +1,30 @@
+-- Rollback: UUIDv7 primary keys
+-- IDs already generated stay UUIDv7
+
+BEGIN;
+
+ALTER TABLE policy_documents ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE policy_acceptances ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE users ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE user_identities ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE api_keys ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE incidents ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE maintenance_windows ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE remember_tokens ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE invite_codes ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE referrals ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE referral_rewards ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE moderation_items ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE mail_outbox ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE newsletter_subscribers ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE surveys ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE survey_responses ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE event_outbox ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE operations ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE public.tenants ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE saved_views ALTER COLUMN id SET DEFAULT gen_random_uuid();
+ALTER TABLE exports ALTER COLUMN id SET DEFAULT gen_random_uuid();
+
+DROP FUNCTION IF EXISTS uuid_generate_v7();
+
+COMMIT;
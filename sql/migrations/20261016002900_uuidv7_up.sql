@@ -0,0 +1,42 @@
+-- Migration: UUIDv7 primary keys
+-- Description: Time-sortable default IDs (uuid_generate_v7) matching ids.NewV7, so new rows append to primary key indexes
+
+BEGIN;
+
+-- UUIDv7 (RFC 9562): 48 bits of Unix milliseconds, then version 7 and random bits taken
+-- from gen_random_uuid(), which already sets the variant
+CREATE OR REPLACE FUNCTION uuid_generate_v7() RETURNS UUID AS $$
+DECLARE
+    value BYTEA := uuid_send(gen_random_uuid());
+    unix_ms BIGINT := floor(extract(epoch FROM clock_timestamp()) * 1000);
+BEGIN
+    value := overlay(value PLACING substring(int8send(unix_ms) FROM 3) FROM 1 FOR 6);
+    value := set_byte(value, 6, (get_byte(value, 6) & 15) | 112);
+    RETURN encode(value, 'hex')::UUID;
+END
+$$ LANGUAGE plpgsql VOLATILE;
+
+-- Existing rows keep their IDs; only new rows get UUIDv7 values
+ALTER TABLE policy_documents ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE policy_acceptances ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE users ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE user_identities ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE api_keys ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE incidents ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE maintenance_windows ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE remember_tokens ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE invite_codes ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE referrals ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE referral_rewards ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE moderation_items ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE mail_outbox ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE newsletter_subscribers ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE surveys ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE survey_responses ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE event_outbox ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE operations ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE public.tenants ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE saved_views ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+ALTER TABLE exports ALTER COLUMN id SET DEFAULT uuid_generate_v7();
+
+COMMIT;